@@ -0,0 +1,519 @@
+package swarm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A2AProtocolVersion is the version of the Agent-to-Agent protocol spoken by
+// A2AClient and the A2A endpoints of the server package.
+const A2AProtocolVersion = "0.2.5"
+
+// A2AAgentCardPath is where an A2A agent publishes its card, relative to the
+// agent's URL.
+const A2AAgentCardPath = "/.well-known/agent-card.json"
+
+// A2ATaskState is the lifecycle state of an A2A task.
+type A2ATaskState string
+
+// A2A task states.
+const (
+	A2ATaskSubmitted     A2ATaskState = "submitted"
+	A2ATaskWorking       A2ATaskState = "working"
+	A2ATaskInputRequired A2ATaskState = "input-required"
+	A2ATaskCompleted     A2ATaskState = "completed"
+	A2ATaskCanceled      A2ATaskState = "canceled"
+	A2ATaskFailed        A2ATaskState = "failed"
+)
+
+// Terminal reports whether a task in the state is finished.
+func (s A2ATaskState) Terminal() bool {
+	return s == A2ATaskCompleted || s == A2ATaskCanceled || s == A2ATaskFailed
+}
+
+// A2A JSON-RPC error codes.
+const (
+	A2AErrParse           = -32700
+	A2AErrInvalidRequest  = -32600
+	A2AErrMethodNotFound  = -32601
+	A2AErrInvalidParams   = -32602
+	A2AErrInternal        = -32603
+	A2AErrTaskNotFound    = -32001
+	A2AErrTaskNotCanceled = -32002
+)
+
+// A2AAgentCard describes an A2A agent and how to reach it.
+type A2AAgentCard struct {
+	Name               string          `json:"name"`
+	Description        string          `json:"description"`
+	URL                string          `json:"url"`
+	Version            string          `json:"version"`
+	ProtocolVersion    string          `json:"protocolVersion"`
+	Capabilities       A2ACapabilities `json:"capabilities"`
+	DefaultInputModes  []string        `json:"defaultInputModes"`
+	DefaultOutputModes []string        `json:"defaultOutputModes"`
+	Skills             []A2ASkill      `json:"skills"`
+}
+
+// A2ACapabilities lists the optional protocol features an agent supports.
+type A2ACapabilities struct {
+	Streaming bool `json:"streaming"`
+}
+
+// A2ASkill is a capability advertised on an agent card.
+type A2ASkill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// A2APart is a piece of message or artifact content. Only text parts are
+// produced; other kinds are carried through unchanged.
+type A2APart struct {
+	Kind string                 `json:"kind"`
+	Text string                 `json:"text,omitempty"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// A2AMessage is a message exchanged between a client and an A2A agent.
+type A2AMessage struct {
+	Kind      string    `json:"kind"`
+	MessageID string    `json:"messageId"`
+	Role      string    `json:"role"`
+	Parts     []A2APart `json:"parts"`
+	TaskID    string    `json:"taskId,omitempty"`
+	ContextID string    `json:"contextId,omitempty"`
+}
+
+// NewA2AMessage creates a text message with the given role ("user" or
+// "agent").
+func NewA2AMessage(role, text string) A2AMessage {
+	return A2AMessage{
+		Kind:      "message",
+		MessageID: NewID("msg-"),
+		Role:      role,
+		Parts:     []A2APart{{Kind: "text", Text: text}},
+	}
+}
+
+// Text returns the text parts of the message, joined by newlines.
+func (m *A2AMessage) Text() string {
+	if m == nil {
+		return ""
+	}
+	return partsText(m.Parts)
+}
+
+// A2ATaskStatus is the state of a task, with the agent's latest message.
+type A2ATaskStatus struct {
+	State     A2ATaskState `json:"state"`
+	Message   *A2AMessage  `json:"message,omitempty"`
+	Timestamp string       `json:"timestamp,omitempty"`
+}
+
+// NewA2ATaskStatus creates a status stamped with the current time.
+func NewA2ATaskStatus(state A2ATaskState, message *A2AMessage) A2ATaskStatus {
+	return A2ATaskStatus{State: state, Message: message, Timestamp: time.Now().UTC().Format(time.RFC3339)}
+}
+
+// A2AArtifact is an output produced by a task.
+type A2AArtifact struct {
+	ArtifactID string    `json:"artifactId"`
+	Name       string    `json:"name,omitempty"`
+	Parts      []A2APart `json:"parts"`
+}
+
+// A2ATask is a unit of work submitted to an A2A agent.
+type A2ATask struct {
+	Kind      string        `json:"kind"`
+	ID        string        `json:"id"`
+	ContextID string        `json:"contextId"`
+	Status    A2ATaskStatus `json:"status"`
+	History   []A2AMessage  `json:"history,omitempty"`
+	Artifacts []A2AArtifact `json:"artifacts,omitempty"`
+}
+
+// Text returns the text of the task's artifacts or, without artifacts, of its
+// status message.
+func (t *A2ATask) Text() string {
+	var text []string
+	for _, artifact := range t.Artifacts {
+		if s := partsText(artifact.Parts); s != "" {
+			text = append(text, s)
+		}
+	}
+	if len(text) == 0 {
+		return t.Status.Message.Text()
+	}
+	return strings.Join(text, "\n")
+}
+
+// A2ATaskStatusUpdateEvent reports a task's status during a stream. Final
+// marks the last event of the stream.
+type A2ATaskStatusUpdateEvent struct {
+	Kind      string        `json:"kind"`
+	TaskID    string        `json:"taskId"`
+	ContextID string        `json:"contextId"`
+	Status    A2ATaskStatus `json:"status"`
+	Final     bool          `json:"final"`
+}
+
+// A2ATaskArtifactUpdateEvent streams a task's artifact. Append adds the parts
+// to the artifact sent by previous events with the same ID.
+type A2ATaskArtifactUpdateEvent struct {
+	Kind      string      `json:"kind"`
+	TaskID    string      `json:"taskId"`
+	ContextID string      `json:"contextId"`
+	Artifact  A2AArtifact `json:"artifact"`
+	Append    bool        `json:"append,omitempty"`
+	LastChunk bool        `json:"lastChunk,omitempty"`
+}
+
+// A2AEvent is an event of a message/stream response; exactly one field is
+// set. It marshals as the event itself, discriminated by its "kind".
+type A2AEvent struct {
+	Task           *A2ATask
+	Message        *A2AMessage
+	StatusUpdate   *A2ATaskStatusUpdateEvent
+	ArtifactUpdate *A2ATaskArtifactUpdateEvent
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e A2AEvent) MarshalJSON() ([]byte, error) {
+	switch {
+	case e.Task != nil:
+		return json.Marshal(e.Task)
+	case e.Message != nil:
+		return json.Marshal(e.Message)
+	case e.StatusUpdate != nil:
+		return json.Marshal(e.StatusUpdate)
+	case e.ArtifactUpdate != nil:
+		return json.Marshal(e.ArtifactUpdate)
+	}
+	return nil, errors.New("empty A2A event")
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *A2AEvent) UnmarshalJSON(data []byte) error {
+	var head struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	*e = A2AEvent{}
+	switch head.Kind {
+	case "task":
+		e.Task = &A2ATask{}
+		return json.Unmarshal(data, e.Task)
+	case "message":
+		e.Message = &A2AMessage{}
+		return json.Unmarshal(data, e.Message)
+	case "status-update":
+		e.StatusUpdate = &A2ATaskStatusUpdateEvent{}
+		return json.Unmarshal(data, e.StatusUpdate)
+	case "artifact-update":
+		e.ArtifactUpdate = &A2ATaskArtifactUpdateEvent{}
+		return json.Unmarshal(data, e.ArtifactUpdate)
+	}
+	return fmt.Errorf("unknown A2A event kind %q", head.Kind)
+}
+
+// A2ARequest is a JSON-RPC request of the A2A protocol.
+type A2ARequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// A2AResponse is a JSON-RPC response of the A2A protocol.
+type A2AResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *A2AError       `json:"error,omitempty"`
+}
+
+// A2AError is a JSON-RPC error.
+type A2AError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *A2AError) Error() string {
+	return fmt.Sprintf("A2A error %d: %s", e.Code, e.Message)
+}
+
+// A2AMessageSendParams are the params of message/send and message/stream.
+type A2AMessageSendParams struct {
+	Message A2AMessage `json:"message"`
+}
+
+// A2ATaskQueryParams are the params of tasks/get and tasks/cancel.
+type A2ATaskQueryParams struct {
+	ID string `json:"id"`
+}
+
+// A2AClient talks to a remote A2A agent.
+type A2AClient struct {
+	// URL is the agent's JSON-RPC endpoint
+	URL string
+	// HTTPClient sends the requests (default http.DefaultClient)
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	contextID string
+	taskID    string
+}
+
+// NewA2AClient creates a client for the A2A agent served at url.
+func NewA2AClient(url string) *A2AClient {
+	return &A2AClient{URL: strings.TrimSuffix(url, "/")}
+}
+
+// WithHTTPClient sets the HTTP client and returns the A2A client.
+func (c *A2AClient) WithHTTPClient(client *http.Client) *A2AClient {
+	c.HTTPClient = client
+	return c
+}
+
+// AgentCard fetches the remote agent's card.
+func (c *A2AClient) AgentCard(ctx context.Context) (*A2AAgentCard, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+A2AAgentCardPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch agent card: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch agent card: %s", resp.Status)
+	}
+	var card A2AAgentCard
+	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
+		return nil, fmt.Errorf("failed to decode agent card: %w", err)
+	}
+	return &card, nil
+}
+
+// SendMessage submits a message and waits for the resulting task. An agent
+// replying with a bare message is reported as a completed task.
+func (c *A2AClient) SendMessage(ctx context.Context, message A2AMessage) (*A2ATask, error) {
+	resp, err := c.post(ctx, "message/send", A2AMessageSendParams{Message: message}, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var rpc A2AResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpc); err != nil {
+		return nil, fmt.Errorf("failed to decode A2A response: %w", err)
+	}
+	if rpc.Error != nil {
+		return nil, rpc.Error
+	}
+	var event A2AEvent
+	if err := json.Unmarshal(rpc.Result, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode A2A result: %w", err)
+	}
+	if event.Message != nil {
+		return &A2ATask{
+			Kind:      "task",
+			ID:        event.Message.TaskID,
+			ContextID: event.Message.ContextID,
+			Status:    NewA2ATaskStatus(A2ATaskCompleted, event.Message),
+		}, nil
+	}
+	if event.Task == nil {
+		return nil, errors.New("A2A result is neither a task nor a message")
+	}
+	return event.Task, nil
+}
+
+// StreamMessage submits a message and streams the task's events. The channel
+// is closed after the final event, or when the stream fails.
+func (c *A2AClient) StreamMessage(ctx context.Context, message A2AMessage) (<-chan A2AEvent, error) {
+	resp, err := c.post(ctx, "message/stream", A2AMessageSendParams{Message: message}, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Errors are answered with a plain JSON-RPC response
+		defer resp.Body.Close()
+		var rpc A2AResponse
+		if err := json.NewDecoder(resp.Body).Decode(&rpc); err != nil {
+			return nil, fmt.Errorf("failed to decode A2A response: %w", err)
+		}
+		if rpc.Error != nil {
+			return nil, rpc.Error
+		}
+		return nil, errors.New("A2A agent did not stream the response")
+	}
+
+	events := make(chan A2AEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var rpc A2AResponse
+			var event A2AEvent
+			if json.Unmarshal([]byte(strings.TrimSpace(data)), &rpc) != nil || rpc.Error != nil ||
+				json.Unmarshal(rpc.Result, &event) != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+			if event.StatusUpdate != nil && event.StatusUpdate.Final {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// GetTask returns the current state of a task.
+func (c *A2AClient) GetTask(ctx context.Context, id string) (*A2ATask, error) {
+	return c.taskCall(ctx, "tasks/get", id)
+}
+
+// CancelTask cancels a running task.
+func (c *A2AClient) CancelTask(ctx context.Context, id string) (*A2ATask, error) {
+	return c.taskCall(ctx, "tasks/cancel", id)
+}
+
+// Handoff returns a tool that delegates the conversation to the remote agent,
+// so swarm agents can hand tasks to it like to a local agent. The model passes
+// the task as "message"; the remote agent's reply is the tool result. Calls
+// continue the same remote conversation, and answer a remote task waiting for
+// input instead of starting a new one.
+func (c *A2AClient) Handoff(name, description string) AgentFunction {
+	if description == "" {
+		description = fmt.Sprintf("Transfer the task to the remote agent %s and return its reply.", name)
+	}
+	return NewContextFunction(
+		"transfer_to_"+toolName(name),
+		description,
+		func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+			text, _ := args["message"].(string)
+			if strings.TrimSpace(text) == "" {
+				return nil, fmt.Errorf("%w: message is required", ErrInvalidParameter)
+			}
+			message := NewA2AMessage("user", text)
+			c.mu.Lock()
+			message.ContextID, message.TaskID = c.contextID, c.taskID
+			c.mu.Unlock()
+
+			task, err := c.SendMessage(cc, message)
+			if err != nil {
+				return nil, fmt.Errorf("remote agent %s failed: %w", name, err)
+			}
+			c.mu.Lock()
+			c.contextID, c.taskID = task.ContextID, ""
+			if task.Status.State == A2ATaskInputRequired {
+				c.taskID = task.ID
+			}
+			c.mu.Unlock()
+
+			switch task.Status.State {
+			case A2ATaskFailed, A2ATaskCanceled:
+				return nil, fmt.Errorf("remote agent %s %s the task: %s", name, task.Status.State, task.Status.Message.Text())
+			case A2ATaskInputRequired:
+				return fmt.Sprintf("%s needs more input: %s", name, task.Text()), nil
+			}
+			return task.Text(), nil
+		},
+		[]Parameter{{Name: "message", Description: "The task for the remote agent", Type: reflect.TypeOf(""), Required: true}},
+	)
+}
+
+// taskCall calls a task method and decodes the resulting task.
+func (c *A2AClient) taskCall(ctx context.Context, method, id string) (*A2ATask, error) {
+	resp, err := c.post(ctx, method, A2ATaskQueryParams{ID: id}, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var rpc A2AResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpc); err != nil {
+		return nil, fmt.Errorf("failed to decode A2A response: %w", err)
+	}
+	if rpc.Error != nil {
+		return nil, rpc.Error
+	}
+	var task A2ATask
+	if err := json.Unmarshal(rpc.Result, &task); err != nil {
+		return nil, fmt.Errorf("failed to decode A2A task: %w", err)
+	}
+	return &task, nil
+}
+
+// post sends a JSON-RPC request.
+func (c *A2AClient) post(ctx context.Context, method string, params interface{}, accept string) (*http.Response, error) {
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal A2A params: %w", err)
+	}
+	body, err := json.Marshal(A2ARequest{JSONRPC: "2.0", ID: NewID("req-"), Method: method, Params: payload})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal A2A request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("A2A %s failed: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("A2A %s failed: %s: %s", method, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return resp, nil
+}
+
+// httpClient returns the client's HTTP client.
+func (c *A2AClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// partsText joins the text parts.
+func partsText(parts []A2APart) string {
+	var text []string
+	for _, part := range parts {
+		if part.Kind == "text" && part.Text != "" {
+			text = append(text, part.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
@@ -0,0 +1,112 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFakeA2AAgent serves an A2A agent that asks for a name once, then greets
+// it. Tasks waiting for input are continued by their ID.
+func newFakeA2AAgent(t *testing.T, requests *[]A2AMessage) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == A2AAgentCardPath {
+			json.NewEncoder(w).Encode(A2AAgentCard{Name: "greeter", Capabilities: A2ACapabilities{Streaming: true}})
+			return
+		}
+		var req A2ARequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var params A2AMessageSendParams
+		json.Unmarshal(req.Params, &params)
+		*requests = append(*requests, params.Message)
+
+		task := A2ATask{Kind: "task", ID: "task-1", ContextID: "ctx-1"}
+		if params.Message.TaskID == "" {
+			reply := NewA2AMessage("agent", "What is your name?")
+			task.Status = NewA2ATaskStatus(A2ATaskInputRequired, &reply)
+		} else {
+			task.Status = NewA2ATaskStatus(A2ATaskCompleted, nil)
+			task.Artifacts = []A2AArtifact{{ArtifactID: "a", Parts: []A2APart{{Kind: "text", Text: "Hello, " + params.Message.Text()}}}}
+		}
+
+		if req.Method == "message/stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range []A2AEvent{
+				{Task: &task},
+				{StatusUpdate: &A2ATaskStatusUpdateEvent{Kind: "status-update", TaskID: task.ID, Status: task.Status, Final: true}},
+			} {
+				result, _ := json.Marshal(event)
+				data, _ := json.Marshal(A2AResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+				fmt.Fprintf(w, "data: %s\n\n", data)
+			}
+			return
+		}
+		result, _ := json.Marshal(A2AEvent{Task: &task})
+		json.NewEncoder(w).Encode(A2AResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestA2AClientHandoff(t *testing.T) {
+	var requests []A2AMessage
+	client := NewA2AClient(newFakeA2AAgent(t, &requests).URL)
+
+	card, err := client.AgentCard(context.Background())
+	AssertNoError(t, err, "fetch agent card")
+	AssertEqual(t, "greeter", card.Name, "agent card name")
+
+	handoff := client.Handoff("greeter", "")
+	AssertEqual(t, "transfer_to_greeter", handoff.Name(), "tool name")
+	AssertNoError(t, handoff.Validate(), "validate tool")
+
+	result, err := handoff.Call(map[string]interface{}{"message": "greet me"})
+	AssertNoError(t, err, "first call")
+	AssertEqual(t, "greeter needs more input: What is your name?", result, "first reply")
+
+	// The follow-up answers the task waiting for input in the same context
+	result, err = handoff.Call(map[string]interface{}{"message": "Ann"})
+	AssertNoError(t, err, "second call")
+	AssertEqual(t, "Hello, Ann", result, "second reply")
+	AssertEqual(t, 2, len(requests), "requests")
+	AssertEqual(t, "ctx-1", requests[1].ContextID, "follow-up context")
+	AssertEqual(t, "task-1", requests[1].TaskID, "follow-up task")
+
+	_, err = handoff.Call(map[string]interface{}{})
+	AssertError(t, err, "call without a message")
+}
+
+func TestA2AClientStream(t *testing.T) {
+	var requests []A2AMessage
+	client := NewA2AClient(newFakeA2AAgent(t, &requests).URL)
+
+	events, err := client.StreamMessage(context.Background(), NewA2AMessage("user", "hi"))
+	AssertNoError(t, err, "stream message")
+	var kinds []string
+	for event := range events {
+		switch {
+		case event.Task != nil:
+			kinds = append(kinds, "task")
+		case event.StatusUpdate != nil:
+			kinds = append(kinds, string(event.StatusUpdate.Status.State))
+		}
+	}
+	AssertEqual(t, "[task input-required]", fmt.Sprint(kinds), "stream events")
+}
+
+func TestA2AEventJSON(t *testing.T) {
+	message := NewA2AMessage("agent", "done")
+	data, err := json.Marshal(A2AEvent{Message: &message})
+	AssertNoError(t, err, "marshal event")
+
+	var event A2AEvent
+	AssertNoError(t, json.Unmarshal(data, &event), "unmarshal event")
+	AssertEqual(t, "done", event.Message.Text(), "message text")
+	AssertError(t, json.Unmarshal([]byte(`{"kind":"bogus"}`), &event), "unknown kind")
+	_, err = json.Marshal(A2AEvent{})
+	AssertError(t, err, "empty event")
+}
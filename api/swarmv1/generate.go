@@ -0,0 +1,5 @@
+// Package swarmv1 contains the Go bindings of the swarm-go gRPC API defined
+// in proto/swarm/v1. The server package implements WorkflowService.
+package swarmv1
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/feiskyer/swarm-go --go-grpc_out=../.. --go-grpc_opt=module=github.com/feiskyer/swarm-go swarm/v1/workflow.proto
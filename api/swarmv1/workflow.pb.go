@@ -0,0 +1,784 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: swarm/v1/workflow.proto
+
+// Package swarm.v1 is the gRPC API of swarm-go. WorkflowService lets services
+// written in any language submit runs of the workflows registered with a
+// swarm-go server, follow their events and manage them.
+
+package swarmv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// RunStatus is the state of a run.
+type RunStatus int32
+
+const (
+	RunStatus_RUN_STATUS_UNSPECIFIED RunStatus = 0
+	RunStatus_RUN_STATUS_PENDING     RunStatus = 1
+	RunStatus_RUN_STATUS_RUNNING     RunStatus = 2
+	RunStatus_RUN_STATUS_COMPLETE    RunStatus = 3
+	RunStatus_RUN_STATUS_FAILED      RunStatus = 4
+	RunStatus_RUN_STATUS_CANCELLED   RunStatus = 5
+	RunStatus_RUN_STATUS_PAUSED      RunStatus = 6
+)
+
+// Enum value maps for RunStatus.
+var (
+	RunStatus_name = map[int32]string{
+		0: "RUN_STATUS_UNSPECIFIED",
+		1: "RUN_STATUS_PENDING",
+		2: "RUN_STATUS_RUNNING",
+		3: "RUN_STATUS_COMPLETE",
+		4: "RUN_STATUS_FAILED",
+		5: "RUN_STATUS_CANCELLED",
+		6: "RUN_STATUS_PAUSED",
+	}
+	RunStatus_value = map[string]int32{
+		"RUN_STATUS_UNSPECIFIED": 0,
+		"RUN_STATUS_PENDING":     1,
+		"RUN_STATUS_RUNNING":     2,
+		"RUN_STATUS_COMPLETE":    3,
+		"RUN_STATUS_FAILED":      4,
+		"RUN_STATUS_CANCELLED":   5,
+		"RUN_STATUS_PAUSED":      6,
+	}
+)
+
+func (x RunStatus) Enum() *RunStatus {
+	p := new(RunStatus)
+	*p = x
+	return p
+}
+
+func (x RunStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RunStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_swarm_v1_workflow_proto_enumTypes[0].Descriptor()
+}
+
+func (RunStatus) Type() protoreflect.EnumType {
+	return &file_swarm_v1_workflow_proto_enumTypes[0]
+}
+
+func (x RunStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RunStatus.Descriptor instead.
+func (RunStatus) EnumDescriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{0}
+}
+
+type ListWorkflowsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWorkflowsRequest) Reset() {
+	*x = ListWorkflowsRequest{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWorkflowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWorkflowsRequest) ProtoMessage() {}
+
+func (x *ListWorkflowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWorkflowsRequest.ProtoReflect.Descriptor instead.
+func (*ListWorkflowsRequest) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{0}
+}
+
+type ListWorkflowsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Names of the registered workflows, sorted.
+	Workflows     []string `protobuf:"bytes,1,rep,name=workflows,proto3" json:"workflows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWorkflowsResponse) Reset() {
+	*x = ListWorkflowsResponse{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWorkflowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWorkflowsResponse) ProtoMessage() {}
+
+func (x *ListWorkflowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWorkflowsResponse.ProtoReflect.Descriptor instead.
+func (*ListWorkflowsResponse) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListWorkflowsResponse) GetWorkflows() []string {
+	if x != nil {
+		return x.Workflows
+	}
+	return nil
+}
+
+type SubmitRunRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Workflow is the name of a registered workflow.
+	Workflow string `protobuf:"bytes,1,opt,name=workflow,proto3" json:"workflow,omitempty"`
+	// Inputs are passed to the workflow's StartEvent.
+	Inputs        *structpb.Struct `protobuf:"bytes,2,opt,name=inputs,proto3" json:"inputs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitRunRequest) Reset() {
+	*x = SubmitRunRequest{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitRunRequest) ProtoMessage() {}
+
+func (x *SubmitRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitRunRequest.ProtoReflect.Descriptor instead.
+func (*SubmitRunRequest) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubmitRunRequest) GetWorkflow() string {
+	if x != nil {
+		return x.Workflow
+	}
+	return ""
+}
+
+func (x *SubmitRunRequest) GetInputs() *structpb.Struct {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+type GetRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunRequest) Reset() {
+	*x = GetRunRequest{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunRequest) ProtoMessage() {}
+
+func (x *GetRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunRequest.ProtoReflect.Descriptor instead.
+func (*GetRunRequest) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRunRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type ListRunsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Workflow optionally restricts the runs to one workflow.
+	Workflow      string `protobuf:"bytes,1,opt,name=workflow,proto3" json:"workflow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRunsRequest) Reset() {
+	*x = ListRunsRequest{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRunsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRunsRequest) ProtoMessage() {}
+
+func (x *ListRunsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRunsRequest.ProtoReflect.Descriptor instead.
+func (*ListRunsRequest) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListRunsRequest) GetWorkflow() string {
+	if x != nil {
+		return x.Workflow
+	}
+	return ""
+}
+
+type ListRunsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Runs          []*Run                 `protobuf:"bytes,1,rep,name=runs,proto3" json:"runs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRunsResponse) Reset() {
+	*x = ListRunsResponse{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRunsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRunsResponse) ProtoMessage() {}
+
+func (x *ListRunsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRunsResponse.ProtoReflect.Descriptor instead.
+func (*ListRunsResponse) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListRunsResponse) GetRuns() []*Run {
+	if x != nil {
+		return x.Runs
+	}
+	return nil
+}
+
+type CancelRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunRequest) Reset() {
+	*x = CancelRunRequest{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunRequest) ProtoMessage() {}
+
+func (x *CancelRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunRequest.ProtoReflect.Descriptor instead.
+func (*CancelRunRequest) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CancelRunRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type StreamEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	RunId string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	// Types optionally restricts the events to the given event types.
+	Types         []string `protobuf:"bytes,2,rep,name=types,proto3" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamEventsRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+// Run is a workflow run.
+type Run struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Workflow string                 `protobuf:"bytes,2,opt,name=workflow,proto3" json:"workflow,omitempty"`
+	Status   RunStatus              `protobuf:"varint,3,opt,name=status,proto3,enum=swarm.v1.RunStatus" json:"status,omitempty"`
+	Inputs   *structpb.Struct       `protobuf:"bytes,4,opt,name=inputs,proto3" json:"inputs,omitempty"`
+	// Result is the result of a completed run.
+	Result *structpb.Value `protobuf:"bytes,5,opt,name=result,proto3" json:"result,omitempty"`
+	// Error describes why a run failed or was cancelled.
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Run) Reset() {
+	*x = Run{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Run) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Run) ProtoMessage() {}
+
+func (x *Run) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Run.ProtoReflect.Descriptor instead.
+func (*Run) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Run) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Run) GetWorkflow() string {
+	if x != nil {
+		return x.Workflow
+	}
+	return ""
+}
+
+func (x *Run) GetStatus() RunStatus {
+	if x != nil {
+		return x.Status
+	}
+	return RunStatus_RUN_STATUS_UNSPECIFIED
+}
+
+func (x *Run) GetInputs() *structpb.Struct {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *Run) GetResult() *structpb.Value {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *Run) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Run) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Run) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+// Event is an event emitted by a run.
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	RunId string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	// Type is the event type, e.g. "StartEvent".
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Data          *structpb.Struct       `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	EventId       string                 `protobuf:"bytes,4,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	CausationId   string                 `protobuf:"bytes,5,opt,name=causation_id,json=causationId,proto3" json:"causation_id,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_swarm_v1_workflow_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_swarm_v1_workflow_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_swarm_v1_workflow_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Event) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Event) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *Event) GetCausationId() string {
+	if x != nil {
+		return x.CausationId
+	}
+	return ""
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_swarm_v1_workflow_proto protoreflect.FileDescriptor
+
+const file_swarm_v1_workflow_proto_rawDesc = "" +
+	"\n" +
+	"\x17swarm/v1/workflow.proto\x12\bswarm.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x16\n" +
+	"\x14ListWorkflowsRequest\"5\n" +
+	"\x15ListWorkflowsResponse\x12\x1c\n" +
+	"\tworkflows\x18\x01 \x03(\tR\tworkflows\"_\n" +
+	"\x10SubmitRunRequest\x12\x1a\n" +
+	"\bworkflow\x18\x01 \x01(\tR\bworkflow\x12/\n" +
+	"\x06inputs\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x06inputs\"&\n" +
+	"\rGetRunRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\"-\n" +
+	"\x0fListRunsRequest\x12\x1a\n" +
+	"\bworkflow\x18\x01 \x01(\tR\bworkflow\"5\n" +
+	"\x10ListRunsResponse\x12!\n" +
+	"\x04runs\x18\x01 \x03(\v2\r.swarm.v1.RunR\x04runs\")\n" +
+	"\x10CancelRunRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\"B\n" +
+	"\x13StreamEventsRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x14\n" +
+	"\x05types\x18\x02 \x03(\tR\x05types\"\xcd\x02\n" +
+	"\x03Run\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bworkflow\x18\x02 \x01(\tR\bworkflow\x12+\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x13.swarm.v1.RunStatusR\x06status\x12/\n" +
+	"\x06inputs\x18\x04 \x01(\v2\x17.google.protobuf.StructR\x06inputs\x12.\n" +
+	"\x06result\x18\x05 \x01(\v2\x16.google.protobuf.ValueR\x06result\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12;\n" +
+	"\vfinished_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\"\xcd\x01\n" +
+	"\x05Event\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12+\n" +
+	"\x04data\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x04data\x12\x19\n" +
+	"\bevent_id\x18\x04 \x01(\tR\aeventId\x12!\n" +
+	"\fcausation_id\x18\x05 \x01(\tR\vcausationId\x12.\n" +
+	"\x04time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x04time*\xb8\x01\n" +
+	"\tRunStatus\x12\x1a\n" +
+	"\x16RUN_STATUS_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12RUN_STATUS_PENDING\x10\x01\x12\x16\n" +
+	"\x12RUN_STATUS_RUNNING\x10\x02\x12\x17\n" +
+	"\x13RUN_STATUS_COMPLETE\x10\x03\x12\x15\n" +
+	"\x11RUN_STATUS_FAILED\x10\x04\x12\x18\n" +
+	"\x14RUN_STATUS_CANCELLED\x10\x05\x12\x15\n" +
+	"\x11RUN_STATUS_PAUSED\x10\x062\x8a\x03\n" +
+	"\x0fWorkflowService\x12P\n" +
+	"\rListWorkflows\x12\x1e.swarm.v1.ListWorkflowsRequest\x1a\x1f.swarm.v1.ListWorkflowsResponse\x126\n" +
+	"\tSubmitRun\x12\x1a.swarm.v1.SubmitRunRequest\x1a\r.swarm.v1.Run\x120\n" +
+	"\x06GetRun\x12\x17.swarm.v1.GetRunRequest\x1a\r.swarm.v1.Run\x12A\n" +
+	"\bListRuns\x12\x19.swarm.v1.ListRunsRequest\x1a\x1a.swarm.v1.ListRunsResponse\x126\n" +
+	"\tCancelRun\x12\x1a.swarm.v1.CancelRunRequest\x1a\r.swarm.v1.Run\x12@\n" +
+	"\fStreamEvents\x12\x1d.swarm.v1.StreamEventsRequest\x1a\x0f.swarm.v1.Event0\x01B2Z0github.com/feiskyer/swarm-go/api/swarmv1;swarmv1b\x06proto3"
+
+var (
+	file_swarm_v1_workflow_proto_rawDescOnce sync.Once
+	file_swarm_v1_workflow_proto_rawDescData []byte
+)
+
+func file_swarm_v1_workflow_proto_rawDescGZIP() []byte {
+	file_swarm_v1_workflow_proto_rawDescOnce.Do(func() {
+		file_swarm_v1_workflow_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_swarm_v1_workflow_proto_rawDesc), len(file_swarm_v1_workflow_proto_rawDesc)))
+	})
+	return file_swarm_v1_workflow_proto_rawDescData
+}
+
+var file_swarm_v1_workflow_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_swarm_v1_workflow_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_swarm_v1_workflow_proto_goTypes = []any{
+	(RunStatus)(0),                // 0: swarm.v1.RunStatus
+	(*ListWorkflowsRequest)(nil),  // 1: swarm.v1.ListWorkflowsRequest
+	(*ListWorkflowsResponse)(nil), // 2: swarm.v1.ListWorkflowsResponse
+	(*SubmitRunRequest)(nil),      // 3: swarm.v1.SubmitRunRequest
+	(*GetRunRequest)(nil),         // 4: swarm.v1.GetRunRequest
+	(*ListRunsRequest)(nil),       // 5: swarm.v1.ListRunsRequest
+	(*ListRunsResponse)(nil),      // 6: swarm.v1.ListRunsResponse
+	(*CancelRunRequest)(nil),      // 7: swarm.v1.CancelRunRequest
+	(*StreamEventsRequest)(nil),   // 8: swarm.v1.StreamEventsRequest
+	(*Run)(nil),                   // 9: swarm.v1.Run
+	(*Event)(nil),                 // 10: swarm.v1.Event
+	(*structpb.Struct)(nil),       // 11: google.protobuf.Struct
+	(*structpb.Value)(nil),        // 12: google.protobuf.Value
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_swarm_v1_workflow_proto_depIdxs = []int32{
+	11, // 0: swarm.v1.SubmitRunRequest.inputs:type_name -> google.protobuf.Struct
+	9,  // 1: swarm.v1.ListRunsResponse.runs:type_name -> swarm.v1.Run
+	0,  // 2: swarm.v1.Run.status:type_name -> swarm.v1.RunStatus
+	11, // 3: swarm.v1.Run.inputs:type_name -> google.protobuf.Struct
+	12, // 4: swarm.v1.Run.result:type_name -> google.protobuf.Value
+	13, // 5: swarm.v1.Run.created_at:type_name -> google.protobuf.Timestamp
+	13, // 6: swarm.v1.Run.finished_at:type_name -> google.protobuf.Timestamp
+	11, // 7: swarm.v1.Event.data:type_name -> google.protobuf.Struct
+	13, // 8: swarm.v1.Event.time:type_name -> google.protobuf.Timestamp
+	1,  // 9: swarm.v1.WorkflowService.ListWorkflows:input_type -> swarm.v1.ListWorkflowsRequest
+	3,  // 10: swarm.v1.WorkflowService.SubmitRun:input_type -> swarm.v1.SubmitRunRequest
+	4,  // 11: swarm.v1.WorkflowService.GetRun:input_type -> swarm.v1.GetRunRequest
+	5,  // 12: swarm.v1.WorkflowService.ListRuns:input_type -> swarm.v1.ListRunsRequest
+	7,  // 13: swarm.v1.WorkflowService.CancelRun:input_type -> swarm.v1.CancelRunRequest
+	8,  // 14: swarm.v1.WorkflowService.StreamEvents:input_type -> swarm.v1.StreamEventsRequest
+	2,  // 15: swarm.v1.WorkflowService.ListWorkflows:output_type -> swarm.v1.ListWorkflowsResponse
+	9,  // 16: swarm.v1.WorkflowService.SubmitRun:output_type -> swarm.v1.Run
+	9,  // 17: swarm.v1.WorkflowService.GetRun:output_type -> swarm.v1.Run
+	6,  // 18: swarm.v1.WorkflowService.ListRuns:output_type -> swarm.v1.ListRunsResponse
+	9,  // 19: swarm.v1.WorkflowService.CancelRun:output_type -> swarm.v1.Run
+	10, // 20: swarm.v1.WorkflowService.StreamEvents:output_type -> swarm.v1.Event
+	15, // [15:21] is the sub-list for method output_type
+	9,  // [9:15] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_swarm_v1_workflow_proto_init() }
+func file_swarm_v1_workflow_proto_init() {
+	if File_swarm_v1_workflow_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_swarm_v1_workflow_proto_rawDesc), len(file_swarm_v1_workflow_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_swarm_v1_workflow_proto_goTypes,
+		DependencyIndexes: file_swarm_v1_workflow_proto_depIdxs,
+		EnumInfos:         file_swarm_v1_workflow_proto_enumTypes,
+		MessageInfos:      file_swarm_v1_workflow_proto_msgTypes,
+	}.Build()
+	File_swarm_v1_workflow_proto = out.File
+	file_swarm_v1_workflow_proto_goTypes = nil
+	file_swarm_v1_workflow_proto_depIdxs = nil
+}
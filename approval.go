@@ -0,0 +1,265 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrToolDenied is returned for tool calls rejected by an approval hook.
+var ErrToolDenied = errors.New("tool call denied")
+
+// ApprovalFunc decides whether a tool call may run. Returning an error denies
+// the call.
+type ApprovalFunc func(call ToolCall) (bool, error)
+
+// approvalFunction gates an AgentFunction behind an approval hook. Calls
+// made by a Swarm are approved and audited by checkApproval; direct calls,
+// such as plan steps, are approved by the function itself.
+type approvalFunction struct {
+	AgentFunction
+	approve ApprovalFunc
+}
+
+// Call calls the gated function once approved, with a background context.
+func (f *approvalFunction) Call(args map[string]interface{}) (interface{}, error) {
+	return f.CallWithContext(&CallContext{Context: context.Background()}, args)
+}
+
+// CallWithContext calls the gated function once approved.
+func (f *approvalFunction) CallWithContext(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+	call := ToolCall{Function: Function{Name: f.Name(), Arguments: toolArguments(args)}}
+	if err := denial(f.hook(cc)(call)); err != nil {
+		return nil, err
+	}
+	return AsContextFunction(f.AgentFunction).CallWithContext(cc, args)
+}
+
+// hook returns the approval handler of the run started with ctx, if any, or
+// the function's own hook.
+func (f *approvalFunction) hook(ctx context.Context) ApprovalFunc {
+	if handler, ok := ctx.Value(approvalHandlerContextKey{}).(ApprovalFunc); ok && handler != nil {
+		return handler
+	}
+	return f.approve
+}
+
+// denial returns the error for a call that was not approved, or nil.
+func denial(approved bool, err error) error {
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: %v", ErrToolDenied, err)
+	case !approved:
+		return ErrToolDenied
+	}
+	return nil
+}
+
+// toolArguments encodes the arguments of a direct call as the model would
+// send them, without the context variables and files added by the swarm.
+func toolArguments(args map[string]interface{}) string {
+	visible := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k != ContextVariablesName && k != FilesName {
+			visible[k] = v
+		}
+	}
+	data, err := json.Marshal(visible)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// approvedFunction returns the function to call once checkApproval approved
+// a call to fn, so the call is not approved twice.
+func approvedFunction(fn AgentFunction) AgentFunction {
+	if gate, ok := fn.(*approvalFunction); ok {
+		return gate.AgentFunction
+	}
+	return fn
+}
+
+// WithApproval wraps fn so every call must be approved before it runs. Denied
+// calls are reported to the model as errors and recorded in the audit log.
+func WithApproval(fn AgentFunction, approve ApprovalFunc) AgentFunction {
+	if fn == nil || approve == nil {
+		return fn
+	}
+	return &approvalFunction{AgentFunction: fn, approve: approve}
+}
+
+// RequireApproval gates the named functions of the agent, or all of them if
+// no names are given, behind approve, and returns the agent for chaining.
+// It applies to functions already added to the agent.
+func (a *Agent) RequireApproval(approve ApprovalFunc, tools ...string) *Agent {
+	for i, fn := range a.Functions {
+		if fn == nil {
+			continue
+		}
+		if len(tools) > 0 && !containsString(tools, fn.Name()) {
+			continue
+		}
+		a.Functions[i] = WithApproval(fn, approve)
+	}
+	return a
+}
+
+// containsString reports whether values contains s.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// approvalHandlerContextKey carries the approval handler of a run.
+type approvalHandlerContextKey struct{}
+
+// WithApprovalHandler returns ctx routing the approval of gated tool calls to
+// approve, in place of the tools' own hooks, for runs started with it. It
+// lets a frontend ask a human, while the hooks given to WithApproval or
+// RequireApproval apply when no one is connected.
+func WithApprovalHandler(ctx context.Context, approve ApprovalFunc) context.Context {
+	return context.WithValue(ctx, approvalHandlerContextKey{}, approve)
+}
+
+// checkApproval asks the run's approval handler or fn's approval hook, if fn
+// is gated, whether the call may run, and records the decision. It returns a
+// non-nil error for denied calls.
+func (s *Swarm) checkApproval(ctx context.Context, fn AgentFunction, call ToolCall, debug bool) error {
+	gate, ok := fn.(*approvalFunction)
+	if !ok {
+		return nil
+	}
+	record := AuditRecord{
+		Action:    AuditApproval,
+		Tool:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+	}
+	start := time.Now()
+	approved, err := gate.hook(ctx)(call)
+	record.Duration = time.Since(start)
+	record.Result = "approved"
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if !approved || err != nil {
+		record.Result = "denied"
+	}
+	audit(s.AuditLogger, record, debug)
+	return denial(approved, err)
+}
+
+// InputRequiredEvent asks a human for input, such as approving a tool call.
+// It is delivered to stream subscribers; reply by sending a
+// HumanResponseEvent with the same RequestID to the workflow context.
+type InputRequiredEvent struct {
+	BaseEvent
+	// RequestID correlates the request with its response
+	RequestID string `json:"request_id"`
+	// Prompt describes the input needed
+	Prompt string `json:"prompt"`
+	// ToolCall is the tool call awaiting approval, if any
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+}
+
+// NewInputRequiredEvent creates an InputRequiredEvent with a new request ID.
+func NewInputRequiredEvent(prompt string, call *ToolCall) *InputRequiredEvent {
+	return &InputRequiredEvent{
+		BaseEvent: BaseEvent{
+			eventType: EventInputRequired,
+		},
+		RequestID: NewID("input-"),
+		Prompt:    prompt,
+		ToolCall:  call,
+	}
+}
+
+// Validate checks if the InputRequiredEvent is properly configured.
+func (e *InputRequiredEvent) Validate() error {
+	if err := e.BaseEvent.Validate(); err != nil {
+		return err
+	}
+	if e.RequestID == "" {
+		return fmt.Errorf("request ID is required")
+	}
+	return nil
+}
+
+// HumanResponseEvent answers an InputRequiredEvent.
+type HumanResponseEvent struct {
+	BaseEvent
+	// RequestID is the ID of the answered request
+	RequestID string `json:"request_id"`
+	// Approved is the decision for approval requests
+	Approved bool `json:"approved"`
+	// Response is optional free-form input
+	Response string `json:"response,omitempty"`
+}
+
+// NewHumanResponseEvent creates a HumanResponseEvent for a request.
+func NewHumanResponseEvent(requestID string, approved bool, response string) *HumanResponseEvent {
+	return &HumanResponseEvent{
+		BaseEvent: BaseEvent{
+			eventType: EventHumanResponse,
+		},
+		RequestID: requestID,
+		Approved:  approved,
+		Response:  response,
+	}
+}
+
+// Validate checks if the HumanResponseEvent is properly configured.
+func (e *HumanResponseEvent) Validate() error {
+	if err := e.BaseEvent.Validate(); err != nil {
+		return err
+	}
+	if e.RequestID == "" {
+		return fmt.Errorf("request ID is required")
+	}
+	return nil
+}
+
+// WorkflowApproval returns an ApprovalFunc for agents run inside a workflow
+// step. Each tool call publishes an InputRequiredEvent on the workflow stream
+// and waits for the matching HumanResponseEvent, sent with
+// handler.Context().SendEvent. The call is denied if timeout (when positive)
+// elapses or the workflow is cancelled first.
+func WorkflowApproval(ctx *Context, timeout time.Duration) ApprovalFunc {
+	return func(call ToolCall) (bool, error) {
+		sub := ctx.Subscribe(EventHumanResponse)
+		defer sub.Unsubscribe()
+
+		request := NewInputRequiredEvent(fmt.Sprintf("Approve call to %s?", call.Function.Name), &call)
+		if err := ctx.publish(request); err != nil {
+			return false, err
+		}
+
+		var expired <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+		for {
+			select {
+			case event, ok := <-sub.Events():
+				if !ok {
+					return false, fmt.Errorf("workflow finished before approval")
+				}
+				if response, ok := event.(*HumanResponseEvent); ok && response.RequestID == request.RequestID {
+					return response.Approved, nil
+				}
+			case <-expired:
+				return false, fmt.Errorf("approval timed out after %s", timeout)
+			case <-ctx.Context().Done():
+				return false, ctx.Context().Err()
+			}
+		}
+	}
+}
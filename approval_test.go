@@ -0,0 +1,162 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// newToolCallClient replies with a call to tool, then with reply.
+func newToolCallClient(tool, args, reply string) *scriptedClient {
+	client := newScriptedClient("", reply)
+	client.CompletionResponse[0].Choices[0].Message.ToolCalls = []openai.ChatCompletionMessageToolCall{
+		MockToolCall{ID: "call-1", Name: tool, Args: args}.ToOpenAI(),
+	}
+	return client
+}
+
+func newDeleteAgent(calls *int) *Agent {
+	return NewAgent("ops").AddFunction(NewAgentFunction("delete_pod", "Delete a pod",
+		func(args map[string]interface{}) (interface{}, error) {
+			*calls++
+			return "deleted", nil
+		},
+		[]Parameter{},
+	))
+}
+
+func TestRequireApprovalDenied(t *testing.T) {
+	calls := 0
+	var asked []ToolCall
+	agent := newDeleteAgent(&calls).RequireApproval(func(call ToolCall) (bool, error) {
+		asked = append(asked, call)
+		return false, nil
+	})
+	logger := &recordingAuditLogger{}
+	swarm := NewSwarm(newToolCallClient("delete_pod", `{"name": "web"}`, "Not allowed"))
+	swarm.AuditLogger = logger
+
+	response, err := swarm.Run(context.Background(), agent, []map[string]interface{}{
+		{"role": "user", "content": "Delete web"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, 0, calls, "denied tool is not executed")
+	AssertEqual(t, 1, len(asked), "approval requests")
+	AssertEqual(t, `{"name": "web"}`, asked[0].Function.Arguments, "approval arguments")
+	content, _ := response.Messages[1]["content"].(string)
+	AssertEqual(t, true, strings.Contains(content, "denied"), "model is told the call was denied")
+
+	var decisions []string
+	for _, record := range logger.records {
+		if record.Action == AuditApproval {
+			decisions = append(decisions, record.Result)
+		}
+	}
+	AssertEqual(t, "denied", strings.Join(decisions, ","), "recorded decision")
+}
+
+func TestRequireApprovalPlanStep(t *testing.T) {
+	calls := 0
+	var asked []ToolCall
+	agent := newDeleteAgent(&calls).RequireApproval(func(call ToolCall) (bool, error) {
+		asked = append(asked, call)
+		return false, nil
+	})
+	client := newScriptedClient(
+		`{"steps": [{"id": "1", "description": "delete web", "tool": "delete_pod", "arguments": {"name": "web"}}]}`,
+		"could not delete",
+	)
+
+	result, err := NewPlanExecutor(NewSwarm(client), NewAgent("planner")).WithTools(agent.Functions...).Run(context.Background(), "goal")
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, 0, calls, "denied plan step is not executed")
+	AssertEqual(t, 1, len(asked), "approval requests")
+	AssertEqual(t, `{"name":"web"}`, asked[0].Function.Arguments, "approval arguments")
+	AssertEqual(t, ErrToolDenied.Error(), result.Steps[0].Error, "step error")
+
+	_, err = agent.Functions[0].Call(map[string]interface{}{"name": "web"})
+	AssertEqual(t, true, errors.Is(err, ErrToolDenied), "direct call is denied")
+	AssertEqual(t, 0, calls, "denied direct call is not executed")
+}
+
+func TestApprovalHandler(t *testing.T) {
+	calls := 0
+	agent := newDeleteAgent(&calls).RequireApproval(func(call ToolCall) (bool, error) {
+		return false, nil
+	})
+	var asked []string
+	ctx := WithApprovalHandler(context.Background(), func(call ToolCall) (bool, error) {
+		asked = append(asked, call.Function.Name)
+		return true, nil
+	})
+
+	_, err := NewSwarm(newToolCallClient("delete_pod", `{}`, "Done")).Run(ctx, agent, []map[string]interface{}{
+		{"role": "user", "content": "Delete web"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "delete_pod", strings.Join(asked, ","), "handler asked instead of the hook")
+	AssertEqual(t, 1, calls, "tool approved by the handler is executed")
+}
+
+func TestRequireApprovalSelectedTools(t *testing.T) {
+	calls := 0
+	agent := newDeleteAgent(&calls).AddFunction(NewAgentFunction("list_pods", "List pods",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "web", nil
+		},
+		[]Parameter{},
+	))
+	agent.RequireApproval(func(call ToolCall) (bool, error) { return true, nil }, "delete_pod")
+
+	_, gated := agent.Functions[0].(*approvalFunction)
+	AssertEqual(t, true, gated, "delete_pod is gated")
+	_, gated = agent.Functions[1].(*approvalFunction)
+	AssertEqual(t, false, gated, "list_pods is not gated")
+
+	_, err := NewSwarm(newToolCallClient("delete_pod", `{}`, "Done")).Run(context.Background(), agent, []map[string]interface{}{
+		{"role": "user", "content": "Delete web"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, 1, calls, "approved tool is executed")
+}
+
+func TestWorkflowApproval(t *testing.T) {
+	calls := 0
+	client := newToolCallClient("delete_pod", `{}`, "Deleted")
+	gate := make(chan struct{})
+
+	workflow := NewWorkflow("approval")
+	workflow.AddStep(NewStep("operate", EventStart, func(ctx *Context, event Event) (Event, error) {
+		<-gate
+		agent := newDeleteAgent(&calls).RequireApproval(WorkflowApproval(ctx, 0))
+		response, err := NewSwarm(client).Run(ctx.Context(), agent, []map[string]interface{}{
+			{"role": "user", "content": "Delete web"},
+		}, nil, "", false, false, 10, true, false)
+		if err != nil {
+			return nil, err
+		}
+		return NewStopEvent(lastContent(response)), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "Run")
+	requests := handler.StreamFiltered(EventInputRequired)
+	close(gate)
+	go func() {
+		for event := range requests {
+			request := event.(*InputRequiredEvent)
+			if request.ToolCall != nil && request.ToolCall.Function.Name == "delete_pod" {
+				handler.Context().SendEvent(NewHumanResponseEvent(request.RequestID, true, ""))
+			}
+		}
+	}()
+
+	result, err := handler.WaitValue()
+	AssertNoError(t, err, "Wait")
+	AssertEqual(t, "Deleted", result, "result")
+	AssertEqual(t, 1, calls, "approved tool is executed")
+}
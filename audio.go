@@ -0,0 +1,212 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrAudioUnsupported is returned when an agent uses audio but the client does
+// not implement AudioClient.
+var ErrAudioUnsupported = errors.New("client does not support audio")
+
+// Default audio models and voice.
+const (
+	DefaultTranscriptionModel = "whisper-1"
+	DefaultSpeechModel        = "tts-1"
+	DefaultVoice              = "alloy"
+	DefaultSpeechFormat       = "mp3"
+)
+
+// AudioClient is implemented by clients that support speech-to-text and
+// text-to-speech. The clients returned by NewOpenAIClient and
+// NewAzureOpenAIClient implement it.
+type AudioClient interface {
+	// Transcribe converts speech to text. The filename's extension tells the
+	// API the audio format (e.g. "input.wav").
+	Transcribe(ctx context.Context, model string, audio io.Reader, filename string) (string, error)
+	// Speech converts text to audio in the given format (mp3, opus, aac, flac,
+	// wav or pcm) using the given voice.
+	Speech(ctx context.Context, model, voice, format, text string) ([]byte, error)
+}
+
+// AudioOptions configures an agent's audio input and output.
+type AudioOptions struct {
+	// TranscriptionModel transcribes audio messages (default "whisper-1")
+	TranscriptionModel string
+	// SpeechModel synthesizes replies (default "tts-1")
+	SpeechModel string
+	// Voice is the speech voice (default "alloy")
+	Voice string
+	// Format is the speech audio format (default "mp3")
+	Format string
+	// Output enables synthesizing the agent's final reply into the message's
+	// "audio" field
+	Output bool
+}
+
+// withDefaults fills unset options.
+func (o AudioOptions) withDefaults() AudioOptions {
+	if o.TranscriptionModel == "" {
+		o.TranscriptionModel = DefaultTranscriptionModel
+	}
+	if o.SpeechModel == "" {
+		o.SpeechModel = DefaultSpeechModel
+	}
+	if o.Voice == "" {
+		o.Voice = DefaultVoice
+	}
+	if o.Format == "" {
+		o.Format = DefaultSpeechFormat
+	}
+	return o
+}
+
+// WithAudio enables audio input and, if opts.Output is set, audio output for
+// the agent, and returns the agent for chaining.
+func (a *Agent) WithAudio(opts AudioOptions) *Agent {
+	a.Audio = &opts
+	return a
+}
+
+// NewAudioMessage creates a user message carrying recorded audio. Agents with
+// audio enabled transcribe it into the message content before calling the
+// model. format is the audio file extension, e.g. "wav" or "mp3".
+func NewAudioMessage(audio []byte, format string) map[string]interface{} {
+	return map[string]interface{}{
+		"role":         "user",
+		"audio":        audio,
+		"audio_format": format,
+	}
+}
+
+// Transcribe converts speech to text.
+//
+// Parameters:
+//   - ctx: The context for the API request (defaults to background if nil)
+//   - model: The transcription model, e.g. "whisper-1"
+//   - audio: The audio data
+//   - filename: The audio file name, whose extension identifies the format
+//
+// Returns the transcribed text or an error if the request fails.
+func (c *openAIClientWrapper) Transcribe(ctx context.Context, model string, audio io.Reader, filename string) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	transcription, err := c.client.Audio.Transcriptions.New(ctx, openai.AudioTranscriptionNewParams{
+		File:  openai.File(audio, filename, ""),
+		Model: openai.AudioModel(model),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	return transcription.Text, nil
+}
+
+// Speech converts text to audio.
+//
+// Parameters:
+//   - ctx: The context for the API request (defaults to background if nil)
+//   - model: The speech model, e.g. "tts-1"
+//   - voice: The voice, e.g. "alloy"
+//   - format: The audio format, e.g. "mp3"
+//   - text: The text to speak
+//
+// Returns the encoded audio or an error if the request fails.
+func (c *openAIClientWrapper) Speech(ctx context.Context, model, voice, format, text string) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	resp, err := c.client.Audio.Speech.New(ctx, openai.AudioSpeechNewParams{
+		Input:          text,
+		Model:          openai.SpeechModel(model),
+		Voice:          openai.AudioSpeechNewParamsVoice(voice),
+		ResponseFormat: openai.AudioSpeechNewParamsResponseFormat(format),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speech audio: %w", err)
+	}
+	return data, nil
+}
+
+// audioClient returns the swarm's client as an AudioClient.
+func (s *Swarm) audioClient() (AudioClient, error) {
+	client, ok := s.Client.(AudioClient)
+	if !ok {
+		return nil, ErrAudioUnsupported
+	}
+	return client, nil
+}
+
+// transcribeHistory returns history with the content of untranscribed audio
+// messages filled in. Messages are copied before being modified, so the
+// caller's messages are left untouched.
+func (s *Swarm) transcribeHistory(ctx context.Context, agent *Agent, history []map[string]interface{}) ([]map[string]interface{}, error) {
+	if agent.Audio == nil {
+		return history, nil
+	}
+	opts := agent.Audio.withDefaults()
+	for i, msg := range history {
+		audio, ok := msg["audio"].([]byte)
+		if content, _ := msg["content"].(string); !ok || content != "" || msg["role"] != "user" {
+			continue
+		}
+
+		client, err := s.audioClient()
+		if err != nil {
+			return nil, err
+		}
+		format, _ := msg["audio_format"].(string)
+		if format == "" {
+			format = "wav"
+		}
+		text, err := client.Transcribe(ctx, opts.TranscriptionModel, bytes.NewReader(audio), "input."+format)
+		if err != nil {
+			return nil, err
+		}
+
+		transcribed := make(map[string]interface{}, len(msg)+1)
+		for k, v := range msg {
+			transcribed[k] = v
+		}
+		transcribed["content"] = text
+		history[i] = transcribed
+	}
+	return history, nil
+}
+
+// synthesizeReply adds spoken audio of the message content when the agent has
+// audio output enabled.
+func (s *Swarm) synthesizeReply(ctx context.Context, agent *Agent, message map[string]interface{}) error {
+	if agent.Audio == nil || !agent.Audio.Output {
+		return nil
+	}
+	content, _ := message["content"].(string)
+	if content == "" {
+		return nil
+	}
+	client, err := s.audioClient()
+	if err != nil {
+		return err
+	}
+	opts := agent.Audio.withDefaults()
+	audio, err := client.Speech(ctx, opts.SpeechModel, opts.Voice, opts.Format, content)
+	if err != nil {
+		return err
+	}
+	message["audio"] = audio
+	message["audio_format"] = opts.Format
+	return nil
+}
@@ -0,0 +1,70 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go/option"
+)
+
+func TestRunWithAudio(t *testing.T) {
+	var prompt, spoken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/audio/transcriptions":
+			file, header, err := r.FormFile("file")
+			AssertNoError(t, err, "transcription file")
+			data, _ := io.ReadAll(file)
+			AssertEqual(t, "input.wav", header.Filename, "audio filename")
+			AssertEqual(t, "RIFF", string(data), "audio data")
+			AssertEqual(t, DefaultTranscriptionModel, r.FormValue("model"), "transcription model")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"text":"What time is it?"}`)
+		case "/chat/completions":
+			var body struct {
+				Messages []struct {
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			prompt = body.Messages[len(body.Messages)-1].Content
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Noon."}}]}`)
+		case "/audio/speech":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			spoken, _ = body["input"].(string)
+			AssertEqual(t, "nova", body["voice"], "voice")
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write([]byte("ID3"))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	swarm := NewSwarm(NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL)))
+	agent := NewAgent("Voice").WithModel("gpt-4o").WithAudio(AudioOptions{Voice: "nova", Output: true})
+
+	messages := []map[string]interface{}{NewAudioMessage([]byte("RIFF"), "wav")}
+	response, err := swarm.Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "What time is it?", prompt, "Audio should be transcribed before the model call")
+	AssertEqual(t, nil, messages[0]["content"], "Caller's message should not be modified")
+	AssertEqual(t, "Noon.", spoken, "Reply should be synthesized")
+	AssertEqual(t, "ID3", string(response.Messages[0]["audio"].([]byte)), "Reply audio")
+	AssertEqual(t, DefaultSpeechFormat, response.Messages[0]["audio_format"], "Reply audio format")
+}
+
+func TestRunWithAudioUnsupportedClient(t *testing.T) {
+	swarm := NewSwarm(NewMockOpenAIClient())
+	agent := NewAgent("Voice").WithAudio(AudioOptions{})
+	messages := []map[string]interface{}{NewAudioMessage([]byte("RIFF"), "wav")}
+	_, err := swarm.Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertError(t, err, "Run should fail without an audio client")
+}
@@ -0,0 +1,212 @@
+package swarm
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditAction identifies the kind of activity recorded in an audit log.
+type AuditAction string
+
+const (
+	// AuditStepStart is recorded before a workflow step handles an event
+	AuditStepStart AuditAction = "step_start"
+	// AuditStepFinish is recorded after a workflow step completes or fails
+	AuditStepFinish AuditAction = "step_finish"
+	// AuditStepRetry is recorded before a failed step is retried
+	AuditStepRetry AuditAction = "step_retry"
+	// AuditToolCall is recorded for every tool call executed by an agent
+	AuditToolCall AuditAction = "tool_call"
+	// AuditHandoff is recorded when a tool transfers control to another agent
+	AuditHandoff AuditAction = "handoff"
+	// AuditApproval is recorded when a tool call requiring approval is
+	// approved or denied; Result holds the decision
+	AuditApproval AuditAction = "approval"
+)
+
+// AuditRecord is a single entry in an audit log.
+type AuditRecord struct {
+	// Time is when the activity happened
+	Time time.Time `json:"time"`
+	// Action is the kind of activity
+	Action AuditAction `json:"action"`
+	// RunID identifies the workflow run, if any
+	RunID string `json:"run_id,omitempty"`
+	// Step is the workflow step name
+	Step string `json:"step,omitempty"`
+	// TaskID is the parallel task ID
+	TaskID string `json:"task_id,omitempty"`
+	// EventType is the type of the event being handled
+	EventType EventType `json:"event_type,omitempty"`
+	// Agent is the agent that received control (for handoffs)
+	Agent string `json:"agent,omitempty"`
+	// Tool is the tool name
+	Tool string `json:"tool,omitempty"`
+	// Arguments are the tool call arguments
+	Arguments string `json:"arguments,omitempty"`
+	// Result is the tool result
+	Result string `json:"result,omitempty"`
+	// Attempt is the 1-based attempt number for steps
+	Attempt int `json:"attempt,omitempty"`
+	// Duration is how long the activity took
+	Duration time.Duration `json:"duration,omitempty"`
+	// Error describes a failure
+	Error string `json:"error,omitempty"`
+	// PrevHash is the hash of the previous record in the log
+	PrevHash string `json:"prev_hash,omitempty"`
+	// Hash chains this record to the previous one, making edits detectable
+	Hash string `json:"hash,omitempty"`
+}
+
+// AuditLogger receives a record for every audited activity.
+// Implementations must be safe for concurrent use.
+type AuditLogger interface {
+	// Log records an activity
+	Log(record AuditRecord) error
+}
+
+// hashRecord computes the chain hash of a record.
+func hashRecord(record AuditRecord) (string, error) {
+	record.Hash = ""
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// JSONLAuditLogger writes hash-chained audit records as JSON lines.
+// Each record includes the hash of its predecessor, so VerifyAuditLog can
+// detect records that were modified, removed or reordered.
+type JSONLAuditLogger struct {
+	w      io.Writer
+	closer io.Closer
+	last   string
+	mu     sync.Mutex
+}
+
+// NewJSONLAuditLogger creates an audit logger writing to w.
+func NewJSONLAuditLogger(w io.Writer) *JSONLAuditLogger {
+	return &JSONLAuditLogger{w: w}
+}
+
+// OpenJSONLAuditLogger opens (or creates) a JSON-lines audit log file and
+// continues the hash chain of any records it already contains.
+func OpenJSONLAuditLogger(path string) (*JSONLAuditLogger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	last, err := lastAuditHash(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &JSONLAuditLogger{w: f, closer: f, last: last}, nil
+}
+
+// lastAuditHash verifies an existing log and returns the hash of its last record.
+func lastAuditHash(r io.Reader) (string, error) {
+	var last string
+	err := walkAuditLog(r, func(record AuditRecord) {
+		last = record.Hash
+	})
+	return last, err
+}
+
+// Log appends a record to the log, filling in its time and hashes.
+func (l *JSONLAuditLogger) Log(record AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	record.PrevHash = l.last
+	hash, err := hashRecord(record)
+	if err != nil {
+		return fmt.Errorf("failed to hash audit record: %w", err)
+	}
+	record.Hash = hash
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	l.last = hash
+	return nil
+}
+
+// Close closes the underlying file if the logger was opened from a path.
+func (l *JSONLAuditLogger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// VerifyAuditLog checks the hash chain of a JSON-lines audit log and returns
+// an error describing the first record that does not match.
+func VerifyAuditLog(r io.Reader) error {
+	return walkAuditLog(r, nil)
+}
+
+// walkAuditLog verifies each record of a log and passes it to fn.
+func walkAuditLog(r io.Reader, fn func(AuditRecord)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var prev string
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("audit log line %d: invalid record: %w", line, err)
+		}
+		if record.PrevHash != prev {
+			return fmt.Errorf("audit log line %d: broken hash chain", line)
+		}
+		hash, err := hashRecord(record)
+		if err != nil {
+			return fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		if hash != record.Hash {
+			return fmt.Errorf("audit log line %d: record hash mismatch", line)
+		}
+		if fn != nil {
+			fn(record)
+		}
+		prev = record.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
+
+// audit sends a record to logger, ignoring a nil logger. Audit failures are
+// reported in debug output but never interrupt execution.
+func audit(logger AuditLogger, record AuditRecord, debug bool) {
+	if logger == nil {
+		return
+	}
+	if err := logger.Log(record); err != nil {
+		DebugPrint(debug, "Failed to write audit record:", err)
+	}
+}
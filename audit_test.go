@@ -0,0 +1,73 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLAuditLoggerHashChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger, err := OpenJSONLAuditLogger(path)
+	AssertNoError(t, err, "OpenJSONLAuditLogger")
+	AssertNoError(t, logger.Log(AuditRecord{Action: AuditStepStart, Step: "outline"}), "Log")
+	AssertNoError(t, logger.Close(), "Close")
+
+	// Reopening continues the existing chain
+	logger, err = OpenJSONLAuditLogger(path)
+	AssertNoError(t, err, "reopen")
+	AssertNoError(t, logger.Log(AuditRecord{Action: AuditStepFinish, Step: "outline"}), "Log")
+	AssertNoError(t, logger.Close(), "Close")
+
+	data, err := os.ReadFile(path)
+	AssertNoError(t, err, "ReadFile")
+	AssertNoError(t, VerifyAuditLog(bytes.NewReader(data)), "VerifyAuditLog")
+
+	tampered := strings.Replace(string(data), "outline", "rewrite", 1)
+	AssertError(t, VerifyAuditLog(strings.NewReader(tampered)), "VerifyAuditLog tampered")
+}
+
+type recordingAuditLogger struct {
+	records []AuditRecord
+}
+
+func (r *recordingAuditLogger) Log(record AuditRecord) error {
+	r.records = append(r.records, record)
+	return nil
+}
+
+func TestWorkflowAuditLog(t *testing.T) {
+	logger := &recordingAuditLogger{}
+	retry := &RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	attempts := 0
+
+	workflow := NewWorkflow("audited")
+	workflow.WithConfig(WorkflowConfig{Timeout: time.Minute, AuditLogger: logger})
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, fmt.Errorf("transient")
+		}
+		return NewStopEvent("done"), nil
+	}, StepConfig{RetryPolicy: retry}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	_, err = handler.Wait()
+	AssertNoError(t, err, "Wait")
+
+	var actions []AuditAction
+	for _, record := range logger.records {
+		actions = append(actions, record.Action)
+	}
+	expected := []AuditAction{AuditStepStart, AuditStepFinish, AuditStepRetry, AuditStepStart, AuditStepFinish}
+	AssertEqual(t, fmt.Sprint(expected), fmt.Sprint(actions), "audited actions")
+	AssertEqual(t, "transient", logger.records[1].Error, "failed attempt error")
+}
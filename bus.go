@@ -0,0 +1,385 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrEventOverflow is returned by Publish when a subscriber using the
+// OverflowError policy has a full buffer.
+var ErrEventOverflow = errors.New("event buffer overflow")
+
+// OverflowPolicy controls what happens when a subscriber's buffer is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits until the subscriber has room (guaranteed delivery)
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest buffered event to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the event being published
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowError discards the event and makes Publish return ErrEventOverflow
+	OverflowError OverflowPolicy = "error"
+	// OverflowQueue queues events beyond the buffer without bound, so delivery
+	// is lossless and never holds up the publisher
+	OverflowQueue OverflowPolicy = "queue"
+)
+
+// WildcardEventType matches every event type when used as a subscription filter.
+const WildcardEventType EventType = "*"
+
+// DefaultSubscriptionBuffer is the channel buffer size used for new subscriptions.
+const DefaultSubscriptionBuffer = 100
+
+// EventFilter reports whether an event should be delivered to a subscriber.
+type EventFilter func(event Event) bool
+
+// MatchEventTypes returns an EventFilter that accepts events whose type matches
+// any of the given patterns. Patterns support shell-style wildcards (e.g. "*",
+// "Parallel*") as implemented by path.Match. An empty pattern list matches all events.
+func MatchEventTypes(patterns ...EventType) EventFilter {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return func(event Event) bool {
+		for _, p := range patterns {
+			if p == WildcardEventType || p == event.Type() {
+				return true
+			}
+			if ok, err := path.Match(string(p), string(event.Type())); err == nil && ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription represents a single consumer registered on an EventBus.
+// Each subscription owns its own buffered channel, so consumers never steal
+// events from each other.
+type Subscription struct {
+	id      uint64
+	ch      chan Event
+	filter  EventFilter
+	policy  OverflowPolicy
+	dropped atomic.Int64
+	bus     *EventBus
+	done    chan struct{}
+	once    sync.Once
+
+	// queue holds the events of an OverflowQueue subscription until its pump
+	// moves them to ch
+	queue    []Event
+	queueMu  sync.Mutex
+	wake     chan struct{}
+	stop     chan struct{}
+	pumpOnce sync.Once
+}
+
+// Events returns the channel on which matching events are delivered.
+// The channel is closed when the subscription is cancelled or the bus is closed;
+// with OverflowQueue, once the events queued before the bus was closed have
+// been received.
+func (s *Subscription) Events() <-chan Event {
+	if s.policy == OverflowQueue {
+		s.pumpOnce.Do(func() { go s.pump() })
+	}
+	return s.ch
+}
+
+// Unsubscribe removes the subscription from its bus and closes its channel.
+// It is safe to call Unsubscribe multiple times.
+func (s *Subscription) Unsubscribe() {
+	s.bus.remove(s)
+}
+
+// Dropped returns the number of events discarded because of the overflow policy.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// deliver sends the event according to the subscription's overflow policy.
+// It must be called with the bus read lock held.
+func (s *Subscription) deliver(ctx context.Context, event Event, busDone <-chan struct{}) error {
+	switch s.policy {
+	case OverflowQueue:
+		s.queueMu.Lock()
+		s.queue = append(s.queue, event)
+		s.queueMu.Unlock()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+		return nil
+	case OverflowDropNewest, OverflowError:
+		select {
+		case s.ch <- event:
+			return nil
+		case <-s.done:
+			return nil
+		default:
+			s.dropped.Add(1)
+			if s.policy == OverflowError {
+				return ErrEventOverflow
+			}
+			return nil
+		}
+	case OverflowDropOldest:
+		if cap(s.ch) == 0 {
+			// Nothing buffered to discard, behave like drop-newest
+			select {
+			case s.ch <- event:
+			case <-s.done:
+			default:
+				s.dropped.Add(1)
+			}
+			return nil
+		}
+		for {
+			select {
+			case s.ch <- event:
+				return nil
+			case <-s.done:
+				return nil
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		case <-busDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	}
+}
+
+// pump moves queued events to the channel of an OverflowQueue subscription.
+// It is started by the first call to Events, so events are queued until there
+// is a consumer, and closes the channel once the subscription is cancelled or
+// the bus is closed and the queue is drained.
+func (s *Subscription) pump() {
+	defer close(s.ch)
+	for {
+		s.queueMu.Lock()
+		if len(s.queue) == 0 {
+			s.queueMu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+			}
+			// Pick up events queued before the subscription was closed
+			s.queueMu.Lock()
+			empty := len(s.queue) == 0
+			s.queueMu.Unlock()
+			if empty {
+				return
+			}
+			continue
+		}
+		event := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.queueMu.Unlock()
+
+		select {
+		case s.ch <- event:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// close closes the subscription's channels. The channel of an OverflowQueue
+// subscription is closed by its pump instead. It must be called once.
+func (s *Subscription) close() {
+	close(s.done)
+	if s.policy != OverflowQueue {
+		close(s.ch)
+	}
+}
+
+// matches reports whether the subscription wants the event.
+func (s *Subscription) matches(event Event) bool {
+	return s.filter == nil || s.filter(event)
+}
+
+// EventBus is an in-process publish/subscribe hub for workflow events.
+// With the default OverflowBlock policy delivery to each subscriber is
+// guaranteed: Publish blocks until every matching subscriber has accepted the
+// event, the subscriber goes away, the bus is closed or the publishing context
+// is done. Other policies trade completeness for throughput.
+//
+// The EventBus is safe for concurrent use by multiple goroutines.
+type EventBus struct {
+	subs       map[uint64]*Subscription
+	nextID     uint64
+	bufferSize int
+	policy     OverflowPolicy
+	done       chan struct{}
+	once       sync.Once
+	mu         sync.RWMutex
+}
+
+// NewEventBus creates a new, empty EventBus with blocking delivery and the
+// default subscription buffer size.
+func NewEventBus() *EventBus {
+	return NewEventBusWithPolicy(DefaultSubscriptionBuffer, OverflowBlock)
+}
+
+// NewEventBusWithPolicy creates an EventBus whose subscriptions default to the
+// given buffer size and overflow policy.
+func NewEventBusWithPolicy(bufferSize int, policy OverflowPolicy) *EventBus {
+	if bufferSize < 0 {
+		bufferSize = DefaultSubscriptionBuffer
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
+	return &EventBus{
+		subs:       make(map[uint64]*Subscription),
+		bufferSize: bufferSize,
+		policy:     policy,
+		done:       make(chan struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for the given event type patterns.
+// Passing no patterns (or WildcardEventType) subscribes to all events.
+func (b *EventBus) Subscribe(types ...EventType) *Subscription {
+	return b.SubscribeWith(MatchEventTypes(types...), b.bufferSize, b.policy)
+}
+
+// SubscribeFunc registers a subscriber with a custom filter and buffer size,
+// using the bus's default overflow policy. A nil filter accepts every event.
+func (b *EventBus) SubscribeFunc(filter EventFilter, bufferSize int) *Subscription {
+	return b.SubscribeWith(filter, bufferSize, b.policy)
+}
+
+// SubscribeWith registers a subscriber with a custom filter, buffer size and
+// overflow policy. If the bus is already closed, the returned subscription's
+// channel is closed immediately.
+func (b *EventBus) SubscribeWith(filter EventFilter, bufferSize int, policy OverflowPolicy) *Subscription {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	sub := &Subscription{
+		id:     b.nextID,
+		ch:     make(chan Event, bufferSize),
+		filter: filter,
+		policy: policy,
+		bus:    b,
+		done:   make(chan struct{}),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	select {
+	case <-b.done:
+		sub.once.Do(sub.close)
+		return sub
+	default:
+	}
+
+	b.subs[sub.id] = sub
+	return sub
+}
+
+// Publish delivers the event to every matching subscriber according to each
+// subscription's overflow policy. Returns the context error if ctx is done
+// before delivery completes, or ErrEventOverflow if an OverflowError
+// subscriber could not accept the event.
+func (b *EventBus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	select {
+	case <-b.done:
+		return nil
+	default:
+	}
+
+	var overflow error
+	for _, sub := range b.subs {
+		if !sub.matches(event) {
+			continue
+		}
+		if err := sub.deliver(ctx, event, b.done); err != nil {
+			if errors.Is(err, ErrEventOverflow) {
+				overflow = err
+				continue
+			}
+			return err
+		}
+	}
+	return overflow
+}
+
+// Len returns the number of active subscriptions.
+func (b *EventBus) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Close closes the bus and all subscription channels.
+// Subsequent publishes are no-ops.
+func (b *EventBus) Close() {
+	b.once.Do(func() {
+		close(b.done)
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, sub := range b.subs {
+		sub.once.Do(sub.close)
+		delete(b.subs, id)
+	}
+}
+
+// remove unregisters a subscription and closes its channel.
+func (b *EventBus) remove(sub *Subscription) {
+	// Unblock any publisher waiting on this subscriber before taking the write lock.
+	closed := false
+	sub.once.Do(func() {
+		close(sub.done)
+		close(sub.stop)
+		closed = true
+	})
+	if !closed {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sub.id)
+	if sub.policy == OverflowQueue {
+		// Unsubscribing discards the queue; the pump closes the channel
+		sub.queueMu.Lock()
+		sub.queue = nil
+		sub.queueMu.Unlock()
+		sub.pumpOnce.Do(func() { close(sub.ch) })
+		return
+	}
+	close(sub.ch)
+}
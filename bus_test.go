@@ -0,0 +1,142 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventBusFanOut(t *testing.T) {
+	bus := NewEventBus()
+	all := bus.Subscribe()
+	parallel := bus.Subscribe("Parallel*")
+	stop := bus.Subscribe(EventStop)
+
+	events := []Event{
+		NewStartEvent(map[string]interface{}{}),
+		NewBaseEvent(EventParallelResult, nil),
+		NewStopEvent("done"),
+	}
+	for _, event := range events {
+		if err := bus.Publish(context.Background(), event); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	bus.Close()
+
+	count := func(sub *Subscription) int {
+		n := 0
+		for range sub.Events() {
+			n++
+		}
+		return n
+	}
+
+	AssertEqual(t, 3, count(all), "wildcard subscriber should receive all events")
+	AssertEqual(t, 1, count(parallel), "pattern subscriber should receive matching events")
+	AssertEqual(t, 1, count(stop), "type subscriber should receive matching events")
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.SubscribeFunc(nil, 0)
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Expected subscription channel to be closed")
+	}
+	AssertEqual(t, 0, bus.Len(), "bus should have no subscribers")
+
+	// Publishing without subscribers must not block.
+	AssertNoError(t, bus.Publish(context.Background(), NewStopEvent("done")), "Publish")
+}
+
+func TestEventBusPublishRespectsContext(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+	bus.SubscribeFunc(nil, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	AssertError(t, bus.Publish(ctx, NewStopEvent("done")), "Publish to a blocked subscriber")
+}
+
+func TestContextSubscribe(t *testing.T) {
+	ctx := NewContext(context.Background())
+	first := ctx.Subscribe()
+	second := ctx.Subscribe()
+
+	AssertNoError(t, ctx.SendEvent(NewStopEvent("done")), "SendEvent")
+
+	for _, sub := range []*Subscription{first, second} {
+		select {
+		case event := <-sub.Events():
+			AssertEqual(t, EventStop, event.Type(), "event type")
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+}
+
+func TestEventBusOverflowPolicies(t *testing.T) {
+	publishThree := func(bus *EventBus) error {
+		var lastErr error
+		for i := 0; i < 3; i++ {
+			if err := bus.Publish(context.Background(), NewBaseEvent(EventType("Tick"), map[string]interface{}{"i": i})); err != nil {
+				lastErr = err
+			}
+		}
+		return lastErr
+	}
+
+	t.Run("drop-newest", func(t *testing.T) {
+		bus := NewEventBusWithPolicy(2, OverflowDropNewest)
+		sub := bus.Subscribe()
+		AssertNoError(t, publishThree(bus), "Publish")
+		AssertEqual(t, int64(1), sub.Dropped(), "dropped events")
+		AssertEqual(t, 0, (<-sub.Events()).Data()["i"], "first event kept")
+	})
+
+	t.Run("drop-oldest", func(t *testing.T) {
+		bus := NewEventBusWithPolicy(2, OverflowDropOldest)
+		sub := bus.Subscribe()
+		AssertNoError(t, publishThree(bus), "Publish")
+		AssertEqual(t, int64(1), sub.Dropped(), "dropped events")
+		AssertEqual(t, 1, (<-sub.Events()).Data()["i"], "oldest event discarded")
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		bus := NewEventBusWithPolicy(2, OverflowQueue)
+		sub := bus.Subscribe()
+		AssertNoError(t, publishThree(bus), "Publish")
+		bus.Close()
+		count := 0
+		for event := range sub.Events() {
+			AssertEqual(t, count, event.Data()["i"], "events in order")
+			count++
+		}
+		AssertEqual(t, 3, count, "queued events")
+		AssertEqual(t, int64(0), sub.Dropped(), "dropped events")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		bus := NewEventBusWithPolicy(2, OverflowError)
+		bus.Subscribe()
+		if err := publishThree(bus); !errors.Is(err, ErrEventOverflow) {
+			t.Errorf("Expected ErrEventOverflow, got %v", err)
+		}
+	})
+}
+
+func TestContextOptions(t *testing.T) {
+	ctx := NewContext(context.Background(), WithEventBuffer(1), WithStreamBuffer(1), WithOverflowPolicy(OverflowDropNewest))
+	AssertEqual(t, 1, cap(ctx.eventChan), "event buffer size")
+
+	sub := ctx.Subscribe()
+	AssertNoError(t, ctx.SendEvent(NewStopEvent("one")), "SendEvent")
+	<-ctx.Events()
+	AssertNoError(t, ctx.SendEvent(NewStopEvent("two")), "SendEvent with full stream")
+	AssertEqual(t, int64(1), sub.Dropped(), "stream events dropped")
+}
@@ -0,0 +1,324 @@
+package swarm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StepCache stores serialized step results so re-running a workflow does not
+// repeat steps that already completed. Implementations must be safe for
+// concurrent use.
+type StepCache interface {
+	// Get returns the cached value for key. The boolean is false on a miss or
+	// when the entry has expired.
+	Get(key string) ([]byte, bool, error)
+	// Set stores value under key. A ttl of zero or less means the entry never expires.
+	Set(key string, value []byte, ttl time.Duration) error
+}
+
+// cacheEntry is a cached value with its expiry time.
+type cacheEntry struct {
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// expired reports whether the entry is past its expiry time.
+func (e cacheEntry) expired() bool {
+	return !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt)
+}
+
+// newCacheEntry creates an entry that expires after ttl (never if ttl <= 0).
+func newCacheEntry(value []byte, ttl time.Duration) cacheEntry {
+	entry := cacheEntry{Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	return entry
+}
+
+// MemoryStepCache is an in-memory StepCache.
+type MemoryStepCache struct {
+	entries map[string]cacheEntry
+	mu      sync.RWMutex
+}
+
+// NewMemoryStepCache creates an empty in-memory step cache.
+func NewMemoryStepCache() *MemoryStepCache {
+	return &MemoryStepCache{entries: make(map[string]cacheEntry)}
+}
+
+// Get returns the cached value for key.
+func (m *MemoryStepCache) Get(key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	if entry.expired() {
+		m.mu.Lock()
+		delete(m.entries, key)
+		m.mu.Unlock()
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// Set stores value under key.
+func (m *MemoryStepCache) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = newCacheEntry(value, ttl)
+	return nil
+}
+
+// FileStepCache is a StepCache that stores one JSON file per entry in a
+// directory, so cached results survive process restarts.
+type FileStepCache struct {
+	dir string
+}
+
+// NewFileStepCache creates a file-based step cache rooted at dir,
+// creating the directory if needed.
+func NewFileStepCache(dir string) (*FileStepCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileStepCache{dir: dir}, nil
+}
+
+// path returns the file path for a key.
+func (f *FileStepCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get reads the cached value for key from disk.
+func (f *FileStepCache) Get(key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("invalid cache entry: %w", err)
+	}
+	if entry.expired() {
+		os.Remove(f.path(key))
+		return nil, false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// Set writes value under key to disk.
+func (f *FileStepCache) Set(key string, value []byte, ttl time.Duration) error {
+	data, err := json.Marshal(newCacheEntry(value, ttl))
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	tmp := f.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return os.Rename(tmp, f.path(key))
+}
+
+// RedisStepCache is a StepCache backed by Redis, so cached entries can be
+// shared between processes. It uses a go-redis client, which handles
+// pooling, reconnects, AUTH and TLS, and works against a single node,
+// Sentinel or Cluster.
+type RedisStepCache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStepCache creates a Redis-backed cache for the server at addr
+// (host:port). An empty password skips AUTH; db selects the logical database.
+// Connections are opened lazily on first use.
+func NewRedisStepCache(addr, password string, db int) *RedisStepCache {
+	return NewRedisStepCacheWithClient(redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	}))
+}
+
+// NewRedisStepCacheWithClient creates a Redis-backed cache on an existing
+// client, for TLS, Sentinel or Cluster setups. Close closes the client.
+func NewRedisStepCacheWithClient(client redis.UniversalClient) *RedisStepCache {
+	return &RedisStepCache{client: client}
+}
+
+// WithPrefix namespaces all keys with prefix and returns the cache.
+func (r *RedisStepCache) WithPrefix(prefix string) *RedisStepCache {
+	r.prefix = prefix
+	return r
+}
+
+// Get returns the cached value for key.
+func (r *RedisStepCache) Get(key string) ([]byte, bool, error) {
+	value, err := r.client.Get(context.Background(), r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis GET failed: %w", err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under key, using a millisecond expiry when ttl > 0.
+func (r *RedisStepCache) Set(key string, value []byte, ttl time.Duration) error {
+	// go-redis treats a negative expiry as KEEPTTL, and Redis rejects a zero
+	// PX, so clamp to "no expiry" and round sub-millisecond TTLs up
+	if ttl < 0 {
+		ttl = 0
+	} else if ttl > 0 {
+		ttl = max(ttl, time.Millisecond)
+	}
+	if err := r.client.Set(context.Background(), r.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis SET failed: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying client.
+func (r *RedisStepCache) Close() error {
+	return r.client.Close()
+}
+
+// delete removes key from the cache.
+func (r *RedisStepCache) delete(key string) error {
+	if err := r.client.Del(context.Background(), r.prefix+key).Err(); err != nil {
+		return fmt.Errorf("redis DEL failed: %w", err)
+	}
+	return nil
+}
+
+// stepCacheKey derives the cache key for a step and its input event. Tracing
+// metadata is excluded, so the same input produces the same key across runs.
+func stepCacheKey(step Step, event Event) (string, error) {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(eventEnvelope{Type: event.Type(), Data: event.Data(), Payload: input})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return step.Name() + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// cacheableEvent reports whether an event can be restored by UnmarshalEvent
+// as the same Go type.
+func cacheableEvent(event Event) bool {
+	if _, ok := event.(*BaseEvent); ok {
+		return true
+	}
+	_, ok := RegisteredEventType(event.Type())
+	return ok
+}
+
+// loadCachedResult returns the cached result event for a step, if present.
+func (w *Workflow) loadCachedResult(step Step, event Event) (Event, string, bool) {
+	cache := w.config.Cache
+	if cache == nil || step.Config().CacheTTL == 0 {
+		return nil, "", false
+	}
+	key, err := stepCacheKey(step, event)
+	if err != nil {
+		return nil, "", false
+	}
+	data, ok, err := cache.Get(key)
+	if err != nil || !ok {
+		if err != nil && w.config.Verbose {
+			fmt.Printf("Step %s cache lookup failed: %v\n", step.Name(), err)
+		}
+		return nil, key, false
+	}
+	result, err := UnmarshalEvent(data)
+	if err != nil {
+		return nil, key, false
+	}
+	// Cached results are new events in this run
+	if carrier, ok := result.(MetadataCarrier); ok {
+		carrier.SetMetadata(EventMetadata{})
+	}
+	return result, key, true
+}
+
+// storeCachedResult caches a step's result event under key.
+func (w *Workflow) storeCachedResult(step Step, key string, result Event) {
+	if key == "" || result == nil || !cacheableEvent(result) {
+		return
+	}
+	data, err := MarshalEvent(result)
+	if err == nil {
+		err = w.config.Cache.Set(key, data, step.Config().CacheTTL)
+	}
+	if err != nil && w.config.Verbose {
+		fmt.Printf("Step %s cache store failed: %v\n", step.Name(), err)
+	}
+}
+
+// taskStoreKey returns the TaskStore key for a task of the run, or "" if the
+// task has no idempotency key or no store is configured.
+func (w *Workflow) taskStoreKey(wfCtx *Context, t Task) string {
+	if w.config.TaskStore == nil || t.IdempotencyKey == "" {
+		return ""
+	}
+	return "task:" + w.config.Name + ":" + wfCtx.RunID() + ":" + t.IdempotencyKey
+}
+
+// completedTask returns the stored result of a task completed in a previous attempt.
+func (w *Workflow) completedTask(wfCtx *Context, t Task) (Event, bool) {
+	key := w.taskStoreKey(wfCtx, t)
+	if key == "" {
+		return nil, false
+	}
+	data, ok, err := w.config.TaskStore.Get(key)
+	if err != nil || !ok {
+		if err != nil && w.config.Verbose {
+			fmt.Printf("Task %s store lookup failed: %v\n", t.ID, err)
+		}
+		return nil, false
+	}
+	result, err := UnmarshalEvent(data)
+	if err != nil {
+		return nil, false
+	}
+	if carrier, ok := result.(MetadataCarrier); ok {
+		carrier.SetMetadata(EventMetadata{})
+	}
+	return result, true
+}
+
+// markTaskCompleted records a successful task result in the TaskStore.
+// Results that cannot be restored as the same type are not recorded.
+func (w *Workflow) markTaskCompleted(wfCtx *Context, t Task, result Event) {
+	key := w.taskStoreKey(wfCtx, t)
+	if key == "" || result == nil || !cacheableEvent(result) {
+		return
+	}
+	data, err := MarshalEvent(result)
+	if err == nil {
+		err = w.config.TaskStore.Set(key, data, w.config.TaskStoreTTL)
+	}
+	if err != nil && w.config.Verbose {
+		fmt.Printf("Task %s store update failed: %v\n", t.ID, err)
+	}
+}
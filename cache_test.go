@@ -0,0 +1,187 @@
+package swarm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStepCacheSkipsCompletedSteps(t *testing.T) {
+	cache := NewMemoryStepCache()
+	var calls atomic.Int32
+
+	run := func() interface{} {
+		workflow := NewWorkflow("cached")
+		workflow.WithConfig(WorkflowConfig{Timeout: time.Minute, Cache: cache})
+		workflow.AddStep(NewStep("outline", EventStart, func(ctx *Context, event Event) (Event, error) {
+			calls.Add(1)
+			return NewBaseEvent(EventType("Outline"), map[string]interface{}{"topic": event.Data()["topic"]}), nil
+		}, StepConfig{CacheTTL: CacheForever}))
+		workflow.AddStep(NewStep("finish", EventType("Outline"), func(ctx *Context, event Event) (Event, error) {
+			return NewStopEvent(event.Data()["topic"]), nil
+		}, StepConfig{}))
+
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{"topic": "go"})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		result, err := handler.WaitValue()
+		AssertNoError(t, err, "Wait")
+		return result
+	}
+
+	AssertEqual(t, "go", run(), "first run result")
+	AssertEqual(t, "go", run(), "cached run result")
+	AssertEqual(t, int32(1), calls.Load(), "cached step should run once")
+}
+
+func TestStepCacheBackends(t *testing.T) {
+	fileCache, err := NewFileStepCache(t.TempDir())
+	AssertNoError(t, err, "NewFileStepCache")
+
+	redisCache := NewRedisStepCache(newFakeRedis(t), "", 0).WithPrefix("test:")
+	defer redisCache.Close()
+
+	for name, cache := range map[string]StepCache{"memory": NewMemoryStepCache(), "file": fileCache, "redis": redisCache} {
+		t.Run(name, func(t *testing.T) {
+			AssertNoError(t, cache.Set("fresh", []byte("v"), time.Minute), "Set")
+			AssertNoError(t, cache.Set("stale", []byte("v"), time.Nanosecond), "Set")
+			time.Sleep(time.Millisecond)
+
+			value, ok, err := cache.Get("fresh")
+			AssertNoError(t, err, "Get fresh")
+			AssertEqual(t, true, ok, "fresh entry hit")
+			AssertEqual(t, "v", string(value), "fresh value")
+
+			_, ok, err = cache.Get("stale")
+			AssertNoError(t, err, "Get stale")
+			AssertEqual(t, false, ok, "expired entry miss")
+		})
+	}
+}
+
+// newFakeRedis starts a minimal RESP server supporting GET, SET ... PX and DEL
+// and returns its address. Other commands, such as the client's HELLO and
+// CLIENT SETINFO handshake, get an error reply so it falls back to RESP2.
+func newFakeRedis(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	AssertNoError(t, err, "Listen")
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	store := make(map[string]cacheEntry)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readFakeRedisCommand(reader)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					switch strings.ToUpper(args[0]) {
+					case "GET":
+						entry, ok := store[args[1]]
+						if !ok || entry.expired() {
+							fmt.Fprint(conn, "$-1\r\n")
+						} else {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(entry.Value), entry.Value)
+						}
+					case "SET":
+						var ttl time.Duration
+						if len(args) == 5 {
+							ms, _ := strconv.Atoi(args[4])
+							ttl = time.Duration(ms) * time.Millisecond
+						}
+						store[args[1]] = newCacheEntry([]byte(args[2]), ttl)
+						fmt.Fprint(conn, "+OK\r\n")
+					case "DEL":
+						_, ok := store[args[1]]
+						delete(store, args[1])
+						if ok {
+							fmt.Fprint(conn, ":1\r\n")
+						} else {
+							fmt.Fprint(conn, ":0\r\n")
+						}
+					default:
+						fmt.Fprint(conn, "-ERR unknown command\r\n")
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// readFakeRedisCommand reads one RESP array of bulk strings.
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestTaskIdempotencyKeys(t *testing.T) {
+	store := NewMemoryStepCache()
+	var calls atomic.Int32
+
+	run := func(runID string) {
+		workflow := NewWorkflow("idempotent")
+		workflow.WithConfig(WorkflowConfig{Name: "idempotent", Timeout: time.Minute, TaskStore: store})
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			return NewParallelEvent([]Task{
+				NewTask("a", EventType("Work"), map[string]interface{}{"n": 1}).WithIdempotencyKey("run-1/a"),
+				NewTask("b", EventType("Work"), map[string]interface{}{"n": 2}).WithIdempotencyKey("run-1/b"),
+			}, "start")
+		}, StepConfig{}))
+		workflow.AddStep(NewStep("work", EventType("Work"), func(ctx *Context, event Event) (Event, error) {
+			calls.Add(1)
+			return NewBaseEvent(EventType("Done"), event.Data()), nil
+		}, StepConfig{}))
+		workflow.AddStep(NewStep("collect", EventParallelResult, func(ctx *Context, event Event) (Event, error) {
+			return NewStopEvent(event.(*ParallelResultEvent).Successful), nil
+		}, StepConfig{}))
+
+		handler, err := workflow.run(context.Background(), []Event{NewStartEvent(map[string]interface{}{})}, nil, WithRunID(runID))
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		result, err := handler.WaitValue()
+		AssertNoError(t, err, "Wait")
+		AssertEqual(t, 2, result, "successful tasks")
+	}
+
+	run("run-1")
+	run("run-1")
+	AssertEqual(t, int32(2), calls.Load(), "completed tasks should not run again")
+	run("run-2")
+	AssertEqual(t, int32(4), calls.Load(), "tasks of another run should run")
+}
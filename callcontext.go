@@ -0,0 +1,143 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+)
+
+// CallContext carries the run state of a tool call. It embeds the run's
+// context, so tools can honor cancellation and deadlines by passing it to
+// context-aware APIs.
+type CallContext struct {
+	context.Context
+	// Agent is the agent that called the tool
+	Agent *Agent
+	// ToolCallID is the model-assigned ID of the call
+	ToolCallID string
+	// RunID identifies the Run or RunAndStream invocation; nested runs
+	// started with the CallContext share it
+	RunID string
+	// ContextVariables are the run's context variables
+	ContextVariables map[string]interface{}
+}
+
+// ContextFunction is an AgentFunction that receives a CallContext. The swarm
+// calls CallWithContext instead of Call when a function implements it.
+type ContextFunction interface {
+	AgentFunction
+	// CallWithContext executes the function with the call's context
+	CallWithContext(cc *CallContext, args map[string]interface{}) (interface{}, error)
+}
+
+// SimpleContextFunction is a helper struct to create a ContextFunction from a
+// simple function.
+type SimpleContextFunction struct {
+	CallFn         func(cc *CallContext, args map[string]interface{}) (interface{}, error)
+	DescString     string
+	NameString     string
+	ParametersList []Parameter
+}
+
+// Call executes the function outside of a run, with a background context.
+func (f *SimpleContextFunction) Call(args map[string]interface{}) (interface{}, error) {
+	return f.CallWithContext(&CallContext{Context: context.Background()}, args)
+}
+
+// CallWithContext executes the function with the call's context.
+func (f *SimpleContextFunction) CallWithContext(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+	if f.CallFn == nil {
+		return nil, fmt.Errorf("%w: CallFn is nil", ErrInvalidFunction)
+	}
+	return f.CallFn(cc, args)
+}
+
+// Description returns the function's documentation.
+func (f *SimpleContextFunction) Description() string {
+	return f.DescString
+}
+
+// Name returns the function's name.
+func (f *SimpleContextFunction) Name() string {
+	return f.NameString
+}
+
+// Parameters returns the function's parameters.
+func (f *SimpleContextFunction) Parameters() []Parameter {
+	return f.ParametersList
+}
+
+// Validate checks if the function is properly configured.
+func (f *SimpleContextFunction) Validate() error {
+	if f.CallFn == nil {
+		return fmt.Errorf("%w: CallFn is nil", ErrInvalidFunction)
+	}
+	if f.NameString == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidFunction)
+	}
+	return nil
+}
+
+// NewContextFunction creates a new AgentFunction whose function receives the
+// CallContext of each call.
+func NewContextFunction(name string, desc string, fn func(cc *CallContext, args map[string]interface{}) (interface{}, error), parameters []Parameter) AgentFunction {
+	return &SimpleContextFunction{
+		CallFn:         fn,
+		DescString:     desc,
+		NameString:     name,
+		ParametersList: parameters,
+	}
+}
+
+// contextAdapter adapts an AgentFunction that ignores the CallContext.
+type contextAdapter struct {
+	AgentFunction
+}
+
+// CallWithContext calls the wrapped function, checking for cancellation first.
+func (f contextAdapter) CallWithContext(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+	if err := cc.Err(); err != nil {
+		return nil, err
+	}
+	return f.Call(args)
+}
+
+// AsContextFunction returns fn as a ContextFunction, adapting functions that
+// only implement Call.
+func AsContextFunction(fn AgentFunction) ContextFunction {
+	if cf, ok := fn.(ContextFunction); ok {
+		return cf
+	}
+	return contextAdapter{fn}
+}
+
+// runIDContextKey carries the ID of the current run.
+type runIDContextKey struct{}
+
+// withRunID returns ctx carrying a run ID, keeping an existing one so nested
+// runs share it.
+func withRunID(ctx context.Context) context.Context {
+	if runIDFromContext(ctx) != "" {
+		return ctx
+	}
+	return context.WithValue(ctx, runIDContextKey{}, NewID("run-"))
+}
+
+// runIDFromContext returns the run ID carried by ctx.
+func runIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDContextKey{}).(string)
+	return id
+}
+
+// callerContextKey carries the agent whose tool calls are being handled.
+type callerContextKey struct{}
+
+// withCaller returns ctx carrying the agent making tool calls.
+func withCaller(ctx context.Context, agent *Agent) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, agent)
+}
+
+// callerFromContext returns the agent carried by ctx.
+func callerFromContext(ctx context.Context) *Agent {
+	agent, _ := ctx.Value(callerContextKey{}).(*Agent)
+	return agent
+}
@@ -0,0 +1,67 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContextFunctionReceivesCallContext(t *testing.T) {
+	var got *CallContext
+	agent := NewAgent("ops").AddFunction(NewContextFunction("lookup", "Look something up",
+		func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+			got = cc
+			return "found", nil
+		},
+		[]Parameter{},
+	))
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	_, err := NewSwarm(newToolCallClient("lookup", `{}`, "done")).Run(ctx, agent, []map[string]interface{}{
+		{"role": "user", "content": "Look up"},
+	}, map[string]interface{}{"user": "alice"}, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+
+	if got == nil {
+		t.Fatal("expected the function to be called")
+	}
+	AssertEqual(t, "value", got.Value(key{}), "run context is passed through")
+	AssertEqual(t, "ops", got.Agent.Name, "calling agent")
+	AssertEqual(t, "call-1", got.ToolCallID, "tool call ID")
+	AssertEqual(t, "alice", got.ContextVariables["user"], "context variables")
+	AssertEqual(t, true, got.RunID != "", "run ID")
+}
+
+func TestAsContextFunctionHonorsCancellation(t *testing.T) {
+	calls := 0
+	fn := AsContextFunction(NewAgentFunction("plain", "A plain function",
+		func(args map[string]interface{}) (interface{}, error) {
+			calls++
+			return "ok", nil
+		},
+		[]Parameter{},
+	))
+
+	result, err := fn.CallWithContext(&CallContext{Context: context.Background()}, nil)
+	AssertNoError(t, err, "CallWithContext")
+	AssertEqual(t, "ok", result, "result")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fn.CallWithContext(&CallContext{Context: ctx}, nil)
+	AssertEqual(t, true, errors.Is(err, context.Canceled), "cancelled call")
+	AssertEqual(t, 1, calls, "cancelled call is skipped")
+}
+
+func TestSimpleContextFunctionCall(t *testing.T) {
+	fn := NewContextFunction("ping", "Ping", func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+		return cc.Err() == nil, nil
+	}, []Parameter{})
+
+	AssertNoError(t, fn.Validate(), "Validate")
+	result, err := fn.Call(nil)
+	AssertNoError(t, err, "Call")
+	AssertEqual(t, true, result, "background context")
+	AssertError(t, (&SimpleContextFunction{NameString: "nil"}).Validate(), "Validate without CallFn")
+}
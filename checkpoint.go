@@ -0,0 +1,298 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrCheckpointNotFound is returned by a CheckpointStore when no checkpoint exists for a key.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// DefaultContinuationPrompt asks the model to resume an interrupted generation.
+const DefaultContinuationPrompt = "Your previous response was interrupted. Continue exactly where it stopped, without repeating any text that was already written."
+
+// GenerationCheckpoint records the partially streamed output of a single generation.
+type GenerationCheckpoint struct {
+	// Key identifies the generation (e.g. "novel/chapter-3")
+	Key string `json:"key"`
+	// Agent is the name of the agent producing the content
+	Agent string `json:"agent"`
+	// Content is the text streamed so far
+	Content string `json:"content"`
+	// Resumes counts how many times the generation has been resumed
+	Resumes int `json:"resumes"`
+	// UpdatedAt is the time of the last checkpoint
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointStore persists generation checkpoints.
+type CheckpointStore interface {
+	// Save stores or replaces the checkpoint for cp.Key
+	Save(cp *GenerationCheckpoint) error
+	// Load returns the checkpoint for key or ErrCheckpointNotFound
+	Load(key string) (*GenerationCheckpoint, error)
+	// Delete removes the checkpoint for key. Deleting a missing key is not an error.
+	Delete(key string) error
+}
+
+// MemoryCheckpointStore is an in-memory CheckpointStore, mainly useful for tests
+// and for resuming within a single process.
+type MemoryCheckpointStore struct {
+	checkpoints map[string]GenerationCheckpoint
+	mu          sync.RWMutex
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]GenerationCheckpoint)}
+}
+
+// Save stores the checkpoint in memory.
+func (m *MemoryCheckpointStore) Save(cp *GenerationCheckpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[cp.Key] = *cp
+	return nil
+}
+
+// Load returns a copy of the stored checkpoint.
+func (m *MemoryCheckpointStore) Load(key string) (*GenerationCheckpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cp, ok := m.checkpoints[key]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+	return &cp, nil
+}
+
+// Delete removes the checkpoint from memory.
+func (m *MemoryCheckpointStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.checkpoints, key)
+	return nil
+}
+
+// FileCheckpointStore stores each checkpoint as a JSON file in a directory.
+type FileCheckpointStore struct {
+	Dir string
+}
+
+// NewFileCheckpointStore creates a file-backed checkpoint store rooted at dir.
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{Dir: dir}
+}
+
+// path returns the file path used for a checkpoint key. Every character
+// but letters, digits and "-_.~" is escaped, so distinct keys never share a
+// file.
+func (f *FileCheckpointStore) path(key string) string {
+	return filepath.Join(f.Dir, url.QueryEscape(key)+".json")
+}
+
+// Save writes the checkpoint atomically to disk.
+func (f *FileCheckpointStore) Save(cp *GenerationCheckpoint) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint dir: %w", err)
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	tmp := f.path(cp.Key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(tmp, f.path(cp.Key))
+}
+
+// Load reads the checkpoint from disk.
+func (f *FileCheckpointStore) Load(key string) (*GenerationCheckpoint, error) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrCheckpointNotFound
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp GenerationCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// Delete removes the checkpoint file.
+func (f *FileCheckpointStore) Delete(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// CheckpointConfig configures partial-output checkpointing for a long generation.
+type CheckpointConfig struct {
+	// Store persists checkpoints. Required.
+	Store CheckpointStore
+	// Key identifies the generation in the store. Required.
+	Key string
+	// Interval is the number of newly streamed characters between checkpoints (default 500)
+	Interval int
+	// MaxResumes limits automatic resumes after a mid-stream failure (default 3)
+	MaxResumes int
+	// ContinuationPrompt is sent to the model when resuming (default DefaultContinuationPrompt)
+	ContinuationPrompt string
+}
+
+// RunWithCheckpoint streams a single generation from the agent and periodically
+// checkpoints the accumulated content. If the stream fails midway, the partial
+// content is kept and the generation is resumed with an explicit continuation
+// prompt instead of being regenerated from scratch. An existing checkpoint for
+// the same key (e.g. from a crashed process) is resumed as well.
+//
+// Tool calls are not executed; the returned Response contains a single assistant
+// message holding the full content. The checkpoint is deleted on success.
+func (s *Swarm) RunWithCheckpoint(
+	ctx context.Context,
+	agent *Agent,
+	messages []map[string]interface{},
+	contextVariables map[string]interface{},
+	modelOverride string,
+	debug bool,
+	config CheckpointConfig,
+) (*Response, error) {
+	if len(messages) == 0 {
+		return nil, ErrEmptyMessages
+	}
+	if agent == nil {
+		return nil, errors.New("agent cannot be nil")
+	}
+	if config.Store == nil || config.Key == "" {
+		return nil, errors.New("checkpoint store and key are required")
+	}
+	if config.Interval <= 0 {
+		config.Interval = 500
+	}
+	if config.MaxResumes <= 0 {
+		config.MaxResumes = 3
+	}
+	if config.ContinuationPrompt == "" {
+		config.ContinuationPrompt = DefaultContinuationPrompt
+	}
+	if contextVariables == nil {
+		contextVariables = make(map[string]interface{})
+	}
+
+	cp, err := config.Store.Load(config.Key)
+	if err != nil {
+		if !errors.Is(err, ErrCheckpointNotFound) {
+			return nil, err
+		}
+		cp = &GenerationCheckpoint{Key: config.Key, Agent: agent.Name}
+	} else {
+		DebugPrint(debug, "Resuming generation from checkpoint:", config.Key)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= config.MaxResumes; attempt++ {
+		history := make([]map[string]interface{}, len(messages), len(messages)+2)
+		copy(history, messages)
+		if cp.Content != "" {
+			history = append(history,
+				map[string]interface{}{"role": "assistant", "content": cp.Content},
+				map[string]interface{}{"role": "user", "content": config.ContinuationPrompt},
+			)
+		}
+
+		lastErr = s.streamWithCheckpoint(ctx, agent, history, contextVariables, modelOverride, cp, config)
+		if lastErr == nil {
+			if err := config.Store.Delete(config.Key); err != nil {
+				DebugPrint(debug, "Failed to delete checkpoint:", err)
+			}
+			return &Response{
+				Messages: []map[string]interface{}{
+					{
+						"role":    "assistant",
+						"sender":  agent.Name,
+						"content": cp.Content,
+					},
+				},
+				Agent:            agent,
+				ContextVariables: contextVariables,
+			}, nil
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+		DebugPrint(debug, "Generation interrupted, resuming from checkpoint:", lastErr)
+		cp.Resumes++
+		if err := config.Store.Save(cp); err != nil {
+			return nil, fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("generation %s failed after %d resumes: %w", config.Key, cp.Resumes, lastErr)
+}
+
+// streamWithCheckpoint runs one streaming attempt, appending content to cp and
+// saving it every config.Interval characters.
+func (s *Swarm) streamWithCheckpoint(
+	ctx context.Context,
+	agent *Agent,
+	history []map[string]interface{},
+	contextVariables map[string]interface{},
+	modelOverride string,
+	cp *GenerationCheckpoint,
+	config CheckpointConfig,
+) error {
+	params, err := s.buildChatParams(ctx, agent, history, contextVariables, modelOverride, false)
+	if err != nil {
+		return err
+	}
+	params.Tools = nil
+	params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+
+	stream, err := s.Client.CreateChatCompletionStream(ctx, params)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	sinceLast := 0
+	for stream.Next() {
+		chunk := stream.Current()
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		cp.Content += delta
+		sinceLast += len(delta)
+		if sinceLast >= config.Interval {
+			cp.UpdatedAt = time.Now()
+			if err := config.Store.Save(cp); err != nil {
+				return fmt.Errorf("failed to save checkpoint: %w", err)
+			}
+			sinceLast = 0
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		cp.UpdatedAt = time.Now()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestRunWithCheckpointResume(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	mockClient.AddStreamChunk(&openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{
+			{Delta: openai.ChatCompletionChunkChoiceDelta{Content: " world"}},
+		},
+	})
+	client := NewSwarm(mockClient)
+
+	store := NewMemoryCheckpointStore()
+	AssertNoError(t, store.Save(&GenerationCheckpoint{Key: "greeting", Content: "Hello"}), "Save")
+
+	response, err := client.RunWithCheckpoint(
+		context.Background(),
+		NewAgent("Writer"),
+		[]map[string]interface{}{{"role": "user", "content": "Say hello"}},
+		nil,
+		"",
+		false,
+		CheckpointConfig{Store: store, Key: "greeting", Interval: 1},
+	)
+	if err != nil {
+		t.Fatalf("RunWithCheckpoint failed: %v", err)
+	}
+
+	AssertEqual(t, "Hello world", response.Messages[0]["content"], "resumed content")
+	if _, err := store.Load("greeting"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("Expected checkpoint to be deleted, got %v", err)
+	}
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store := NewFileCheckpointStore(t.TempDir())
+
+	if _, err := store.Load("missing"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("Expected ErrCheckpointNotFound, got %v", err)
+	}
+
+	AssertNoError(t, store.Save(&GenerationCheckpoint{Key: "novel/chapter-1", Content: "It was"}), "Save")
+	cp, err := store.Load("novel/chapter-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	AssertEqual(t, "It was", cp.Content, "checkpoint content")
+
+	// Keys differing only in separators do not share a file
+	AssertNoError(t, store.Save(&GenerationCheckpoint{Key: "novel_chapter-1", Content: "Once"}), "Save similar key")
+	cp, err = store.Load("novel/chapter-1")
+	AssertNoError(t, err, "Load")
+	AssertEqual(t, "It was", cp.Content, "checkpoint content after saving a similar key")
+
+	AssertNoError(t, store.Delete("novel/chapter-1"), "Delete")
+	AssertNoError(t, store.Delete("novel/chapter-1"), "Delete missing")
+}
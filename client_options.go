@@ -0,0 +1,218 @@
+package swarm
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/openai/openai-go/option"
+)
+
+// clientOptions holds the settings applied by ClientOption functions.
+type clientOptions struct {
+	httpClient *http.Client
+	proxy      func(*http.Request) (*url.URL, error)
+	timeout    time.Duration
+	headers    http.Header
+	transport  *TransportConfig
+}
+
+// ClientOption configures the transport of clients created by NewOpenAIClient,
+// NewOpenAIClientWithBaseURL and NewAzureOpenAIClient.
+type ClientOption func(*clientOptions)
+
+// WithHTTPClient sends requests through client instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithProxy sends requests through the proxy at proxyURL, e.g.
+// "http://proxy.corp:3128". An invalid URL fails every request with the parse
+// error. When combined with WithHTTPClient, the proxy is applied to a copy of
+// the client's *http.Transport; custom RoundTrippers are left unchanged.
+func WithProxy(proxyURL string) ClientOption {
+	return func(o *clientOptions) {
+		proxy, err := url.Parse(proxyURL)
+		o.proxy = func(*http.Request) (*url.URL, error) {
+			return proxy, err
+		}
+	}
+}
+
+// WithRequestTimeout limits each request attempt to timeout. Retries made by
+// the underlying client get their own timeout.
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithHeader adds a header sent with every request, e.g. for API gateways
+// that require their own authentication.
+func WithHeader(key, value string) ClientOption {
+	return func(o *clientOptions) {
+		if o.headers == nil {
+			o.headers = http.Header{}
+		}
+		o.headers.Add(key, value)
+	}
+}
+
+// WithTransport tunes the connection pool of the client's transport. Like
+// WithProxy it applies to a copy of the transport with its own pool; create a
+// shared client with NewHTTPClient to tune one pool used by many clients.
+func WithTransport(config TransportConfig) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = &config
+	}
+}
+
+// TransportConfig tunes the connections of an HTTP transport, e.g. to keep
+// enough idle connections for workflows running many agents at once. Zero
+// fields keep the settings of the transport being tuned.
+type TransportConfig struct {
+	// MaxIdleConns limits the idle connections to all hosts
+	MaxIdleConns int `yaml:"max_idle_conns" json:"max_idle_conns"`
+	// MaxIdleConnsPerHost limits the idle connections kept to each host
+	// (net/http defaults to 2, too few for concurrent requests to one API)
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+	// MaxConnsPerHost limits all connections to each host (default unlimited)
+	MaxConnsPerHost int `yaml:"max_conns_per_host" json:"max_conns_per_host"`
+	// IdleConnTimeout closes connections idle for longer
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout" json:"idle_conn_timeout"`
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool `yaml:"disable_keep_alives" json:"disable_keep_alives"`
+	// KeepAlive is the interval of TCP keep-alive probes, negative disables
+	// them
+	KeepAlive time.Duration `yaml:"keep_alive" json:"keep_alive"`
+	// DialTimeout limits establishing a connection
+	DialTimeout time.Duration `yaml:"dial_timeout" json:"dial_timeout"`
+	// TLSHandshakeTimeout limits the TLS handshake
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout" json:"tls_handshake_timeout"`
+	// ResponseHeaderTimeout limits the wait for the response headers after
+	// the request is sent; streamed bodies are not limited
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout" json:"response_header_timeout"`
+}
+
+// DefaultTransportConfig returns settings suited to many concurrent requests
+// to a single LLM API.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+		DialTimeout:         30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// apply tunes transport with the non-zero settings of c.
+func (c TransportConfig) apply(transport *http.Transport) {
+	if c.MaxIdleConns > 0 {
+		transport.MaxIdleConns = c.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	}
+	if c.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = c.MaxConnsPerHost
+	}
+	if c.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = c.IdleConnTimeout
+	}
+	if c.DisableKeepAlives {
+		transport.DisableKeepAlives = true
+	}
+	if c.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	}
+	if c.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+	}
+	if c.DialTimeout != 0 || c.KeepAlive != 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if c.DialTimeout > 0 {
+			dialer.Timeout = c.DialTimeout
+		}
+		if c.KeepAlive != 0 {
+			dialer.KeepAlive = c.KeepAlive
+		}
+		transport.DialContext = dialer.DialContext
+	}
+}
+
+// NewHTTPClient creates an HTTP client with its own transport tuned by
+// config. Pass it with WithHTTPClient to every client constructor so agents
+// and providers reuse the connections of one pool:
+//
+//	httpClient := NewHTTPClient(DefaultTransportConfig())
+//	primary := NewOpenAIClient(key, WithHTTPClient(httpClient))
+//	backup := NewAzureOpenAIClient(azureKey, endpoint, version, WithHTTPClient(httpClient))
+func NewHTTPClient(config TransportConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	config.apply(transport)
+	return &http.Client{Transport: transport}
+}
+
+// requestOptions converts the client options to openai-go request options.
+func (o *clientOptions) requestOptions() []option.RequestOption {
+	var opts []option.RequestOption
+	if client := o.client(); client != nil {
+		opts = append(opts, option.WithHTTPClient(client))
+	}
+	if o.timeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(o.timeout))
+	}
+	for key, values := range o.headers {
+		for _, value := range values {
+			opts = append(opts, option.WithHeaderAdd(key, value))
+		}
+	}
+	return opts
+}
+
+// client returns the HTTP client to use, with the proxy and transport
+// settings applied to a copy of its transport. It returns nil to keep the
+// openai-go default.
+func (o *clientOptions) client() *http.Client {
+	if o.proxy == nil && o.transport == nil {
+		return o.httpClient
+	}
+
+	client := &http.Client{}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		client = &copied
+	}
+	base := http.DefaultTransport.(*http.Transport)
+	if client.Transport != nil {
+		custom, ok := client.Transport.(*http.Transport)
+		if !ok {
+			// The settings can't be applied to a custom RoundTripper
+			return o.httpClient
+		}
+		base = custom
+	}
+	transport := base.Clone()
+	if o.proxy != nil {
+		transport.Proxy = o.proxy
+	}
+	if o.transport != nil {
+		o.transport.apply(transport)
+	}
+	client.Transport = transport
+	return client
+}
+
+// newClientOptions applies opts and returns the resulting request options.
+func newClientOptions(opts []ClientOption) []option.RequestOption {
+	var options clientOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options.requestOptions()
+}
@@ -0,0 +1,222 @@
+// Command swarm runs SimpleFlow YAML workflows without writing Go.
+//
+// Usage:
+//
+//	swarm run flow.yaml [--input key=value]... [--output result.json] [--quiet] [--resume]
+//
+// Progress is written to stderr and the results to stdout (or --output) as
+// JSON. Completed steps are recorded in a checkpoint file next to the flow
+// (or --checkpoint) until the flow succeeds; --resume continues a failed or
+// interrupted run after its completed steps. Steps may only reference agents by name if they are registered, so
+// flows run by the CLI use the instructions in the YAML file. Tools declared
+// in the file's tools section (http, shell and template tools) are available
+// to the steps that list them. "${NAME}" references in the file are replaced
+// with environment variables, e.g. for API tokens of http tools.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+const usage = `Usage: swarm <command> [arguments]
+
+Commands:
+  run <flow.yaml>   run a SimpleFlow workflow
+
+Run "swarm run -h" for the options of run.
+`
+
+// inputFlags collects repeated --input key=value flags.
+type inputFlags map[string]interface{}
+
+// String implements flag.Value.
+func (f inputFlags) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a key=value pair. Values that are valid JSON, such as numbers,
+// booleans, arrays or objects, are decoded; others are kept as strings.
+func (f inputFlags) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid input %q, expected key=value", pair)
+	}
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		parsed = value
+	}
+	f[key] = parsed
+	return nil
+}
+
+// stepOutput is the result of a step in the JSON output.
+type stepOutput struct {
+	Name     string `json:"name"`
+	Content  string `json:"content,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// runOutput is the JSON output of the run command.
+type runOutput struct {
+	Flow   string       `json:"flow"`
+	Result string       `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Steps  []stepOutput `json:"steps"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "run":
+		return runFlow(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+// runFlow implements "swarm run".
+func runFlow(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	inputs := inputFlags{}
+	fs.Var(inputs, "input", "workflow input as key=value (repeatable)")
+	output := fs.String("output", "", "write the JSON results to this file instead of stdout")
+	quiet := fs.Bool("quiet", false, "do not print progress")
+	checkpoint := fs.String("checkpoint", "", "record completed steps in this file (default <flow.yaml>.checkpoint.json)")
+	resume := fs.Bool("resume", false, "skip the steps completed by the last run")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: swarm run <flow.yaml> [--input key=value]... [--output file] [--quiet] [--checkpoint file] [--resume]")
+		fs.PrintDefaults()
+	}
+
+	// Allow flags after the file name, e.g. "swarm run flow.yaml --input a=b"
+	var path string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" {
+		fs.Usage()
+		return 2
+	}
+
+	flow, err := swarm.LoadSimpleFlow(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if flow.Inputs == nil {
+		flow.Inputs = make(map[string]interface{}, len(inputs))
+	}
+	for k, v := range inputs {
+		flow.Inputs[k] = v
+	}
+	flow.Checkpoint = *checkpoint
+	if flow.Checkpoint == "" {
+		flow.Checkpoint = swarm.SimpleFlowCheckpointPath(path)
+	}
+	flow.Resume = *resume
+
+	result := runOutput{Flow: flow.Name, Steps: make([]stepOutput, 0, len(flow.Steps))}
+	flow.Progress = func(p swarm.SimpleFlowProgress) {
+		if p.Result == nil {
+			if !*quiet {
+				fmt.Fprintf(stderr, "[%d/%d] %s...\n", p.Index+1, p.Total, p.Step)
+			}
+			return
+		}
+		step := stepOutput{Name: p.Step, Content: p.Result.Content, Duration: p.Duration.Round(time.Millisecond).String()}
+		status := "done"
+		switch {
+		case p.Result.Error != nil:
+			step.Error = p.Result.Error.Error()
+			status = "failed"
+		case p.Result.Resumed:
+			status = "resumed"
+		case p.Result.Skipped:
+			status = "skipped"
+		}
+		result.Steps = append(result.Steps, step)
+		if !*quiet {
+			fmt.Fprintf(stderr, "[%d/%d] %s %s (%s)\n", p.Index+1, p.Total, p.Step, status, step.Duration)
+		}
+	}
+
+	client, err := swarm.NewDefaultSwarm()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	content, _, runErr := flow.Run(ctx, client)
+	result.Result = content
+	if runErr != nil {
+		result.Error = runErr.Error()
+		fmt.Fprintf(stderr, "Error: %v\n", runErr)
+	}
+
+	if err := writeOutput(result, *output, stdout); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if runErr != nil {
+		return 1
+	}
+	return 0
+}
+
+// writeOutput writes the results as indented JSON to path, or to stdout if
+// path is empty.
+func writeOutput(result runOutput, path string, stdout io.Writer) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// SummaryName is the message key marking the summary added by
+// SummarizeOldestCompactor.
+const SummaryName = "summary"
+
+// Compactor shrinks a conversation history to about target tokens for model.
+// The returned history must not start with tool results whose call was
+// removed. s is the swarm running the agent, for strategies calling a model.
+type Compactor func(ctx context.Context, s *Swarm, history []map[string]interface{}, model string, target int) ([]map[string]interface{}, error)
+
+// CompactionConfig configures the history compaction of an agent. Before
+// each request, a history larger than Threshold is compacted by Strategy to
+// Target, and the compacted history is used for the rest of the run and
+// returned in Response.History. A compacted history that still overflows the
+// context window is then trimmed like any other, dropping its oldest
+// messages from that request only.
+type CompactionConfig struct {
+	// Strategy compacts the history
+	Strategy Compactor
+	// Threshold is the history size in tokens that triggers compaction
+	// (default three quarters of the context window left by MaxTokens)
+	Threshold int
+	// Target is the history size in tokens to compact to (default half the
+	// Threshold)
+	Target int
+}
+
+// WithCompaction compacts the agent's history with strategy once it exceeds
+// threshold tokens (0 for the default) and returns the agent for chaining.
+func (a *Agent) WithCompaction(strategy Compactor, threshold int) *Agent {
+	a.Compaction = &CompactionConfig{Strategy: strategy, Threshold: threshold}
+	return a
+}
+
+// limits returns the threshold and target of the agent's compaction for
+// model, or a zero threshold if the model's context window is unknown.
+func (c *CompactionConfig) limits(agent *Agent, model string) (int, int) {
+	threshold := c.Threshold
+	if threshold <= 0 {
+		window := agent.ContextWindow
+		if window == 0 {
+			window = ContextWindow(model)
+		}
+		threshold = (window - agent.MaxTokens) * 3 / 4
+	}
+	target := c.Target
+	if target <= 0 || target > threshold {
+		target = threshold / 2
+	}
+	return threshold, target
+}
+
+// historyCompaction is the compacted history of a run. Only the messages
+// appended since the last compaction are added to it, so a strategy calling
+// a model runs once each time the threshold is crossed.
+type historyCompaction struct {
+	compacted []map[string]interface{}
+	offset    int
+	active    bool
+}
+
+// view returns the history to send: the compacted history followed by the
+// messages appended since, or history itself if it was never compacted.
+func (c *historyCompaction) view(history []map[string]interface{}) []map[string]interface{} {
+	if !c.active {
+		return history
+	}
+	view := make([]map[string]interface{}, 0, len(c.compacted)+len(history)-c.offset)
+	return append(append(view, c.compacted...), history[c.offset:]...)
+}
+
+// history returns the compacted history of the run, or nil if it was never
+// compacted.
+func (c *historyCompaction) history(history []map[string]interface{}) []map[string]interface{} {
+	if !c.active {
+		return nil
+	}
+	return c.view(history)
+}
+
+// compactHistory returns the history to send for agent, compacting it first
+// if it exceeds the agent's threshold.
+func (s *Swarm) compactHistory(ctx context.Context, c *historyCompaction, agent *Agent, history []map[string]interface{}, model string) ([]map[string]interface{}, error) {
+	view := c.view(history)
+	config := agent.Compaction
+	if config == nil || config.Strategy == nil {
+		return view, nil
+	}
+	threshold, target := config.limits(agent, model)
+	if threshold <= 0 {
+		return view, nil
+	}
+	tokens, err := CountMessageTokens(view, model)
+	if err != nil {
+		return nil, err
+	}
+	if tokens <= threshold {
+		return view, nil
+	}
+	compacted, err := config.Strategy(ctx, s, view, model, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact history: %w", err)
+	}
+	c.compacted, c.offset, c.active = compacted, len(history), true
+	return c.view(history), nil
+}
+
+// messageGroup is a range of messages kept or removed together: an
+// assistant message with the tool results following it, or a single message.
+type messageGroup struct {
+	start, end int
+	tokens     int
+}
+
+// messageGroups splits history into groups, returning the system message
+// indexes apart from the other groups.
+func messageGroups(history []map[string]interface{}, model string) (system []int, groups []messageGroup, err error) {
+	tokenizer := TokenizerForModel(model)
+	for i, msg := range history {
+		tokens, err := messageTokens(tokenizer, msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch role, _ := msg["role"].(string); {
+		case role == "system":
+			system = append(system, i)
+		case role == "tool" && len(groups) > 0 && groups[len(groups)-1].end == i:
+			groups[len(groups)-1].end++
+			groups[len(groups)-1].tokens += tokens
+		default:
+			groups = append(groups, messageGroup{start: i, end: i + 1, tokens: tokens})
+		}
+	}
+	return system, groups, nil
+}
+
+// keepGroups returns the system messages of history followed by the kept
+// groups, dropping tool results left at the start without their call.
+func keepGroups(history []map[string]interface{}, system []int, groups []messageGroup) []map[string]interface{} {
+	kept := make([]map[string]interface{}, 0, len(history))
+	for _, i := range system {
+		kept = append(kept, history[i])
+	}
+	for _, group := range groups {
+		for i := group.start; i < group.end; i++ {
+			if len(kept) == len(system) && history[i]["role"] == "tool" {
+				continue
+			}
+			kept = append(kept, history[i])
+		}
+	}
+	return kept
+}
+
+// DropOldestCompactor drops the oldest messages until the history fits the
+// target, like TrimToBudget. System messages and the latest message are kept
+// even if they do not fit.
+func DropOldestCompactor() Compactor {
+	return func(_ context.Context, _ *Swarm, history []map[string]interface{}, model string, target int) ([]map[string]interface{}, error) {
+		kept, err := TrimToBudget(history, model, target)
+		if err != nil && !errors.Is(err, ErrTokenBudgetExceeded) {
+			return nil, err
+		}
+		return kept, nil
+	}
+}
+
+// KeepLastCompactor keeps the system messages and the last n other messages,
+// regardless of the target. The call of a kept tool result is kept with it.
+func KeepLastCompactor(n int) Compactor {
+	return func(_ context.Context, _ *Swarm, history []map[string]interface{}, model string, _ int) ([]map[string]interface{}, error) {
+		system, groups, err := messageGroups(history, model)
+		if err != nil {
+			return nil, err
+		}
+		first, count := len(groups), 0
+		for first > 0 && (count < n || first == len(groups)) {
+			first--
+			count += groups[first].end - groups[first].start
+		}
+		return keepGroups(history, system, groups[first:]), nil
+	}
+}
+
+// SummarizeOldestCompactor replaces the oldest messages with a summary
+// written by summarizer (an agent using the compacted agent's model if nil),
+// keeping the latest messages that fit in three quarters of the target. The
+// summary is a user message marked with SummaryName, placed after the system
+// messages, and is itself summarized by later compactions.
+func SummarizeOldestCompactor(summarizer *Agent) Compactor {
+	return func(ctx context.Context, s *Swarm, history []map[string]interface{}, model string, target int) ([]map[string]interface{}, error) {
+		system, groups, err := messageGroups(history, model)
+		if err != nil {
+			return nil, err
+		}
+		first, tokens := len(groups)-1, 0
+		for ; first >= 0; first-- {
+			tokens += groups[first].tokens
+			if tokens > target*3/4 && first < len(groups)-1 {
+				first++
+				break
+			}
+		}
+		if first <= 0 {
+			return history, nil
+		}
+
+		var transcript strings.Builder
+		for _, group := range groups[:first] {
+			writeTranscript(&transcript, history[group.start:group.end])
+		}
+		agent := summarizer
+		if agent == nil {
+			agent = NewAgent("summarizer").WithModel(model).
+				WithInstructions("You summarize conversations for the assistant that continues them.")
+		}
+		response, err := s.Run(ctx, agent, []map[string]interface{}{{
+			"role": "user",
+			"content": fmt.Sprintf("Summarize the conversation below in at most %d words. Keep the facts, decisions, "+
+				"open questions and tool results needed to continue it.\n\n%s", max(50, target/8), transcript.String()),
+		}}, nil, "", false, false, 1, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize history: %w", err)
+		}
+
+		kept := keepGroups(history, system, groups[first:])
+		summary := map[string]interface{}{
+			"role":      "user",
+			"content":   "Summary of the earlier conversation:\n" + lastContent(response),
+			SummaryName: true,
+		}
+		compacted := append(kept[:len(system):len(system)], summary)
+		return append(compacted, kept[len(system):]...), nil
+	}
+}
+
+// writeTranscript writes messages as lines of "role: content", with tool
+// calls as "role called name(arguments)".
+func writeTranscript(b *strings.Builder, messages []map[string]interface{}) {
+	for _, msg := range messages {
+		role, _ := msg["role"].(string)
+		if content, _ := msg["content"].(string); content != "" {
+			fmt.Fprintf(b, "%s: %s\n", role, content)
+		}
+		switch toolCalls := msg["tool_calls"].(type) {
+		case []openai.ChatCompletionMessageToolCall:
+			for _, tc := range toolCalls {
+				fmt.Fprintf(b, "%s called %s(%s)\n", role, tc.Function.Name, tc.Function.Arguments)
+			}
+		case []map[string]interface{}:
+			for _, tc := range toolCalls {
+				if fn, ok := tc["function"].(map[string]interface{}); ok {
+					fmt.Fprintf(b, "%s called %v(%v)\n", role, fn["name"], fn["arguments"])
+				}
+			}
+		}
+	}
+}
+
+// ImportanceScorer rates how important it is to keep the message at index
+// of a history of count messages. Higher scores are kept longer.
+type ImportanceScorer func(msg map[string]interface{}, index, count int) float64
+
+// DefaultImportance favors recent messages, user messages over assistant
+// replies over tool results, and summaries above all.
+func DefaultImportance(msg map[string]interface{}, index, count int) float64 {
+	score := float64(index+1) / float64(count)
+	switch msg["role"] {
+	case "user":
+		score++
+	case "tool":
+		score += 0.25
+	default:
+		score += 0.5
+	}
+	if summary, _ := msg[SummaryName].(bool); summary {
+		score += 2
+	}
+	return score
+}
+
+// ImportanceCompactor drops the least important messages, as rated by score
+// (DefaultImportance if nil), until the history fits the target. System
+// messages and the latest message are always kept, and an assistant message
+// and its tool results are rated and dropped together by their best score.
+func ImportanceCompactor(score ImportanceScorer) Compactor {
+	if score == nil {
+		score = DefaultImportance
+	}
+	return func(_ context.Context, _ *Swarm, history []map[string]interface{}, model string, target int) ([]map[string]interface{}, error) {
+		system, groups, err := messageGroups(history, model)
+		if err != nil || len(groups) == 0 {
+			return history, err
+		}
+		total := tokensPerReply
+		scores := make([]float64, len(groups))
+		for g, group := range groups {
+			total += group.tokens
+			for i := group.start; i < group.end; i++ {
+				if s := score(history[i], i, len(history)); i == group.start || s > scores[g] {
+					scores[g] = s
+				}
+			}
+		}
+		for _, i := range system {
+			tokens, err := messageTokens(TokenizerForModel(model), history[i])
+			if err != nil {
+				return nil, err
+			}
+			total += tokens
+		}
+
+		order := make([]int, len(groups)-1)
+		for g := range order {
+			order[g] = g
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+		dropped := make(map[int]bool)
+		for _, g := range order {
+			if total <= target {
+				break
+			}
+			dropped[g] = true
+			total -= groups[g].tokens
+		}
+
+		kept := make([]messageGroup, 0, len(groups)-len(dropped))
+		for g, group := range groups {
+			if !dropped[g] {
+				kept = append(kept, group)
+			}
+		}
+		return keepGroups(history, system, kept), nil
+	}
+}
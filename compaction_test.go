@@ -0,0 +1,114 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// compactionHistory returns a conversation of long messages with a tool call
+// in the middle.
+func compactionHistory() []map[string]interface{} {
+	long := strings.Repeat("word ", 100)
+	return []map[string]interface{}{
+		{"role": "system", "content": "rules"},
+		{"role": "user", "content": "first " + long},
+		{"role": "assistant", "content": "answer " + long},
+		{"role": "assistant", "content": "", "tool_calls": []map[string]interface{}{
+			{"function": map[string]interface{}{"name": "lookup", "arguments": "{}"}},
+		}},
+		{"role": "tool", "content": "result " + long},
+		{"role": "user", "content": "second " + long},
+		{"role": "assistant", "content": "latest answer"},
+	}
+}
+
+// contents returns the first word of the content of each message.
+func contents(history []map[string]interface{}) string {
+	words := make([]string, len(history))
+	for i, msg := range history {
+		content, _ := msg["content"].(string)
+		if fields := strings.Fields(content); len(fields) > 0 {
+			words[i] = fields[0]
+		}
+	}
+	return strings.Join(words, ",")
+}
+
+func TestCompactors(t *testing.T) {
+	history := compactionHistory()
+
+	kept, err := DropOldestCompactor()(context.Background(), nil, history, "gpt-4o", 250)
+	AssertNoError(t, err, "drop oldest")
+	AssertEqual(t, "rules,second,latest", contents(kept), "drop oldest")
+
+	kept, err = KeepLastCompactor(3)(context.Background(), nil, history, "gpt-4o", 0)
+	AssertNoError(t, err, "keep last")
+	AssertEqual(t, "rules,,result,second,latest", contents(kept), "tool call kept with its result")
+
+	kept, err = ImportanceCompactor(nil)(context.Background(), nil, history, "gpt-4o", 250)
+	AssertNoError(t, err, "importance")
+	AssertEqual(t, "rules,second,latest", contents(kept), "tool results and replies dropped first")
+
+	kept, err = ImportanceCompactor(nil)(context.Background(), nil, history, "gpt-4o", 350)
+	AssertNoError(t, err, "importance")
+	AssertEqual(t, "rules,first,second,latest", contents(kept), "user messages kept longer")
+
+	kept, err = ImportanceCompactor(func(msg map[string]interface{}, index, count int) float64 {
+		if msg["role"] == "tool" {
+			return 10
+		}
+		return float64(index)
+	})(context.Background(), nil, history, "gpt-4o", 200)
+	AssertNoError(t, err, "custom importance")
+	AssertEqual(t, "rules,,result,latest", contents(kept), "custom scores")
+}
+
+func TestSessionCompaction(t *testing.T) {
+	client := newScriptedClient("the story so far", "reply")
+	agent := NewAgent("agent").WithCompaction(SummarizeOldestCompactor(nil), 400)
+	session := NewSwarm(client).NewSession(agent, "")
+	session.Messages = compactionHistory()
+	session.ForkedAt = 3
+
+	response, err := session.Send(context.Background(), "next question")
+	AssertNoError(t, err, "Send")
+	AssertEqual(t, 2, len(client.requests), "summary and reply requests")
+	summaryPrompt := client.requests[0].Messages[1].OfUser.Content.OfString.Value
+	if !strings.Contains(summaryPrompt, "user: first word") || !strings.Contains(summaryPrompt, "assistant called lookup({})") {
+		t.Errorf("Expected the oldest messages in the summary prompt, got %q", summaryPrompt)
+	}
+
+	AssertEqual(t, "rules,Summary,second,latest,next,reply", contents(session.Messages), "compacted session history")
+	AssertEqual(t, true, session.Messages[1][SummaryName], "summary marked")
+	AssertEqual(t, len(session.Messages), len(response.History), "response history")
+	AssertEqual(t, 0, session.ForkedAt, "fork point reset")
+	sent := client.requests[1].Messages
+	AssertEqual(t, 5, len(sent), "instructions and compacted history sent")
+	AssertEqual(t, "Summary of the earlier conversation:\nthe story so far", sent[1].OfUser.Content.OfString.Value, "summary sent")
+
+	// The compacted history is below the threshold, so it is not compacted again
+	client.SetCompletionResponse(client.CompletionResponse[1])
+	response, err = session.Send(context.Background(), "thanks")
+	AssertNoError(t, err, "second Send")
+	AssertEqual(t, 3, len(client.requests), "no new summary")
+	if response.History != nil {
+		t.Error("Expected no history for a run without compaction")
+	}
+	AssertEqual(t, "rules,Summary,second,latest,next,reply,thanks,reply", contents(session.Messages), "session history")
+}
+
+func TestCompactionThenTrim(t *testing.T) {
+	client := newScriptedClient("reply")
+	keepAll := func(_ context.Context, _ *Swarm, history []map[string]interface{}, _ string, _ int) ([]map[string]interface{}, error) {
+		return history, nil
+	}
+	agent := NewAgent("agent").WithCompaction(keepAll, 100)
+	agent.ContextWindow, agent.MaxTokens = 300, 50
+
+	response, err := NewSwarm(client).Run(context.Background(), agent, compactionHistory()[1:], nil, "", false, false, 1, false, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, 7, len(response.History), "compacted history keeps every message")
+	// The instructions, then the messages of the compacted history that fit
+	AssertEqual(t, 3, len(client.requests[0].Messages), "request trimmed to the context window")
+}
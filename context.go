@@ -2,34 +2,147 @@ package swarm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
+// ErrEventVetoed is returned by SendEvent when an interceptor rejects an event.
+var ErrEventVetoed = errors.New("event vetoed")
+
+// EventInterceptor is invoked for every event sent through a Context before it
+// is dispatched. It may enrich or replace the event by returning a different one,
+// or veto dispatch by returning an error. Returning a nil event with a nil error
+// keeps the event unchanged.
+type EventInterceptor func(ctx *Context, event Event) (Event, error)
+
 // Context represents a workflow execution context that manages state and event flow.
 // It wraps a standard context.Context and provides additional functionality for
 // event handling, state management, and workflow control.
 //
 // The Context is safe for concurrent use by multiple goroutines.
 type Context struct {
+	runID     string
 	ctx       context.Context
 	cancel    context.CancelFunc
 	eventChan chan Event
-	streamCh  chan Event
+	bus       *EventBus
+	streamSub *Subscription
+	hooks     []EventInterceptor
 	state     map[string]interface{}
+	parent    *Context
+	deleted   map[string]bool
+	watchers  map[string][]*stateWatcher
+	nextWatch uint64
+	recorder  *runRecorder
 	mu        sync.RWMutex
 }
 
+// StateChange describes a modification of a Context state key.
+type StateChange struct {
+	// Key is the state key that changed
+	Key string
+	// Value is the new value (nil when deleted)
+	Value interface{}
+	// Previous is the value before the change, if any
+	Previous interface{}
+	// Deleted reports whether the key was removed
+	Deleted bool
+}
+
+// stateWatcher is a registered Watch channel.
+type stateWatcher struct {
+	id uint64
+	ch chan StateChange
+}
+
+// WatchAllKeys can be passed to Watch to observe changes to every key.
+const WatchAllKeys = "*"
+
+// DefaultEventBuffer is the default buffer size of the engine event channel.
+const DefaultEventBuffer = 100
+
+// contextOptions holds the settings applied by ContextOption functions.
+type contextOptions struct {
+	eventBuffer  int
+	streamBuffer int
+	overflow     OverflowPolicy
+	runID        string
+}
+
+// ContextOption configures a Context created by NewContext.
+type ContextOption func(*contextOptions)
+
+// WithEventBuffer sets the buffer size of the engine event channel.
+// Sends to this channel always block when it is full so that the workflow
+// engine never loses events.
+func WithEventBuffer(size int) ContextOption {
+	return func(o *contextOptions) {
+		if size >= 0 {
+			o.eventBuffer = size
+		}
+	}
+}
+
+// WithStreamBuffer sets the default buffer size of stream subscriptions.
+func WithStreamBuffer(size int) ContextOption {
+	return func(o *contextOptions) {
+		if size >= 0 {
+			o.streamBuffer = size
+		}
+	}
+}
+
+// WithOverflowPolicy sets the default overflow policy of stream subscriptions.
+func WithOverflowPolicy(policy OverflowPolicy) ContextOption {
+	return func(o *contextOptions) {
+		if policy != "" {
+			o.overflow = policy
+		}
+	}
+}
+
+// WithRunID sets the run ID of the Context instead of generating one.
+func WithRunID(id string) ContextOption {
+	return func(o *contextOptions) {
+		if id != "" {
+			o.runID = id
+		}
+	}
+}
+
 // NewContext creates a new workflow Context with the provided parent context.
-// It initializes event channels with a buffer size of 100 and creates an empty state map.
-func NewContext(ctx context.Context) *Context {
-	ctx, cancel := context.WithCancel(ctx)
+// By default it initializes the event channel with a buffer size of 100, an
+// event bus whose subscriptions block when full, and an empty state map.
+func NewContext(ctx context.Context, opts ...ContextOption) *Context {
+	options := contextOptions{
+		eventBuffer:  DefaultEventBuffer,
+		streamBuffer: DefaultSubscriptionBuffer,
+		overflow:     OverflowBlock,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.runID == "" {
+		options.runID = NewID("run-")
+	}
+
+	recorder := newRunRecorder()
+	ctx, cancel := context.WithCancel(WithUsageTracker(ctx, recorder.usage))
+	bus := NewEventBusWithPolicy(options.streamBuffer, options.overflow)
 	return &Context{
+		runID:     options.runID,
 		ctx:       ctx,
 		cancel:    cancel,
-		eventChan: make(chan Event, 100), // Buffer size of 100
-		streamCh:  make(chan Event, 100), // Buffer size of 100
+		eventChan: make(chan Event, options.eventBuffer),
+		bus:       bus,
+		// The stream queues every event from the start of the run, without
+		// holding up steps when its reader is slow or absent
+		streamSub: bus.SubscribeWith(nil, options.streamBuffer, OverflowQueue),
 		state:     make(map[string]interface{}),
+		recorder:  recorder,
 	}
 }
 
@@ -38,6 +151,13 @@ func (c *Context) Context() context.Context {
 	return c.ctx
 }
 
+// RunID returns the identifier stamped on every event sent through this Context.
+func (c *Context) RunID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.runID
+}
+
 // Cancel cancels the Context and all operations using it.
 // After calling Cancel, all event channels will be closed and subsequent operations
 // will return context.Canceled error.
@@ -47,7 +167,7 @@ func (c *Context) Cancel() {
 
 // SendEvent sends an event to the workflow's event channel.
 // It validates the event before sending and returns an error if the event is nil
-// or invalid. The event is also sent to the stream channel if there are listeners.
+// or invalid. The event is also published to every matching bus subscriber.
 //
 // Returns an error if the context is canceled or if the event is invalid.
 func (c *Context) SendEvent(event Event) error {
@@ -55,36 +175,149 @@ func (c *Context) SendEvent(event Event) error {
 		return fmt.Errorf("event cannot be nil")
 	}
 
+	// Stamp tracing metadata
+	c.stamp(event)
+
+	// Run interceptors
+	event, err := c.intercept(event)
+	if err != nil {
+		return err
+	}
+
 	// Validate event
 	if err := event.Validate(); err != nil {
 		return fmt.Errorf("invalid event: %w", err)
 	}
+	if err := validateEventSchema(event); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
 
+	// Deliver to external subscribers first, so they have received every
+	// event the engine handled when the run ends and the bus is closed
+	published := c.bus.Publish(c.ctx, event)
+	if published != nil && !errors.Is(published, ErrEventOverflow) {
+		return published
+	}
 	select {
 	case <-c.ctx.Done():
 		return c.ctx.Err()
 	case c.eventChan <- event:
-		// Also send to stream channel if anyone is listening
-		select {
-		case c.streamCh <- event:
-		default:
-			// Stream channel is full or no one is listening, skip
+		return published
+	}
+}
+
+// SendAfter schedules event to be dispatched by the workflow engine after d.
+// The timer is owned by the engine, so steps can schedule follow-ups such as
+// polling or reminders without spawning goroutines of their own.
+func (c *Context) SendAfter(d time.Duration, event Event) error {
+	if event == nil {
+		return fmt.Errorf("event cannot be nil")
+	}
+	return c.SendEvent(NewDelayEvent(d, event))
+}
+
+// ReportProgress publishes a ProgressEvent for the named step to stream
+// subscribers so UIs can render progress for long-running steps. Progress events
+// bypass the engine event channel and never trigger steps.
+func (c *Context) ReportProgress(stepName string, percent float64, message string) error {
+	return c.publish(NewProgressEvent(stepName, percent, message))
+}
+
+// publish delivers an event to stream subscribers without routing it to steps.
+func (c *Context) publish(event Event) error {
+	c.stamp(event)
+
+	event, err := c.intercept(event)
+	if err != nil {
+		return err
+	}
+	if err := event.Validate(); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
+	return c.bus.Publish(c.ctx, event)
+}
+
+// Use appends interceptors to the Context's hook chain.
+// Interceptors run in registration order for every subsequent SendEvent call.
+func (c *Context) Use(interceptors ...EventInterceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, i := range interceptors {
+		if i != nil {
+			c.hooks = append(c.hooks, i)
 		}
-		return nil
 	}
 }
 
+// stamp fills in missing RunID, EventID and Timestamp metadata.
+func (c *Context) stamp(event Event) {
+	carrier, ok := event.(MetadataCarrier)
+	if !ok {
+		return
+	}
+	meta := carrier.Metadata()
+	if meta.RunID == "" {
+		meta.RunID = c.RunID()
+	}
+	if meta.EventID == "" {
+		meta.EventID = NewID("evt-")
+	}
+	if meta.Timestamp.IsZero() {
+		meta.Timestamp = time.Now()
+	}
+	carrier.SetMetadata(meta)
+}
+
+// intercept runs the hook chain over the event.
+func (c *Context) intercept(event Event) (Event, error) {
+	c.mu.RLock()
+	hooks := c.hooks
+	c.mu.RUnlock()
+
+	for _, hook := range hooks {
+		next, err := hook(c, event)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrEventVetoed, event.Type(), err)
+		}
+		if next != nil {
+			event = next
+		}
+	}
+	return event, nil
+}
+
 // Events returns a receive-only channel for consuming workflow events.
-// The channel has a buffer size of 100 events.
+// The channel has a buffer size of 100 events unless WithEventBuffer is used.
 func (c *Context) Events() <-chan Event {
 	return c.eventChan
 }
 
 // Stream returns a receive-only channel for streaming workflow events.
-// Unlike Events(), this channel is intended for real-time monitoring and may drop
-// events if no receiver is ready.
+// Unlike Events(), this channel is intended for real-time monitoring. It is backed
+// by a single wildcard bus subscription created with the Context, so every call
+// returns the same channel. The subscription uses OverflowQueue, so it receives
+// every event of the run, including those sent before the first call, and a slow
+// reader never holds up the engine. The channel is closed once the run has ended
+// and every event has been received. Use Subscribe for independent consumers.
 func (c *Context) Stream() <-chan Event {
-	return c.streamCh
+	return c.streamSub.Events()
+}
+
+// Subscribe registers a new bus subscriber for the given event type patterns.
+// Each subscription receives its own copy of every matching event.
+func (c *Context) Subscribe(types ...EventType) *Subscription {
+	return c.bus.Subscribe(types...)
+}
+
+// Bus returns the event bus used to fan out events to subscribers.
+func (c *Context) Bus() *EventBus {
+	return c.bus
+}
+
+// closeStreams closes the event bus, terminating all subscriber channels and
+// releasing publishers blocked on subscribers that stopped reading.
+func (c *Context) closeStreams() {
+	c.bus.Close()
 }
 
 // Set stores a key-value pair in the Context's state map.
@@ -92,16 +325,102 @@ func (c *Context) Stream() <-chan Event {
 func (c *Context) Set(key string, value interface{}) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	previous := c.state[key]
 	c.state[key] = value
+	delete(c.deleted, key)
+	c.notify(StateChange{Key: key, Value: value, Previous: previous})
+}
+
+// Watch returns a channel that receives a StateChange whenever key is set or
+// deleted, so steps can react to shared-state changes without polling. Pass
+// WatchAllKeys to observe every key. Notifications never block writers: when
+// the watcher falls behind, the oldest pending change is discarded.
+//
+// The channel is closed when stop is called or the Context is cancelled.
+func (c *Context) Watch(key string) (changes <-chan StateChange, stop func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.watchers == nil {
+		c.watchers = make(map[string][]*stateWatcher)
+	}
+	c.nextWatch++
+	w := &stateWatcher{id: c.nextWatch, ch: make(chan StateChange, 16)}
+	c.watchers[key] = append(c.watchers[key], w)
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.unwatch(key, w)
+		})
+	}
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			stop()
+		case <-done:
+		}
+	}()
+
+	return w.ch, stop
+}
+
+// unwatch removes a watcher and closes its channel. Callers must hold c.mu.
+func (c *Context) unwatch(key string, w *stateWatcher) {
+	watchers := c.watchers[key]
+	for i, existing := range watchers {
+		if existing.id == w.id {
+			c.watchers[key] = append(watchers[:i:i], watchers[i+1:]...)
+			close(w.ch)
+			break
+		}
+	}
+	if len(c.watchers[key]) == 0 {
+		delete(c.watchers, key)
+	}
+}
+
+// notify delivers a change to matching watchers. Callers must hold c.mu.
+func (c *Context) notify(change StateChange) {
+	if len(c.watchers) == 0 {
+		return
+	}
+	for _, key := range []string{change.Key, WatchAllKeys} {
+		for _, w := range c.watchers[key] {
+			for {
+				select {
+				case w.ch <- change:
+				default:
+					// Drop the oldest change to make room for the latest
+					select {
+					case <-w.ch:
+					default:
+					}
+					continue
+				}
+				break
+			}
+		}
+	}
 }
 
 // Get retrieves a value from the Context's state map.
 // Returns the value and a boolean indicating whether the key was found.
 func (c *Context) Get(key string) (interface{}, bool) {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
 	value, ok := c.state[key]
-	return value, ok
+	hidden := c.deleted[key]
+	parent := c.parent
+	c.mu.RUnlock()
+
+	if ok || hidden || parent == nil {
+		return value, ok
+	}
+	return parent.Get(key)
 }
 
 // GetString retrieves a string value from the Context's state map.
@@ -155,26 +474,50 @@ func (c *Context) GetMap(key string) (map[string]interface{}, bool) {
 // Delete removes a key and its associated value from the Context's state map.
 // If the key doesn't exist, the operation is a no-op.
 func (c *Context) Delete(key string) {
+	var inherited bool
+	if c.parent != nil {
+		inherited = c.parent.Has(key)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	previous, ok := c.state[key]
 	delete(c.state, key)
+	if inherited {
+		// Hide the parent's value until Merge
+		c.deleted[key] = true
+	}
+	if ok || inherited {
+		c.notify(StateChange{Key: key, Previous: previous, Deleted: true})
+	}
 }
 
 // Clear removes all key-value pairs from the Context's state map.
 // This operation is atomic and thread-safe.
 func (c *Context) Clear() {
+	var inherited map[string]interface{}
+	if c.parent != nil {
+		inherited = c.parent.Clone()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	previous := c.state
 	c.state = make(map[string]interface{})
+	for key := range inherited {
+		c.deleted[key] = true
+	}
+	for key, value := range previous {
+		c.notify(StateChange{Key: key, Previous: value, Deleted: true})
+	}
 }
 
 // Keys returns a slice containing all keys present in the Context's state map.
 // The order of keys in the returned slice is not guaranteed to be stable.
 func (c *Context) Keys() []string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	keys := make([]string, 0, len(c.state))
-	for k := range c.state {
+	state := c.Clone()
+	keys := make([]string, 0, len(state))
+	for k := range state {
 		keys = append(keys, k)
 	}
 	return keys
@@ -182,28 +525,89 @@ func (c *Context) Keys() []string {
 
 // Len returns the number of key-value pairs in the Context's state map.
 func (c *Context) Len() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return len(c.state)
+	return len(c.Clone())
 }
 
 // Has checks if a key exists in the Context's state map.
 // Returns true if the key exists, false otherwise.
 func (c *Context) Has(key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	_, ok := c.state[key]
+	_, ok := c.Get(key)
 	return ok
 }
 
 // Clone creates and returns a deep copy of the Context's state map.
 // The returned map is independent of the Context and can be safely modified.
+// For child contexts the copy includes values inherited from the parent.
 func (c *Context) Clone() map[string]interface{} {
+	var clone map[string]interface{}
+	if c.parent != nil {
+		clone = c.parent.Clone()
+	} else {
+		clone = make(map[string]interface{})
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	clone := make(map[string]interface{}, len(c.state))
+	for k := range c.deleted {
+		delete(clone, k)
+	}
 	for k, v := range c.state {
 		clone[k] = v
 	}
 	return clone
 }
+
+// Child creates a scoped Context for a sub-flow or parallel task. Reads fall
+// through to the parent, while writes and deletes stay local to the child until
+// Merge is called. The child shares the parent's event channel, bus and
+// interceptors, and is cancelled together with the parent.
+func (c *Context) Child() *Context {
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	c.mu.RLock()
+	runID := c.runID
+	hooks := make([]EventInterceptor, len(c.hooks))
+	copy(hooks, c.hooks)
+	c.mu.RUnlock()
+
+	return &Context{
+		runID:     runID,
+		ctx:       ctx,
+		cancel:    cancel,
+		eventChan: c.eventChan,
+		bus:       c.bus,
+		streamSub: c.streamSub,
+		hooks:     hooks,
+		state:     make(map[string]interface{}),
+		parent:    c,
+		deleted:   make(map[string]bool),
+		recorder:  c.recorder,
+	}
+}
+
+// Parent returns the parent Context, or nil for a root Context.
+func (c *Context) Parent() *Context {
+	return c.parent
+}
+
+// Merge applies the child's local writes and deletes to its parent and resets
+// the child's local state. It is a no-op for a root Context.
+func (c *Context) Merge() {
+	if c.parent == nil {
+		return
+	}
+
+	c.mu.Lock()
+	state := c.state
+	deleted := c.deleted
+	c.state = make(map[string]interface{})
+	c.deleted = make(map[string]bool)
+	c.mu.Unlock()
+
+	for k := range deleted {
+		c.parent.Delete(k)
+	}
+	for k, v := range state {
+		c.parent.Set(k, v)
+	}
+}
@@ -0,0 +1,288 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestContextInterceptors(t *testing.T) {
+	ctx := NewContext(context.Background())
+
+	var seen []EventType
+	ctx.Use(
+		func(ctx *Context, event Event) (Event, error) {
+			seen = append(seen, event.Type())
+			event.Data()["audited"] = true
+			return nil, nil
+		},
+		func(ctx *Context, event Event) (Event, error) {
+			if event.Type() == EventType("Forbidden") {
+				return nil, fmt.Errorf("not allowed")
+			}
+			return event, nil
+		},
+	)
+
+	AssertNoError(t, ctx.SendEvent(NewBaseEvent(EventType("Allowed"), nil)), "SendEvent allowed")
+	event := <-ctx.Events()
+	AssertEqual(t, true, event.Data()["audited"], "interceptor should enrich event data")
+
+	err := ctx.SendEvent(NewBaseEvent(EventType("Forbidden"), nil))
+	if !errors.Is(err, ErrEventVetoed) {
+		t.Errorf("Expected ErrEventVetoed, got %v", err)
+	}
+	AssertEqual(t, 2, len(seen), "interceptor should see every event")
+	AssertEqual(t, 0, len(ctx.Events()), "vetoed event should not be dispatched")
+}
+
+func TestEventMetadataPropagation(t *testing.T) {
+	var mu sync.Mutex
+	byType := make(map[EventType]EventMetadata)
+
+	workflow := NewWorkflow("metadata")
+	workflow.Use(func(ctx *Context, event Event) (Event, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		byType[event.Type()] = event.(MetadataCarrier).Metadata()
+		return nil, nil
+	})
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		return NewBaseEvent(EventType("Next"), nil), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("next", EventType("Next"), func(ctx *Context, event Event) (Event, error) {
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if _, err := handler.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	start, next, stop := byType[EventStart], byType[EventType("Next")], byType[EventStop]
+	for _, meta := range []EventMetadata{start, next, stop} {
+		AssertEqual(t, handler.Context().RunID(), meta.RunID, "run ID")
+		if meta.EventID == "" || meta.Timestamp.IsZero() {
+			t.Errorf("Expected event ID and timestamp, got %+v", meta)
+		}
+	}
+	AssertEqual(t, start.EventID, next.CausationID, "Next should be caused by start")
+	AssertEqual(t, next.EventID, stop.CausationID, "Stop should be caused by Next")
+}
+
+func TestContextSnapshotRestore(t *testing.T) {
+	type budget struct {
+		Limit int    `json:"limit"`
+		Owner string `json:"owner"`
+	}
+	RegisterStateType[budget]("test.budget")
+
+	ctx := NewContext(context.Background())
+	ctx.Set("topic", "ai")
+	ctx.Set("count", 3)
+	ctx.Set("chapters", []string{"One", "Two"})
+	ctx.Set("budget", budget{Limit: 10, Owner: "ops"})
+	ctx.Set("empty", nil)
+
+	data, err := ctx.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	restored, err := RestoreContext(context.Background(), data)
+	if err != nil {
+		t.Fatalf("RestoreContext failed: %v", err)
+	}
+
+	AssertEqual(t, ctx.RunID(), restored.RunID(), "run ID")
+	if count, ok := restored.GetInt("count"); !ok || count != 3 {
+		t.Errorf("Expected int count 3, got %v", count)
+	}
+	if chapters, _ := restored.Get("chapters"); len(chapters.([]string)) != 2 {
+		t.Errorf("Expected []string chapters, got %v", chapters)
+	}
+	if b, _ := restored.Get("budget"); b.(budget).Owner != "ops" {
+		t.Errorf("Expected custom budget type, got %#v", b)
+	}
+	AssertEqual(t, true, restored.Has("empty"), "nil values preserved")
+
+	ctx.Set("channel", make(chan int))
+	if _, err := ctx.Snapshot(); err == nil {
+		t.Error("Expected error for unregistered type")
+	}
+}
+
+func TestContextLoadSnapshotConcurrentRunID(t *testing.T) {
+	source := NewContext(context.Background())
+	data, err := source.Snapshot()
+	AssertNoError(t, err, "Snapshot")
+
+	ctx := NewContext(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Stay within the event buffer, which nothing drains
+		for i := 0; i < 50; i++ {
+			ctx.SendEvent(NewBaseEvent(EventType("Tick"), nil))
+			ctx.RunID()
+		}
+	}()
+	AssertNoError(t, ctx.LoadSnapshot(data), "LoadSnapshot")
+	wg.Wait()
+	AssertEqual(t, source.RunID(), ctx.RunID(), "restored run ID")
+}
+
+func TestContextLoadSnapshotNotifiesWatchers(t *testing.T) {
+	source := NewContext(context.Background())
+	source.Set("keep", "v")
+	source.Set("change", "new")
+	source.Set("add", "v")
+	data, err := source.Snapshot()
+	AssertNoError(t, err, "Snapshot")
+
+	ctx := NewContext(context.Background())
+	ctx.Set("keep", "v")
+	ctx.Set("change", "old")
+	ctx.Set("drop", "v")
+	all, stop := ctx.Watch(WatchAllKeys)
+	defer stop()
+	AssertNoError(t, ctx.LoadSnapshot(data), "LoadSnapshot")
+
+	changes := make(map[string]StateChange)
+	for len(all) > 0 {
+		change := <-all
+		changes[change.Key] = change
+	}
+	AssertEqual(t, 3, len(changes), "changed keys only")
+	AssertEqual(t, "old", changes["change"].Previous, "previous value")
+	AssertEqual(t, "new", changes["change"].Value, "new value")
+	AssertEqual(t, "v", changes["add"].Value, "added value")
+	AssertEqual(t, true, changes["drop"].Deleted, "removed key")
+}
+
+func TestContextLoadSnapshotResetsDeletes(t *testing.T) {
+	parent := NewContext(context.Background())
+	parent.Set("a", "parent")
+	parent.Set("b", "parent")
+	source := NewContext(context.Background())
+	source.Set("a", "snapshot")
+	data, err := source.Snapshot()
+	AssertNoError(t, err, "Snapshot")
+
+	child := parent.Child()
+	child.Delete("a")
+	AssertNoError(t, child.LoadSnapshot(data), "LoadSnapshot")
+	_, ok := child.Get("b")
+	AssertEqual(t, false, ok, "inherited key missing from the snapshot is hidden")
+
+	changes, stop := parent.Watch("a")
+	defer stop()
+	child.Merge()
+	AssertEqual(t, 1, len(changes), "no stale delete merged")
+	value, _ := parent.Get("a")
+	AssertEqual(t, "snapshot", value, "merged value")
+	_, ok = parent.Get("b")
+	AssertEqual(t, false, ok, "hidden key deleted on merge")
+}
+
+func TestContextWatch(t *testing.T) {
+	ctx := NewContext(context.Background())
+	budget, stop := ctx.Watch("budget")
+	all, stopAll := ctx.Watch(WatchAllKeys)
+	defer stopAll()
+
+	ctx.Set("budget", 10)
+	ctx.Set("other", true)
+	ctx.Delete("budget")
+
+	change := <-budget
+	AssertEqual(t, 10, change.Value, "set value")
+	change = <-budget
+	AssertEqual(t, true, change.Deleted, "delete notification")
+	AssertEqual(t, 10, change.Previous, "previous value")
+	AssertEqual(t, 3, len(all), "wildcard watcher sees every change")
+
+	stop()
+	if _, ok := <-budget; ok {
+		t.Error("Expected watch channel to be closed after stop")
+	}
+
+	ctx.Cancel()
+	for range all {
+	}
+}
+
+func TestContextChildMerge(t *testing.T) {
+	parent := NewContext(context.Background())
+	parent.Set("topic", "ai")
+	parent.Set("draft", "v1")
+
+	child := parent.Child()
+	defer child.Cancel()
+	child.Set("draft", "v2")
+	child.Set("score", 9)
+	child.Delete("topic")
+
+	AssertEqual(t, false, child.Has("topic"), "child delete hides parent key")
+	AssertEqual(t, 2, child.Len(), "child merged view")
+	if draft, _ := parent.GetString("draft"); draft != "v1" {
+		t.Errorf("Expected parent draft to stay v1, got %q", draft)
+	}
+	AssertEqual(t, false, parent.Has("score"), "child writes isolated")
+	AssertEqual(t, parent.RunID(), child.RunID(), "child shares run ID")
+
+	child.Merge()
+	AssertEqual(t, false, parent.Has("topic"), "merged delete")
+	if score, _ := parent.GetInt("score"); score != 9 {
+		t.Errorf("Expected merged score 9, got %v", score)
+	}
+	if draft, _ := child.GetString("draft"); draft != "v2" {
+		t.Errorf("Expected child to read merged draft, got %q", draft)
+	}
+
+	parent.Cancel()
+	<-child.Context().Done()
+}
+
+func TestContextReportProgress(t *testing.T) {
+	ctx := NewContext(context.Background())
+	sub := ctx.Subscribe(EventProgress)
+
+	AssertNoError(t, ctx.ReportProgress("write", 50, "halfway"), "ReportProgress")
+	AssertError(t, ctx.ReportProgress("write", 150, ""), "ReportProgress out of range")
+
+	progress := (<-sub.Events()).(*ProgressEvent)
+	AssertEqual(t, "write", progress.StepName, "step name")
+	AssertEqual(t, 50.0, progress.Percent, "percent")
+	AssertEqual(t, ctx.RunID(), progress.RunID(), "progress events are stamped")
+	AssertEqual(t, 0, len(ctx.Events()), "progress events bypass the engine")
+}
+
+func TestContextStreamBeforeRead(t *testing.T) {
+	ctx := NewContext(context.Background(), WithStreamBuffer(2))
+	go func() {
+		for range ctx.Events() {
+		}
+	}()
+
+	// Sending more events than the stream buffer must not wait for a reader
+	for i := 0; i < 10; i++ {
+		AssertNoError(t, ctx.SendEvent(NewBaseEvent(EventType("Tick"), map[string]interface{}{"i": i})), "SendEvent")
+	}
+	ctx.closeStreams()
+
+	count := 0
+	for event := range ctx.Stream() {
+		AssertEqual(t, count, event.Data()["i"], "events in order")
+		count++
+	}
+	AssertEqual(t, 10, count, "streamed events")
+}
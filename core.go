@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -30,6 +33,22 @@ const ContextVariablesName = "context_variables"
 type Swarm struct {
 	// Client is the interface to OpenAI's API
 	Client OpenAIClient
+	// AuditLogger optionally records every tool call and agent handoff
+	AuditLogger AuditLogger
+	// TracerProvider optionally enables OpenTelemetry spans
+	TracerProvider trace.TracerProvider
+	// FileUploadThreshold uploads non-text attachments larger than this many
+	// bytes instead of inlining them; zero always inlines
+	FileUploadThreshold int
+	// Registry resolves agents by name (DefaultRegistry if nil)
+	Registry *AgentRegistry
+	// ToolErrors optionally reports tool failures in detail and caps them
+	ToolErrors *ToolErrorPolicy
+	// LoopGuard optionally stops runs stuck in tool-call loops
+	LoopGuard *LoopGuard
+	// StreamCoalesce merges the deltas RunAndStream streams within this
+	// interval into one StreamChunk; zero emits every delta
+	StreamCoalesce time.Duration
 }
 
 // NewSwarm creates a new Swarm instance with the provided OpenAI client.
@@ -46,18 +65,25 @@ func NewSwarm(client OpenAIClient) *Swarm {
 	return &Swarm{Client: client}
 }
 
+// WithAuditLogger sets the audit logger and returns the swarm.
+func (s *Swarm) WithAuditLogger(logger AuditLogger) *Swarm {
+	s.AuditLogger = logger
+	return s
+}
+
 // NewDefaultSwarm creates a new Swarm instance with default OpenAI client configuration.
-// It uses the OPENAI_API_KEY environment variable for authentication.
+// It uses the OPENAI_API_KEY environment variable for authentication, and
+// opts such as WithTransport to tune the client.
 // Returns an error if the API key is not set or if client creation fails.
-func NewDefaultSwarm() (*Swarm, error) {
+func NewDefaultSwarm(opts ...ClientOption) (*Swarm, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey != "" {
 		apiBase := os.Getenv("OPENAI_API_BASE")
 		if apiBase == "" {
-			return NewSwarm(NewOpenAIClient(apiKey)), nil
+			return NewSwarm(NewOpenAIClient(apiKey, opts...)), nil
 		}
 
-		return NewSwarm(NewOpenAIClientWithBaseURL(apiKey, apiBase)), nil
+		return NewSwarm(NewOpenAIClientWithBaseURL(apiKey, apiBase, opts...)), nil
 	}
 
 	azureAPIKey := os.Getenv("AZURE_OPENAI_API_KEY")
@@ -79,7 +105,7 @@ func NewDefaultSwarm() (*Swarm, error) {
 		return nil, fmt.Errorf("required environment variables not set: %s", strings.Join(missingEnvs, ", "))
 	}
 
-	return NewSwarm(NewAzureOpenAIClient(azureAPIKey, azureAPIBase, azureAPIVersion)), nil
+	return NewSwarm(NewAzureOpenAIClient(azureAPIKey, azureAPIBase, azureAPIVersion, opts...)), nil
 }
 
 // getChatCompletion sends a request to OpenAI's chat completion API and returns the response.
@@ -111,45 +137,92 @@ func (s *Swarm) getChatCompletion(
 		contextVariables = make(map[string]interface{})
 	}
 
-	instructions, err := s.getInstructions(agent, contextVariables)
+	params, err := s.buildChatParams(ctx, agent, history, contextVariables, modelOverride, jsonMode)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare messages
-	model := modelOverride
-	if model == "" {
-		model = agent.Model
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
 	}
-	messages := prepareMessages(instructions, history, model)
+	DebugPrint(debug, "Getting chat completion for:", string(paramsJSON))
 
-	// Prepare tools
-	tools := prepareTools(agent)
+	estimated := estimateRequestTokens(ctx, history, resolveModel(agent, modelOverride))
+	if err := waitRequestLimiters(ctx, estimated); err != nil {
+		return nil, err
+	}
+	ctx, span := s.startChatSpan(ctx, agent, modelOverride)
+	completion, err := s.Client.CreateChatCompletion(withHostedTools(ctx, agent.HostedTools), params)
+	if err == nil {
+		setUsageAttributes(span, completion.Usage)
+		recordTokenUsage(ctx, estimated, completion.Usage.TotalTokens)
+	}
+	endSpan(span, err)
+	return completion, err
+}
+
+// buildChatParams prepares the chat completion parameters for the agent,
+// resolving its instructions (with recalled memories), tools and response
+// format.
+func (s *Swarm) buildChatParams(
+	ctx context.Context,
+	agent *Agent,
+	history []map[string]interface{},
+	contextVariables map[string]interface{},
+	modelOverride string,
+	jsonMode bool,
+) (openai.ChatCompletionNewParams, error) {
+	instructions, err := s.getInstructions(agent, contextVariables)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+	instructions, err = s.recallMemories(ctx, agent, history, contextVariables, instructions)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+
+	// Prepare messages, trimming history that would overflow the context window
+	model := resolveModel(agent, modelOverride)
+	history, err = trimHistory(agent, instructions, history, model)
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+	messages := prepareMessages(instructions, history, model)
 
 	// Create completion parameters
 	params := openai.ChatCompletionNewParams{
 		Messages: messages,
-		Model:    openai.ChatModel(modelOverride),
+		Model:    openai.ChatModel(model),
+	}
+	if agent.ReasoningEffort != "" && CapabilitiesForModel(model).ReasoningEffort {
+		params.ReasoningEffort = openai.ReasoningEffort(agent.ReasoningEffort)
+	}
+	if temperature, ok := temperatureFromContext(ctx); ok && !CapabilitiesForModel(model).Reasoning {
+		params.Temperature = openai.Float(temperature)
+	}
+	if maxTokens, ok := maxTokensFromContext(ctx); ok && maxTokens > 0 {
+		params.MaxCompletionTokens = openai.Int(int64(maxTokens))
 	}
 	if jsonMode {
 		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
 			OfJSONObject: &openai.ResponseFormatJSONObjectParam{},
 		}
 	}
+
+	// Prepare tools
+	tools := prepareTools(agent)
 	if len(tools) > 0 {
 		params.Tools = tools
-		if agent.ToolChoice != nil {
-			params.ToolChoice = *agent.ToolChoice
+		choice, err := resolveToolChoice(ctx, agent)
+		if err != nil {
+			return openai.ChatCompletionNewParams{}, err
+		}
+		if choice != nil {
+			params.ToolChoice = *choice
 		}
 	}
-
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	DebugPrint(debug, "Getting chat completion for:", string(paramsJSON))
-
-	return s.Client.CreateChatCompletion(ctx, params)
+	return params, nil
 }
 
 // getInstructions safely extracts instructions from the agent based on its type.
@@ -161,6 +234,8 @@ func (s *Swarm) getInstructions(agent *Agent, contextVariables map[string]interf
 		return i(contextVariables), nil
 	case func() string:
 		return i(), nil
+	case *template.Template:
+		return renderInstructions(i, contextVariables)
 	default:
 		return "", ErrInvalidInstruction
 	}
@@ -175,6 +250,7 @@ func prepareTools(agent *Agent) []openai.ChatCompletionToolParam {
 			if params, ok := funcJSON["function"].(map[string]interface{})["parameters"].(map[string]interface{}); ok {
 				if props, ok := params["properties"].(map[string]interface{}); ok {
 					delete(props, ContextVariablesName)
+					delete(props, FilesName)
 				}
 			}
 
@@ -196,12 +272,7 @@ func prepareTools(agent *Agent) []openai.ChatCompletionToolParam {
 
 func prepareMessages(instructions string, history []map[string]interface{}, model string) []openai.ChatCompletionMessageParamUnion {
 	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(instructions),
-	}
-	if strings.Contains(model, "o1") || strings.Contains(model, "o3") || strings.Contains(strings.ToLower(model), "deekseek") {
-		messages = []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(instructions),
-		}
+		instructionMessage(instructions, model),
 	}
 
 	for _, msg := range history {
@@ -210,7 +281,7 @@ func prepareMessages(instructions string, history []map[string]interface{}, mode
 
 		switch role {
 		case "user":
-			messages = append(messages, openai.UserMessage(content))
+			messages = append(messages, userMessage(content, messageFiles(msg)))
 		case "system":
 
 		case "function":
@@ -268,6 +339,7 @@ func (s *Swarm) handleFunctionResult(result interface{}, debug bool) (*Result, e
 
 // handleToolCalls processes tool calls from the chat completion
 func (s *Swarm) handleToolCalls(
+	ctx context.Context,
 	toolCalls []openai.ChatCompletionMessageToolCall,
 	functions []AgentFunction,
 	contextVariables map[string]interface{},
@@ -309,12 +381,7 @@ func (s *Swarm) handleToolCalls(
 		if !exists {
 			errMsg := fmt.Sprintf("Tool %q not found in function map", name)
 			DebugPrint(debug, errMsg)
-			response.Messages = append(response.Messages, map[string]interface{}{
-				"role":         "tool",
-				"tool_call_id": toolCall.ID,
-				"tool_name":    name,
-				"content":      fmt.Sprintf("Error: %s", errMsg),
-			})
+			response.addToolError(toolCall, ToolErrorNotFound, errMsg, s.toolErrorContent(ToolErrorNotFound, name, errMsg, nil, functionMap), 0)
 			continue
 		}
 
@@ -322,45 +389,62 @@ func (s *Swarm) handleToolCalls(
 		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
 			errMsg := fmt.Sprintf("Failed to parse arguments for tool %q: %v", name, err)
 			DebugPrint(debug, errMsg)
-			response.Messages = append(response.Messages, map[string]interface{}{
-				"role":         "tool",
-				"tool_call_id": toolCall.ID,
-				"tool_name":    name,
-				"content":      fmt.Sprintf("Error: %s", errMsg),
-			})
+			response.addToolError(toolCall, ToolErrorInvalidArguments, errMsg, s.toolErrorContent(ToolErrorInvalidArguments, name, errMsg, fn, functionMap), 0)
+			continue
+		}
+
+		if err := s.checkApproval(ctx, fn, ToolCall{Function: Function{Name: name, Arguments: toolCall.Function.Arguments}}, debug); err != nil {
+			errMsg := fmt.Sprintf("Function %q execution denied: %v", name, err)
+			DebugPrint(debug, errMsg)
+			response.addToolError(toolCall, ToolErrorDenied, errMsg, s.toolErrorContent(ToolErrorDenied, name, errMsg, fn, functionMap), 0)
 			continue
 		}
 
-		// Add context variables to args
+		// Add context variables and attachments to args
 		args[ContextVariablesName] = contextVariables
+		if files := filesFromContext(ctx); len(files) > 0 {
+			args[FilesName] = files
+		}
 
 		// Execute function
-		rawResult, err := fn.Call(args)
+		_, span := s.tracer().Start(ctx, "tool.call", trace.WithAttributes(attrTool.String(name)))
+		start := time.Now()
+		rawResult, err := AsContextFunction(approvedFunction(fn)).CallWithContext(&CallContext{
+			Context:          ctx,
+			Agent:            callerFromContext(ctx),
+			ToolCallID:       toolCall.ID,
+			RunID:            runIDFromContext(ctx),
+			ContextVariables: contextVariables,
+		}, args)
+		endSpan(span, err)
+		record := AuditRecord{
+			Action:    AuditToolCall,
+			Tool:      name,
+			Arguments: toolCall.Function.Arguments,
+			Duration:  time.Since(start),
+		}
 		if err != nil {
+			record.Error = err.Error()
+			audit(s.AuditLogger, record, debug)
 			errMsg := fmt.Sprintf("Function %q execution failed: %v", name, err)
 			DebugPrint(debug, errMsg)
-			response.Messages = append(response.Messages, map[string]interface{}{
-				"role":         "tool",
-				"tool_call_id": toolCall.ID,
-				"tool_name":    name,
-				"content":      fmt.Sprintf("Error: %s", errMsg),
-			})
+			response.addToolError(toolCall, ToolErrorExecution, errMsg, s.toolErrorContent(ToolErrorExecution, name, errMsg, fn, functionMap), record.Duration)
 			continue
 		}
 
 		result, err := s.handleFunctionResult(rawResult, debug)
 		if err != nil {
+			record.Error = err.Error()
+			audit(s.AuditLogger, record, debug)
 			errMsg := fmt.Sprintf("Failed to handle result for tool %q: %v", name, err)
 			DebugPrint(debug, errMsg)
-			response.Messages = append(response.Messages, map[string]interface{}{
-				"role":         "tool",
-				"tool_call_id": toolCall.ID,
-				"tool_name":    name,
-				"content":      fmt.Sprintf("Error: %s", errMsg),
-			})
+			response.addToolError(toolCall, ToolErrorResult, errMsg, s.toolErrorContent(ToolErrorResult, name, errMsg, fn, functionMap), record.Duration)
 			continue
 		}
 
+		record.Result = result.Value
+		audit(s.AuditLogger, record, debug)
+
 		// Update context variables from result
 		for k, v := range result.ContextVariables {
 			contextVariables[k] = v
@@ -370,6 +454,9 @@ func (s *Swarm) handleToolCalls(
 		// Update agent if transferred
 		if result.Agent != nil {
 			response.Agent = result.Agent
+			reason, _ := args["reason"].(string)
+			response.Handoffs = append(response.Handoffs, HandoffRecord{To: result.Agent.Name, Tool: name, Reason: reason})
+			audit(s.AuditLogger, AuditRecord{Action: AuditHandoff, Tool: name, Agent: result.Agent.Name}, debug)
 		}
 
 		// Create tool response message
@@ -386,11 +473,36 @@ func (s *Swarm) handleToolCalls(
 		}
 
 		response.Messages = append(response.Messages, message)
+		response.toolResults = append(response.toolResults, ToolResult{
+			ID:        toolCall.ID,
+			Name:      name,
+			Arguments: toolCall.Function.Arguments,
+			Result:    result.Value,
+			Duration:  record.Duration,
+		})
 	}
 
 	return response, nil
 }
 
+// addToolError records a failed tool call as a tool message with content.
+func (r *Response) addToolError(toolCall openai.ChatCompletionMessageToolCall, kind ToolErrorKind, errMsg, content string, duration time.Duration) {
+	r.Messages = append(r.Messages, map[string]interface{}{
+		"role":         "tool",
+		"tool_call_id": toolCall.ID,
+		"tool_name":    toolCall.Function.Name,
+		"content":      content,
+	})
+	r.toolResults = append(r.toolResults, ToolResult{
+		ID:        toolCall.ID,
+		Name:      toolCall.Function.Name,
+		Arguments: toolCall.Function.Arguments,
+		Error:     errMsg,
+		ErrorKind: kind,
+		Duration:  duration,
+	})
+}
+
 // RunAndStream executes an interaction with the OpenAI model and returns a channel
 // that streams the response tokens as they arrive.
 //
@@ -405,6 +517,13 @@ func (s *Swarm) handleToolCalls(
 //   - executeTools: Whether to execute tool calls
 //
 // Returns a channel of response tokens or an error if the streaming setup fails.
+// Each model delta is reported as a *StreamChunk under "chunk" as it arrives,
+// and each executed tool call as a *ToolResult under "tool_result" before the
+// next model turn starts. Reasoning is only reported as chunks, while the
+// complete "content" and "tool_calls" blocks repeat the chunks they follow.
+// The last chunk carries the final *Response under "response". If the run
+// fails, the same chunk also carries the error message under "error", and
+// the Response reports the error in Error with StopReason StopError.
 func (s *Swarm) RunAndStream(
 	ctx context.Context,
 	agent *Agent,
@@ -430,55 +549,107 @@ func (s *Swarm) RunAndStream(
 
 	resultChan := make(chan map[string]interface{})
 	activeAgent := agent
+	var handoffs []HandoffRecord
+	failures := toolFailures{}
+	var loops loopState
+	stopReason, stopDetail := StopMaxTurns, ""
+	var usage Usage
+	var compaction historyCompaction
 	history := make([]map[string]interface{}, len(messages))
 	copy(history, messages)
 	initLen := len(messages)
 
-	// Prepare tools
-	tools := prepareTools(agent)
+	response := func() *Response {
+		return &Response{
+			Messages:         history[initLen:],
+			History:          compaction.history(history),
+			Agent:            activeAgent,
+			ContextVariables: contextVariables,
+			Handoffs:         handoffs,
+			StopReason:       stopReason,
+			StopDetail:       stopDetail,
+			TokensUsed:       usage.TotalTokens,
+			Cost:             usage.Cost,
+			Usage:            usage,
+		}
+	}
+	// fail ends the run with a terminal chunk reporting err, both as a
+	// message under "error" and on the final Response
+	fail := func(err error) {
+		DebugPrint(debug, err)
+		stopReason, stopDetail = StopError, err.Error()
+		final := response()
+		final.Error = err
+		resultChan <- map[string]interface{}{
+			"error":    err.Error(),
+			"response": final,
+		}
+	}
 
 	go func() {
 		defer close(resultChan)
 
-		for len(history)-initLen < maxTurns {
-			instructions, err := s.getInstructions(activeAgent, contextVariables)
+		ctx, runSpan := s.tracer().Start(withRunID(ctx), "swarm.run", trace.WithAttributes(attrAgent.String(agent.Name)))
+		defer runSpan.End()
+
+		transcribed, err := s.transcribeHistory(ctx, agent, history)
+		if err != nil {
+			fail(fmt.Errorf("failed to transcribe audio: %w", err))
+			return
+		}
+		uploaded, err := s.uploadFiles(ctx, transcribed)
+		if err != nil {
+			fail(fmt.Errorf("failed to upload files: %w", err))
+			return
+		}
+		history = uploaded
+
+		for turn := 1; len(history)-initLen < maxTurns; turn++ {
+			model := resolveModel(activeAgent, modelOverride)
+			requestHistory, err := s.compactHistory(ctx, &compaction, activeAgent, history, model)
 			if err != nil {
-				DebugPrint(debug, "Failed to get instructions:", err)
+				fail(err)
 				return
 			}
-			model := modelOverride
-			if model == "" {
-				model = activeAgent.Model
-			}
-			messages := prepareMessages(instructions, history, model)
-			params := openai.ChatCompletionNewParams{
-				Messages: messages,
-				Model:    modelOverride,
-			}
-			if jsonMode {
-				params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
-					OfJSONObject: &openai.ResponseFormatJSONObjectParam{},
-				}
+			params, err := s.buildChatParams(ctx, activeAgent, requestHistory, contextVariables, modelOverride, jsonMode)
+			if err != nil {
+				fail(fmt.Errorf("failed to get instructions: %w", err))
+				return
 			}
-			if len(tools) > 0 {
-				params.Tools = tools
-				if agent.ToolChoice != nil {
-					params.ToolChoice = *agent.ToolChoice
-				}
+			estimated := estimateRequestTokens(ctx, requestHistory, model)
+			if err := waitRequestLimiters(ctx, estimated); err != nil {
+				fail(fmt.Errorf("failed to wait for the request limiter: %w", err))
+				return
 			}
-			stream, err := s.Client.CreateChatCompletionStream(ctx, params)
+			turnCtx, chatSpan := s.startChatSpan(ctx, activeAgent, modelOverride)
+			stream, err := s.Client.CreateChatCompletionStream(withHostedTools(turnCtx, activeAgent.HostedTools), params)
 			if err != nil {
-				DebugPrint(debug, "Failed to create chat completion stream:", err)
+				endSpan(chatSpan, err)
+				fail(fmt.Errorf("failed to create chat completion stream: %w", err))
 				return
 			}
 
 			resultChan <- map[string]interface{}{"delim": "start"}
 			acc := openai.ChatCompletionAccumulator{}
+			var reasoning strings.Builder
+			deltas := chunkCoalescer{interval: s.StreamCoalesce, emit: func(chunk *StreamChunk) {
+				resultChan <- map[string]interface{}{
+					"chunk":  chunk,
+					"sender": chunk.Sender,
+				}
+			}}
 			for stream.Next() {
 				chunk := stream.Current()
 				acc.AddChunk(chunk)
+				for _, delta := range streamChunks(chunk, activeAgent.Name) {
+					if delta.Type == StreamChunkReasoning {
+						reasoning.WriteString(delta.Delta)
+					}
+					deltas.add(delta)
+				}
 
 				if content, ok := acc.JustFinishedContent(); ok {
+					deltas.finish(StreamChunkContent, 0)
 					resultChan <- map[string]interface{}{
 						"content": content,
 						"sender":  activeAgent.Name,
@@ -486,6 +657,7 @@ func (s *Swarm) RunAndStream(
 				}
 
 				if tool, ok := acc.JustFinishedToolCall(); ok {
+					deltas.finish(StreamChunkToolCall, tool.Index)
 					resultChan <- map[string]interface{}{
 						"tool_calls": []map[string]interface{}{
 							{
@@ -499,17 +671,24 @@ func (s *Swarm) RunAndStream(
 					}
 				}
 			}
+			deltas.flush()
 
 			resultChan <- map[string]interface{}{"delim": "end"}
 
+			setUsageAttributes(chatSpan, acc.Usage)
+			recordTokenUsage(ctx, estimated, acc.Usage.TotalTokens)
+			requestUsage := newUsage(requestModel(activeAgent, modelOverride, acc.Model), acc.Usage)
+			usage.Add(requestUsage)
+			trackUsage(ctx, requestUsage)
+			endSpan(chatSpan, stream.Err())
 			if err := stream.Err(); err != nil {
-				DebugPrint(debug, "Stream error:", err)
+				fail(fmt.Errorf("stream error: %w", err))
 				return
 			}
 
 			// Process accumulated response
 			if len(acc.Choices) == 0 {
-				DebugPrint(debug, "No choices in the response.")
+				fail(errors.New("no choices in the response"))
 				return
 			}
 
@@ -522,6 +701,9 @@ func (s *Swarm) RunAndStream(
 			if len(acc.Choices[0].Message.ToolCalls) > 0 {
 				message["tool_calls"] = acc.Choices[0].Message.ToolCalls
 			}
+			if reasoning.Len() > 0 {
+				message["reasoning_content"] = reasoning.String()
+			}
 
 			DebugPrint(debug, "Received completion:", message)
 			history = append(history, message)
@@ -529,33 +711,66 @@ func (s *Swarm) RunAndStream(
 			toolCalls := acc.Choices[0].Message.ToolCalls
 			if len(toolCalls) == 0 || !executeTools {
 				DebugPrint(debug, "Ending turn.")
+				if err := s.synthesizeReply(turnCtx, activeAgent, message); err != nil {
+					fail(fmt.Errorf("failed to synthesize speech: %w", err))
+					return
+				}
+				if err := s.rememberRun(turnCtx, activeAgent, history, contextVariables, message); err != nil {
+					fail(err)
+					return
+				}
+				stopReason = StopCompleted
+				break
+			}
+			if reason, detail := s.LoopGuard.check(&loops, toolCalls); reason != "" {
+				DebugPrint(debug, "Stopping run:", detail)
+				history = append(history, stoppedToolMessages(toolCalls, detail)...)
+				stopReason, stopDetail = reason, detail
 				break
 			}
 
 			// Handle tool calls
-			response, err := s.handleToolCalls(toolCalls, activeAgent.Functions, contextVariables, debug)
+			response, err := s.handleToolCalls(withCaller(withConversationFiles(turnCtx, history), activeAgent), toolCalls, activeAgent.Functions, contextVariables, debug)
 			if err != nil {
-				DebugPrint(debug, "Tool call error:", err)
+				fail(err)
 				return
 			}
+			for i := range response.toolResults {
+				resultChan <- map[string]interface{}{
+					"tool_result": &response.toolResults[i],
+					"sender":      activeAgent.Name,
+				}
+			}
 
 			history = append(history, response.Messages...)
+			if err := failures.record(s.ToolErrors, response.toolResults); err != nil {
+				fail(err)
+				return
+			}
+			ctx = releaseToolChoice(ctx)
 			for k, v := range response.ContextVariables {
 				contextVariables[k] = v
 			}
+			handoffs = appendHandoffs(handoffs, activeAgent.Name, turn, response.Handoffs)
+			state := &RunState{
+				Turn:       turn,
+				Agent:      activeAgent,
+				Message:    message,
+				ToolNames:  toolCallNames(toolCalls),
+				Messages:   history[initLen:],
+				TokensUsed: usage.TotalTokens,
+			}
 			if response.Agent != nil {
 				activeAgent = response.Agent
 			}
+			if reason := checkStopConditions(ctx, state); reason != "" {
+				stopReason, stopDetail = StopConditionMet, reason
+				break
+			}
 		}
 
 		// Send final response
-		resultChan <- map[string]interface{}{
-			"response": &Response{
-				Messages:         history[initLen:],
-				Agent:            activeAgent,
-				ContextVariables: contextVariables,
-			},
-		}
+		resultChan <- map[string]interface{}{"response": response()}
 	}()
 
 	return resultChan, nil
@@ -588,7 +803,7 @@ func (s *Swarm) Run(
 	maxTurns int,
 	executeTools bool,
 	jsonMode bool,
-) (*Response, error) {
+) (_ *Response, err error) {
 	if stream {
 		ch, err := s.RunAndStream(ctx, agent, messages, contextVariables, modelOverride, debug, maxTurns, executeTools, false)
 		if err != nil {
@@ -603,6 +818,9 @@ func (s *Swarm) Run(
 				}
 			}
 		}
+		if finalResponse != nil && finalResponse.Error != nil {
+			return nil, finalResponse.Error
+		}
 		return finalResponse, nil
 	}
 
@@ -610,22 +828,53 @@ func (s *Swarm) Run(
 		contextVariables = make(map[string]interface{})
 	}
 
+	ctx, runSpan := s.tracer().Start(withRunID(ctx), "swarm.run", trace.WithAttributes(attrAgent.String(agent.Name)))
+	defer func() { endSpan(runSpan, err) }()
+
 	activeAgent := agent
+	var handoffs []HandoffRecord
+	failures := toolFailures{}
+	var loops loopState
+	stopReason, stopDetail := StopMaxTurns, ""
+	var usage Usage
+	var compaction historyCompaction
 	history := make([]map[string]interface{}, len(messages))
 	copy(history, messages)
 	initLen := len(messages)
+	if history, err = s.transcribeHistory(ctx, agent, history); err != nil {
+		return nil, err
+	}
+	if history, err = s.uploadFiles(ctx, history); err != nil {
+		return nil, err
+	}
 
-	for len(history)-initLen < maxTurns {
-		completion, err := s.getChatCompletion(ctx, activeAgent, history, contextVariables, modelOverride, debug, jsonMode)
+	for turn := 1; len(history)-initLen < maxTurns; turn++ {
+		turnCtx, turnSpan := s.tracer().Start(ctx, "swarm.turn", trace.WithAttributes(
+			attrAgent.String(activeAgent.Name),
+			attrTurn.Int(turn),
+		))
+		requestHistory, err := s.compactHistory(turnCtx, &compaction, activeAgent, history, resolveModel(activeAgent, modelOverride))
+		if err != nil {
+			endSpan(turnSpan, err)
+			return nil, err
+		}
+		completion, err := s.getChatCompletion(turnCtx, activeAgent, requestHistory, contextVariables, modelOverride, debug, jsonMode)
 		if err != nil {
+			endSpan(turnSpan, err)
 			return nil, err
 		}
+		requestUsage := newUsage(requestModel(activeAgent, modelOverride, completion.Model), completion.Usage)
+		usage.Add(requestUsage)
+		trackUsage(ctx, requestUsage)
 
 		message := map[string]interface{}{
 			"content": completion.Choices[0].Message.Content,
 			"sender":  activeAgent.Name,
 			"role":    "assistant",
 		}
+		if reasoning := reasoningContent(completion.Choices[0].Message.JSON.ExtraFields); reasoning != "" {
+			message["reasoning_content"] = reasoning
+		}
 		if len(completion.Choices[0].Message.ToolCalls) > 0 {
 			message["tool_calls"] = completion.Choices[0].Message.ToolCalls
 		}
@@ -635,27 +884,80 @@ func (s *Swarm) Run(
 
 		if len(completion.Choices[0].Message.ToolCalls) == 0 || !executeTools {
 			DebugPrint(debug, "Ending turn.")
+			err := s.synthesizeReply(turnCtx, activeAgent, message)
+			if err == nil {
+				err = s.rememberRun(turnCtx, activeAgent, history, contextVariables, message)
+			}
+			endSpan(turnSpan, err)
+			if err != nil {
+				return nil, err
+			}
+			stopReason = StopCompleted
+			break
+		}
+		if reason, detail := s.LoopGuard.check(&loops, completion.Choices[0].Message.ToolCalls); reason != "" {
+			DebugPrint(debug, "Stopping run:", detail)
+			history = append(history, stoppedToolMessages(completion.Choices[0].Message.ToolCalls, detail)...)
+			stopReason, stopDetail = reason, detail
+			endSpan(turnSpan, nil)
 			break
 		}
 
 		// Handle tool calls
-		response, err := s.handleToolCalls(completion.Choices[0].Message.ToolCalls, activeAgent.Functions, contextVariables, debug)
+		response, err := s.handleToolCalls(withCaller(withConversationFiles(turnCtx, history), activeAgent), completion.Choices[0].Message.ToolCalls, activeAgent.Functions, contextVariables, debug)
+		endSpan(turnSpan, err)
 		if err != nil {
 			return nil, err
 		}
 
 		history = append(history, response.Messages...)
+		if err := failures.record(s.ToolErrors, response.toolResults); err != nil {
+			return nil, err
+		}
+		ctx = releaseToolChoice(ctx)
 		for k, v := range response.ContextVariables {
 			contextVariables[k] = v
 		}
+		handoffs = appendHandoffs(handoffs, activeAgent.Name, turn, response.Handoffs)
+		state := &RunState{
+			Turn:       turn,
+			Agent:      activeAgent,
+			Message:    message,
+			ToolNames:  toolCallNames(completion.Choices[0].Message.ToolCalls),
+			Messages:   history[initLen:],
+			TokensUsed: usage.TotalTokens,
+		}
 		if response.Agent != nil {
 			activeAgent = response.Agent
 		}
+		if reason := checkStopConditions(ctx, state); reason != "" {
+			stopReason, stopDetail = StopConditionMet, reason
+			break
+		}
 	}
 
 	return &Response{
 		Messages:         history[initLen:],
+		History:          compaction.history(history),
 		Agent:            activeAgent,
 		ContextVariables: contextVariables,
+		Handoffs:         handoffs,
+		StopReason:       stopReason,
+		StopDetail:       stopDetail,
+		TokensUsed:       usage.TotalTokens,
+		Cost:             usage.Cost,
+		Usage:            usage,
 	}, nil
 }
+
+// appendHandoffs completes the handoffs made by one turn's tool calls, which
+// start from the active agent, and appends them to the run's handoffs.
+func appendHandoffs(handoffs []HandoffRecord, from string, turn int, records []HandoffRecord) []HandoffRecord {
+	for _, record := range records {
+		record.From = from
+		record.Turn = turn
+		handoffs = append(handoffs, record)
+		from = record.To
+	}
+	return handoffs
+}
@@ -2,11 +2,15 @@ package swarm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
 )
 
 // Mock types for testing
@@ -133,7 +137,7 @@ func TestHandleToolCalls(t *testing.T) {
 	toolCalls := []openai.ChatCompletionMessageToolCall{mockCall.ToOpenAI()}
 
 	// Pass the agent's functions directly
-	response, err := swarm.handleToolCalls(toolCalls, agent.Functions, nil, false)
+	response, err := swarm.handleToolCalls(context.Background(), toolCalls, agent.Functions, nil, false)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -445,6 +449,72 @@ func TestRunAndStreamWithAgentTransfer(t *testing.T) {
 	}
 }
 
+// streamRecorder records the requests of a MockOpenAIClient's streams.
+type streamRecorder struct {
+	*MockOpenAIClient
+	requests []openai.ChatCompletionNewParams
+}
+
+func (r *streamRecorder) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	r.requests = append(r.requests, params)
+	return r.MockOpenAIClient.CreateChatCompletionStream(ctx, params)
+}
+
+func TestBuildChatParamsModel(t *testing.T) {
+	swarm := NewSwarm(NewMockOpenAIClient())
+	agent := NewAgent("agent").WithModel("agent-model")
+	history := []map[string]interface{}{{"role": "user", "content": "Hi"}}
+
+	params, err := swarm.buildChatParams(context.Background(), agent, history, nil, "", false)
+	AssertNoError(t, err, "buildChatParams")
+	AssertEqual(t, openai.ChatModel("agent-model"), params.Model, "agent model without override")
+
+	params, err = swarm.buildChatParams(context.Background(), agent, history, nil, "override-model", false)
+	AssertNoError(t, err, "buildChatParams")
+	AssertEqual(t, openai.ChatModel("override-model"), params.Model, "override wins")
+}
+
+func TestRunAndStreamUsesActiveAgent(t *testing.T) {
+	client := &streamRecorder{MockOpenAIClient: NewMockOpenAIClient()}
+	agent2 := NewAgent("Agent2").WithModel("agent2-model").AddFunction(NewAgentFunction("lookup", "Look up",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "found", nil
+		},
+		[]Parameter{},
+	))
+	agent1 := NewAgent("Agent1").WithModel("agent1-model").AddFunction(NewAgentFunction("transfer", "Transfer to Agent2",
+		func(args map[string]interface{}) (interface{}, error) {
+			return &Result{Value: "Transferring", Agent: agent2}, nil
+		},
+		[]Parameter{},
+	))
+	client.AddStreamChunk(&openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{{
+			Delta: openai.ChatCompletionChunkChoiceDelta{
+				ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+					Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Name: "transfer", Arguments: "{}"},
+				}},
+			},
+		}},
+	})
+
+	ch, err := NewSwarm(client).RunAndStream(context.Background(), agent1, []map[string]interface{}{
+		{"role": "user", "content": "Hello"},
+	}, nil, "", false, 3, true, false)
+	AssertNoError(t, err, "RunAndStream")
+	for range ch {
+	}
+
+	if len(client.requests) < 2 {
+		t.Fatalf("Expected a request after the handoff, got %d requests", len(client.requests))
+	}
+	AssertEqual(t, openai.ChatModel("agent1-model"), client.requests[0].Model, "first agent model")
+	AssertEqual(t, "transfer", client.requests[0].Tools[0].Function.Name, "first agent tools")
+	AssertEqual(t, openai.ChatModel("agent2-model"), client.requests[1].Model, "active agent model")
+	AssertEqual(t, 1, len(client.requests[1].Tools), "active agent tool count")
+	AssertEqual(t, "lookup", client.requests[1].Tools[0].Function.Name, "active agent tools")
+}
+
 func TestToolPreparationWithContextVariables(t *testing.T) {
 	agent := NewAgent("TestAgent")
 	testFunc := NewAgentFunction(
@@ -539,3 +609,208 @@ func TestMessageAccumulation(t *testing.T) {
 		t.Error("Expected to see end delimiter")
 	}
 }
+
+func TestRunRecordsHandoffs(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	swarm := NewSwarm(mockClient)
+	triage := NewAgent("triage")
+	billing := NewAgent("billing")
+	refunds := NewAgent("refunds")
+	transferTo := func(target *Agent) AgentFunction {
+		return NewAgentFunction("transfer_to_"+target.Name, "Transfer to "+target.Name,
+			func(args map[string]interface{}) (interface{}, error) {
+				return target, nil
+			},
+			[]Parameter{{Name: "reason", Type: reflect.TypeOf(""), Description: "Why"}},
+		)
+	}
+	triage.AddFunction(transferTo(billing))
+	billing.AddFunction(transferTo(refunds))
+
+	toolCall := func(name, args string) *openai.ChatCompletion {
+		return &openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role:      "assistant",
+				ToolCalls: []openai.ChatCompletionMessageToolCall{MockToolCall{ID: name, Name: name, Args: args}.ToOpenAI()},
+			}}},
+		}
+	}
+	mockClient.SetCompletionResponse(toolCall("transfer_to_billing", `{"reason": "invoice question"}`))
+	mockClient.SetCompletionResponse(toolCall("transfer_to_refunds", `{}`))
+	mockClient.SetCompletionResponse(&openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Refund issued"}}},
+	})
+
+	response, err := swarm.Run(context.Background(), triage, []map[string]interface{}{
+		{"role": "user", "content": "Refund my invoice"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, 2, len(response.Handoffs), "handoff count")
+	first := response.Handoffs[0]
+	AssertEqual(t, "triage", first.From, "first handoff from")
+	AssertEqual(t, "billing", first.To, "first handoff to")
+	AssertEqual(t, "transfer_to_billing", first.Tool, "first handoff tool")
+	AssertEqual(t, "invoice question", first.Reason, "first handoff reason")
+	AssertEqual(t, 1, first.Turn, "first handoff turn")
+	AssertEqual(t, 2, response.Handoffs[1].Turn, "second handoff turn")
+	AssertEqual(t, "triage,billing,refunds", strings.Join(response.AgentTrail(), ","), "agent trail")
+}
+
+func TestAgentTrailWithoutHandoffs(t *testing.T) {
+	response := &Response{Agent: NewAgent("solo")}
+	AssertEqual(t, "solo", strings.Join(response.AgentTrail(), ","), "agent trail")
+}
+
+func TestRunAndStreamErrors(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	mockClient.Error = fmt.Errorf("service unavailable")
+	swarm := NewSwarm(mockClient)
+	agent := NewAgent("TestAgent")
+	messages := []map[string]interface{}{{"role": "user", "content": "Hello"}}
+
+	ch, err := swarm.RunAndStream(context.Background(), agent, messages, nil, "", false, 1, true, false)
+	AssertNoError(t, err, "RunAndStream")
+	var last map[string]interface{}
+	for chunk := range ch {
+		last = chunk
+	}
+	response, ok := last["response"].(*Response)
+	if !ok {
+		t.Fatalf("Expected the last chunk to carry the response, got %v", last)
+	}
+	AssertEqual(t, StopError, response.StopReason, "stop reason")
+	AssertEqual(t, true, errors.Is(response.Error, mockClient.Error), "response error")
+	AssertEqual(t, response.Error.Error(), last["error"], "error chunk")
+
+	_, err = swarm.Run(context.Background(), agent, messages, nil, "", true, false, 1, true, false)
+	AssertEqual(t, true, errors.Is(err, mockClient.Error), "streamed Run returns the error")
+}
+
+func TestRunAndStreamChunks(t *testing.T) {
+	stream := func(coalesce time.Duration) []*StreamChunk {
+		mockClient := NewMockOpenAIClient()
+		for _, delta := range []openai.ChatCompletionChunkChoiceDelta{
+			{Content: "Hel"},
+			{Content: "lo"},
+			{ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+				ID:       "call_1",
+				Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Name: "lookup", Arguments: `{"q":`},
+			}}},
+			{ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+				Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Arguments: `"go"}`},
+			}}},
+		} {
+			mockClient.AddStreamChunk(&openai.ChatCompletionChunk{
+				Choices: []openai.ChatCompletionChunkChoice{{Delta: delta}},
+			})
+		}
+		swarm := NewSwarm(mockClient).WithStreamCoalescing(coalesce)
+		ch, err := swarm.RunAndStream(context.Background(), NewAgent("TestAgent"), []map[string]interface{}{
+			{"role": "user", "content": "Hello"},
+		}, nil, "", false, 1, false, false)
+		AssertNoError(t, err, "RunAndStream")
+
+		var chunks []*StreamChunk
+		for chunk := range ch {
+			if delta, ok := chunk["chunk"].(*StreamChunk); ok {
+				chunks = append(chunks, delta)
+			}
+		}
+		return chunks
+	}
+
+	chunks := stream(0)
+	AssertEqual(t, 4, len(chunks), "one chunk per delta")
+	AssertEqual(t, StreamChunkContent, chunks[0].Type, "content chunk")
+	AssertEqual(t, "Hel", chunks[0].Delta, "first delta")
+	AssertEqual(t, "lo", chunks[1].Delta, "second delta")
+	AssertEqual(t, "TestAgent", chunks[1].Sender, "sender")
+	AssertEqual(t, StreamChunkToolCall, chunks[2].Type, "tool call chunk")
+	AssertEqual(t, "call_1", chunks[2].ToolCallDelta.ID, "tool call ID")
+	AssertEqual(t, "lookup", chunks[2].ToolCallDelta.Name, "tool name")
+	AssertEqual(t, `"go"}`, chunks[3].ToolCallDelta.Arguments, "arguments delta")
+
+	chunks = stream(time.Hour)
+	AssertEqual(t, 2, len(chunks), "coalesced chunks")
+	AssertEqual(t, "Hello", chunks[0].Delta, "coalesced content")
+	AssertEqual(t, `{"q":"go"}`, chunks[1].ToolCallDelta.Arguments, "coalesced arguments")
+	AssertEqual(t, "lookup", chunks[1].ToolCallDelta.Name, "coalesced tool name")
+}
+
+func TestChunkCoalescerFlushesAfterInterval(t *testing.T) {
+	emitted := make(chan *StreamChunk, 1)
+	coalescer := &chunkCoalescer{interval: 10 * time.Millisecond, emit: func(chunk *StreamChunk) { emitted <- chunk }}
+	coalescer.add(&StreamChunk{Type: StreamChunkContent, Delta: "Hel"})
+	coalescer.add(&StreamChunk{Type: StreamChunkContent, Delta: "lo"})
+
+	// No further delta arrives, so the merged chunk is emitted on its own
+	select {
+	case chunk := <-emitted:
+		AssertEqual(t, "Hello", chunk.Delta, "merged delta")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the held back chunk to be flushed after the interval")
+	}
+	coalescer.flush()
+	AssertEqual(t, 0, len(emitted), "nothing left to flush")
+}
+
+func TestRunAndStreamEmitsToolResults(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	swarm := NewSwarm(mockClient)
+	agent := NewAgent("TestAgent").AddFunction(NewAgentFunction("getWeather", "Get the weather",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "72°F", nil
+		},
+		[]Parameter{},
+	))
+	mockClient.AddStreamChunk(&openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{{
+			Delta: openai.ChatCompletionChunkChoiceDelta{
+				ToolCalls: []openai.ChatCompletionChunkChoiceDeltaToolCall{{
+					Function: openai.ChatCompletionChunkChoiceDeltaToolCallFunction{Name: "getWeather", Arguments: "{}"},
+				}},
+			},
+		}},
+	})
+
+	ch, err := swarm.RunAndStream(context.Background(), agent, []map[string]interface{}{
+		{"role": "user", "content": "Weather?"},
+	}, nil, "", false, 2, true, false)
+	AssertNoError(t, err, "RunAndStream")
+
+	var events []string
+	var first *ToolResult
+	for chunk := range ch {
+		if delim, ok := chunk["delim"].(string); ok {
+			events = append(events, delim)
+		}
+		if result, ok := chunk["tool_result"].(*ToolResult); ok {
+			events = append(events, "tool_result")
+			if first == nil {
+				first = result
+			}
+		}
+	}
+
+	AssertEqual(t, "start,end,tool_result", strings.Join(events, ","), "tool results precede the next turn")
+	if first == nil {
+		t.Fatal("expected a tool result")
+	}
+	AssertEqual(t, "getWeather", first.Name, "tool name")
+	AssertEqual(t, "{}", first.Arguments, "tool arguments")
+	AssertEqual(t, "72°F", first.Result, "tool result")
+	AssertEqual(t, "", first.Error, "tool error")
+}
+
+func TestHandleToolCallsRecordsErrors(t *testing.T) {
+	swarm := NewSwarm(NewMockOpenAIClient())
+	toolCalls := []openai.ChatCompletionMessageToolCall{
+		MockToolCall{ID: "1", Name: "missing", Args: "{}"}.ToOpenAI(),
+	}
+
+	response, err := swarm.handleToolCalls(context.Background(), toolCalls, []AgentFunction{}, nil, false)
+	AssertNoError(t, err, "handleToolCalls")
+	AssertEqual(t, 1, len(response.toolResults), "tool result count")
+	AssertEqual(t, true, strings.Contains(response.toolResults[0].Error, "not found"), "tool error")
+}
@@ -0,0 +1,208 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DebateTurn is one agent's answer in a debate round.
+type DebateTurn struct {
+	// Round is the debate round, from 1
+	Round int `json:"round"`
+	// Agent is the name of the agent that answered
+	Agent string `json:"agent"`
+	// Content is the agent's answer
+	Content string `json:"content"`
+}
+
+// DebateResult is the outcome of a debate.
+type DebateResult struct {
+	// Question is the debated question
+	Question string `json:"question"`
+	// Transcript lists every answer, by round and agent order
+	Transcript []DebateTurn `json:"transcript"`
+	// Answers maps each agent to its final answer
+	Answers map[string]string `json:"answers"`
+	// Winner is the name of the agent whose answer was chosen
+	Winner string `json:"winner"`
+	// Decision is the chosen answer, or the judge's reasoned verdict
+	Decision string `json:"decision"`
+}
+
+// VoteFunc picks the winning agent from the final answers and returns the
+// winner's name and the decision.
+type VoteFunc func(question string, answers map[string]string) (winner string, decision string, err error)
+
+// Debate has agents answer question independently, then revise their answers
+// over rounds after seeing each other's, and asks judge to pick the winner.
+// It is useful for validating high-stakes answers.
+//
+// Parameters:
+//   - ctx: The context for the debate
+//   - question: The question to debate
+//   - agents: The debating agents (at least two, with distinct names)
+//   - rounds: The number of answer rounds (at least 1)
+//   - judge: The agent that picks the winner
+//
+// Returns the transcript and decision, or an error if any agent fails.
+func (s *Swarm) Debate(ctx context.Context, question string, agents []*Agent, rounds int, judge *Agent) (*DebateResult, error) {
+	if judge == nil {
+		return nil, fmt.Errorf("%w: debate needs a judge", ErrInvalidParameter)
+	}
+	return s.debate(ctx, question, agents, rounds, func(question string, answers map[string]string) (string, string, error) {
+		return s.judgeDebate(ctx, judge, question, answers)
+	})
+}
+
+// DebateWithVote is like Debate but picks the winner with vote instead of a
+// judge agent, e.g. MajorityVote.
+func (s *Swarm) DebateWithVote(ctx context.Context, question string, agents []*Agent, rounds int, vote VoteFunc) (*DebateResult, error) {
+	if vote == nil {
+		return nil, fmt.Errorf("%w: debate needs a vote function", ErrInvalidParameter)
+	}
+	return s.debate(ctx, question, agents, rounds, vote)
+}
+
+// debate runs the rounds and applies vote to the final answers.
+func (s *Swarm) debate(ctx context.Context, question string, agents []*Agent, rounds int, vote VoteFunc) (*DebateResult, error) {
+	if len(agents) < 2 {
+		return nil, fmt.Errorf("%w: debate needs at least two agents", ErrInvalidParameter)
+	}
+	if rounds < 1 {
+		return nil, fmt.Errorf("%w: debate needs at least one round", ErrInvalidParameter)
+	}
+	seen := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		if seen[agent.Name] {
+			return nil, fmt.Errorf("%w: duplicate debater %q", ErrInvalidParameter, agent.Name)
+		}
+		seen[agent.Name] = true
+	}
+
+	result := &DebateResult{Question: question}
+	answers := make([]string, len(agents))
+	for round := 1; round <= rounds; round++ {
+		previous := answers
+		answers = make([]string, len(agents))
+
+		g, gctx := errgroup.WithContext(ctx)
+		for i, agent := range agents {
+			prompt := debatePrompt(question, agents, previous, i, round)
+			g.Go(func() error {
+				response, err := s.Run(gctx, agent, []map[string]interface{}{
+					{"role": "user", "content": prompt},
+				}, nil, "", false, false, 10, true, false)
+				if err != nil {
+					return fmt.Errorf("debater %s failed in round %d: %w", agent.Name, round, err)
+				}
+				answers[i] = lastContent(response)
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+
+		for i, agent := range agents {
+			result.Transcript = append(result.Transcript, DebateTurn{Round: round, Agent: agent.Name, Content: answers[i]})
+		}
+	}
+
+	result.Answers = make(map[string]string, len(agents))
+	for i, agent := range agents {
+		result.Answers[agent.Name] = answers[i]
+	}
+	winner, decision, err := vote(question, result.Answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decide debate: %w", err)
+	}
+	result.Winner = winner
+	result.Decision = decision
+	return result, nil
+}
+
+// debatePrompt builds the prompt for agent i: the bare question in the first
+// round, and the question with the other agents' previous answers afterwards.
+func debatePrompt(question string, agents []*Agent, previous []string, i, round int) string {
+	if round == 1 {
+		return question
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question: %s\n\nYour previous answer:\n%s\n\nOther answers:\n", question, previous[i])
+	for j, agent := range agents {
+		if j != i {
+			fmt.Fprintf(&b, "- %s: %s\n", agent.Name, previous[j])
+		}
+	}
+	b.WriteString("\nCritique the other answers and give your revised answer.")
+	return b.String()
+}
+
+// judgeDebate asks judge to pick the best answer.
+func (s *Swarm) judgeDebate(ctx context.Context, judge *Agent, question string, answers map[string]string) (string, string, error) {
+	names := make([]string, 0, len(answers))
+	for name := range answers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question: %s\n\nAnswers:\n", question)
+	for _, name := range names {
+		fmt.Fprintf(&b, "- %s: %s\n", name, answers[name])
+	}
+	b.WriteString("\nPick the best answer. ")
+	b.WriteString(`Reply with a JSON object: {"winner": <agent name>, "decision": <the final answer>}.`)
+
+	response, err := s.Run(ctx, judge, []map[string]interface{}{
+		{"role": "user", "content": b.String()},
+	}, nil, "", false, false, 10, true, true)
+	if err != nil {
+		return "", "", err
+	}
+	var verdict struct {
+		Winner   string `json:"winner"`
+		Decision string `json:"decision"`
+	}
+	if err := parseJSONReply(lastContent(response), &verdict); err != nil {
+		return "", "", err
+	}
+	if _, ok := answers[verdict.Winner]; !ok {
+		return "", "", fmt.Errorf("judge picked unknown debater %q", verdict.Winner)
+	}
+	if verdict.Decision == "" {
+		verdict.Decision = answers[verdict.Winner]
+	}
+	return verdict.Winner, verdict.Decision, nil
+}
+
+// MajorityVote picks the most common final answer, comparing answers
+// case-insensitively after trimming whitespace. Ties go to the agent whose
+// name sorts first.
+func MajorityVote(_ string, answers map[string]string) (string, string, error) {
+	if len(answers) == 0 {
+		return "", "", fmt.Errorf("no answers to vote on")
+	}
+	names := make([]string, 0, len(answers))
+	for name := range answers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counts := make(map[string]int, len(answers))
+	for _, name := range names {
+		counts[strings.ToLower(strings.TrimSpace(answers[name]))]++
+	}
+	winner := names[0]
+	best := 0
+	for _, name := range names {
+		if n := counts[strings.ToLower(strings.TrimSpace(answers[name]))]; n > best {
+			winner, best = name, n
+		}
+	}
+	return winner, answers[winner], nil
+}
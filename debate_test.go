@@ -0,0 +1,54 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDebate(t *testing.T) {
+	client := newRouterClient(func(prompt string) string {
+		switch {
+		case strings.HasPrefix(prompt, "Question: 2+2") && strings.Contains(prompt, "Pick the best answer"):
+			return `{"winner": "bob", "decision": "4"}`
+		case strings.Contains(prompt, "Critique"):
+			return "4"
+		default:
+			return "5"
+		}
+	})
+	s := NewSwarm(client)
+	alice, bob := NewAgent("alice"), NewAgent("bob")
+
+	result, err := s.Debate(context.Background(), "2+2?", []*Agent{alice, bob}, 2, NewAgent("judge"))
+	AssertNoError(t, err, "Debate")
+	AssertEqual(t, 4, len(result.Transcript), "transcript length")
+	AssertEqual(t, DebateTurn{Round: 1, Agent: "alice", Content: "5"}, result.Transcript[0], "first answer")
+	AssertEqual(t, DebateTurn{Round: 2, Agent: "bob", Content: "4"}, result.Transcript[3], "revised answer")
+	AssertEqual(t, "4", result.Answers["alice"], "final answers")
+	AssertEqual(t, "bob", result.Winner, "winner")
+	AssertEqual(t, "4", result.Decision, "decision")
+}
+
+func TestDebateWithVote(t *testing.T) {
+	client := newRouterClient(func(prompt string) string { return "Paris" })
+	s := NewSwarm(client)
+	agents := []*Agent{NewAgent("a"), NewAgent("b"), NewAgent("c")}
+
+	result, err := s.DebateWithVote(context.Background(), "Capital of France?", agents, 1, MajorityVote)
+	AssertNoError(t, err, "DebateWithVote")
+	AssertEqual(t, "a", result.Winner, "tie goes to first name")
+	AssertEqual(t, "Paris", result.Decision, "decision")
+
+	_, err = s.DebateWithVote(context.Background(), "q", agents[:1], 1, MajorityVote)
+	AssertError(t, err, "Expected error for a single debater")
+	_, err = s.Debate(context.Background(), "q", []*Agent{NewAgent("a"), NewAgent("a")}, 1, NewAgent("judge"))
+	AssertError(t, err, "Expected error for duplicate debaters")
+}
+
+func TestMajorityVote(t *testing.T) {
+	winner, decision, err := MajorityVote("q", map[string]string{"a": "no", "b": " Yes", "c": "yes "})
+	AssertNoError(t, err, "MajorityVote")
+	AssertEqual(t, "b", winner, "winner")
+	AssertEqual(t, " Yes", decision, "decision")
+}
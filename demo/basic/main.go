@@ -1,13 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"reflect"
 
 	"github.com/feiskyer/swarm-go"
 )
 
 func main() {
+	// A -p "prompt" argument is answered non-interactively
+	var prompt string
+	flag.StringVar(&prompt, "p", "", "answer the prompt and exit")
+	flag.StringVar(&prompt, "prompt", "", "answer the prompt and exit")
+	flag.Parse()
+
 	// Create a new agent
 	agent := swarm.NewAgent("Assistant").WithModel("gpt-4o").
 		WithInstructions("You are a helpful assistant.")
@@ -30,5 +38,5 @@ func main() {
 	agent.AddFunction(weatherFunc)
 
 	// Run the demo loop
-	swarm.RunDemoLoop(agent, nil, false, false)
+	os.Exit(swarm.RunDemoLoop(agent, swarm.ReplOptions{Prompt: prompt}))
 }
@@ -201,8 +201,8 @@ func main() {
 		return
 	}
 
-	if result != nil {
-		data := result.(*JokeEvent)
+	if result.Value != nil {
+		data := result.Value.(*JokeEvent)
 		fmt.Printf("Topic: %s\n\n", data.Topic)
 		fmt.Printf("Joke: %s\n\n", data.Joke)
 		fmt.Printf("Critique: %s\n\n", data.Critique)
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"sort"
 	"strings"
 	"time"
 
@@ -25,6 +24,12 @@ type OutlineEvent struct {
 	Chapters []string `json:"chapters"`
 }
 
+func init() {
+	// Allow outline and chapter events to be rebuilt from persisted JSON
+	swarm.RegisterEventType[OutlineEvent](EventOutline)
+	swarm.RegisterEventType[ChapterEvent](EventChapter)
+}
+
 // NewOutlineEvent creates a new OutlineEvent
 func NewOutlineEvent(topic string, chapters []string) *OutlineEvent {
 	return swarm.NewEvent(EventOutline, OutlineEvent{
@@ -195,17 +200,12 @@ func handleWriteChapter(ctx *swarm.Context, event swarm.Event, client *swarm.Swa
 	ctx.Set(fmt.Sprintf("chapter_%d", writeTask.Chapter), chapterContent)
 
 	// Create chapter event
-	chapterEvent := NewChapterEvent(writeTask.Title, chapterContent)
-	chapterEvent.BaseEvent.SetData(map[string]interface{}{
-		"chapter": writeTask.Chapter,
-	})
-	return chapterEvent, nil
+	return NewChapterEvent(writeTask.Title, chapterContent), nil
 }
 
 func handleOutlineEventResult(ctx *swarm.Context, event swarm.Event) (swarm.Event, error) {
 	// Check if it's a parallel result event
 	if resultEvent, ok := event.(*swarm.ParallelResultEvent); ok {
-		results := resultEvent.GetResults()
 		errors := resultEvent.GetErrors()
 
 		if len(errors) > 0 {
@@ -215,54 +215,14 @@ func handleOutlineEventResult(ctx *swarm.Context, event swarm.Event) (swarm.Even
 			}
 		}
 
-		// Create ordered array of chapters
-		orderedChapters := make([]struct {
-			Title   string
-			Content string
-			Chapter int
-		}, 0, len(results))
-
-		// Collect chapters with their chapter numbers
-		for _, result := range results {
-			if chapterEvent, ok := result.(*ChapterEvent); ok {
-				data := chapterEvent.Data()
-				if chapterVal, ok := data["chapter"].(int); ok {
-					orderedChapters = append(orderedChapters, struct {
-						Title   string
-						Content string
-						Chapter int
-					}{
-						Title:   chapterEvent.Title,
-						Content: chapterEvent.Content,
-						Chapter: chapterVal,
-					})
-				} else if chapterFloat, ok := data["chapter"].(float64); ok {
-					orderedChapters = append(orderedChapters, struct {
-						Title   string
-						Content string
-						Chapter int
-					}{
-						Title:   chapterEvent.Title,
-						Content: chapterEvent.Content,
-						Chapter: int(chapterFloat),
-					})
-				} else {
-					fmt.Printf("Warning: Chapter %s missing chapter number (type: %T)\n", chapterEvent.Title, data["chapter"])
-				}
-			}
-		}
-
-		// Sort chapters by their chapter number
-		sort.Slice(orderedChapters, func(i, j int) bool {
-			return orderedChapters[i].Chapter < orderedChapters[j].Chapter
-		})
-
-		// Create the final chapters map with ordered titles
+		// Collect the chapters in outline order
 		chapters := make(map[string]string)
 		var chapterTitles []string
-		for _, chapter := range orderedChapters {
-			chapters[chapter.Title] = chapter.Content
-			chapterTitles = append(chapterTitles, chapter.Title)
+		for _, result := range resultEvent.OrderedResults() {
+			if chapterEvent, ok := result.(*ChapterEvent); ok {
+				chapters[chapterEvent.Title] = chapterEvent.Content
+				chapterTitles = append(chapterTitles, chapterEvent.Title)
+			}
 		}
 
 		topicVal, ok := ctx.Get("topic")
@@ -375,8 +335,8 @@ func main() {
 	}
 
 	// Output the final novel
-	if result != nil {
-		data := result.(map[string]interface{})
+	if result.Value != nil {
+		data := result.Value.(map[string]interface{})
 		fmt.Printf("\nNovel about: %s\n\n", data["topic"])
 
 		chapters := data["chapters"].(map[string]string)
@@ -390,7 +350,7 @@ func main() {
 }
 
 func trackSteps(handler *swarm.WorkflowHandler) {
-	for event := range handler.Stream() {
+	for event := range handler.StreamFiltered(EventOutline, EventChapter, swarm.EventParallelResult) {
 		switch event.Type() {
 		case EventOutline:
 			if outline, ok := event.(*OutlineEvent); ok {
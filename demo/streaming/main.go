@@ -1,13 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"reflect"
 
 	"github.com/feiskyer/swarm-go"
 )
 
 func main() {
+	// A -p "prompt" argument is answered non-interactively
+	var prompt string
+	flag.StringVar(&prompt, "p", "", "answer the prompt and exit")
+	flag.StringVar(&prompt, "prompt", "", "answer the prompt and exit")
+	flag.Parse()
+
 	// Create a new agent
 	agent := swarm.NewAgent("Assistant")
 	agent.WithModel("gpt-4o").
@@ -31,5 +39,5 @@ func main() {
 	))
 
 	// Run the demo loop
-	swarm.RunDemoLoop(agent, nil, true, false)
+	os.Exit(swarm.RunDemoLoop(agent, swarm.ReplOptions{Stream: true, Prompt: prompt}))
 }
@@ -0,0 +1,268 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	// ErrEngineClosed is returned when submitting runs to a closed WorkflowEngine.
+	ErrEngineClosed = errors.New("workflow engine closed")
+	// ErrRunNotFound is returned for unknown run IDs.
+	ErrRunNotFound = errors.New("workflow run not found")
+	// ErrRunActive is returned when removing a run that has not finished.
+	ErrRunActive = errors.New("workflow run is still active")
+)
+
+// EngineConfig configures a WorkflowEngine.
+type EngineConfig struct {
+	// MaxConcurrentRuns limits the runs executing at once, further runs
+	// queue until a slot is free (default unlimited)
+	MaxConcurrentRuns int `yaml:"max_concurrent_runs" json:"max_concurrent_runs"`
+	// LLMRequestsPerSecond limits the chat completion requests of all runs
+	// together (default unlimited)
+	LLMRequestsPerSecond float64 `yaml:"llm_requests_per_second" json:"llm_requests_per_second"`
+	// LLMBurst is the number of requests allowed at once above the rate
+	// (default 1)
+	LLMBurst int `yaml:"llm_burst" json:"llm_burst"`
+}
+
+// WorkflowEngine runs many workflows concurrently within global limits and
+// keeps track of the runs by ID. It is safe for concurrent use.
+//
+// The LLM request limit applies to the Swarm runs of steps that pass their
+// Context's context.Context (ctx.Context()) to Swarm.Run.
+type WorkflowEngine struct {
+	config  EngineConfig
+	slots   *semaphore.Weighted
+	limiter *RateLimiter
+	runs    map[string]*EngineRun
+	closed  bool
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// NewWorkflowEngine creates an engine with the given limits.
+func NewWorkflowEngine(config EngineConfig) *WorkflowEngine {
+	engine := &WorkflowEngine{
+		config: config,
+		runs:   make(map[string]*EngineRun),
+	}
+	if config.MaxConcurrentRuns > 0 {
+		engine.slots = semaphore.NewWeighted(int64(config.MaxConcurrentRuns))
+	}
+	if config.LLMRequestsPerSecond > 0 {
+		engine.limiter = NewRateLimiter(config.LLMRequestsPerSecond, config.LLMBurst)
+	}
+	return engine
+}
+
+// Submit queues a run of workflow with inputs and returns it without waiting
+// for it to start. The run stops when ctx is done or it is cancelled.
+func (e *WorkflowEngine) Submit(ctx context.Context, workflow *Workflow, inputs map[string]interface{}) (*EngineRun, error) {
+	if workflow == nil {
+		return nil, fmt.Errorf("%w: workflow is nil", ErrInvalidParameter)
+	}
+	if inputs == nil {
+		inputs = make(map[string]interface{})
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &EngineRun{
+		ID:          NewID("run-"),
+		Workflow:    workflow.config.Name,
+		SubmittedAt: time.Now(),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		cancel()
+		return nil, ErrEngineClosed
+	}
+	e.runs[run.ID] = run
+	e.wg.Add(1)
+	e.mu.Unlock()
+
+	go e.execute(runCtx, run, workflow, inputs)
+	return run, nil
+}
+
+// execute waits for a slot and runs the workflow.
+func (e *WorkflowEngine) execute(ctx context.Context, run *EngineRun, workflow *Workflow, inputs map[string]interface{}) {
+	defer e.wg.Done()
+	defer run.cancel()
+
+	if e.slots != nil {
+		if err := e.slots.Acquire(ctx, 1); err != nil {
+			run.finish(WorkflowStatusCancelled, err)
+			return
+		}
+		defer e.slots.Release(1)
+	}
+
+	ctx = WithRequestLimiter(ctx, e.limiter)
+	handler, err := workflow.run(ctx, []Event{NewStartEvent(inputs)}, nil, WithRunID(run.ID))
+	if err != nil {
+		run.finish(WorkflowStatusFailed, err)
+		return
+	}
+	run.start(handler)
+
+	// Hold the slot until the steps of a failed run have finished too
+	_, err = handler.Wait()
+	<-handler.doneChan
+	run.mu.Lock()
+	run.result, run.err = handler.workflowResult(), err
+	run.mu.Unlock()
+	close(run.done)
+}
+
+// Get returns the run with id.
+func (e *WorkflowEngine) Get(id string) (*EngineRun, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	run, ok := e.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return run, nil
+}
+
+// List returns the runs in the order they were submitted.
+func (e *WorkflowEngine) List() []*EngineRun {
+	e.mu.Lock()
+	runs := make([]*EngineRun, 0, len(e.runs))
+	for _, run := range e.runs {
+		runs = append(runs, run)
+	}
+	e.mu.Unlock()
+	sort.Slice(runs, func(i, j int) bool { return runs[i].SubmittedAt.Before(runs[j].SubmittedAt) })
+	return runs
+}
+
+// Cancel stops the run with id, whether queued or running.
+func (e *WorkflowEngine) Cancel(id string) error {
+	run, err := e.Get(id)
+	if err != nil {
+		return err
+	}
+	run.Cancel()
+	return nil
+}
+
+// Remove forgets the finished run with id, returning ErrRunActive for runs
+// that have not finished.
+func (e *WorkflowEngine) Remove(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	run, ok := e.runs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	select {
+	case <-run.done:
+	default:
+		return fmt.Errorf("%w: %s", ErrRunActive, id)
+	}
+	delete(e.runs, id)
+	return nil
+}
+
+// Close rejects further runs, cancels the runs in progress and waits for
+// them to finish.
+func (e *WorkflowEngine) Close() {
+	e.mu.Lock()
+	e.closed = true
+	runs := make([]*EngineRun, 0, len(e.runs))
+	for _, run := range e.runs {
+		runs = append(runs, run)
+	}
+	e.mu.Unlock()
+
+	for _, run := range runs {
+		run.Cancel()
+	}
+	e.wg.Wait()
+}
+
+// EngineRun is a workflow run submitted to a WorkflowEngine.
+type EngineRun struct {
+	// ID is the run ID, also carried by the run's Context and events
+	ID string
+	// Workflow is the name of the workflow
+	Workflow string
+	// SubmittedAt is when the run was submitted
+	SubmittedAt time.Time
+
+	cancel  context.CancelFunc
+	done    chan struct{}
+	handler *WorkflowHandler
+	result  *WorkflowResult
+	err     error
+	mu      sync.Mutex
+}
+
+// start records the handler of a run that left the queue.
+func (r *EngineRun) start(handler *WorkflowHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handler = handler
+}
+
+// finish records the outcome of a run that never started.
+func (r *EngineRun) finish(status WorkflowStatus, err error) {
+	r.mu.Lock()
+	r.result = &WorkflowResult{RunID: r.ID, Status: status, StartedAt: r.SubmittedAt}
+	r.err = err
+	r.mu.Unlock()
+	close(r.done)
+}
+
+// Handler returns the handler of the run, or nil while it is queued.
+func (r *EngineRun) Handler() *WorkflowHandler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.handler
+}
+
+// Status returns the status of the run, WorkflowStatusPending while queued.
+func (r *EngineRun) Status() WorkflowStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case r.result != nil:
+		return r.result.Status
+	case r.handler != nil:
+		return r.handler.Status()
+	default:
+		return WorkflowStatusPending
+	}
+}
+
+// Wait blocks until the run has finished and returns its result, with the
+// error that failed the run, if any.
+func (r *EngineRun) Wait() (*WorkflowResult, error) {
+	<-r.done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result, r.err
+}
+
+// Done returns a channel closed when the run has finished.
+func (r *EngineRun) Done() <-chan struct{} {
+	return r.done
+}
+
+// Cancel stops the run, whether queued or running.
+func (r *EngineRun) Cancel() {
+	r.cancel()
+}
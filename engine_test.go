@@ -0,0 +1,128 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkflowEngineConcurrency(t *testing.T) {
+	var active, peak atomic.Int32
+	workflow := NewWorkflow("concurrent")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return NewStopEvent(ctx.RunID()), nil
+	}, StepConfig{}))
+
+	engine := NewWorkflowEngine(EngineConfig{MaxConcurrentRuns: 2})
+	defer engine.Close()
+	var runs []*EngineRun
+	for i := 0; i < 5; i++ {
+		run, err := engine.Submit(context.Background(), workflow, nil)
+		AssertNoError(t, err, "submit")
+		runs = append(runs, run)
+	}
+
+	for _, run := range runs {
+		result, err := run.Wait()
+		AssertNoError(t, err, "wait")
+		AssertEqual(t, WorkflowStatusComplete, result.Status, "status")
+		AssertEqual(t, run.ID, result.Value, "run ID in the workflow context")
+		AssertEqual(t, run.ID, result.RunID, "result run ID")
+	}
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent runs, got %d", peak.Load())
+	}
+
+	listed := engine.List()
+	AssertEqual(t, 5, len(listed), "listed runs")
+	AssertEqual(t, runs[0].ID, listed[0].ID, "first listed run")
+	run, err := engine.Get(runs[3].ID)
+	AssertNoError(t, err, "get")
+	AssertEqual(t, "concurrent", run.Workflow, "workflow name")
+}
+
+func TestWorkflowEngineCancel(t *testing.T) {
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	workflow := NewWorkflow("blocking")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Context().Done():
+		}
+		return NewStopEvent("released"), nil
+	}, StepConfig{}))
+
+	engine := NewWorkflowEngine(EngineConfig{MaxConcurrentRuns: 1})
+	first, err := engine.Submit(context.Background(), workflow, nil)
+	AssertNoError(t, err, "submit first")
+	<-started
+	queued, err := engine.Submit(context.Background(), workflow, nil)
+	AssertNoError(t, err, "submit queued")
+	time.Sleep(20 * time.Millisecond)
+	AssertEqual(t, WorkflowStatusPending, queued.Status(), "queued status")
+	if queued.Handler() != nil {
+		t.Error("Expected no handler for a queued run")
+	}
+
+	// Cancelling a queued run never starts it
+	AssertNoError(t, engine.Cancel(queued.ID), "cancel")
+	result, err := queued.Wait()
+	AssertEqual(t, true, errors.Is(err, context.Canceled), "Expected context.Canceled")
+	AssertEqual(t, WorkflowStatusCancelled, result.Status, "cancelled status")
+
+	AssertEqual(t, true, errors.Is(engine.Remove(first.ID), ErrRunActive), "Expected ErrRunActive")
+	close(release)
+	value, err := first.Wait()
+	AssertNoError(t, err, "first run")
+	AssertEqual(t, "released", value.Value, "first result")
+
+	AssertNoError(t, engine.Remove(first.ID), "remove")
+	_, err = engine.Get(first.ID)
+	AssertEqual(t, true, errors.Is(err, ErrRunNotFound), "Expected ErrRunNotFound")
+	AssertEqual(t, true, errors.Is(engine.Cancel("run-unknown"), ErrRunNotFound), "Expected ErrRunNotFound")
+
+	engine.Close()
+	_, err = engine.Submit(context.Background(), workflow, nil)
+	AssertEqual(t, ErrEngineClosed, err, "submit after close")
+}
+
+func TestWorkflowEngineLLMRateLimit(t *testing.T) {
+	client := newScriptedClient("one", "two", "three")
+	swarm := NewSwarm(client)
+	agent := NewAgent("agent")
+	workflow := NewWorkflow("limited")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		for i := 0; i < 3; i++ {
+			if _, err := swarm.Run(ctx.Context(), agent, []map[string]interface{}{
+				{"role": "user", "content": "hi"},
+			}, nil, "", false, false, 1, true, false); err != nil {
+				return nil, err
+			}
+		}
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	engine := NewWorkflowEngine(EngineConfig{LLMRequestsPerSecond: 20})
+	defer engine.Close()
+	start := time.Now()
+	run, err := engine.Submit(context.Background(), workflow, nil)
+	AssertNoError(t, err, "submit")
+	_, err = run.Wait()
+	AssertNoError(t, err, "wait")
+	AssertEqual(t, 3, len(client.requests), "requests")
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected the requests to be paced at 20/s, finished after %v", elapsed)
+	}
+}
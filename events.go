@@ -1,6 +1,8 @@
 package swarm
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -27,6 +29,10 @@ const (
 	EventParallel EventType = "ParallelEvent"
 	// EventParallelResult represents the aggregated results from parallel task execution
 	EventParallelResult EventType = "ParallelResultEvent"
+	// EventProgress reports the progress of a long-running step
+	EventProgress EventType = "ProgressEvent"
+	// EventDelay schedules another event to be dispatched after a delay
+	EventDelay EventType = "DelayEvent"
 )
 
 // EventValidator defines the interface for validating event data.
@@ -52,11 +58,44 @@ type Event interface {
 	Validate() error
 }
 
+// EventMetadata carries tracing identifiers for an event. It is populated
+// automatically by the workflow engine so causal chains can be reconstructed
+// across parallel branches.
+type EventMetadata struct {
+	// RunID identifies the workflow run that produced the event
+	RunID string `json:"run_id,omitempty"`
+	// EventID uniquely identifies the event
+	EventID string `json:"event_id,omitempty"`
+	// CausationID is the EventID of the event that triggered this one
+	CausationID string `json:"causation_id,omitempty"`
+	// Timestamp is the time the event was sent
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// MetadataCarrier is implemented by events that carry EventMetadata.
+// BaseEvent implements it, so every event embedding BaseEvent does too.
+type MetadataCarrier interface {
+	// Metadata returns the event's tracing metadata
+	Metadata() EventMetadata
+	// SetMetadata replaces the event's tracing metadata
+	SetMetadata(meta EventMetadata)
+}
+
+// NewID returns a random 128-bit hex identifier with the given prefix.
+func NewID(prefix string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + hex.EncodeToString(b)
+}
+
 // BaseEvent provides common functionality for all event types.
 // It implements the basic Event interface and can be embedded in specific event types.
 type BaseEvent struct {
 	eventType EventType
 	data      map[string]interface{}
+	meta      EventMetadata
 }
 
 // NewBaseEvent creates a new BaseEvent with the given event type and data.
@@ -131,6 +170,36 @@ func (e *BaseEvent) Get(key string) interface{} {
 	return e.data[key]
 }
 
+// Metadata returns the event's tracing metadata.
+func (e *BaseEvent) Metadata() EventMetadata {
+	return e.meta
+}
+
+// SetMetadata replaces the event's tracing metadata.
+func (e *BaseEvent) SetMetadata(meta EventMetadata) {
+	e.meta = meta
+}
+
+// RunID returns the ID of the workflow run that produced the event.
+func (e *BaseEvent) RunID() string {
+	return e.meta.RunID
+}
+
+// EventID returns the unique ID of the event.
+func (e *BaseEvent) EventID() string {
+	return e.meta.EventID
+}
+
+// CausationID returns the ID of the event that triggered this event.
+func (e *BaseEvent) CausationID() string {
+	return e.meta.CausationID
+}
+
+// Timestamp returns the time the event was sent.
+func (e *BaseEvent) Timestamp() time.Time {
+	return e.meta.Timestamp
+}
+
 // Validate validates the base event
 func (e *BaseEvent) Validate() error {
 	if e.Type() == "" {
@@ -184,6 +253,12 @@ func NewStopEvent(result interface{}) *StopEvent {
 	}
 }
 
+// NewTypedStopEvent creates a new StopEvent whose result is statically typed as T.
+// Pair it with WaitAs[T] to retrieve the result without type assertions.
+func NewTypedStopEvent[T any](result T) *StopEvent {
+	return NewStopEvent(result)
+}
+
 // Validate checks if the StopEvent is properly configured.
 func (e *StopEvent) Validate() error {
 	if err := e.BaseEvent.Validate(); err != nil {
@@ -195,6 +270,77 @@ func (e *StopEvent) Validate() error {
 	return nil
 }
 
+// ProgressEvent reports how far a long-running step has progressed.
+// Progress events are delivered to stream subscribers only; they are not routed
+// to steps.
+type ProgressEvent struct {
+	BaseEvent
+	// StepName is the name of the step reporting progress
+	StepName string `json:"step_name"`
+	// Percent is the completion percentage between 0 and 100
+	Percent float64 `json:"percent"`
+	// Message optionally describes the current activity
+	Message string `json:"message,omitempty"`
+}
+
+// NewProgressEvent creates a new ProgressEvent for the given step.
+func NewProgressEvent(stepName string, percent float64, message string) *ProgressEvent {
+	return &ProgressEvent{
+		BaseEvent: BaseEvent{
+			eventType: EventProgress,
+		},
+		StepName: stepName,
+		Percent:  percent,
+		Message:  message,
+	}
+}
+
+// Validate checks if the ProgressEvent is properly configured.
+func (e *ProgressEvent) Validate() error {
+	if err := e.BaseEvent.Validate(); err != nil {
+		return err
+	}
+	if e.Percent < 0 || e.Percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100, got %v", e.Percent)
+	}
+	return nil
+}
+
+// DelayEvent asks the workflow engine to dispatch Next once Delay has elapsed.
+// Pending delays are discarded when the workflow finishes.
+type DelayEvent struct {
+	BaseEvent
+	// Delay is how long to wait before dispatching Next
+	Delay time.Duration `json:"delay"`
+	// Next is the event dispatched after the delay
+	Next Event `json:"-"`
+}
+
+// NewDelayEvent creates a new DelayEvent that dispatches next after duration.
+func NewDelayEvent(duration time.Duration, next Event) *DelayEvent {
+	return &DelayEvent{
+		BaseEvent: BaseEvent{
+			eventType: EventDelay,
+		},
+		Delay: duration,
+		Next:  next,
+	}
+}
+
+// Validate checks if the DelayEvent is properly configured.
+func (e *DelayEvent) Validate() error {
+	if err := e.BaseEvent.Validate(); err != nil {
+		return err
+	}
+	if e.Next == nil {
+		return fmt.Errorf("next event is required")
+	}
+	if e.Delay < 0 {
+		return fmt.Errorf("delay must not be negative")
+	}
+	return e.Next.Validate()
+}
+
 // ErrorEvent represents an error in the workflow
 type ErrorEvent struct {
 	BaseEvent
@@ -202,6 +348,9 @@ type ErrorEvent struct {
 	StepName  string `json:"step_name,omitempty"`
 	TaskID    string `json:"task_id,omitempty"`
 	Retriable bool   `json:"retriable"`
+
+	// handled is set once the error has been routed to error handler steps
+	handled bool
 }
 
 // NewErrorEvent creates a new ErrorEvent with the given error.
@@ -277,6 +426,11 @@ type Task struct {
 	Priority int `json:"priority"`
 	// Timeout specifies the maximum duration allowed for task execution
 	Timeout time.Duration `json:"timeout"`
+	// IdempotencyKey identifies the task across attempts of the same run,
+	// including runs restored with its run ID. Tasks whose key is recorded
+	// as completed in WorkflowConfig.TaskStore are skipped and their stored
+	// result is reused.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // NewTask creates a new task with default values
@@ -303,6 +457,12 @@ func (t Task) WithTimeout(timeout time.Duration) Task {
 	return t
 }
 
+// WithIdempotencyKey sets the task idempotency key and returns the task.
+func (t Task) WithIdempotencyKey(key string) Task {
+	t.IdempotencyKey = key
+	return t
+}
+
 // Validate validates the task configuration
 func (t Task) Validate() error {
 	if t.ID == "" {
@@ -319,6 +479,18 @@ type ParallelEvent struct {
 	BaseEvent
 	Tasks      []Task `json:"tasks"`
 	SourceStep string `json:"source_step"` // Name of the step that generated this parallel event
+	// MaxParallel limits the concurrent tasks of the event, overriding
+	// WorkflowConfig.MaxParallelTasks
+	MaxParallel int `json:"max_parallel,omitempty"`
+	// Order lists the task IDs in submission order, as Tasks are sorted by
+	// priority (default the order of Tasks)
+	Order []string `json:"order,omitempty"`
+	// FailFast cancels the remaining tasks when a task fails and fails the
+	// workflow instead of passing the results downstream
+	FailFast bool `json:"fail_fast,omitempty"`
+	// MinSuccessRate fails the workflow when fewer than this fraction of the
+	// tasks succeed (default 0, always pass the results downstream)
+	MinSuccessRate float64 `json:"min_success_rate,omitempty"`
 }
 
 // NewParallelEvent creates a new ParallelEvent with the given tasks and source step.
@@ -333,9 +505,13 @@ func NewParallelEvent(tasks []Task, sourceStep string) (*ParallelEvent, error) {
 	// Sort tasks by priority (higher priority first)
 	sortedTasks := make([]Task, len(tasks))
 	copy(sortedTasks, tasks)
-	sort.Slice(sortedTasks, func(i, j int) bool {
+	sort.SliceStable(sortedTasks, func(i, j int) bool {
 		return sortedTasks[i].Priority > sortedTasks[j].Priority
 	})
+	order := make([]string, len(tasks))
+	for i, task := range tasks {
+		order[i] = task.ID
+	}
 
 	return &ParallelEvent{
 		BaseEvent: BaseEvent{
@@ -343,9 +519,22 @@ func NewParallelEvent(tasks []Task, sourceStep string) (*ParallelEvent, error) {
 		},
 		Tasks:      sortedTasks,
 		SourceStep: sourceStep,
+		Order:      order,
 	}, nil
 }
 
+// order returns the task IDs in submission order.
+func (e *ParallelEvent) order() []string {
+	if len(e.Order) > 0 {
+		return e.Order
+	}
+	order := make([]string, len(e.Tasks))
+	for i, task := range e.Tasks {
+		order[i] = task.ID
+	}
+	return order
+}
+
 // Validate checks if the ParallelEvent is properly configured.
 func (e *ParallelEvent) Validate() error {
 	if err := e.BaseEvent.Validate(); err != nil {
@@ -354,6 +543,12 @@ func (e *ParallelEvent) Validate() error {
 	if len(e.Tasks) == 0 {
 		return fmt.Errorf("at least one task is required")
 	}
+	if e.MaxParallel < 0 {
+		return fmt.Errorf("max parallel must not be negative")
+	}
+	if e.MinSuccessRate < 0 || e.MinSuccessRate > 1 {
+		return fmt.Errorf("min success rate must be between 0 and 1")
+	}
 	for _, task := range e.Tasks {
 		if err := task.Validate(); err != nil {
 			return fmt.Errorf("invalid task %s: %w", task.ID, err)
@@ -362,11 +557,67 @@ func (e *ParallelEvent) Validate() error {
 	return nil
 }
 
+// WithMaxParallel limits the concurrent tasks of the event and returns the
+// event.
+func (e *ParallelEvent) WithMaxParallel(n int) *ParallelEvent {
+	e.MaxParallel = n
+	return e
+}
+
+// WithFailFast cancels the remaining tasks when a task fails and returns the
+// event.
+func (e *ParallelEvent) WithFailFast() *ParallelEvent {
+	e.FailFast = true
+	return e
+}
+
+// WithMinSuccessRate fails the workflow when fewer than rate (0 to 1) of the
+// tasks succeed and returns the event.
+func (e *ParallelEvent) WithMinSuccessRate(rate float64) *ParallelEvent {
+	e.MinSuccessRate = rate
+	return e
+}
+
+// checkOutcome returns an error wrapping ErrParallelTasksFailed if the
+// failures of the tasks fail the workflow.
+func (e *ParallelEvent) checkOutcome(failures map[string]error, firstFailure string) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	if e.FailFast && firstFailure != "" {
+		return fmt.Errorf("%w: task %s failed: %w", ErrParallelTasksFailed, firstFailure, failures[firstFailure])
+	}
+	succeeded := len(e.Tasks) - len(failures)
+	if e.MinSuccessRate > 0 && float64(succeeded) < e.MinSuccessRate*float64(len(e.Tasks)) {
+		return fmt.Errorf("%w: %d of %d tasks succeeded, below the minimum success rate %g",
+			ErrParallelTasksFailed, succeeded, len(e.Tasks), e.MinSuccessRate)
+	}
+	return nil
+}
+
 // GetTasks returns the tasks to be executed in parallel.
 func (e *ParallelEvent) GetTasks() []Task {
 	return e.Tasks
 }
 
+// TaskMetadata describes the execution of a parallel task.
+type TaskMetadata struct {
+	// Type is the task type
+	Type EventType `json:"type"`
+	// Priority is the task priority
+	Priority int `json:"priority"`
+	// Status is the final status of the task
+	Status TaskStatus `json:"status"`
+	// Duration is how long the task ran, excluding the wait for a slot
+	Duration time.Duration `json:"duration"`
+	// Attempts counts the step attempts of the task, including retries
+	Attempts int `json:"attempts"`
+	// Error is the error that failed the task, if any
+	Error string `json:"error,omitempty"`
+	// Usage totals the requests of the agent runs made by the task's steps
+	Usage Usage `json:"usage"`
+}
+
 // ParallelResultEvent represents the results of parallel execution
 type ParallelResultEvent struct {
 	BaseEvent
@@ -376,6 +627,12 @@ type ParallelResultEvent struct {
 	Failed     int                    `json:"failed"`
 	Duration   time.Duration          `json:"duration"`
 	SourceStep string                 `json:"source_step"` // Name of the step that generated the original parallel event
+	// Order lists the task IDs in submission order
+	Order []string `json:"order,omitempty"`
+	// Tasks describes the execution of each task by ID
+	Tasks map[string]TaskMetadata `json:"tasks,omitempty"`
+	// Usage totals the usage of the tasks
+	Usage Usage `json:"usage"`
 }
 
 // NewParallelResultEvent creates a new ParallelResultEvent with the given results, errors, duration and source step.
@@ -425,6 +682,45 @@ func (e *ParallelResultEvent) GetResults() map[string]interface{} {
 	return e.Results
 }
 
+// OrderedResults returns the results in task submission order, with nil
+// for tasks that produced no result.
+func (e *ParallelResultEvent) OrderedResults() []interface{} {
+	results := make([]interface{}, len(e.Order))
+	for i, id := range e.Order {
+		results[i] = e.Results[id]
+	}
+	return results
+}
+
+// ResultsAs decodes the results of e into T in task submission order, as
+// NewTypedStep decodes events. Tasks that produced no result yield the zero
+// T. A failed task returns its error.
+func ResultsAs[T any](e *ParallelResultEvent) ([]T, error) {
+	results := make([]T, len(e.Order))
+	for i, id := range e.Order {
+		if err, ok := e.Errors[id]; ok && err != nil {
+			return nil, fmt.Errorf("task %s failed: %w", id, err)
+		}
+		var err error
+		switch result := e.Results[id].(type) {
+		case nil:
+		case T:
+			results[i] = result
+		case Event:
+			results[i], err = decodeEvent[T](result)
+		default:
+			var data []byte
+			if data, err = json.Marshal(result); err == nil {
+				err = json.Unmarshal(data, &results[i])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", id, err)
+		}
+	}
+	return results, nil
+}
+
 // GetErrors returns the errors from parallel execution.
 func (e *ParallelResultEvent) GetErrors() map[string]error {
 	return e.Errors
@@ -435,6 +731,28 @@ func (e *ParallelResultEvent) GetStats() (successful int, failed int, duration t
 	return e.Successful, e.Failed, e.Duration
 }
 
+// eventID returns the EventID of an event, or "" if it carries no metadata.
+func eventID(event Event) string {
+	if carrier, ok := event.(MetadataCarrier); ok {
+		return carrier.Metadata().EventID
+	}
+	return ""
+}
+
+// setCausation records cause as the triggering event of event.
+// Existing causation IDs set by the producer are preserved.
+func setCausation(event Event, cause Event) {
+	carrier, ok := event.(MetadataCarrier)
+	if !ok || cause == nil {
+		return
+	}
+	meta := carrier.Metadata()
+	if meta.CausationID == "" {
+		meta.CausationID = eventID(cause)
+		carrier.SetMetadata(meta)
+	}
+}
+
 // ToMap converts an interface{} to map[string]interface{} using JSON marshaling.
 func ToMap(v interface{}) (map[string]interface{}, error) {
 	data := make(map[string]interface{})
@@ -0,0 +1,441 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// EventExperimentTrial is the task type of experiment trials, each running
+// one variant on one input.
+const EventExperimentTrial EventType = "ExperimentTrialEvent"
+
+// ScoreFunc scores a variant's output for an input; higher is better.
+type ScoreFunc func(ctx context.Context, input, output string) (float64, error)
+
+// Variant is one arm of an experiment: an agent, usually differing from the
+// other arm in its instructions or model.
+type Variant struct {
+	// Name labels the variant in reports (defaults to "A" and "B")
+	Name string `json:"name"`
+	// Agent answers the inputs
+	Agent *Agent `json:"-"`
+}
+
+// Experiment compares two agent variants on the same inputs. Every
+// (variant, input) pair runs as a task of a ParallelEvent, and the outputs
+// are scored by a judge agent or a ScoreFunc.
+type Experiment struct {
+	// Name identifies the experiment in reports
+	Name string
+	// Swarm runs the variants and the judge
+	Swarm *Swarm
+	// A and B are the variants under comparison
+	A, B Variant
+	// Inputs are the user messages sent to both variants
+	Inputs []string
+	// Judge optionally scores each output from 0 to 10
+	Judge *Agent
+	// Score optionally scores outputs in code; it takes precedence over Judge
+	Score ScoreFunc
+	// Pricing is the cost per million tokens by model, used to estimate
+	// the cost of responses that do not report one
+	Pricing map[string]float64
+	// MaxTurns caps the model and tool turns of each agent run
+	MaxTurns int
+	// Timeout bounds the whole experiment (default 5 minutes)
+	Timeout time.Duration
+}
+
+// Trial is the outcome of running one variant on one input.
+type Trial struct {
+	// Variant is the name of the variant
+	Variant string `json:"variant"`
+	// Input is the index of the input in Experiment.Inputs
+	Input int `json:"input"`
+	// Output is the variant's final reply
+	Output string `json:"output,omitempty"`
+	// Latency is the duration of the agent run
+	Latency time.Duration `json:"latency"`
+	// Tokens and Cost are the usage of the agent run
+	Tokens int     `json:"tokens"`
+	Cost   float64 `json:"cost"`
+	// Score is the judge's score, if the experiment scores outputs
+	Score float64 `json:"score"`
+	// Reason is the judge's explanation of the score
+	Reason string `json:"reason,omitempty"`
+	// Error is set if the run or its scoring failed
+	Error string `json:"error,omitempty"`
+}
+
+// VariantStats aggregates the trials of a variant.
+type VariantStats struct {
+	Name string `json:"name"`
+	// Runs and Failures count the trials of the variant
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+	// MeanLatency and P95Latency are computed over successful trials
+	MeanLatency time.Duration `json:"mean_latency"`
+	P95Latency  time.Duration `json:"p95_latency"`
+	// TotalTokens and TotalCost sum the usage of all trials
+	TotalTokens int     `json:"total_tokens"`
+	TotalCost   float64 `json:"total_cost"`
+	// MeanScore is the mean score of successful trials
+	MeanScore float64 `json:"mean_score"`
+	// Wins counts the inputs on which the variant scored higher
+	Wins int `json:"wins"`
+}
+
+// ExperimentReport compares the variants of an experiment.
+type ExperimentReport struct {
+	Name string       `json:"name"`
+	A    VariantStats `json:"a"`
+	B    VariantStats `json:"b"`
+	// Ties counts the inputs on which both variants scored the same
+	Ties int `json:"ties"`
+	// Winner is the name of the variant with the higher mean score, or ""
+	// if outputs are not scored or the scores are equal
+	Winner string `json:"winner,omitempty"`
+	// Trials lists every trial by input, then variant
+	Trials []Trial `json:"trials"`
+	// Duration is the wall time of the experiment
+	Duration time.Duration `json:"duration"`
+}
+
+// NewExperiment creates an experiment comparing agents a and b on inputs.
+func NewExperiment(name string, s *Swarm, a, b *Agent, inputs ...string) *Experiment {
+	return &Experiment{
+		Name:     name,
+		Swarm:    s,
+		A:        Variant{Name: "A", Agent: a},
+		B:        Variant{Name: "B", Agent: b},
+		Inputs:   inputs,
+		MaxTurns: 10,
+	}
+}
+
+// WithJudge sets the agent that scores outputs and returns the experiment.
+func (e *Experiment) WithJudge(judge *Agent) *Experiment {
+	e.Judge = judge
+	return e
+}
+
+// WithScore sets the function that scores outputs and returns the experiment.
+func (e *Experiment) WithScore(score ScoreFunc) *Experiment {
+	e.Score = score
+	return e
+}
+
+// WithPricing sets the cost per million tokens by model and returns the
+// experiment.
+func (e *Experiment) WithPricing(pricing map[string]float64) *Experiment {
+	e.Pricing = pricing
+	return e
+}
+
+// RunExperiment runs exp and returns its report.
+func (s *Swarm) RunExperiment(ctx context.Context, exp *Experiment) (*ExperimentReport, error) {
+	if exp.Swarm == nil {
+		exp.Swarm = s
+	}
+	return exp.Run(ctx)
+}
+
+// Run runs the experiment and returns its report.
+func (e *Experiment) Run(ctx context.Context) (*ExperimentReport, error) {
+	workflow, err := e.Workflow()
+	if err != nil {
+		return nil, err
+	}
+	handler, err := workflow.Run(ctx, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	return WaitAs[*ExperimentReport](handler)
+}
+
+// Workflow builds the experiment workflow. It starts from a StartEvent and
+// stops with an *ExperimentReport.
+func (e *Experiment) Workflow() (*Workflow, error) {
+	if e.Swarm == nil {
+		return nil, fmt.Errorf("%w: experiment needs a swarm", ErrInvalidParameter)
+	}
+	if e.A.Agent == nil || e.B.Agent == nil {
+		return nil, fmt.Errorf("%w: experiment needs two variants", ErrInvalidParameter)
+	}
+	if e.A.Name == "" {
+		e.A.Name = "A"
+	}
+	if e.B.Name == "" {
+		e.B.Name = "B"
+	}
+	if e.A.Name == e.B.Name {
+		return nil, fmt.Errorf("%w: duplicate variant %q", ErrInvalidParameter, e.A.Name)
+	}
+	if len(e.Inputs) == 0 {
+		return nil, fmt.Errorf("%w: experiment has no inputs", ErrInvalidParameter)
+	}
+
+	config := DefaultConfig()
+	config.Name = "experiment"
+	if e.Timeout > 0 {
+		config.Timeout = e.Timeout
+	}
+	workflow := NewWorkflow("experiment").WithConfig(config)
+	steps := []Step{
+		NewStep("start", EventStart, e.start, StepConfig{}),
+		NewStep("trial", EventExperimentTrial, e.trial, StepConfig{}),
+		NewStep("report", EventParallelResult, e.report, StepConfig{}),
+	}
+	for _, step := range steps {
+		if err := workflow.AddStep(step); err != nil {
+			return nil, err
+		}
+	}
+	return workflow, nil
+}
+
+// start dispatches a trial for every variant and input.
+func (e *Experiment) start(ctx *Context, event Event) (Event, error) {
+	ctx.Set("experiment.start", time.Now())
+	tasks := make([]Task, 0, 2*len(e.Inputs))
+	for i := range e.Inputs {
+		for _, variant := range e.variants() {
+			trial := Trial{Variant: variant.Name, Input: i}
+			tasks = append(tasks, NewTask(trialID(variant.Name, i), EventExperimentTrial, trial))
+		}
+	}
+	return NewParallelEvent(tasks, "experiment")
+}
+
+// trial runs a variant on an input and scores its output.
+func (e *Experiment) trial(ctx *Context, event Event) (Event, error) {
+	var trial Trial
+	if err := ToStruct(event.Data(), &trial); err != nil {
+		return nil, fmt.Errorf("invalid experiment trial: %w", err)
+	}
+	var agent *Agent
+	for _, variant := range e.variants() {
+		if variant.Name == trial.Variant {
+			agent = variant.Agent
+		}
+	}
+	if agent == nil || trial.Input < 0 || trial.Input >= len(e.Inputs) {
+		return nil, fmt.Errorf("%w: unknown trial %s", ErrInvalidParameter, trialID(trial.Variant, trial.Input))
+	}
+	input := e.Inputs[trial.Input]
+
+	start := time.Now()
+	response, err := e.Swarm.Run(ctx.Context(), agent, []map[string]interface{}{
+		{"role": "user", "content": input},
+	}, nil, "", false, false, e.maxTurns(), true, false)
+	if err != nil {
+		return nil, fmt.Errorf("variant %s failed on input %d: %w", trial.Variant, trial.Input, err)
+	}
+	trial.Latency = time.Since(start)
+	trial.Output = lastContent(response)
+	trial.Tokens = response.TokensUsed
+	trial.Cost = response.Cost
+	if trial.Cost == 0 {
+		// The run's tokens are already in the workflow usage, only add the
+		// estimated cost
+		trial.Cost = e.Pricing[agent.Model] * float64(response.TokensUsed) / 1e6
+		ctx.RecordUsage(0, trial.Cost)
+	}
+
+	// Scoring failures are recorded on the trial, so they do not rerun the
+	// variant
+	switch {
+	case e.Score != nil:
+		trial.Score, err = e.Score(ctx.Context(), input, trial.Output)
+	case e.Judge != nil:
+		trial.Score, trial.Reason, err = e.judge(ctx.Context(), input, trial.Output)
+	}
+	if err != nil {
+		trial.Error = fmt.Sprintf("scoring failed: %v", err)
+	}
+
+	data, err := ToMap(trial)
+	if err != nil {
+		return nil, err
+	}
+	return NewBaseEvent(EventExperimentTrial, data), nil
+}
+
+// report aggregates the trials into the experiment report.
+func (e *Experiment) report(ctx *Context, event Event) (Event, error) {
+	results := event.(*ParallelResultEvent)
+	report := &ExperimentReport{Name: e.Name}
+	if value, ok := ctx.Get("experiment.start"); ok {
+		if start, ok := value.(time.Time); ok {
+			report.Duration = time.Since(start)
+		}
+	}
+
+	for id, value := range results.Results {
+		var trial Trial
+		switch v := value.(type) {
+		case *ErrorEvent:
+			trial = parseTrialID(id)
+			trial.Error = fmt.Sprint(v.Error)
+		case Event:
+			if err := ToStruct(v.Data(), &trial); err != nil {
+				trial = parseTrialID(id)
+				trial.Error = err.Error()
+			}
+		}
+		report.Trials = append(report.Trials, trial)
+	}
+	// Map iteration order is random; keep trials deterministic
+	order := map[string]int{e.A.Name: 0, e.B.Name: 1}
+	sort.Slice(report.Trials, func(i, j int) bool {
+		a, b := report.Trials[i], report.Trials[j]
+		if a.Input != b.Input {
+			return a.Input < b.Input
+		}
+		return order[a.Variant] < order[b.Variant]
+	})
+
+	report.A = variantStats(e.A.Name, report.Trials)
+	report.B = variantStats(e.B.Name, report.Trials)
+	if e.Score != nil || e.Judge != nil {
+		e.countWins(report)
+	}
+	return NewStopEvent(report), nil
+}
+
+// countWins compares the variants' scores input by input and picks the
+// overall winner by mean score.
+func (e *Experiment) countWins(report *ExperimentReport) {
+	scores := make(map[int]map[string]float64)
+	for _, trial := range report.Trials {
+		if trial.Error != "" {
+			continue
+		}
+		if scores[trial.Input] == nil {
+			scores[trial.Input] = make(map[string]float64)
+		}
+		scores[trial.Input][trial.Variant] = trial.Score
+	}
+	for _, byVariant := range scores {
+		a, okA := byVariant[e.A.Name]
+		b, okB := byVariant[e.B.Name]
+		switch {
+		case okA && okB && a == b:
+			report.Ties++
+		case okA && (!okB || a > b):
+			report.A.Wins++
+		case okB:
+			report.B.Wins++
+		}
+	}
+	switch {
+	case report.A.MeanScore > report.B.MeanScore:
+		report.Winner = e.A.Name
+	case report.B.MeanScore > report.A.MeanScore:
+		report.Winner = e.B.Name
+	}
+}
+
+// variantStats aggregates the trials of the named variant.
+func variantStats(name string, trials []Trial) VariantStats {
+	stats := VariantStats{Name: name}
+	var latencies []time.Duration
+	var total time.Duration
+	var score float64
+	for _, trial := range trials {
+		if trial.Variant != name {
+			continue
+		}
+		stats.Runs++
+		stats.TotalTokens += trial.Tokens
+		stats.TotalCost += trial.Cost
+		if trial.Error != "" {
+			stats.Failures++
+			continue
+		}
+		latencies = append(latencies, trial.Latency)
+		total += trial.Latency
+		score += trial.Score
+	}
+	if len(latencies) == 0 {
+		return stats
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.MeanLatency = total / time.Duration(len(latencies))
+	stats.P95Latency = latencies[int(math.Ceil(0.95*float64(len(latencies))))-1]
+	stats.MeanScore = score / float64(len(latencies))
+	return stats
+}
+
+// String renders the report as a comparison table.
+func (r *ExperimentReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Experiment %s (%d trials in %v)\n", r.Name, len(r.Trials), r.Duration.Round(time.Millisecond))
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "variant\truns\tfailures\tmean latency\tp95 latency\ttokens\tcost\tmean score\twins")
+	for _, stats := range []VariantStats{r.A, r.B} {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%v\t%v\t%d\t%.4f\t%.2f\t%d\n", stats.Name, stats.Runs, stats.Failures,
+			stats.MeanLatency.Round(time.Millisecond), stats.P95Latency.Round(time.Millisecond),
+			stats.TotalTokens, stats.TotalCost, stats.MeanScore, stats.Wins)
+	}
+	w.Flush()
+	if r.Winner != "" {
+		fmt.Fprintf(&b, "Winner: %s (%d ties)\n", r.Winner, r.Ties)
+	} else {
+		fmt.Fprintf(&b, "No winner (%d ties)\n", r.Ties)
+	}
+	return b.String()
+}
+
+// judge asks the judge agent to score an output.
+func (e *Experiment) judge(ctx context.Context, input, output string) (float64, string, error) {
+	prompt := fmt.Sprintf("Input:\n%s\n\nResponse:\n%s\n\nScore how well the response answers the input, from 0 (useless) to 10 (perfect). "+
+		`Reply with a JSON object: {"score": <number>, "reason": <short explanation>}.`, input, output)
+	response, err := e.Swarm.Run(ctx, e.Judge, []map[string]interface{}{
+		{"role": "user", "content": prompt},
+	}, nil, "", false, false, e.maxTurns(), true, true)
+	if err != nil {
+		return 0, "", err
+	}
+	var verdict struct {
+		Score  float64 `json:"score"`
+		Reason string  `json:"reason"`
+	}
+	if err := parseJSONReply(lastContent(response), &verdict); err != nil {
+		return 0, "", err
+	}
+	return verdict.Score, verdict.Reason, nil
+}
+
+// variants returns the variants under comparison.
+func (e *Experiment) variants() []Variant {
+	return []Variant{e.A, e.B}
+}
+
+func (e *Experiment) maxTurns() int {
+	if e.MaxTurns <= 0 {
+		return 10
+	}
+	return e.MaxTurns
+}
+
+// trialID identifies the task of a trial.
+func trialID(variant string, input int) string {
+	return fmt.Sprintf("%s/%d", variant, input)
+}
+
+// parseTrialID recovers the variant and input of a failed trial's task.
+func parseTrialID(id string) Trial {
+	i := strings.LastIndex(id, "/")
+	if i < 0 {
+		return Trial{Variant: id}
+	}
+	input, _ := strconv.Atoi(id[i+1:])
+	return Trial{Variant: id[:i], Input: input}
+}
@@ -0,0 +1,99 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// variantClient answers with a reply chosen by the requested model and the last
+// user message, and reports 100 tokens per completion.
+type variantClient struct {
+	*MockOpenAIClient
+	reply func(model, prompt string) string
+}
+
+func (c *variantClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	var prompt string
+	for _, msg := range params.Messages {
+		if msg.OfUser != nil {
+			prompt = msg.OfUser.Content.OfString.Value
+		}
+	}
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: c.reply(params.Model, prompt), Role: "assistant"}},
+		},
+		Usage: openai.CompletionUsage{TotalTokens: 100},
+	}, nil
+}
+
+func TestExperimentWithJudge(t *testing.T) {
+	client := &variantClient{MockOpenAIClient: NewMockOpenAIClient(), reply: func(model, prompt string) string {
+		switch {
+		case strings.Contains(prompt, "Score how well") && strings.Contains(prompt, "detailed"):
+			return `{"score": 9, "reason": "thorough"}`
+		case strings.Contains(prompt, "Score how well"):
+			return "```json\n" + `{"score": 4, "reason": "terse"}` + "\n```"
+		case model == "large":
+			return "detailed " + prompt
+		default:
+			return prompt
+		}
+	}}
+	s := NewSwarm(client)
+	exp := NewExperiment("prompts", nil, NewAgent("short").WithModel("small"), NewAgent("long").WithModel("large"), "q1", "q2", "q3").
+		WithJudge(NewAgent("judge")).
+		WithPricing(map[string]float64{"small": 1, "large": 10})
+	exp.A.Name = "short"
+
+	report, err := s.RunExperiment(context.Background(), exp)
+	AssertNoError(t, err, "RunExperiment")
+	AssertEqual(t, 6, len(report.Trials), "trials")
+	AssertEqual(t, "short", report.Trials[0].Variant, "trials are ordered by input, then variant")
+	AssertEqual(t, "B", report.Trials[1].Variant, "default variant name")
+	AssertEqual(t, 2, report.Trials[4].Input, "trial input")
+	AssertEqual(t, "detailed q3", report.Trials[5].Output, "trial output")
+	AssertEqual(t, "thorough", report.Trials[5].Reason, "judge reason")
+
+	AssertEqual(t, 3, report.A.Runs, "runs")
+	AssertEqual(t, 4.0, report.A.MeanScore, "mean score of A")
+	AssertEqual(t, 9.0, report.B.MeanScore, "mean score of B")
+	AssertEqual(t, 300, report.B.TotalTokens, "tokens")
+	AssertEqual(t, 0.003, report.B.TotalCost, "cost from pricing")
+	AssertEqual(t, 3, report.B.Wins, "wins")
+	AssertEqual(t, 0, report.Ties, "ties")
+	AssertEqual(t, "B", report.Winner, "winner")
+	if !strings.Contains(report.String(), "Winner: B") {
+		t.Errorf("Expected the winner in the report, got:\n%s", report)
+	}
+}
+
+func TestExperimentFailures(t *testing.T) {
+	client := &variantClient{MockOpenAIClient: NewMockOpenAIClient(), reply: func(model, prompt string) string { return prompt }}
+	exp := NewExperiment("score", NewSwarm(client), NewAgent("a"), NewAgent("b"), "good", "bad").
+		WithScore(func(ctx context.Context, input, output string) (float64, error) {
+			if input == "bad" {
+				return 0, errors.New("no reference answer")
+			}
+			return 1, nil
+		})
+
+	report, err := exp.Run(context.Background())
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, 1, report.A.Failures, "scoring failures")
+	AssertEqual(t, 1.0, report.A.MeanScore, "mean score excludes failures")
+	AssertEqual(t, 1, report.Ties, "ties")
+	AssertEqual(t, "", report.Winner, "no winner on equal scores")
+	AssertEqual(t, "scoring failed: no reference answer", report.Trials[2].Error, "trial error")
+
+	_, err = NewExperiment("empty", NewSwarm(client), NewAgent("a"), NewAgent("b")).Run(context.Background())
+	AssertError(t, err, "Expected error without inputs")
+	exp = NewExperiment("dup", NewSwarm(client), NewAgent("a"), NewAgent("b"), "q")
+	exp.B.Name = "A"
+	_, err = exp.Run(context.Background())
+	AssertError(t, err, "Expected error for duplicate variants")
+}
@@ -0,0 +1,389 @@
+// Package fakellm provides a scriptable fake LLM provider for tests. It
+// implements swarm.OpenAIClient, answering each request with the responses of
+// the first rule whose matcher accepts it:
+//
+//	llm := fakellm.New()
+//	llm.When(fakellm.PromptContains("weather")).
+//		CallTool("get_weather", map[string]interface{}{"city": "Paris"}).
+//		Reply("It is sunny in Paris.")
+//	s := swarm.NewSwarm(llm)
+//
+// A rule serves its responses in order on successive matches and repeats the
+// last one once they are exhausted, so a rule can script a tool call followed
+// by the answer the model gives after seeing the tool's result. Streaming
+// requests receive the same responses split into chunks.
+package fakellm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// ErrUnexpectedRequest is returned for requests no rule matches.
+var ErrUnexpectedRequest = errors.New("fakellm: unexpected request")
+
+// DefaultChunkSize is the number of characters per streamed content chunk.
+const DefaultChunkSize = 8
+
+// EmbeddingDimensions is the size of the vectors returned by Embeddings.
+const EmbeddingDimensions = 16
+
+// ToolCall is a tool call made by a scripted response.
+type ToolCall struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Response is a scripted model response: content, tool calls, or an error.
+type Response struct {
+	Content   string
+	ToolCalls []ToolCall
+	Err       error
+}
+
+// Rule scripts the responses to the requests accepted by its matcher.
+type Rule struct {
+	match     Matcher
+	responses []Response
+	times     int
+	calls     int
+	mu        *sync.Mutex
+}
+
+// Reply adds a response with content and returns the rule.
+func (r *Rule) Reply(content string) *Rule {
+	r.responses = append(r.responses, Response{Content: content})
+	return r
+}
+
+// CallTool adds a response calling the named tool and returns the rule.
+func (r *Rule) CallTool(name string, args map[string]interface{}) *Rule {
+	return r.CallTools(ToolCall{Name: name, Arguments: args})
+}
+
+// CallTools adds a response calling several tools in parallel and returns the
+// rule.
+func (r *Rule) CallTools(calls ...ToolCall) *Rule {
+	r.responses = append(r.responses, Response{ToolCalls: calls})
+	return r
+}
+
+// Fail adds a response failing the request with err and returns the rule.
+func (r *Rule) Fail(err error) *Rule {
+	r.responses = append(r.responses, Response{Err: err})
+	return r
+}
+
+// Times limits the rule to n matches and returns the rule. Later requests
+// fall through to the following rules.
+func (r *Rule) Times(n int) *Rule {
+	r.times = n
+	return r
+}
+
+// Calls returns the number of requests the rule answered.
+func (r *Rule) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+// next returns the response for the rule's next match.
+func (r *Rule) next() Response {
+	i := r.calls
+	if i >= len(r.responses) {
+		i = len(r.responses) - 1
+	}
+	r.calls++
+	return r.responses[i]
+}
+
+// Client is a fake LLM provider implementing swarm.OpenAIClient. It is safe
+// for concurrent use.
+type Client struct {
+	// ChunkSize is the number of characters per streamed content chunk
+	ChunkSize int
+
+	rules    []*Rule
+	requests []Request
+	toolIDs  int
+	mu       sync.Mutex
+}
+
+// New creates a fake provider without rules.
+func New() *Client {
+	return &Client{ChunkSize: DefaultChunkSize}
+}
+
+// When adds a rule answering the requests accepted by m, checked after the
+// rules added before it, and returns it for scripting its responses.
+func (c *Client) When(m Matcher) *Rule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rule := &Rule{match: m, mu: &c.mu}
+	c.rules = append(c.rules, rule)
+	return rule
+}
+
+// Otherwise adds a rule answering every request no earlier rule matched.
+func (c *Client) Otherwise() *Rule {
+	return c.When(Any())
+}
+
+// Requests returns the chat completion requests received so far.
+func (c *Client) Requests() []Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Request(nil), c.requests...)
+}
+
+// CreateChatCompletion answers a request with the next response of the first
+// matching rule.
+func (c *Client) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req, err := decodeRequest(params, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, ids, err := c.respond(req)
+	if err != nil {
+		return nil, err
+	}
+	return completion(req.Model, resp, ids)
+}
+
+// CreateChatCompletionStream answers a request like CreateChatCompletion,
+// streaming content in chunks of ChunkSize characters and tool calls as a
+// name chunk followed by an arguments chunk.
+func (c *Client) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req, err := decodeRequest(params, true)
+	if err != nil {
+		return nil, err
+	}
+	resp, ids, err := c.respond(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.stream(req.Model, resp, ids)
+}
+
+// Embeddings returns deterministic vectors derived from a hash of each input,
+// so equal inputs have equal embeddings.
+func (c *Client) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		sum := sha256.Sum256([]byte(input))
+		vector := make([]float64, EmbeddingDimensions)
+		for j := range vector {
+			vector[j] = float64(binary.BigEndian.Uint16(sum[2*j:]))/32768 - 1
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// respond records a request and picks its response, with IDs for its tool
+// calls.
+func (c *Client) respond(req Request) (Response, []string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	for _, rule := range c.rules {
+		if len(rule.responses) == 0 || (rule.times > 0 && rule.calls >= rule.times) || !rule.match(req) {
+			continue
+		}
+		resp := rule.next()
+		if resp.Err != nil {
+			return Response{}, nil, resp.Err
+		}
+		ids := make([]string, len(resp.ToolCalls))
+		for i := range ids {
+			c.toolIDs++
+			ids[i] = fmt.Sprintf("call_%d", c.toolIDs)
+		}
+		return resp, ids, nil
+	}
+	return Response{}, nil, fmt.Errorf("%w: no rule matches prompt %q", ErrUnexpectedRequest, req.Prompt())
+}
+
+// completion builds the chat completion of a response.
+func completion(model string, resp Response, ids []string) (*openai.ChatCompletion, error) {
+	message := map[string]interface{}{"role": "assistant", "content": resp.Content}
+	finishReason := "stop"
+	if len(resp.ToolCalls) > 0 {
+		calls := make([]map[string]interface{}, len(resp.ToolCalls))
+		for i, call := range resp.ToolCalls {
+			arguments, err := json.Marshal(call.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("fakellm: invalid arguments for %s: %w", call.Name, err)
+			}
+			calls[i] = map[string]interface{}{
+				"id":       ids[i],
+				"type":     "function",
+				"function": map[string]interface{}{"name": call.Name, "arguments": string(arguments)},
+			}
+		}
+		message["tool_calls"] = calls
+		finishReason = "tool_calls"
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"id":      "chatcmpl-fake",
+		"object":  "chat.completion",
+		"model":   model,
+		"choices": []map[string]interface{}{{"index": 0, "message": message, "finish_reason": finishReason}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result openai.ChatCompletion
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// stream encodes a response as a stream of completion chunks.
+func (c *Client) stream(model string, resp Response, ids []string) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	size := c.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	var deltas []map[string]interface{}
+	content := []rune(resp.Content)
+	for start := 0; start < len(content); start += size {
+		end := min(start+size, len(content))
+		deltas = append(deltas, map[string]interface{}{"role": "assistant", "content": string(content[start:end])})
+	}
+	finishReason := "stop"
+	for i, call := range resp.ToolCalls {
+		arguments, err := json.Marshal(call.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("fakellm: invalid arguments for %s: %w", call.Name, err)
+		}
+		deltas = append(deltas,
+			map[string]interface{}{"tool_calls": []map[string]interface{}{{
+				"index": i, "id": ids[i], "type": "function",
+				"function": map[string]interface{}{"name": call.Name, "arguments": ""},
+			}}},
+			map[string]interface{}{"tool_calls": []map[string]interface{}{{
+				"index": i, "function": map[string]interface{}{"arguments": string(arguments)},
+			}}},
+		)
+		finishReason = "tool_calls"
+	}
+
+	var buf bytes.Buffer
+	write := func(choice map[string]interface{}) error {
+		data, err := json.Marshal(map[string]interface{}{
+			"id":      "chatcmpl-fake",
+			"object":  "chat.completion.chunk",
+			"model":   model,
+			"choices": []map[string]interface{}{choice},
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", data)
+		return nil
+	}
+	for _, delta := range deltas {
+		if err := write(map[string]interface{}{"index": 0, "delta": delta}); err != nil {
+			return nil, err
+		}
+	}
+	if err := write(map[string]interface{}{"index": 0, "delta": map[string]interface{}{}, "finish_reason": finishReason}); err != nil {
+		return nil, err
+	}
+	buf.WriteString("data: [DONE]\n\n")
+
+	httpResp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(&buf),
+	}
+	return ssestream.NewStream[openai.ChatCompletionChunk](ssestream.NewDecoder(httpResp), nil), nil
+}
+
+// decodeRequest decodes the parts of a request matchers look at.
+func decodeRequest(params openai.ChatCompletionNewParams, stream bool) (Request, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return Request{}, fmt.Errorf("fakellm: failed to encode request: %w", err)
+	}
+	var raw struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role       string          `json:"role"`
+			Content    json.RawMessage `json:"content"`
+			ToolCallID string          `json:"tool_call_id"`
+			ToolCalls  []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Request{}, fmt.Errorf("fakellm: failed to decode request: %w", err)
+	}
+
+	req := Request{Model: raw.Model, Stream: stream}
+	for _, m := range raw.Messages {
+		msg := Message{Role: m.Role, Content: textContent(m.Content), ToolCallID: m.ToolCallID}
+		for _, call := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, call.Function.Name)
+			msg.toolCallIDs = append(msg.toolCallIDs, call.ID)
+		}
+		req.Messages = append(req.Messages, msg)
+	}
+	for _, tool := range raw.Tools {
+		req.Tools = append(req.Tools, tool.Function.Name)
+	}
+	return req, nil
+}
+
+// textContent returns the text of message content, given as a string or as
+// an array of content parts.
+func textContent(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(content, &parts)
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
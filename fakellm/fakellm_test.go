@@ -0,0 +1,101 @@
+package fakellm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// weatherAgent returns an agent with a get_weather tool recording its calls.
+func weatherAgent(cities *[]string) *swarm.Agent {
+	getWeather := swarm.NewAgentFunction("get_weather", "Get the weather of a city", func(args map[string]interface{}) (interface{}, error) {
+		city, _ := args["city"].(string)
+		*cities = append(*cities, city)
+		return "sunny in " + city, nil
+	}, []swarm.Parameter{
+		{Name: "city", Description: "The city", Type: reflect.TypeOf(""), Required: true},
+	})
+	return swarm.NewAgent("assistant").WithInstructions("You report the weather.").AddFunction(getWeather)
+}
+
+func lastReply(resp *swarm.Response) string {
+	content, _ := resp.Messages[len(resp.Messages)-1]["content"].(string)
+	return content
+}
+
+func TestToolCallSequence(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		llm := New()
+		llm.When(PromptContains("weather")).
+			CallTool("get_weather", map[string]interface{}{"city": "Paris"}).
+			Reply("It is sunny in Paris.")
+		llm.When(All(SystemContains("report the weather"), HasTool("get_weather"))).Reply("Ask me about the weather.")
+
+		var cities []string
+		resp, err := swarm.NewSwarm(llm).Run(context.Background(), weatherAgent(&cities),
+			[]map[string]interface{}{{"role": "user", "content": "What's the weather in Paris?"}},
+			nil, "", stream, false, 5, true, false)
+		if err != nil {
+			t.Fatalf("stream=%v: run failed: %v", stream, err)
+		}
+		if got := lastReply(resp); got != "It is sunny in Paris." {
+			t.Errorf("stream=%v: unexpected reply %q", stream, got)
+		}
+		if !reflect.DeepEqual(cities, []string{"Paris"}) {
+			t.Errorf("stream=%v: unexpected tool calls %v", stream, cities)
+		}
+
+		requests := llm.Requests()
+		if len(requests) != 2 || requests[0].Stream != stream {
+			t.Fatalf("stream=%v: unexpected requests %+v", stream, requests)
+		}
+		if result, ok := requests[1].ToolResult("get_weather"); !ok || result != "sunny in Paris" {
+			t.Errorf("stream=%v: unexpected tool result %q", stream, result)
+		}
+
+		resp, err = swarm.NewSwarm(llm).Run(context.Background(), weatherAgent(&cities),
+			[]map[string]interface{}{{"role": "user", "content": "Hello"}},
+			nil, "", stream, false, 5, true, false)
+		if err != nil || lastReply(resp) != "Ask me about the weather." {
+			t.Errorf("stream=%v: expected the fallback rule, got %v", stream, err)
+		}
+	}
+}
+
+func TestRuleLimits(t *testing.T) {
+	llm := New()
+	busy := errors.New("overloaded")
+	first := llm.When(Any()).Fail(busy).Times(1)
+	llm.When(Model("gpt-4o")).Reply("hi")
+
+	agent := swarm.NewAgent("assistant").WithModel("gpt-4o")
+	messages := []map[string]interface{}{{"role": "user", "content": "Hello"}}
+	if _, err := swarm.NewSwarm(llm).Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false); !errors.Is(err, busy) {
+		t.Errorf("expected the scripted error, got %v", err)
+	}
+	resp, err := swarm.NewSwarm(llm).Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	if err != nil || lastReply(resp) != "hi" {
+		t.Errorf("expected the second rule after the first is used up, got %v", err)
+	}
+	if first.Calls() != 1 {
+		t.Errorf("expected 1 call of the first rule, got %d", first.Calls())
+	}
+
+	_, err = swarm.NewSwarm(llm).Run(context.Background(), agent.Clone().WithModel("gpt-4o-mini"), messages, nil, "", false, false, 1, true, false)
+	if !errors.Is(err, ErrUnexpectedRequest) {
+		t.Errorf("expected ErrUnexpectedRequest, got %v", err)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	vectors, err := New().Embeddings(context.Background(), "text-embedding-3-small", []string{"a", "b", "a"})
+	if err != nil {
+		t.Fatalf("Embeddings: %v", err)
+	}
+	if len(vectors[0]) != EmbeddingDimensions || !reflect.DeepEqual(vectors[0], vectors[2]) || reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Errorf("expected deterministic, distinct vectors, got %v", vectors)
+	}
+}
@@ -0,0 +1,144 @@
+package fakellm
+
+import (
+	"strings"
+)
+
+// Message is a message of a request, decoded from the chat completion params.
+type Message struct {
+	Role    string
+	Content string
+	// ToolCallID is the ID of the call a tool message answers
+	ToolCallID string
+	// ToolCalls are the names of the tools called by an assistant message
+	ToolCalls []string
+	// toolCallIDs are the IDs of ToolCalls, in the same order
+	toolCallIDs []string
+}
+
+// Request is a chat completion request received by the fake.
+type Request struct {
+	Model    string
+	Messages []Message
+	// Tools are the names of the tools offered to the model
+	Tools  []string
+	Stream bool
+}
+
+// Prompt returns the content of the last user message.
+func (r Request) Prompt() string {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if r.Messages[i].Role == "user" {
+			return r.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// System returns the content of the system (or developer) messages.
+func (r Request) System() string {
+	var parts []string
+	for _, msg := range r.Messages {
+		if msg.Role == "system" || msg.Role == "developer" {
+			parts = append(parts, msg.Content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ToolResult returns the result of the last call of the named tool made since
+// the last user message, and whether there is one.
+func (r Request) ToolResult(name string) (string, bool) {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		msg := r.Messages[i]
+		if msg.Role == "user" {
+			break
+		}
+		if msg.Role == "tool" && r.toolName(msg.ToolCallID) == name {
+			return msg.Content, true
+		}
+	}
+	return "", false
+}
+
+// toolName returns the name of the tool called with id.
+func (r Request) toolName(id string) string {
+	for _, msg := range r.Messages {
+		for i, callID := range msg.toolCallIDs {
+			if callID == id {
+				return msg.ToolCalls[i]
+			}
+		}
+	}
+	return ""
+}
+
+// Matcher selects the requests a rule answers.
+type Matcher func(req Request) bool
+
+// Any matches every request.
+func Any() Matcher {
+	return func(Request) bool { return true }
+}
+
+// PromptContains matches requests whose last user message contains s.
+func PromptContains(s string) Matcher {
+	return func(req Request) bool { return strings.Contains(req.Prompt(), s) }
+}
+
+// SystemContains matches requests whose system messages contain s.
+func SystemContains(s string) Matcher {
+	return func(req Request) bool { return strings.Contains(req.System(), s) }
+}
+
+// Model matches requests for the model.
+func Model(model string) Matcher {
+	return func(req Request) bool { return req.Model == model }
+}
+
+// HasTool matches requests offering the named tool.
+func HasTool(name string) Matcher {
+	return func(req Request) bool {
+		for _, tool := range req.Tools {
+			if tool == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ToolResult matches requests carrying a result of the named tool since the
+// last user message, i.e. the model's turn after calling it.
+func ToolResult(name string) Matcher {
+	return func(req Request) bool {
+		_, ok := req.ToolResult(name)
+		return ok
+	}
+}
+
+// ToolResultContains matches requests carrying a result of the named tool
+// that contains s.
+func ToolResultContains(name, s string) Matcher {
+	return func(req Request) bool {
+		result, ok := req.ToolResult(name)
+		return ok && strings.Contains(result, s)
+	}
+}
+
+// All matches requests matched by every matcher.
+func All(matchers ...Matcher) Matcher {
+	return func(req Request) bool {
+		for _, m := range matchers {
+			if !m(req) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Not matches requests not matched by m.
+func Not(m Matcher) Matcher {
+	return func(req Request) bool { return !m(req) }
+}
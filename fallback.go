@@ -0,0 +1,243 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// Default health tracking settings for FallbackClient.
+const (
+	defaultFailureThreshold = 3
+	defaultFailureCooldown  = 30 * time.Second
+)
+
+// ProviderHealth is a snapshot of a fallback provider's health.
+type ProviderHealth struct {
+	// Index is the provider's position in the chain (0 is the primary)
+	Index int
+	// Healthy is false while the provider is cooling down after failures
+	Healthy bool
+	// ConsecutiveFailures counts retryable failures since the last success
+	ConsecutiveFailures int
+	// UnhealthyUntil is when the provider becomes eligible again
+	UnhealthyUntil time.Time
+	// LastError is the most recent retryable error
+	LastError error
+}
+
+// providerState tracks the health of one provider.
+type providerState struct {
+	client         OpenAIClient
+	failures       int
+	unhealthyUntil time.Time
+	lastErr        error
+}
+
+// FallbackClient is an OpenAIClient that tries a chain of providers in order,
+// moving to the next one when a provider returns a rate-limit, server or
+// network error. Providers that fail repeatedly are skipped for a cooldown
+// period, so an outage does not add latency to every request.
+type FallbackClient struct {
+	providers        []*providerState
+	failureThreshold int
+	cooldown         time.Duration
+	mu               sync.Mutex
+}
+
+// NewFallbackClient creates a client that sends requests to primary and falls
+// back to secondaries in order. Use NewModelClient to fall back to a different
+// model on the same provider.
+func NewFallbackClient(primary OpenAIClient, secondaries ...OpenAIClient) *FallbackClient {
+	providers := make([]*providerState, 0, len(secondaries)+1)
+	for _, client := range append([]OpenAIClient{primary}, secondaries...) {
+		providers = append(providers, &providerState{client: client})
+	}
+	return &FallbackClient{
+		providers:        providers,
+		failureThreshold: defaultFailureThreshold,
+		cooldown:         defaultFailureCooldown,
+	}
+}
+
+// WithFailureThreshold sets how many consecutive failures mark a provider
+// unhealthy, and returns the client.
+func (f *FallbackClient) WithFailureThreshold(n int) *FallbackClient {
+	if n > 0 {
+		f.failureThreshold = n
+	}
+	return f
+}
+
+// WithCooldown sets how long an unhealthy provider is skipped, and returns
+// the client.
+func (f *FallbackClient) WithCooldown(d time.Duration) *FallbackClient {
+	f.cooldown = d
+	return f
+}
+
+// Health returns the current health of every provider in chain order.
+func (f *FallbackClient) Health() []ProviderHealth {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	health := make([]ProviderHealth, len(f.providers))
+	for i, p := range f.providers {
+		health[i] = ProviderHealth{
+			Index:               i,
+			Healthy:             !now.Before(p.unhealthyUntil),
+			ConsecutiveFailures: p.failures,
+			UnhealthyUntil:      p.unhealthyUntil,
+			LastError:           p.lastErr,
+		}
+	}
+	return health
+}
+
+// CreateChatCompletion sends the request to the first provider that succeeds.
+func (f *FallbackClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	return tryProviders(f, ctx, func(client OpenAIClient) (*openai.ChatCompletion, error) {
+		return client.CreateChatCompletion(ctx, params)
+	})
+}
+
+// CreateChatCompletionStream opens a stream on the first provider that
+// succeeds. Errors after the stream is established are not retried.
+func (f *FallbackClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	return tryProviders(f, ctx, func(client OpenAIClient) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+		stream, err := client.CreateChatCompletionStream(ctx, params)
+		if err == nil && stream != nil && stream.Err() != nil {
+			err = stream.Err()
+			stream.Close()
+		}
+		return stream, err
+	})
+}
+
+// Embeddings creates embeddings on the first provider that succeeds.
+func (f *FallbackClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	return tryProviders(f, ctx, func(client OpenAIClient) ([][]float64, error) {
+		return client.Embeddings(ctx, model, inputs)
+	})
+}
+
+// tryProviders calls fn on each provider in order until one succeeds or fails
+// with a non-retryable error.
+func tryProviders[T any](f *FallbackClient, ctx context.Context, fn func(OpenAIClient) (T, error)) (T, error) {
+	var zero T
+	var errs []error
+	for _, i := range f.order() {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		result, err := fn(f.providers[i].client)
+		if err == nil {
+			f.recordSuccess(i)
+			return result, nil
+		}
+		if !isRetryableError(err) {
+			return zero, err
+		}
+		f.recordFailure(i, err)
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+	}
+	return zero, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// order returns provider indexes to try: healthy providers first in chain
+// order, then unhealthy ones as a last resort.
+func (f *FallbackClient) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	healthy := make([]int, 0, len(f.providers))
+	var unhealthy []int
+	for i, p := range f.providers {
+		if now.Before(p.unhealthyUntil) {
+			unhealthy = append(unhealthy, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// recordSuccess resets a provider's failure count.
+func (f *FallbackClient) recordSuccess(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.providers[i]
+	p.failures = 0
+	p.unhealthyUntil = time.Time{}
+}
+
+// recordFailure counts a retryable failure and starts the cooldown once the
+// threshold is reached.
+func (f *FallbackClient) recordFailure(i int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := f.providers[i]
+	p.failures++
+	p.lastErr = err
+	if p.failures >= f.failureThreshold {
+		p.unhealthyUntil = time.Now().Add(f.cooldown)
+	}
+}
+
+// isRetryableError reports whether err is worth retrying on another provider:
+// rate limits, timeouts, server errors and transport failures. Invalid
+// requests and context cancellation are not.
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode >= http.StatusInternalServerError:
+			return true
+		default:
+			return false
+		}
+	}
+	// Transport-level errors (connection refused, DNS, resets)
+	return true
+}
+
+// modelClient rewrites the model of every request.
+type modelClient struct {
+	client OpenAIClient
+	model  string
+}
+
+// NewModelClient wraps client so every request uses model, regardless of the
+// agent's model. It is typically used as a FallbackClient secondary, e.g. to
+// fall back from a large model to a smaller one.
+func NewModelClient(client OpenAIClient, model string) OpenAIClient {
+	return &modelClient{client: client, model: model}
+}
+
+// CreateChatCompletion forwards the request with the model replaced.
+func (m *modelClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	params.Model = openai.ChatModel(m.model)
+	return m.client.CreateChatCompletion(ctx, params)
+}
+
+// CreateChatCompletionStream forwards the request with the model replaced.
+func (m *modelClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	params.Model = openai.ChatModel(m.model)
+	return m.client.CreateChatCompletionStream(ctx, params)
+}
+
+// Embeddings forwards the request unchanged, since the override is a chat model.
+func (m *modelClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	return m.client.Embeddings(ctx, model, inputs)
+}
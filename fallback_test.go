@@ -0,0 +1,105 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// newAPIError builds an OpenAI API error with the given status code.
+func newAPIError(status int) error {
+	return &openai.Error{
+		StatusCode: status,
+		Request:    &http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/chat/completions"}},
+		Response:   &http.Response{StatusCode: status},
+	}
+}
+
+func newReplyClient(content string) *MockOpenAIClient {
+	client := NewMockOpenAIClient()
+	for i := 0; i < 5; i++ {
+		client.SetCompletionResponse(&openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: content, Role: "assistant"}},
+			},
+		})
+	}
+	return client
+}
+
+func TestFallbackClient(t *testing.T) {
+	primary := newReplyClient("primary")
+	secondary := newReplyClient("secondary")
+	client := NewFallbackClient(primary, secondary).WithFailureThreshold(2).WithCooldown(time.Minute)
+	params := openai.ChatCompletionNewParams{Model: openai.ChatModel("gpt-4o")}
+
+	completion, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "healthy primary")
+	AssertEqual(t, "primary", completion.Choices[0].Message.Content, "Primary should serve requests")
+
+	// Rate limits fall back to the next provider
+	primary.Error = newAPIError(http.StatusTooManyRequests)
+	completion, err = client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "fallback")
+	AssertEqual(t, "secondary", completion.Choices[0].Message.Content, "Secondary should serve after a 429")
+	AssertEqual(t, true, client.Health()[0].Healthy, "One failure is below the threshold")
+
+	// Reaching the threshold marks the primary unhealthy and skips it
+	_, err = client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "fallback")
+	health := client.Health()
+	AssertEqual(t, false, health[0].Healthy, "Primary should be unhealthy")
+	AssertEqual(t, 2, health[0].ConsecutiveFailures, "Failure count")
+
+	primary.Error = nil
+	calls := primary.CompletionIter
+	completion, err = client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "cooldown")
+	AssertEqual(t, "secondary", completion.Choices[0].Message.Content, "Unhealthy primary should be skipped")
+	AssertEqual(t, calls, primary.CompletionIter, "Primary should not be called during cooldown")
+}
+
+func TestFallbackClientErrors(t *testing.T) {
+	primary := newReplyClient("primary")
+	secondary := newReplyClient("secondary")
+	client := NewFallbackClient(primary, secondary)
+	params := openai.ChatCompletionNewParams{Model: openai.ChatModel("gpt-4o")}
+
+	// Invalid requests are not retried on other providers
+	primary.Error = newAPIError(http.StatusBadRequest)
+	_, err := client.CreateChatCompletion(context.Background(), params)
+	AssertError(t, err, "bad request")
+	AssertEqual(t, 0, secondary.CompletionIter, "Secondary should not be called for a 400")
+
+	// All providers failing returns every error
+	primary.Error = newAPIError(http.StatusInternalServerError)
+	secondary.Error = errors.New("connection refused")
+	_, err = client.CreateChatCompletion(context.Background(), params)
+	AssertError(t, err, "all providers down")
+	var apiErr *openai.Error
+	AssertEqual(t, true, errors.As(err, &apiErr), "Provider errors should be wrapped")
+}
+
+func TestModelClient(t *testing.T) {
+	mockClient := &modelRecordingClient{}
+	client := NewModelClient(mockClient, "gpt-4o-mini")
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionNewParams{Model: openai.ChatModel("gpt-4o")})
+	AssertNoError(t, err, "CreateChatCompletion")
+	AssertEqual(t, "gpt-4o-mini", mockClient.model, "Model should be overridden")
+}
+
+// modelRecordingClient records the model of the last request.
+type modelRecordingClient struct {
+	MockOpenAIClient
+	model string
+}
+
+func (m *modelRecordingClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	m.model = string(params.Model)
+	return &openai.ChatCompletion{}, nil
+}
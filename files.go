@@ -0,0 +1,234 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// FilesName is the key used to pass the conversation's file attachments to
+// tool functions, alongside ContextVariablesName.
+const FilesName = "files"
+
+// FilePart is a file attached to a user message. Text files such as CSV, JSON
+// and Markdown are inlined as text; other files (e.g. PDFs) are sent as file
+// content, either inline as base64 or by reference once uploaded.
+type FilePart struct {
+	// Name is the file name, including its extension
+	Name string `json:"name"`
+	// MimeType is the media type (detected from Name if empty)
+	MimeType string `json:"mime_type,omitempty"`
+	// Data is the file content; it may be empty if FileID is set
+	Data []byte `json:"-"`
+	// FileID references a file uploaded to the OpenAI Files API
+	FileID string `json:"file_id,omitempty"`
+}
+
+// NewFilePart creates a file attachment, detecting its media type from name.
+func NewFilePart(name string, data []byte) FilePart {
+	return FilePart{Name: name, MimeType: detectMimeType(name), Data: data}
+}
+
+// LoadFilePart reads a file from disk as an attachment.
+func LoadFilePart(path string) (FilePart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FilePart{}, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return NewFilePart(filepath.Base(path), data), nil
+}
+
+// NewFileMessage creates a user message with text content and file attachments.
+func NewFileMessage(content string, files ...FilePart) map[string]interface{} {
+	return map[string]interface{}{
+		"role":    "user",
+		"content": content,
+		"files":   files,
+	}
+}
+
+// detectMimeType returns the media type for a file name.
+func detectMimeType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch ext {
+	case ".csv":
+		return "text/csv"
+	case ".md":
+		return "text/markdown"
+	case ".pdf":
+		return "application/pdf"
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// isText reports whether the file should be inlined as text.
+func (f FilePart) isText() bool {
+	mimeType := f.MimeType
+	if mimeType == "" {
+		mimeType = detectMimeType(f.Name)
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return f.FileID == "" && (strings.HasPrefix(mimeType, "text/") ||
+		mimeType == "application/json" || mimeType == "application/xml" || mimeType == "application/yaml")
+}
+
+// contentPart converts the file to a chat message content part.
+func (f FilePart) contentPart() openai.ChatCompletionContentPartUnionParam {
+	if f.isText() {
+		return openai.TextContentPart(fmt.Sprintf("File %s:\n%s", f.Name, f.Data))
+	}
+	file := openai.ChatCompletionContentPartFileFileParam{}
+	if f.FileID != "" {
+		file.FileID = openai.String(f.FileID)
+	} else {
+		mimeType := f.MimeType
+		if mimeType == "" {
+			mimeType = detectMimeType(f.Name)
+		}
+		file.Filename = openai.String(f.Name)
+		file.FileData = openai.String("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(f.Data))
+	}
+	return openai.FileContentPart(file)
+}
+
+// FileUploader is implemented by clients that can upload files for use in
+// conversations. The clients returned by NewOpenAIClient and
+// NewAzureOpenAIClient implement it.
+type FileUploader interface {
+	// UploadFile uploads a file and returns its file ID.
+	UploadFile(ctx context.Context, name string, data io.Reader) (string, error)
+}
+
+// UploadFile uploads a file to the OpenAI Files API for use as model input.
+//
+// Parameters:
+//   - ctx: The context for the API request (defaults to background if nil)
+//   - name: The file name
+//   - data: The file content
+//
+// Returns the uploaded file ID or an error if the upload fails.
+func (c *openAIClientWrapper) UploadFile(ctx context.Context, name string, data io.Reader) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	file, err := c.client.Files.New(ctx, openai.FileNewParams{
+		File:    openai.File(data, name, detectMimeType(name)),
+		Purpose: openai.FilePurposeUserData,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file %s: %w", name, err)
+	}
+	return file.ID, nil
+}
+
+// WithFileUpload uploads non-text attachments larger than threshold bytes
+// through the client's FileUploader instead of inlining them, and returns the
+// swarm. A threshold of zero disables uploads.
+func (s *Swarm) WithFileUpload(threshold int) *Swarm {
+	s.FileUploadThreshold = threshold
+	return s
+}
+
+// messageFiles returns the file attachments of a message.
+func messageFiles(msg map[string]interface{}) []FilePart {
+	switch files := msg["files"].(type) {
+	case []FilePart:
+		return files
+	case FilePart:
+		return []FilePart{files}
+	}
+	return nil
+}
+
+// conversationFiles returns every file attached to the history.
+func conversationFiles(history []map[string]interface{}) []FilePart {
+	var files []FilePart
+	for _, msg := range history {
+		files = append(files, messageFiles(msg)...)
+	}
+	return files
+}
+
+// uploadFiles uploads large attachments in history and returns the history
+// with their FileID set. Messages are copied before being modified.
+func (s *Swarm) uploadFiles(ctx context.Context, history []map[string]interface{}) ([]map[string]interface{}, error) {
+	if s.FileUploadThreshold <= 0 {
+		return history, nil
+	}
+	for i, msg := range history {
+		files := messageFiles(msg)
+		var uploaded []FilePart
+		for j, f := range files {
+			if f.FileID != "" || f.isText() || len(f.Data) <= s.FileUploadThreshold {
+				continue
+			}
+			uploader, ok := s.Client.(FileUploader)
+			if !ok {
+				return nil, fmt.Errorf("client does not support file uploads")
+			}
+			id, err := uploader.UploadFile(ctx, f.Name, bytes.NewReader(f.Data))
+			if err != nil {
+				return nil, err
+			}
+			if uploaded == nil {
+				uploaded = append([]FilePart(nil), files...)
+			}
+			uploaded[j].FileID = id
+		}
+		if uploaded == nil {
+			continue
+		}
+		copied := make(map[string]interface{}, len(msg))
+		for k, v := range msg {
+			copied[k] = v
+		}
+		copied["files"] = uploaded
+		history[i] = copied
+	}
+	return history, nil
+}
+
+// userMessage builds a user message, adding content parts for attachments.
+func userMessage(content string, files []FilePart) openai.ChatCompletionMessageParamUnion {
+	if len(files) == 0 {
+		return openai.UserMessage(content)
+	}
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(files)+1)
+	if content != "" {
+		parts = append(parts, openai.TextContentPart(content))
+	}
+	for _, f := range files {
+		parts = append(parts, f.contentPart())
+	}
+	return openai.UserMessage(parts)
+}
+
+// filesContextKey carries the conversation's attachments to tool calls.
+type filesContextKey struct{}
+
+// withConversationFiles returns ctx carrying the history's attachments.
+func withConversationFiles(ctx context.Context, history []map[string]interface{}) context.Context {
+	files := conversationFiles(history)
+	if len(files) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, filesContextKey{}, files)
+}
+
+// filesFromContext returns the attachments carried by ctx.
+func filesFromContext(ctx context.Context) []FilePart {
+	files, _ := ctx.Value(filesContextKey{}).([]FilePart)
+	return files
+}
@@ -0,0 +1,103 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/option"
+)
+
+func TestPrepareMessagesWithFiles(t *testing.T) {
+	history := []map[string]interface{}{
+		NewFileMessage("Summarize these",
+			NewFilePart("sales.csv", []byte("region,total\nwest,10")),
+			NewFilePart("report.pdf", []byte("%PDF-1.7")),
+			FilePart{Name: "uploaded.pdf", FileID: "file-123"},
+		),
+	}
+	messages := prepareMessages("Be brief.", history, "gpt-4o")
+	data, err := json.Marshal(messages[1])
+	AssertNoError(t, err, "Marshal message")
+
+	var msg struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			File struct {
+				FileData string `json:"file_data"`
+				FileID   string `json:"file_id"`
+				Filename string `json:"filename"`
+			} `json:"file"`
+		} `json:"content"`
+	}
+	AssertNoError(t, json.Unmarshal(data, &msg), "Unmarshal message")
+	AssertEqual(t, 4, len(msg.Content), "content parts")
+	AssertEqual(t, "Summarize these", msg.Content[0].Text, "text part")
+	AssertEqual(t, "File sales.csv:\nregion,total\nwest,10", msg.Content[1].Text, "CSV is inlined as text")
+	AssertEqual(t, "file", msg.Content[2].Type, "PDF is a file part")
+	AssertEqual(t, "report.pdf", msg.Content[2].File.Filename, "PDF filename")
+	AssertEqual(t, "data:application/pdf;base64,JVBERi0xLjc=", msg.Content[2].File.FileData, "PDF is inlined as base64")
+	AssertEqual(t, "file-123", msg.Content[3].File.FileID, "uploaded file reference")
+}
+
+func TestRunUploadsFilesAndExposesThemToTools(t *testing.T) {
+	var uploads int
+	var fileIDs []string
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/files":
+			uploads++
+			AssertEqual(t, "user_data", r.FormValue("purpose"), "upload purpose")
+			fmt.Fprint(w, `{"id":"file-abc","object":"file","bytes":8,"created_at":0,"filename":"report.pdf","purpose":"user_data","status":"processed"}`)
+		case "/chat/completions":
+			calls++
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			data, _ := json.Marshal(body["messages"])
+			if strings.Contains(string(data), "file-abc") {
+				fileIDs = append(fileIDs, "file-abc")
+			}
+			if calls == 1 {
+				fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"list_files","arguments":"{}"}}]}}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"id":"2","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Done"}}]}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var toolFiles []FilePart
+	agent := NewAgent("Reader").WithModel("gpt-4o")
+	agent.Functions = []AgentFunction{NewAgentFunction("list_files", "List attached files",
+		func(args map[string]interface{}) (interface{}, error) {
+			toolFiles, _ = args[FilesName].([]FilePart)
+			return "ok", nil
+		}, []Parameter{})}
+
+	swarm := NewSwarm(NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL))).WithFileUpload(4)
+	messages := []map[string]interface{}{NewFileMessage("Read this", NewFilePart("report.pdf", []byte("%PDF-1.7")))}
+	_, err := swarm.Run(context.Background(), agent, messages, nil, "", false, false, 3, true, false)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, 1, uploads, "File should be uploaded once")
+	AssertEqual(t, 2, len(fileIDs), "Every request should reference the uploaded file")
+	AssertEqual(t, "", messages[0]["files"].([]FilePart)[0].FileID, "Caller's message should not be modified")
+	AssertEqual(t, 1, len(toolFiles), "Tool should receive the attachments")
+	AssertEqual(t, "file-abc", toolFiles[0].FileID, "Tool should see the uploaded file ID")
+}
+
+func TestFilePartMimeType(t *testing.T) {
+	AssertEqual(t, "text/csv", NewFilePart("a.CSV", nil).MimeType, "csv")
+	AssertEqual(t, "application/pdf", NewFilePart("a.pdf", nil).MimeType, "pdf")
+	AssertEqual(t, true, NewFilePart("a.json", nil).isText(), "json is text")
+	AssertEqual(t, false, NewFilePart("a.pdf", nil).isText(), "pdf is binary")
+}
@@ -0,0 +1,301 @@
+package swarm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// ErrFixtureNotFound is returned by ReplayClient for requests that were not
+// recorded.
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// DefaultRedactions match common credentials (OpenAI and Slack tokens, AWS
+// access keys and bearer tokens) and are always redacted from fixtures.
+var DefaultRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`(?i)bearer [A-Za-z0-9._~+/-]+=*`),
+}
+
+// Fixture is a recorded LLM call, stored as a golden file named after its
+// key. Exactly one of Completion and Embeddings is set.
+type Fixture struct {
+	// Key is the hash of the redacted, normalized request
+	Key string `json:"key"`
+	// Request is the redacted, normalized request, for reviewing diffs
+	Request json.RawMessage `json:"request"`
+	// Completion is the redacted chat completion
+	Completion json.RawMessage `json:"completion,omitempty"`
+	// Embeddings are the recorded embedding vectors
+	Embeddings [][]float64 `json:"embeddings,omitempty"`
+}
+
+// fixtureStore reads and writes fixtures in a directory. Requests are
+// redacted before hashing, so secrets never reach golden files and the
+// recording and replaying clients must use the same redactions.
+type fixtureStore struct {
+	dir        string
+	redactions []*regexp.Regexp
+	mu         sync.Mutex
+}
+
+func newFixtureStore(dir string) *fixtureStore {
+	return &fixtureStore{dir: dir, redactions: append([]*regexp.Regexp(nil), DefaultRedactions...)}
+}
+
+// redact replaces the secrets in data with redactedValue.
+func (s *fixtureStore) redact(data []byte) []byte {
+	for _, re := range s.redactions {
+		data = re.ReplaceAll(data, []byte(redactedValue))
+	}
+	return data
+}
+
+// addSecrets redacts the literal values of secrets, e.g. from environment
+// variables. Empty values are ignored.
+func (s *fixtureStore) addSecrets(secrets []string) {
+	for _, secret := range secrets {
+		if secret != "" {
+			s.redactions = append(s.redactions, regexp.MustCompile(regexp.QuoteMeta(secret)))
+		}
+	}
+}
+
+// chatRequest returns the key and redacted request of a chat completion.
+func (s *fixtureStore) chatRequest(params openai.ChatCompletionNewParams) (string, []byte, error) {
+	canonical, err := canonicalRequest(params)
+	if err != nil {
+		return "", nil, err
+	}
+	key, redacted := s.keyOf("chat-", canonical)
+	return key, redacted, nil
+}
+
+// embeddingsRequest returns the key and redacted request of an embeddings call.
+func (s *fixtureStore) embeddingsRequest(model string, inputs []string) (string, []byte, error) {
+	canonical, err := json.Marshal(map[string]interface{}{"model": model, "input": inputs})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	key, redacted := s.keyOf("embeddings-", canonical)
+	return key, redacted, nil
+}
+
+// keyOf redacts a canonical request and returns its key and redacted form.
+func (s *fixtureStore) keyOf(prefix string, canonical []byte) (string, []byte) {
+	redacted := s.redact(canonical)
+	sum := sha256.Sum256(redacted)
+	return prefix + hex.EncodeToString(sum[:16]), redacted
+}
+
+func (s *fixtureStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// load reads the fixture of key.
+func (s *fixtureStore) load(key string, model string) (*Fixture, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no recording of this %s request at %s", ErrFixtureNotFound, model, s.path(key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", s.path(key), err)
+	}
+	return &fixture, nil
+}
+
+// save writes a fixture, replacing an earlier recording of the same request.
+func (s *fixtureStore) save(fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(s.path(fixture.Key), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// RecordingClient is an OpenAIClient decorator that forwards calls to a real
+// client and saves each response as a golden file in a directory, for
+// ReplayClient to serve in tests. Secrets matching DefaultRedactions and
+// those added with WithRedaction or WithSecrets are redacted from the files.
+//
+// Streaming requests are recorded as regular completions and returned as a
+// single-chunk stream, so they replay identically.
+type RecordingClient struct {
+	client OpenAIClient
+	store  *fixtureStore
+}
+
+// NewRecordingClient wraps client, recording its responses into dir.
+func NewRecordingClient(client OpenAIClient, dir string) *RecordingClient {
+	return &RecordingClient{client: client, store: newFixtureStore(dir)}
+}
+
+// WithRedaction adds patterns to redact from fixtures and returns the client.
+func (c *RecordingClient) WithRedaction(patterns ...*regexp.Regexp) *RecordingClient {
+	c.store.redactions = append(c.store.redactions, patterns...)
+	return c
+}
+
+// WithSecrets adds literal values to redact from fixtures, such as
+// credentials read from the environment, and returns the client.
+func (c *RecordingClient) WithSecrets(secrets ...string) *RecordingClient {
+	c.store.addSecrets(secrets)
+	return c
+}
+
+// CreateChatCompletion forwards a request and records its response.
+func (c *RecordingClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	key, request, err := c.store.chatRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	completion, err := c.client.CreateChatCompletion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(completion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode completion: %w", err)
+	}
+	data = c.store.redact(data)
+	if err := c.store.save(&Fixture{Key: key, Request: request, Completion: data}); err != nil {
+		return nil, err
+	}
+	// Return the redacted completion, so recording and replaying runs behave
+	// the same
+	var redacted openai.ChatCompletion
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		return nil, fmt.Errorf("failed to decode completion: %w", err)
+	}
+	return &redacted, nil
+}
+
+// CreateChatCompletionStream records a streaming request as a regular
+// completion and returns it as a stream.
+func (c *RecordingClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	// Stream options are rejected by non-streaming requests
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{}
+	completion, err := c.CreateChatCompletion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fixtureStream(completion)
+}
+
+// Embeddings forwards an embeddings request and records its response.
+func (c *RecordingClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	key, request, err := c.store.embeddingsRequest(model, inputs)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := c.client.Embeddings(ctx, model, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.save(&Fixture{Key: key, Request: request, Embeddings: vectors}); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+// ReplayClient is an OpenAIClient that serves responses recorded by a
+// RecordingClient, matching requests by the hash of their redacted,
+// normalized JSON. Requests without a recording fail with
+// ErrFixtureNotFound; it never calls a model.
+type ReplayClient struct {
+	store *fixtureStore
+}
+
+// NewReplayClient creates a client replaying the fixtures in dir.
+func NewReplayClient(dir string) *ReplayClient {
+	return &ReplayClient{store: newFixtureStore(dir)}
+}
+
+// WithRedaction adds the patterns the fixtures were recorded with and returns
+// the client.
+func (c *ReplayClient) WithRedaction(patterns ...*regexp.Regexp) *ReplayClient {
+	c.store.redactions = append(c.store.redactions, patterns...)
+	return c
+}
+
+// WithSecrets adds the literal secrets the fixtures were recorded with and
+// returns the client.
+func (c *ReplayClient) WithSecrets(secrets ...string) *ReplayClient {
+	c.store.addSecrets(secrets)
+	return c
+}
+
+// CreateChatCompletion returns the recorded completion of a request.
+func (c *ReplayClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	key, _, err := c.store.chatRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	fixture, err := c.store.load(key, string(params.Model))
+	if err != nil {
+		return nil, err
+	}
+	if len(fixture.Completion) == 0 {
+		return nil, fmt.Errorf("%w: fixture %s has no completion", ErrFixtureNotFound, key)
+	}
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(fixture.Completion, &completion); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", key, err)
+	}
+	return &completion, nil
+}
+
+// CreateChatCompletionStream returns the recorded completion of a request as
+// a stream.
+func (c *ReplayClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	completion, err := c.CreateChatCompletion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return fixtureStream(completion)
+}
+
+// Embeddings returns the recorded vectors of an embeddings request.
+func (c *ReplayClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	key, _, err := c.store.embeddingsRequest(model, inputs)
+	if err != nil {
+		return nil, err
+	}
+	fixture, err := c.store.load(key, model)
+	if err != nil {
+		return nil, err
+	}
+	return fixture.Embeddings, nil
+}
+
+// fixtureStream returns a completion as a stream.
+func fixtureStream(completion *openai.ChatCompletion) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("recorded completion has no choices")
+	}
+	return completionStream(completion)
+}
@@ -0,0 +1,61 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	mock := NewMockOpenAIClient()
+	mock.SetCompletionResponse(&openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Hello! Your key is sk-abcdefghijklmnopqrstuvwx", Role: "assistant"}},
+		},
+	})
+	mock.EmbeddingResponse = [][]float64{{0.1, 0.2}}
+
+	agent := NewAgent("assistant").WithInstructions("The database password is hunter2.")
+	messages := []map[string]interface{}{{"role": "user", "content": "Hi"}}
+	recorder := NewRecordingClient(mock, dir).WithSecrets("hunter2").WithRedaction(regexp.MustCompile(`acct-\d+`))
+	recorded, err := NewSwarm(recorder).Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertNoError(t, err, "recording run")
+	AssertEqual(t, "Hello! Your key is [REDACTED]", lastContent(recorded), "recorded reply is redacted")
+	_, err = recorder.Embeddings(context.Background(), "text-embedding-3-small", []string{"acct-42"})
+	AssertNoError(t, err, "recording embeddings")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	AssertEqual(t, 2, len(files), "fixture files")
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		for _, secret := range []string{"hunter2", "sk-abcdefghijklmnopqrstuvwx", "acct-42"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("Fixture %s leaks %q", file, secret)
+			}
+		}
+	}
+
+	replay := NewReplayClient(dir).WithSecrets("hunter2").WithRedaction(regexp.MustCompile(`acct-\d+`))
+	s := NewSwarm(replay)
+	replayed, err := s.Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertNoError(t, err, "replaying run")
+	AssertEqual(t, lastContent(recorded), lastContent(replayed), "replayed reply")
+	streamed, err := s.Run(context.Background(), agent, messages, nil, "", true, false, 1, true, false)
+	AssertNoError(t, err, "replaying streamed run")
+	AssertEqual(t, lastContent(recorded), lastContent(streamed), "replayed stream")
+	vectors, err := replay.Embeddings(context.Background(), "text-embedding-3-small", []string{"acct-7"})
+	AssertNoError(t, err, "replaying embeddings with a redacted input")
+	AssertEqual(t, 0.2, vectors[0][1], "replayed vectors")
+
+	_, err = s.Run(context.Background(), agent, []map[string]interface{}{{"role": "user", "content": "Bye"}}, nil, "", false, false, 1, true, false)
+	if !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("Expected ErrFixtureNotFound for an unrecorded request, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package swarm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SessionDiff compares two branches of a conversation.
+type SessionDiff struct {
+	// Common is the number of leading messages the branches share
+	Common int
+	// Left are the messages of the first session after the shared ones
+	Left []map[string]interface{}
+	// Right are the messages of the second session after the shared ones
+	Right []map[string]interface{}
+}
+
+// Fork branches the session at its current end, e.g. to explore two
+// strategies from the same point. See ForkAt.
+func (s *Session) Fork(id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.forkAt(len(s.Messages), id)
+}
+
+// ForkAt branches the session after its first index messages. The branch is
+// an independent session with the same agent, settings and context variables
+// that records the session as its Parent; an empty id generates one. It is
+// saved to the session's store, if any, and can be merged back with Merge.
+func (s *Session) ForkAt(index int, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.forkAt(index, id)
+}
+
+// forkAt branches the session without locking it.
+func (s *Session) forkAt(index int, id string) (*Session, error) {
+	if index < 0 || index > len(s.Messages) {
+		return nil, fmt.Errorf("fork point %d out of range [0, %d]", index, len(s.Messages))
+	}
+	if index < len(s.Messages) {
+		if role, _ := s.Messages[index]["role"].(string); role == "tool" {
+			return nil, fmt.Errorf("fork point %d splits the tool results from their tool calls", index)
+		}
+	}
+
+	branch := s.Swarm.NewSession(s.Agent, id).WithStore(s.Store).WithModel(s.Model).WithMaxTurns(s.MaxTurns)
+	branch.Messages = append([]map[string]interface{}(nil), s.Messages[:index]...)
+	branch.WithContextVariables(s.ContextVariables)
+	branch.Parent, branch.ForkedAt = s.ID, index
+	if err := branch.save(); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+// Compare returns where the session and other diverge.
+func (s *Session) Compare(other *Session) *SessionDiff {
+	left, right := s.snapshotMessages(), other.snapshotMessages()
+	common := 0
+	for common < len(left) && common < len(right) && reflect.DeepEqual(left[common], right[common]) {
+		common++
+	}
+	return &SessionDiff{
+		Common: common,
+		Left:   left[common:],
+		Right:  right[common:],
+	}
+}
+
+// Merge adopts the outcome of a branch forked from the session: the messages
+// after the fork point are replaced by the branch's, and the branch's active
+// agent and context variables become the session's. The merged session is
+// saved to its store, if any.
+func (s *Session) Merge(branch *Session) error {
+	if branch == s {
+		return fmt.Errorf("cannot merge session %s into itself", s.ID)
+	}
+	state := branch.State()
+	if state.Parent != s.ID {
+		return fmt.Errorf("session %s was not forked from %s", branch.ID, s.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.ForkedAt > len(s.Messages) {
+		return fmt.Errorf("fork point %d of session %s is beyond the history of %s", state.ForkedAt, branch.ID, s.ID)
+	}
+	s.Messages = append(s.Messages[:state.ForkedAt:state.ForkedAt], state.Messages[state.ForkedAt:]...)
+	branch.mu.Lock()
+	if branch.Agent != nil {
+		s.Agent = branch.Agent
+	}
+	branch.mu.Unlock()
+	for k, v := range state.ContextVariables {
+		s.ContextVariables[k] = v
+	}
+	return s.save()
+}
+
+// snapshotMessages returns a copy of the session's message list.
+func (s *Session) snapshotMessages() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]interface{}(nil), s.Messages...)
+}
@@ -0,0 +1,60 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessionFork(t *testing.T) {
+	client := newRouterClient(func(prompt string) string {
+		return "re: " + prompt
+	})
+	store := NewMemorySessionStore()
+	session := NewSwarm(client).NewSession(NewAgent("assistant"), "main").WithStore(store)
+	_, err := session.Send(context.Background(), "Plan a trip")
+	AssertNoError(t, err, "send")
+
+	branch, err := session.Fork("alt")
+	AssertNoError(t, err, "fork")
+	AssertEqual(t, "main", branch.Parent, "parent")
+	AssertEqual(t, 2, branch.ForkedAt, "fork point")
+
+	_, err = session.Send(context.Background(), "Go by train")
+	AssertNoError(t, err, "send on main")
+	_, err = branch.Send(context.Background(), "Go by plane")
+	AssertNoError(t, err, "send on branch")
+
+	diff := session.Compare(branch)
+	AssertEqual(t, 2, diff.Common, "shared messages")
+	AssertEqual(t, "Go by train", diff.Left[0]["content"], "main diverges")
+	AssertEqual(t, "Go by plane", diff.Right[0]["content"], "branch diverges")
+
+	stored, err := store.Load("alt")
+	AssertNoError(t, err, "load branch")
+	AssertEqual(t, "main", stored.Parent, "stored parent")
+
+	AssertNoError(t, session.Merge(branch), "merge")
+	AssertEqual(t, 4, len(session.Messages), "merged history")
+	AssertEqual(t, "re: Go by plane", lastContent(&Response{Messages: session.Messages}), "merged reply")
+	AssertError(t, branch.Merge(session), "merge into a session that is not the parent")
+}
+
+func TestSessionForkAt(t *testing.T) {
+	session := NewSwarm(NewMockOpenAIClient()).NewSession(NewAgent("assistant"), "main")
+	session.Messages = []map[string]interface{}{
+		{"role": "user", "content": "Weather?"},
+		{"role": "assistant", "content": ""},
+		{"role": "tool", "tool_call_id": "call-1", "content": "sunny"},
+		{"role": "assistant", "content": "It is sunny"},
+	}
+
+	branch, err := session.ForkAt(1, "")
+	AssertNoError(t, err, "fork before the tool call")
+	AssertEqual(t, 1, len(branch.Messages), "branch history")
+	AssertEqual(t, true, branch.ID != "", "generated ID")
+
+	_, err = session.ForkAt(2, "")
+	AssertError(t, err, "fork between a tool call and its result")
+	_, err = session.ForkAt(5, "")
+	AssertError(t, err, "fork point out of range")
+}
@@ -0,0 +1,317 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoSpeaker is returned when a speaker selector cannot pick an agent.
+var ErrNoSpeaker = errors.New("no speaker selected")
+
+// DefaultGroupChatRounds is the default maximum number of rounds of a GroupChat.
+const DefaultGroupChatRounds = 10
+
+// SpeakerSelector picks the agent that replies next. last is the agent that
+// spoke in the previous round, or nil before the first round.
+type SpeakerSelector func(ctx context.Context, s *Swarm, agents []*Agent, history []map[string]interface{}, last *Agent) (*Agent, error)
+
+// TerminationCondition reports whether a group chat should stop after a
+// round. round counts completed rounds, starting at 1.
+type TerminationCondition func(history []map[string]interface{}, round int) bool
+
+// GroupChat runs several agents in one shared conversation. Each round, the
+// selector picks a speaker who replies (and may call its own tools) with the
+// full conversation in view; other agents' replies are shown to it as named
+// user messages. The chat stops when a termination condition holds or after
+// MaxRounds rounds.
+type GroupChat struct {
+	// Agents are the participants
+	Agents []*Agent
+	// Selector picks the next speaker (default round-robin)
+	Selector SpeakerSelector
+	// Terminations stop the chat when any returns true
+	Terminations []TerminationCondition
+	// MaxRounds caps the number of rounds (default 10)
+	MaxRounds int
+	// MaxTurns caps the model and tool turns within one speaker's reply
+	MaxTurns int
+	// Debug enables debug logging
+	Debug bool
+}
+
+// NewGroupChat creates a round-robin group chat of agents.
+func NewGroupChat(agents ...*Agent) *GroupChat {
+	return &GroupChat{
+		Agents:    agents,
+		Selector:  RoundRobinSelector(),
+		MaxRounds: DefaultGroupChatRounds,
+		MaxTurns:  10,
+	}
+}
+
+// WithSelector sets the speaker selection strategy and returns the chat.
+func (g *GroupChat) WithSelector(selector SpeakerSelector) *GroupChat {
+	if selector != nil {
+		g.Selector = selector
+	}
+	return g
+}
+
+// WithTermination adds termination conditions and returns the chat.
+func (g *GroupChat) WithTermination(conditions ...TerminationCondition) *GroupChat {
+	g.Terminations = append(g.Terminations, conditions...)
+	return g
+}
+
+// WithMaxRounds sets the maximum number of rounds and returns the chat.
+func (g *GroupChat) WithMaxRounds(rounds int) *GroupChat {
+	if rounds > 0 {
+		g.MaxRounds = rounds
+	}
+	return g
+}
+
+// Run runs the group chat starting from messages and returns the messages
+// added by the agents. Response.Agent is the last speaker.
+func (g *GroupChat) Run(ctx context.Context, s *Swarm, messages []map[string]interface{}, contextVariables map[string]interface{}) (*Response, error) {
+	if len(g.Agents) == 0 {
+		return nil, fmt.Errorf("%w: group chat has no agents", ErrInvalidParameter)
+	}
+	if len(messages) == 0 {
+		return nil, ErrEmptyMessages
+	}
+	if contextVariables == nil {
+		contextVariables = make(map[string]interface{})
+	}
+	selector := g.Selector
+	if selector == nil {
+		selector = RoundRobinSelector()
+	}
+	maxRounds := g.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = DefaultGroupChatRounds
+	}
+	maxTurns := g.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = 10
+	}
+
+	history := make([]map[string]interface{}, len(messages))
+	copy(history, messages)
+	initLen := len(messages)
+	var speaker *Agent
+
+	for round := 1; round <= maxRounds; round++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		next, err := selector(ctx, s, g.Agents, history, speaker)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select speaker in round %d: %w", round, err)
+		}
+		if next == nil {
+			return nil, fmt.Errorf("round %d: %w", round, ErrNoSpeaker)
+		}
+		speaker = next
+		DebugPrint(g.Debug, "Group chat round", round, "speaker:", speaker.Name)
+
+		response, err := s.Run(ctx, speaker, speakerView(history, speaker), contextVariables, "", false, g.Debug, maxTurns, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s failed in round %d: %w", speaker.Name, round, err)
+		}
+		// Attribute tool results too, so other speakers don't see them
+		for _, msg := range response.Messages {
+			if _, ok := msg["sender"].(string); !ok {
+				msg["sender"] = speaker.Name
+			}
+		}
+		history = append(history, response.Messages...)
+		for k, v := range response.ContextVariables {
+			contextVariables[k] = v
+		}
+
+		if g.terminated(history, round) {
+			break
+		}
+	}
+
+	return &Response{
+		Messages:         history[initLen:],
+		Agent:            speaker,
+		ContextVariables: contextVariables,
+	}, nil
+}
+
+// terminated reports whether any termination condition holds.
+func (g *GroupChat) terminated(history []map[string]interface{}, round int) bool {
+	for _, cond := range g.Terminations {
+		if cond(history, round) {
+			return true
+		}
+	}
+	return false
+}
+
+// speakerView returns history as seen by speaker: its own messages are kept,
+// while other agents' replies become user messages prefixed with the sender's
+// name and their tool traffic is dropped.
+func speakerView(history []map[string]interface{}, speaker *Agent) []map[string]interface{} {
+	view := make([]map[string]interface{}, 0, len(history))
+	for _, msg := range history {
+		sender, _ := msg["sender"].(string)
+		if sender == "" || sender == speaker.Name {
+			view = append(view, msg)
+			continue
+		}
+		if msg["role"] == "tool" || msg["role"] == "function" {
+			continue
+		}
+		content, _ := msg["content"].(string)
+		if content == "" {
+			continue
+		}
+		view = append(view, map[string]interface{}{
+			"role":    "user",
+			"content": sender + ": " + content,
+		})
+	}
+	return view
+}
+
+// RoundRobinSelector picks agents in order, starting with the first.
+func RoundRobinSelector() SpeakerSelector {
+	return func(_ context.Context, _ *Swarm, agents []*Agent, _ []map[string]interface{}, last *Agent) (*Agent, error) {
+		if len(agents) == 0 {
+			return nil, ErrNoSpeaker
+		}
+		for i, agent := range agents {
+			if agent == last {
+				return agents[(i+1)%len(agents)], nil
+			}
+		}
+		return agents[0], nil
+	}
+}
+
+// SpeakerRule selects Agent (by name) when Match returns true.
+type SpeakerRule struct {
+	// Match inspects the conversation and the previous speaker
+	Match func(history []map[string]interface{}, last *Agent) bool
+	// Agent is the name of the agent to select
+	Agent string
+}
+
+// RuleBasedSelector picks the agent of the first matching rule, falling back
+// to fallback (round-robin if nil) when no rule matches.
+func RuleBasedSelector(rules []SpeakerRule, fallback SpeakerSelector) SpeakerSelector {
+	if fallback == nil {
+		fallback = RoundRobinSelector()
+	}
+	return func(ctx context.Context, s *Swarm, agents []*Agent, history []map[string]interface{}, last *Agent) (*Agent, error) {
+		for _, rule := range rules {
+			if rule.Match == nil || !rule.Match(history, last) {
+				continue
+			}
+			if agent := findAgent(agents, rule.Agent); agent != nil {
+				return agent, nil
+			}
+			return nil, fmt.Errorf("%w: unknown agent %q", ErrNoSpeaker, rule.Agent)
+		}
+		return fallback(ctx, s, agents, history, last)
+	}
+}
+
+// LLMSelector asks the moderator agent which agent should speak next. The
+// moderator is sent the participants and the conversation and must answer
+// with an agent name. Unrecognized answers fall back to round-robin.
+func LLMSelector(moderator *Agent) SpeakerSelector {
+	fallback := RoundRobinSelector()
+	return func(ctx context.Context, s *Swarm, agents []*Agent, history []map[string]interface{}, last *Agent) (*Agent, error) {
+		var prompt strings.Builder
+		prompt.WriteString("You moderate a group conversation. Participants:\n")
+		for _, agent := range agents {
+			prompt.WriteString("- " + agent.Name)
+			if instructions, ok := agent.Instructions.(string); ok && instructions != "" {
+				prompt.WriteString(": " + instructions)
+			}
+			prompt.WriteString("\n")
+		}
+		prompt.WriteString("\nConversation:\n")
+		for _, msg := range history {
+			content, _ := msg["content"].(string)
+			if content == "" || msg["role"] == "tool" {
+				continue
+			}
+			name, _ := msg["sender"].(string)
+			if name == "" {
+				name, _ = msg["role"].(string)
+			}
+			prompt.WriteString(name + ": " + content + "\n")
+		}
+		prompt.WriteString("\nReply with only the name of the participant who should speak next.")
+
+		response, err := s.Run(ctx, moderator, []map[string]interface{}{
+			{"role": "user", "content": prompt.String()},
+		}, nil, "", false, false, 1, false, false)
+		if err != nil {
+			return nil, err
+		}
+		var answer string
+		if n := len(response.Messages); n > 0 {
+			answer, _ = response.Messages[n-1]["content"].(string)
+		}
+		if agent := matchAgentName(agents, answer); agent != nil {
+			return agent, nil
+		}
+		return fallback(ctx, s, agents, history, last)
+	}
+}
+
+// matchAgentName finds the agent named in a model's answer, preferring an
+// exact (case-insensitive) match over a mention.
+func matchAgentName(agents []*Agent, answer string) *Agent {
+	answer = strings.Trim(strings.TrimSpace(answer), `"'.`)
+	for _, agent := range agents {
+		if strings.EqualFold(agent.Name, answer) {
+			return agent
+		}
+	}
+	lower := strings.ToLower(answer)
+	for _, agent := range agents {
+		if strings.Contains(lower, strings.ToLower(agent.Name)) {
+			return agent
+		}
+	}
+	return nil
+}
+
+// findAgent returns the agent with the given name, or nil.
+func findAgent(agents []*Agent, name string) *Agent {
+	for _, agent := range agents {
+		if agent.Name == name {
+			return agent
+		}
+	}
+	return nil
+}
+
+// TerminateOnKeyword stops the chat once the latest message contains keyword,
+// e.g. "TERMINATE" or "APPROVED".
+func TerminateOnKeyword(keyword string) TerminationCondition {
+	return func(history []map[string]interface{}, _ int) bool {
+		if len(history) == 0 {
+			return false
+		}
+		content, _ := history[len(history)-1]["content"].(string)
+		return strings.Contains(content, keyword)
+	}
+}
+
+// TerminateAfterSpeaker stops the chat once the named agent has spoken.
+func TerminateAfterSpeaker(name string) TerminationCondition {
+	return func(history []map[string]interface{}, _ int) bool {
+		return len(history) > 0 && history[len(history)-1]["sender"] == name
+	}
+}
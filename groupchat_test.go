@@ -0,0 +1,113 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// scriptedClient replies with the next content and records each request.
+type scriptedClient struct {
+	*MockOpenAIClient
+	requests []openai.ChatCompletionNewParams
+}
+
+func newScriptedClient(replies ...string) *scriptedClient {
+	mock := NewMockOpenAIClient()
+	for _, reply := range replies {
+		mock.SetCompletionResponse(&openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: reply, Role: "assistant"}},
+			},
+		})
+	}
+	return &scriptedClient{MockOpenAIClient: mock}
+}
+
+func (c *scriptedClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	c.requests = append(c.requests, params)
+	return c.MockOpenAIClient.CreateChatCompletion(ctx, params)
+}
+
+func TestGroupChatRoundRobin(t *testing.T) {
+	client := newScriptedClient("draft", "needs work", "revised", "APPROVED")
+	writer := NewAgent("writer")
+	reviewer := NewAgent("reviewer")
+
+	chat := NewGroupChat(writer, reviewer).WithTermination(TerminateOnKeyword("APPROVED"))
+	response, err := chat.Run(context.Background(), NewSwarm(client), []map[string]interface{}{
+		{"role": "user", "content": "Write a haiku"},
+	}, nil)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, 4, len(response.Messages), "message count")
+	senders := make([]string, len(response.Messages))
+	for i, msg := range response.Messages {
+		senders[i] = msg["sender"].(string)
+	}
+	AssertEqual(t, "writer,reviewer,writer,reviewer", strings.Join(senders, ","), "speaker order")
+	AssertEqual(t, "reviewer", response.Agent.Name, "last speaker")
+
+	// The reviewer sees the writer's draft as a named user message
+	reviewerRequest := client.requests[1]
+	last := reviewerRequest.Messages[len(reviewerRequest.Messages)-1]
+	AssertEqual(t, "writer: draft", last.OfUser.Content.OfString.Value, "reviewer view")
+}
+
+func TestGroupChatMaxRounds(t *testing.T) {
+	client := newScriptedClient("a", "b", "c", "d")
+	chat := NewGroupChat(NewAgent("one"), NewAgent("two")).WithMaxRounds(3)
+	response, err := chat.Run(context.Background(), NewSwarm(client), []map[string]interface{}{
+		{"role": "user", "content": "go"},
+	}, nil)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, 3, len(response.Messages), "rounds")
+	AssertEqual(t, "one", response.Agent.Name, "last speaker")
+}
+
+func TestGroupChatSelectors(t *testing.T) {
+	planner := NewAgent("planner")
+	coder := NewAgent("coder")
+	tester := NewAgent("tester")
+	agents := []*Agent{planner, coder, tester}
+	history := []map[string]interface{}{{"role": "assistant", "sender": "coder", "content": "done, please test"}}
+
+	t.Run("rule based", func(t *testing.T) {
+		selector := RuleBasedSelector([]SpeakerRule{{
+			Match: func(history []map[string]interface{}, _ *Agent) bool {
+				if len(history) == 0 {
+					return false
+				}
+				content, _ := history[len(history)-1]["content"].(string)
+				return strings.Contains(content, "test")
+			},
+			Agent: "tester",
+		}}, nil)
+
+		agent, err := selector(context.Background(), nil, agents, history, coder)
+		AssertNoError(t, err, "rule match")
+		AssertEqual(t, tester, agent, "rule speaker")
+
+		agent, err = selector(context.Background(), nil, agents, history[:0], nil)
+		AssertNoError(t, err, "fallback")
+		AssertEqual(t, planner, agent, "round-robin fallback")
+	})
+
+	t.Run("llm chosen", func(t *testing.T) {
+		client := newScriptedClient("Tester.", "nobody")
+		selector := LLMSelector(NewAgent("moderator"))
+		s := NewSwarm(client)
+
+		agent, err := selector(context.Background(), s, agents, history, coder)
+		AssertNoError(t, err, "LLM selection")
+		AssertEqual(t, tester, agent, "LLM speaker")
+		prompt := client.requests[0].Messages[1].OfUser.Content.OfString.Value
+		AssertEqual(t, true, strings.Contains(prompt, "coder: done, please test"), "prompt includes conversation")
+
+		agent, err = selector(context.Background(), s, agents, history, coder)
+		AssertNoError(t, err, "LLM fallback")
+		AssertEqual(t, tester, agent, "round-robin after coder")
+	})
+}
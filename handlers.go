@@ -0,0 +1,89 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrHandlerNotFound is returned when no step handler is registered under a name.
+	ErrHandlerNotFound = errors.New("handler not found")
+	// ErrHandlerRegistered is returned when registering a duplicate handler name.
+	ErrHandlerRegistered = errors.New("handler already registered")
+)
+
+// HandlerRegistry maps names to step handlers, so workflows loaded from YAML
+// can refer to Go code by name. It is safe for concurrent use.
+type HandlerRegistry struct {
+	handlers map[string]StepFunc
+	mu       sync.RWMutex
+}
+
+// DefaultHandlerRegistry is the registry used by LoadWorkflow when no other
+// registry is given.
+var DefaultHandlerRegistry = NewHandlerRegistry()
+
+// NewHandlerRegistry creates an empty registry.
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{handlers: make(map[string]StepFunc)}
+}
+
+// Register adds the handler under name.
+func (r *HandlerRegistry) Register(name string, handler StepFunc) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: handler name is empty", ErrInvalidName)
+	}
+	if handler == nil {
+		return fmt.Errorf("%w: handler %s is nil", ErrInvalidFunction, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.handlers[name]; exists {
+		return fmt.Errorf("%w: %s", ErrHandlerRegistered, name)
+	}
+	r.handlers[name] = handler
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is intended for
+// package initialization.
+func (r *HandlerRegistry) MustRegister(name string, handler StepFunc) *HandlerRegistry {
+	if err := r.Register(name, handler); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Unregister removes the named handler, if registered.
+func (r *HandlerRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.handlers, name)
+}
+
+// Get returns the named handler.
+func (r *HandlerRegistry) Get(name string) (StepFunc, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHandlerNotFound, name)
+	}
+	return handler, nil
+}
+
+// Names returns the registered handler names in sorted order.
+func (r *HandlerRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,244 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// HedgeOutcome describes one attempt of a hedged request. Losing attempts that
+// completed before being cancelled still report their usage, since the
+// provider bills for it.
+type HedgeOutcome struct {
+	// Provider is 0 for the primary and 1 for the secondary
+	Provider int
+	// Won is true for the attempt whose response was returned
+	Won bool
+	// Usage is the token usage of a completed attempt (zero for streams and
+	// cancelled attempts)
+	Usage openai.CompletionUsage
+	// Latency is how long the attempt ran
+	Latency time.Duration
+	// Err is the attempt's error, if any
+	Err error
+}
+
+// HedgeStats summarizes a HedgingClient's behaviour.
+type HedgeStats struct {
+	// Requests is the number of requests sent
+	Requests int64
+	// Hedged is the number of requests that fired the secondary
+	Hedged int64
+	// SecondaryWins is the number of requests answered by the secondary
+	SecondaryWins int64
+	// WastedTokens is the total tokens of completed attempts that lost
+	WastedTokens int64
+}
+
+// HedgingClient is an OpenAIClient for latency-sensitive calls. It sends each
+// request to the primary and, if no response arrives within the hedge delay
+// (or the primary fails), sends the same request to the secondary. The first
+// success is returned and the other attempt is cancelled.
+type HedgingClient struct {
+	primary   OpenAIClient
+	secondary OpenAIClient
+	delay     time.Duration
+	onOutcome func(HedgeOutcome)
+
+	stats HedgeStats
+	mu    sync.Mutex
+}
+
+// NewHedgingClient creates a client that hedges requests to primary with
+// secondary after delay.
+func NewHedgingClient(primary, secondary OpenAIClient, delay time.Duration) *HedgingClient {
+	return &HedgingClient{primary: primary, secondary: secondary, delay: delay}
+}
+
+// WithOutcomeCallback registers fn to receive every attempt's outcome, for
+// cost accounting of both winning and losing attempts, and returns the client.
+// fn may be called after the request has returned and must be safe for
+// concurrent use.
+func (h *HedgingClient) WithOutcomeCallback(fn func(HedgeOutcome)) *HedgingClient {
+	h.onOutcome = fn
+	return h
+}
+
+// Stats returns a snapshot of the client's hedging statistics.
+func (h *HedgingClient) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+// CreateChatCompletion returns the first successful completion.
+func (h *HedgingClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	return hedge(h, ctx,
+		func(ctx context.Context, client OpenAIClient) (*openai.ChatCompletion, error) {
+			return client.CreateChatCompletion(ctx, params)
+		},
+		func(completion *openai.ChatCompletion) openai.CompletionUsage {
+			if completion == nil {
+				return openai.CompletionUsage{}
+			}
+			return completion.Usage
+		},
+		nil)
+}
+
+// CreateChatCompletionStream returns the first stream to be established,
+// closing the other.
+func (h *HedgingClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	return hedge(h, ctx,
+		func(ctx context.Context, client OpenAIClient) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+			stream, err := client.CreateChatCompletionStream(ctx, params)
+			if err == nil && stream != nil && stream.Err() != nil {
+				err = stream.Err()
+				stream.Close()
+			}
+			return stream, err
+		},
+		func(*ssestream.Stream[openai.ChatCompletionChunk]) openai.CompletionUsage {
+			return openai.CompletionUsage{}
+		},
+		func(stream *ssestream.Stream[openai.ChatCompletionChunk]) {
+			if stream != nil {
+				stream.Close()
+			}
+		})
+}
+
+// Embeddings returns the first successful embeddings response.
+func (h *HedgingClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	return hedge(h, ctx,
+		func(ctx context.Context, client OpenAIClient) ([][]float64, error) {
+			return client.Embeddings(ctx, model, inputs)
+		},
+		func([][]float64) openai.CompletionUsage {
+			return openai.CompletionUsage{}
+		},
+		nil)
+}
+
+// hedgeAttempt is the result of one attempt.
+type hedgeAttempt[T any] struct {
+	provider int
+	result   T
+	err      error
+	latency  time.Duration
+}
+
+// hedge runs call against the primary and, after the delay or a primary
+// failure, against the secondary. usage extracts token usage for accounting
+// and discard releases a losing result.
+func hedge[T any](h *HedgingClient, ctx context.Context,
+	call func(context.Context, OpenAIClient) (T, error),
+	usage func(T) openai.CompletionUsage,
+	discard func(T)) (T, error) {
+	h.mu.Lock()
+	h.stats.Requests++
+	h.mu.Unlock()
+
+	results := make(chan hedgeAttempt[T], 2)
+	var cancels [2]context.CancelFunc
+	launch := func(provider int, client OpenAIClient) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels[provider] = cancel
+		go func() {
+			start := time.Now()
+			result, err := call(attemptCtx, client)
+			results <- hedgeAttempt[T]{provider: provider, result: result, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	launch(0, h.primary)
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	var zero T
+	var errs []error
+	pending, hedged := 1, false
+	fireSecondary := func() {
+		if hedged {
+			return
+		}
+		hedged = true
+		pending++
+		h.mu.Lock()
+		h.stats.Hedged++
+		h.mu.Unlock()
+		launch(1, h.secondary)
+	}
+
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			fireSecondary()
+		case <-ctx.Done():
+			for _, cancel := range cancels {
+				if cancel != nil {
+					cancel()
+				}
+			}
+			go drainHedge(h, results, pending, usage, discard)
+			return zero, ctx.Err()
+		case attempt := <-results:
+			pending--
+			if attempt.err != nil {
+				errs = append(errs, fmt.Errorf("provider %d: %w", attempt.provider, attempt.err))
+				cancels[attempt.provider]()
+				h.report(attempt.provider, false, usage(attempt.result), attempt.latency, attempt.err)
+				fireSecondary()
+				continue
+			}
+
+			// Cancel the loser and account for it in the background. Results
+			// without a discard func are fully read, so the winner's context can
+			// be released too; streams keep theirs until the parent is done.
+			for i, cancel := range cancels {
+				if cancel != nil && (i != attempt.provider || discard == nil) {
+					cancel()
+				}
+			}
+			go drainHedge(h, results, pending, usage, discard)
+			if attempt.provider == 1 {
+				h.mu.Lock()
+				h.stats.SecondaryWins++
+				h.mu.Unlock()
+			}
+			h.report(attempt.provider, true, usage(attempt.result), attempt.latency, nil)
+			return attempt.result, nil
+		}
+	}
+	return zero, fmt.Errorf("all hedged attempts failed: %w", errors.Join(errs...))
+}
+
+// drainHedge waits for n outstanding losing attempts, releasing their results
+// and reporting their usage.
+func drainHedge[T any](h *HedgingClient, results <-chan hedgeAttempt[T], n int, usage func(T) openai.CompletionUsage, discard func(T)) {
+	for ; n > 0; n-- {
+		attempt := <-results
+		u := usage(attempt.result)
+		if attempt.err == nil {
+			h.mu.Lock()
+			h.stats.WastedTokens += u.TotalTokens
+			h.mu.Unlock()
+			if discard != nil {
+				discard(attempt.result)
+			}
+		}
+		h.report(attempt.provider, false, u, attempt.latency, attempt.err)
+	}
+}
+
+// report delivers an attempt outcome to the callback.
+func (h *HedgingClient) report(provider int, won bool, usage openai.CompletionUsage, latency time.Duration, err error) {
+	if h.onOutcome != nil {
+		h.onOutcome(HedgeOutcome{Provider: provider, Won: won, Usage: usage, Latency: latency, Err: err})
+	}
+}
@@ -0,0 +1,112 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// delayedClient answers after a delay unless its context is cancelled.
+type delayedClient struct {
+	MockOpenAIClient
+	delay   time.Duration
+	content string
+	err     error
+}
+
+func (d *delayedClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	select {
+	case <-time.After(d.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: d.content, Role: "assistant"}},
+		},
+		Usage: openai.CompletionUsage{TotalTokens: 10},
+	}, nil
+}
+
+func TestHedgingClient(t *testing.T) {
+	params := openai.ChatCompletionNewParams{Model: openai.ChatModel("gpt-4o")}
+
+	t.Run("fast primary is not hedged", func(t *testing.T) {
+		client := NewHedgingClient(&delayedClient{content: "primary"}, &delayedClient{content: "secondary"}, time.Second)
+		completion, err := client.CreateChatCompletion(context.Background(), params)
+		AssertNoError(t, err, "CreateChatCompletion")
+		AssertEqual(t, "primary", completion.Choices[0].Message.Content, "Primary should win")
+		AssertEqual(t, int64(0), client.Stats().Hedged, "No hedge expected")
+	})
+
+	t.Run("slow primary is hedged and cancelled", func(t *testing.T) {
+		var mu sync.Mutex
+		var outcomes []HedgeOutcome
+		done := make(chan struct{})
+		client := NewHedgingClient(
+			&delayedClient{delay: time.Second, content: "primary"},
+			&delayedClient{content: "secondary"},
+			10*time.Millisecond,
+		).WithOutcomeCallback(func(o HedgeOutcome) {
+			mu.Lock()
+			defer mu.Unlock()
+			outcomes = append(outcomes, o)
+			if len(outcomes) == 2 {
+				close(done)
+			}
+		})
+
+		start := time.Now()
+		completion, err := client.CreateChatCompletion(context.Background(), params)
+		AssertNoError(t, err, "CreateChatCompletion")
+		AssertEqual(t, "secondary", completion.Choices[0].Message.Content, "Secondary should win")
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("Hedged request should not wait for the slow primary")
+		}
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the cancelled primary to be reported")
+		}
+		stats := client.Stats()
+		AssertEqual(t, int64(1), stats.Hedged, "Hedged")
+		AssertEqual(t, int64(1), stats.SecondaryWins, "SecondaryWins")
+		mu.Lock()
+		defer mu.Unlock()
+		for _, o := range outcomes {
+			if o.Provider == 0 {
+				AssertEqual(t, false, o.Won, "Primary lost")
+				AssertEqual(t, true, errors.Is(o.Err, context.Canceled), "Primary should be cancelled")
+			}
+		}
+	})
+
+	t.Run("failing primary hedges immediately", func(t *testing.T) {
+		client := NewHedgingClient(
+			&delayedClient{err: newAPIError(500)},
+			&delayedClient{content: "secondary"},
+			time.Hour,
+		)
+		completion, err := client.CreateChatCompletion(context.Background(), params)
+		AssertNoError(t, err, "CreateChatCompletion")
+		AssertEqual(t, "secondary", completion.Choices[0].Message.Content, "Secondary should win")
+	})
+
+	t.Run("both failing", func(t *testing.T) {
+		client := NewHedgingClient(
+			&delayedClient{err: newAPIError(500)},
+			&delayedClient{err: newAPIError(503)},
+			time.Millisecond,
+		)
+		_, err := client.CreateChatCompletion(context.Background(), params)
+		AssertError(t, err, "Both providers failed")
+	})
+}
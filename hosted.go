@@ -0,0 +1,360 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// Hosted tool types supported by the OpenAI Responses API.
+const (
+	HostedToolWebSearch  = "web_search"
+	HostedToolFileSearch = "file_search"
+)
+
+// HostedTool is a tool executed by the model provider rather than by a local
+// AgentFunction. Agents with hosted tools are served through the Responses
+// API, which the client translates to and from chat completions.
+type HostedTool struct {
+	// Type is HostedToolWebSearch or HostedToolFileSearch
+	Type string
+	// VectorStoreIDs are the vector stores searched by file_search
+	VectorStoreIDs []string
+}
+
+// WithHostedTool adds a provider-hosted tool such as "web_search" and returns
+// the agent for chaining. Use WithFileSearch for file_search.
+func (a *Agent) WithHostedTool(toolType string) *Agent {
+	a.HostedTools = append(a.HostedTools, HostedTool{Type: toolType})
+	return a
+}
+
+// WithFileSearch adds the hosted file_search tool over the given vector stores
+// and returns the agent for chaining.
+func (a *Agent) WithFileSearch(vectorStoreIDs ...string) *Agent {
+	a.HostedTools = append(a.HostedTools, HostedTool{Type: HostedToolFileSearch, VectorStoreIDs: vectorStoreIDs})
+	return a
+}
+
+// hostedToolsContextKey carries an agent's hosted tools to the client.
+type hostedToolsContextKey struct{}
+
+// withHostedTools returns ctx carrying tools for the client to translate.
+func withHostedTools(ctx context.Context, tools []HostedTool) context.Context {
+	if len(tools) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, hostedToolsContextKey{}, tools)
+}
+
+// hostedToolsFromContext returns the hosted tools carried by ctx.
+func hostedToolsFromContext(ctx context.Context) []HostedTool {
+	tools, _ := ctx.Value(hostedToolsContextKey{}).([]HostedTool)
+	return tools
+}
+
+// responsesTool converts a hosted tool to its Responses API definition.
+func (t HostedTool) responsesTool() (map[string]interface{}, error) {
+	switch t.Type {
+	case HostedToolWebSearch:
+		return map[string]interface{}{"type": "web_search_preview"}, nil
+	case HostedToolFileSearch:
+		if len(t.VectorStoreIDs) == 0 {
+			return nil, fmt.Errorf("%w: file_search requires vector store IDs", ErrInvalidParameter)
+		}
+		return map[string]interface{}{"type": "file_search", "vector_store_ids": t.VectorStoreIDs}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported hosted tool %q", ErrInvalidParameter, t.Type)
+	}
+}
+
+// chatRequest is the subset of a chat completion request translated to the
+// Responses API.
+type chatRequest struct {
+	Model           string                   `json:"model"`
+	Messages        []map[string]interface{} `json:"messages"`
+	Tools           []map[string]interface{} `json:"tools"`
+	ToolChoice      interface{}              `json:"tool_choice"`
+	Temperature     *float64                 `json:"temperature"`
+	MaxTokens       *int64                   `json:"max_completion_tokens"`
+	ReasoningEffort string                   `json:"reasoning_effort"`
+	ResponseFormat  map[string]interface{}   `json:"response_format"`
+}
+
+// toResponsesRequest translates chat completion params and hosted tools into a
+// Responses API request body.
+func toResponsesRequest(params openai.ChatCompletionNewParams, hosted []HostedTool) (map[string]interface{}, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var chat chatRequest
+	if err := json.Unmarshal(data, &chat); err != nil {
+		return nil, fmt.Errorf("failed to translate request: %w", err)
+	}
+
+	var instructions []string
+	input := make([]map[string]interface{}, 0, len(chat.Messages))
+	for _, msg := range chat.Messages {
+		role, _ := msg["role"].(string)
+		switch role {
+		case "system", "developer":
+			if text := textContent(msg["content"]); text != "" {
+				instructions = append(instructions, text)
+			}
+		case "tool":
+			input = append(input, map[string]interface{}{
+				"type":    "function_call_output",
+				"call_id": msg["tool_call_id"],
+				"output":  textContent(msg["content"]),
+			})
+		case "assistant":
+			if text := textContent(msg["content"]); text != "" {
+				input = append(input, map[string]interface{}{"role": "assistant", "content": text})
+			}
+			toolCalls, _ := msg["tool_calls"].([]interface{})
+			for _, tc := range toolCalls {
+				call, _ := tc.(map[string]interface{})
+				fn, _ := call["function"].(map[string]interface{})
+				input = append(input, map[string]interface{}{
+					"type":      "function_call",
+					"call_id":   call["id"],
+					"name":      fn["name"],
+					"arguments": fn["arguments"],
+				})
+			}
+		default:
+			input = append(input, map[string]interface{}{"role": role, "content": inputContent(msg["content"])})
+		}
+	}
+
+	tools := make([]map[string]interface{}, 0, len(chat.Tools)+len(hosted))
+	for _, tool := range chat.Tools {
+		fn, _ := tool["function"].(map[string]interface{})
+		tools = append(tools, map[string]interface{}{
+			"type":        "function",
+			"name":        fn["name"],
+			"description": fn["description"],
+			"parameters":  fn["parameters"],
+		})
+	}
+	for _, tool := range hosted {
+		def, err := tool.responsesTool()
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, def)
+	}
+
+	body := map[string]interface{}{
+		"model": chat.Model,
+		"input": input,
+		"tools": tools,
+		"store": false,
+	}
+	if len(instructions) > 0 {
+		body["instructions"] = strings.Join(instructions, "\n\n")
+	}
+	if chat.Temperature != nil {
+		body["temperature"] = *chat.Temperature
+	}
+	if chat.MaxTokens != nil {
+		body["max_output_tokens"] = *chat.MaxTokens
+	}
+	if chat.ReasoningEffort != "" {
+		body["reasoning"] = map[string]interface{}{"effort": chat.ReasoningEffort}
+	}
+	if chat.ResponseFormat != nil {
+		body["text"] = map[string]interface{}{"format": chat.ResponseFormat}
+	}
+	switch choice := chat.ToolChoice.(type) {
+	case string:
+		body["tool_choice"] = choice
+	case map[string]interface{}:
+		if fn, ok := choice["function"].(map[string]interface{}); ok {
+			body["tool_choice"] = map[string]interface{}{"type": "function", "name": fn["name"]}
+		}
+	}
+	return body, nil
+}
+
+// textContent flattens chat message content to text.
+func textContent(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []interface{}:
+		var parts []string
+		for _, p := range c {
+			if part, ok := p.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+// inputContent converts chat user content parts to Responses input content.
+func inputContent(content interface{}) interface{} {
+	parts, ok := content.([]interface{})
+	if !ok {
+		return content
+	}
+	converted := make([]map[string]interface{}, 0, len(parts))
+	for _, p := range parts {
+		part, _ := p.(map[string]interface{})
+		switch part["type"] {
+		case "text":
+			converted = append(converted, map[string]interface{}{"type": "input_text", "text": part["text"]})
+		case "image_url":
+			image, _ := part["image_url"].(map[string]interface{})
+			converted = append(converted, map[string]interface{}{"type": "input_image", "image_url": image["url"]})
+		case "file":
+			file, _ := part["file"].(map[string]interface{})
+			item := map[string]interface{}{"type": "input_file"}
+			for k, v := range file {
+				item[k] = v
+			}
+			converted = append(converted, item)
+		}
+	}
+	return converted
+}
+
+// responsesResult is the subset of a Responses API result translated back to
+// a chat completion.
+type responsesResult struct {
+	ID     string `json:"id"`
+	Model  string `json:"model"`
+	Output []struct {
+		Type      string `json:"type"`
+		CallID    string `json:"call_id"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+		Content   []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+		TotalTokens  int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// toChatCompletion converts a Responses API result to a chat completion.
+// Hosted tool calls are executed by the provider and only their resulting
+// text is returned; local function calls become tool calls.
+func (r responsesResult) toChatCompletion() (*openai.ChatCompletion, error) {
+	var text []string
+	toolCalls := make([]map[string]interface{}, 0)
+	for _, item := range r.Output {
+		switch item.Type {
+		case "message":
+			for _, c := range item.Content {
+				if c.Type == "output_text" {
+					text = append(text, c.Text)
+				}
+			}
+		case "function_call":
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":       item.CallID,
+				"type":     "function",
+				"function": map[string]interface{}{"name": item.Name, "arguments": item.Arguments},
+			})
+		}
+	}
+
+	message := map[string]interface{}{"role": "assistant", "content": strings.Join(text, "")}
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+		finishReason = "tool_calls"
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"id":      r.ID,
+		"object":  "chat.completion",
+		"model":   r.Model,
+		"choices": []map[string]interface{}{{"index": 0, "finish_reason": finishReason, "message": message}},
+		"usage": map[string]interface{}{
+			"prompt_tokens":     r.Usage.InputTokens,
+			"completion_tokens": r.Usage.OutputTokens,
+			"total_tokens":      r.Usage.TotalTokens,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var completion openai.ChatCompletion
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return nil, fmt.Errorf("failed to translate response: %w", err)
+	}
+	return &completion, nil
+}
+
+// createResponse serves a chat completion request with hosted tools through
+// the Responses API.
+func (c *openAIClientWrapper) createResponse(ctx context.Context, params openai.ChatCompletionNewParams, hosted []HostedTool) (*openai.ChatCompletion, error) {
+	body, err := toResponsesRequest(params, hosted)
+	if err != nil {
+		return nil, err
+	}
+	var result responsesResult
+	if err := c.client.Post(ctx, "responses", body, &result); err != nil {
+		return nil, fmt.Errorf("failed to create response: %w", wrapRateLimit(err))
+	}
+	return result.toChatCompletion()
+}
+
+// completionStream wraps a finished completion as a single-chunk stream, for
+// streaming requests served by non-streaming APIs.
+func completionStream(completion *openai.ChatCompletion) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	choice := completion.Choices[0]
+	toolCalls := make([]map[string]interface{}, len(choice.Message.ToolCalls))
+	for i, tc := range choice.Message.ToolCalls {
+		toolCalls[i] = map[string]interface{}{
+			"index":    i,
+			"id":       tc.ID,
+			"type":     "function",
+			"function": map[string]interface{}{"name": tc.Function.Name, "arguments": tc.Function.Arguments},
+		}
+	}
+	delta := map[string]interface{}{"role": "assistant", "content": choice.Message.Content}
+	if len(toolCalls) > 0 {
+		delta["tool_calls"] = toolCalls
+	}
+
+	var buf bytes.Buffer
+	for _, chunk := range []map[string]interface{}{
+		{"choices": []map[string]interface{}{{"index": 0, "delta": delta}}},
+		{"choices": []map[string]interface{}{{"index": 0, "delta": map[string]interface{}{}, "finish_reason": choice.FinishReason}}, "usage": completion.Usage},
+	} {
+		chunk["id"] = completion.ID
+		chunk["object"] = "chat.completion.chunk"
+		chunk["model"] = completion.Model
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", data)
+	}
+	buf.WriteString("data: [DONE]\n\n")
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(&buf),
+	}
+	return ssestream.NewStream[openai.ChatCompletionChunk](ssestream.NewDecoder(resp), nil), nil
+}
@@ -0,0 +1,107 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/openai/openai-go/option"
+)
+
+// newResponsesServer serves the Responses API, replying with a function call
+// first and a message afterwards, and records request bodies.
+func newResponsesServer(t *testing.T, requests *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AssertEqual(t, "/responses", r.URL.Path, "request path")
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		*requests = append(*requests, body)
+		w.Header().Set("Content-Type", "application/json")
+		if len(*requests) == 1 {
+			fmt.Fprint(w, `{"id":"resp_1","model":"gpt-4o","output":[
+				{"type":"web_search_call","id":"ws_1","status":"completed"},
+				{"type":"function_call","call_id":"call_1","name":"lookup","arguments":"{\"city\":\"Paris\"}"}
+			],"usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"resp_2","model":"gpt-4o","output":[
+			{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Sunny in Paris."}]}
+		],"usage":{"input_tokens":20,"output_tokens":5,"total_tokens":25}}`)
+	}))
+}
+
+func newLookupAgent() *Agent {
+	agent := NewAgent("Searcher").WithModel("gpt-4o").WithInstructions("Search the web.").WithHostedTool(HostedToolWebSearch)
+	agent.Functions = []AgentFunction{NewAgentFunction("lookup", "Look up a city",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "found " + fmt.Sprint(args["city"]), nil
+		}, []Parameter{{Name: "city", Type: reflect.TypeOf(""), Description: "City name", Required: true}})}
+	return agent
+}
+
+func TestRunWithHostedTools(t *testing.T) {
+	var requests []map[string]interface{}
+	server := newResponsesServer(t, &requests)
+	defer server.Close()
+
+	swarm := NewSwarm(NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL)))
+	messages := []map[string]interface{}{{"role": "user", "content": "Weather in Paris?"}}
+	response, err := swarm.Run(context.Background(), newLookupAgent(), messages, nil, "", false, false, 5, true, false)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, 2, len(requests), "Responses API calls")
+	first := requests[0]
+	AssertEqual(t, "Search the web.", first["instructions"], "instructions")
+	tools := first["tools"].([]interface{})
+	AssertEqual(t, 2, len(tools), "function and hosted tools")
+	AssertEqual(t, "lookup", tools[0].(map[string]interface{})["name"], "function tool")
+	AssertEqual(t, "web_search_preview", tools[1].(map[string]interface{})["type"], "hosted tool")
+
+	input := requests[1]["input"].([]interface{})
+	output := input[len(input)-1].(map[string]interface{})
+	AssertEqual(t, "function_call_output", output["type"], "tool result item")
+	AssertEqual(t, "call_1", output["call_id"], "tool result call ID")
+	AssertEqual(t, "found Paris", output["output"], "tool result output")
+
+	last := response.Messages[len(response.Messages)-1]
+	AssertEqual(t, "Sunny in Paris.", last["content"], "final content")
+}
+
+func TestRunAndStreamWithHostedTools(t *testing.T) {
+	var requests []map[string]interface{}
+	server := newResponsesServer(t, &requests)
+	defer server.Close()
+
+	swarm := NewSwarm(NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL)))
+	messages := []map[string]interface{}{{"role": "user", "content": "Weather in Paris?"}}
+	ch, err := swarm.RunAndStream(context.Background(), newLookupAgent(), messages, nil, "", false, 5, true, false)
+	AssertNoError(t, err, "RunAndStream")
+
+	var response *Response
+	for msg := range ch {
+		if r, ok := msg["response"].(*Response); ok {
+			response = r
+		}
+	}
+	if response == nil {
+		t.Fatal("Expected final response")
+	}
+	AssertEqual(t, 2, len(requests), "Responses API calls")
+	AssertEqual(t, "Sunny in Paris.", response.Messages[len(response.Messages)-1]["content"], "final content")
+}
+
+func TestHostedToolValidation(t *testing.T) {
+	_, err := HostedTool{Type: HostedToolFileSearch}.responsesTool()
+	AssertError(t, err, "file_search without vector stores")
+	_, err = HostedTool{Type: "code_interpreter"}.responsesTool()
+	AssertError(t, err, "unsupported hosted tool")
+
+	agent := NewAgent("Docs").WithFileSearch("vs_1")
+	def, err := agent.HostedTools[0].responsesTool()
+	AssertNoError(t, err, "file_search")
+	AssertEqual(t, "file_search", def["type"], "file_search type")
+}
@@ -0,0 +1,306 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// redactedValue replaces sensitive values in logged requests.
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are always redacted from logged requests.
+var sensitiveHeaders = []string{"Authorization", "Api-Key", "Openai-Organization", "Openai-Project"}
+
+// RequestLog describes a single LLM request, either at the HTTP level (one per
+// attempt, see RequestLogger.Middleware) or at the client level (one per call,
+// see NewLoggingClient).
+type RequestLog struct {
+	// Time is when the request started
+	Time time.Time `json:"time"`
+	// Method and URL identify HTTP requests
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Model is the requested model for client-level logs
+	Model string `json:"model,omitempty"`
+	// Stream reports whether a streaming completion was requested
+	Stream bool `json:"stream,omitempty"`
+	// Attempt is the 1-based HTTP attempt number; values above 1 are retries
+	Attempt int `json:"attempt,omitempty"`
+	// StatusCode is the HTTP status code, if a response was received
+	StatusCode int `json:"status_code,omitempty"`
+	// Latency is how long the request took
+	Latency time.Duration `json:"latency"`
+	// Headers are the request headers with credentials redacted
+	Headers http.Header `json:"headers,omitempty"`
+	// RequestBody is the request body (or params), if body logging is enabled
+	RequestBody string `json:"request_body,omitempty"`
+	// ResponseBody is the response body, if body logging is enabled
+	ResponseBody string `json:"response_body,omitempty"`
+	// Error is the request error, if any
+	Error string `json:"error,omitempty"`
+}
+
+// RequestInspector receives every logged request.
+type RequestInspector func(entry RequestLog)
+
+// RequestLogger records LLM requests to a log.Logger or an inspector callback.
+// Logging, body capture and redaction can be toggled at runtime and are safe to
+// change while requests are in flight.
+type RequestLogger struct {
+	enabled   atomic.Bool
+	bodies    atomic.Bool
+	redact    atomic.Bool
+	logger    *log.Logger
+	inspector RequestInspector
+}
+
+// NewRequestLogger creates an enabled RequestLogger. If inspector is nil,
+// entries are written as JSON to the standard logger. Bodies are not captured
+// and message contents are redacted until configured otherwise.
+func NewRequestLogger(inspector RequestInspector) *RequestLogger {
+	l := &RequestLogger{logger: log.Default(), inspector: inspector}
+	l.enabled.Store(true)
+	l.redact.Store(true)
+	return l
+}
+
+// WithLogger sets the log.Logger used when no inspector is configured.
+func (l *RequestLogger) WithLogger(logger *log.Logger) *RequestLogger {
+	if logger != nil {
+		l.logger = logger
+	}
+	return l
+}
+
+// SetEnabled turns logging on or off.
+func (l *RequestLogger) SetEnabled(enabled bool) {
+	l.enabled.Store(enabled)
+}
+
+// Enabled reports whether logging is on.
+func (l *RequestLogger) Enabled() bool {
+	return l.enabled.Load()
+}
+
+// SetLogBodies turns request and response body capture on or off.
+func (l *RequestLogger) SetLogBodies(enabled bool) {
+	l.bodies.Store(enabled)
+}
+
+// SetRedact controls whether message contents in captured bodies are redacted.
+func (l *RequestLogger) SetRedact(enabled bool) {
+	l.redact.Store(enabled)
+}
+
+// record delivers an entry to the inspector or logger.
+func (l *RequestLogger) record(entry RequestLog) {
+	if l.inspector != nil {
+		l.inspector(entry)
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		l.logger.Printf("swarm: failed to marshal request log: %v", err)
+		return
+	}
+	l.logger.Printf("swarm: %s", data)
+}
+
+// body prepares a captured body for logging.
+func (l *RequestLogger) body(data []byte) string {
+	if !l.redact.Load() {
+		return string(data)
+	}
+	return redactBody(data)
+}
+
+// Middleware returns an openai-go middleware that logs every HTTP attempt,
+// including retries, with status code and latency. Streaming response bodies
+// are never captured. Use it with NewOpenAIClientWithOptions:
+//
+//	client := NewOpenAIClientWithOptions(option.WithAPIKey(key), option.WithMiddleware(logger.Middleware()))
+func (l *RequestLogger) Middleware() option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if !l.enabled.Load() {
+			return next(req)
+		}
+
+		entry := RequestLog{
+			Time:    time.Now(),
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Attempt: 1,
+			Headers: redactHeaders(req.Header),
+		}
+		if n, err := strconv.Atoi(req.Header.Get("X-Stainless-Retry-Count")); err == nil {
+			entry.Attempt = n + 1
+		}
+		if l.bodies.Load() && req.GetBody != nil {
+			if body, err := req.GetBody(); err == nil {
+				data, _ := io.ReadAll(body)
+				body.Close()
+				entry.RequestBody = l.body(data)
+			}
+		}
+
+		resp, err := next(req)
+		entry.Latency = time.Since(entry.Time)
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if resp != nil {
+			entry.StatusCode = resp.StatusCode
+			entry.Stream = strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+			if l.bodies.Load() && !entry.Stream && resp.Body != nil {
+				data, readErr := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(data))
+				if readErr == nil {
+					entry.ResponseBody = l.body(data)
+				}
+			}
+		}
+		l.record(entry)
+		return resp, err
+	}
+}
+
+// redactHeaders returns a copy of h with credentials removed.
+func redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, key := range sensitiveHeaders {
+		if out.Get(key) != "" {
+			out.Set(key, redactedValue)
+		}
+	}
+	return out
+}
+
+// redactBody replaces message contents in a JSON body. Non-JSON bodies are
+// redacted entirely.
+func redactBody(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return redactedValue
+	}
+	redactValue(v)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return redactedValue
+	}
+	return string(out)
+}
+
+// redactValue recursively replaces "content", "arguments" and "input" fields.
+func redactValue(v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if k == "content" || k == "arguments" || k == "input" {
+				if child != nil {
+					val[k] = redactedValue
+				}
+				continue
+			}
+			redactValue(child)
+		}
+	case []interface{}:
+		for _, child := range val {
+			redactValue(child)
+		}
+	}
+}
+
+// loggingClient is an OpenAIClient decorator that logs every call.
+type loggingClient struct {
+	client OpenAIClient
+	logger *RequestLogger
+}
+
+// NewLoggingClient wraps client so every chat completion call is recorded with
+// its params, latency, status code and error. It works with any OpenAIClient;
+// use RequestLogger.Middleware for per-attempt HTTP details.
+func NewLoggingClient(client OpenAIClient, logger *RequestLogger) OpenAIClient {
+	return &loggingClient{client: client, logger: logger}
+}
+
+// CreateChatCompletion logs and forwards a chat completion request.
+func (c *loggingClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if !c.logger.Enabled() {
+		return c.client.CreateChatCompletion(ctx, params)
+	}
+	entry := c.start(string(params.Model), params, false)
+	completion, err := c.client.CreateChatCompletion(ctx, params)
+	if err == nil && c.logger.bodies.Load() {
+		if data, merr := json.Marshal(completion); merr == nil {
+			entry.ResponseBody = c.logger.body(data)
+		}
+	}
+	c.finish(entry, err)
+	return completion, err
+}
+
+// CreateChatCompletionStream logs and forwards a streaming request. Latency
+// covers stream creation only.
+func (c *loggingClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	if !c.logger.Enabled() {
+		return c.client.CreateChatCompletionStream(ctx, params)
+	}
+	entry := c.start(string(params.Model), params, true)
+	stream, err := c.client.CreateChatCompletionStream(ctx, params)
+	c.finish(entry, err)
+	return stream, err
+}
+
+// Embeddings logs and forwards an embeddings request.
+func (c *loggingClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	if !c.logger.Enabled() {
+		return c.client.Embeddings(ctx, model, inputs)
+	}
+	entry := c.start(model, map[string]interface{}{"model": model, "input": inputs}, false)
+	vectors, err := c.client.Embeddings(ctx, model, inputs)
+	c.finish(entry, err)
+	return vectors, err
+}
+
+// start creates the log entry for a call.
+func (c *loggingClient) start(model string, params interface{}, stream bool) RequestLog {
+	entry := RequestLog{Time: time.Now(), Model: model, Stream: stream}
+	if c.logger.bodies.Load() {
+		if data, err := json.Marshal(params); err == nil {
+			entry.RequestBody = c.logger.body(data)
+		}
+	}
+	return entry
+}
+
+// finish completes and records the log entry for a call.
+func (c *loggingClient) finish(entry RequestLog, err error) {
+	entry.Latency = time.Since(entry.Time)
+	if err != nil {
+		entry.Error = err.Error()
+		var apiErr *openai.Error
+		if errors.As(err, &apiErr) {
+			entry.StatusCode = apiErr.StatusCode
+		}
+	} else {
+		entry.StatusCode = http.StatusOK
+	}
+	c.logger.record(entry)
+}
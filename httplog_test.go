@@ -0,0 +1,113 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error":{"message":"boom"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"secret reply"}}]}`)
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var entries []RequestLog
+	logger := NewRequestLogger(func(entry RequestLog) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, entry)
+	})
+	logger.SetLogBodies(true)
+
+	client := NewOpenAIClientWithOptions(
+		option.WithAPIKey("sk-test"),
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(1),
+		option.WithMiddleware(logger.Middleware()),
+	)
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel("gpt-4o"),
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("secret prompt")},
+	}
+	completion, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "CreateChatCompletion")
+	AssertEqual(t, "secret reply", completion.Choices[0].Message.Content, "Response body should still be readable")
+
+	AssertEqual(t, 2, len(entries), "One entry per attempt")
+	AssertEqual(t, 1, entries[0].Attempt, "First attempt")
+	AssertEqual(t, http.StatusInternalServerError, entries[0].StatusCode, "First status")
+	AssertEqual(t, 2, entries[1].Attempt, "Retry attempt")
+	AssertEqual(t, http.StatusOK, entries[1].StatusCode, "Retry status")
+	AssertEqual(t, redactedValue, entries[1].Headers.Get("Authorization"), "Authorization header should be redacted")
+	if strings.Contains(entries[1].RequestBody, "secret prompt") || !strings.Contains(entries[1].RequestBody, "gpt-4o") {
+		t.Errorf("Expected redacted request body with params, got %s", entries[1].RequestBody)
+	}
+	if strings.Contains(entries[1].ResponseBody, "secret reply") {
+		t.Errorf("Expected redacted response body, got %s", entries[1].ResponseBody)
+	}
+
+	// Disabled loggers record nothing
+	logger.SetEnabled(false)
+	_, err = client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "CreateChatCompletion while disabled")
+	AssertEqual(t, 2, len(entries), "No entries while disabled")
+
+	// Unredacted bodies are logged as-is
+	logger.SetEnabled(true)
+	logger.SetRedact(false)
+	_, err = client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "CreateChatCompletion unredacted")
+	AssertEqual(t, 3, len(entries), "Entry after re-enabling")
+	if !strings.Contains(entries[2].RequestBody, "secret prompt") {
+		t.Errorf("Expected unredacted request body, got %s", entries[2].RequestBody)
+	}
+}
+
+func TestLoggingClient(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	mockClient.SetCompletionResponse(&openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Hi", Role: "assistant"}},
+		},
+	})
+
+	var entries []RequestLog
+	logger := NewRequestLogger(func(entry RequestLog) { entries = append(entries, entry) })
+	swarm := NewSwarm(NewLoggingClient(mockClient, logger))
+
+	agent := NewAgent("Logger")
+	agent.Model = "gpt-4o"
+	messages := []map[string]interface{}{{"role": "user", "content": "Hello"}}
+	_, err := swarm.Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, 1, len(entries), "One entry per call")
+	AssertEqual(t, "gpt-4o", entries[0].Model, "Model")
+	AssertEqual(t, http.StatusOK, entries[0].StatusCode, "Status")
+	AssertEqual(t, "", entries[0].RequestBody, "Bodies are off by default")
+
+	mockClient.Error = fmt.Errorf("connection refused")
+	_, err = swarm.Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertError(t, err, "Run should fail")
+	AssertEqual(t, 2, len(entries), "Failed calls are logged")
+	if !strings.Contains(entries[1].Error, "connection refused") {
+		t.Errorf("Expected logged error, got %q", entries[1].Error)
+	}
+}
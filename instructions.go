@@ -0,0 +1,85 @@
+package swarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Missing-key modes for template instructions, matching text/template's
+// "missingkey" option.
+const (
+	// MissingKeyError fails the run when a referenced variable is missing
+	MissingKeyError = "error"
+	// MissingKeyDefault renders missing variables as "<no value>"
+	MissingKeyDefault = "default"
+	// MissingKeyZero renders the zero value (also "<no value>" for context
+	// variables, which are untyped)
+	MissingKeyZero = "zero"
+)
+
+// instructionFuncs are the functions available in template instructions.
+var instructionFuncs = template.FuncMap{
+	// default returns value, or fallback if value is missing or empty:
+	// {{default "there" (index . "user_name")}}
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewTemplateInstructions parses text as a text/template rendered against the
+// context variables on every turn, e.g. "You are helping {{.user_name}}."
+// Use the result as Agent.Instructions. missingKey selects how missing
+// variables are handled; an empty mode means MissingKeyError. Besides the
+// builtins, templates can use default, join, upper, lower and json.
+func NewTemplateInstructions(text string, missingKey string) (*template.Template, error) {
+	if missingKey == "" {
+		missingKey = MissingKeyError
+	}
+	switch missingKey {
+	case MissingKeyError, MissingKeyDefault, MissingKeyZero:
+	default:
+		return nil, fmt.Errorf("%w: unknown missing key mode %q", ErrInvalidParameter, missingKey)
+	}
+
+	tmpl, err := template.New("instructions").
+		Funcs(instructionFuncs).
+		Option("missingkey=" + missingKey).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse instructions template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// MustTemplateInstructions is like NewTemplateInstructions but panics on error.
+func MustTemplateInstructions(text string, missingKey string) *template.Template {
+	tmpl, err := NewTemplateInstructions(text, missingKey)
+	if err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+// renderInstructions executes a template against the context variables.
+func renderInstructions(tmpl *template.Template, contextVariables map[string]interface{}) (string, error) {
+	if contextVariables == nil {
+		contextVariables = map[string]interface{}{}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, contextVariables); err != nil {
+		return "", fmt.Errorf("failed to render instructions: %w", err)
+	}
+	return b.String(), nil
+}
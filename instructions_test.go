@@ -0,0 +1,45 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTemplateInstructions(t *testing.T) {
+	s := NewSwarm(NewMockOpenAIClient())
+	agent := NewAgent("assistant").WithInstructions(MustTemplateInstructions(
+		`You help {{.user_name}} ({{upper .tier}}). Topics: {{join .topics ", "}}. Greeting: {{default "Hi" (index . "greeting")}}`, ""))
+
+	instructions, err := s.getInstructions(agent, map[string]interface{}{
+		"user_name": "Ada",
+		"tier":      "gold",
+		"topics":    []string{"billing", "refunds"},
+	})
+	AssertNoError(t, err, "render")
+	AssertEqual(t, "You help Ada (GOLD). Topics: billing, refunds. Greeting: Hi", instructions, "rendered instructions")
+}
+
+func TestTemplateInstructionsMissingKey(t *testing.T) {
+	s := NewSwarm(NewMockOpenAIClient())
+
+	strict := NewAgent("strict").WithInstructions(MustTemplateInstructions("Hello {{.user_name}}", MissingKeyError))
+	_, err := s.getInstructions(strict, nil)
+	AssertError(t, err, "Expected missing key error")
+	AssertEqual(t, true, strings.Contains(err.Error(), "user_name"), "error names the key")
+
+	lenient := NewAgent("lenient").WithInstructions(MustTemplateInstructions("Hello {{.user_name}}", MissingKeyDefault))
+	instructions, err := s.getInstructions(lenient, nil)
+	AssertNoError(t, err, "default mode")
+	AssertEqual(t, "Hello <no value>", instructions, "default rendering")
+
+	_, err = NewTemplateInstructions("Hello", "ignore")
+	AssertEqual(t, true, errors.Is(err, ErrInvalidParameter), "unknown mode")
+	_, err = NewTemplateInstructions("Hello {{", "")
+	AssertError(t, err, "parse error")
+
+	// Rendering errors fail the run
+	_, err = s.Run(context.Background(), strict, []map[string]interface{}{{"role": "user", "content": "hi"}}, nil, "", false, false, 1, true, false)
+	AssertError(t, err, "Expected Run to fail")
+}
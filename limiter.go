@@ -0,0 +1,215 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that paces requests, such as the LLM requests
+// of many workflow runs sharing a provider quota. It is safe for concurrent
+// use.
+type RateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// NewRateLimiter creates a limiter allowing perSecond tokens per second on
+// average and bursts of up to burst tokens (at least 1). A limiter with a
+// rate that is not positive never waits.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n tokens are available or ctx is done. Requests larger
+// than the burst wait until the bucket has refilled to cover them.
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || l.rate <= 0 || n <= 0 {
+		return nil
+	}
+	delay := l.reserve(float64(n))
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(float64(n))
+		return ctx.Err()
+	}
+}
+
+// reserve takes n tokens, going into debt if needed, and returns how long
+// to wait until the debt is repaid.
+func (l *RateLimiter) reserve(n float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens -= n
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(-l.tokens / l.rate * float64(time.Second)))
+}
+
+// cancel returns the tokens of a reservation that was not used.
+func (l *RateLimiter) cancel(n float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens = math.Min(l.tokens+n, l.burst)
+}
+
+// refill adds the tokens accrued since the last update. The caller must
+// hold l.mu.
+func (l *RateLimiter) refill() {
+	now := time.Now()
+	l.tokens = math.Min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	l.last = now
+}
+
+// adjust takes n more tokens without waiting, or returns -n tokens if n is
+// negative.
+func (l *RateLimiter) adjust(n int) {
+	if l == nil || l.rate <= 0 || n == 0 {
+		return
+	}
+	if n < 0 {
+		l.cancel(float64(-n))
+		return
+	}
+	l.reserve(float64(n))
+}
+
+// LLMRateLimit limits the LLM requests of a workflow run, see
+// WorkflowConfig.LLMRateLimit.
+type LLMRateLimit struct {
+	// RequestsPerSecond limits the chat completion requests (default unlimited)
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	// Burst is the number of requests allowed at once above the rate
+	// (default 1)
+	Burst int `yaml:"burst" json:"burst"`
+	// TokensPerMinute limits the tokens of the requests (default unlimited).
+	// Prompt tokens are estimated before each request and corrected with the
+	// usage the provider reports.
+	TokensPerMinute int `yaml:"tokens_per_minute" json:"tokens_per_minute"`
+}
+
+// validate checks the limits.
+func (l *LLMRateLimit) validate() error {
+	if l.RequestsPerSecond < 0 || l.Burst < 0 || l.TokensPerMinute < 0 {
+		return fmt.Errorf("%w: LLM rate limits must not be negative", ErrInvalidParameter)
+	}
+	return nil
+}
+
+// apply returns ctx carrying new limiters enforcing the limits.
+func (l *LLMRateLimit) apply(ctx context.Context) context.Context {
+	if l.RequestsPerSecond > 0 {
+		ctx = WithRequestLimiter(ctx, NewRateLimiter(l.RequestsPerSecond, l.Burst))
+	}
+	if l.TokensPerMinute > 0 {
+		ctx = WithTokenLimiter(ctx, NewRateLimiter(float64(l.TokensPerMinute)/60, l.TokensPerMinute))
+	}
+	return ctx
+}
+
+// requestLimitersContextKey carries the limiters that pace LLM requests.
+type requestLimitersContextKey struct{}
+
+// tokenLimitersContextKey carries the limiters that pace LLM tokens.
+type tokenLimitersContextKey struct{}
+
+// WithRequestLimiter returns ctx carrying limiter, so every chat completion
+// request made by a Swarm with ctx, or a context derived from it, first waits
+// for a token. Limiters added to a context carrying others apply in addition.
+func WithRequestLimiter(ctx context.Context, limiter *RateLimiter) context.Context {
+	return withLimiter(ctx, requestLimitersContextKey{}, limiter)
+}
+
+// WithTokenLimiter returns ctx carrying limiter, so every chat completion
+// request made by a Swarm with ctx, or a context derived from it, first waits
+// for as many tokens as its prompt is estimated to use. Limiters added to a
+// context carrying others apply in addition.
+func WithTokenLimiter(ctx context.Context, limiter *RateLimiter) context.Context {
+	return withLimiter(ctx, tokenLimitersContextKey{}, limiter)
+}
+
+// withLimiter returns ctx carrying limiter in addition to the limiters
+// under key.
+func withLimiter(ctx context.Context, key interface{}, limiter *RateLimiter) context.Context {
+	if limiter == nil {
+		return ctx
+	}
+	existing := limitersFromContext(ctx, key)
+	limiters := make([]*RateLimiter, len(existing), len(existing)+1)
+	copy(limiters, existing)
+	return context.WithValue(ctx, key, append(limiters, limiter))
+}
+
+// limitersFromContext returns the limiters carried by ctx under key.
+func limitersFromContext(ctx context.Context, key interface{}) []*RateLimiter {
+	limiters, _ := ctx.Value(key).([]*RateLimiter)
+	return limiters
+}
+
+// estimateRequestTokens estimates the prompt tokens of a request for the
+// token limiters carried by ctx, or returns 0 if there are none.
+func estimateRequestTokens(ctx context.Context, history []map[string]interface{}, model string) int {
+	if len(limitersFromContext(ctx, tokenLimitersContextKey{})) == 0 {
+		return 0
+	}
+	tokens, err := CountMessageTokens(history, model)
+	if err != nil {
+		return 0
+	}
+	return tokens
+}
+
+// waitRequestLimiters waits for a token from each request limiter carried by
+// ctx, and for the estimated tokens of the request from each token limiter.
+func waitRequestLimiters(ctx context.Context, tokens int) error {
+	for _, limiter := range limitersFromContext(ctx, requestLimitersContextKey{}) {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	for _, limiter := range limitersFromContext(ctx, tokenLimitersContextKey{}) {
+		if err := limiter.WaitN(ctx, tokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordTokenUsage corrects the token limiters carried by ctx with the
+// tokens a request used, if the provider reported them.
+func recordTokenUsage(ctx context.Context, estimated int, used int64) {
+	if used <= 0 {
+		return
+	}
+	for _, limiter := range limitersFromContext(ctx, tokenLimitersContextKey{}) {
+		limiter.adjust(int(used) - estimated)
+	}
+}
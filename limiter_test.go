@@ -0,0 +1,68 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		AssertNoError(t, limiter.Wait(context.Background()), "wait")
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Expected the burst of 2 to be followed by paced waits, finished after %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	AssertError(t, limiter.WaitN(ctx, 10), "Expected a cancelled wait to fail")
+	AssertNoError(t, NewRateLimiter(0, 1).WaitN(context.Background(), 1000), "unlimited")
+}
+
+func TestWorkflowLLMRateLimit(t *testing.T) {
+	mock := NewMockOpenAIClient()
+	for i := 0; i < 2; i++ {
+		mock.SetCompletionResponse(&openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: "ok", Role: "assistant"}},
+			},
+			Usage: openai.CompletionUsage{TotalTokens: 6010},
+		})
+	}
+	swarm := NewSwarm(mock)
+	agent := NewAgent("agent")
+
+	config := DefaultConfig()
+	config.LLMRateLimit = &LLMRateLimit{TokensPerMinute: 6000}
+	workflow := NewWorkflow("limited").WithConfig(config)
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		for i := 0; i < 2; i++ {
+			if _, err := swarm.Run(ctx.Context(), agent, []map[string]interface{}{
+				{"role": "user", "content": "hi"},
+			}, nil, "", false, false, 1, true, false); err != nil {
+				return nil, err
+			}
+		}
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	// The first request uses more than the budget of a minute, so the second
+	// waits for the debt to be repaid at 100 tokens per second
+	start := time.Now()
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	_, err = handler.Wait()
+	AssertNoError(t, err, "wait")
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the second request to wait for tokens, finished after %v", elapsed)
+	}
+
+	config.LLMRateLimit = &LLMRateLimit{RequestsPerSecond: -1}
+	_, err = NewWorkflow("invalid").WithConfig(config).Run(context.Background(), map[string]interface{}{})
+	AssertError(t, err, "Expected negative limits to be rejected")
+}
@@ -0,0 +1,95 @@
+package swarm
+
+import (
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// StopReason explains why a run ended.
+type StopReason string
+
+const (
+	// StopCompleted means the model replied without calling tools
+	StopCompleted StopReason = "completed"
+	// StopMaxTurns means the run used up its turn budget
+	StopMaxTurns StopReason = "max_turns"
+	// StopToolLoop means the model kept repeating the same tool call
+	StopToolLoop StopReason = "tool_loop"
+	// StopMaxToolCalls means the run reached its tool invocation limit
+	StopMaxToolCalls StopReason = "max_tool_calls"
+	// StopError means the run failed, see Response.Error
+	StopError StopReason = "error"
+)
+
+// DefaultMaxToolRepeats is the default number of identical consecutive tool
+// calls a LoopGuard allows.
+const DefaultMaxToolRepeats = 3
+
+// LoopGuard stops runs that degenerate into tool-call loops. A tripped guard
+// ends the run without executing the offending calls; the Response reports
+// why in StopReason and StopDetail.
+type LoopGuard struct {
+	// MaxRepeats is how many times in a row the same tool may be called with
+	// the same arguments (default 3, negative disables the check)
+	MaxRepeats int
+	// MaxToolCalls caps the tool invocations of a run (0 means no cap)
+	MaxToolCalls int
+}
+
+// WithLoopGuard sets the loop guard and returns the swarm.
+func (s *Swarm) WithLoopGuard(guard *LoopGuard) *Swarm {
+	s.LoopGuard = guard
+	return s
+}
+
+// loopState tracks the tool calls of a run for the loop guard.
+type loopState struct {
+	last    string
+	repeats int
+	calls   int
+}
+
+// check records a turn's tool calls and reports whether the guard trips.
+func (g *LoopGuard) check(state *loopState, toolCalls []openai.ChatCompletionMessageToolCall) (StopReason, string) {
+	if g == nil {
+		return "", ""
+	}
+	maxRepeats := g.MaxRepeats
+	if maxRepeats == 0 {
+		maxRepeats = DefaultMaxToolRepeats
+	}
+	for _, call := range toolCalls {
+		state.calls++
+		if g.MaxToolCalls > 0 && state.calls > g.MaxToolCalls {
+			return StopMaxToolCalls, fmt.Sprintf("tool call limit of %d reached", g.MaxToolCalls)
+		}
+
+		key := call.Function.Name + "\x00" + call.Function.Arguments
+		if key == state.last {
+			state.repeats++
+		} else {
+			state.last, state.repeats = key, 1
+		}
+		if maxRepeats > 0 && state.repeats > maxRepeats {
+			return StopToolLoop, fmt.Sprintf("tool %q called %d times in a row with arguments %s",
+				call.Function.Name, state.repeats, call.Function.Arguments)
+		}
+	}
+	return "", ""
+}
+
+// stoppedToolMessages answers tool calls that were not executed because the
+// run stopped, so the transcript stays valid for later runs.
+func stoppedToolMessages(toolCalls []openai.ChatCompletionMessageToolCall, detail string) []map[string]interface{} {
+	messages := make([]map[string]interface{}, 0, len(toolCalls))
+	for _, call := range toolCalls {
+		messages = append(messages, map[string]interface{}{
+			"role":         "tool",
+			"tool_call_id": call.ID,
+			"tool_name":    call.Function.Name,
+			"content":      fmt.Sprintf("Error: run stopped: %s", detail),
+		})
+	}
+	return messages
+}
@@ -0,0 +1,71 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func newEchoAgent(calls *int) *Agent {
+	return NewAgent("looper").AddFunction(NewAgentFunction("search", "Search",
+		func(args map[string]interface{}) (interface{}, error) {
+			*calls++
+			return "nothing", nil
+		},
+		[]Parameter{},
+	))
+}
+
+func TestLoopGuardDetectsRepeats(t *testing.T) {
+	calls := 0
+	swarm := NewSwarm(newFailingToolClient("search", `{"q": "x"}`, 10)).WithLoopGuard(&LoopGuard{MaxRepeats: 2})
+
+	response, err := swarm.Run(context.Background(), newEchoAgent(&calls), []map[string]interface{}{
+		{"role": "user", "content": "Find x"},
+	}, nil, "", false, false, 20, true, false)
+	AssertNoError(t, err, "Run")
+
+	AssertEqual(t, StopToolLoop, response.StopReason, "stop reason")
+	AssertEqual(t, true, strings.Contains(response.StopDetail, `"search" called 3 times`), "stop detail")
+	AssertEqual(t, 2, calls, "repeated call is not executed")
+	last := response.Messages[len(response.Messages)-1]
+	AssertEqual(t, "tool", last["role"], "unexecuted call is answered")
+}
+
+func TestLoopGuardMaxToolCalls(t *testing.T) {
+	client := NewMockOpenAIClient()
+	for _, args := range []string{`{"q": 1}`, `{"q": 2}`, `{"q": 3}`} {
+		client.SetCompletionResponse(&openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role:      "assistant",
+				ToolCalls: []openai.ChatCompletionMessageToolCall{MockToolCall{ID: "call", Name: "search", Args: args}.ToOpenAI()},
+			}}},
+		})
+	}
+	calls := 0
+	swarm := NewSwarm(client).WithLoopGuard(&LoopGuard{MaxToolCalls: 2})
+
+	response, err := swarm.Run(context.Background(), newEchoAgent(&calls), []map[string]interface{}{
+		{"role": "user", "content": "Find"},
+	}, nil, "", false, false, 20, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, StopMaxToolCalls, response.StopReason, "stop reason")
+	AssertEqual(t, 2, calls, "tool calls")
+}
+
+func TestRunStopReasons(t *testing.T) {
+	response, err := NewSwarm(newScriptedClient("hi")).Run(context.Background(), NewAgent("a"), []map[string]interface{}{
+		{"role": "user", "content": "Hi"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, StopCompleted, response.StopReason, "completed run")
+
+	calls := 0
+	response, err = NewSwarm(newFailingToolClient("search", `{}`, 1)).Run(context.Background(), newEchoAgent(&calls), []map[string]interface{}{
+		{"role": "user", "content": "Find"},
+	}, nil, "", false, false, 2, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, StopMaxTurns, response.StopReason, "turn budget exhausted")
+}
@@ -0,0 +1,255 @@
+package swarm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// eventTypes maps event types to the concrete Go struct used to rebuild them.
+var (
+	eventTypes   = make(map[EventType]reflect.Type)
+	eventTypesMu sync.RWMutex
+)
+
+func init() {
+	RegisterEventType[StartEvent](EventStart)
+	RegisterEventType[StopEvent](EventStop)
+	RegisterEventType[ErrorEvent](EventError)
+	RegisterEventType[ParallelEvent](EventParallel)
+	RegisterEventType[ParallelResultEvent](EventParallelResult)
+	RegisterEventType[ProgressEvent](EventProgress)
+	RegisterEventType[InputRequiredEvent](EventInputRequired)
+	RegisterEventType[HumanResponseEvent](EventHumanResponse)
+	RegisterEventType[DelayEvent](EventDelay)
+	RegisterEventType[StalledEvent](EventStalled)
+}
+
+// RegisterEventType associates an event type with the Go struct T so that
+// UnmarshalEvent can reconstruct it as *T. T must embed BaseEvent or *BaseEvent
+// and *T must implement Event. It panics otherwise, since registrations happen
+// at init time and a mismatch is a programming error.
+func RegisterEventType[T any](eventType EventType) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if !reflect.PointerTo(typ).Implements(reflect.TypeOf((*Event)(nil)).Elem()) {
+		panic(fmt.Sprintf("swarm: *%s does not implement Event", typ))
+	}
+	if _, ok := baseEventField(typ); !ok {
+		panic(fmt.Sprintf("swarm: %s does not embed BaseEvent", typ))
+	}
+
+	eventTypesMu.Lock()
+	defer eventTypesMu.Unlock()
+	eventTypes[eventType] = typ
+}
+
+// RegisteredEventType returns the Go type registered for an event type, if any.
+func RegisteredEventType(eventType EventType) (reflect.Type, bool) {
+	eventTypesMu.RLock()
+	defer eventTypesMu.RUnlock()
+	typ, ok := eventTypes[eventType]
+	return typ, ok
+}
+
+// eventEnvelope is the serialized form of an event.
+type eventEnvelope struct {
+	Type     EventType              `json:"type"`
+	Metadata EventMetadata          `json:"metadata"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Payload  json.RawMessage        `json:"payload,omitempty"`
+}
+
+// MarshalEvent serializes an event together with its type, metadata, data map
+// and the exported fields of its concrete struct.
+func MarshalEvent(event Event) ([]byte, error) {
+	if event == nil {
+		return nil, fmt.Errorf("event cannot be nil")
+	}
+
+	envelope := eventEnvelope{
+		Type: event.Type(),
+		Data: event.Data(),
+	}
+	if carrier, ok := event.(MetadataCarrier); ok {
+		envelope.Metadata = carrier.Metadata()
+	}
+	if _, ok := event.(*BaseEvent); !ok {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", event.Type(), err)
+		}
+		envelope.Payload = payload
+	}
+	return json.Marshal(envelope)
+}
+
+// UnmarshalEvent reconstructs an event produced by MarshalEvent. Registered
+// event types are rebuilt as their original struct; unknown types fall back to
+// a *BaseEvent carrying the data map and metadata.
+func UnmarshalEvent(data []byte) (Event, error) {
+	var envelope eventEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	if envelope.Type == "" {
+		return nil, fmt.Errorf("invalid event: event type is required")
+	}
+
+	base := &BaseEvent{eventType: envelope.Type, data: envelope.Data, meta: envelope.Metadata}
+	typ, ok := RegisteredEventType(envelope.Type)
+	if !ok {
+		return base, nil
+	}
+
+	value := reflect.New(typ)
+	if len(envelope.Payload) > 0 {
+		if err := json.Unmarshal(envelope.Payload, value.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to decode %s as %s: %w", envelope.Type, typ, err)
+		}
+	}
+	index, _ := baseEventField(typ)
+	field := value.Elem().Field(index)
+	if field.Kind() == reflect.Pointer {
+		field.Set(reflect.ValueOf(base))
+	} else {
+		field.Set(reflect.ValueOf(*base))
+	}
+	return value.Interface().(Event), nil
+}
+
+// baseEventField returns the index of the embedded BaseEvent or *BaseEvent field.
+func baseEventField(typ reflect.Type) (int, bool) {
+	if typ.Kind() != reflect.Struct {
+		return 0, false
+	}
+	baseType := reflect.TypeOf(BaseEvent{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && (field.Type == baseType || field.Type == reflect.PointerTo(baseType)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// errorEventJSON is the wire form of ErrorEvent with the error as a string.
+type errorEventJSON struct {
+	Error     string `json:"error"`
+	StepName  string `json:"step_name,omitempty"`
+	TaskID    string `json:"task_id,omitempty"`
+	Retriable bool   `json:"retriable"`
+}
+
+// MarshalJSON encodes the error as its message.
+func (e *ErrorEvent) MarshalJSON() ([]byte, error) {
+	wire := errorEventJSON{StepName: e.StepName, TaskID: e.TaskID, Retriable: e.Retriable}
+	if e.Error != nil {
+		wire.Error = e.Error.Error()
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes an error message back into an error value.
+func (e *ErrorEvent) UnmarshalJSON(data []byte) error {
+	var wire errorEventJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	if wire.Error != "" {
+		e.Error = errors.New(wire.Error)
+	}
+	e.StepName, e.TaskID, e.Retriable = wire.StepName, wire.TaskID, wire.Retriable
+	return nil
+}
+
+// parallelResultJSON is the wire form of ParallelResultEvent with errors as strings.
+type parallelResultJSON struct {
+	Results    map[string]interface{}  `json:"results"`
+	Errors     map[string]string       `json:"errors"`
+	Successful int                     `json:"successful"`
+	Failed     int                     `json:"failed"`
+	Duration   int64                   `json:"duration"`
+	SourceStep string                  `json:"source_step"`
+	Order      []string                `json:"order,omitempty"`
+	Tasks      map[string]TaskMetadata `json:"tasks,omitempty"`
+	Usage      Usage                   `json:"usage"`
+}
+
+// MarshalJSON encodes task errors as their messages.
+func (e *ParallelResultEvent) MarshalJSON() ([]byte, error) {
+	wire := parallelResultJSON{
+		Results:    e.Results,
+		Errors:     make(map[string]string, len(e.Errors)),
+		Successful: e.Successful,
+		Failed:     e.Failed,
+		Duration:   int64(e.Duration),
+		SourceStep: e.SourceStep,
+		Order:      e.Order,
+		Tasks:      e.Tasks,
+		Usage:      e.Usage,
+	}
+	for id, err := range e.Errors {
+		if err != nil {
+			wire.Errors[id] = err.Error()
+		}
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes task error messages back into error values.
+func (e *ParallelResultEvent) UnmarshalJSON(data []byte) error {
+	var wire parallelResultJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	e.Results = wire.Results
+	e.Errors = make(map[string]error, len(wire.Errors))
+	for id, msg := range wire.Errors {
+		e.Errors[id] = errors.New(msg)
+	}
+	e.Successful, e.Failed = wire.Successful, wire.Failed
+	e.Duration = time.Duration(wire.Duration)
+	e.SourceStep = wire.SourceStep
+	e.Order, e.Tasks, e.Usage = wire.Order, wire.Tasks, wire.Usage
+	return nil
+}
+
+// delayEventJSON is the wire form of DelayEvent with the next event encoded
+// by MarshalEvent.
+type delayEventJSON struct {
+	Delay int64           `json:"delay"`
+	Next  json.RawMessage `json:"next,omitempty"`
+}
+
+// MarshalJSON encodes the delayed event together with its delay.
+func (e *DelayEvent) MarshalJSON() ([]byte, error) {
+	wire := delayEventJSON{Delay: int64(e.Delay)}
+	if e.Next != nil {
+		next, err := MarshalEvent(e.Next)
+		if err != nil {
+			return nil, err
+		}
+		wire.Next = next
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes the delayed event with UnmarshalEvent.
+func (e *DelayEvent) UnmarshalJSON(data []byte) error {
+	var wire delayEventJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	e.Delay = time.Duration(wire.Delay)
+	if len(wire.Next) > 0 {
+		next, err := UnmarshalEvent(wire.Next)
+		if err != nil {
+			return err
+		}
+		e.Next = next
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package swarm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testOutlineEvent struct {
+	*BaseEvent
+	Topic    string   `json:"topic"`
+	Chapters []string `json:"chapters"`
+}
+
+func TestMarshalEventRoundTrip(t *testing.T) {
+	RegisterEventType[testOutlineEvent](EventType("TestOutline"))
+
+	original := NewEvent(EventType("TestOutline"), testOutlineEvent{Topic: "go", Chapters: []string{"One"}})
+	original.SetMetadata(EventMetadata{RunID: "run-1", EventID: "evt-1"})
+
+	data, err := MarshalEvent(original)
+	if err != nil {
+		t.Fatalf("MarshalEvent failed: %v", err)
+	}
+	event, err := UnmarshalEvent(data)
+	if err != nil {
+		t.Fatalf("UnmarshalEvent failed: %v", err)
+	}
+
+	outline, ok := event.(*testOutlineEvent)
+	if !ok {
+		t.Fatalf("Expected *testOutlineEvent, got %T", event)
+	}
+	AssertEqual(t, "go", outline.Topic, "topic")
+	AssertEqual(t, EventType("TestOutline"), outline.Type(), "event type")
+	AssertEqual(t, "evt-1", outline.EventID(), "event ID")
+}
+
+func TestMarshalBuiltinEvents(t *testing.T) {
+	data, err := MarshalEvent(NewErrorEvent(errString("boom")).WithStep("write"))
+	AssertNoError(t, err, "MarshalEvent error event")
+	event, err := UnmarshalEvent(data)
+	AssertNoError(t, err, "UnmarshalEvent error event")
+	errEvent := event.(*ErrorEvent)
+	AssertEqual(t, "boom", errEvent.Error.Error(), "error message")
+	AssertEqual(t, "write", errEvent.StepName, "step name")
+
+	data, err = MarshalEvent(NewBaseEvent(EventType("Unknown"), map[string]interface{}{"k": "v"}))
+	AssertNoError(t, err, "MarshalEvent base event")
+	event, err = UnmarshalEvent(data)
+	AssertNoError(t, err, "UnmarshalEvent base event")
+	AssertEqual(t, "v", event.Data()["k"], "unknown types fall back to BaseEvent")
+
+	resultEvent := NewParallelResultEvent(map[string]interface{}{"a": "ok"}, map[string]error{"b": errString("failed")}, time.Second, "start")
+	resultEvent.Order = []string{"b", "a"}
+	resultEvent.Tasks = map[string]TaskMetadata{"a": {Type: "Work", Attempts: 2, Status: TaskStatusComplete}}
+	data, err = MarshalEvent(resultEvent)
+	AssertNoError(t, err, "MarshalEvent parallel result event")
+	event, err = UnmarshalEvent(data)
+	AssertNoError(t, err, "UnmarshalEvent parallel result event")
+	decoded := event.(*ParallelResultEvent)
+	AssertEqual(t, "b,a", strings.Join(decoded.Order, ","), "task order")
+	AssertEqual(t, 2, decoded.Tasks["a"].Attempts, "task metadata")
+	AssertEqual(t, "failed", decoded.Errors["b"].Error(), "task error")
+}
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
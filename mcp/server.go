@@ -0,0 +1,346 @@
+// Package mcp serves swarm tools and agents as a Model Context Protocol (MCP)
+// server, so MCP hosts such as Claude Desktop and IDEs can call them.
+//
+// Tools are AgentFunctions, described to the host with the same JSON schema
+// the swarm sends to the model. Whole agents can be exposed as tools too:
+// calling one runs the agent on the given message, with its own tools, and
+// returns its final reply. Tool approval gates are not applied, since MCP
+// hosts confirm tool calls with the user themselves.
+//
+// ServeStdio serves hosts that launch the server as a subprocess; as an
+// http.Handler, the server serves the SSE transport at /sse and /message, and
+// the streamable HTTP transport (in JSON response mode) at /mcp.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// ProtocolVersion is the latest MCP protocol version supported by the server.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the protocol versions the server can negotiate.
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+)
+
+// Tool describes a tool to MCP clients.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// Content is a content block of a tool result.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// CallToolResult is the result of a tools/call request. Tool failures are
+// reported with IsError rather than as protocol errors, so the model sees
+// them.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Error is a JSON-RPC error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// request is a JSON-RPC request or notification; notifications have no ID.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// handlerFunc runs a tool with its arguments and returns its text output.
+type handlerFunc func(ctx context.Context, args map[string]interface{}) (string, error)
+
+// tool is a registered tool.
+type tool struct {
+	Tool
+	call handlerFunc
+}
+
+// Server is an MCP server exposing swarm tools and agents. It implements
+// http.Handler.
+type Server struct {
+	// Name and Version identify the server to clients
+	Name    string
+	Version string
+	// Swarm runs the agents added with AddAgent
+	Swarm *swarm.Swarm
+	// MaxTurns limits the turns of agent runs (default swarm.DefaultSessionMaxTurns)
+	MaxTurns int
+
+	tools    map[string]*tool
+	order    []string
+	sessions map[string]*sseSession
+	mu       sync.Mutex
+}
+
+// NewServer creates an MCP server without tools.
+func NewServer(name, version string) *Server {
+	return &Server{
+		Name:     name,
+		Version:  version,
+		tools:    make(map[string]*tool),
+		sessions: make(map[string]*sseSession),
+	}
+}
+
+// WithSwarm sets the swarm running the exposed agents and returns the server.
+func (s *Server) WithSwarm(sw *swarm.Swarm) *Server {
+	s.Swarm = sw
+	return s
+}
+
+// WithMaxTurns sets the turn budget of agent runs and returns the server.
+func (s *Server) WithMaxTurns(maxTurns int) *Server {
+	s.MaxTurns = maxTurns
+	return s
+}
+
+// AddTools exposes functions as tools and returns the server. A function
+// replaces an earlier tool of the same name.
+func (s *Server) AddTools(functions ...swarm.AgentFunction) *Server {
+	for _, fn := range functions {
+		schema, _ := swarm.FunctionToJSON(fn)["function"].(map[string]interface{})
+		params, _ := schema["parameters"].(map[string]interface{})
+		s.add(&tool{
+			Tool: Tool{Name: fn.Name(), Description: fn.Description(), InputSchema: params},
+			call: func(ctx context.Context, args map[string]interface{}) (string, error) {
+				return callFunction(ctx, fn, args)
+			},
+		})
+	}
+	return s
+}
+
+// AddAgent exposes an agent as a tool named after it, taking a "message"
+// for the agent, and returns the server. The server's Swarm runs the agent.
+func (s *Server) AddAgent(agent *swarm.Agent, description string) *Server {
+	if description == "" {
+		description = fmt.Sprintf("Ask the %s agent", agent.Name)
+	}
+	s.add(&tool{
+		Tool: Tool{
+			Name:        agent.Name,
+			Description: description,
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"message": map[string]interface{}{"type": "string", "description": "The message for the agent"},
+				},
+				"required": []string{"message"},
+			},
+		},
+		call: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			return s.runAgent(ctx, agent, args)
+		},
+	})
+	return s
+}
+
+// Tools returns the exposed tools in the order they were added.
+func (s *Server) Tools() []Tool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tools := make([]Tool, 0, len(s.order))
+	for _, name := range s.order {
+		tools = append(tools, s.tools[name].Tool)
+	}
+	return tools
+}
+
+// add registers a tool.
+func (s *Server) add(t *tool) {
+	if t.InputSchema == nil {
+		t.InputSchema = map[string]interface{}{"type": "object"}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tools[t.Name]; !ok {
+		s.order = append(s.order, t.Name)
+	}
+	s.tools[t.Name] = t
+}
+
+// handle answers a JSON-RPC message. It returns nil for notifications.
+func (s *Server) handle(ctx context.Context, data []byte) *response {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: ErrCodeParse, Message: err.Error()}}
+	}
+	if len(req.ID) == 0 {
+		// Notifications, such as notifications/initialized, need no reply
+		return nil
+	}
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &Error{Code: ErrCodeInvalidRequest, Message: "invalid JSON-RPC request"}
+		return resp
+	}
+
+	result, err := s.dispatch(ctx, req.Method, req.Params)
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: ErrCodeInternal, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// dispatch runs a request method.
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		version := ProtocolVersion
+		if slices.Contains(supportedVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": s.Name, "version": s.Version},
+		}, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": s.Tools()}, nil
+
+	case "tools/call":
+		var p struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		t, ok := s.tools[p.Name]
+		s.mu.Unlock()
+		if !ok {
+			return nil, &Error{Code: ErrCodeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+		}
+		if p.Arguments == nil {
+			p.Arguments = make(map[string]interface{})
+		}
+		text, err := t.call(ctx, p.Arguments)
+		if err != nil {
+			return CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		return CallToolResult{Content: []Content{{Type: "text", Text: text}}}, nil
+	}
+	return nil, &Error{Code: ErrCodeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+}
+
+// decodeParams decodes optional request parameters.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: ErrCodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// callFunction calls a function the way the swarm calls tools, and formats
+// its result as the swarm does for the model.
+func callFunction(ctx context.Context, fn swarm.AgentFunction, args map[string]interface{}) (string, error) {
+	contextVariables := make(map[string]interface{})
+	args[swarm.ContextVariablesName] = contextVariables
+	result, err := swarm.AsContextFunction(fn).CallWithContext(&swarm.CallContext{
+		Context:          ctx,
+		ContextVariables: contextVariables,
+	}, args)
+	if err != nil {
+		return "", err
+	}
+	switch v := result.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case *swarm.Result:
+		if v.Error != nil {
+			return "", v.Error
+		}
+		return v.Value, nil
+	case *swarm.Agent:
+		return fmt.Sprintf(`{"assistant":"%s"}`, v.Name), nil
+	}
+	return fmt.Sprintf("%v", result), nil
+}
+
+// runAgent runs an agent on the "message" argument and returns its reply.
+func (s *Server) runAgent(ctx context.Context, agent *swarm.Agent, args map[string]interface{}) (string, error) {
+	message, _ := args["message"].(string)
+	if message == "" {
+		return "", errors.New("message is required")
+	}
+	if s.Swarm == nil {
+		return "", fmt.Errorf("no swarm to run agent %s", agent.Name)
+	}
+	maxTurns := s.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = swarm.DefaultSessionMaxTurns
+	}
+	messages := []map[string]interface{}{{"role": "user", "content": message}}
+	resp, err := s.Swarm.Run(ctx, agent, messages, nil, "", false, false, maxTurns, true, false)
+	if err != nil {
+		return "", err
+	}
+	for i := len(resp.Messages) - 1; i >= 0; i-- {
+		if text, ok := resp.Messages[i]["content"].(string); ok && text != "" && resp.Messages[i]["role"] == "assistant" {
+			return text, nil
+		}
+	}
+	return "", nil
+}
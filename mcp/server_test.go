@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/feiskyer/swarm-go/fakellm"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	add := swarm.NewAgentFunction("add", "Add two numbers", func(args map[string]interface{}) (interface{}, error) {
+		return args["a"].(float64) + args["b"].(float64), nil
+	}, []swarm.Parameter{
+		{Name: "a", Description: "First number", Type: reflect.TypeOf(float64(0)), Required: true},
+		{Name: "b", Description: "Second number", Type: reflect.TypeOf(float64(0)), Required: true},
+	})
+	fail := swarm.NewAgentFunction("fail", "Always fails", func(args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("disk full")
+	}, nil)
+	llm := fakellm.New()
+	llm.When(fakellm.PromptContains("hi")).Reply("echo hi")
+	return NewServer("test", "1.0").
+		WithSwarm(swarm.NewSwarm(llm)).
+		AddTools(add, fail).
+		AddAgent(swarm.NewAgent("assistant").WithModel("gpt-4o"), "")
+}
+
+// rpc encodes a JSON-RPC request.
+func rpc(id int, method string, params interface{}) string {
+	data, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	return string(data)
+}
+
+// decodeResponse decodes a JSON-RPC response with its result into result.
+func decodeResponse(t *testing.T, data []byte, result interface{}) *Error {
+	t.Helper()
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("decode response %s: %v", data, err)
+	}
+	if resp.Error == nil && result != nil {
+		json.Unmarshal(resp.Result, result)
+	}
+	return resp.Error
+}
+
+func TestServeStdio(t *testing.T) {
+	server := newTestServer(t)
+	in := strings.Join([]string{
+		rpc(1, "initialize", map[string]interface{}{"protocolVersion": "2025-03-26", "capabilities": map[string]interface{}{}}),
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		rpc(2, "tools/list", nil),
+		rpc(3, "tools/call", map[string]interface{}{"name": "add", "arguments": map[string]interface{}{"a": 1, "b": 2}}),
+		rpc(4, "tools/call", map[string]interface{}{"name": "fail"}),
+		rpc(5, "tools/call", map[string]interface{}{"name": "assistant", "arguments": map[string]interface{}{"message": "hi"}}),
+		rpc(6, "tools/call", map[string]interface{}{"name": "missing"}),
+		rpc(7, "resources/list", nil),
+		`not json`,
+	}, "\n") + "\n"
+	var out strings.Builder
+	if err := server.ServeStdio(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("ServeStdio: %v", err)
+	}
+
+	responses := make(map[string][]byte)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.Unmarshal([]byte(line), &resp)
+		responses[string(resp.ID)] = []byte(line)
+	}
+	if len(responses) != 8 {
+		t.Fatalf("expected 8 responses, got %d: %s", len(responses), out.String())
+	}
+
+	var initialized struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name string `json:"name"`
+		} `json:"serverInfo"`
+	}
+	decodeResponse(t, responses["1"], &initialized)
+	if initialized.ProtocolVersion != "2025-03-26" || initialized.ServerInfo.Name != "test" {
+		t.Errorf("unexpected initialize result %+v", initialized)
+	}
+
+	var list struct {
+		Tools []Tool `json:"tools"`
+	}
+	decodeResponse(t, responses["2"], &list)
+	if len(list.Tools) != 3 || list.Tools[0].Name != "add" || list.Tools[2].Name != "assistant" {
+		t.Fatalf("unexpected tools %+v", list.Tools)
+	}
+	if props, _ := list.Tools[0].InputSchema["properties"].(map[string]interface{}); props["a"] == nil {
+		t.Errorf("unexpected input schema %v", list.Tools[0].InputSchema)
+	}
+
+	for id, want := range map[string]CallToolResult{
+		"3": {Content: []Content{{Type: "text", Text: "3"}}},
+		"4": {Content: []Content{{Type: "text", Text: "disk full"}}, IsError: true},
+		"5": {Content: []Content{{Type: "text", Text: "echo hi"}}},
+	} {
+		var result CallToolResult
+		if err := decodeResponse(t, responses[id], &result); err != nil {
+			t.Errorf("call %s failed: %v", id, err)
+		}
+		if !reflect.DeepEqual(want, result) {
+			t.Errorf("call %s: expected %+v, got %+v", id, want, result)
+		}
+	}
+	if err := decodeResponse(t, responses["6"], nil); err == nil || err.Code != ErrCodeInvalidParams {
+		t.Errorf("expected invalid params for an unknown tool, got %v", err)
+	}
+	if err := decodeResponse(t, responses["7"], nil); err == nil || err.Code != ErrCodeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+	if err := decodeResponse(t, responses["null"], nil); err == nil || err.Code != ErrCodeParse {
+		t.Errorf("expected parse error, got %v", err)
+	}
+}
+
+func TestServeSSE(t *testing.T) {
+	server := httptest.NewServer(http.StripPrefix("/mcp-server", newTestServer(t)))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/mcp-server/sse", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /sse: %v", err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	next := func() (string, string) {
+		var event, data string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event: %v", err)
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event != "":
+				return event, data
+			}
+		}
+	}
+
+	event, endpoint := next()
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "message?sessionId=") {
+		t.Fatalf("unexpected endpoint event %s %s", event, endpoint)
+	}
+	post, err := http.Post(server.URL+"/mcp-server/"+endpoint, "application/json",
+		strings.NewReader(rpc(1, "tools/call", map[string]interface{}{"name": "add", "arguments": map[string]interface{}{"a": 2, "b": 3}})))
+	if err != nil {
+		t.Fatalf("POST message: %v", err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", post.StatusCode)
+	}
+
+	event, data := next()
+	var result CallToolResult
+	if err := decodeResponse(t, []byte(data), &result); err != nil || event != "message" || result.Content[0].Text != "5" {
+		t.Errorf("unexpected message %s %s", event, data)
+	}
+
+	unknown, _ := http.Post(server.URL+"/mcp-server/message?sessionId=unknown", "application/json", strings.NewReader(rpc(1, "ping", nil)))
+	unknown.Body.Close()
+	if unknown.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown session, got %d", unknown.StatusCode)
+	}
+}
+
+func TestServeStreamableHTTP(t *testing.T) {
+	server := httptest.NewServer(newTestServer(t))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/mcp", "application/json", strings.NewReader(rpc(1, "ping", nil)))
+	if err != nil {
+		t.Fatalf("POST /mcp: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err := decodeResponse(t, data, nil); err != nil || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected ping reply %s", data)
+	}
+
+	resp, err = http.Post(server.URL+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	if err != nil {
+		t.Fatalf("POST /mcp: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected status 202 for a notification, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// maxMessageSize bounds the size of incoming JSON-RPC messages.
+const maxMessageSize = 10 << 20
+
+// ServeStdio serves a client over newline-delimited JSON-RPC messages read
+// from in and written to out, as MCP hosts do with servers they launch as
+// subprocesses. Requests are handled concurrently. It returns when in reaches
+// EOF or ctx is done.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	encoder := json.NewEncoder(out)
+
+	lines := make(chan []byte)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			select {
+			case lines <- bytes.Clone(line):
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp := s.handle(ctx, line)
+				if resp == nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				encoder.Encode(resp)
+			}()
+		}
+	}
+}
+
+// sseSession is a client connected to the SSE transport.
+type sseSession struct {
+	messages chan *response
+	done     <-chan struct{}
+}
+
+// ServeHTTP implements http.Handler for the SSE and streamable HTTP
+// transports.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/sse"):
+		s.serveSSE(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/message"):
+		s.serveSSEMessage(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/mcp"):
+		s.serveStreamable(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveSSE opens an SSE session: it first sends the "endpoint" event with
+// the URL to post messages to, then the responses as "message" events.
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	id := swarm.NewID("mcp-")
+	session := &sseSession{messages: make(chan *response, 16), done: r.Context().Done()}
+	s.mu.Lock()
+	s.sessions[id] = session
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.sessions, id)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	// The endpoint is relative to the SSE URL, so the server can be mounted
+	// under any prefix
+	fmt.Fprintf(w, "event: endpoint\ndata: message?sessionId=%s\n\n", id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case resp := <-session.messages:
+			data, err := json.Marshal(resp)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// serveSSEMessage accepts a message of an SSE session and sends the response
+// on the session's stream.
+func (s *Server) serveSSEMessage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	session, ok := s.sessions[r.URL.Query().Get("sessionId")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	go func() {
+		// Requests outlive the POST, but not the session
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-session.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if resp := s.handle(ctx, data); resp != nil {
+			select {
+			case session.messages <- resp:
+			case <-session.done:
+			}
+		}
+	}()
+}
+
+// serveStreamable answers a message of the streamable HTTP transport with a
+// JSON response, or 202 for notifications.
+func (s *Server) serveStreamable(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := s.handle(r.Context(), data)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,265 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ConversationIDName is the context variable holding the conversation ID that
+// keys agent memories. Use a stable ID, such as a user ID, for memories that
+// persist across sessions. Memories are shared by all conversations of an
+// agent when it is unset.
+const ConversationIDName = "conversation_id"
+
+// DefaultMemoryRecallLimit is the default number of memories recalled per turn.
+const DefaultMemoryRecallLimit = 5
+
+// MemoryEntry is a fact remembered by an agent.
+type MemoryEntry struct {
+	// Content is the remembered text
+	Content string `json:"content"`
+	// Time is when the entry was recorded
+	Time time.Time `json:"time"`
+}
+
+// Memory stores facts for agents, keyed by agent name and conversation ID.
+// Implementations must be safe for concurrent use.
+type Memory interface {
+	// Recall returns up to limit entries relevant to query, most relevant first.
+	Recall(ctx context.Context, agent, conversationID, query string, limit int) ([]MemoryEntry, error)
+	// Remember records new entries.
+	Remember(ctx context.Context, agent, conversationID string, entries ...MemoryEntry) error
+}
+
+// WithMemory enables memory for the agent and returns the agent for chaining.
+// Relevant memories are added to the agent's instructions on every turn, and
+// each run's request and final reply are recorded afterwards.
+func (a *Agent) WithMemory(memory Memory) *Agent {
+	a.Memory = memory
+	return a
+}
+
+// memoryKey identifies the memories of an agent in a conversation.
+type memoryKey struct {
+	agent          string
+	conversationID string
+}
+
+// InMemoryMemory is a Memory held in process memory. Entries are ranked by
+// the number of words they share with the query, then by recency.
+type InMemoryMemory struct {
+	entries map[memoryKey][]MemoryEntry
+	mu      sync.RWMutex
+}
+
+// NewInMemoryMemory creates an empty in-process memory.
+func NewInMemoryMemory() *InMemoryMemory {
+	return &InMemoryMemory{entries: make(map[memoryKey][]MemoryEntry)}
+}
+
+// Recall returns up to limit entries relevant to query.
+func (m *InMemoryMemory) Recall(_ context.Context, agent, conversationID, query string, limit int) ([]MemoryEntry, error) {
+	m.mu.RLock()
+	entries := append([]MemoryEntry(nil), m.entries[memoryKey{agent, conversationID}]...)
+	m.mu.RUnlock()
+	return rankMemories(entries, query, limit), nil
+}
+
+// Remember records new entries.
+func (m *InMemoryMemory) Remember(_ context.Context, agent, conversationID string, entries ...MemoryEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryKey{agent, conversationID}
+	m.entries[key] = append(m.entries[key], entries...)
+	return nil
+}
+
+// FileMemory is a Memory persisted as one JSON file per agent and
+// conversation under a directory, so memories survive restarts. Recall ranks
+// entries like InMemoryMemory.
+type FileMemory struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileMemory creates a file-backed memory in dir, creating it if needed.
+func NewFileMemory(dir string) (*FileMemory, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	return &FileMemory{dir: dir}, nil
+}
+
+// Recall returns up to limit entries relevant to query.
+func (m *FileMemory) Recall(_ context.Context, agent, conversationID, query string, limit int) ([]MemoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries, err := m.load(agent, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return rankMemories(entries, query, limit), nil
+}
+
+// Remember records new entries.
+func (m *FileMemory) Remember(_ context.Context, agent, conversationID string, entries ...MemoryEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, err := m.load(agent, conversationID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(append(existing, entries...))
+	if err != nil {
+		return fmt.Errorf("failed to marshal memories: %w", err)
+	}
+	path := m.path(agent, conversationID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write memories: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write memories: %w", err)
+	}
+	return nil
+}
+
+// load reads the stored entries, returning none if the file does not exist.
+func (m *FileMemory) load(agent, conversationID string) ([]MemoryEntry, error) {
+	data, err := os.ReadFile(m.path(agent, conversationID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memories: %w", err)
+	}
+	var entries []MemoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal memories: %w", err)
+	}
+	return entries, nil
+}
+
+// path returns the file storing an agent's memories for a conversation.
+func (m *FileMemory) path(agent, conversationID string) string {
+	name := toolName(agent)
+	if conversationID != "" {
+		name += "__" + toolName(conversationID)
+	}
+	return filepath.Join(m.dir, name+".json")
+}
+
+// rankMemories orders entries by word overlap with query, then by recency,
+// and returns at most limit of them.
+func rankMemories(entries []MemoryEntry, query string, limit int) []MemoryEntry {
+	if limit <= 0 {
+		limit = DefaultMemoryRecallLimit
+	}
+	queryWords := memoryWords(query)
+	scores := make([]int, len(entries))
+	for i, entry := range entries {
+		for word := range memoryWords(entry.Content) {
+			if queryWords[word] {
+				scores[i]++
+			}
+		}
+	}
+
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if scores[order[a]] != scores[order[b]] {
+			return scores[order[a]] > scores[order[b]]
+		}
+		return entries[order[a]].Time.After(entries[order[b]].Time)
+	})
+
+	ranked := make([]MemoryEntry, 0, min(limit, len(entries)))
+	for _, i := range order[:min(limit, len(order))] {
+		ranked = append(ranked, entries[i])
+	}
+	return ranked
+}
+
+// memoryWords returns the lowercase words of text longer than two letters.
+func memoryWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 2 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// conversationID returns the conversation ID from the context variables.
+func conversationID(contextVariables map[string]interface{}) string {
+	id, _ := contextVariables[ConversationIDName].(string)
+	return id
+}
+
+// lastUserContent returns the content of the most recent user message.
+func lastUserContent(history []map[string]interface{}) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i]["role"] == "user" {
+			content, _ := history[i]["content"].(string)
+			return content
+		}
+	}
+	return ""
+}
+
+// recallMemories appends the agent's memories relevant to the latest user
+// message to instructions.
+func (s *Swarm) recallMemories(ctx context.Context, agent *Agent, history []map[string]interface{}, contextVariables map[string]interface{}, instructions string) (string, error) {
+	if agent.Memory == nil {
+		return instructions, nil
+	}
+	entries, err := agent.Memory.Recall(ctx, agent.Name, conversationID(contextVariables), lastUserContent(history), DefaultMemoryRecallLimit)
+	if err != nil {
+		return "", fmt.Errorf("failed to recall memories: %w", err)
+	}
+	if len(entries) == 0 {
+		return instructions, nil
+	}
+	var b strings.Builder
+	b.WriteString(instructions)
+	b.WriteString("\n\nRelevant memories from previous conversations:")
+	for _, entry := range entries {
+		b.WriteString("\n- " + entry.Content)
+	}
+	return b.String(), nil
+}
+
+// rememberRun records the run's request and final reply in the agent's memory.
+func (s *Swarm) rememberRun(ctx context.Context, agent *Agent, history []map[string]interface{}, contextVariables map[string]interface{}, reply map[string]interface{}) error {
+	if agent.Memory == nil {
+		return nil
+	}
+	content, _ := reply["content"].(string)
+	request := lastUserContent(history)
+	if content == "" || request == "" {
+		return nil
+	}
+	entry := MemoryEntry{
+		Content: fmt.Sprintf("User said: %s | You replied: %s", request, content),
+		Time:    time.Now(),
+	}
+	if err := agent.Memory.Remember(ctx, agent.Name, conversationID(contextVariables), entry); err != nil {
+		return fmt.Errorf("failed to record memories: %w", err)
+	}
+	return nil
+}
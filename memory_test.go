@@ -0,0 +1,73 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStores(t *testing.T) {
+	fileMemory, err := NewFileMemory(t.TempDir())
+	AssertNoError(t, err, "NewFileMemory")
+
+	stores := map[string]Memory{
+		"in-memory": NewInMemoryMemory(),
+		"file":      fileMemory,
+	}
+	for name, memory := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			AssertNoError(t, memory.Remember(ctx, "assistant", "user-1",
+				MemoryEntry{Content: "User's favourite colour is blue", Time: now.Add(-time.Hour)},
+				MemoryEntry{Content: "User lives in Berlin", Time: now.Add(-time.Minute)},
+				MemoryEntry{Content: "User has a dog named Rex", Time: now},
+			), "Remember")
+
+			entries, err := memory.Recall(ctx, "assistant", "user-1", "What colour should I paint?", 2)
+			AssertNoError(t, err, "Recall")
+			AssertEqual(t, 2, len(entries), "recall limit")
+			AssertEqual(t, "User's favourite colour is blue", entries[0].Content, "most relevant first")
+			AssertEqual(t, "User has a dog named Rex", entries[1].Content, "then most recent")
+
+			entries, err = memory.Recall(ctx, "assistant", "user-2", "colour", 5)
+			AssertNoError(t, err, "Recall other conversation")
+			AssertEqual(t, 0, len(entries), "conversations are isolated")
+		})
+	}
+}
+
+func TestFileMemoryPersists(t *testing.T) {
+	dir := t.TempDir()
+	memory, err := NewFileMemory(dir)
+	AssertNoError(t, err, "NewFileMemory")
+	AssertNoError(t, memory.Remember(context.Background(), "Support Agent", "u/1", MemoryEntry{Content: "prefers email"}), "Remember")
+
+	reopened, err := NewFileMemory(dir)
+	AssertNoError(t, err, "reopen")
+	entries, err := reopened.Recall(context.Background(), "Support Agent", "u/1", "", 5)
+	AssertNoError(t, err, "Recall")
+	AssertEqual(t, 1, len(entries), "persisted entries")
+	AssertEqual(t, "prefers email", entries[0].Content, "persisted content")
+}
+
+func TestRunWithMemory(t *testing.T) {
+	memory := NewInMemoryMemory()
+	AssertNoError(t, memory.Remember(context.Background(), "assistant", "alice", MemoryEntry{Content: "User's name is Alice"}), "seed")
+
+	client := newScriptedClient("Hello Alice!")
+	agent := NewAgent("assistant").WithMemory(memory)
+	_, err := NewSwarm(client).Run(context.Background(), agent, []map[string]interface{}{
+		{"role": "user", "content": "Do you remember my name?"},
+	}, map[string]interface{}{ConversationIDName: "alice"}, "", false, false, 5, true, false)
+	AssertNoError(t, err, "Run")
+
+	system := client.requests[0].Messages[0].OfSystem.Content.OfString.Value
+	AssertEqual(t, true, strings.Contains(system, "- User's name is Alice"), "recalled memory in instructions")
+
+	entries, err := memory.Recall(context.Background(), "assistant", "alice", "remember name", 5)
+	AssertNoError(t, err, "Recall")
+	AssertEqual(t, 2, len(entries), "run recorded")
+	AssertEqual(t, "User said: Do you remember my name? | You replied: Hello Alice!", entries[0].Content, "recorded exchange")
+}
@@ -15,6 +15,7 @@ type MockOpenAIClient struct {
 	CompletionIter     int
 	CompletionResponse []*openai.ChatCompletion
 	StreamResponse     *MockStream
+	EmbeddingResponse  [][]float64
 	Error              error
 }
 
@@ -49,6 +50,13 @@ func (m *MockOpenAIClient) CreateChatCompletion(ctx context.Context, params open
 	return m.CompletionResponse[iter], nil
 }
 
+func (m *MockOpenAIClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.EmbeddingResponse, nil
+}
+
 func (m *MockOpenAIClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
 	if m.Error != nil {
 		return nil, m.Error
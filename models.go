@@ -0,0 +1,106 @@
+package swarm
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/resp"
+)
+
+// Roles used to send agent instructions.
+const (
+	InstructionRoleSystem    = "system"
+	InstructionRoleDeveloper = "developer"
+	InstructionRoleUser      = "user"
+)
+
+// ModelCapabilities describes how requests must be shaped for a model family.
+type ModelCapabilities struct {
+	// Reasoning reports whether the model is a reasoning model that accepts
+	// reasoning_effort or returns reasoning_content
+	Reasoning bool
+	// ReasoningEffort reports whether the model accepts the reasoning_effort
+	// parameter
+	ReasoningEffort bool
+	// InstructionRole is the role used for agent instructions: "system",
+	// "developer" or "user" for models without instruction support
+	InstructionRole string
+}
+
+// defaultCapabilities apply to models without a registered entry.
+var defaultCapabilities = ModelCapabilities{InstructionRole: InstructionRoleSystem}
+
+// modelCapabilities maps lowercase model name prefixes to their capabilities.
+var (
+	modelCapabilities = map[string]ModelCapabilities{
+		"o1":                {Reasoning: true, ReasoningEffort: true, InstructionRole: InstructionRoleDeveloper},
+		"o1-mini":           {Reasoning: true, InstructionRole: InstructionRoleUser},
+		"o1-preview":        {Reasoning: true, InstructionRole: InstructionRoleUser},
+		"o3":                {Reasoning: true, ReasoningEffort: true, InstructionRole: InstructionRoleDeveloper},
+		"o4-mini":           {Reasoning: true, ReasoningEffort: true, InstructionRole: InstructionRoleDeveloper},
+		"deepseek-reasoner": {Reasoning: true, InstructionRole: InstructionRoleUser},
+		"deepseek-r1":       {Reasoning: true, InstructionRole: InstructionRoleUser},
+	}
+	modelCapabilitiesMu sync.RWMutex
+)
+
+// RegisterModelCapabilities sets the capabilities of models whose name starts
+// with modelPrefix (case-insensitive). The longest matching prefix wins.
+func RegisterModelCapabilities(modelPrefix string, caps ModelCapabilities) {
+	modelCapabilitiesMu.Lock()
+	defer modelCapabilitiesMu.Unlock()
+	if caps.InstructionRole == "" {
+		caps.InstructionRole = InstructionRoleSystem
+	}
+	modelCapabilities[strings.ToLower(modelPrefix)] = caps
+}
+
+// CapabilitiesForModel returns the capabilities of model. Provider prefixes such
+// as "azure/" or "deepseek/" are ignored.
+func CapabilitiesForModel(model string) ModelCapabilities {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	modelCapabilitiesMu.RLock()
+	defer modelCapabilitiesMu.RUnlock()
+	best := ""
+	caps := defaultCapabilities
+	for prefix, c := range modelCapabilities {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best, caps = prefix, c
+		}
+	}
+	return caps
+}
+
+// instructionMessage builds the message carrying the agent's instructions in
+// the role the model supports.
+func instructionMessage(instructions, model string) openai.ChatCompletionMessageParamUnion {
+	switch CapabilitiesForModel(model).InstructionRole {
+	case InstructionRoleDeveloper:
+		return openai.DeveloperMessage(instructions)
+	case InstructionRoleUser:
+		return openai.UserMessage(instructions)
+	default:
+		return openai.SystemMessage(instructions)
+	}
+}
+
+// reasoningContent extracts the non-standard reasoning_content field returned
+// by reasoning models such as DeepSeek R1.
+func reasoningContent(extra map[string]resp.Field) string {
+	// Unknown fields are never marked present, so decode the raw value
+	field, ok := extra["reasoning_content"]
+	if !ok || field.Raw() == "" {
+		return ""
+	}
+	var content string
+	if err := json.Unmarshal([]byte(field.Raw()), &content); err != nil {
+		return ""
+	}
+	return content
+}
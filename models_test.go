@@ -0,0 +1,123 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+func TestCapabilitiesForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		role  string
+		ok    bool
+	}{
+		{"gpt-4o", InstructionRoleSystem, false},
+		{"o1", InstructionRoleDeveloper, true},
+		{"o1-mini", InstructionRoleUser, false},
+		{"o3-mini-2025-01-31", InstructionRoleDeveloper, true},
+		{"deepseek/DeepSeek-R1", InstructionRoleUser, false},
+		{"deepseek-reasoner", InstructionRoleUser, false},
+		// Substring matches must not be treated as reasoning models
+		{"my-o1-finetune", InstructionRoleSystem, false},
+	}
+	for _, tt := range tests {
+		caps := CapabilitiesForModel(tt.model)
+		AssertEqual(t, tt.role, caps.InstructionRole, tt.model+" instruction role")
+		AssertEqual(t, tt.ok, caps.ReasoningEffort, tt.model+" reasoning effort")
+	}
+
+	RegisterModelCapabilities("qwq", ModelCapabilities{Reasoning: true})
+	defer func() {
+		modelCapabilitiesMu.Lock()
+		delete(modelCapabilities, "qwq")
+		modelCapabilitiesMu.Unlock()
+	}()
+	caps := CapabilitiesForModel("QwQ-32B")
+	AssertEqual(t, true, caps.Reasoning, "registered model")
+	AssertEqual(t, InstructionRoleSystem, caps.InstructionRole, "default instruction role")
+}
+
+func TestReasoningEffortParams(t *testing.T) {
+	swarm := NewSwarm(NewMockOpenAIClient())
+	agent := NewAgent("Thinker").WithReasoningEffort("high")
+	history := []map[string]interface{}{{"role": "user", "content": "Hi"}}
+
+	agent.Model = "o3-mini"
+	params, err := swarm.buildChatParams(context.Background(), agent, history, nil, "", false)
+	AssertNoError(t, err, "buildChatParams")
+	AssertEqual(t, openai.ReasoningEffortHigh, params.ReasoningEffort, "reasoning effort for o3")
+	AssertEqual(t, true, params.Messages[0].OfDeveloper != nil, "o3 instructions use the developer role")
+
+	agent.Model = "gpt-4o"
+	params, err = swarm.buildChatParams(context.Background(), agent, history, nil, "", false)
+	AssertNoError(t, err, "buildChatParams")
+	AssertEqual(t, openai.ReasoningEffort(""), params.ReasoningEffort, "no reasoning effort for gpt-4o")
+	AssertEqual(t, true, params.Messages[0].OfSystem != nil, "gpt-4o instructions use the system role")
+}
+
+func TestRunReasoningContent(t *testing.T) {
+	var completion openai.ChatCompletion
+	err := json.Unmarshal([]byte(`{"id":"1","object":"chat.completion","model":"deepseek-reasoner","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"42","reasoning_content":"Let me think."}}]}`), &completion)
+	AssertNoError(t, err, "Unmarshal completion")
+
+	mockClient := NewMockOpenAIClient()
+	mockClient.SetCompletionResponse(&completion)
+	swarm := NewSwarm(mockClient)
+	agent := NewAgent("Thinker").WithModel("deepseek-reasoner")
+
+	response, err := swarm.Run(context.Background(), agent, []map[string]interface{}{{"role": "user", "content": "?"}}, nil, "", false, false, 1, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "42", response.Messages[0]["content"], "content")
+	AssertEqual(t, "Let me think.", response.Messages[0]["reasoning_content"], "reasoning content")
+}
+
+func TestRunAndStreamReasoningContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		deltas := []string{
+			`{"role":"assistant","content":null,"reasoning_content":"Let me "}`,
+			`{"content":null,"reasoning_content":"think."}`,
+			`{"content":"42"}`,
+		}
+		for _, delta := range deltas {
+			fmt.Fprintf(w, "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"model\":\"deepseek-reasoner\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL))
+	swarm := NewSwarm(client)
+	agent := NewAgent("Thinker").WithModel("deepseek-reasoner")
+
+	ch, err := swarm.RunAndStream(context.Background(), agent, []map[string]interface{}{{"role": "user", "content": "?"}}, nil, "", false, 1, true, false)
+	AssertNoError(t, err, "RunAndStream")
+
+	var streamed strings.Builder
+	var response *Response
+	for msg := range ch {
+		if _, ok := msg["reasoning_content"]; ok {
+			t.Errorf("Expected reasoning only as stream chunks, got %v", msg)
+		}
+		if delta, ok := msg["chunk"].(*StreamChunk); ok && delta.Type == StreamChunkReasoning {
+			streamed.WriteString(delta.Delta)
+		}
+		if r, ok := msg["response"].(*Response); ok {
+			response = r
+		}
+	}
+	AssertEqual(t, "Let me think.", streamed.String(), "streamed reasoning")
+	if response == nil {
+		t.Fatal("Expected final response")
+	}
+	AssertEqual(t, "42", response.Messages[0]["content"], "content")
+	AssertEqual(t, "Let me think.", response.Messages[0]["reasoning_content"], "accumulated reasoning")
+}
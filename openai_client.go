@@ -33,6 +33,25 @@ type OpenAIClient interface {
 	//
 	// Returns a Stream of ChatCompletionChunk or an error if the stream creation fails.
 	CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error)
+
+	// Embeddings creates embedding vectors for the inputs using the given
+	// embedding model (or Azure deployment).
+	//
+	// Parameters:
+	//   - ctx: The context for the API request
+	//   - model: The embedding model, e.g. "text-embedding-3-small"
+	//   - inputs: The texts to embed
+	//
+	// Returns one vector per input, in input order, or an error if the request fails.
+	Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error)
+}
+
+// Pinger is implemented by clients that can check their connection to the
+// provider without generating tokens, e.g. for readiness probes. The clients
+// returned by NewOpenAIClient and NewAzureOpenAIClient implement it.
+type Pinger interface {
+	// Ping checks that the provider is reachable and accepts the credentials.
+	Ping(ctx context.Context) error
 }
 
 // openAIClientWrapper wraps the OpenAI client to implement the OpenAIClient interface.
@@ -46,13 +65,16 @@ type openAIClientWrapper struct {
 //
 // Parameters:
 //   - apiKey: The OpenAI API key for authentication
-func NewOpenAIClient(apiKey string) OpenAIClient {
+//   - opts: Optional transport settings such as WithProxy or WithRequestTimeout
+func NewOpenAIClient(apiKey string, opts ...ClientOption) OpenAIClient {
 	if apiKey == "" {
 		return nil
 	}
 
+	requestOpts := []option.RequestOption{option.WithAPIKey(apiKey)}
+
 	return &openAIClientWrapper{
-		client: openai.NewClient(option.WithAPIKey(apiKey)),
+		client: openai.NewClient(append(requestOpts, newClientOptions(opts)...)...),
 	}
 }
 
@@ -62,19 +84,31 @@ func NewOpenAIClient(apiKey string) OpenAIClient {
 // Parameters:
 //   - apiKey: The OpenAI API key for authentication
 //   - baseURL: The custom base URL for the API endpoint
-func NewOpenAIClientWithBaseURL(apiKey string, baseURL string) OpenAIClient {
+//   - opts: Optional transport settings such as WithProxy or WithRequestTimeout
+func NewOpenAIClientWithBaseURL(apiKey string, baseURL string, opts ...ClientOption) OpenAIClient {
 	if apiKey == "" {
 		return nil
 	}
 
-	if baseURL == "" {
-		return &openAIClientWrapper{
-			client: openai.NewClient(option.WithAPIKey(apiKey)),
-		}
+	requestOpts := []option.RequestOption{option.WithAPIKey(apiKey)}
+	if baseURL != "" {
+		requestOpts = append(requestOpts, option.WithBaseURL(baseURL))
 	}
 
 	return &openAIClientWrapper{
-		client: openai.NewClient(option.WithAPIKey(apiKey), option.WithBaseURL(baseURL)),
+		client: openai.NewClient(append(requestOpts, newClientOptions(opts)...)...),
+	}
+}
+
+// NewOpenAIClientWithOptions creates a new OpenAI client wrapper from raw
+// openai-go request options, e.g. to attach middleware such as
+// RequestLogger.Middleware.
+//
+// Parameters:
+//   - opts: The request options passed to openai.NewClient
+func NewOpenAIClientWithOptions(opts ...option.RequestOption) OpenAIClient {
+	return &openAIClientWrapper{
+		client: openai.NewClient(opts...),
 	}
 }
 
@@ -85,16 +119,19 @@ func NewOpenAIClientWithBaseURL(apiKey string, baseURL string) OpenAIClient {
 //   - apiKey: The Azure OpenAI API key
 //   - endpoint: The Azure OpenAI endpoint URL
 //   - apiVersion: The Azure OpenAI API version
-func NewAzureOpenAIClient(apiKey, endpoint, apiVersion string) OpenAIClient {
+//   - opts: Optional transport settings such as WithProxy or WithRequestTimeout
+func NewAzureOpenAIClient(apiKey, endpoint, apiVersion string, opts ...ClientOption) OpenAIClient {
 	if apiKey == "" || endpoint == "" {
 		return nil
 	}
 
+	requestOpts := []option.RequestOption{
+		azure.WithEndpoint(endpoint, apiVersion),
+		azure.WithAPIKey(apiKey),
+	}
+
 	return &openAIClientWrapper{
-		client: openai.NewClient(
-			azure.WithEndpoint(endpoint, apiVersion),
-			azure.WithAPIKey(apiKey),
-		),
+		client: openai.NewClient(append(requestOpts, newClientOptions(opts)...)...),
 	}
 }
 
@@ -110,10 +147,13 @@ func (c *openAIClientWrapper) CreateChatCompletion(ctx context.Context, params o
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if hosted := hostedToolsFromContext(ctx); len(hosted) > 0 {
+		return c.createResponse(ctx, params, hosted)
+	}
 
 	completion, err := c.client.Chat.Completions.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create chat completion: %w", err)
+		return nil, fmt.Errorf("failed to create chat completion: %w", wrapRateLimit(err))
 	}
 
 	return completion, nil
@@ -131,11 +171,69 @@ func (c *openAIClientWrapper) CreateChatCompletionStream(ctx context.Context, pa
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if hosted := hostedToolsFromContext(ctx); len(hosted) > 0 {
+		// The Responses API stream format differs, so serve hosted tools
+		// without streaming and replay the result as a single chunk
+		completion, err := c.createResponse(ctx, params, hosted)
+		if err != nil {
+			return nil, err
+		}
+		return completionStream(completion)
+	}
 
 	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
 	if stream == nil {
 		return nil, fmt.Errorf("failed to create streaming completion")
 	}
+	if err := wrapRateLimit(stream.Err()); err != nil {
+		if _, limited := RetryAfter(err); limited {
+			stream.Close()
+			return nil, fmt.Errorf("failed to create streaming completion: %w", err)
+		}
+	}
 
 	return stream, nil
 }
+
+// Embeddings creates embedding vectors for the inputs.
+// It wraps the underlying API call and returns the vectors in input order.
+//
+// Parameters:
+//   - ctx: The context for the API request (defaults to background if nil)
+//   - model: The embedding model or Azure deployment name
+//   - inputs: The texts to embed
+//
+// Returns one vector per input or an error if the request fails.
+func (c *openAIClientWrapper) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	response, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModel(model),
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: inputs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings: %w", wrapRateLimit(err))
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, data := range response.Data {
+		if data.Index < 0 || int(data.Index) >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
+
+// Ping checks the connection to the API by listing the available models.
+func (c *openAIClientWrapper) Ping(ctx context.Context) error {
+	if _, err := c.client.Models.List(ctx); err != nil {
+		return fmt.Errorf("failed to reach the API: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/option"
+)
+
+func TestEmbeddings(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AssertEqual(t, "/embeddings", r.URL.Path, "request path")
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		// Results arrive out of order and must be sorted by index
+		fmt.Fprint(w, `{"object":"list","model":"text-embedding-3-small","data":[
+			{"object":"embedding","index":1,"embedding":[0.3,0.4]},
+			{"object":"embedding","index":0,"embedding":[0.1,0.2]}
+		],"usage":{"prompt_tokens":4,"total_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL))
+	vectors, err := client.Embeddings(context.Background(), "text-embedding-3-small", []string{"first", "second"})
+	AssertNoError(t, err, "Embeddings")
+	AssertEqual(t, "text-embedding-3-small", request["model"], "model")
+	AssertEqual(t, 2, len(vectors), "vector count")
+	AssertEqual(t, 0.1, vectors[0][0], "first vector")
+	AssertEqual(t, 0.3, vectors[1][0], "second vector")
+}
+
+func TestPing(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AssertEqual(t, "/models", r.URL.Path, "request path")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"gpt-4o","object":"model","created":0,"owned_by":"openai"}]}`)
+	}))
+	defer server.Close()
+
+	pinger, ok := NewOpenAIClientWithBaseURL("sk-test", server.URL).(Pinger)
+	AssertEqual(t, true, ok, "client implements Pinger")
+	AssertNoError(t, pinger.Ping(context.Background()), "Ping")
+
+	status = http.StatusUnauthorized
+	AssertError(t, pinger.Ping(context.Background()), "Ping with rejected credentials")
+}
+
+func TestCachingClientEmbeddings(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	mockClient.EmbeddingResponse = [][]float64{{1, 2}}
+	client := NewCachingClient(mockClient, NewMemoryStepCache(), time.Minute)
+
+	_, err := client.Embeddings(context.Background(), "text-embedding-3-small", []string{"hello"})
+	AssertNoError(t, err, "first Embeddings")
+	mockClient.EmbeddingResponse = [][]float64{{3, 4}}
+	vectors, err := client.Embeddings(context.Background(), "text-embedding-3-small", []string{"hello"})
+	AssertNoError(t, err, "cached Embeddings")
+	AssertEqual(t, 1.0, vectors[0][0], "cached vector")
+
+	vectors, err = client.Embeddings(context.Background(), "text-embedding-3-large", []string{"hello"})
+	AssertNoError(t, err, "other model")
+	AssertEqual(t, 3.0, vectors[0][0], "different model is a miss")
+}
+
+func TestClientOptions(t *testing.T) {
+	embeddingsResponse := `{"object":"list","model":"m","data":[{"object":"embedding","index":0,"embedding":[1]}]}`
+
+	t.Run("headers and http client", func(t *testing.T) {
+		var gotHeader string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotHeader = r.Header.Get("X-Gateway-Key")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, embeddingsResponse)
+		}))
+		defer server.Close()
+
+		transport := &countingTransport{base: http.DefaultTransport}
+		client := NewOpenAIClientWithBaseURL("sk-test", server.URL,
+			WithHTTPClient(&http.Client{Transport: transport}),
+			WithHeader("X-Gateway-Key", "secret"),
+		)
+		_, err := client.Embeddings(context.Background(), "m", []string{"hi"})
+		AssertNoError(t, err, "Embeddings")
+		AssertEqual(t, "secret", gotHeader, "custom header")
+		AssertEqual(t, 1, transport.requests, "custom http client used")
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		var proxiedHost string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxiedHost = r.URL.Host
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, embeddingsResponse)
+		}))
+		defer proxy.Close()
+
+		client := NewOpenAIClientWithBaseURL("sk-test", "http://api.example.invalid/v1/", WithProxy(proxy.URL))
+		_, err := client.Embeddings(context.Background(), "m", []string{"hi"})
+		AssertNoError(t, err, "Embeddings through proxy")
+		AssertEqual(t, "api.example.invalid", proxiedHost, "proxied host")
+	})
+
+	t.Run("request timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer server.Close()
+
+		client := NewOpenAIClientWithOptions(append([]option.RequestOption{
+			option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL), option.WithMaxRetries(0),
+		}, newClientOptions([]ClientOption{WithRequestTimeout(20 * time.Millisecond)})...)...)
+		start := time.Now()
+		_, err := client.Embeddings(context.Background(), "m", []string{"hi"})
+		AssertError(t, err, "Expected timeout")
+		AssertEqual(t, true, time.Since(start) < 500*time.Millisecond, "request should time out early")
+	})
+
+	t.Run("transport", func(t *testing.T) {
+		var options clientOptions
+		shared := NewHTTPClient(DefaultTransportConfig())
+		WithHTTPClient(shared)(&options)
+		WithTransport(TransportConfig{MaxIdleConnsPerHost: 7, DisableKeepAlives: true})(&options)
+		tuned := options.client().Transport.(*http.Transport)
+		AssertEqual(t, 7, tuned.MaxIdleConnsPerHost, "tuned idle connections per host")
+		AssertEqual(t, 100, tuned.MaxIdleConns, "settings of the base transport kept")
+		AssertEqual(t, true, tuned.DisableKeepAlives, "keep-alives disabled")
+		AssertEqual(t, 100, shared.Transport.(*http.Transport).MaxIdleConnsPerHost, "shared transport unchanged")
+	})
+
+	t.Run("shared http client", func(t *testing.T) {
+		var conns atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, embeddingsResponse)
+		}))
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		server.Start()
+		defer server.Close()
+
+		shared := NewHTTPClient(DefaultTransportConfig())
+		for _, client := range []OpenAIClient{
+			NewOpenAIClientWithBaseURL("sk-test", server.URL, WithHTTPClient(shared)),
+			NewOpenAIClientWithBaseURL("sk-other", server.URL, WithHTTPClient(shared)),
+		} {
+			_, err := client.Embeddings(context.Background(), "m", []string{"hi"})
+			AssertNoError(t, err, "Embeddings")
+		}
+		AssertEqual(t, int32(1), conns.Load(), "clients reuse one connection")
+	})
+}
+
+// countingTransport counts requests sent through it.
+type countingTransport struct {
+	base     http.RoundTripper
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests++
+	return c.base.RoundTrip(r)
+}
@@ -0,0 +1,434 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event types used by the plan-and-execute workflow.
+const (
+	// EventPlanStep asks the executor to run the plan step at an index
+	EventPlanStep EventType = "PlanStepEvent"
+	// EventPlanComplete signals that every plan step has run
+	EventPlanComplete EventType = "PlanCompleteEvent"
+)
+
+// DefaultPlanSteps is the default maximum number of steps in a plan.
+const DefaultPlanSteps = 10
+
+// planAttempts is how many times the planner may answer before an invalid
+// plan fails the run; later attempts are told what was wrong.
+const planAttempts = 2
+
+// PlanStep is one step of a plan. A step is executed by calling Tool with
+// Arguments, by running the sub-agent named Agent, or, if neither is set, by
+// the planner itself.
+type PlanStep struct {
+	// ID identifies the step
+	ID string `json:"id"`
+	// Description says what the step should accomplish
+	Description string `json:"description"`
+	// Tool is the name of the tool to call
+	Tool string `json:"tool,omitempty"`
+	// Arguments are passed to Tool
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	// Agent is the name of the sub-agent that performs the step
+	Agent string `json:"agent,omitempty"`
+	// Output is the step's result
+	Output string `json:"output,omitempty"`
+	// Error is set if the step failed
+	Error string `json:"error,omitempty"`
+}
+
+// PlanResult is the outcome of a plan-and-execute run.
+type PlanResult struct {
+	// Goal is the goal that was planned for
+	Goal string `json:"goal"`
+	// Answer is the synthesized final answer
+	Answer string `json:"answer"`
+	// Steps lists the plan's steps with their outputs, in order
+	Steps []PlanStep `json:"steps"`
+}
+
+// PlanSchema is the JSON Schema plans are validated against.
+var PlanSchema = &Schema{
+	Type:     "object",
+	Required: []string{"steps"},
+	Properties: map[string]*Schema{
+		"steps": {
+			Type:     "array",
+			MinItems: intPtr(1),
+			Items: &Schema{
+				Type:     "object",
+				Required: []string{"id", "description"},
+				Properties: map[string]*Schema{
+					"id":          {Type: "string", MinLength: intPtr(1)},
+					"description": {Type: "string", MinLength: intPtr(1)},
+					"tool":        {Type: "string"},
+					"arguments":   {Type: "object"},
+					"agent":       {Type: "string"},
+				},
+			},
+		},
+	},
+}
+
+// PlanExecutor implements the plan-and-execute pattern: a planner agent
+// emits a structured plan for a goal, the steps run in order through tools or
+// sub-agents, and the planner synthesizes the final answer from their
+// outputs. Progress is reported as ProgressEvents on the workflow stream.
+type PlanExecutor struct {
+	// Swarm runs the agents
+	Swarm *Swarm
+	// Planner writes the plan and executes steps without a tool or agent
+	Planner *Agent
+	// Synthesizer writes the final answer (defaults to Planner)
+	Synthesizer *Agent
+	// Tools can be called by plan steps, selected by name
+	Tools []AgentFunction
+	// Agents can perform plan steps, selected by name
+	Agents []*Agent
+	// MaxSteps caps the number of steps in a plan (default 10)
+	MaxSteps int
+	// MaxTurns caps the model and tool turns of each agent run
+	MaxTurns int
+}
+
+// NewPlanExecutor creates a plan-and-execute runner with the given planner.
+func NewPlanExecutor(s *Swarm, planner *Agent) *PlanExecutor {
+	return &PlanExecutor{
+		Swarm:    s,
+		Planner:  planner,
+		MaxSteps: DefaultPlanSteps,
+		MaxTurns: 10,
+	}
+}
+
+// WithTools adds tools plan steps can call and returns the executor.
+func (pe *PlanExecutor) WithTools(tools ...AgentFunction) *PlanExecutor {
+	pe.Tools = append(pe.Tools, tools...)
+	return pe
+}
+
+// WithAgents adds sub-agents plan steps can delegate to and returns the
+// executor.
+func (pe *PlanExecutor) WithAgents(agents ...*Agent) *PlanExecutor {
+	pe.Agents = append(pe.Agents, agents...)
+	return pe
+}
+
+// WithSynthesizer sets the agent that writes the final answer and returns the
+// executor.
+func (pe *PlanExecutor) WithSynthesizer(agent *Agent) *PlanExecutor {
+	pe.Synthesizer = agent
+	return pe
+}
+
+// WithMaxSteps sets the maximum number of plan steps and returns the executor.
+func (pe *PlanExecutor) WithMaxSteps(n int) *PlanExecutor {
+	if n > 0 {
+		pe.MaxSteps = n
+	}
+	return pe
+}
+
+// Run plans and executes goal and returns the result.
+func (pe *PlanExecutor) Run(ctx context.Context, goal string) (*PlanResult, error) {
+	workflow, err := pe.Workflow()
+	if err != nil {
+		return nil, err
+	}
+	handler, err := workflow.Run(ctx, map[string]interface{}{"goal": goal})
+	if err != nil {
+		return nil, err
+	}
+	return WaitAs[*PlanResult](handler)
+}
+
+// Workflow builds the plan-and-execute workflow. It starts from a StartEvent
+// with a "goal" input and stops with a *PlanResult.
+func (pe *PlanExecutor) Workflow() (*Workflow, error) {
+	if pe.Swarm == nil || pe.Planner == nil {
+		return nil, fmt.Errorf("%w: plan executor needs a swarm and a planner", ErrInvalidParameter)
+	}
+
+	workflow := NewWorkflow("plan-execute")
+	steps := []Step{
+		// The planner is re-asked with feedback on invalid plans, so the
+		// step itself runs once
+		NewStep("plan", EventStart, pe.plan, StepConfig{RetryPolicy: &RetryPolicy{
+			MaxRetries:      1,
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second,
+			Multiplier:      1,
+		}}),
+		NewStep("execute", EventPlanStep, pe.execute, StepConfig{}),
+		NewStep("synthesize", EventPlanComplete, pe.synthesize, StepConfig{}),
+	}
+	for _, step := range steps {
+		if err := workflow.AddStep(step); err != nil {
+			return nil, err
+		}
+	}
+	return workflow, nil
+}
+
+// plan asks the planner for a valid plan and starts executing it.
+func (pe *PlanExecutor) plan(ctx *Context, event Event) (Event, error) {
+	goal, _ := event.Data()["goal"].(string)
+	if goal == "" {
+		return nil, fmt.Errorf("%w: goal is required", ErrInvalidParameter)
+	}
+	ctx.Set("plan.goal", goal)
+	if err := ctx.ReportProgress("plan", 0, "planning"); err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf("Goal: %s\n\n%s\n\nBreak the goal into at most %d ordered steps. %s",
+		goal, pe.capabilities(), pe.maxSteps(), planStepFormat)
+	var steps []PlanStep
+	for attempt := 1; ; attempt++ {
+		var err error
+		steps, err = pe.askPlan(ctx, prompt)
+		if err == nil {
+			break
+		}
+		if attempt >= planAttempts {
+			return nil, fmt.Errorf("planner failed: %w", err)
+		}
+		prompt = fmt.Sprintf("%s\n\nYour previous plan was rejected: %v", prompt, err)
+	}
+
+	ctx.Set("plan.steps", steps)
+	return planStepEvent(0), nil
+}
+
+// askPlan runs the planner and validates the plan it returns.
+func (pe *PlanExecutor) askPlan(ctx *Context, prompt string) ([]PlanStep, error) {
+	response, err := pe.Swarm.Run(ctx.Context(), pe.Planner, []map[string]interface{}{
+		{"role": "user", "content": prompt},
+	}, nil, "", false, false, pe.maxTurns(), true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+	if err := parseJSONReply(lastContent(response), &raw); err != nil {
+		return nil, err
+	}
+	if err := PlanSchema.Validate(raw); err != nil {
+		return nil, err
+	}
+	var plan struct {
+		Steps []PlanStep `json:"steps"`
+	}
+	if err := ToStruct(raw.(map[string]interface{}), &plan); err != nil {
+		return nil, err
+	}
+
+	if len(plan.Steps) > pe.maxSteps() {
+		return nil, fmt.Errorf("plan has %d steps, at most %d allowed", len(plan.Steps), pe.maxSteps())
+	}
+	for _, step := range plan.Steps {
+		if step.Tool != "" && step.Agent != "" {
+			return nil, fmt.Errorf("step %q sets both a tool and an agent", step.ID)
+		}
+		if step.Tool != "" && pe.findTool(step.Tool) == nil {
+			return nil, fmt.Errorf("step %q uses unknown tool %q", step.ID, step.Tool)
+		}
+		if step.Agent != "" && findAgent(pe.Agents, step.Agent) == nil {
+			return nil, fmt.Errorf("step %q uses unknown agent %q", step.ID, step.Agent)
+		}
+	}
+	return plan.Steps, nil
+}
+
+// execute runs one plan step and moves on to the next. Step failures are
+// recorded and left for the synthesizer rather than aborting the plan.
+func (pe *PlanExecutor) execute(ctx *Context, event Event) (Event, error) {
+	var position struct {
+		Index int `json:"index"`
+	}
+	if err := ToStruct(event.Data(), &position); err != nil {
+		return nil, fmt.Errorf("invalid plan step event: %w", err)
+	}
+	value, _ := ctx.Get("plan.steps")
+	prev, ok := value.([]PlanStep)
+	if !ok || position.Index < 0 || position.Index >= len(prev) {
+		return nil, fmt.Errorf("plan state missing")
+	}
+	// Copy the steps so a retried execution does not see partial updates
+	steps := append([]PlanStep(nil), prev...)
+	step := &steps[position.Index]
+
+	message := fmt.Sprintf("step %d/%d: %s", position.Index+1, len(steps), step.Description)
+	if err := ctx.ReportProgress("execute", float64(position.Index)*100/float64(len(steps)), message); err != nil {
+		return nil, err
+	}
+	output, err := pe.runStep(ctx, steps[:position.Index], *step)
+	if err != nil {
+		step.Error = err.Error()
+	} else {
+		step.Output = output
+	}
+	ctx.Set("plan.steps", steps)
+
+	if position.Index+1 < len(steps) {
+		return planStepEvent(position.Index + 1), nil
+	}
+	if err := ctx.ReportProgress("execute", 100, "all steps done"); err != nil {
+		return nil, err
+	}
+	return NewBaseEvent(EventPlanComplete, map[string]interface{}{}), nil
+}
+
+// runStep executes a step through its tool or agent, given the completed
+// steps before it.
+func (pe *PlanExecutor) runStep(ctx *Context, done []PlanStep, step PlanStep) (string, error) {
+	if step.Tool != "" {
+		fn := pe.findTool(step.Tool)
+		arguments := toolArguments(step.Arguments)
+		if err := pe.Swarm.checkApproval(ctx.Context(), fn, ToolCall{Function: Function{Name: step.Tool, Arguments: arguments}}, false); err != nil {
+			return "", err
+		}
+		result, err := AsContextFunction(approvedFunction(fn)).CallWithContext(&CallContext{
+			Context:    ctx.Context(),
+			Agent:      pe.Planner,
+			ToolCallID: step.ID,
+			RunID:      ctx.RunID(),
+		}, step.Arguments)
+		if err != nil {
+			return "", err
+		}
+		if s, ok := result.(string); ok {
+			return s, nil
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Sprint(result), nil
+		}
+		return string(data), nil
+	}
+
+	agent := pe.Planner
+	if step.Agent != "" {
+		agent = findAgent(pe.Agents, step.Agent)
+	}
+	goal, _ := ctx.GetString("plan.goal")
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Overall goal: %s\n", goal)
+	if len(done) > 0 {
+		prompt.WriteString("\nCompleted steps:\n")
+		writePlanSteps(&prompt, done)
+	}
+	fmt.Fprintf(&prompt, "\nYour task: %s", step.Description)
+
+	response, err := pe.Swarm.Run(ctx.Context(), agent, []map[string]interface{}{
+		{"role": "user", "content": prompt.String()},
+	}, nil, "", false, false, pe.maxTurns(), true, false)
+	if err != nil {
+		return "", err
+	}
+	return lastContent(response), nil
+}
+
+// synthesize asks the synthesizer for the final answer.
+func (pe *PlanExecutor) synthesize(ctx *Context, event Event) (Event, error) {
+	goal, _ := ctx.GetString("plan.goal")
+	value, _ := ctx.Get("plan.steps")
+	steps, _ := value.([]PlanStep)
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Goal: %s\n\nStep results:\n", goal)
+	writePlanSteps(&prompt, steps)
+	prompt.WriteString("\nUsing these results, write the final answer to the goal.")
+
+	synthesizer := pe.Synthesizer
+	if synthesizer == nil {
+		synthesizer = pe.Planner
+	}
+	response, err := pe.Swarm.Run(ctx.Context(), synthesizer, []map[string]interface{}{
+		{"role": "user", "content": prompt.String()},
+	}, nil, "", false, false, pe.maxTurns(), true, false)
+	if err != nil {
+		return nil, fmt.Errorf("synthesizer failed: %w", err)
+	}
+	return NewStopEvent(&PlanResult{Goal: goal, Answer: lastContent(response), Steps: steps}), nil
+}
+
+// planStepFormat describes the JSON reply expected from the planner.
+const planStepFormat = `Reply with a JSON object: {"steps": [{"id": string, "description": string, "tool": string, "arguments": object, "agent": string}]}. Set "tool" and "arguments" to call a tool, or "agent" to delegate to an agent; omit both to do the step yourself.`
+
+// capabilities describes the tools and agents for the planning prompt.
+func (pe *PlanExecutor) capabilities() string {
+	var b strings.Builder
+	b.WriteString("Tools:")
+	if len(pe.Tools) == 0 {
+		b.WriteString(" none")
+	}
+	for _, tool := range pe.Tools {
+		fmt.Fprintf(&b, "\n- %s: %s", tool.Name(), tool.Description())
+		for _, param := range tool.Parameters() {
+			fmt.Fprintf(&b, "\n  - %s (%v): %s", param.Name, param.Type, param.Description)
+		}
+	}
+	b.WriteString("\n\nAgents:")
+	if len(pe.Agents) == 0 {
+		b.WriteString(" none")
+	}
+	for _, agent := range pe.Agents {
+		b.WriteString("\n- " + agent.Name)
+		if instructions, ok := agent.Instructions.(string); ok && instructions != "" {
+			b.WriteString(": " + instructions)
+		}
+	}
+	return b.String()
+}
+
+// findTool returns the tool with the given name, or nil.
+func (pe *PlanExecutor) findTool(name string) AgentFunction {
+	for _, tool := range pe.Tools {
+		if tool.Name() == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+func (pe *PlanExecutor) maxSteps() int {
+	if pe.MaxSteps <= 0 {
+		return DefaultPlanSteps
+	}
+	return pe.MaxSteps
+}
+
+func (pe *PlanExecutor) maxTurns() int {
+	if pe.MaxTurns <= 0 {
+		return 10
+	}
+	return pe.MaxTurns
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// planStepEvent creates the event that runs the step at index.
+func planStepEvent(index int) Event {
+	return NewBaseEvent(EventPlanStep, map[string]interface{}{"index": index})
+}
+
+// writePlanSteps writes one line per step with its output or error.
+func writePlanSteps(b *strings.Builder, steps []PlanStep) {
+	for _, step := range steps {
+		fmt.Fprintf(b, "- [%s] %s: ", step.ID, step.Description)
+		if step.Error != "" {
+			fmt.Fprintf(b, "FAILED: %s\n", step.Error)
+		} else {
+			fmt.Fprintf(b, "%s\n", step.Output)
+		}
+	}
+}
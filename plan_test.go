@@ -0,0 +1,136 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPlanExecutor(t *testing.T) {
+	gate := make(chan struct{})
+	client := newRouterClient(func(prompt string) string {
+		switch {
+		case strings.HasPrefix(prompt, "Goal:") && strings.Contains(prompt, "Break the goal"):
+			<-gate
+			return `{"steps": [
+				{"id": "1", "description": "look up the weather", "tool": "get_weather", "arguments": {"city": "Paris"}},
+				{"id": "2", "description": "suggest clothes", "agent": "stylist"}
+			]}`
+		case strings.Contains(prompt, "Your task: suggest clothes"):
+			if !strings.Contains(prompt, "sunny in Paris") {
+				return "missing context"
+			}
+			return "wear a t-shirt"
+		case strings.Contains(prompt, "write the final answer"):
+			return "It is sunny, wear a t-shirt."
+		}
+		return "unexpected prompt"
+	})
+	weather := NewAgentFunction("get_weather", "Get the weather for a city",
+		func(args map[string]interface{}) (interface{}, error) {
+			return fmt.Sprintf("sunny in %s", args["city"]), nil
+		},
+		[]Parameter{{Name: "city", Description: "The city"}},
+	)
+
+	executor := NewPlanExecutor(NewSwarm(client), NewAgent("planner")).
+		WithTools(weather).
+		WithAgents(NewAgent("stylist"))
+	workflow, err := executor.Workflow()
+	AssertNoError(t, err, "Workflow")
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{"goal": "What should I wear in Paris?"})
+	AssertNoError(t, err, "Run")
+	progress := handler.StreamFiltered(EventProgress)
+	close(gate)
+
+	result, err := WaitAs[*PlanResult](handler)
+	AssertNoError(t, err, "Wait")
+	AssertEqual(t, "It is sunny, wear a t-shirt.", result.Answer, "answer")
+	AssertEqual(t, 2, len(result.Steps), "step count")
+	AssertEqual(t, "sunny in Paris", result.Steps[0].Output, "tool output")
+	AssertEqual(t, "wear a t-shirt", result.Steps[1].Output, "agent output")
+
+	var percents []string
+	for event := range progress {
+		percents = append(percents, fmt.Sprint(event.(*ProgressEvent).Percent))
+	}
+	AssertEqual(t, "0,0,50,100", strings.Join(percents, ","), "progress")
+}
+
+func TestPlanExecutorReplansInvalidPlan(t *testing.T) {
+	client := newScriptedClient(
+		`{"steps": [{"id": "1", "description": "search", "tool": "search"}]}`,
+		`{"steps": [{"id": "1", "description": "think"}]}`,
+		"thought",
+		"answer",
+	)
+
+	result, err := NewPlanExecutor(NewSwarm(client), NewAgent("planner")).Run(context.Background(), "goal")
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "answer", result.Answer, "answer")
+	AssertEqual(t, "thought", result.Steps[0].Output, "planner executes steps without tool or agent")
+
+	retry := client.requests[1].Messages[len(client.requests[1].Messages)-1].OfUser.Content.OfString.Value
+	AssertEqual(t, true, strings.Contains(retry, `unknown tool "search"`), "retry explains the rejection")
+}
+
+func TestPlanExecutorSchemaViolation(t *testing.T) {
+	client := newRouterClient(func(prompt string) string {
+		return `{"steps": [{"id": "1"}]}`
+	})
+
+	_, err := NewPlanExecutor(NewSwarm(client), NewAgent("planner")).Run(context.Background(), "goal")
+	AssertError(t, err, "Run")
+	AssertEqual(t, true, strings.Contains(err.Error(), "schema validation failed"), "schema error")
+}
+
+func TestPlanExecutorRecordsStepErrors(t *testing.T) {
+	client := newScriptedClient(
+		`{"steps": [{"id": "1", "description": "fail", "tool": "broken"}]}`,
+		"could not finish",
+	)
+	broken := NewAgentFunction("broken", "Always fails",
+		func(args map[string]interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("boom")
+		},
+		[]Parameter{},
+	)
+
+	result, err := NewPlanExecutor(NewSwarm(client), NewAgent("planner")).WithTools(broken).Run(context.Background(), "goal")
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "boom", result.Steps[0].Error, "step error")
+	synthesis := client.requests[1].Messages[len(client.requests[1].Messages)-1].OfUser.Content.OfString.Value
+	AssertEqual(t, true, strings.Contains(synthesis, "FAILED: boom"), "synthesizer sees failures")
+}
+
+func TestPlanExecutorToolCallContext(t *testing.T) {
+	client := newScriptedClient(
+		`{"steps": [{"id": "1", "description": "look up", "tool": "lookup"}]}`,
+		"done",
+	)
+	var called *CallContext
+	lookup := NewContextFunction("lookup", "Look up",
+		func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+			called = cc
+			return "found", nil
+		},
+		[]Parameter{},
+	)
+
+	planner := NewAgent("planner")
+	executor := NewPlanExecutor(NewSwarm(client), planner).WithTools(lookup)
+	workflow, err := executor.Workflow()
+	AssertNoError(t, err, "Workflow")
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{"goal": "goal"})
+	AssertNoError(t, err, "Run")
+	_, err = WaitAs[*PlanResult](handler)
+	AssertNoError(t, err, "Wait")
+
+	if called == nil || called.Context == nil {
+		t.Fatal("Expected the tool to be called with a CallContext")
+	}
+	AssertEqual(t, handler.Context().RunID(), called.RunID, "run ID")
+	AssertEqual(t, "1", called.ToolCallID, "step ID")
+	AssertEqual(t, planner, called.Agent, "calling agent")
+}
@@ -0,0 +1,306 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"plugin"
+	"sort"
+	"sync"
+)
+
+// PluginAPIVersion is the plugin API version implemented by this package.
+// Plugins built against a newer API version are rejected.
+const PluginAPIVersion = 1
+
+// PluginSymbol is the exported symbol looked up in Go plugin (.so) files.
+// It must be a variable of a type implementing Plugin.
+const PluginSymbol = "SwarmPlugin"
+
+// ErrPluginRegistered indicates that a plugin or extension with the same name already exists.
+var ErrPluginRegistered = errors.New("plugin already registered")
+
+// PluginCapability names a kind of extension a plugin may contribute.
+type PluginCapability string
+
+const (
+	// CapabilityProvider allows registering LLM client providers
+	CapabilityProvider PluginCapability = "provider"
+	// CapabilityTool allows registering agent tools
+	CapabilityTool PluginCapability = "tool"
+	// CapabilityStore allows registering storage backends
+	CapabilityStore PluginCapability = "store"
+	// CapabilityMiddleware allows registering event interceptors
+	CapabilityMiddleware PluginCapability = "middleware"
+)
+
+// PluginInfo describes a plugin and the capabilities it requests.
+type PluginInfo struct {
+	Name         string             `json:"name"`
+	Version      string             `json:"version"`
+	APIVersion   int                `json:"api_version"`
+	Capabilities []PluginCapability `json:"capabilities"`
+}
+
+// Plugin is implemented by third-party extensions.
+type Plugin interface {
+	// Info returns the plugin metadata used for version and capability negotiation
+	Info() PluginInfo
+	// Init registers the plugin's extensions with the host
+	Init(host *PluginHost) error
+}
+
+// ProviderFactory creates an OpenAIClient from provider-specific configuration.
+type ProviderFactory func(config map[string]string) (OpenAIClient, error)
+
+// StoreFactory creates a storage backend from configuration.
+// The concrete type depends on the store kind (e.g. CheckpointStore).
+type StoreFactory func(config map[string]string) (interface{}, error)
+
+// PluginHost is handed to a plugin during Init. It only accepts extensions for
+// capabilities the plugin declared in its PluginInfo.
+type PluginHost struct {
+	info     PluginInfo
+	registry *pluginRegistry
+	added    []registration
+}
+
+// registration is an extension added by a plugin, removed again if the
+// plugin fails to initialize.
+type registration struct {
+	m    map[string]interface{}
+	name string
+}
+
+// add registers an extension of the plugin.
+func (h *PluginHost) add(m map[string]interface{}, name string, v interface{}) error {
+	if err := h.registry.add(m, name, v); err != nil {
+		return err
+	}
+	h.added = append(h.added, registration{m: m, name: name})
+	return nil
+}
+
+// rollback removes the plugin and every extension it registered.
+func (h *PluginHost) rollback() {
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+	for _, r := range h.added {
+		delete(r.m, r.name)
+	}
+	h.added = nil
+	delete(h.registry.plugins, h.info.Name)
+}
+
+// allowed checks that the plugin negotiated the capability.
+func (h *PluginHost) allowed(capability PluginCapability) error {
+	for _, c := range h.info.Capabilities {
+		if c == capability {
+			return nil
+		}
+	}
+	return fmt.Errorf("plugin %s did not declare capability %q", h.info.Name, capability)
+}
+
+// RegisterProvider registers an LLM client provider under name.
+func (h *PluginHost) RegisterProvider(name string, factory ProviderFactory) error {
+	if err := h.allowed(CapabilityProvider); err != nil {
+		return err
+	}
+	return h.add(h.registry.providers, name, factory)
+}
+
+// RegisterTool registers an agent tool that can be looked up by name.
+func (h *PluginHost) RegisterTool(fn AgentFunction) error {
+	if err := h.allowed(CapabilityTool); err != nil {
+		return err
+	}
+	if fn == nil {
+		return fmt.Errorf("%w: nil tool", ErrInvalidFunction)
+	}
+	if err := fn.Validate(); err != nil {
+		return err
+	}
+	return h.add(h.registry.tools, fn.Name(), fn)
+}
+
+// RegisterStore registers a storage backend factory of the given kind
+// (e.g. "checkpoint") under name.
+func (h *PluginHost) RegisterStore(kind, name string, factory StoreFactory) error {
+	if err := h.allowed(CapabilityStore); err != nil {
+		return err
+	}
+	return h.add(h.registry.stores, kind+"/"+name, factory)
+}
+
+// RegisterMiddleware registers a named event interceptor.
+func (h *PluginHost) RegisterMiddleware(name string, interceptor EventInterceptor) error {
+	if err := h.allowed(CapabilityMiddleware); err != nil {
+		return err
+	}
+	return h.add(h.registry.middlewares, name, interceptor)
+}
+
+// pluginRegistry holds every registered plugin and extension.
+type pluginRegistry struct {
+	plugins     map[string]PluginInfo
+	providers   map[string]interface{}
+	tools       map[string]interface{}
+	stores      map[string]interface{}
+	middlewares map[string]interface{}
+	mu          sync.RWMutex
+}
+
+var plugins = &pluginRegistry{
+	plugins:     make(map[string]PluginInfo),
+	providers:   make(map[string]interface{}),
+	tools:       make(map[string]interface{}),
+	stores:      make(map[string]interface{}),
+	middlewares: make(map[string]interface{}),
+}
+
+// add stores an extension, rejecting duplicates.
+func (r *pluginRegistry) add(m map[string]interface{}, name string, v interface{}) error {
+	if name == "" {
+		return ErrInvalidName
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := m[name]; exists {
+		return fmt.Errorf("%w: %s", ErrPluginRegistered, name)
+	}
+	m[name] = v
+	return nil
+}
+
+// get looks up an extension.
+func (r *pluginRegistry) get(m map[string]interface{}, name string) (interface{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := m[name]
+	return v, ok
+}
+
+// negotiate validates plugin metadata against the host API.
+func negotiate(info PluginInfo) error {
+	if info.Name == "" {
+		return fmt.Errorf("%w: plugin name is empty", ErrInvalidName)
+	}
+	if info.APIVersion <= 0 || info.APIVersion > PluginAPIVersion {
+		return fmt.Errorf("plugin %s requires API version %d, host supports %d", info.Name, info.APIVersion, PluginAPIVersion)
+	}
+	for _, c := range info.Capabilities {
+		switch c {
+		case CapabilityProvider, CapabilityTool, CapabilityStore, CapabilityMiddleware:
+		default:
+			return fmt.Errorf("plugin %s requests unsupported capability %q", info.Name, c)
+		}
+	}
+	return nil
+}
+
+// RegisterPlugin negotiates and initializes a plugin. It is typically called
+// from the plugin package's init function.
+func RegisterPlugin(p Plugin) error {
+	if p == nil {
+		return errors.New("plugin cannot be nil")
+	}
+	info := p.Info()
+	if err := negotiate(info); err != nil {
+		return err
+	}
+
+	plugins.mu.Lock()
+	if _, exists := plugins.plugins[info.Name]; exists {
+		plugins.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrPluginRegistered, info.Name)
+	}
+	plugins.plugins[info.Name] = info
+	plugins.mu.Unlock()
+
+	host := &PluginHost{info: info, registry: plugins}
+	if err := p.Init(host); err != nil {
+		host.rollback()
+		return fmt.Errorf("failed to initialize plugin %s: %w", info.Name, err)
+	}
+	return nil
+}
+
+// MustRegisterPlugin is like RegisterPlugin but panics on error.
+func MustRegisterPlugin(p Plugin) {
+	if err := RegisterPlugin(p); err != nil {
+		panic(err)
+	}
+}
+
+// LoadPlugin opens a Go plugin file and registers the Plugin exported as PluginSymbol.
+func LoadPlugin(path string) error {
+	lib, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open plugin %s: %w", path, err)
+	}
+	sym, err := lib.Lookup(PluginSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to lookup %s in %s: %w", PluginSymbol, path, err)
+	}
+
+	var p Plugin
+	switch v := sym.(type) {
+	case Plugin:
+		p = v
+	case *Plugin:
+		p = *v
+	default:
+		return fmt.Errorf("symbol %s in %s does not implement Plugin", PluginSymbol, path)
+	}
+	return RegisterPlugin(p)
+}
+
+// Plugins returns the metadata of all registered plugins, sorted by name.
+func Plugins() []PluginInfo {
+	plugins.mu.RLock()
+	defer plugins.mu.RUnlock()
+	infos := make([]PluginInfo, 0, len(plugins.plugins))
+	for _, info := range plugins.plugins {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// NewProviderClient creates a client using a provider registered by a plugin.
+func NewProviderClient(name string, config map[string]string) (OpenAIClient, error) {
+	v, ok := plugins.get(plugins.providers, name)
+	if !ok {
+		return nil, fmt.Errorf("provider %q not registered", name)
+	}
+	return v.(ProviderFactory)(config)
+}
+
+// PluginTool returns a tool registered by a plugin.
+func PluginTool(name string) (AgentFunction, bool) {
+	v, ok := plugins.get(plugins.tools, name)
+	if !ok {
+		return nil, false
+	}
+	return v.(AgentFunction), true
+}
+
+// NewPluginStore creates a storage backend of the given kind registered by a plugin.
+func NewPluginStore(kind, name string, config map[string]string) (interface{}, error) {
+	v, ok := plugins.get(plugins.stores, kind+"/"+name)
+	if !ok {
+		return nil, fmt.Errorf("%s store %q not registered", kind, name)
+	}
+	return v.(StoreFactory)(config)
+}
+
+// PluginMiddleware returns an event interceptor registered by a plugin.
+func PluginMiddleware(name string) (EventInterceptor, bool) {
+	v, ok := plugins.get(plugins.middlewares, name)
+	if !ok {
+		return nil, false
+	}
+	return v.(EventInterceptor), true
+}
@@ -0,0 +1,103 @@
+package swarm
+
+import (
+	"errors"
+	"testing"
+)
+
+type testPlugin struct {
+	info PluginInfo
+}
+
+func (p *testPlugin) Info() PluginInfo {
+	return p.info
+}
+
+func (p *testPlugin) Init(host *PluginHost) error {
+	tool := NewAgentFunction("pluginEcho", "Echo input", func(args map[string]interface{}) (interface{}, error) {
+		return args["input"], nil
+	}, nil)
+	if err := host.RegisterTool(tool); err != nil {
+		return err
+	}
+	return host.RegisterProvider("plugin-mock", func(config map[string]string) (OpenAIClient, error) {
+		return NewMockOpenAIClient(), nil
+	})
+}
+
+// resetPlugins clears the global plugin registry after a test.
+func resetPlugins(t *testing.T) {
+	t.Cleanup(func() {
+		plugins = &pluginRegistry{
+			plugins:     make(map[string]PluginInfo),
+			providers:   make(map[string]interface{}),
+			tools:       make(map[string]interface{}),
+			stores:      make(map[string]interface{}),
+			middlewares: make(map[string]interface{}),
+		}
+	})
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	resetPlugins(t)
+	err := RegisterPlugin(&testPlugin{info: PluginInfo{
+		Name:         "test-plugin",
+		Version:      "1.0.0",
+		APIVersion:   PluginAPIVersion,
+		Capabilities: []PluginCapability{CapabilityTool, CapabilityProvider},
+	}})
+	if err != nil {
+		t.Fatalf("RegisterPlugin failed: %v", err)
+	}
+
+	if _, ok := PluginTool("pluginEcho"); !ok {
+		t.Error("Expected plugin tool to be registered")
+	}
+	if _, err := NewProviderClient("plugin-mock", nil); err != nil {
+		t.Errorf("NewProviderClient failed: %v", err)
+	}
+
+	err = RegisterPlugin(&testPlugin{info: PluginInfo{Name: "test-plugin", APIVersion: PluginAPIVersion}})
+	if !errors.Is(err, ErrPluginRegistered) {
+		t.Errorf("Expected ErrPluginRegistered, got %v", err)
+	}
+}
+
+func TestPluginNegotiation(t *testing.T) {
+	resetPlugins(t)
+	AssertError(t, RegisterPlugin(&testPlugin{info: PluginInfo{
+		Name:       "future-plugin",
+		APIVersion: PluginAPIVersion + 1,
+	}}), "newer API version")
+
+	// Tool registration must fail without the declared capability.
+	AssertError(t, RegisterPlugin(&testPlugin{info: PluginInfo{
+		Name:       "undeclared-plugin",
+		APIVersion: PluginAPIVersion,
+	}}), "undeclared capability")
+
+	for _, info := range Plugins() {
+		if info.Name == "undeclared-plugin" {
+			t.Error("Failed plugin should not remain registered")
+		}
+	}
+}
+
+func TestRegisterPluginRollback(t *testing.T) {
+	resetPlugins(t)
+	// The tool is registered before the undeclared provider fails Init.
+	AssertError(t, RegisterPlugin(&testPlugin{info: PluginInfo{
+		Name:         "partial-plugin",
+		APIVersion:   PluginAPIVersion,
+		Capabilities: []PluginCapability{CapabilityTool},
+	}}), "undeclared provider")
+	if _, ok := PluginTool("pluginEcho"); ok {
+		t.Error("Expected the tool of a failed plugin to be removed")
+	}
+
+	AssertNoError(t, RegisterPlugin(&testPlugin{info: PluginInfo{
+		Name:         "partial-plugin",
+		APIVersion:   PluginAPIVersion,
+		Capabilities: []PluginCapability{CapabilityTool, CapabilityProvider},
+	}}), "RegisterPlugin after rollback")
+}
@@ -0,0 +1,221 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// RateLimitError is returned when the provider rejects a request with HTTP 429.
+// It carries the wait time and quota information from the response headers and
+// wraps the underlying API error.
+type RateLimitError struct {
+	// RetryAfter is how long the provider asked to wait (zero if not given)
+	RetryAfter time.Duration
+	// ResetRequests and ResetTokens are the times until the request and
+	// token quotas reset (zero if not given)
+	ResetRequests time.Duration
+	ResetTokens   time.Duration
+	// RemainingRequests and RemainingTokens are the remaining quotas, or -1
+	// if not given
+	RemainingRequests int
+	RemainingTokens   int
+	// Err is the underlying API error
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	msg := "rate limited"
+	if wait := e.Wait(); wait > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", wait)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying API error.
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Wait returns how long to wait before retrying: RetryAfter if given,
+// otherwise the longest quota reset time.
+func (e *RateLimitError) Wait() time.Duration {
+	if e.RetryAfter > 0 {
+		return e.RetryAfter
+	}
+	return max(e.ResetRequests, e.ResetTokens)
+}
+
+// RetryAfter returns the wait time requested by a rate limit error anywhere in
+// err's chain.
+func RetryAfter(err error) (time.Duration, bool) {
+	var rle *RateLimitError
+	if !errors.As(err, &rle) {
+		return 0, false
+	}
+	return rle.Wait(), true
+}
+
+// wrapRateLimit converts 429 API errors into *RateLimitError and returns other
+// errors unchanged.
+func wrapRateLimit(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+	var rle *RateLimitError
+	if errors.As(err, &rle) {
+		return err
+	}
+
+	rle = &RateLimitError{RemainingRequests: -1, RemainingTokens: -1, Err: err}
+	if apiErr.Response == nil {
+		return rle
+	}
+	header := apiErr.Response.Header
+	rle.RetryAfter = parseRetryAfter(header)
+	rle.ResetRequests = parseResetDuration(header.Get("x-ratelimit-reset-requests"))
+	rle.ResetTokens = parseResetDuration(header.Get("x-ratelimit-reset-tokens"))
+	if n, err := strconv.Atoi(header.Get("x-ratelimit-remaining-requests")); err == nil {
+		rle.RemainingRequests = n
+	}
+	if n, err := strconv.Atoi(header.Get("x-ratelimit-remaining-tokens")); err == nil {
+		rle.RemainingTokens = n
+	}
+	return rle
+}
+
+// parseRetryAfter reads retry-after-ms or retry-after (seconds or HTTP date).
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("retry-after")
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// parseResetDuration parses quota reset headers such as "1s", "6m0s" or "20ms".
+func parseResetDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return 0
+}
+
+// Default settings for RetryClient.
+const (
+	defaultRateLimitRetries = 3
+	defaultRateLimitBackoff = time.Second
+	defaultRateLimitMaxWait = time.Minute
+)
+
+// RetryClient is an OpenAIClient decorator that retries rate-limited requests,
+// waiting as long as the provider asks (or backing off exponentially when it
+// does not say). Other errors are returned immediately.
+type RetryClient struct {
+	client     OpenAIClient
+	maxRetries int
+	maxWait    time.Duration
+}
+
+// NewRetryClient wraps client so rate-limited requests are retried up to
+// maxRetries times (3 if maxRetries <= 0).
+func NewRetryClient(client OpenAIClient, maxRetries int) *RetryClient {
+	if maxRetries <= 0 {
+		maxRetries = defaultRateLimitRetries
+	}
+	return &RetryClient{client: client, maxRetries: maxRetries, maxWait: defaultRateLimitMaxWait}
+}
+
+// WithMaxWait caps a single wait between retries, and returns the client. A
+// rate limit asking for a longer wait is returned to the caller instead.
+func (r *RetryClient) WithMaxWait(d time.Duration) *RetryClient {
+	r.maxWait = d
+	return r
+}
+
+// CreateChatCompletion sends the request, retrying rate limits.
+func (r *RetryClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	return retryRateLimits(r, ctx, func() (*openai.ChatCompletion, error) {
+		return r.client.CreateChatCompletion(ctx, params)
+	})
+}
+
+// CreateChatCompletionStream opens a stream, retrying rate limits.
+func (r *RetryClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	return retryRateLimits(r, ctx, func() (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+		stream, err := r.client.CreateChatCompletionStream(ctx, params)
+		if err == nil && stream != nil && stream.Err() != nil {
+			err = stream.Err()
+			stream.Close()
+			return nil, err
+		}
+		return stream, err
+	})
+}
+
+// Embeddings creates embeddings, retrying rate limits.
+func (r *RetryClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	return retryRateLimits(r, ctx, func() ([][]float64, error) {
+		return r.client.Embeddings(ctx, model, inputs)
+	})
+}
+
+// retryRateLimits calls fn until it succeeds, fails with another error, or
+// the retries are exhausted.
+func retryRateLimits[T any](r *RetryClient, ctx context.Context, fn func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := fn()
+		if err == nil {
+			return result, nil
+		}
+		err = wrapRateLimit(err)
+		wait, limited := RetryAfter(err)
+		if !limited || attempt >= r.maxRetries {
+			return result, err
+		}
+		if wait <= 0 {
+			wait = defaultRateLimitBackoff * time.Duration(math.Pow(2, float64(attempt)))
+		}
+		if r.maxWait > 0 && wait > r.maxWait {
+			return result, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+func TestRateLimitError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("retry-after-ms", "1500")
+		w.Header().Set("x-ratelimit-reset-requests", "6m0s")
+		w.Header().Set("x-ratelimit-reset-tokens", "20ms")
+		w.Header().Set("x-ratelimit-remaining-requests", "0")
+		w.Header().Set("x-ratelimit-remaining-tokens", "1200")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"Rate limit reached","type":"requests"}}`)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClientWithOptions(option.WithAPIKey("sk-test"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionNewParams{Model: "gpt-4o"})
+	AssertError(t, err, "Expected rate limit error")
+
+	var rle *RateLimitError
+	AssertEqual(t, true, errors.As(err, &rle), "Expected RateLimitError")
+	AssertEqual(t, 1500*time.Millisecond, rle.RetryAfter, "RetryAfter")
+	AssertEqual(t, 6*time.Minute, rle.ResetRequests, "ResetRequests")
+	AssertEqual(t, 20*time.Millisecond, rle.ResetTokens, "ResetTokens")
+	AssertEqual(t, 0, rle.RemainingRequests, "RemainingRequests")
+	AssertEqual(t, 1200, rle.RemainingTokens, "RemainingTokens")
+	AssertEqual(t, true, isRetryableError(err), "Rate limits should stay retryable")
+
+	var apiErr *openai.Error
+	AssertEqual(t, true, errors.As(err, &apiErr), "Expected wrapped API error")
+	AssertEqual(t, int32(1), calls.Load(), "Expected a single request")
+
+	_, err = client.Embeddings(context.Background(), "text-embedding-3-small", []string{"hi"})
+	wait, ok := RetryAfter(err)
+	AssertEqual(t, true, ok, "Expected rate limit error from embeddings")
+	AssertEqual(t, 1500*time.Millisecond, wait, "Embeddings wait")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"seconds", http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{"milliseconds win", http.Header{"Retry-After": {"2"}, "Retry-After-Ms": {"250"}}, 250 * time.Millisecond},
+		{"past date", http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, 0},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertEqual(t, tt.want, parseRetryAfter(tt.header), "parseRetryAfter")
+		})
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	wait := parseRetryAfter(http.Header{"Retry-After": {future}})
+	AssertEqual(t, true, wait > 59*time.Minute && wait <= time.Hour, "Expected about an hour")
+}
+
+func newRateLimitError(wait time.Duration) error {
+	return &RateLimitError{RetryAfter: wait, RemainingRequests: -1, RemainingTokens: -1, Err: newAPIError(http.StatusTooManyRequests)}
+}
+
+func TestRetryClient(t *testing.T) {
+	t.Run("retries rate limits", func(t *testing.T) {
+		mock := newReplyClient("ok")
+		flaky := &flakyClient{OpenAIClient: mock, errs: []error{newRateLimitError(time.Millisecond), newRateLimitError(time.Millisecond)}}
+		client := NewRetryClient(flaky, 3)
+
+		completion, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionNewParams{})
+		AssertNoError(t, err, "Expected success after retries")
+		AssertEqual(t, "ok", completion.Choices[0].Message.Content, "Unexpected content")
+		AssertEqual(t, 3, flaky.calls, "Expected two retries")
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		flaky := &flakyClient{OpenAIClient: newReplyClient("ok"), errs: []error{
+			newRateLimitError(time.Millisecond), newRateLimitError(time.Millisecond), newRateLimitError(time.Millisecond),
+		}}
+		_, err := NewRetryClient(flaky, 1).CreateChatCompletion(context.Background(), openai.ChatCompletionNewParams{})
+		_, ok := RetryAfter(err)
+		AssertEqual(t, true, ok, "Expected rate limit error")
+		AssertEqual(t, 2, flaky.calls, "Expected one retry")
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		flaky := &flakyClient{OpenAIClient: newReplyClient("ok"), errs: []error{newAPIError(http.StatusInternalServerError)}}
+		_, err := NewRetryClient(flaky, 3).CreateChatCompletion(context.Background(), openai.ChatCompletionNewParams{})
+		AssertError(t, err, "Expected server error")
+		AssertEqual(t, 1, flaky.calls, "Expected no retries")
+	})
+
+	t.Run("returns long waits to the caller", func(t *testing.T) {
+		flaky := &flakyClient{OpenAIClient: newReplyClient("ok"), errs: []error{newRateLimitError(time.Hour)}}
+		_, err := NewRetryClient(flaky, 3).WithMaxWait(time.Second).CreateChatCompletion(context.Background(), openai.ChatCompletionNewParams{})
+		wait, _ := RetryAfter(err)
+		AssertEqual(t, time.Hour, wait, "Expected provider wait")
+		AssertEqual(t, 1, flaky.calls, "Expected no retries")
+	})
+
+	t.Run("respects context cancellation", func(t *testing.T) {
+		flaky := &flakyClient{OpenAIClient: newReplyClient("ok"), errs: []error{newRateLimitError(time.Minute)}}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := NewRetryClient(flaky, 3).CreateChatCompletion(ctx, openai.ChatCompletionNewParams{})
+		AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded), "Expected deadline error")
+	})
+}
+
+func TestRetryPolicyHonorsRetryAfter(t *testing.T) {
+	policy := &RetryPolicy{InitialInterval: 10 * time.Millisecond, MaxInterval: 100 * time.Millisecond, Multiplier: 2}
+	AssertEqual(t, 10*time.Millisecond, policy.retryDelay(0, errors.New("boom")), "Expected backoff")
+	AssertEqual(t, 10*time.Millisecond, policy.retryDelay(0, newRateLimitError(time.Millisecond)), "Expected backoff when longer")
+	AssertEqual(t, 2*time.Second, policy.retryDelay(0, fmt.Errorf("step: %w", newRateLimitError(2*time.Second))), "Expected provider wait")
+}
+
+// flakyClient returns errs in order before delegating to the wrapped client.
+type flakyClient struct {
+	OpenAIClient
+	errs  []error
+	calls int
+}
+
+func (f *flakyClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return f.OpenAIClient.CreateChatCompletion(ctx, params)
+}
@@ -0,0 +1,135 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxRevisions is the default revision limit of ReflectiveRun.
+const DefaultMaxRevisions = 2
+
+// ReflectionConfig configures ReflectiveRun.
+type ReflectionConfig struct {
+	// Critic evaluates answers (defaults to the answering agent)
+	Critic *Agent
+	// Criteria are the requirements the answer is checked against
+	Criteria []string
+	// MaxRevisions caps the number of revisions (default 2)
+	MaxRevisions int
+	// MaxTurns caps the model and tool turns of each run (default 10)
+	MaxTurns int
+}
+
+// ReflectionRound records one critique of an answer.
+type ReflectionRound struct {
+	// Answer is the critiqued answer
+	Answer string `json:"answer"`
+	// Approved reports whether the answer met the criteria
+	Approved bool `json:"approved"`
+	// Feedback is the critic's feedback
+	Feedback string `json:"feedback"`
+}
+
+// ReflectiveResponse is the result of ReflectiveRun. The embedded Response
+// holds every message, including the critic's feedback, and the final answer
+// is the last message.
+type ReflectiveResponse struct {
+	*Response
+	// Rounds lists each critique in order
+	Rounds []ReflectionRound
+	// Approved reports whether the final answer was approved
+	Approved bool
+}
+
+// ReflectiveRun runs agent, then has a critic evaluate the answer against the
+// configured criteria. Rejected answers are revised using the critic's
+// feedback until one is approved or MaxRevisions is reached.
+//
+// Parameters:
+//   - ctx: The context for the run
+//   - agent: The agent that answers
+//   - messages: The conversation to answer
+//   - contextVariables: Variables to be used in the conversation
+//   - config: The critic, criteria and revision limit
+//
+// Returns the final answer with the critique history, or an error if a run fails.
+func (s *Swarm) ReflectiveRun(ctx context.Context, agent *Agent, messages []map[string]interface{}, contextVariables map[string]interface{}, config ReflectionConfig) (*ReflectiveResponse, error) {
+	maxRevisions := config.MaxRevisions
+	if maxRevisions <= 0 {
+		maxRevisions = DefaultMaxRevisions
+	}
+	maxTurns := config.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = 10
+	}
+	if contextVariables == nil {
+		contextVariables = make(map[string]interface{})
+	}
+
+	response, err := s.Run(ctx, agent, messages, contextVariables, "", false, false, maxTurns, true, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReflectiveResponse{Response: response}
+	task := lastUserContent(messages)
+
+	for revision := 0; ; revision++ {
+		critic := config.Critic
+		if critic == nil {
+			critic = result.Agent
+		}
+		answer := lastContent(result.Response)
+		round, err := s.critique(ctx, critic, task, answer, config.Criteria)
+		if err != nil {
+			return nil, err
+		}
+		result.Rounds = append(result.Rounds, *round)
+		result.Approved = round.Approved
+		if round.Approved || revision >= maxRevisions {
+			return result, nil
+		}
+
+		feedback := map[string]interface{}{
+			"role":    "user",
+			"content": "Revise your answer based on this feedback:\n" + round.Feedback,
+		}
+		history := append(append(append([]map[string]interface{}{}, messages...), result.Messages...), feedback)
+		revised, err := s.Run(ctx, result.Agent, history, result.ContextVariables, "", false, false, maxTurns, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("revision %d failed: %w", revision+1, err)
+		}
+		result.Messages = append(append(result.Messages, feedback), revised.Messages...)
+		result.Agent = revised.Agent
+		result.ContextVariables = revised.ContextVariables
+	}
+}
+
+// critique asks critic to evaluate answer against criteria.
+func (s *Swarm) critique(ctx context.Context, critic *Agent, task, answer string, criteria []string) (*ReflectionRound, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Evaluate the answer to the task.\n\nTask: %s\n\nAnswer:\n%s\n", task, answer)
+	if len(criteria) > 0 {
+		prompt.WriteString("\nCriteria:\n")
+		for _, criterion := range criteria {
+			prompt.WriteString("- " + criterion + "\n")
+		}
+	}
+	prompt.WriteString("\n")
+	prompt.WriteString(`Reply with a JSON object: {"approved": bool, "feedback": "what must change, if not approved"}.`)
+
+	response, err := s.Run(ctx, critic, []map[string]interface{}{
+		{"role": "user", "content": prompt.String()},
+	}, nil, "", false, false, 1, false, true)
+	if err != nil {
+		return nil, fmt.Errorf("critique failed: %w", err)
+	}
+	var verdict struct {
+		Approved bool   `json:"approved"`
+		Feedback string `json:"feedback"`
+	}
+	if err := parseJSONReply(lastContent(response), &verdict); err != nil {
+		return nil, fmt.Errorf("critique failed: %w", err)
+	}
+	return &ReflectionRound{Answer: answer, Approved: verdict.Approved, Feedback: verdict.Feedback}, nil
+}
@@ -0,0 +1,62 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReflectiveRunRevisesUntilApproved(t *testing.T) {
+	client := newScriptedClient(
+		"Why did the chicken cross the road?",
+		`{"approved": false, "feedback": "Add a punchline."}`,
+		"Why did the chicken cross the road? To get to the other side.",
+		`{"approved": true, "feedback": ""}`,
+	)
+	writer := NewAgent("writer")
+	critic := NewAgent("critic")
+
+	response, err := NewSwarm(client).ReflectiveRun(context.Background(), writer, []map[string]interface{}{
+		{"role": "user", "content": "Tell a joke"},
+	}, nil, ReflectionConfig{Critic: critic, Criteria: []string{"has a punchline"}})
+	AssertNoError(t, err, "ReflectiveRun")
+
+	AssertEqual(t, true, response.Approved, "approved")
+	AssertEqual(t, 2, len(response.Rounds), "round count")
+	AssertEqual(t, "Add a punchline.", response.Rounds[0].Feedback, "feedback")
+	AssertEqual(t, "Why did the chicken cross the road? To get to the other side.", lastContent(response.Response), "final answer")
+	AssertEqual(t, 3, len(response.Messages), "message count")
+
+	critique := client.requests[1].Messages[len(client.requests[1].Messages)-1].OfUser.Content.OfString.Value
+	AssertEqual(t, true, strings.Contains(critique, "has a punchline"), "criteria in critique prompt")
+	revision := client.requests[2].Messages[len(client.requests[2].Messages)-1].OfUser.Content.OfString.Value
+	AssertEqual(t, true, strings.Contains(revision, "Add a punchline."), "feedback in revision prompt")
+}
+
+func TestReflectiveRunStopsAtMaxRevisions(t *testing.T) {
+	client := newScriptedClient(
+		"draft",
+		`{"approved": false, "feedback": "worse"}`,
+		"revised",
+		`{"approved": false, "feedback": "still bad"}`,
+	)
+
+	response, err := NewSwarm(client).ReflectiveRun(context.Background(), NewAgent("writer"), []map[string]interface{}{
+		{"role": "user", "content": "Write"},
+	}, nil, ReflectionConfig{MaxRevisions: 1})
+	AssertNoError(t, err, "ReflectiveRun")
+
+	AssertEqual(t, false, response.Approved, "approved")
+	AssertEqual(t, 2, len(response.Rounds), "round count")
+	AssertEqual(t, "revised", lastContent(response.Response), "final answer")
+	AssertEqual(t, 4, len(client.requests), "request count")
+}
+
+func TestReflectiveRunInvalidCritique(t *testing.T) {
+	client := newScriptedClient("draft", "looks good")
+
+	_, err := NewSwarm(client).ReflectiveRun(context.Background(), NewAgent("writer"), []map[string]interface{}{
+		{"role": "user", "content": "Write"},
+	}, nil, ReflectionConfig{})
+	AssertError(t, err, "ReflectiveRun")
+}
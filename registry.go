@@ -0,0 +1,172 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrAgentNotFound is returned when no agent is registered under a name.
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrAgentRegistered is returned when registering a duplicate agent name.
+	ErrAgentRegistered = errors.New("agent already registered")
+)
+
+// AgentRegistry maps agent names to agents, so handoffs, YAML workflows and
+// servers can refer to agents by name. It is safe for concurrent use.
+type AgentRegistry struct {
+	agents map[string]*Agent
+	mu     sync.RWMutex
+}
+
+// DefaultRegistry is the registry used when no other registry is configured,
+// e.g. to resolve the agent of a SimpleFlow step loaded from YAML.
+var DefaultRegistry = NewAgentRegistry()
+
+// NewAgentRegistry creates an empty registry.
+func NewAgentRegistry() *AgentRegistry {
+	return &AgentRegistry{agents: make(map[string]*Agent)}
+}
+
+// Register validates and adds agents. It fails without registering any agent
+// if one is invalid or its name is already taken.
+func (r *AgentRegistry) Register(agents ...*Agent) error {
+	for i, agent := range agents {
+		if err := validateAgent(agent); err != nil {
+			return err
+		}
+		for _, other := range agents[:i] {
+			if other.Name == agent.Name {
+				return fmt.Errorf("%w: %s", ErrAgentRegistered, agent.Name)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, agent := range agents {
+		if _, exists := r.agents[agent.Name]; exists {
+			return fmt.Errorf("%w: %s", ErrAgentRegistered, agent.Name)
+		}
+	}
+	for _, agent := range agents {
+		r.agents[agent.Name] = agent
+	}
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is intended for
+// package initialization.
+func (r *AgentRegistry) MustRegister(agents ...*Agent) *AgentRegistry {
+	if err := r.Register(agents...); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Unregister removes the named agent, if registered.
+func (r *AgentRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.agents, name)
+}
+
+// Get returns the named agent.
+func (r *AgentRegistry) Get(name string) (*Agent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	agent, ok := r.agents[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, name)
+	}
+	return agent, nil
+}
+
+// Names returns the registered agent names in sorted order.
+func (r *AgentRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.agents))
+	for name := range r.agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handoff returns a tool that transfers the conversation to the named agent.
+// The agent is looked up when the tool is called, so it may be registered
+// after the handoff is created, e.g. for agents that hand off to each other.
+func (r *AgentRegistry) Handoff(name, description string) AgentFunction {
+	if description == "" {
+		description = fmt.Sprintf("Transfer the conversation to %s.", name)
+	}
+	return NewAgentFunction(
+		"transfer_to_"+toolName(name),
+		description,
+		func(args map[string]interface{}) (interface{}, error) {
+			return r.Get(name)
+		},
+		[]Parameter{},
+	)
+}
+
+// validateAgent checks that an agent can be registered.
+func validateAgent(agent *Agent) error {
+	if agent == nil {
+		return fmt.Errorf("%w: agent is nil", ErrInvalidParameter)
+	}
+	if strings.TrimSpace(agent.Name) == "" {
+		return fmt.Errorf("%w: agent name is empty", ErrInvalidName)
+	}
+	seen := make(map[string]bool, len(agent.Functions))
+	for _, f := range agent.Functions {
+		if f == nil {
+			return fmt.Errorf("%w: agent %s has a nil function", ErrInvalidFunction, agent.Name)
+		}
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.Name, err)
+		}
+		if seen[f.Name()] {
+			return fmt.Errorf("%w: agent %s has duplicate function %s", ErrInvalidFunction, agent.Name, f.Name())
+		}
+		seen[f.Name()] = true
+	}
+	return nil
+}
+
+// toolName converts an agent name to a valid tool name fragment.
+func toolName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// WithRegistry sets the registry used to look up agents by name and returns
+// the swarm.
+func (s *Swarm) WithRegistry(registry *AgentRegistry) *Swarm {
+	s.Registry = registry
+	return s
+}
+
+// Agent returns the named agent from the swarm's registry, or from
+// DefaultRegistry if none is set.
+func (s *Swarm) Agent(name string) (*Agent, error) {
+	return s.registry().Get(name)
+}
+
+// registry returns the swarm's registry or DefaultRegistry.
+func (s *Swarm) registry() *AgentRegistry {
+	if s.Registry != nil {
+		return s.Registry
+	}
+	return DefaultRegistry
+}
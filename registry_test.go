@@ -0,0 +1,91 @@
+package swarm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAgentRegistry(t *testing.T) {
+	registry := NewAgentRegistry()
+	billing := NewAgent("billing")
+	support := NewAgent("support")
+	AssertNoError(t, registry.Register(billing, support), "Register")
+
+	agent, err := registry.Get("billing")
+	AssertNoError(t, err, "Get")
+	AssertEqual(t, billing, agent, "registered agent")
+	AssertEqual(t, "billing,support", strings.Join(registry.Names(), ","), "names")
+
+	_, err = registry.Get("sales")
+	AssertEqual(t, true, errors.Is(err, ErrAgentNotFound), "missing agent")
+
+	err = registry.Register(NewAgent("sales"), NewAgent("billing"))
+	AssertEqual(t, true, errors.Is(err, ErrAgentRegistered), "duplicate agent")
+	_, err = registry.Get("sales")
+	AssertEqual(t, true, errors.Is(err, ErrAgentNotFound), "failed registration is atomic")
+
+	err = registry.Register(NewAgent("dup"), NewAgent("dup"))
+	AssertEqual(t, true, errors.Is(err, ErrAgentRegistered), "duplicate within call")
+
+	registry.Unregister("support")
+	AssertEqual(t, "billing", strings.Join(registry.Names(), ","), "names after unregister")
+}
+
+func TestAgentRegistryValidation(t *testing.T) {
+	registry := NewAgentRegistry()
+	AssertError(t, registry.Register(nil), "nil agent")
+	AssertError(t, registry.Register(&Agent{Name: " "}), "empty name")
+
+	fn := NewAgentFunction("lookup", "", func(map[string]interface{}) (interface{}, error) { return "", nil }, []Parameter{})
+	agent := NewAgent("tools").AddFunction(fn).AddFunction(fn)
+	err := registry.Register(agent)
+	AssertEqual(t, true, errors.Is(err, ErrInvalidFunction), "duplicate function")
+
+	broken := NewAgent("broken")
+	broken.Functions = append(broken.Functions, &SimpleAgentFunction{NameString: "noop"})
+	AssertError(t, registry.Register(broken), "invalid function")
+}
+
+func TestAgentRegistryHandoff(t *testing.T) {
+	registry := NewAgentRegistry()
+	handoff := registry.Handoff("Spanish Agent", "")
+	AssertEqual(t, "transfer_to_Spanish_Agent", handoff.Name(), "tool name")
+
+	// Agents may be registered after the handoff is created
+	_, err := handoff.Call(nil)
+	AssertEqual(t, true, errors.Is(err, ErrAgentNotFound), "unregistered target")
+
+	spanish := NewAgent("Spanish Agent")
+	registry.MustRegister(spanish)
+	target, err := handoff.Call(nil)
+	AssertNoError(t, err, "handoff")
+	AssertEqual(t, spanish, target, "handoff target")
+
+	s := NewSwarm(NewMockOpenAIClient()).WithRegistry(registry)
+	agent, err := s.Agent("Spanish Agent")
+	AssertNoError(t, err, "Swarm.Agent")
+	AssertEqual(t, spanish, agent, "swarm lookup")
+}
+
+func TestSimpleFlowAgentReference(t *testing.T) {
+	registry := NewAgentRegistry()
+	writer := NewAgent("writer").WithModel("gpt-4o").WithInstructions("Write well.")
+	registry.MustRegister(writer)
+
+	flow := &SimpleFlow{
+		Registry: registry,
+		Steps: []SimpleFlowStep{
+			{Name: "draft", AgentName: "writer"},
+			{Name: "publish", Instructions: "Publish it."},
+		},
+	}
+	AssertNoError(t, flow.Initialize(), "Initialize")
+	AssertEqual(t, "gpt-4o", flow.Steps[0].Agent.Model, "registered model")
+	AssertEqual(t, "Write well.\n\nHandoff to the next step after you finish your task.", flow.Steps[0].Agent.Instructions, "instructions")
+	AssertEqual(t, "Write well.", writer.Instructions, "registered agent is not modified")
+	AssertEqual(t, 0, len(writer.Functions), "registered agent functions are not modified")
+
+	missing := &SimpleFlow{Registry: registry, Steps: []SimpleFlowStep{{Name: "x", AgentName: "ghost"}}}
+	AssertEqual(t, true, errors.Is(missing.Initialize(), ErrAgentNotFound), "unknown agent")
+}
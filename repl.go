@@ -0,0 +1,581 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+// DefaultHistoryFile is the file, relative to the user's home directory, in
+// which RunDemoLoop keeps the input history.
+const DefaultHistoryFile = ".swarm_history"
+
+// TranscriptEnv names the environment variable holding the transcript file of
+// RunDemoLoop.
+const TranscriptEnv = "SWARM_TRANSCRIPT"
+
+// DefaultReplMaxTurns is the default turn budget of each REPL exchange.
+const DefaultReplMaxTurns = 10
+
+// Exit codes returned by RunRepl.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// ReplOptions configures RunRepl.
+type ReplOptions struct {
+	// Model overrides the agents' models (default: each agent's own Model)
+	Model string
+	// MaxTurns limits the turns of each exchange (default 10)
+	MaxTurns int
+	// Stream prints replies as they are generated
+	Stream bool
+	// Debug enables debug output
+	Debug bool
+	// Temperature overrides the sampling temperature of non-reasoning models
+	Temperature *float64
+	// ContextVariables are the initial context variables
+	ContextVariables map[string]interface{}
+	// Prompt is answered non-interactively instead of starting a session
+	Prompt string
+	// Transcript is a file the conversation is restored from on start and
+	// saved to on exit (optional)
+	Transcript string
+}
+
+// RunRepl starts an interactive session with startingAgent. Lines starting
+// with a slash are commands, such as /reset, /agent, /model, /temperature,
+// /set, /save, /load, /tools and /debug; /help lists them. Input continues on
+// the next line after a trailing backslash, or until closing triple quotes
+// after opening ones.
+//
+// When options.Prompt is set or stdin is not a terminal, RunRepl runs
+// non-interactively instead: the prompt and any piped input are sent as one
+// message and only the final reply is written to stdout, for use in shell
+// pipelines. The returned exit code is 0 on success, 1 if the run failed or
+// stopped early, and 2 if there was no input.
+func RunRepl(startingAgent *Agent, options ReplOptions) int {
+	stdinTerminal := readline.IsTerminal(int(os.Stdin.Fd()))
+	interactive := options.Prompt == "" && stdinTerminal
+	if interactive {
+		fmt.Println("Starting Swarm CLI 🐝")
+	}
+
+	client, err := NewDefaultSwarm()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Swarm client: %v\n", err)
+		return exitFailure
+	}
+
+	loop := newDemoLoop(client, startingAgent, options)
+	if transcript := options.Transcript; transcript != "" {
+		if err := loop.loadTranscript(transcript); err == nil {
+			if interactive {
+				fmt.Printf("Restored %d messages from %s\n", len(loop.messages), transcript)
+			}
+		} else if !errors.Is(err, ErrSessionNotFound) {
+			fmt.Fprintf(os.Stderr, "Error restoring transcript: %v\n", err)
+		}
+		defer func() {
+			if err := loop.saveTranscript(transcript); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving transcript: %v\n", err)
+			}
+		}()
+	}
+
+	if !interactive {
+		var in io.Reader
+		if !stdinTerminal {
+			in = os.Stdin
+		}
+		return loop.runPiped(context.Background(), options.Prompt, in, os.Stdout, os.Stderr)
+	}
+
+	reader, err := newLineReader()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing input: %v\n", err)
+		return exitFailure
+	}
+	defer reader.Close()
+
+	prompt := reader.Config.Prompt
+	var pending multilineInput
+	for {
+		if pending.pending() {
+			reader.SetPrompt(continuationPrompt)
+		} else {
+			reader.SetPrompt(prompt)
+		}
+
+		line, err := reader.Readline()
+		if err == readline.ErrInterrupt {
+			// Ctrl+C discards a partial input and exits on an empty one
+			if line == "" && !pending.pending() {
+				fmt.Println("Exiting Swarm CLI 🐝")
+				return exitOK
+			}
+			pending.reset()
+			continue
+		}
+		if err != nil {
+			if err == io.EOF {
+				fmt.Println("Exiting Swarm CLI 🐝")
+				return exitOK
+			}
+
+			fmt.Printf("Error reading input: %v\n", err)
+			continue
+		}
+
+		input, ok := pending.add(line)
+		if !ok {
+			continue
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		if strings.HasPrefix(input, "/") {
+			if err := loop.command(input); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			continue
+		}
+		loop.send(context.Background(), input)
+	}
+}
+
+// continuationPrompt is shown while a multi-line input is incomplete.
+const continuationPrompt = "... "
+
+// newLineReader creates the line editor of the demo loop. It supports arrow
+// key editing, history persisted across runs, and Ctrl+R reverse search.
+func newLineReader() (*readline.Instance, error) {
+	return readline.NewEx(&readline.Config{
+		Prompt:            colorGray + "User" + colorReset + ": ",
+		HistoryFile:       historyFilePath(),
+		HistorySearchFold: true,
+		InterruptPrompt:   "^C",
+		EOFPrompt:         "exit",
+	})
+}
+
+// historyFilePath returns the path of the history file, or "" (no
+// persistent history) if the home directory is unknown.
+func historyFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, DefaultHistoryFile)
+}
+
+// multilineInput assembles user input spanning several lines. A line ending
+// with a backslash continues on the next line, and text between lines
+// starting and ending with triple quotes (""") is taken verbatim, e.g. for
+// pasting code or long documents.
+type multilineInput struct {
+	lines  []string
+	quoted bool
+}
+
+// add adds a line and returns the input once it is complete.
+func (m *multilineInput) add(line string) (string, bool) {
+	if m.quoted {
+		trimmed := strings.TrimRight(line, " \t")
+		if strings.HasSuffix(trimmed, `"""`) {
+			m.lines = append(m.lines, strings.TrimSuffix(trimmed, `"""`))
+			return m.finish(), true
+		}
+		m.lines = append(m.lines, line)
+		return "", false
+	}
+
+	if len(m.lines) == 0 {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), `"""`); ok {
+			if len(rest) >= 3 && strings.HasSuffix(rest, `"""`) {
+				m.lines = append(m.lines, strings.TrimSuffix(rest, `"""`))
+				return m.finish(), true
+			}
+			m.quoted = true
+			if rest != "" {
+				m.lines = append(m.lines, rest)
+			}
+			return "", false
+		}
+	}
+
+	if continued, ok := strings.CutSuffix(line, `\`); ok {
+		m.lines = append(m.lines, continued)
+		return "", false
+	}
+	m.lines = append(m.lines, line)
+	return m.finish(), true
+}
+
+// pending reports whether an incomplete input has been started.
+func (m *multilineInput) pending() bool {
+	return m.quoted || len(m.lines) > 0
+}
+
+// reset discards the incomplete input.
+func (m *multilineInput) reset() {
+	m.lines, m.quoted = nil, false
+}
+
+// finish returns the assembled input and resets m.
+func (m *multilineInput) finish() string {
+	input := strings.Join(m.lines, "\n")
+	m.reset()
+	return input
+}
+
+// demoLoop is the conversation state of RunRepl.
+type demoLoop struct {
+	swarm            *Swarm
+	startingAgent    *Agent
+	agent            *Agent
+	messages         []map[string]interface{}
+	contextVariables map[string]interface{}
+	model            string
+	maxTurns         int
+	temperature      *float64
+	stream           bool
+	debug            bool
+	out              io.Writer
+}
+
+// newDemoLoop creates the state of a demo loop starting with agent.
+func newDemoLoop(swarm *Swarm, agent *Agent, options ReplOptions) *demoLoop {
+	maxTurns := options.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultReplMaxTurns
+	}
+	contextVariables := make(map[string]interface{}, len(options.ContextVariables))
+	for k, v := range options.ContextVariables {
+		contextVariables[k] = v
+	}
+	return &demoLoop{
+		swarm:            swarm,
+		startingAgent:    agent,
+		agent:            agent,
+		messages:         make([]map[string]interface{}, 0),
+		contextVariables: contextVariables,
+		model:            options.Model,
+		maxTurns:         maxTurns,
+		temperature:      options.Temperature,
+		stream:           options.Stream,
+		debug:            options.Debug,
+		out:              os.Stdout,
+	}
+}
+
+// send runs the active agent on a user message and prints the reply.
+func (l *demoLoop) send(ctx context.Context, input string) {
+	if !l.stream {
+		response, err := l.ask(ctx, input)
+		if err != nil {
+			fmt.Fprintf(l.out, "Error in run: %v\n", err)
+			return
+		}
+		prettyPrintMessages(response.Messages)
+		return
+	}
+
+	l.messages = append(l.messages, map[string]interface{}{
+		"role":    "user",
+		"content": input,
+	})
+	responseChan, err := l.swarm.RunAndStream(l.runContext(ctx), l.agent, l.messages, l.contextVariables, l.model, l.debug, l.maxTurns, true, false)
+	if err != nil {
+		fmt.Fprintf(l.out, "Error in stream: %v\n", err)
+		return
+	}
+
+	response := processAndPrintStreamingResponse(responseChan)
+	if response != nil && response.Error == nil {
+		l.messages = response.Conversation(l.messages)
+		l.agent = response.Agent
+	}
+}
+
+// ask runs the active agent on a user message without printing anything.
+// The conversation is only extended if the run succeeds.
+func (l *demoLoop) ask(ctx context.Context, input string) (*Response, error) {
+	history := append(l.messages[:len(l.messages):len(l.messages)], map[string]interface{}{
+		"role":    "user",
+		"content": input,
+	})
+	response, err := l.swarm.Run(l.runContext(ctx), l.agent, history, l.contextVariables, l.model, false, l.debug, l.maxTurns, true, false)
+	if err != nil {
+		return nil, err
+	}
+	l.messages = response.Conversation(history)
+	l.agent = response.Agent
+	return response, nil
+}
+
+// runContext returns ctx carrying the loop's run settings.
+func (l *demoLoop) runContext(ctx context.Context) context.Context {
+	if l.temperature != nil {
+		ctx = withTemperature(ctx, *l.temperature)
+	}
+	return ctx
+}
+
+// runPiped answers a single prompt non-interactively: the prompt, followed by
+// everything read from in (if not nil), is sent to the agent and the final
+// reply is written to out. It returns the process exit code.
+func (l *demoLoop) runPiped(ctx context.Context, prompt string, in io.Reader, out, errOut io.Writer) int {
+	input := prompt
+	if in != nil {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			fmt.Fprintf(errOut, "Error reading input: %v\n", err)
+			return exitFailure
+		}
+		if input != "" && len(data) > 0 {
+			input += "\n\n"
+		}
+		input += string(data)
+	}
+	input = strings.TrimSpace(input)
+	if input == "" {
+		fmt.Fprintln(errOut, "Error: no input")
+		return exitUsage
+	}
+
+	response, err := l.ask(ctx, input)
+	if err != nil {
+		fmt.Fprintf(errOut, "Error: %v\n", err)
+		return exitFailure
+	}
+	fmt.Fprintln(out, lastContent(response))
+	if response.StopReason != StopCompleted {
+		fmt.Fprintf(errOut, "Error: run stopped before completing (%s) %s\n", response.StopReason, response.StopDetail)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// demoCommands describes the slash commands of the demo loop.
+var demoCommands = [][2]string{
+	{"/reset", "clear the conversation and return to the starting agent"},
+	{"/agent <name>", "switch to another agent"},
+	{"/model <name>", "switch to another model, or back to the agent's with default"},
+	{"/temperature <t>", "set the sampling temperature, or reset it with default"},
+	{"/set <key> <value>", "set a context variable (values are parsed as JSON when valid)"},
+	{"/unset <key>", "remove a context variable"},
+	{"/vars", "list the context variables"},
+	{"/save <file>", "save the conversation to a file"},
+	{"/load <file>", "load a conversation saved with /save"},
+	{"/tools", "list the tools of the active agent"},
+	{"/debug on|off", "toggle debug output"},
+	{"/help", "show this help"},
+}
+
+// command executes a slash command.
+func (l *demoLoop) command(line string) error {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	arg := strings.Join(args, " ")
+
+	switch name {
+	case "/reset":
+		l.messages = make([]map[string]interface{}, 0)
+		l.agent = l.startingAgent
+		fmt.Fprintln(l.out, "Conversation cleared.")
+	case "/agent":
+		if arg == "" {
+			fmt.Fprintf(l.out, "Active agent: %s\n", l.agent.Name)
+			return nil
+		}
+		agent, err := l.lookupAgent(arg)
+		if err != nil {
+			return err
+		}
+		l.agent = agent
+		fmt.Fprintf(l.out, "Switched to agent %s.\n", agent.Name)
+	case "/model":
+		switch arg {
+		case "":
+			fmt.Fprintf(l.out, "Model: %s\n", resolveModel(l.agent, l.model))
+		case "default":
+			l.model = ""
+			fmt.Fprintf(l.out, "Using the agent's model %s.\n", l.agent.Model)
+		default:
+			l.model = arg
+			fmt.Fprintf(l.out, "Switched to model %s.\n", arg)
+		}
+	case "/temperature":
+		switch arg {
+		case "":
+			if l.temperature == nil {
+				fmt.Fprintln(l.out, "Temperature: model default")
+			} else {
+				fmt.Fprintf(l.out, "Temperature: %g\n", *l.temperature)
+			}
+		case "default":
+			l.temperature = nil
+			fmt.Fprintln(l.out, "Using the model's default temperature.")
+		default:
+			temperature, err := strconv.ParseFloat(arg, 64)
+			if err != nil || temperature < 0 || temperature > 2 {
+				return fmt.Errorf("invalid temperature %q, expected a number between 0 and 2", arg)
+			}
+			l.temperature = &temperature
+			fmt.Fprintf(l.out, "Temperature set to %g.\n", temperature)
+		}
+	case "/set":
+		if len(args) < 2 {
+			return errors.New("usage: /set <key> <value>")
+		}
+		value := strings.Join(args[1:], " ")
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			parsed = value
+		}
+		l.contextVariables[args[0]] = parsed
+		fmt.Fprintf(l.out, "Set %s.\n", args[0])
+	case "/unset":
+		if arg == "" {
+			return errors.New("usage: /unset <key>")
+		}
+		delete(l.contextVariables, arg)
+		fmt.Fprintf(l.out, "Removed %s.\n", arg)
+	case "/vars":
+		keys := make([]string, 0, len(l.contextVariables))
+		for k := range l.contextVariables {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			value, _ := json.Marshal(l.contextVariables[k])
+			fmt.Fprintf(l.out, "  %s = %s\n", k, value)
+		}
+	case "/save":
+		if arg == "" {
+			return errors.New("usage: /save <file>")
+		}
+		if err := l.saveTranscript(arg); err != nil {
+			return err
+		}
+		fmt.Fprintf(l.out, "Saved %d messages to %s.\n", len(l.messages), arg)
+	case "/load":
+		if arg == "" {
+			return errors.New("usage: /load <file>")
+		}
+		if err := l.loadTranscript(arg); err != nil {
+			return err
+		}
+		fmt.Fprintf(l.out, "Loaded %d messages from %s.\n", len(l.messages), arg)
+	case "/tools":
+		if len(l.agent.Functions) == 0 {
+			fmt.Fprintf(l.out, "Agent %s has no tools.\n", l.agent.Name)
+			return nil
+		}
+		for _, fn := range l.agent.Functions {
+			fmt.Fprintf(l.out, "  %s: %s\n", fn.Name(), fn.Description())
+		}
+	case "/debug":
+		switch arg {
+		case "on":
+			l.debug = true
+		case "off":
+			l.debug = false
+		default:
+			return errors.New("usage: /debug on|off")
+		}
+		fmt.Fprintf(l.out, "Debug %s.\n", arg)
+	case "/help":
+		for _, cmd := range demoCommands {
+			fmt.Fprintf(l.out, "  %-20s %s\n", cmd[0], cmd[1])
+		}
+	default:
+		return fmt.Errorf("unknown command %s, type /help for the list of commands", name)
+	}
+	return nil
+}
+
+// saveTranscript writes the conversation and context variables to path.
+func (l *demoLoop) saveTranscript(path string) error {
+	state := &SessionState{
+		Agent:            l.agent.Name,
+		Messages:         l.messages,
+		ContextVariables: l.contextVariables,
+		UpdatedAt:        time.Now(),
+	}
+	return writeSessionFile(path, state)
+}
+
+// loadTranscript replaces the conversation with one saved by saveTranscript.
+func (l *demoLoop) loadTranscript(path string) error {
+	state, err := readSessionFile(path)
+	if err != nil {
+		return err
+	}
+	agent, err := l.lookupAgent(state.Agent)
+	if err != nil {
+		return err
+	}
+	l.agent, l.messages = agent, state.Messages
+	for k, v := range state.ContextVariables {
+		l.contextVariables[k] = v
+	}
+	return nil
+}
+
+// lookupAgent finds an agent by name among the starting and active agents
+// and the swarm's registry.
+func (l *demoLoop) lookupAgent(name string) (*Agent, error) {
+	for _, agent := range []*Agent{l.agent, l.startingAgent} {
+		if agent != nil && agent.Name == name {
+			return agent, nil
+		}
+	}
+	return l.swarm.Agent(name)
+}
+
+// temperatureContextKey carries a per-run temperature override.
+type temperatureContextKey struct{}
+
+// withTemperature returns ctx carrying a temperature for the runs started
+// with it.
+func withTemperature(ctx context.Context, temperature float64) context.Context {
+	return context.WithValue(ctx, temperatureContextKey{}, temperature)
+}
+
+// temperatureFromContext returns the temperature carried by ctx, if any.
+func temperatureFromContext(ctx context.Context) (float64, bool) {
+	temperature, ok := ctx.Value(temperatureContextKey{}).(float64)
+	return temperature, ok
+}
+
+// maxTokensContextKey carries a per-run completion token limit.
+type maxTokensContextKey struct{}
+
+// withMaxTokens returns ctx carrying a completion token limit for the runs
+// started with it.
+func withMaxTokens(ctx context.Context, maxTokens int) context.Context {
+	return context.WithValue(ctx, maxTokensContextKey{}, maxTokens)
+}
+
+// maxTokensFromContext returns the completion token limit carried by ctx, if
+// any.
+func maxTokensFromContext(ctx context.Context) (int, bool) {
+	maxTokens, ok := ctx.Value(maxTokensContextKey{}).(int)
+	return maxTokens, ok
+}
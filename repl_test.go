@@ -0,0 +1,188 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryFilePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	AssertEqual(t, filepath.Join(home, DefaultHistoryFile), historyFilePath(), "history file")
+}
+
+func newTestDemoLoop(t *testing.T) (*demoLoop, *bytes.Buffer) {
+	t.Helper()
+	billing := NewAgent("billing")
+	support := NewAgent("support").AddFunction(NewAgentFunction("lookup_order", "Look up an order",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "shipped", nil
+		},
+		[]Parameter{},
+	))
+	registry := NewAgentRegistry().MustRegister(support, billing)
+	loop := newDemoLoop(NewSwarm(NewMockOpenAIClient()).WithRegistry(registry), support, ReplOptions{})
+	out := &bytes.Buffer{}
+	loop.out = out
+	return loop, out
+}
+
+func TestDemoLoopCommands(t *testing.T) {
+	loop, out := newTestDemoLoop(t)
+
+	AssertNoError(t, loop.command("/agent billing"), "/agent")
+	AssertEqual(t, "billing", loop.agent.Name, "active agent")
+	AssertError(t, loop.command("/agent nobody"), "/agent with an unknown agent")
+
+	AssertNoError(t, loop.command("/model gpt-4o-mini"), "/model")
+	AssertEqual(t, "gpt-4o-mini", loop.model, "model")
+
+	AssertNoError(t, loop.command("/debug on"), "/debug on")
+	AssertEqual(t, true, loop.debug, "debug")
+	AssertError(t, loop.command("/debug maybe"), "/debug with a bad argument")
+
+	loop.messages = append(loop.messages, map[string]interface{}{"role": "user", "content": "Hi"})
+	AssertNoError(t, loop.command("/reset"), "/reset")
+	AssertEqual(t, 0, len(loop.messages), "messages after reset")
+	AssertEqual(t, "support", loop.agent.Name, "agent after reset")
+
+	out.Reset()
+	AssertNoError(t, loop.command("/tools"), "/tools")
+	AssertEqual(t, "  lookup_order: Look up an order\n", out.String(), "tools output")
+
+	AssertError(t, loop.command("/unknown"), "unknown command")
+}
+
+func TestDemoLoopSaveLoad(t *testing.T) {
+	loop, _ := newTestDemoLoop(t)
+	path := filepath.Join(t.TempDir(), "chat.json")
+	billing, _ := loop.swarm.Agent("billing")
+	loop.agent = billing
+	loop.messages = []map[string]interface{}{
+		{"role": "user", "content": "Refund please"},
+		{"role": "assistant", "content": "Done", "sender": "billing"},
+	}
+	AssertNoError(t, loop.command("/save "+path), "/save")
+
+	restored, _ := newTestDemoLoop(t)
+	AssertNoError(t, restored.command("/load "+path), "/load")
+	AssertEqual(t, "billing", restored.agent.Name, "restored agent")
+	AssertEqual(t, 2, len(restored.messages), "restored messages")
+	AssertEqual(t, "Done", restored.messages[1]["content"], "restored reply")
+
+	AssertError(t, restored.command("/load"), "/load without a file")
+}
+
+func TestMultilineInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{name: "single line", lines: []string{"hello"}, want: "hello"},
+		{name: "backslash continuation", lines: []string{`first \`, `second \`, "third"}, want: "first \nsecond \nthird"},
+		{name: "triple quotes", lines: []string{`"""`, "func main() {", `	fmt.Println("hi")`, "}", `"""`}, want: "func main() {\n\tfmt.Println(\"hi\")\n}\n"},
+		{name: "text after opening quotes", lines: []string{`"""Review this:`, `x := 1"""`}, want: "Review this:\nx := 1"},
+		{name: "quoted single line", lines: []string{`"""one line"""`}, want: "one line"},
+		{name: "backslash inside quotes", lines: []string{`"""`, `a \`, `"""`}, want: "a \\\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input multilineInput
+			for i, line := range tt.lines {
+				got, ok := input.add(line)
+				if i < len(tt.lines)-1 {
+					AssertEqual(t, false, ok, "incomplete input")
+					AssertEqual(t, true, input.pending(), "pending input")
+					continue
+				}
+				AssertEqual(t, true, ok, "complete input")
+				AssertEqual(t, tt.want, got, "input")
+				AssertEqual(t, false, input.pending(), "pending after completion")
+			}
+		})
+	}
+}
+
+func TestDemoLoopTranscript(t *testing.T) {
+	loop, _ := newTestDemoLoop(t)
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	AssertEqual(t, true, errors.Is(loop.loadTranscript(path), ErrSessionNotFound), "missing transcript")
+
+	loop.contextVariables = map[string]interface{}{"user": "ann"}
+	loop.messages = []map[string]interface{}{{"role": "user", "content": "Hi"}}
+	AssertNoError(t, loop.saveTranscript(path), "save transcript")
+
+	restored, _ := newTestDemoLoop(t)
+	AssertNoError(t, restored.loadTranscript(path), "load transcript")
+	AssertEqual(t, 1, len(restored.messages), "restored messages")
+	AssertEqual(t, "ann", restored.contextVariables["user"], "restored context variables")
+}
+
+func TestDemoLoopSettings(t *testing.T) {
+	loop, out := newTestDemoLoop(t)
+	AssertEqual(t, "", loop.model, "agent model by default")
+	AssertEqual(t, DefaultReplMaxTurns, loop.maxTurns, "default max turns")
+
+	AssertNoError(t, loop.command("/model o3"), "/model")
+	AssertNoError(t, loop.command("/model default"), "/model default")
+	AssertEqual(t, "", loop.model, "model after reset")
+
+	AssertNoError(t, loop.command("/temperature 0.2"), "/temperature")
+	AssertEqual(t, 0.2, *loop.temperature, "temperature")
+	AssertError(t, loop.command("/temperature hot"), "/temperature with a bad value")
+	AssertNoError(t, loop.command("/temperature default"), "/temperature default")
+	AssertEqual(t, true, loop.temperature == nil, "temperature after reset")
+
+	AssertNoError(t, loop.command("/set limit 3"), "/set number")
+	AssertNoError(t, loop.command("/set user Ann Lee"), "/set string")
+	AssertEqual(t, float64(3), loop.contextVariables["limit"], "parsed number")
+	AssertEqual(t, "Ann Lee", loop.contextVariables["user"], "string value")
+	out.Reset()
+	AssertNoError(t, loop.command("/vars"), "/vars")
+	AssertEqual(t, "  limit = 3\n  user = \"Ann Lee\"\n", out.String(), "vars output")
+	AssertNoError(t, loop.command("/unset limit"), "/unset")
+	AssertEqual(t, 1, len(loop.contextVariables), "variables after unset")
+}
+
+func TestTemperatureOverride(t *testing.T) {
+	swarm := NewSwarm(NewMockOpenAIClient())
+	history := []map[string]interface{}{{"role": "user", "content": "Hi"}}
+	ctx := withTemperature(context.Background(), 0.2)
+
+	params, err := swarm.buildChatParams(ctx, NewAgent("assistant").WithModel("gpt-4o"), history, nil, "", false)
+	AssertNoError(t, err, "buildChatParams")
+	AssertEqual(t, 0.2, params.Temperature.Value, "temperature")
+
+	params, err = swarm.buildChatParams(ctx, NewAgent("assistant").WithModel("o3"), history, nil, "", false)
+	AssertNoError(t, err, "buildChatParams for a reasoning model")
+	AssertEqual(t, false, params.Temperature.IsPresent(), "no temperature for reasoning models")
+
+	params, err = swarm.buildChatParams(context.Background(), NewAgent("assistant"), history, nil, "", false)
+	AssertNoError(t, err, "buildChatParams without override")
+	AssertEqual(t, false, params.Temperature.IsPresent(), "no temperature by default")
+}
+
+func TestDemoLoopRunPiped(t *testing.T) {
+	client := newScriptedClient("A short summary")
+	loop := newDemoLoop(NewSwarm(client), NewAgent("assistant"), ReplOptions{})
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+
+	code := loop.runPiped(context.Background(), "Summarize:", strings.NewReader("line one\nline two\n"), out, errOut)
+	AssertEqual(t, exitOK, code, "exit code")
+	AssertEqual(t, "A short summary\n", out.String(), "stdout")
+	AssertEqual(t, "", errOut.String(), "stderr")
+	AssertEqual(t, "Summarize:\n\nline one\nline two", client.requests[0].Messages[1].OfUser.Content.OfString.Value, "prompt and piped input")
+
+	AssertEqual(t, exitUsage, loop.runPiped(context.Background(), "", strings.NewReader("  \n"), out, errOut), "exit code without input")
+
+	client.Error = errors.New("service unavailable")
+	errOut.Reset()
+	AssertEqual(t, exitFailure, loop.runPiped(context.Background(), "Again", nil, out, errOut), "exit code on failure")
+	AssertEqual(t, "Error: service unavailable\n", errOut.String(), "error output")
+}
@@ -0,0 +1,175 @@
+package swarm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+// volatileParams are request fields that do not affect the completion and are
+// excluded from response cache keys.
+var volatileParams = []string{"user", "metadata", "store", "stream", "stream_options"}
+
+// CachingClient is an OpenAIClient decorator that serves exact-match repeated
+// requests from a StepCache instead of calling the model. Only deterministic
+// requests (temperature 0, a single choice) and embeddings are cached;
+// streaming requests always pass through.
+type CachingClient struct {
+	client OpenAIClient
+	store  StepCache
+	ttl    time.Duration
+	prefix string
+	unset  bool
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCachingClient wraps client with a response cache backed by store (e.g.
+// NewMemoryStepCache, NewFileStepCache or NewRedisStepCache). Entries expire
+// after ttl; a ttl of zero or less keeps them forever.
+func NewCachingClient(client OpenAIClient, store StepCache, ttl time.Duration) *CachingClient {
+	return &CachingClient{client: client, store: store, ttl: ttl, prefix: "llm:"}
+}
+
+// WithKeyPrefix sets the prefix of cache keys, e.g. to separate test suites
+// sharing a store, and returns the client.
+func (c *CachingClient) WithKeyPrefix(prefix string) *CachingClient {
+	c.prefix = prefix
+	return c
+}
+
+// WithUnsetTemperature also caches requests that leave the temperature to the
+// model's default, e.g. to replay recorded conversations in tests, and returns
+// the client. Their keys differ from those of requests at temperature 0.
+func (c *CachingClient) WithUnsetTemperature() *CachingClient {
+	c.unset = true
+	return c
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *CachingClient) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// CreateChatCompletion returns a cached completion for an identical earlier
+// request, or calls the wrapped client and caches its response.
+func (c *CachingClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	// Hosted tools such as web search return fresh results on every call
+	if !cacheableParams(params, c.unset) || len(hostedToolsFromContext(ctx)) > 0 {
+		return c.client.CreateChatCompletion(ctx, params)
+	}
+	key, err := ResponseCacheKey(params)
+	if err != nil {
+		return c.client.CreateChatCompletion(ctx, params)
+	}
+	key = c.prefix + key
+
+	if data, ok, err := c.store.Get(key); err == nil && ok {
+		var completion openai.ChatCompletion
+		if err := json.Unmarshal(data, &completion); err == nil {
+			c.hits.Add(1)
+			return &completion, nil
+		}
+	}
+	c.misses.Add(1)
+
+	completion, err := c.client.CreateChatCompletion(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(completion); err == nil {
+		// A failed cache write must not fail the request
+		_ = c.store.Set(key, data, c.ttl)
+	}
+	return completion, nil
+}
+
+// CreateChatCompletionStream forwards streaming requests without caching.
+func (c *CachingClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	return c.client.CreateChatCompletionStream(ctx, params)
+}
+
+// Embeddings returns cached vectors for an identical earlier request, or calls
+// the wrapped client and caches its response. Embeddings are deterministic, so
+// every request is cacheable.
+func (c *CachingClient) Embeddings(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	data, err := json.Marshal(map[string]interface{}{"model": model, "input": inputs})
+	if err != nil {
+		return c.client.Embeddings(ctx, model, inputs)
+	}
+	sum := sha256.Sum256(data)
+	key := c.prefix + "embeddings:" + hex.EncodeToString(sum[:])
+
+	if data, ok, err := c.store.Get(key); err == nil && ok {
+		var vectors [][]float64
+		if err := json.Unmarshal(data, &vectors); err == nil {
+			c.hits.Add(1)
+			return vectors, nil
+		}
+	}
+	c.misses.Add(1)
+
+	vectors, err := c.client.Embeddings(ctx, model, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(vectors); err == nil {
+		_ = c.store.Set(key, data, c.ttl)
+	}
+	return vectors, nil
+}
+
+// cacheableParams reports whether a request is deterministic enough to cache:
+// its temperature is 0, or unset if unset is true.
+func cacheableParams(params openai.ChatCompletionNewParams, unset bool) bool {
+	if params.Temperature.IsPresent() {
+		if params.Temperature.Or(1) != 0 {
+			return false
+		}
+	} else if !unset {
+		return false
+	}
+	return !params.N.IsPresent() || params.N.Or(1) == 1
+}
+
+// ResponseCacheKey returns the normalized cache key of a request: a hash of
+// its canonical JSON (model, messages, tools and sampling parameters), ignoring
+// fields that do not affect the response such as user and metadata.
+func ResponseCacheKey(params openai.ChatCompletionNewParams) (string, error) {
+	canonical, err := canonicalRequest(params)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalRequest returns the canonical JSON of a request without the
+// fields that do not affect the response.
+func canonicalRequest(params openai.ChatCompletionNewParams) ([]byte, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to normalize request: %w", err)
+	}
+	for _, field := range volatileParams {
+		delete(normalized, field)
+	}
+	// Maps marshal with sorted keys, giving a canonical encoding
+	canonical, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize request: %w", err)
+	}
+	return canonical, nil
+}
@@ -0,0 +1,119 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+func TestCachingClient(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	mockClient.SetCompletionResponse(&openai.ChatCompletion{
+		ID: "first",
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Hi", Role: "assistant"}},
+		},
+	})
+	mockClient.SetCompletionResponse(&openai.ChatCompletion{
+		ID: "second",
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Hello again", Role: "assistant"}},
+		},
+	})
+	client := NewCachingClient(mockClient, NewMemoryStepCache(), time.Minute)
+
+	params := openai.ChatCompletionNewParams{
+		Model:       openai.ChatModel("gpt-4o"),
+		Messages:    []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+		Temperature: openai.Float(0),
+	}
+	first, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "first request")
+
+	// Volatile fields do not change the key
+	params.User = openai.String("someone")
+	cached, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "cached request")
+	AssertEqual(t, first.ID, cached.ID, "Repeated request should be served from cache")
+	AssertEqual(t, "Hi", cached.Choices[0].Message.Content, "Cached content")
+	AssertEqual(t, 1, mockClient.CompletionIter, "Model should be called once")
+
+	hits, misses := client.Stats()
+	AssertEqual(t, int64(1), hits, "hits")
+	AssertEqual(t, int64(1), misses, "misses")
+
+	// Non-deterministic requests bypass the cache
+	params.Temperature = openai.Float(0.7)
+	fresh, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "sampled request")
+	AssertEqual(t, "second", fresh.ID, "Sampled request should reach the model")
+}
+
+func TestCachingClientUnsetTemperature(t *testing.T) {
+	mockClient := NewMockOpenAIClient()
+	for _, id := range []string{"first", "second", "third"} {
+		mockClient.SetCompletionResponse(&openai.ChatCompletion{
+			ID: id,
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Content: id, Role: "assistant"}},
+			},
+		})
+	}
+	store := NewMemoryStepCache()
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel("gpt-4o"),
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+	}
+
+	// The model's default temperature may sample, so it is not cached
+	client := NewCachingClient(mockClient, store, time.Minute)
+	_, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "unset temperature")
+	_, misses := client.Stats()
+	AssertEqual(t, int64(0), misses, "unset temperature bypasses the cache")
+
+	client = NewCachingClient(mockClient, store, time.Minute).WithUnsetTemperature()
+	first, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "opted in")
+	params.Temperature = openai.Float(0)
+	zero, err := client.CreateChatCompletion(context.Background(), params)
+	AssertNoError(t, err, "zero temperature")
+	AssertEqual(t, "second", first.ID, "unset temperature cached")
+	AssertEqual(t, "third", zero.ID, "zero temperature has its own key")
+	AssertEqual(t, 3, mockClient.CompletionIter, "model calls")
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	base := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel("gpt-4o"),
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+	}
+	key, err := ResponseCacheKey(base)
+	AssertNoError(t, err, "ResponseCacheKey")
+
+	other := base
+	other.Model = openai.ChatModel("gpt-4o-mini")
+	otherKey, err := ResponseCacheKey(other)
+	AssertNoError(t, err, "ResponseCacheKey")
+	if key == otherKey {
+		t.Error("Different models should produce different keys")
+	}
+
+	other = base
+	other.Temperature = openai.Float(0)
+	otherKey, err = ResponseCacheKey(other)
+	AssertNoError(t, err, "ResponseCacheKey")
+	if key == otherKey {
+		t.Error("Unset and zero temperatures should produce different keys")
+	}
+
+	other = base
+	other.Messages = []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Bye")}
+	otherKey, err = ResponseCacheKey(other)
+	AssertNoError(t, err, "ResponseCacheKey")
+	if key == otherKey {
+		t.Error("Different messages should produce different keys")
+	}
+}
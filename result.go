@@ -0,0 +1,160 @@
+package swarm
+
+import (
+	"sync"
+	"time"
+)
+
+// StepTrace records one attempt of a step in a workflow run.
+type StepTrace struct {
+	// Step is the name of the step
+	Step string `json:"step"`
+	// TaskID is the ID of the parallel task the step ran for, if any
+	TaskID string `json:"task_id,omitempty"`
+	// EventType is the type of the handled event
+	EventType EventType `json:"event_type"`
+	// EventID is the ID of the handled event
+	EventID string `json:"event_id,omitempty"`
+	// Attempt is the attempt number, starting at 1
+	Attempt int `json:"attempt"`
+	// StartedAt is when the attempt started
+	StartedAt time.Time `json:"started_at"`
+	// Duration is how long the attempt took
+	Duration time.Duration `json:"duration"`
+	// Output is the type of the returned event, if any
+	Output EventType `json:"output,omitempty"`
+	// Error is the error returned by the attempt, if any
+	Error string `json:"error,omitempty"`
+}
+
+// WorkflowResult is the outcome of a workflow run returned by
+// WorkflowHandler.Wait.
+type WorkflowResult struct {
+	// RunID is the ID of the run
+	RunID string `json:"run_id"`
+	// Value is the result of the StopEvent that completed the run
+	Value interface{} `json:"value,omitempty"`
+	// Status is the status of the run
+	Status WorkflowStatus `json:"status"`
+	// Steps are the step attempts of the run, in the order they finished
+	Steps []StepTrace `json:"steps"`
+	// Retries counts the attempts after the first of each step
+	Retries int `json:"retries"`
+	// Usage totals the requests of the agent runs made with the run's
+	// Context, including those of parallel tasks, and the usage reported by
+	// Context.RecordUsage
+	Usage Usage `json:"usage"`
+	// TokensUsed and Cost are the totals of Usage
+	TokensUsed int     `json:"tokens_used"`
+	Cost       float64 `json:"cost"`
+	// Tasks reports the concurrency of the parallel tasks of the run
+	Tasks TaskConcurrency `json:"tasks"`
+	// Event is the event that terminated the run: the StopEvent or the
+	// unhandled ErrorEvent, or nil if the run was cancelled or stalled
+	Event Event `json:"-"`
+	// StartedAt is when the run started
+	StartedAt time.Time `json:"started_at"`
+	// Duration is how long the run took, or has taken so far
+	Duration time.Duration `json:"duration"`
+}
+
+// TaskConcurrency reports the parallel tasks of a workflow run.
+type TaskConcurrency struct {
+	// Running is the number of tasks running now
+	Running int `json:"running"`
+	// Peak is the largest number of tasks that ran at once
+	Peak int `json:"peak"`
+	// Limit is the concurrency limit of the latest ParallelEvent
+	Limit int `json:"limit"`
+}
+
+// runRecorder collects the step traces and usage of a run. It is shared by
+// a Context and its children.
+type runRecorder struct {
+	startedAt  time.Time
+	finishedAt time.Time
+	steps      []StepTrace
+	usage      *UsageTracker
+	tasks      TaskConcurrency
+	mu         sync.Mutex
+}
+
+// newRunRecorder creates a recorder for a run starting now.
+func newRunRecorder() *runRecorder {
+	return &runRecorder{startedAt: time.Now(), usage: NewUsageTracker()}
+}
+
+// finish records that the run finished now, unless it already did.
+func (r *runRecorder) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.finishedAt.IsZero() {
+		r.finishedAt = time.Now()
+	}
+}
+
+// setTaskLimit records the task limit of the latest ParallelEvent.
+func (r *runRecorder) setTaskLimit(limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks.Limit = limit
+}
+
+// taskStarted records that a parallel task acquired a slot.
+func (r *runRecorder) taskStarted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks.Running++
+	r.tasks.Peak = max(r.tasks.Peak, r.tasks.Running)
+}
+
+// taskFinished records that a parallel task released its slot.
+func (r *runRecorder) taskFinished() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks.Running--
+}
+
+// taskConcurrency returns the parallel task concurrency of the run.
+func (r *runRecorder) taskConcurrency() TaskConcurrency {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tasks
+}
+
+// addStep records a step attempt.
+func (r *runRecorder) addStep(trace StepTrace) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, trace)
+}
+
+// fill copies the recorded traces and usage into result.
+func (r *runRecorder) fill(result *WorkflowResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result.StartedAt = r.startedAt
+	if r.finishedAt.IsZero() {
+		result.Duration = time.Since(r.startedAt)
+	} else {
+		result.Duration = r.finishedAt.Sub(r.startedAt)
+	}
+	result.Steps = append([]StepTrace(nil), r.steps...)
+	result.Usage = r.usage.Usage()
+	result.TokensUsed = result.Usage.TotalTokens
+	result.Cost = result.Usage.Cost
+	result.Tasks = r.tasks
+	for _, step := range r.steps {
+		if step.Attempt > 1 {
+			result.Retries++
+		}
+	}
+}
+
+// RecordUsage adds the token usage and cost of work done by a step to the
+// totals of the WorkflowResult. Agent runs made with the Context's
+// context.Context are added automatically; use it for other work, such as
+// requests made without a Swarm.
+func (c *Context) RecordUsage(tokens int, cost float64) {
+	c.recorder.usage.Add(Usage{TotalTokens: tokens, Cost: cost})
+}
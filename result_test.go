@@ -0,0 +1,77 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWorkflowResult(t *testing.T) {
+	retry := &RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	attempts := 0
+	workflow := NewWorkflow("result")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, fmt.Errorf("flaky")
+		}
+		ctx.RecordUsage(10, 0.5)
+		return NewBaseEvent(EventType("Next"), nil), nil
+	}, StepConfig{RetryPolicy: retry}))
+	workflow.AddStep(NewStep("next", EventType("Next"), func(ctx *Context, event Event) (Event, error) {
+		ctx.RecordUsage(5, 0.25)
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	result, err := handler.Wait()
+	AssertNoError(t, err, "wait")
+	AssertEqual(t, "done", result.Value, "value")
+	AssertEqual(t, WorkflowStatusComplete, result.Status, "status")
+	AssertEqual(t, handler.Context().RunID(), result.RunID, "run ID")
+	AssertEqual(t, 3, len(result.Steps), "step traces")
+	AssertEqual(t, 1, result.Retries, "retries")
+	AssertEqual(t, 15, result.TokensUsed, "tokens")
+	AssertEqual(t, 0.75, result.Cost, "cost")
+	AssertEqual(t, EventStop, result.Event.Type(), "terminating event")
+
+	first, retried, next := result.Steps[0], result.Steps[1], result.Steps[2]
+	AssertEqual(t, "start", first.Step, "first step")
+	AssertEqual(t, "flaky", first.Error, "first error")
+	AssertEqual(t, 2, retried.Attempt, "retried attempt")
+	AssertEqual(t, EventType("Next"), retried.Output, "retried output")
+	AssertEqual(t, EventType("Next"), next.EventType, "next event type")
+	AssertEqual(t, EventStop, next.Output, "next output")
+	if result.Duration <= 0 || result.StartedAt.After(first.StartedAt) {
+		t.Errorf("Expected the run to span its steps, got %v from %v", result.Duration, result.StartedAt)
+	}
+
+	value, err := handler.WaitValue()
+	AssertNoError(t, err, "WaitValue")
+	AssertEqual(t, "done", value, "raw result")
+}
+
+func TestWorkflowResultFailed(t *testing.T) {
+	noRetry := &RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	workflow := NewWorkflow("result-failed")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		return nil, fmt.Errorf("boom")
+	}, StepConfig{RetryPolicy: noRetry}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	result, err := handler.Wait()
+	AssertError(t, err, "Expected the run to fail")
+	AssertEqual(t, WorkflowStatusFailed, result.Status, "status")
+	AssertEqual(t, 1, len(result.Steps), "step traces")
+	AssertEqual(t, "boom", result.Steps[0].Error, "step error")
+	errorEvent, ok := result.Event.(*ErrorEvent)
+	AssertEqual(t, true, ok, "terminating event is an ErrorEvent")
+	AssertEqual(t, "start", errorEvent.StepName, "failed step")
+
+	value, err := handler.WaitValue()
+	AssertError(t, err, "WaitValue error")
+	AssertEqual(t, nil, value, "raw result")
+}
@@ -0,0 +1,227 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimeWindow is a daily time range during which deferred work may run.
+// Start and End are offsets from midnight; a window whose End is before its
+// Start wraps around midnight (e.g. 22:00-06:00).
+type TimeWindow struct {
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+// NewTimeWindow creates a window from "HH:MM" start and end strings.
+func NewTimeWindow(start, end string, loc *time.Location) (TimeWindow, error) {
+	parse := func(s string) (time.Duration, error) {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window time %q: %w", s, err)
+		}
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+	s, err := parse(start)
+	if err != nil {
+		return TimeWindow{}, err
+	}
+	e, err := parse(end)
+	if err != nil {
+		return TimeWindow{}, err
+	}
+	return TimeWindow{Start: s, End: e, Location: loc}, nil
+}
+
+// Contains reports whether t falls inside the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Next returns the next time at or after t when the window opens.
+func (w TimeWindow) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	next := midnight.Add(w.Start)
+	if !next.After(t) {
+		next = midnight.AddDate(0, 0, 1).Add(w.Start)
+	}
+	return next
+}
+
+// HeadroomFunc reports whether there is enough provider quota to start deferred work.
+type HeadroomFunc func(ctx context.Context) bool
+
+// SchedulerConfig configures deferral of low-priority work.
+type SchedulerConfig struct {
+	// Windows are the daily time windows in which deferred work may run. Empty means any time.
+	Windows []TimeWindow
+	// Headroom optionally gates deferred work on available quota.
+	Headroom HeadroomFunc
+	// DeferBelow defers runs and tasks whose priority is lower than this value.
+	DeferBelow int
+	// PollInterval controls how often quota headroom is re-checked (default 1 minute).
+	PollInterval time.Duration
+}
+
+// QueuedRun describes a workflow run or task waiting for its scheduling window.
+type QueuedRun struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	Priority   int       `json:"priority"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// Scheduler defers low-priority workflow runs and tasks to configured time
+// windows or until quota headroom exists. High-priority work is admitted
+// immediately.
+//
+// The Scheduler is safe for concurrent use by multiple goroutines.
+type Scheduler struct {
+	config SchedulerConfig
+	queue  map[string]QueuedRun
+	nextID int
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// NewScheduler creates a new Scheduler with the given configuration.
+func NewScheduler(config SchedulerConfig) *Scheduler {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Minute
+	}
+	return &Scheduler{
+		config: config,
+		queue:  make(map[string]QueuedRun),
+		now:    time.Now,
+	}
+}
+
+// Ready reports whether deferred work may run right now.
+func (s *Scheduler) Ready(ctx context.Context) bool {
+	return s.inWindow(s.now()) && (s.config.Headroom == nil || s.config.Headroom(ctx))
+}
+
+// inWindow reports whether t is inside any configured window.
+func (s *Scheduler) inWindow(t time.Time) bool {
+	if len(s.config.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.config.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextCheck returns how long to sleep before re-evaluating readiness.
+func (s *Scheduler) nextCheck(t time.Time) time.Duration {
+	if s.inWindow(t) {
+		return s.config.PollInterval
+	}
+	wait := time.Duration(-1)
+	for _, w := range s.config.Windows {
+		if d := w.Next(t).Sub(t); wait < 0 || d < wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		return s.config.PollInterval
+	}
+	return wait
+}
+
+// Admit blocks until work with the given priority may run. Work at or above
+// DeferBelow is admitted immediately; other work is listed in Queue() while waiting.
+// Returns the context error if ctx is done first.
+func (s *Scheduler) Admit(ctx context.Context, kind, name string, priority int) error {
+	if priority >= s.config.DeferBelow || s.Ready(ctx) {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("%s-%d", kind, s.nextID)
+	s.queue[id] = QueuedRun{
+		ID:         id,
+		Kind:       kind,
+		Name:       name,
+		Priority:   priority,
+		EnqueuedAt: s.now(),
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.queue, id)
+		s.mu.Unlock()
+	}()
+
+	for {
+		timer := time.NewTimer(s.nextCheck(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if s.Ready(ctx) {
+			return nil
+		}
+	}
+}
+
+// Queue returns the runs and tasks currently waiting, oldest first.
+func (s *Scheduler) Queue() []QueuedRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := make([]QueuedRun, 0, len(s.queue))
+	for _, r := range s.queue {
+		runs = append(runs, r)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].EnqueuedAt.Before(runs[j].EnqueuedAt)
+	})
+	return runs
+}
+
+// Submit starts the workflow once its priority is admitted. It returns
+// immediately; the returned channel yields the WorkflowHandler (or an error)
+// when the run actually starts.
+func (s *Scheduler) Submit(ctx context.Context, w *Workflow, inputs map[string]interface{}, priority int) <-chan ScheduledResult {
+	ch := make(chan ScheduledResult, 1)
+	go func() {
+		defer close(ch)
+		if err := s.Admit(ctx, "run", w.config.Name, priority); err != nil {
+			ch <- ScheduledResult{Err: err}
+			return
+		}
+		handler, err := w.Run(ctx, inputs)
+		ch <- ScheduledResult{Handler: handler, Err: err}
+	}()
+	return ch
+}
+
+// ScheduledResult is delivered when a scheduled workflow run starts.
+type ScheduledResult struct {
+	Handler *WorkflowHandler
+	Err     error
+}
@@ -0,0 +1,106 @@
+package swarm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	night, err := NewTimeWindow("22:00", "06:00", time.UTC)
+	if err != nil {
+		t.Fatalf("NewTimeWindow failed: %v", err)
+	}
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	AssertEqual(t, true, night.Contains(at(23, 0)), "23:00 in night window")
+	AssertEqual(t, true, night.Contains(at(5, 59)), "05:59 in night window")
+	AssertEqual(t, false, night.Contains(at(12, 0)), "12:00 outside night window")
+	AssertEqual(t, at(22, 0), night.Next(at(12, 0)), "next window start")
+
+	if _, err := NewTimeWindow("25:00", "06:00", nil); err == nil {
+		t.Error("Expected error for invalid window time")
+	}
+}
+
+func TestSchedulerAdmit(t *testing.T) {
+	var headroom atomic.Bool
+	scheduler := NewScheduler(SchedulerConfig{
+		DeferBelow:   1,
+		PollInterval: 5 * time.Millisecond,
+		Headroom: func(ctx context.Context) bool {
+			return headroom.Load()
+		},
+	})
+
+	// High-priority work is admitted immediately.
+	AssertNoError(t, scheduler.Admit(context.Background(), "task", "urgent", 1), "Admit urgent")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- scheduler.Admit(context.Background(), "task", "batch", 0)
+	}()
+
+	deadline := time.After(time.Second)
+	for len(scheduler.Queue()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("Timed out waiting for deferred task to be queued")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	AssertEqual(t, "batch", scheduler.Queue()[0].Name, "queued task name")
+
+	headroom.Store(true)
+	select {
+	case err := <-done:
+		AssertNoError(t, err, "Admit batch")
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for deferred task to be admitted")
+	}
+	AssertEqual(t, 0, len(scheduler.Queue()), "queue should be empty")
+}
+
+func TestSchedulerAdmitsTaskAfterTimeout(t *testing.T) {
+	var headroom atomic.Bool
+	scheduler := NewScheduler(SchedulerConfig{
+		DeferBelow:   1,
+		PollInterval: 5 * time.Millisecond,
+		Headroom: func(ctx context.Context) bool {
+			return headroom.Load()
+		},
+	})
+	config := DefaultConfig()
+	config.Timeout = 100 * time.Millisecond
+	config.Scheduler = scheduler
+
+	workflow := NewWorkflow("deferred").WithConfig(config)
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		return NewParallelEvent([]Task{{ID: "batch", Type: EventType("Batch"), Timeout: 100 * time.Millisecond}}, "start")
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("batch", EventType("Batch"), func(ctx *Context, event Event) (Event, error) {
+		return NewBaseEvent(EventType("Done"), nil), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("collect", EventParallelResult, func(ctx *Context, event Event) (Event, error) {
+		result := event.(*ParallelResultEvent)
+		if err := result.Errors["batch"]; err != nil {
+			return nil, err
+		}
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "Run")
+	// The window opens after both the workflow and the task timeouts passed
+	time.Sleep(3 * config.Timeout)
+	AssertEqual(t, 1, len(scheduler.Queue()), "task deferred")
+	headroom.Store(true)
+
+	result, err := handler.Wait()
+	AssertNoError(t, err, "Wait")
+	AssertEqual(t, "done", result.Value, "result")
+}
@@ -0,0 +1,280 @@
+package swarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Schema is a JSON Schema document supporting the commonly used subset of the
+// specification: type, properties, required, items, enum, additionalProperties,
+// numeric and length bounds and string patterns.
+type Schema struct {
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+}
+
+// ParseSchema parses a JSON Schema document.
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// SchemaValidationError lists every violation found while validating a value.
+type SchemaValidationError struct {
+	Errors []string
+}
+
+// Error implements the error interface.
+func (e *SchemaValidationError) Error() string {
+	return "schema validation failed: " + strings.Join(e.Errors, "; ")
+}
+
+// Validate checks value against the schema. Go values are normalized through
+// JSON first, so structs and typed maps are validated by their JSON form.
+// Returns a *SchemaValidationError describing all violations.
+func (s *Schema) Validate(value interface{}) error {
+	normalized, err := normalizeJSON(value)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	s.validate("$", normalized, &errs)
+	if len(errs) > 0 {
+		return &SchemaValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// ValidateJSON parses data as JSON and validates it against the schema.
+func (s *Schema) ValidateJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &SchemaValidationError{Errors: []string{fmt.Sprintf("$: invalid JSON: %v", err)}}
+	}
+	return s.Validate(value)
+}
+
+// String returns the schema as a JSON document.
+func (s *Schema) String() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// normalizeJSON converts a Go value into its generic JSON representation.
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal failed: %w", err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %w", err)
+	}
+	return normalized, nil
+}
+
+// jsonTypeOf returns the JSON Schema type name of a normalized value.
+func jsonTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// validate appends violations found at path to errs.
+func (s *Schema) validate(path string, value interface{}, errs *[]string) {
+	if s == nil {
+		return
+	}
+
+	if s.Type != "" {
+		actual := jsonTypeOf(value)
+		ok := actual == s.Type || (s.Type == "number" && actual == "integer")
+		if !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, s.Type, actual))
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, candidate := range s.Enum {
+			if normalized, err := normalizeJSON(candidate); err == nil && reflect.DeepEqual(normalized, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*errs = append(*errs, fmt.Sprintf("%s: value %v is not one of %v", path, value, s.Enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			*errs = append(*errs, fmt.Sprintf("%s: %v is less than minimum %v", path, v, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			*errs = append(*errs, fmt.Sprintf("%s: %v is greater than maximum %v", path, v, *s.Maximum))
+		}
+	case string:
+		// JSON Schema lengths count characters, not bytes
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			*errs = append(*errs, fmt.Sprintf("%s: length %d is less than minLength %d", path, length, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			*errs = append(*errs, fmt.Sprintf("%s: length %d is greater than maxLength %d", path, length, *s.MaxLength))
+		}
+		if s.Pattern != "" {
+			re, err := compilePattern(s.Pattern)
+			if err != nil {
+				*errs = append(*errs, fmt.Sprintf("%s: invalid pattern %q: %v", path, s.Pattern, err))
+			} else if !re.MatchString(v) {
+				*errs = append(*errs, fmt.Sprintf("%s: %q does not match pattern %q", path, v, s.Pattern))
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			*errs = append(*errs, fmt.Sprintf("%s: %d items is less than minItems %d", path, len(v), *s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			*errs = append(*errs, fmt.Sprintf("%s: %d items is greater than maxItems %d", path, len(v), *s.MaxItems))
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, fmt.Sprintf("%s: unexpected property %q", path, k))
+				}
+				continue
+			}
+			prop.validate(path+"."+k, v[k], errs)
+		}
+	}
+}
+
+var (
+	patternCache   = make(map[string]*regexp.Regexp)
+	patternCacheMu sync.Mutex
+)
+
+// compilePattern compiles and caches schema regular expressions.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCacheMu.Lock()
+	defer patternCacheMu.Unlock()
+	if re, ok := patternCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache[pattern] = re
+	return re, nil
+}
+
+var (
+	eventSchemas   = make(map[EventType]*Schema)
+	eventSchemasMu sync.RWMutex
+)
+
+// RegisterEventSchema registers a JSON schema for an event type. Every event of
+// that type sent through Context.SendEvent is validated against it, and
+// malformed events are rejected with a descriptive error. Passing a nil schema
+// removes the registration.
+func RegisterEventSchema(eventType EventType, schema *Schema) {
+	eventSchemasMu.Lock()
+	defer eventSchemasMu.Unlock()
+	if schema == nil {
+		delete(eventSchemas, eventType)
+		return
+	}
+	eventSchemas[eventType] = schema
+}
+
+// EventSchema returns the schema registered for an event type, if any.
+func EventSchema(eventType EventType) (*Schema, bool) {
+	eventSchemasMu.RLock()
+	defer eventSchemasMu.RUnlock()
+	schema, ok := eventSchemas[eventType]
+	return schema, ok
+}
+
+// validateEventSchema validates the event payload against its registered schema.
+// The payload combines the exported fields of typed events with Data().
+func validateEventSchema(event Event) error {
+	schema, ok := EventSchema(event.Type())
+	if !ok {
+		return nil
+	}
+
+	payload := make(map[string]interface{})
+	if _, isBase := event.(*BaseEvent); !isBase {
+		if fields, err := ToMap(event); err == nil {
+			payload = fields
+		}
+	}
+	for k, v := range event.Data() {
+		payload[k] = v
+	}
+
+	if err := schema.Validate(payload); err != nil {
+		return fmt.Errorf("%s payload: %w", event.Type(), err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["topic", "chapters"],
+		"additionalProperties": false,
+		"properties": {
+			"topic": {"type": "string", "minLength": 1},
+			"chapters": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+			"rating": {"type": "integer", "minimum": 1, "maximum": 5},
+			"tone": {"enum": ["funny", "dark"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+
+	AssertNoError(t, schema.Validate(map[string]interface{}{
+		"topic":    "time travel",
+		"chapters": []string{"One"},
+		"rating":   3,
+		"tone":     "funny",
+	}), "valid payload")
+
+	err = schema.Validate(map[string]interface{}{
+		"topik":    "typo",
+		"chapters": []interface{}{1},
+		"rating":   9,
+		"tone":     "sad",
+	})
+	var validationErr *SchemaValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected SchemaValidationError, got %v", err)
+	}
+	for _, want := range []string{`missing required property "topic"`, `unexpected property "topik"`, "$.chapters[0]: expected string", "greater than maximum", "is not one of"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestSchemaValidateStringLength(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"type": "string", "minLength": 2, "maxLength": 3}`))
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+
+	// Three characters encoded in nine bytes
+	AssertNoError(t, schema.Validate("日本語"), "multibyte string within maxLength")
+	AssertError(t, schema.Validate("日本語です"), "multibyte string over maxLength")
+	err = schema.Validate("é")
+	if err == nil || !strings.Contains(err.Error(), "length 1 is less than minLength 2") {
+		t.Errorf("Expected the length in characters, got %v", err)
+	}
+}
+
+func TestSendEventValidatesSchema(t *testing.T) {
+	eventType := EventType("SchemaTestEvent")
+	RegisterEventSchema(eventType, &Schema{
+		Type:     "object",
+		Required: []string{"topic"},
+	})
+	defer RegisterEventSchema(eventType, nil)
+
+	ctx := NewContext(context.Background())
+	AssertNoError(t, ctx.SendEvent(NewBaseEvent(eventType, map[string]interface{}{"topic": "ai"})), "valid event")
+
+	err := ctx.SendEvent(NewBaseEvent(eventType, map[string]interface{}{"topik": "ai"}))
+	if err == nil || !strings.Contains(err.Error(), `missing required property "topic"`) {
+		t.Errorf("Expected schema validation error, got %v", err)
+	}
+}
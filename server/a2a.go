@@ -0,0 +1,292 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// a2aTask is a task submitted to an agent over A2A.
+type a2aTask struct {
+	task   swarm.A2ATask
+	cancel context.CancelFunc
+}
+
+// handleAgentCard publishes the A2A agent card of an agent.
+func (s *Server) handleAgentCard(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.Swarm.Agent(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	skills := []swarm.A2ASkill{}
+	for _, skill := range agent.Skills {
+		skills = append(skills, swarm.A2ASkill{ID: skill, Name: skill, Description: skill, Tags: []string{skill}})
+	}
+	if len(skills) == 0 {
+		skills = append(skills, swarm.A2ASkill{ID: "chat", Name: "chat", Description: "Chat with " + agent.Name, Tags: []string{"chat"}})
+	}
+	writeJSON(w, http.StatusOK, swarm.A2AAgentCard{
+		Name:               agent.Name,
+		Description:        fmt.Sprintf("swarm-go agent %s", agent.Name),
+		URL:                fmt.Sprintf("%s://%s/a2a/%s", scheme, r.Host, agent.Name),
+		Version:            "1.0.0",
+		ProtocolVersion:    swarm.A2AProtocolVersion,
+		Capabilities:       swarm.A2ACapabilities{Streaming: true},
+		DefaultInputModes:  []string{"text/plain"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills:             skills,
+	})
+}
+
+// handleA2A serves the A2A JSON-RPC methods of an agent: message/send,
+// message/stream, tasks/get and tasks/cancel. The A2A context ID of a message
+// selects the session continuing the conversation.
+func (s *Server) handleA2A(w http.ResponseWriter, r *http.Request) {
+	var req swarm.A2ARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRPC(w, nil, nil, &swarm.A2AError{Code: swarm.A2AErrParse, Message: err.Error()})
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		writeRPC(w, req.ID, nil, &swarm.A2AError{Code: swarm.A2AErrInvalidRequest, Message: "invalid JSON-RPC request"})
+		return
+	}
+	agent, err := s.Swarm.Agent(r.PathValue("name"))
+	if err != nil {
+		writeRPC(w, req.ID, nil, &swarm.A2AError{Code: swarm.A2AErrInvalidRequest, Message: err.Error()})
+		return
+	}
+
+	switch req.Method {
+	case "message/send":
+		s.a2aSend(w, r, req, agent)
+	case "message/stream":
+		s.a2aStream(w, r, req, agent)
+	case "tasks/get", "tasks/cancel":
+		var params swarm.A2ATaskQueryParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			writeRPC(w, req.ID, nil, &swarm.A2AError{Code: swarm.A2AErrInvalidParams, Message: err.Error()})
+			return
+		}
+		task, rpcErr := s.a2aTaskQuery(params.ID, req.Method == "tasks/cancel")
+		writeRPC(w, req.ID, task, rpcErr)
+	default:
+		writeRPC(w, req.ID, nil, &swarm.A2AError{Code: swarm.A2AErrMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)})
+	}
+}
+
+// a2aSend runs a submitted message to completion and replies with the task.
+func (s *Server) a2aSend(w http.ResponseWriter, r *http.Request, req swarm.A2ARequest, agent *swarm.Agent) {
+	ctx, task, session, release, text, rpcErr := s.startA2ATask(r.Context(), req, agent)
+	if rpcErr != nil {
+		writeRPC(w, req.ID, nil, rpcErr)
+		return
+	}
+	defer release()
+	response, err := session.Send(ctx, text)
+	writeRPC(w, req.ID, s.finishA2ATask(task, response, err), nil)
+}
+
+// a2aStream runs a submitted message, streaming the task as server-sent
+// events: the submitted task, a "working" status, artifact updates with the
+// content deltas and a final status.
+func (s *Server) a2aStream(w http.ResponseWriter, r *http.Request, req swarm.A2ARequest, agent *swarm.Agent) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeRPC(w, req.ID, nil, &swarm.A2AError{Code: swarm.A2AErrInternal, Message: "streaming is not supported"})
+		return
+	}
+	ctx, task, session, release, text, rpcErr := s.startA2ATask(r.Context(), req, agent)
+	if rpcErr != nil {
+		writeRPC(w, req.ID, nil, rpcErr)
+		return
+	}
+	defer release()
+	chunks, err := session.Stream(ctx, text)
+	if err != nil {
+		s.finishA2ATask(task, nil, err)
+		writeRPC(w, req.ID, nil, &swarm.A2AError{Code: swarm.A2AErrInternal, Message: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	send := func(event swarm.A2AEvent) {
+		writeData(w, swarm.A2AResponse{JSONRPC: "2.0", ID: req.ID, Result: rawJSON(event)})
+		flusher.Flush()
+	}
+
+	submitted := s.a2aSnapshot(task)
+	submitted.Status = swarm.NewA2ATaskStatus(swarm.A2ATaskSubmitted, nil)
+	send(swarm.A2AEvent{Task: submitted})
+	send(swarm.A2AEvent{StatusUpdate: &swarm.A2ATaskStatusUpdateEvent{
+		Kind: "status-update", TaskID: submitted.ID, ContextID: submitted.ContextID,
+		Status: swarm.NewA2ATaskStatus(swarm.A2ATaskWorking, nil),
+	}})
+
+	artifactID := swarm.NewID("artifact-")
+	appended := false
+	var response *swarm.Response
+	for chunk := range chunks {
+		if r, ok := chunk["response"].(*swarm.Response); ok {
+			response = r
+			continue
+		}
+		content, _ := chunk["content"].(string)
+		if content == "" || chunk["tool_calls"] != nil {
+			continue
+		}
+		send(swarm.A2AEvent{ArtifactUpdate: &swarm.A2ATaskArtifactUpdateEvent{
+			Kind: "artifact-update", TaskID: submitted.ID, ContextID: submitted.ContextID,
+			Artifact: swarm.A2AArtifact{ArtifactID: artifactID, Name: "response", Parts: []swarm.A2APart{{Kind: "text", Text: content}}},
+			Append:   appended,
+		}})
+		appended = true
+	}
+	switch {
+	case response == nil:
+		err = errors.New("run failed")
+	case response.Error != nil:
+		err, response = response.Error, nil
+	}
+	final := s.finishA2ATask(task, response, err)
+	send(swarm.A2AEvent{StatusUpdate: &swarm.A2ATaskStatusUpdateEvent{
+		Kind: "status-update", TaskID: final.ID, ContextID: final.ContextID, Status: final.Status, Final: true,
+	}})
+}
+
+// startA2ATask registers the task of a submitted message and returns the
+// context, session and text to run it with, and the function releasing the
+// session once the run is over.
+func (s *Server) startA2ATask(ctx context.Context, req swarm.A2ARequest, agent *swarm.Agent) (context.Context, *a2aTask, *swarm.Session, func(), string, *swarm.A2AError) {
+	var params swarm.A2AMessageSendParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, nil, nil, nil, "", &swarm.A2AError{Code: swarm.A2AErrInvalidParams, Message: err.Error()}
+	}
+	message := params.Message
+	text := message.Text()
+	if strings.TrimSpace(text) == "" {
+		return nil, nil, nil, nil, "", &swarm.A2AError{Code: swarm.A2AErrInvalidParams, Message: "message has no text"}
+	}
+
+	s.mu.Lock()
+	task, ok := s.a2aTasks[message.TaskID]
+	switch {
+	case message.TaskID == "":
+	case !ok:
+		s.mu.Unlock()
+		return nil, nil, nil, nil, "", &swarm.A2AError{Code: swarm.A2AErrTaskNotFound, Message: fmt.Sprintf("task %s not found", message.TaskID)}
+	case task.task.Status.State.Terminal():
+		s.mu.Unlock()
+		return nil, nil, nil, nil, "", &swarm.A2AError{Code: swarm.A2AErrInvalidParams, Message: fmt.Sprintf("task %s is %s", message.TaskID, task.task.Status.State)}
+	}
+	if task == nil {
+		contextID := message.ContextID
+		if contextID == "" {
+			contextID = swarm.NewID("ctx-")
+		}
+		task = &a2aTask{task: swarm.A2ATask{Kind: "task", ID: swarm.NewID("task-"), ContextID: contextID}}
+		s.a2aTasks[task.task.ID] = task
+	}
+	message.TaskID, message.ContextID = task.task.ID, task.task.ContextID
+	task.task.History = append(task.task.History, message)
+	task.task.Status = swarm.NewA2ATaskStatus(swarm.A2ATaskWorking, nil)
+	ctx, task.cancel = context.WithCancel(ctx)
+	contextID := task.task.ContextID
+	s.mu.Unlock()
+
+	session, release, err := s.session(contextID, agent)
+	if err != nil {
+		s.finishA2ATask(task, nil, err)
+		return nil, nil, nil, nil, "", &swarm.A2AError{Code: swarm.A2AErrInternal, Message: err.Error()}
+	}
+	return ctx, task, session, release, text, nil
+}
+
+// finishA2ATask records the outcome of a task's run and returns a snapshot
+// of the task. Canceled tasks stay canceled.
+func (s *Server) finishA2ATask(task *a2aTask, response *swarm.Response, err error) *swarm.A2ATask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task.cancel()
+	t := &task.task
+	switch {
+	case t.Status.State == swarm.A2ATaskCanceled:
+	case err != nil:
+		reply := swarm.NewA2AMessage("agent", err.Error())
+		reply.TaskID, reply.ContextID = t.ID, t.ContextID
+		t.Status = swarm.NewA2ATaskStatus(swarm.A2ATaskFailed, &reply)
+	default:
+		content := finalContent(response.Messages)
+		reply := swarm.NewA2AMessage("agent", content)
+		reply.TaskID, reply.ContextID = t.ID, t.ContextID
+		t.History = append(t.History, reply)
+		t.Artifacts = append(t.Artifacts, swarm.A2AArtifact{
+			ArtifactID: swarm.NewID("artifact-"),
+			Name:       "response",
+			Parts:      []swarm.A2APart{{Kind: "text", Text: content}},
+		})
+		t.Status = swarm.NewA2ATaskStatus(swarm.A2ATaskCompleted, &reply)
+	}
+	return copyA2ATask(t)
+}
+
+// a2aTaskQuery returns a task, canceling it first if requested.
+func (s *Server) a2aTaskQuery(id string, cancel bool) (*swarm.A2ATask, *swarm.A2AError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.a2aTasks[id]
+	if !ok {
+		return nil, &swarm.A2AError{Code: swarm.A2AErrTaskNotFound, Message: fmt.Sprintf("task %s not found", id)}
+	}
+	if cancel {
+		if task.task.Status.State.Terminal() {
+			return nil, &swarm.A2AError{Code: swarm.A2AErrTaskNotCanceled, Message: fmt.Sprintf("task %s is %s", id, task.task.Status.State)}
+		}
+		task.cancel()
+		task.task.Status = swarm.NewA2ATaskStatus(swarm.A2ATaskCanceled, nil)
+	}
+	return copyA2ATask(&task.task), nil
+}
+
+// a2aSnapshot returns a copy of a task.
+func (s *Server) a2aSnapshot(task *a2aTask) *swarm.A2ATask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyA2ATask(&task.task)
+}
+
+// copyA2ATask copies a task's history and artifacts.
+func copyA2ATask(task *swarm.A2ATask) *swarm.A2ATask {
+	c := *task
+	c.History = append([]swarm.A2AMessage(nil), task.History...)
+	c.Artifacts = append([]swarm.A2AArtifact(nil), task.Artifacts...)
+	return &c
+}
+
+// writeRPC writes a JSON-RPC response with either a result or an error.
+func writeRPC(w http.ResponseWriter, id interface{}, result interface{}, rpcErr *swarm.A2AError) {
+	resp := swarm.A2AResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		resp.Result = rawJSON(result)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rawJSON marshals a result known to be marshalable.
+func rawJSON(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
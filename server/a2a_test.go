@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+func TestA2A(t *testing.T) {
+	server := newTestServer(t)
+	client := swarm.NewA2AClient(server.URL + "/a2a/assistant")
+	ctx := context.Background()
+
+	card, err := client.AgentCard(ctx)
+	if err != nil {
+		t.Fatalf("fetch agent card: %v", err)
+	}
+	if card.Name != "assistant" || card.URL != client.URL || !card.Capabilities.Streaming {
+		t.Errorf("unexpected agent card: %+v", card)
+	}
+
+	task, err := client.SendMessage(ctx, swarm.NewA2AMessage("user", "hello"))
+	if err != nil {
+		t.Fatalf("send message: %v", err)
+	}
+	if task.Status.State != swarm.A2ATaskCompleted || task.Text() != "echo hello (2 messages)" {
+		t.Errorf("unexpected task: %+v", task)
+	}
+
+	// Messages in the same context continue its session
+	follow := swarm.NewA2AMessage("user", "again")
+	follow.ContextID = task.ContextID
+	events, err := client.StreamMessage(ctx, follow)
+	if err != nil {
+		t.Fatalf("stream message: %v", err)
+	}
+	var streamed string
+	var final *swarm.A2ATaskStatusUpdateEvent
+	for event := range events {
+		if event.ArtifactUpdate != nil {
+			streamed += event.ArtifactUpdate.Artifact.Parts[0].Text
+		}
+		if event.StatusUpdate != nil {
+			final = event.StatusUpdate
+		}
+	}
+	if streamed != "echo again (4 messages)" || final == nil || !final.Final || final.Status.State != swarm.A2ATaskCompleted {
+		t.Errorf("unexpected stream: %q, final %+v", streamed, final)
+	}
+
+	got, err := client.GetTask(ctx, final.TaskID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if got.Text() != "echo again (4 messages)" || len(got.History) != 2 {
+		t.Errorf("unexpected stored task: %+v", got)
+	}
+
+	var rpcErr *swarm.A2AError
+	if _, err := client.CancelTask(ctx, final.TaskID); !errors.As(err, &rpcErr) || rpcErr.Code != swarm.A2AErrTaskNotCanceled {
+		t.Errorf("expected canceling a completed task to fail, got %v", err)
+	}
+	if _, err := client.GetTask(ctx, "missing"); !errors.As(err, &rpcErr) || rpcErr.Code != swarm.A2AErrTaskNotFound {
+		t.Errorf("expected an unknown task to be reported, got %v", err)
+	}
+}
+
+func TestA2AErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	for body, code := range map[string]int{
+		`not json`: swarm.A2AErrParse,
+		`{"jsonrpc":"2.0","id":1,"method":"tasks/list"}`:                                        swarm.A2AErrMethodNotFound,
+		`{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"role":"user"}}}`: swarm.A2AErrInvalidParams,
+		`{"jsonrpc":"1.0","id":1,"method":"message/send"}`:                                      swarm.A2AErrInvalidRequest,
+	} {
+		resp, err := http.Post(server.URL+"/a2a/assistant", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		var rpc swarm.A2AResponse
+		json.NewDecoder(resp.Body).Decode(&rpc)
+		resp.Body.Close()
+		if rpc.Error == nil || rpc.Error.Code != code {
+			t.Errorf("%s: expected error %d, got %+v", body, code, rpc.Error)
+		}
+	}
+}
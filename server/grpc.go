@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/feiskyer/swarm-go/api/swarmv1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// WorkflowService implements the gRPC WorkflowService of api/swarmv1 over a
+// set of WorkflowRuns, so services in any language can submit and monitor
+// workflow runs. Register it with swarmv1.RegisterWorkflowServiceServer.
+type WorkflowService struct {
+	swarmv1.UnimplementedWorkflowServiceServer
+
+	// Runs starts and tracks the runs
+	Runs *WorkflowRuns
+}
+
+// NewWorkflowService creates a gRPC service for the workflows of runs.
+func NewWorkflowService(runs *WorkflowRuns) *WorkflowService {
+	return &WorkflowService{Runs: runs}
+}
+
+// ListWorkflows lists the registered workflows.
+func (s *WorkflowService) ListWorkflows(ctx context.Context, req *swarmv1.ListWorkflowsRequest) (*swarmv1.ListWorkflowsResponse, error) {
+	return &swarmv1.ListWorkflowsResponse{Workflows: s.Runs.Names()}, nil
+}
+
+// SubmitRun starts a workflow run.
+func (s *WorkflowService) SubmitRun(ctx context.Context, req *swarmv1.SubmitRunRequest) (*swarmv1.Run, error) {
+	if req.GetWorkflow() == "" {
+		return nil, status.Error(codes.InvalidArgument, "workflow is required")
+	}
+	run, err := s.Runs.Start(req.GetWorkflow(), req.GetInputs().AsMap())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return runProto(run)
+}
+
+// GetRun returns a run.
+func (s *WorkflowService) GetRun(ctx context.Context, req *swarmv1.GetRunRequest) (*swarmv1.Run, error) {
+	run, err := s.Runs.Get(req.GetRunId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return runProto(run)
+}
+
+// ListRuns lists runs, most recent first.
+func (s *WorkflowService) ListRuns(ctx context.Context, req *swarmv1.ListRunsRequest) (*swarmv1.ListRunsResponse, error) {
+	resp := &swarmv1.ListRunsResponse{}
+	for _, run := range s.Runs.List(req.GetWorkflow()) {
+		pb, err := runProto(run)
+		if err != nil {
+			return nil, err
+		}
+		resp.Runs = append(resp.Runs, pb)
+	}
+	return resp, nil
+}
+
+// CancelRun cancels a run and returns it once it has stopped.
+func (s *WorkflowService) CancelRun(ctx context.Context, req *swarmv1.CancelRunRequest) (*swarmv1.Run, error) {
+	run, err := s.Runs.Get(req.GetRunId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	run.Cancel()
+	select {
+	case <-run.Done():
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return runProto(run)
+}
+
+// StreamEvents streams the events of a run.
+func (s *WorkflowService) StreamEvents(req *swarmv1.StreamEventsRequest, stream swarmv1.WorkflowService_StreamEventsServer) error {
+	run, err := s.Runs.Get(req.GetRunId())
+	if err != nil {
+		return grpcError(err)
+	}
+	for event := range run.Events(stream.Context()) {
+		if len(req.GetTypes()) > 0 && !slices.Contains(req.GetTypes(), string(event.Type())) {
+			continue
+		}
+		pb, err := eventProto(run.ID, event)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(pb); err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
+// runProto converts a run to its protobuf message.
+func runProto(run *WorkflowRun) (*swarmv1.Run, error) {
+	inputs, err := structProto(run.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	pb := &swarmv1.Run{
+		Id:        run.ID,
+		Workflow:  run.Workflow,
+		Status:    statusProto(run.Status()),
+		Inputs:    inputs,
+		CreatedAt: timestamppb.New(run.CreatedAt),
+	}
+	if finishedAt := run.FinishedAt(); !finishedAt.IsZero() {
+		pb.FinishedAt = timestamppb.New(finishedAt)
+		result, err := run.Result()
+		if err != nil {
+			pb.Error = err.Error()
+		} else if pb.Result, err = valueProto(result); err != nil {
+			return nil, err
+		}
+	}
+	return pb, nil
+}
+
+// eventProto converts an event to its protobuf message.
+func eventProto(runID string, event swarm.Event) (*swarmv1.Event, error) {
+	data, err := structProto(event.Data())
+	if err != nil {
+		return nil, err
+	}
+	pb := &swarmv1.Event{RunId: runID, Type: string(event.Type()), Data: data}
+	if carrier, ok := event.(swarm.MetadataCarrier); ok {
+		metadata := carrier.Metadata()
+		pb.EventId, pb.CausationId = metadata.EventID, metadata.CausationID
+		if !metadata.Timestamp.IsZero() {
+			pb.Time = timestamppb.New(metadata.Timestamp)
+		}
+	}
+	return pb, nil
+}
+
+// statusProto converts a workflow status.
+func statusProto(s swarm.WorkflowStatus) swarmv1.RunStatus {
+	switch s {
+	case swarm.WorkflowStatusPending:
+		return swarmv1.RunStatus_RUN_STATUS_PENDING
+	case swarm.WorkflowStatusRunning:
+		return swarmv1.RunStatus_RUN_STATUS_RUNNING
+	case swarm.WorkflowStatusComplete:
+		return swarmv1.RunStatus_RUN_STATUS_COMPLETE
+	case swarm.WorkflowStatusFailed:
+		return swarmv1.RunStatus_RUN_STATUS_FAILED
+	case swarm.WorkflowStatusCancelled:
+		return swarmv1.RunStatus_RUN_STATUS_CANCELLED
+	case swarm.WorkflowStatusPaused:
+		return swarmv1.RunStatus_RUN_STATUS_PAUSED
+	}
+	return swarmv1.RunStatus_RUN_STATUS_UNSPECIFIED
+}
+
+// structProto converts a map to a protobuf Struct through JSON, so values
+// such as structs and typed slices are accepted.
+func structProto(m map[string]interface{}) (*structpb.Struct, error) {
+	if m == nil {
+		return nil, nil
+	}
+	var plain map[string]interface{}
+	if err := jsonRoundTrip(m, &plain); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert %v: %v", m, err)
+	}
+	return structpb.NewStruct(plain)
+}
+
+// valueProto converts a value to a protobuf Value through JSON.
+func valueProto(v interface{}) (*structpb.Value, error) {
+	var plain interface{}
+	if err := jsonRoundTrip(v, &plain); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert result: %v", err)
+	}
+	return structpb.NewValue(plain)
+}
+
+// jsonRoundTrip decodes the JSON encoding of v into out.
+func jsonRoundTrip(v interface{}, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// grpcError maps an error to a gRPC status.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrWorkflowNotFound), errors.Is(err, ErrRunNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/feiskyer/swarm-go/api/swarmv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestRuns registers a "greet" workflow that stops with a greeting and a
+// "wait" workflow that runs until it is cancelled.
+func newTestRuns() *WorkflowRuns {
+	return NewWorkflowRuns().
+		Register("greet", func() (*swarm.Workflow, error) {
+			workflow := swarm.NewWorkflow("greet")
+			err := workflow.AddStep(swarm.NewStartStep(func(ctx *swarm.Context, event swarm.Event) (swarm.Event, error) {
+				return swarm.NewStopEvent(map[string]interface{}{"greeting": "hello " + event.Data()["name"].(string)}), nil
+			}, nil))
+			return workflow, err
+		}).
+		Register("wait", func() (*swarm.Workflow, error) {
+			workflow := swarm.NewWorkflow("wait")
+			err := workflow.AddStep(swarm.NewStartStep(func(ctx *swarm.Context, event swarm.Event) (swarm.Event, error) {
+				<-ctx.Context().Done()
+				return nil, nil
+			}, nil))
+			return workflow, err
+		})
+}
+
+func newGRPCClient(t *testing.T, runs *WorkflowRuns) swarmv1.WorkflowServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	swarmv1.RegisterWorkflowServiceServer(server, NewWorkflowService(runs))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return swarmv1.NewWorkflowServiceClient(conn)
+}
+
+func TestGRPCSubmitAndStream(t *testing.T) {
+	client := newGRPCClient(t, newTestRuns())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	workflows, err := client.ListWorkflows(ctx, &swarmv1.ListWorkflowsRequest{})
+	if err != nil || len(workflows.Workflows) != 2 || workflows.Workflows[0] != "greet" {
+		t.Fatalf("unexpected workflows %v: %v", workflows, err)
+	}
+
+	inputs, _ := structpb.NewStruct(map[string]interface{}{"name": "ann"})
+	run, err := client.SubmitRun(ctx, &swarmv1.SubmitRunRequest{Workflow: "greet", Inputs: inputs})
+	if err != nil {
+		t.Fatalf("submit run: %v", err)
+	}
+
+	stream, err := client.StreamEvents(ctx, &swarmv1.StreamEventsRequest{RunId: run.Id})
+	if err != nil {
+		t.Fatalf("stream events: %v", err)
+	}
+	var types []string
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("receive event: %v", err)
+		}
+		if event.RunId != run.Id || event.EventId == "" {
+			t.Errorf("unexpected event %v", event)
+		}
+		types = append(types, event.Type)
+	}
+	if len(types) != 2 || types[0] != "StartEvent" || types[1] != "StopEvent" {
+		t.Errorf("expected start and stop events, got %v", types)
+	}
+
+	got, err := client.GetRun(ctx, &swarmv1.GetRunRequest{RunId: run.Id})
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if got.Status != swarmv1.RunStatus_RUN_STATUS_COMPLETE || got.Result.GetStructValue().AsMap()["greeting"] != "hello ann" {
+		t.Errorf("unexpected run %v", got)
+	}
+
+	runs, err := client.ListRuns(ctx, &swarmv1.ListRunsRequest{Workflow: "greet"})
+	if err != nil || len(runs.Runs) != 1 || runs.Runs[0].Id != run.Id {
+		t.Errorf("unexpected runs %v: %v", runs, err)
+	}
+}
+
+func TestGRPCCancelRun(t *testing.T) {
+	client := newGRPCClient(t, newTestRuns())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	run, err := client.SubmitRun(ctx, &swarmv1.SubmitRunRequest{Workflow: "wait"})
+	if err != nil {
+		t.Fatalf("submit run: %v", err)
+	}
+	cancelled, err := client.CancelRun(ctx, &swarmv1.CancelRunRequest{RunId: run.Id})
+	if err != nil {
+		t.Fatalf("cancel run: %v", err)
+	}
+	if cancelled.Status != swarmv1.RunStatus_RUN_STATUS_CANCELLED || cancelled.Error == "" || cancelled.FinishedAt == nil {
+		t.Errorf("unexpected cancelled run %v", cancelled)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := newGRPCClient(t, newTestRuns())
+	ctx := context.Background()
+
+	if _, err := client.SubmitRun(ctx, &swarmv1.SubmitRunRequest{Workflow: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown workflow, got %v", err)
+	}
+	if _, err := client.SubmitRun(ctx, &swarmv1.SubmitRunRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without a workflow, got %v", err)
+	}
+	if _, err := client.GetRun(ctx, &swarmv1.GetRunRequest{RunId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown run, got %v", err)
+	}
+}
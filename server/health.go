@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// DefaultHealthCheckTimeout bounds each readiness check.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// ProviderCheck is the name of the built-in readiness check of the model
+// provider, run when the swarm's client implements swarm.Pinger.
+const ProviderCheck = "provider"
+
+// HealthCheck reports whether a dependency of the server is available.
+type HealthCheck func(ctx context.Context) error
+
+// Health is the body of /healthz and /readyz responses.
+type Health struct {
+	// Status is "ok", or "unavailable" when a readiness check failed
+	Status string `json:"status"`
+	// Checks are the results of the readiness checks, by name
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+	// Sessions is the number of chat sessions held in memory
+	Sessions int `json:"sessions"`
+	// Workflows counts the workflow runs in flight, if workflows are served
+	Workflows *WorkflowLoad `json:"workflows,omitempty"`
+}
+
+// CheckResult is the outcome of a readiness check.
+type CheckResult struct {
+	// Status is "ok" or "unavailable"
+	Status string `json:"status"`
+	// Error explains a failed check
+	Error string `json:"error,omitempty"`
+	// Latency is the duration of the check in milliseconds
+	Latency float64 `json:"latency_ms"`
+}
+
+// WorkflowLoad counts the workflow runs in flight.
+type WorkflowLoad struct {
+	// Pending, Running and Paused count the runs by status
+	Pending int `json:"pending"`
+	Running int `json:"running"`
+	Paused  int `json:"paused"`
+	// QueueDepth is the number of events queued for dispatch across runs
+	QueueDepth int `json:"queue_depth"`
+}
+
+// Health statuses.
+const (
+	healthOK          = "ok"
+	healthUnavailable = "unavailable"
+)
+
+// WithHealthCheck adds a readiness check reported by /readyz under name, and
+// returns the server. A check named ProviderCheck replaces the built-in one.
+func (s *Server) WithHealthCheck(name string, check HealthCheck) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+	return s
+}
+
+// handleHealthz is the liveness probe: it reports the server's load without
+// checking its dependencies.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.health(nil))
+}
+
+// handleReadyz is the readiness probe: it runs the readiness checks
+// concurrently and replies 503 if any of them fails.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	results := s.runHealthChecks(r.Context())
+	health := s.health(results)
+	status := http.StatusOK
+	if health.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// health describes the server's load and the results of its checks.
+func (s *Server) health(results map[string]CheckResult) Health {
+	health := Health{Status: healthOK, Checks: results}
+	for _, result := range results {
+		if result.Status != healthOK {
+			health.Status = healthUnavailable
+		}
+	}
+	s.mu.Lock()
+	health.Sessions = len(s.sessions)
+	s.mu.Unlock()
+
+	if s.Workflows != nil {
+		load := &WorkflowLoad{}
+		for _, run := range s.Workflows.List("") {
+			switch run.Status() {
+			case swarm.WorkflowStatusPending:
+				load.Pending++
+			case swarm.WorkflowStatusRunning:
+				load.Running++
+			case swarm.WorkflowStatusPaused:
+				load.Paused++
+			default:
+				continue
+			}
+			load.QueueDepth += len(run.Handler().Context().Events())
+		}
+		health.Workflows = load
+	}
+	return health
+}
+
+// runHealthChecks runs the readiness checks, each bounded by the server's
+// HealthCheckTimeout.
+func (s *Server) runHealthChecks(ctx context.Context) map[string]CheckResult {
+	s.mu.Lock()
+	checks := make(map[string]HealthCheck, len(s.checks)+1)
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	s.mu.Unlock()
+	if _, ok := checks[ProviderCheck]; !ok && s.Swarm != nil {
+		if pinger, ok := s.Swarm.Client.(swarm.Pinger); ok {
+			checks[ProviderCheck] = pinger.Ping
+		}
+	}
+
+	timeout := s.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	results := make(map[string]CheckResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			// Checks that ignore their context still fail on time
+			done := make(chan error, 1)
+			go func() { done <- check(checkCtx) }()
+			var err error
+			select {
+			case err = <-done:
+			case <-checkCtx.Done():
+				err = checkCtx.Err()
+			}
+			result := CheckResult{Status: healthOK, Latency: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status, result.Error = healthUnavailable, err.Error()
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+func getHealth(t *testing.T, url string) (int, Health) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var health Health
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatalf("decode health: %v", err)
+	}
+	return resp.StatusCode, health
+}
+
+func TestHealthProbes(t *testing.T) {
+	var providerDown atomic.Bool
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if providerDown.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[]}`)
+	}))
+	defer provider.Close()
+
+	runs := newTestRuns()
+	srv := New(swarm.NewSwarm(swarm.NewOpenAIClientWithBaseURL("test", provider.URL))).
+		WithWorkflows(runs).
+		WithHealthCheck("database", func(ctx context.Context) error { return nil })
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	run, err := runs.Start("wait", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("start run: %v", err)
+	}
+	defer run.Cancel()
+
+	status, health := getHealth(t, server.URL+"/healthz")
+	if status != http.StatusOK || health.Status != "ok" || len(health.Checks) != 0 {
+		t.Errorf("unexpected liveness %d %+v", status, health)
+	}
+	if health.Workflows == nil || health.Workflows.Running+health.Workflows.Pending != 1 {
+		t.Errorf("unexpected workflow load %+v", health.Workflows)
+	}
+
+	status, health = getHealth(t, server.URL+"/readyz")
+	if status != http.StatusOK || health.Checks[ProviderCheck].Status != "ok" || health.Checks["database"].Status != "ok" {
+		t.Errorf("unexpected readiness %d %+v", status, health)
+	}
+
+	providerDown.Store(true)
+	status, health = getHealth(t, server.URL+"/readyz")
+	if status != http.StatusServiceUnavailable || health.Status != "unavailable" || health.Checks[ProviderCheck].Error == "" {
+		t.Errorf("unexpected readiness with the provider down %d %+v", status, health)
+	}
+	if status, _ := getHealth(t, server.URL+"/healthz"); status != http.StatusOK {
+		t.Errorf("liveness must not depend on the provider, got %d", status)
+	}
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	srv := New(nil).WithHealthCheck("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}).WithHealthCheck("broken", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	srv.HealthCheckTimeout = 10 * time.Millisecond
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	start := time.Now()
+	status, health := getHealth(t, server.URL+"/readyz")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("readiness waited %v for a stuck check", elapsed)
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", status)
+	}
+	if health.Checks["stuck"].Error != context.DeadlineExceeded.Error() || health.Checks["broken"].Error != "connection refused" {
+		t.Errorf("unexpected checks %+v", health.Checks)
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// CompletionRequest is the body of an OpenAI-compatible chat completion
+// request. Model names the agent to run; the other OpenAI parameters are
+// ignored in favor of the agent's own settings.
+type CompletionRequest struct {
+	// Model is the name of the agent
+	Model string `json:"model"`
+	// Messages is the conversation so far
+	Messages []CompletionMessage `json:"messages"`
+	// Stream requests a server-sent event stream of chunks
+	Stream bool `json:"stream,omitempty"`
+}
+
+// CompletionMessage is a message of an OpenAI-compatible chat completion.
+type CompletionMessage struct {
+	// Role is "system", "developer", "user" or "assistant"
+	Role string `json:"role"`
+	// Content is a string, or a list of content parts of which the text parts
+	// are used
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the textual content of the message.
+func (m CompletionMessage) text() (string, error) {
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return "", nil
+	}
+	var content string
+	if err := json.Unmarshal(m.Content, &content); err == nil {
+		return content, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", fmt.Errorf("invalid %s message content: %w", m.Role, err)
+	}
+	var text []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			text = append(text, part.Text)
+		}
+	}
+	return strings.Join(text, "\n"), nil
+}
+
+// handleModels lists the served agents as OpenAI models.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	registry := s.Swarm.Registry
+	if registry == nil {
+		registry = swarm.DefaultRegistry
+	}
+	models := []map[string]interface{}{}
+	for _, name := range registry.Names() {
+		models = append(models, map[string]interface{}{"id": name, "object": "model", "created": 0, "owned_by": "swarm-go"})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": models})
+}
+
+// handleCompletions answers an OpenAI-compatible chat completion request with
+// the agent named by its model. The conversation is taken from the request,
+// so no session is kept between requests.
+func (s *Server) handleCompletions(w http.ResponseWriter, r *http.Request) {
+	var req CompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCompletionError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	agent, err := s.Swarm.Agent(req.Model)
+	if err != nil {
+		writeCompletionError(w, http.StatusNotFound, err)
+		return
+	}
+	messages, err := completionMessages(req.Messages)
+	if err != nil {
+		writeCompletionError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	maxTurns := s.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = swarm.DefaultSessionMaxTurns
+	}
+	id := swarm.NewID("chatcmpl-")
+	if req.Stream {
+		s.streamCompletion(w, r, agent, messages, maxTurns, id, req.Model)
+		return
+	}
+
+	response, err := s.Swarm.Run(r.Context(), agent, messages, nil, "", false, false, maxTurns, true, false)
+	if err != nil {
+		writeCompletionError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   req.Model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]interface{}{"role": "assistant", "content": finalContent(response.Messages)},
+			"finish_reason": finishReason(response.StopReason),
+		}},
+		"usage": map[string]interface{}{"total_tokens": response.TokensUsed},
+	})
+}
+
+// streamCompletion answers a chat completion request with a stream of
+// chat.completion.chunk events carrying the content deltas, terminated by
+// "[DONE]". Tool calls are executed by the server and are not streamed.
+func (s *Server) streamCompletion(w http.ResponseWriter, r *http.Request, agent *swarm.Agent, messages []map[string]interface{}, maxTurns int, id, model string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeCompletionError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	chunks, err := s.Swarm.RunAndStream(r.Context(), agent, messages, nil, "", false, maxTurns, true, false)
+	if err != nil {
+		writeCompletionError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	created := time.Now().Unix()
+	writeChunk := func(delta map[string]interface{}, finish interface{}) {
+		writeData(w, map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		flusher.Flush()
+	}
+
+	writeChunk(map[string]interface{}{"role": "assistant", "content": ""}, nil)
+	var response *swarm.Response
+	for chunk := range chunks {
+		switch {
+		case chunk["response"] != nil:
+			response, _ = chunk["response"].(*swarm.Response)
+		case chunk["chunk"] != nil:
+			delta, _ := chunk["chunk"].(*swarm.StreamChunk)
+			switch {
+			case delta == nil:
+			case delta.Type == swarm.StreamChunkContent:
+				writeChunk(map[string]interface{}{"content": delta.Delta}, nil)
+			case delta.Type == swarm.StreamChunkReasoning:
+				writeChunk(map[string]interface{}{"reasoning_content": delta.Delta}, nil)
+			}
+		}
+	}
+	if response == nil || response.Error != nil {
+		message := "run failed"
+		if response != nil {
+			message = response.Error.Error()
+		}
+		writeData(w, map[string]interface{}{"error": map[string]string{"message": message, "type": "server_error"}})
+	} else {
+		writeChunk(map[string]interface{}{}, finishReason(response.StopReason))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// completionMessages converts the messages of a chat completion request to
+// the conversation history of a run.
+func completionMessages(messages []CompletionMessage) ([]map[string]interface{}, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("messages are required")
+	}
+	history := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		role := msg.Role
+		switch role {
+		case "developer":
+			role = "system"
+		case "system", "user", "assistant":
+		default:
+			return nil, fmt.Errorf("unsupported message role %q", msg.Role)
+		}
+		content, err := msg.text()
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, map[string]interface{}{"role": role, "content": content})
+	}
+	return history, nil
+}
+
+// finishReason maps the stop reason of a run to an OpenAI finish reason.
+func finishReason(reason swarm.StopReason) string {
+	switch reason {
+	case swarm.StopCompleted, swarm.StopConditionMet, "":
+		return "stop"
+	}
+	return "length"
+}
+
+// writeData writes a server-sent event without an event name, as OpenAI
+// streams do.
+func writeData(w http.ResponseWriter, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "data: %s\n\n", payload)
+}
+
+// writeCompletionError writes an error in the format of the OpenAI API.
+func writeCompletionError(w http.ResponseWriter, status int, err error) {
+	kind := "invalid_request_error"
+	if status >= http.StatusInternalServerError {
+		kind = "server_error"
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{"message": err.Error(), "type": kind},
+	})
+}
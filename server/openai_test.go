@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func postCompletion(t *testing.T, url string, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url+"/v1/chat/completions", "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("POST /v1/chat/completions: %v", err)
+	}
+	return resp
+}
+
+func TestCompletions(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postCompletion(t, server.URL, `{"model":"assistant","messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":"hello"},
+		{"role":"user","content":[{"type":"text","text":"how are you"}]}
+	]}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var completion struct {
+		Object  string `json:"object"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if completion.Object != "chat.completion" || completion.Model != "assistant" || len(completion.Choices) != 1 {
+		t.Fatalf("unexpected completion: %+v", completion)
+	}
+	// The agent's instructions are prepended to the client's three messages
+	choice := completion.Choices[0]
+	if choice.Message.Role != "assistant" || choice.Message.Content != "echo how are you (4 messages)" || choice.FinishReason != "stop" {
+		t.Errorf("unexpected choice: %+v", choice)
+	}
+}
+
+func TestCompletionsStream(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postCompletion(t, server.URL, `{"model":"assistant","stream":true,"messages":[{"role":"user","content":"hello"}]}`)
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	var content strings.Builder
+	var finish, last string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		last = data
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil || chunk.Object != "chat.completion.chunk" {
+			t.Fatalf("unexpected chunk %s: %v", data, err)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finish = *chunk.Choices[0].FinishReason
+		}
+	}
+
+	if content.String() != "echo hello (2 messages)" || finish != "stop" || last != "[DONE]" {
+		t.Errorf("unexpected stream: content %q, finish reason %q, last %q", content.String(), finish, last)
+	}
+}
+
+func TestCompletionsErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"model":"nobody","messages":[{"role":"user","content":"hi"}]}`, http.StatusNotFound},
+		{`{"model":"assistant","messages":[]}`, http.StatusBadRequest},
+		{`{"model":"assistant","messages":[{"role":"tool","content":"42"}]}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	} {
+		resp := postCompletion(t, server.URL, tc.body)
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status || body.Error.Message == "" {
+			t.Errorf("%s: expected status %d with an error, got %d %+v", tc.body, tc.status, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestModels(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("GET /v1/models: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if len(body.Data) != 1 || body.Data[0].ID != "assistant" {
+		t.Errorf("expected the assistant model, got %+v", body.Data)
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+// RunRequest is the body of a request starting a workflow run.
+type RunRequest struct {
+	// Inputs are the inputs of the StartEvent
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+}
+
+// RunDetails describes a workflow run together with its metrics.
+type RunDetails struct {
+	RunInfo
+	Metrics RunMetrics `json:"metrics"`
+}
+
+// InputResponse answers an input request of a workflow run.
+type InputResponse struct {
+	// RequestID is the ID of the answered InputRequiredEvent
+	RequestID string `json:"request_id"`
+	// Approved is the decision for approval requests
+	Approved bool `json:"approved"`
+	// Response is optional free-form input
+	Response string `json:"response,omitempty"`
+}
+
+// handleWorkflows lists the registered workflows.
+func (s *Server) handleWorkflows(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	if s.Workflows != nil {
+		names = s.Workflows.Names()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"workflows": names})
+}
+
+// handleStartRun starts a workflow run and replies without waiting for it.
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	if s.Workflows == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrWorkflowNotFound, r.PathValue("name")))
+		return
+	}
+	var req RunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if req.Inputs == nil {
+		req.Inputs = map[string]interface{}{}
+	}
+	run, err := s.Workflows.Start(r.PathValue("name"), req.Inputs)
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, run.Info())
+}
+
+// handleRuns lists the runs, optionally of the workflow in the "workflow"
+// query parameter, most recent first.
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	runs := []RunInfo{}
+	if s.Workflows != nil {
+		for _, run := range s.Workflows.List(r.URL.Query().Get("workflow")) {
+			runs = append(runs, run.Info())
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": runs})
+}
+
+// handleRun describes a run with its metrics.
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, RunDetails{RunInfo: run.Info(), Metrics: run.Metrics()})
+}
+
+// handleRunEvents streams the events of a run as server-sent events named
+// after the event types, replaying those already emitted. The "type" query
+// parameter, which may be repeated, restricts the stream to some types. A
+// final "done" event carries the outcome of the run.
+func (s *Server) handleRunEvents(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	types := r.URL.Query()["type"]
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for event := range run.Events(r.Context()) {
+		if len(types) > 0 && !slices.Contains(types, string(event.Type())) {
+			continue
+		}
+		data, err := swarm.MarshalEvent(event)
+		if err != nil {
+			writeEvent(w, "error", map[string]string{"error": err.Error()})
+		} else {
+			writeEvent(w, string(event.Type()), json.RawMessage(data))
+		}
+		flusher.Flush()
+	}
+	if r.Context().Err() == nil {
+		writeEvent(w, "done", run.Info())
+		flusher.Flush()
+	}
+}
+
+// handleCancelRun cancels a run and replies once it has stopped.
+func (s *Server) handleCancelRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	run.Cancel()
+	select {
+	case <-run.Done():
+	case <-r.Context().Done():
+		return
+	}
+	writeJSON(w, http.StatusOK, run.Info())
+}
+
+// handlePauseRun pauses a run.
+func (s *Server) handlePauseRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	if err := run.Pause(); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run.Info())
+}
+
+// handleResumeRun resumes a paused run.
+func (s *Server) handleResumeRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	if err := run.Resume(); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run.Info())
+}
+
+// handleRunInput answers a pending input request of a run.
+func (s *Server) handleRunInput(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.run(w, r)
+	if !ok {
+		return
+	}
+	var req InputResponse
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if req.RequestID == "" {
+		writeError(w, http.StatusBadRequest, errors.New("request_id is required"))
+		return
+	}
+	if err := run.Respond(req.RequestID, req.Approved, req.Response); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, RunDetails{RunInfo: run.Info(), Metrics: run.Metrics()})
+}
+
+// run returns the run identified by the request path, or writes an error.
+func (s *Server) run(w http.ResponseWriter, r *http.Request) (*WorkflowRun, bool) {
+	id := r.PathValue("id")
+	if s.Workflows == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrRunNotFound, id))
+		return nil, false
+	}
+	run, err := s.Workflows.Get(id)
+	if err != nil {
+		writeRunError(w, err)
+		return nil, false
+	}
+	return run, true
+}
+
+// writeRunError writes a workflow run error with its HTTP status.
+func writeRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrWorkflowNotFound), errors.Is(err, ErrRunNotFound), errors.Is(err, ErrInputNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, swarm.ErrWorkflowNotRunning):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
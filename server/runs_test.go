@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+func newRunsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	runs := newTestRuns().Register("approve", func() (*swarm.Workflow, error) {
+		workflow := swarm.NewWorkflow("approve")
+		err := workflow.AddStep(swarm.NewStartStep(func(ctx *swarm.Context, event swarm.Event) (swarm.Event, error) {
+			ctx.ReportProgress("approve", 50, "waiting for approval")
+			approved, err := swarm.WorkflowApproval(ctx, 0)(swarm.ToolCall{Function: swarm.Function{Name: "deploy"}})
+			if err != nil {
+				return nil, err
+			}
+			return swarm.NewStopEvent(map[string]interface{}{"approved": approved}), nil
+		}, nil))
+		return workflow, err
+	})
+	server := httptest.NewServer(New(nil).WithWorkflows(runs))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doJSON(t *testing.T, method, url string, body interface{}, out interface{}) int {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req, _ := http.NewRequest(method, url, &payload)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+// waitForRun polls a run until accept returns true.
+func waitForRun(t *testing.T, url string, accept func(RunDetails) bool) RunDetails {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var details RunDetails
+		doJSON(t, http.MethodGet, url, nil, &details)
+		if accept(details) {
+			return details
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not reach the expected state: %+v", details)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunsStartAndList(t *testing.T) {
+	server := newRunsServer(t)
+
+	var workflows struct {
+		Workflows []string `json:"workflows"`
+	}
+	doJSON(t, http.MethodGet, server.URL+"/v1/workflows", nil, &workflows)
+	if strings.Join(workflows.Workflows, ",") != "approve,greet,wait" {
+		t.Errorf("unexpected workflows %v", workflows.Workflows)
+	}
+
+	var info RunInfo
+	status := doJSON(t, http.MethodPost, server.URL+"/v1/workflows/greet/runs", RunRequest{Inputs: map[string]interface{}{"name": "ann"}}, &info)
+	if status != http.StatusCreated || info.ID == "" {
+		t.Fatalf("unexpected start reply %d %+v", status, info)
+	}
+	details := waitForRun(t, server.URL+"/v1/runs/"+info.ID, func(d RunDetails) bool { return d.FinishedAt != nil })
+	if details.Status != swarm.WorkflowStatusComplete || details.Result.(map[string]interface{})["greeting"] != "hello ann" {
+		t.Errorf("unexpected run %+v", details)
+	}
+	if details.Metrics.Events == 0 || details.Metrics.EventCounts[swarm.EventStart] != 1 {
+		t.Errorf("unexpected metrics %+v", details.Metrics)
+	}
+
+	var list struct {
+		Runs []RunInfo `json:"runs"`
+	}
+	doJSON(t, http.MethodGet, server.URL+"/v1/runs?workflow=greet", nil, &list)
+	if len(list.Runs) != 1 || list.Runs[0].ID != info.ID {
+		t.Errorf("unexpected runs %+v", list.Runs)
+	}
+	doJSON(t, http.MethodGet, server.URL+"/v1/runs?workflow=wait", nil, &list)
+	if len(list.Runs) != 0 {
+		t.Errorf("unexpected runs %+v", list.Runs)
+	}
+
+	if status := doJSON(t, http.MethodPost, server.URL+"/v1/workflows/missing/runs", nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown workflow, got %d", status)
+	}
+	if status := doJSON(t, http.MethodGet, server.URL+"/v1/runs/missing", nil, nil); status != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown run, got %d", status)
+	}
+}
+
+func TestRunsPauseResumeCancel(t *testing.T) {
+	server := newRunsServer(t)
+	var info RunInfo
+	doJSON(t, http.MethodPost, server.URL+"/v1/workflows/wait/runs", nil, &info)
+	runURL := server.URL + "/v1/runs/" + info.ID
+	waitForRun(t, runURL, func(d RunDetails) bool { return d.Status == swarm.WorkflowStatusRunning })
+
+	if status := doJSON(t, http.MethodPost, runURL+"/pause", nil, &info); status != http.StatusOK || info.Status != swarm.WorkflowStatusPaused {
+		t.Fatalf("unexpected pause reply %d %+v", status, info)
+	}
+	if status := doJSON(t, http.MethodPost, runURL+"/pause", nil, nil); status != http.StatusConflict {
+		t.Errorf("expected status 409 pausing a paused run, got %d", status)
+	}
+	if status := doJSON(t, http.MethodPost, runURL+"/resume", nil, &info); status != http.StatusOK || info.Status != swarm.WorkflowStatusRunning {
+		t.Fatalf("unexpected resume reply %d %+v", status, info)
+	}
+	if status := doJSON(t, http.MethodPost, runURL+"/cancel", nil, &info); status != http.StatusOK || info.Status != swarm.WorkflowStatusCancelled || info.FinishedAt == nil {
+		t.Fatalf("unexpected cancel reply %d %+v", status, info)
+	}
+	if status := doJSON(t, http.MethodPost, runURL+"/resume", nil, nil); status != http.StatusConflict {
+		t.Errorf("expected status 409 resuming a cancelled run, got %d", status)
+	}
+}
+
+func TestRunsInputAndEvents(t *testing.T) {
+	server := newRunsServer(t)
+	var info RunInfo
+	doJSON(t, http.MethodPost, server.URL+"/v1/workflows/approve/runs", nil, &info)
+	runURL := server.URL + "/v1/runs/" + info.ID
+
+	details := waitForRun(t, runURL, func(d RunDetails) bool { return len(d.Metrics.PendingInputs) == 1 })
+	request := details.Metrics.PendingInputs[0]
+	if request.ToolCall == nil || request.ToolCall.Function.Name != "deploy" {
+		t.Errorf("unexpected input request %+v", request)
+	}
+	if details.Metrics.Progress["approve"] != 50 {
+		t.Errorf("unexpected progress %v", details.Metrics.Progress)
+	}
+
+	if status := doJSON(t, http.MethodPost, runURL+"/input", InputResponse{RequestID: "unknown"}, nil); status != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown input request, got %d", status)
+	}
+	if status := doJSON(t, http.MethodPost, runURL+"/input", InputResponse{RequestID: request.RequestID, Approved: true}, nil); status != http.StatusOK {
+		t.Fatalf("unexpected input reply %d", status)
+	}
+	details = waitForRun(t, runURL, func(d RunDetails) bool { return d.FinishedAt != nil })
+	if details.Result.(map[string]interface{})["approved"] != true || len(details.Metrics.PendingInputs) != 0 {
+		t.Errorf("unexpected run %+v", details)
+	}
+
+	resp, err := http.Get(runURL + "/events?type=InputRequiredEvent&type=StopEvent")
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	defer resp.Body.Close()
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	if strings.Join(events, ",") != "InputRequiredEvent,StopEvent,done" {
+		t.Errorf("unexpected events %v", events)
+	}
+}
@@ -0,0 +1,492 @@
+// Package server exposes swarm agents over HTTP, so they can back web UIs
+// and other services directly.
+//
+// Agents registered in the swarm's registry are served under
+// /v1/agents/{name}/chat. Each request carries one user message and an
+// optional session ID; the server keeps the conversation, the active agent
+// and the context variables of each session, and executes tool calls.
+// Replies are returned as JSON, or streamed as server-sent events when the
+// request sets "stream" or accepts text/event-stream.
+//
+// Chat frontends that need to interact during a run can instead connect to
+// /v1/agents/{name}/ws. The WebSocket carries typed WSMessages: content
+// deltas, tool calls and results, handoffs and the final response, as well
+// as approval requests for gated tools, which the client answers on the same
+// connection. Only same-origin WebSocket connections are accepted unless
+// WithCheckOrigin allows others.
+//
+// Existing OpenAI clients and chat UIs can use the agents without a custom
+// integration through the OpenAI-compatible /v1/models and
+// /v1/chat/completions endpoints, where the model name selects the agent.
+// These endpoints are stateless: each request carries the whole
+// conversation.
+//
+// Each agent is also an Agent-to-Agent (A2A) protocol agent at /a2a/{name},
+// publishing its card under /a2a/{name}/.well-known/agent-card.json. Other
+// A2A agents, including swarm agents using swarm.A2AClient, can submit tasks
+// to it with or without streaming; the A2A context ID selects the session.
+//
+// Workflows are served to other services over gRPC: WorkflowService
+// implements the WorkflowService of api/swarmv1 (see proto/swarm/v1) over
+// WorkflowRuns, which starts registered workflows by name and tracks their
+// runs and events. Webhooks configured with WithWebhook start them over HTTP
+// at /v1/workflows/{name}/trigger, optionally posting the outcome of each run
+// to a callback URL.
+//
+// Dashboards manage the runs of WithWorkflows over HTTP: /v1/workflows lists
+// the workflows and POST /v1/workflows/{name}/runs starts one; /v1/runs lists
+// the runs, and /v1/runs/{id} describes a run with its metrics, including
+// pending input requests. Under /v1/runs/{id}, "events" streams the run's
+// events as server-sent events, "cancel", "pause" and "resume" control it,
+// and "input" answers its input requests, such as tool call approvals.
+//
+// For Kubernetes deployments, /healthz is a liveness probe reporting the
+// number of sessions and of workflow runs in flight, and /readyz a readiness
+// probe that also runs the checks added with WithHealthCheck, and checks the
+// model provider when the swarm's client implements swarm.Pinger. It replies
+// 503 when a check fails.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultSessionTTL is how long an idle session is held in memory
+	DefaultSessionTTL = 30 * time.Minute
+	// DefaultMaxSessions is the number of sessions held in memory
+	DefaultMaxSessions = 1000
+)
+
+// ChatRequest is the body of a chat request.
+type ChatRequest struct {
+	// Message is the user message
+	Message string `json:"message"`
+	// SessionID continues a conversation; a new session is started if empty
+	SessionID string `json:"session_id,omitempty"`
+	// ContextVariables are merged into the session's context variables
+	ContextVariables map[string]interface{} `json:"context_variables,omitempty"`
+	// Stream requests a server-sent event stream
+	Stream bool `json:"stream,omitempty"`
+}
+
+// ChatResponse is the reply to a chat request, and the data of the final
+// "response" event of a stream.
+type ChatResponse struct {
+	// SessionID identifies the conversation for follow-up requests
+	SessionID string `json:"session_id"`
+	// Agent is the active agent after the reply
+	Agent string `json:"agent"`
+	// Content is the final reply
+	Content string `json:"content"`
+	// Messages are the messages added by the reply, including tool calls
+	Messages []map[string]interface{} `json:"messages"`
+	// ContextVariables are the session's context variables after the reply
+	ContextVariables map[string]interface{} `json:"context_variables,omitempty"`
+	// StopReason explains why the run ended
+	StopReason swarm.StopReason `json:"stop_reason,omitempty"`
+}
+
+// Server serves the agents of a swarm over HTTP. It implements http.Handler.
+type Server struct {
+	// Swarm runs the conversations; its registry provides the agents
+	Swarm *swarm.Swarm
+	// Store persists sessions (default: in memory). Sessions are stored under
+	// "<agent>/<id>", so a session ID only continues a conversation with the
+	// agent it was started with.
+	Store swarm.SessionStore
+	// MaxTurns limits the turns of each request (default swarm.DefaultSessionMaxTurns)
+	MaxTurns int
+	// ApprovalTimeout bounds the wait for WebSocket clients to approve tool
+	// calls (default DefaultApprovalTimeout)
+	ApprovalTimeout time.Duration
+	// Workflows are the workflows served by the run management endpoints and
+	// triggered by webhooks
+	Workflows *WorkflowRuns
+	// OnCallbackError is called when a run's callback cannot be delivered
+	OnCallbackError func(run *WorkflowRun, err error)
+	// HTTPClient sends webhook callbacks (default http.DefaultClient)
+	HTTPClient *http.Client
+	// HealthCheckTimeout bounds each readiness check (default
+	// DefaultHealthCheckTimeout)
+	HealthCheckTimeout time.Duration
+	// SessionTTL is how long an idle session is held in memory (default
+	// DefaultSessionTTL). Evicted sessions are loaded from the Store again,
+	// except with a MemorySessionStore, which forgets them too.
+	SessionTTL time.Duration
+	// MaxSessions limits the sessions held in memory, evicting the least
+	// recently used ones (default DefaultMaxSessions)
+	MaxSessions int
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	upgrader   websocket.Upgrader
+	mux        *http.ServeMux
+	sessions   map[string]*heldSession
+	a2aTasks   map[string]*a2aTask
+	webhooks   map[string]Webhook
+	signatures map[string]time.Time
+	checks     map[string]HealthCheck
+	mu         sync.Mutex
+}
+
+// New creates a server for the agents registered with s.
+func New(s *swarm.Swarm) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := &Server{
+		Swarm:      s,
+		Store:      swarm.NewMemorySessionStore(),
+		ctx:        ctx,
+		cancel:     cancel,
+		mux:        http.NewServeMux(),
+		sessions:   make(map[string]*heldSession),
+		a2aTasks:   make(map[string]*a2aTask),
+		webhooks:   make(map[string]Webhook),
+		signatures: make(map[string]time.Time),
+		checks:     make(map[string]HealthCheck),
+	}
+	srv.mux.HandleFunc("GET /healthz", srv.handleHealthz)
+	srv.mux.HandleFunc("GET /readyz", srv.handleReadyz)
+	srv.mux.HandleFunc("GET /v1/agents", srv.handleAgents)
+	srv.mux.HandleFunc("POST /v1/agents/{name}/chat", srv.handleChat)
+	srv.mux.HandleFunc("GET /v1/agents/{name}/ws", srv.handleWebSocket)
+	srv.mux.HandleFunc("GET /v1/models", srv.handleModels)
+	srv.mux.HandleFunc("POST /v1/chat/completions", srv.handleCompletions)
+	srv.mux.HandleFunc("GET /a2a/{name}"+swarm.A2AAgentCardPath, srv.handleAgentCard)
+	srv.mux.HandleFunc("POST /a2a/{name}", srv.handleA2A)
+	srv.mux.HandleFunc("GET /v1/workflows", srv.handleWorkflows)
+	srv.mux.HandleFunc("POST /v1/workflows/{name}/runs", srv.handleStartRun)
+	srv.mux.HandleFunc("POST /v1/workflows/{name}/trigger", srv.handleTrigger)
+	srv.mux.HandleFunc("GET /v1/runs", srv.handleRuns)
+	srv.mux.HandleFunc("GET /v1/runs/{id}", srv.handleRun)
+	srv.mux.HandleFunc("GET /v1/runs/{id}/events", srv.handleRunEvents)
+	srv.mux.HandleFunc("POST /v1/runs/{id}/cancel", srv.handleCancelRun)
+	srv.mux.HandleFunc("POST /v1/runs/{id}/pause", srv.handlePauseRun)
+	srv.mux.HandleFunc("POST /v1/runs/{id}/resume", srv.handleResumeRun)
+	srv.mux.HandleFunc("POST /v1/runs/{id}/input", srv.handleRunInput)
+	return srv
+}
+
+// Close stops the server's background work, such as pending webhook
+// callbacks. It does not stop the workflow runs or close the listener.
+func (s *Server) Close() {
+	s.cancel()
+}
+
+// WithStore sets the session store and returns the server.
+func (s *Server) WithStore(store swarm.SessionStore) *Server {
+	s.Store = store
+	return s
+}
+
+// WithCheckOrigin sets the function deciding which origins may open
+// WebSocket connections and returns the server.
+func (s *Server) WithCheckOrigin(check func(r *http.Request) bool) *Server {
+	s.upgrader.CheckOrigin = check
+	return s
+}
+
+// WithWorkflows sets the workflows served over HTTP and returns the server.
+func (s *Server) WithWorkflows(runs *WorkflowRuns) *Server {
+	s.Workflows = runs
+	return s
+}
+
+// WithMaxTurns sets the turn budget of each request and returns the server.
+func (s *Server) WithMaxTurns(maxTurns int) *Server {
+	s.MaxTurns = maxTurns
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleAgents lists the served agents.
+func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
+	registry := s.Swarm.Registry
+	if registry == nil {
+		registry = swarm.DefaultRegistry
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"agents": registry.Names()})
+}
+
+// handleChat answers a user message of a session.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.Swarm.Agent(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var req ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("message is required"))
+		return
+	}
+
+	session, release, err := s.session(req.SessionID, agent)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer release()
+	session.WithContextVariables(req.ContextVariables)
+
+	if req.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamChat(w, r, session, req.Message)
+		return
+	}
+	response, err := session.Send(r.Context(), req.Message)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newChatResponse(session, response))
+}
+
+// streamChat answers a user message as server-sent events: "delta" events
+// carrying each swarm.StreamChunk, including the reasoning, "content" events
+// with the complete text the deltas streamed, "tool_call" and "tool_result"
+// events while the run progresses, then a final "response" or "error" event.
+func (s *Server) streamChat(w http.ResponseWriter, r *http.Request, session *swarm.Session, message string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	chunks, err := session.Stream(r.Context(), message)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	completed := false
+	for chunk := range chunks {
+		event, data := streamEvent(chunk)
+		if event == "" {
+			continue
+		}
+		if response, ok := chunk["response"].(*swarm.Response); ok {
+			if event == "response" {
+				data = newChatResponse(session, response)
+			}
+			completed = true
+		}
+		writeEvent(w, event, data)
+		flusher.Flush()
+	}
+	if !completed {
+		writeEvent(w, "error", map[string]string{"error": "run failed"})
+		flusher.Flush()
+	}
+}
+
+// streamEvent maps a RunAndStream chunk to a server-sent event.
+func streamEvent(chunk map[string]interface{}) (string, interface{}) {
+	switch {
+	case chunk["error"] != nil:
+		return "error", map[string]interface{}{"error": chunk["error"]}
+	case chunk["response"] != nil:
+		return "response", nil
+	case chunk["tool_result"] != nil:
+		return "tool_result", chunk["tool_result"]
+	case chunk["chunk"] != nil:
+		return "delta", chunk["chunk"]
+	case chunk["tool_calls"] != nil:
+		return "tool_call", chunk
+	case chunk["content"] != nil:
+		return "content", chunk
+	}
+	return "", nil
+}
+
+// heldSession is a session held in memory, the time it was last used and the
+// number of requests using it.
+type heldSession struct {
+	session  *swarm.Session
+	lastUsed time.Time
+	users    int
+}
+
+// session returns the session with id of agent, loading it from the store or
+// starting it. An empty id starts a new session. The session is not evicted
+// until the returned release function is called.
+func (s *Server) session(id string, agent *swarm.Agent) (*swarm.Session, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictSessions(now)
+	held, ok := s.sessions[sessionKey(agent.Name, id)]
+	if !ok {
+		session, err := s.loadSession(id, agent)
+		if err != nil {
+			return nil, nil, err
+		}
+		held = &heldSession{session: session.WithMaxTurns(s.MaxTurns)}
+		s.sessions[sessionKey(agent.Name, session.ID)] = held
+	}
+	held.lastUsed = now
+	held.users++
+	release := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		held.users--
+		held.lastUsed = time.Now()
+	}
+	return held.session, release, nil
+}
+
+// loadSession loads the session with id of agent from the store, or starts it
+// if there is none.
+func (s *Server) loadSession(id string, agent *swarm.Agent) (*swarm.Session, error) {
+	store := agentStore{SessionStore: s.Store, agent: agent.Name}
+	if id != "" {
+		session, err := s.Swarm.LoadSession(store, id, agent)
+		if err == nil || !errors.Is(err, swarm.ErrSessionNotFound) {
+			return session, err
+		}
+	}
+	return s.Swarm.NewSession(agent, id).WithStore(store), nil
+}
+
+// sessionKey returns the key of the session with id of the named agent.
+func sessionKey(agent, id string) string {
+	return url.PathEscape(agent) + "/" + id
+}
+
+// agentStore is a SessionStore scoped to the sessions of one agent.
+type agentStore struct {
+	swarm.SessionStore
+	agent string
+}
+
+// Save stores the state under the key scoped to the agent.
+func (a agentStore) Save(state *swarm.SessionState) error {
+	scoped := *state
+	scoped.ID = sessionKey(a.agent, state.ID)
+	return a.SessionStore.Save(&scoped)
+}
+
+// Load returns the state of the agent's session with id.
+func (a agentStore) Load(id string) (*swarm.SessionState, error) {
+	state, err := a.SessionStore.Load(sessionKey(a.agent, id))
+	if err != nil {
+		return nil, err
+	}
+	scoped := *state
+	scoped.ID = id
+	return &scoped, nil
+}
+
+// Delete removes the agent's session with id.
+func (a agentStore) Delete(id string) error {
+	return a.SessionStore.Delete(sessionKey(a.agent, id))
+}
+
+// evictSessions drops the sessions idle for longer than the SessionTTL, then
+// the least recently used ones until a new session fits in MaxSessions.
+// Sessions used by a request are kept, so a run in flight is never evicted
+// before it commits. The caller must hold s.mu.
+func (s *Server) evictSessions(now time.Time) {
+	ttl := s.SessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	limit := s.MaxSessions
+	if limit <= 0 {
+		limit = DefaultMaxSessions
+	}
+	for id, held := range s.sessions {
+		if held.users == 0 && now.Sub(held.lastUsed) > ttl {
+			s.evictSession(id)
+		}
+	}
+	for len(s.sessions) >= limit {
+		var oldest string
+		for id, held := range s.sessions {
+			if held.users == 0 && (oldest == "" || held.lastUsed.Before(s.sessions[oldest].lastUsed)) {
+				oldest = id
+			}
+		}
+		if oldest == "" {
+			// Every session is in use
+			return
+		}
+		s.evictSession(oldest)
+	}
+}
+
+// evictSession drops a session from memory. The caller must hold s.mu.
+func (s *Server) evictSession(id string) {
+	delete(s.sessions, id)
+	if store, ok := s.Store.(*swarm.MemorySessionStore); ok {
+		store.Delete(id)
+	}
+}
+
+// newChatResponse describes the outcome of a chat request.
+func newChatResponse(session *swarm.Session, response *swarm.Response) *ChatResponse {
+	state := session.State()
+	return &ChatResponse{
+		SessionID:        state.ID,
+		Agent:            state.Agent,
+		Content:          finalContent(response.Messages),
+		Messages:         response.Messages,
+		ContextVariables: state.ContextVariables,
+		StopReason:       response.StopReason,
+	}
+}
+
+// finalContent returns the last non-empty content of messages.
+func finalContent(messages []map[string]interface{}) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if text, ok := messages[i]["content"].(string); ok && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeEvent writes a server-sent event with JSON data.
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{"error": err.Error()})
+		event = "error"
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/feiskyer/swarm-go/fakellm"
+)
+
+// newFakeLLM scripts replies naming the prompt and the number of messages
+// in the request, so tests can check that sessions carry their history. A
+// "call delete_pod" prompt is answered with a call to the tool.
+func newFakeLLM() *fakellm.Client {
+	llm := fakellm.New()
+	llm.When(fakellm.ToolResult("delete_pod")).Reply("echo deleted (4 messages)")
+	llm.When(fakellm.PromptContains("call delete_pod")).CallTool("delete_pod", map[string]interface{}{})
+	for _, turn := range []struct {
+		prompt   string
+		messages int
+	}{{"hello", 2}, {"again", 4}, {"how are you", 4}, {"thanks", 6}} {
+		llm.When(fakellm.All(fakellm.PromptContains(turn.prompt), messageCount(turn.messages))).
+			Reply(fmt.Sprintf("echo %s (%d messages)", turn.prompt, turn.messages))
+	}
+	return llm
+}
+
+// messageCount matches requests with n messages.
+func messageCount(n int) fakellm.Matcher {
+	return func(req fakellm.Request) bool { return len(req.Messages) == n }
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	registry := swarm.NewAgentRegistry().MustRegister(swarm.NewAgent("assistant").WithModel("gpt-4o"))
+	client := swarm.NewSwarm(newFakeLLM()).WithRegistry(registry)
+	server := httptest.NewServer(New(client))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postChat(t *testing.T, url string, req ChatRequest) *http.Response {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	return resp
+}
+
+func TestChat(t *testing.T) {
+	server := newTestServer(t)
+	url := server.URL + "/v1/agents/assistant/chat"
+
+	resp := postChat(t, url, ChatRequest{Message: "hello", ContextVariables: map[string]interface{}{"user": "ann"}})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	var first ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&first); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if first.SessionID == "" || first.Agent != "assistant" || first.Content != "echo hello (2 messages)" {
+		t.Errorf("unexpected response: %+v", first)
+	}
+	if first.ContextVariables["user"] != "ann" {
+		t.Errorf("expected context variables to be kept, got %v", first.ContextVariables)
+	}
+
+	// The session carries the conversation into the next request
+	resp = postChat(t, url, ChatRequest{Message: "again", SessionID: first.SessionID})
+	defer resp.Body.Close()
+	var second ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&second); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if second.SessionID != first.SessionID || second.Content != "echo again (4 messages)" {
+		t.Errorf("unexpected follow-up response: %+v", second)
+	}
+}
+
+func TestChatSessionEviction(t *testing.T) {
+	registry := swarm.NewAgentRegistry().MustRegister(swarm.NewAgent("assistant").WithModel("gpt-4o"))
+	client := swarm.NewSwarm(newFakeLLM()).WithRegistry(registry)
+	srv := New(client).WithStore(swarm.NewFileSessionStore(t.TempDir()))
+	srv.MaxSessions = 2
+	server := httptest.NewServer(srv)
+	defer server.Close()
+	url := server.URL + "/v1/agents/assistant/chat"
+
+	chat := func(req ChatRequest) ChatResponse {
+		resp := postChat(t, url, req)
+		defer resp.Body.Close()
+		var chatResp ChatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return chatResp
+	}
+	first := chat(ChatRequest{Message: "hello"})
+	for i := 0; i < 3; i++ {
+		chat(ChatRequest{Message: "hello"})
+	}
+	if sessions := len(srv.sessions); sessions != 2 {
+		t.Errorf("expected 2 sessions in memory, got %d", sessions)
+	}
+
+	// An evicted session is loaded from the store again
+	if again := chat(ChatRequest{Message: "again", SessionID: first.SessionID}); again.Content != "echo again (4 messages)" {
+		t.Errorf("unexpected response for an evicted session: %+v", again)
+	}
+}
+
+func TestSessionEvictionSkipsSessionsInUse(t *testing.T) {
+	agent := swarm.NewAgent("assistant").WithModel("gpt-4o")
+	srv := New(swarm.NewSwarm(newFakeLLM()))
+	srv.MaxSessions = 1
+	srv.SessionTTL = time.Nanosecond
+
+	busy, release, err := srv.session("", agent)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	_, releaseOther, err := srv.session("", agent)
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	if _, ok := srv.sessions[sessionKey(agent.Name, busy.ID)]; !ok {
+		t.Error("expected a session in use to be kept")
+	}
+
+	release()
+	releaseOther()
+	time.Sleep(time.Millisecond)
+	if _, release, err = srv.session("", agent); err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	defer release()
+	if _, ok := srv.sessions[sessionKey(agent.Name, busy.ID)]; ok {
+		t.Error("expected an idle session to be evicted once released")
+	}
+}
+
+func TestChatSessionScopedToAgent(t *testing.T) {
+	registry := swarm.NewAgentRegistry().MustRegister(
+		swarm.NewAgent("assistant").WithModel("gpt-4o"),
+		swarm.NewAgent("admin").WithModel("gpt-4o"),
+	)
+	client := swarm.NewSwarm(newFakeLLM()).WithRegistry(registry)
+	server := httptest.NewServer(New(client))
+	defer server.Close()
+
+	chat := func(agent string, req ChatRequest) ChatResponse {
+		resp := postChat(t, server.URL+"/v1/agents/"+agent+"/chat", req)
+		defer resp.Body.Close()
+		var chatResp ChatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return chatResp
+	}
+	first := chat("assistant", ChatRequest{Message: "hello"})
+
+	// Another agent reusing the session ID starts its own conversation
+	other := chat("admin", ChatRequest{Message: "hello", SessionID: first.SessionID})
+	if other.Agent != "admin" || other.Content != "echo hello (2 messages)" {
+		t.Errorf("expected a new conversation with admin, got %+v", other)
+	}
+	if again := chat("assistant", ChatRequest{Message: "again", SessionID: first.SessionID}); again.Content != "echo again (4 messages)" {
+		t.Errorf("expected the assistant's conversation to continue, got %+v", again)
+	}
+}
+
+func TestChatErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postChat(t, server.URL+"/v1/agents/nobody/chat", ChatRequest{Message: "hi"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown agent, got %d", resp.StatusCode)
+	}
+
+	resp = postChat(t, server.URL+"/v1/agents/assistant/chat", ChatRequest{})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a message, got %d", resp.StatusCode)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postChat(t, server.URL+"/v1/agents/assistant/chat", ChatRequest{Message: "hello", Stream: true})
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	var events []string
+	var content strings.Builder
+	var final ChatResponse
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+			events = append(events, event)
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			switch event {
+			case "content":
+				var chunk map[string]interface{}
+				json.Unmarshal([]byte(data), &chunk)
+				content.WriteString(chunk["content"].(string))
+			case "response":
+				json.Unmarshal([]byte(data), &final)
+			}
+		}
+	}
+
+	if events[len(events)-1] != "response" {
+		t.Errorf("expected the stream to end with a response event, got %v", events)
+	}
+	if content.String() != "echo hello (2 messages)" || final.Content != content.String() {
+		t.Errorf("unexpected streamed content %q, final %q", content.String(), final.Content)
+	}
+	if final.SessionID == "" {
+		t.Error("expected a session ID in the final response")
+	}
+}
+
+func TestListAgents(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/v1/agents")
+	if err != nil {
+		t.Fatalf("GET /v1/agents: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Agents []string `json:"agents"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if strings.Join(body.Agents, ",") != "assistant" {
+		t.Errorf("expected [assistant], got %v", body.Agents)
+	}
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SignatureHeader carries the signature of webhook requests and callbacks as
+// "sha256=<hex>", computed by Signature.
+const SignatureHeader = "X-Signature-256"
+
+// TimestampHeader carries the Unix time at which a webhook request or callback
+// was signed.
+const TimestampHeader = "X-Signature-Timestamp"
+
+// DefaultWebhookTolerance is how far the timestamp of a signed webhook request
+// may be from the server's clock.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// CallbackURLHeader overrides the callback URL of a triggered run when the
+// webhook allows it.
+const CallbackURLHeader = "X-Callback-URL"
+
+// maxWebhookPayload bounds the size of webhook payloads.
+const maxWebhookPayload = 10 << 20
+
+// callbackAttempts is how often a callback is tried before giving up, and
+// callbackRetryDelay the delay before the first retry, doubled after each.
+var (
+	callbackAttempts   = 3
+	callbackRetryDelay = time.Second
+)
+
+// Webhook configures how incoming webhooks trigger a workflow.
+type Webhook struct {
+	// Secret, if set, requires requests to be signed in SignatureHeader and
+	// TimestampHeader, and signs callbacks the same way. Each signature is
+	// accepted once, within the Tolerance.
+	Secret string
+	// Tolerance bounds the age of signed requests (default
+	// DefaultWebhookTolerance)
+	Tolerance time.Duration
+	// Inputs maps a webhook request and its payload to the StartEvent inputs.
+	// By default a JSON object payload is used as the inputs, and any other
+	// payload is passed as "payload".
+	Inputs func(r *http.Request, payload []byte) (map[string]interface{}, error)
+	// CallbackURL receives the outcome of each triggered run as a JSON POST
+	CallbackURL string
+	// AllowCallbackURL lets requests choose the callback URL with
+	// CallbackURLHeader. With a Secret, the URL is covered by the signature.
+	AllowCallbackURL bool
+}
+
+// WithWebhook lets webhooks trigger the named workflow of the server's
+// workflow runs at POST /v1/workflows/{name}/trigger, and returns the server.
+func (s *Server) WithWebhook(workflow string, hook Webhook) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks[workflow] = hook
+	return s
+}
+
+// handleTrigger starts a workflow run from a webhook and replies with the
+// run without waiting for it.
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s.mu.Lock()
+	hook, ok := s.webhooks[name]
+	s.mu.Unlock()
+	if !ok || s.Workflows == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrWorkflowNotFound, name))
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayload))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read payload: %w", err))
+		return
+	}
+	if hook.Secret != "" {
+		if err := s.verifyWebhook(r, hook, payload); err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+	}
+	mapInputs := hook.Inputs
+	if mapInputs == nil {
+		mapInputs = webhookInputs
+	}
+	inputs, err := mapInputs(r, payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %w", err))
+		return
+	}
+	callbackURL := hook.CallbackURL
+	if url := r.Header.Get(CallbackURLHeader); url != "" {
+		if !hook.AllowCallbackURL {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s is not allowed for workflow %s", CallbackURLHeader, name))
+			return
+		}
+		callbackURL = url
+	}
+
+	run, err := s.Workflows.Start(name, inputs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if callbackURL != "" {
+		go s.callback(run, callbackURL, hook.Secret)
+	}
+	writeJSON(w, http.StatusAccepted, run.Info())
+}
+
+// verifyWebhook checks the signature of a webhook request, rejecting stale
+// timestamps and signatures already used.
+func (s *Server) verifyWebhook(r *http.Request, hook Webhook, payload []byte) error {
+	tolerance := hook.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	unix, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s", TimestampHeader)
+	}
+	now := time.Now()
+	timestamp := time.Unix(unix, 0)
+	if timestamp.Before(now.Add(-tolerance)) || timestamp.After(now.Add(tolerance)) {
+		return fmt.Errorf("%s is outside the tolerance", TimestampHeader)
+	}
+	signature := r.Header.Get(SignatureHeader)
+	want := Signature(hook.Secret, timestamp, r.Header.Get(CallbackURLHeader), payload)
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return errors.New("invalid payload signature")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for seen, expiry := range s.signatures {
+		if now.After(expiry) {
+			delete(s.signatures, seen)
+		}
+	}
+	if _, ok := s.signatures[signature]; ok {
+		return errors.New("replayed payload signature")
+	}
+	s.signatures[signature] = timestamp.Add(tolerance)
+	return nil
+}
+
+// callback posts the outcome of a run to url once it finishes, retrying
+// failed deliveries until the server is closed.
+func (s *Server) callback(run *WorkflowRun, url, secret string) {
+	select {
+	case <-run.Done():
+	case <-s.ctx.Done():
+		return
+	}
+	payload, err := json.Marshal(run.Info())
+	if err != nil {
+		return
+	}
+	delay := callbackRetryDelay
+	for attempt := 1; attempt <= callbackAttempts; attempt++ {
+		if err = s.postCallback(url, secret, payload); err == nil {
+			return
+		}
+		if attempt == callbackAttempts {
+			break
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			delay *= 2
+			continue
+		case <-s.ctx.Done():
+			timer.Stop()
+			err = fmt.Errorf("callback to %s: %w", url, s.ctx.Err())
+		}
+		break
+	}
+	if s.OnCallbackError != nil {
+		s.OnCallbackError(run, err)
+	}
+}
+
+// postCallback delivers a callback payload.
+func (s *Server) postCallback(url, secret string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		now := time.Now()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(SignatureHeader, Signature(secret, now, url, payload))
+	}
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback to %s failed: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// webhookInputs is the default mapping of webhook payloads to inputs.
+func webhookInputs(r *http.Request, payload []byte) (map[string]interface{}, error) {
+	var inputs map[string]interface{}
+	if err := json.Unmarshal(payload, &inputs); err == nil && inputs != nil {
+		return inputs, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err == nil {
+		return map[string]interface{}{"payload": value}, nil
+	}
+	return map[string]interface{}{"payload": string(payload)}, nil
+}
+
+// Signature returns the SignatureHeader value of a webhook request or callback:
+// the HMAC-SHA256 of its Unix timestamp, callback URL and payload, separated by
+// newlines. The callback URL of a request is its CallbackURLHeader, empty if
+// not set, and that of a callback the URL it is posted to.
+func Signature(secret string, timestamp time.Time, callbackURL string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s\n", timestamp.Unix(), callbackURL)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
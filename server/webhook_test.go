@@ -0,0 +1,213 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newWebhookServer(t *testing.T, hook Webhook) *httptest.Server {
+	t.Helper()
+	srv := New(nil).WithWorkflows(newTestRuns()).WithWebhook("greet", hook)
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func trigger(t *testing.T, url string, payload []byte, header http.Header) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/workflows/greet/trigger", bytes.NewReader(payload))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	return resp
+}
+
+// signed returns the headers of a webhook request signed now.
+func signed(secret, callbackURL string, payload []byte) http.Header {
+	now := time.Now()
+	header := http.Header{
+		TimestampHeader: {strconv.FormatInt(now.Unix(), 10)},
+		SignatureHeader: {Signature(secret, now, callbackURL, payload)},
+	}
+	if callbackURL != "" {
+		header.Set(CallbackURLHeader, callbackURL)
+	}
+	return header
+}
+
+func TestWebhookTriggerAndCallback(t *testing.T) {
+	callbackRetryDelay = time.Millisecond
+	deliveries := make(chan RunInfo, 1)
+	failures := 1
+	var receiver *httptest.Server
+	receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first delivery fails and is retried
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var info RunInfo
+		json.Unmarshal(body, &info)
+		unix, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if r.Header.Get(SignatureHeader) != Signature("s3cret", time.Unix(unix, 0), receiver.URL, body) {
+			t.Errorf("unexpected callback signature %q", r.Header.Get(SignatureHeader))
+		}
+		deliveries <- info
+	}))
+	defer receiver.Close()
+	server := newWebhookServer(t, Webhook{Secret: "s3cret", CallbackURL: receiver.URL})
+
+	payload := []byte(`{"name":"ann"}`)
+	resp := trigger(t, server.URL, payload, signed("s3cret", "", payload))
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", resp.StatusCode)
+	}
+	var started RunInfo
+	json.NewDecoder(resp.Body).Decode(&started)
+	if started.ID == "" || started.Workflow != "greet" || started.Inputs["name"] != "ann" {
+		t.Errorf("unexpected run %+v", started)
+	}
+
+	select {
+	case info := <-deliveries:
+		result, _ := info.Result.(map[string]interface{})
+		if info.ID != started.ID || info.Status != "complete" || result["greeting"] != "hello ann" || info.FinishedAt == nil {
+			t.Errorf("unexpected callback %+v", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not delivered")
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	server := newWebhookServer(t, Webhook{Secret: "s3cret"})
+	payload := []byte(`{"name":"ann"}`)
+
+	resp := trigger(t, server.URL, payload, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without a signature, got %d", resp.StatusCode)
+	}
+
+	resp = trigger(t, server.URL, payload, signed("s3cret", "http://example.com", payload))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a disallowed callback URL, got %d", resp.StatusCode)
+	}
+
+	stale := signed("s3cret", "", payload)
+	past := time.Now().Add(-time.Hour)
+	stale.Set(TimestampHeader, strconv.FormatInt(past.Unix(), 10))
+	stale.Set(SignatureHeader, Signature("s3cret", past, "", payload))
+	resp = trigger(t, server.URL, payload, stale)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a stale signature, got %d", resp.StatusCode)
+	}
+
+	resp, err := http.Post(server.URL+"/v1/workflows/wait/trigger", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for a workflow without webhook, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookReplay(t *testing.T) {
+	server := newWebhookServer(t, Webhook{Secret: "s3cret", AllowCallbackURL: true})
+	payload := []byte(`{"name":"ann"}`)
+
+	header := signed("s3cret", "", payload)
+	resp := trigger(t, server.URL, payload, header)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", resp.StatusCode)
+	}
+	resp = trigger(t, server.URL, payload, header)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a replayed request, got %d", resp.StatusCode)
+	}
+
+	// The callback URL is covered by the signature
+	redirected := signed("s3cret", "", payload)
+	redirected.Set(CallbackURLHeader, "http://attacker.example.com")
+	resp = trigger(t, server.URL, payload, redirected)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status 401 for a callback URL not signed, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookCallbackStopsOnClose(t *testing.T) {
+	defer func(delay time.Duration) { callbackRetryDelay = delay }(callbackRetryDelay)
+	callbackRetryDelay = time.Hour
+	attempts := make(chan struct{}, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		attempts <- struct{}{}
+	}))
+	defer receiver.Close()
+
+	failures := make(chan error, 1)
+	srv := New(nil).WithWorkflows(newTestRuns()).WithWebhook("greet", Webhook{CallbackURL: receiver.URL})
+	srv.HTTPClient = receiver.Client()
+	srv.OnCallbackError = func(run *WorkflowRun, err error) { failures <- err }
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	resp := trigger(t, server.URL, []byte(`{"name":"ann"}`), nil)
+	resp.Body.Close()
+	select {
+	case <-attempts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not attempted")
+	}
+
+	// Closing the server stops the wait for the next retry
+	srv.Close()
+	select {
+	case err := <-failures:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the callback to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback retries did not stop")
+	}
+}
+
+func TestWebhookInputs(t *testing.T) {
+	for payload, want := range map[string]interface{}{
+		`{"name":"ann"}`: "ann",
+		`[1,2]`:          nil,
+		`plain text`:     nil,
+	} {
+		inputs, err := webhookInputs(nil, []byte(payload))
+		if err != nil {
+			t.Fatalf("%s: %v", payload, err)
+		}
+		if want != nil && inputs["name"] != want {
+			t.Errorf("%s: expected the object as inputs, got %v", payload, inputs)
+		}
+		if want == nil && inputs["payload"] == nil {
+			t.Errorf("%s: expected the payload under \"payload\", got %v", payload, inputs)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// pongWait is how long a WebSocket connection may stay silent before it
+	// is considered dead
+	pongWait = 60 * time.Second
+	// pingPeriod is the interval of keepalive pings, shorter than pongWait
+	pingPeriod = pongWait * 9 / 10
+	// writeWait bounds the time to write a WebSocket frame
+	writeWait = 10 * time.Second
+	// DefaultApprovalTimeout is how long a WebSocket run waits for the
+	// client to answer an approval request before denying the call
+	DefaultApprovalTimeout = 5 * time.Minute
+)
+
+// Types of WebSocket messages.
+const (
+	// MessageUser is sent by the client with a user message
+	MessageUser = "message"
+	// MessageApproval is sent by the client to answer an approval request
+	MessageApproval = "approval"
+	// MessageContent carries a content delta
+	MessageContent = "content"
+	// MessageReasoning carries a reasoning delta
+	MessageReasoning = "reasoning"
+	// MessageToolCall announces tool calls
+	MessageToolCall = "tool_call"
+	// MessageToolResult carries the outcome of a tool call
+	MessageToolResult = "tool_result"
+	// MessageHandoff reports a transfer between agents
+	MessageHandoff = "handoff"
+	// MessageApprovalRequest asks the client to approve a tool call
+	MessageApprovalRequest = "approval_request"
+	// MessageResponse carries the final ChatResponse of a run
+	MessageResponse = "response"
+	// MessageError reports a failure
+	MessageError = "error"
+)
+
+// WSMessage is a message of the WebSocket protocol. Clients send "message"
+// and "approval" messages; the server answers with the other types.
+type WSMessage struct {
+	// Type is the kind of message
+	Type string `json:"type"`
+	// Message is the user message of a "message"
+	Message string `json:"message,omitempty"`
+	// SessionID selects the conversation of a "message"; the connection's
+	// current session is used if empty
+	SessionID string `json:"session_id,omitempty"`
+	// ContextVariables are merged into the session by a "message"
+	ContextVariables map[string]interface{} `json:"context_variables,omitempty"`
+	// RequestID correlates approval requests and answers
+	RequestID string `json:"request_id,omitempty"`
+	// Approved is the decision of an "approval"
+	Approved bool `json:"approved,omitempty"`
+	// Content is the delta of "content" and "reasoning" messages
+	Content string `json:"content,omitempty"`
+	// Sender is the agent producing a delta or tool call
+	Sender string `json:"sender,omitempty"`
+	// ToolCall is the call announced by "tool_call" or awaiting approval
+	ToolCall *swarm.ToolCall `json:"tool_call,omitempty"`
+	// ToolResult is the outcome carried by "tool_result"
+	ToolResult *swarm.ToolResult `json:"tool_result,omitempty"`
+	// Handoff is the transfer reported by "handoff"
+	Handoff *swarm.HandoffRecord `json:"handoff,omitempty"`
+	// Response is the outcome carried by "response"
+	Response *ChatResponse `json:"response,omitempty"`
+	// Error describes the failure of an "error"
+	Error string `json:"error,omitempty"`
+}
+
+// wsConn is a WebSocket connection serving one conversation at a time.
+type wsConn struct {
+	server  *Server
+	conn    *websocket.Conn
+	agent   *swarm.Agent
+	session *swarm.Session
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	running bool
+	pending map[string]chan bool
+}
+
+// handleWebSocket serves chats with an agent over a WebSocket connection.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	agent, err := s.Swarm.Agent(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client
+		return
+	}
+	c := &wsConn{server: s, conn: conn, agent: agent, pending: make(map[string]chan bool)}
+	c.serve(r.Context())
+}
+
+// serve reads client messages until the connection closes, keeping it alive
+// with pings.
+func (c *wsConn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer c.conn.Close()
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	go c.keepalive(ctx)
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg WSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.send(WSMessage{Type: MessageError, Error: fmt.Sprintf("invalid message: %v", err)})
+			continue
+		}
+
+		switch msg.Type {
+		case MessageUser:
+			c.startRun(ctx, msg)
+		case MessageApproval:
+			c.answer(msg.RequestID, msg.Approved)
+		default:
+			c.send(WSMessage{Type: MessageError, Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+// keepalive pings the client until ctx is done.
+func (c *wsConn) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.writeMu.Lock()
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+			c.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startRun answers a user message in the background, so approval answers
+// can be read while the run waits for them.
+func (c *wsConn) startRun(ctx context.Context, msg WSMessage) {
+	if strings.TrimSpace(msg.Message) == "" {
+		c.send(WSMessage{Type: MessageError, Error: "message is required"})
+		return
+	}
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		c.send(WSMessage{Type: MessageError, Error: "a run is already in progress"})
+		return
+	}
+	c.running = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			c.running = false
+			c.mu.Unlock()
+		}()
+		if err := c.run(ctx, msg); err != nil {
+			c.send(WSMessage{Type: MessageError, Error: err.Error()})
+		}
+	}()
+}
+
+// run streams the reply to a user message.
+func (c *wsConn) run(ctx context.Context, msg WSMessage) error {
+	// Each run holds the session, so it is not evicted while the run is in
+	// flight; between runs the connection continues it by ID
+	id := msg.SessionID
+	if id == "" && c.session != nil {
+		id = c.session.ID
+	}
+	session, release, err := c.server.session(id, c.agent)
+	if err != nil {
+		return err
+	}
+	defer release()
+	c.session = session
+	c.session.WithContextVariables(msg.ContextVariables)
+
+	chunks, err := c.session.Stream(swarm.WithApprovalHandler(ctx, c.approve(ctx)), msg.Message)
+	if err != nil {
+		return err
+	}
+	completed := false
+	for chunk := range chunks {
+		if errMsg, ok := chunk["error"].(string); ok {
+			c.send(WSMessage{Type: MessageError, Error: errMsg})
+		}
+		switch {
+		case chunk["response"] != nil:
+			response := chunk["response"].(*swarm.Response)
+			if response.Error != nil {
+				// Already reported as an error message
+				completed = true
+				continue
+			}
+			for i := range response.Handoffs {
+				c.send(WSMessage{Type: MessageHandoff, Handoff: &response.Handoffs[i]})
+			}
+			c.send(WSMessage{Type: MessageResponse, Response: newChatResponse(c.session, response)})
+			completed = true
+		case chunk["tool_result"] != nil:
+			result := chunk["tool_result"].(*swarm.ToolResult)
+			c.send(WSMessage{Type: MessageToolResult, ToolResult: result, Sender: senderOf(chunk)})
+		case chunk["tool_calls"] != nil:
+			for _, call := range chunk["tool_calls"].([]map[string]interface{}) {
+				function, _ := call["function"].(map[string]interface{})
+				name, _ := function["name"].(string)
+				arguments, _ := function["arguments"].(string)
+				c.send(WSMessage{Type: MessageToolCall, Sender: senderOf(chunk), ToolCall: &swarm.ToolCall{
+					Function: swarm.Function{Name: name, Arguments: arguments},
+				}})
+			}
+		case chunk["chunk"] != nil:
+			if delta, _ := chunk["chunk"].(*swarm.StreamChunk); delta != nil && delta.Type == swarm.StreamChunkReasoning {
+				c.send(WSMessage{Type: MessageReasoning, Content: delta.Delta, Sender: delta.Sender})
+			}
+		case chunk["content"] != nil:
+			content, _ := chunk["content"].(string)
+			c.send(WSMessage{Type: MessageContent, Content: content, Sender: senderOf(chunk)})
+		}
+	}
+	if !completed {
+		return errors.New("run failed")
+	}
+	return nil
+}
+
+// approve returns an approval handler asking the client to approve tool
+// calls. Calls are denied if the client does not answer in time.
+func (c *wsConn) approve(ctx context.Context) swarm.ApprovalFunc {
+	return func(call swarm.ToolCall) (bool, error) {
+		requestID := swarm.NewID("approval-")
+		answer := make(chan bool, 1)
+		c.mu.Lock()
+		c.pending[requestID] = answer
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.pending, requestID)
+			c.mu.Unlock()
+		}()
+
+		if err := c.send(WSMessage{Type: MessageApprovalRequest, RequestID: requestID, ToolCall: &call}); err != nil {
+			return false, err
+		}
+		timeout := c.server.ApprovalTimeout
+		if timeout <= 0 {
+			timeout = DefaultApprovalTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case approved := <-answer:
+			return approved, nil
+		case <-timer.C:
+			return false, fmt.Errorf("approval timed out after %s", timeout)
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// answer delivers the client's decision on an approval request.
+func (c *wsConn) answer(requestID string, approved bool) {
+	c.mu.Lock()
+	answer, ok := c.pending[requestID]
+	c.mu.Unlock()
+	if !ok {
+		c.send(WSMessage{Type: MessageError, Error: fmt.Sprintf("unknown approval request %q", requestID)})
+		return
+	}
+	select {
+	case answer <- approved:
+	default:
+	}
+}
+
+// send writes a message to the client.
+func (c *wsConn) send(msg WSMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// senderOf returns the sender of a stream chunk.
+func senderOf(chunk map[string]interface{}) string {
+	sender, _ := chunk["sender"].(string)
+	return sender
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/gorilla/websocket"
+)
+
+func newWebSocketServer(t *testing.T, calls *int) *httptest.Server {
+	t.Helper()
+	ops := swarm.NewAgent("ops").WithModel("gpt-4o").AddFunction(swarm.NewAgentFunction("delete_pod", "Delete a pod",
+		func(args map[string]interface{}) (interface{}, error) {
+			*calls++
+			return "deleted", nil
+		},
+		[]swarm.Parameter{},
+	)).RequireApproval(func(call swarm.ToolCall) (bool, error) {
+		return false, nil
+	})
+	registry := swarm.NewAgentRegistry().MustRegister(ops)
+	client := swarm.NewSwarm(newFakeLLM()).WithRegistry(registry)
+	server := httptest.NewServer(New(client))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return conn
+}
+
+// readUntil reads messages until one of the given type arrives, returning
+// the types of all messages read.
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) ([]string, WSMessage) {
+	t.Helper()
+	var types []string
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read: %v (after %v)", err, types)
+		}
+		types = append(types, msg.Type)
+		if msg.Type == msgType {
+			return types, msg
+		}
+	}
+}
+
+func TestWebSocketApproval(t *testing.T) {
+	calls := 0
+	conn := dialWebSocket(t, newWebSocketServer(t, &calls), "/v1/agents/ops/ws")
+
+	if err := conn.WriteJSON(WSMessage{Type: MessageUser, Message: "call delete_pod"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	types, request := readUntil(t, conn, MessageApprovalRequest)
+	if request.ToolCall == nil || request.ToolCall.Function.Name != "delete_pod" {
+		t.Fatalf("unexpected approval request: %+v", request)
+	}
+
+	// The client's approval overrides the agent's deny-all hook
+	if err := conn.WriteJSON(WSMessage{Type: MessageApproval, RequestID: request.RequestID, Approved: true}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	rest, final := readUntil(t, conn, MessageResponse)
+	// The tool call is announced while the approval is pending, so it may
+	// arrive on either side of the request
+	types = append(types, rest...)
+	if !slices.Contains(types, MessageToolCall) || !slices.Contains(types, MessageToolResult) || !slices.Contains(types, MessageContent) {
+		t.Errorf("expected tool call, tool result and content messages, got %v", types)
+	}
+	if calls != 1 {
+		t.Errorf("expected the approved tool to run once, ran %d times", calls)
+	}
+	if final.Response == nil || final.Response.Content != "echo deleted (4 messages)" {
+		t.Errorf("unexpected final response: %+v", final.Response)
+	}
+
+	// Follow-up messages continue the connection's session
+	if err := conn.WriteJSON(WSMessage{Type: MessageUser, Message: "thanks"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, next := readUntil(t, conn, MessageResponse)
+	if next.Response.SessionID != final.Response.SessionID || next.Response.Content != "echo thanks (6 messages)" {
+		t.Errorf("unexpected follow-up response: %+v", next.Response)
+	}
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	calls := 0
+	server := newWebSocketServer(t, &calls)
+	conn := dialWebSocket(t, server, "/v1/agents/ops/ws")
+
+	conn.WriteJSON(WSMessage{Type: "bogus"})
+	if _, msg := readUntil(t, conn, MessageError); !strings.Contains(msg.Error, "unknown message type") {
+		t.Errorf("unexpected error: %q", msg.Error)
+	}
+	conn.WriteJSON(WSMessage{Type: MessageApproval, RequestID: "missing", Approved: true})
+	if _, msg := readUntil(t, conn, MessageError); !strings.Contains(msg.Error, "unknown approval request") {
+		t.Errorf("unexpected error: %q", msg.Error)
+	}
+
+	if _, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/agents/nobody/ws", nil); err == nil {
+		t.Error("expected connecting to an unknown agent to fail")
+	}
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+)
+
+var (
+	// ErrWorkflowNotFound is returned when starting an unregistered workflow
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	// ErrRunNotFound is returned for unknown run IDs
+	ErrRunNotFound = errors.New("workflow run not found")
+	// ErrInputNotFound is returned when answering an input request that is
+	// not pending
+	ErrInputNotFound = errors.New("input request not found")
+)
+
+// WorkflowFactory builds a fresh workflow for each run.
+type WorkflowFactory func() (*swarm.Workflow, error)
+
+// WorkflowRuns starts runs of named workflows and keeps track of them, so
+// they can be monitored and managed by ID after the request that started
+// them has returned.
+type WorkflowRuns struct {
+	factories map[string]WorkflowFactory
+	runs      map[string]*WorkflowRun
+	mu        sync.Mutex
+}
+
+// NewWorkflowRuns creates an empty set of workflows and runs.
+func NewWorkflowRuns() *WorkflowRuns {
+	return &WorkflowRuns{
+		factories: make(map[string]WorkflowFactory),
+		runs:      make(map[string]*WorkflowRun),
+	}
+}
+
+// Register makes the workflows built by factory available under name and
+// returns the runs for chaining.
+func (r *WorkflowRuns) Register(name string, factory WorkflowFactory) *WorkflowRuns {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[name] = factory
+	return r
+}
+
+// Names returns the names of the registered workflows, sorted.
+func (r *WorkflowRuns) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start runs the named workflow with inputs in the background. The run is
+// independent of the caller's context; use Cancel to stop it.
+func (r *WorkflowRuns) Start(name string, inputs map[string]interface{}) (*WorkflowRun, error) {
+	r.mu.Lock()
+	factory, ok := r.factories[name]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, name)
+	}
+	workflow, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build workflow %s: %w", name, err)
+	}
+
+	run := &WorkflowRun{
+		Workflow:  name,
+		Inputs:    inputs,
+		CreatedAt: time.Now(),
+		changed:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	workflow.Use(run.record)
+	handler, err := workflow.Run(context.Background(), inputs)
+	if err != nil {
+		return nil, err
+	}
+	run.ID, run.handler = handler.Context().RunID(), handler
+
+	r.mu.Lock()
+	r.runs[run.ID] = run
+	r.mu.Unlock()
+	go func() {
+		run.finish(handler.WaitValue())
+	}()
+	return run, nil
+}
+
+// Get returns the run with id.
+func (r *WorkflowRuns) Get(id string) (*WorkflowRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return run, nil
+}
+
+// List returns the runs of the named workflow, or of all workflows if name
+// is empty, most recent first.
+func (r *WorkflowRuns) List(name string) []*WorkflowRun {
+	r.mu.Lock()
+	runs := make([]*WorkflowRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		if name == "" || run.Workflow == name {
+			runs = append(runs, run)
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(runs, func(i, j int) bool { return runs[i].CreatedAt.After(runs[j].CreatedAt) })
+	return runs
+}
+
+// RunInfo describes a workflow run in JSON responses and callbacks.
+type RunInfo struct {
+	ID         string                 `json:"run_id"`
+	Workflow   string                 `json:"workflow"`
+	Status     swarm.WorkflowStatus   `json:"status"`
+	Inputs     map[string]interface{} `json:"inputs,omitempty"`
+	Result     interface{}            `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+}
+
+// RunMetrics summarizes the events of a workflow run.
+type RunMetrics struct {
+	// Events is the number of events emitted so far
+	Events int `json:"events"`
+	// EventCounts counts the events by type
+	EventCounts map[swarm.EventType]int `json:"event_counts"`
+	// Progress is the latest progress percentage reported by each step
+	Progress map[string]float64 `json:"progress,omitempty"`
+	// Duration is the run time in seconds, up to now for runs in progress
+	Duration float64 `json:"duration_seconds"`
+	// PendingInputs are the input requests awaiting a human response
+	PendingInputs []*swarm.InputRequiredEvent `json:"pending_inputs,omitempty"`
+	// Tasks reports the running, peak and maximum parallel tasks
+	Tasks swarm.TaskConcurrency `json:"tasks"`
+	// Usage totals the token usage and cost of the run so far
+	Usage swarm.Usage `json:"usage"`
+}
+
+// WorkflowRun is a run started by WorkflowRuns. It records the run's events,
+// so late subscribers see them from the start.
+type WorkflowRun struct {
+	// ID is the workflow run ID, also carried by the run's event metadata
+	ID string
+	// Workflow is the name of the workflow
+	Workflow string
+	// Inputs are the inputs of the StartEvent
+	Inputs map[string]interface{}
+	// CreatedAt is when the run was started
+	CreatedAt time.Time
+
+	handler    *swarm.WorkflowHandler
+	mu         sync.Mutex
+	events     []swarm.Event
+	changed    chan struct{}
+	done       chan struct{}
+	finishedAt time.Time
+	result     interface{}
+	err        error
+}
+
+// Handler returns the run's workflow handler.
+func (r *WorkflowRun) Handler() *swarm.WorkflowHandler {
+	return r.handler
+}
+
+// Status returns the run's status.
+func (r *WorkflowRun) Status() swarm.WorkflowStatus {
+	return r.handler.Status()
+}
+
+// Done returns a channel closed when the run finishes.
+func (r *WorkflowRun) Done() <-chan struct{} {
+	return r.done
+}
+
+// Result returns the outcome of a finished run; both are nil while the run
+// is in progress.
+func (r *WorkflowRun) Result() (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result, r.err
+}
+
+// FinishedAt returns when the run finished, or the zero time while it is in
+// progress.
+func (r *WorkflowRun) FinishedAt() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finishedAt
+}
+
+// Info describes the run.
+func (r *WorkflowRun) Info() RunInfo {
+	info := RunInfo{
+		ID:        r.ID,
+		Workflow:  r.Workflow,
+		Status:    r.Status(),
+		Inputs:    r.Inputs,
+		CreatedAt: r.CreatedAt,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.finishedAt.IsZero() {
+		finishedAt := r.finishedAt
+		info.FinishedAt, info.Result = &finishedAt, r.result
+		if r.err != nil {
+			info.Error = r.err.Error()
+		}
+	}
+	return info
+}
+
+// Metrics summarizes the run's events.
+func (r *WorkflowRun) Metrics() RunMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	metrics := RunMetrics{
+		Events:      len(r.events),
+		EventCounts: make(map[swarm.EventType]int),
+		Tasks:       r.handler.TaskConcurrency(),
+		Usage:       r.handler.Usage(),
+	}
+	end := r.finishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	metrics.Duration = end.Sub(r.CreatedAt).Seconds()
+
+	answered := make(map[string]bool)
+	for _, event := range r.events {
+		metrics.EventCounts[event.Type()]++
+		switch e := event.(type) {
+		case *swarm.ProgressEvent:
+			if metrics.Progress == nil {
+				metrics.Progress = make(map[string]float64)
+			}
+			metrics.Progress[e.StepName] = e.Percent
+		case *swarm.HumanResponseEvent:
+			answered[e.RequestID] = true
+		}
+	}
+	if r.finishedAt.IsZero() {
+		for _, event := range r.events {
+			if request, ok := event.(*swarm.InputRequiredEvent); ok && !answered[request.RequestID] {
+				metrics.PendingInputs = append(metrics.PendingInputs, request)
+			}
+		}
+	}
+	return metrics
+}
+
+// Cancel stops the run.
+func (r *WorkflowRun) Cancel() {
+	r.handler.Cancel()
+}
+
+// Pause holds the run's events until Resume is called.
+func (r *WorkflowRun) Pause() error {
+	return r.handler.Pause()
+}
+
+// Resume continues a paused run.
+func (r *WorkflowRun) Resume() error {
+	return r.handler.Resume()
+}
+
+// Respond answers a pending input request of the run, such as a tool call
+// approval.
+func (r *WorkflowRun) Respond(requestID string, approved bool, response string) error {
+	pending := slices.ContainsFunc(r.Metrics().PendingInputs, func(request *swarm.InputRequiredEvent) bool {
+		return request.RequestID == requestID
+	})
+	if !pending {
+		return fmt.Errorf("%w: %s", ErrInputNotFound, requestID)
+	}
+	return r.handler.Context().SendEvent(swarm.NewHumanResponseEvent(requestID, approved, response))
+}
+
+// Events streams the run's events, starting with those already emitted,
+// until the run finishes or ctx is done.
+func (r *WorkflowRun) Events(ctx context.Context) <-chan swarm.Event {
+	events := make(chan swarm.Event)
+	go func() {
+		defer close(events)
+		next := 0
+		for {
+			r.mu.Lock()
+			pending := r.events[next:]
+			next = len(r.events)
+			changed := r.changed
+			finished := !r.finishedAt.IsZero()
+			r.mu.Unlock()
+
+			for _, event := range pending {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if finished {
+				return
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// record is an EventInterceptor keeping the run's events.
+func (r *WorkflowRun) record(_ *swarm.Context, event swarm.Event) (swarm.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	r.notify()
+	return nil, nil
+}
+
+// finish records the run's outcome.
+func (r *WorkflowRun) finish(result interface{}, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result, r.err, r.finishedAt = result, err, time.Now()
+	r.notify()
+	close(r.done)
+}
+
+// notify wakes up event subscribers. It must be called with r.mu held.
+func (r *WorkflowRun) notify() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
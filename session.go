@@ -0,0 +1,502 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrSessionNotFound is returned by a SessionStore when no session exists for an ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+// DefaultSessionMaxTurns is the default turn budget of each Session.Send.
+const DefaultSessionMaxTurns = 10
+
+// SessionState is the persisted state of a Session.
+type SessionState struct {
+	// ID identifies the session
+	ID string `json:"id"`
+	// Agent is the name of the active agent
+	Agent string `json:"agent"`
+	// Messages is the conversation history
+	Messages []map[string]interface{} `json:"messages"`
+	// ContextVariables are the conversation's context variables
+	ContextVariables map[string]interface{} `json:"context_variables,omitempty"`
+	// Parent is the ID of the session this one was forked from
+	Parent string `json:"parent,omitempty"`
+	// ForkedAt is the number of messages inherited from the parent
+	ForkedAt int `json:"forked_at,omitempty"`
+	// UpdatedAt is the time of the last update
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SessionStore persists sessions in memory, files or Redis. Other backends,
+// such as SQL databases, can be plugged in by implementing it, e.g. from a
+// plugin registering a "session" store.
+type SessionStore interface {
+	// Save stores or replaces the state for state.ID
+	Save(state *SessionState) error
+	// Load returns the state for id or ErrSessionNotFound
+	Load(id string) (*SessionState, error)
+	// Delete removes the state for id. Deleting a missing session is not an error.
+	Delete(id string) error
+}
+
+// MemorySessionStore is an in-memory SessionStore, mainly useful for tests and
+// for sessions that only live as long as the process.
+type MemorySessionStore struct {
+	sessions map[string]SessionState
+	mu       sync.RWMutex
+}
+
+// NewMemorySessionStore creates an empty in-memory session store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]SessionState)}
+}
+
+// Save stores a copy of the state in memory.
+func (m *MemorySessionStore) Save(state *SessionState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[state.ID] = copySessionState(state)
+	return nil
+}
+
+// Load returns a copy of the stored state.
+func (m *MemorySessionStore) Load(id string) (*SessionState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	state = copySessionState(&state)
+	return &state, nil
+}
+
+// Delete removes the state from memory.
+func (m *MemorySessionStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// copySessionState copies the state's message list and context variables.
+func copySessionState(state *SessionState) SessionState {
+	copied := *state
+	copied.Messages = append([]map[string]interface{}(nil), state.Messages...)
+	copied.ContextVariables = make(map[string]interface{}, len(state.ContextVariables))
+	for k, v := range state.ContextVariables {
+		copied.ContextVariables[k] = v
+	}
+	return copied
+}
+
+// FileSessionStore stores each session as a JSON file in a directory.
+type FileSessionStore struct {
+	Dir string
+}
+
+// NewFileSessionStore creates a file-backed session store rooted at dir.
+func NewFileSessionStore(dir string) *FileSessionStore {
+	return &FileSessionStore{Dir: dir}
+}
+
+// path returns the file path used for a session ID, escaped like the keys of
+// FileCheckpointStore so distinct IDs never share a file.
+func (f *FileSessionStore) path(id string) string {
+	return filepath.Join(f.Dir, url.QueryEscape(id)+".json")
+}
+
+// Save writes the state atomically to disk.
+func (f *FileSessionStore) Save(state *SessionState) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create session dir: %w", err)
+	}
+	return writeSessionFile(f.path(state.ID), state)
+}
+
+// Load reads the state from disk.
+func (f *FileSessionStore) Load(id string) (*SessionState, error) {
+	return readSessionFile(f.path(id))
+}
+
+// Delete removes the session file.
+func (f *FileSessionStore) Delete(id string) error {
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// RedisSessionStore is a SessionStore backed by Redis, so sessions
+// can be shared between processes. Each session is stored as JSON under its
+// ID, prefixed with "session:" by default.
+type RedisSessionStore struct {
+	redis *RedisStepCache
+	ttl   time.Duration
+}
+
+// NewRedisSessionStore creates a Redis-backed session store for the server
+// at addr (host:port), with the same options as NewRedisStepCache.
+func NewRedisSessionStore(addr, password string, db int) *RedisSessionStore {
+	return &RedisSessionStore{redis: NewRedisStepCache(addr, password, db).WithPrefix("session:")}
+}
+
+// NewRedisSessionStoreWithClient creates a Redis-backed session store on an
+// existing client. Close closes the client.
+func NewRedisSessionStoreWithClient(client redis.UniversalClient) *RedisSessionStore {
+	return &RedisSessionStore{redis: NewRedisStepCacheWithClient(client).WithPrefix("session:")}
+}
+
+// WithPrefix sets the prefix of session keys and returns the store.
+func (r *RedisSessionStore) WithPrefix(prefix string) *RedisSessionStore {
+	r.redis.WithPrefix(prefix)
+	return r
+}
+
+// WithTTL expires sessions not saved for ttl and returns the store.
+func (r *RedisSessionStore) WithTTL(ttl time.Duration) *RedisSessionStore {
+	r.ttl = ttl
+	return r
+}
+
+// Save stores the state as JSON.
+func (r *RedisSessionStore) Save(state *SessionState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	return r.redis.Set(state.ID, data, r.ttl)
+}
+
+// Load reads the state from Redis.
+func (r *RedisSessionStore) Load(id string) (*SessionState, error) {
+	data, ok, err := r.redis.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return decodeSessionState(data)
+}
+
+// Delete removes the state from Redis.
+func (r *RedisSessionStore) Delete(id string) error {
+	return r.redis.delete(id)
+}
+
+// Close closes the underlying client.
+func (r *RedisSessionStore) Close() error {
+	return r.redis.Close()
+}
+
+// writeSessionFile writes a session state atomically as JSON.
+func writeSessionFile(path string, state *SessionState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// readSessionFile reads a session state written by writeSessionFile.
+func readSessionFile(path string) (*SessionState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	return decodeSessionState(data)
+}
+
+// decodeSessionState decodes a session state encoded as JSON.
+func decodeSessionState(data []byte) (*SessionState, error) {
+	var state SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	if err := restoreToolCalls(state.Messages); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// restoreToolCalls converts the tool calls of messages decoded from JSON
+// back to the type Run sends to the model.
+func restoreToolCalls(messages []map[string]interface{}) error {
+	for _, message := range messages {
+		raw, ok := message["tool_calls"]
+		if !ok {
+			continue
+		}
+		if _, ok := raw.([]openai.ChatCompletionMessageToolCall); ok {
+			continue
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tool calls: %w", err)
+		}
+		var toolCalls []openai.ChatCompletionMessageToolCall
+		if err := json.Unmarshal(data, &toolCalls); err != nil {
+			return fmt.Errorf("failed to unmarshal tool calls: %w", err)
+		}
+		message["tool_calls"] = toolCalls
+	}
+	return nil
+}
+
+// Session is a conversation with a swarm. It owns the message history, the
+// active agent and the context variables, so callers only send user
+// messages. With a store, the session is saved after every exchange and can
+// be resumed with LoadSession. A Session is safe for concurrent use; sends
+// are serialized.
+type Session struct {
+	// ID identifies the session in its store
+	ID string
+	// Swarm runs the conversation
+	Swarm *Swarm
+	// Agent is the active agent
+	Agent *Agent
+	// Messages is the conversation history
+	Messages []map[string]interface{}
+	// ContextVariables are passed to every run
+	ContextVariables map[string]interface{}
+	// Store persists the session (optional)
+	Store SessionStore
+	// Model overrides the agents' model (optional)
+	Model string
+	// MaxTurns limits the turns of each Send (default 10)
+	MaxTurns int
+	// Parent is the ID of the session this one was forked from
+	Parent string
+	// ForkedAt is the number of messages inherited from the parent
+	ForkedAt int
+
+	mu sync.Mutex
+}
+
+// NewSession creates a session with agent as the active agent. An empty id
+// generates one.
+func (s *Swarm) NewSession(agent *Agent, id string) *Session {
+	if id == "" {
+		id = NewID("session-")
+	}
+	return &Session{
+		ID:               id,
+		Swarm:            s,
+		Agent:            agent,
+		ContextVariables: make(map[string]interface{}),
+	}
+}
+
+// LoadSession resumes a session saved in store. The active agent is looked
+// up in the swarm's registry by name, falling back to agent (which may be
+// nil) when it has that name but is not registered.
+func (s *Swarm) LoadSession(store SessionStore, id string, agent *Agent) (*Session, error) {
+	state, err := store.Load(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session %s: %w", id, err)
+	}
+	active, err := s.Agent(state.Agent)
+	if err != nil {
+		if agent == nil || agent.Name != state.Agent {
+			return nil, fmt.Errorf("failed to restore agent of session %s: %w", id, err)
+		}
+		active = agent
+	}
+	session := s.NewSession(active, id).WithStore(store)
+	session.Messages = state.Messages
+	session.Parent, session.ForkedAt = state.Parent, state.ForkedAt
+	for k, v := range state.ContextVariables {
+		session.ContextVariables[k] = v
+	}
+	return session, nil
+}
+
+// WithStore sets the session's store and returns the session.
+func (s *Session) WithStore(store SessionStore) *Session {
+	s.Store = store
+	return s
+}
+
+// WithModel sets the model override and returns the session.
+func (s *Session) WithModel(model string) *Session {
+	s.Model = model
+	return s
+}
+
+// WithMaxTurns sets the turn budget of each Send and returns the session.
+func (s *Session) WithMaxTurns(maxTurns int) *Session {
+	s.MaxTurns = maxTurns
+	return s
+}
+
+// WithContextVariables merges variables into the session's context variables
+// and returns the session.
+func (s *Session) WithContextVariables(variables map[string]interface{}) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range variables {
+		s.ContextVariables[k] = v
+	}
+	return s
+}
+
+// Send adds a user message to the conversation and runs the active agent.
+// The reply, any handoff and updated context variables are kept for the next
+// Send. If the run fails, the session is left unchanged.
+func (s *Session) Send(ctx context.Context, userMsg string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, variables, maxTurns, err := s.prepare(userMsg)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.Swarm.Run(ctx, s.Agent, history, variables, s.Model, false, false, maxTurns, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", s.ID, err)
+	}
+	if err := s.commit(history, response); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+// Stream is like Send, but streams the run like Swarm.RunAndStream. The
+// session is updated when the final response arrives and stays locked until
+// the returned channel is closed, so the channel must be drained.
+func (s *Session) Stream(ctx context.Context, userMsg string) (<-chan map[string]interface{}, error) {
+	s.mu.Lock()
+
+	history, variables, maxTurns, err := s.prepare(userMsg)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	chunks, err := s.Swarm.RunAndStream(ctx, s.Agent, history, variables, s.Model, false, maxTurns, true, false)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("session %s: %w", s.ID, err)
+	}
+
+	out := make(chan map[string]interface{})
+	go func() {
+		defer s.mu.Unlock()
+		defer close(out)
+		for chunk := range chunks {
+			if response, ok := chunk["response"].(*Response); ok && response.Error == nil {
+				if err := s.commit(history, response); err != nil {
+					chunk["error"] = err.Error()
+				}
+			}
+			out <- chunk
+		}
+	}()
+	return out, nil
+}
+
+// prepare returns the history of a run answering userMsg, a copy of the
+// context variables for the run to update, and its turn budget.
+func (s *Session) prepare(userMsg string) ([]map[string]interface{}, map[string]interface{}, int, error) {
+	if s.Agent == nil {
+		return nil, nil, 0, fmt.Errorf("session %s has no active agent", s.ID)
+	}
+	maxTurns := s.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultSessionMaxTurns
+	}
+	history := append(append([]map[string]interface{}(nil), s.Messages...), map[string]interface{}{
+		"role":    "user",
+		"content": userMsg,
+	})
+	variables := make(map[string]interface{}, len(s.ContextVariables))
+	for k, v := range s.ContextVariables {
+		variables[k] = v
+	}
+	return history, variables, maxTurns, nil
+}
+
+// commit records a successful run and saves the session.
+func (s *Session) commit(history []map[string]interface{}, response *Response) error {
+	s.Messages = response.Conversation(history)
+	if response.History != nil {
+		// The compacted history no longer starts with the parent's messages
+		s.ForkedAt = 0
+	}
+	if response.Agent != nil {
+		s.Agent = response.Agent
+	}
+	for k, v := range response.ContextVariables {
+		s.ContextVariables[k] = v
+	}
+	return s.save()
+}
+
+// Reset clears the conversation history, keeping the active agent and
+// context variables, and deletes the session from its store.
+func (s *Session) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages = nil
+	if s.Store == nil {
+		return nil
+	}
+	if err := s.Store.Delete(s.ID); err != nil {
+		return fmt.Errorf("failed to reset session %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+// State returns a snapshot of the session.
+func (s *Session) State() *SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state()
+}
+
+// state returns a snapshot of the session without locking it.
+func (s *Session) state() *SessionState {
+	state := &SessionState{
+		ID:               s.ID,
+		Messages:         s.Messages,
+		ContextVariables: s.ContextVariables,
+		Parent:           s.Parent,
+		ForkedAt:         s.ForkedAt,
+		UpdatedAt:        time.Now(),
+	}
+	if s.Agent != nil {
+		state.Agent = s.Agent.Name
+	}
+	copied := copySessionState(state)
+	return &copied
+}
+
+// save persists the session if it has a store.
+func (s *Session) save() error {
+	if s.Store == nil {
+		return nil
+	}
+	if err := s.Store.Save(s.state()); err != nil {
+		return fmt.Errorf("failed to save session %s: %w", s.ID, err)
+	}
+	return nil
+}
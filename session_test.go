@@ -0,0 +1,181 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestSessionSend(t *testing.T) {
+	client := newScriptedClient("Hi Ann", "Your name is Ann")
+	store := NewMemorySessionStore()
+	session := NewSwarm(client).NewSession(NewAgent("assistant"), "user-1").WithStore(store)
+
+	_, err := session.Send(context.Background(), "I am Ann")
+	AssertNoError(t, err, "first send")
+	response, err := session.Send(context.Background(), "What is my name?")
+	AssertNoError(t, err, "second send")
+	AssertEqual(t, "Your name is Ann", lastContent(response), "reply")
+
+	// The second request carries the whole conversation
+	AssertEqual(t, 4, len(client.requests[1].Messages), "system prompt, user, assistant, user")
+	AssertEqual(t, 4, len(session.Messages), "session history")
+
+	state, err := store.Load("user-1")
+	AssertNoError(t, err, "load state")
+	AssertEqual(t, "assistant", state.Agent, "stored agent")
+	AssertEqual(t, 4, len(state.Messages), "stored history")
+
+	AssertNoError(t, session.Reset(), "reset")
+	AssertEqual(t, 0, len(session.Messages), "history after reset")
+	_, err = store.Load("user-1")
+	AssertEqual(t, true, errors.Is(err, ErrSessionNotFound), "stored state after reset")
+}
+
+func TestSessionSendFailureKeepsHistory(t *testing.T) {
+	client := newScriptedClient("Hello")
+	session := NewSwarm(client).NewSession(NewAgent("assistant"), "")
+
+	_, err := session.Send(context.Background(), "Hi")
+	AssertNoError(t, err, "first send")
+	client.Error = errors.New("service unavailable")
+	_, err = session.Send(context.Background(), "Again")
+	AssertError(t, err, "send with a failing client")
+	AssertEqual(t, 2, len(session.Messages), "history unchanged by the failed send")
+}
+
+func TestSessionSendFailureKeepsVariables(t *testing.T) {
+	client := newToolCallClient("remember", `{}`, "Noted")
+	agent := NewAgent("assistant").AddFunction(NewAgentFunction("remember", "Remember the user",
+		func(args map[string]interface{}) (interface{}, error) {
+			// The model fails after the tool updated the variables
+			client.Error = errors.New("service unavailable")
+			return &Result{Value: "ok", ContextVariables: map[string]interface{}{"name": "Ann"}}, nil
+		},
+		[]Parameter{},
+	))
+	session := NewSwarm(client).NewSession(agent, "").WithContextVariables(map[string]interface{}{"name": "Bob"})
+
+	_, err := session.Send(context.Background(), "I am Ann")
+	AssertError(t, err, "send with a failing client")
+	AssertEqual(t, "Bob", session.ContextVariables["name"], "variables unchanged by the failed send")
+}
+
+func TestSessionHandoffAndResume(t *testing.T) {
+	billing := NewAgent("billing")
+	support := NewAgent("support").AddFunction(NewAgentFunction("transfer_to_billing", "Transfer to billing",
+		func(args map[string]interface{}) (interface{}, error) {
+			return billing, nil
+		},
+		[]Parameter{},
+	))
+	registry := NewAgentRegistry().MustRegister(support, billing)
+	store := NewFileSessionStore(t.TempDir())
+
+	client := newToolCallClient("transfer_to_billing", `{}`, "Billing here")
+	session := NewSwarm(client).WithRegistry(registry).NewSession(support, "user-2").WithStore(store)
+	session.WithContextVariables(map[string]interface{}{"plan": "pro"})
+	_, err := session.Send(context.Background(), "I was double charged")
+	AssertNoError(t, err, "send")
+	AssertEqual(t, "billing", session.Agent.Name, "active agent after handoff")
+
+	client = newScriptedClient("Refund issued")
+	resumed, err := NewSwarm(client).WithRegistry(registry).LoadSession(store, "user-2", nil)
+	AssertNoError(t, err, "load session")
+	AssertEqual(t, "billing", resumed.Agent.Name, "restored agent")
+	AssertEqual(t, "pro", resumed.ContextVariables["plan"], "restored context variables")
+
+	_, err = resumed.Send(context.Background(), "Refund please")
+	AssertNoError(t, err, "send after resume")
+	request := client.requests[0].Messages
+	AssertEqual(t, 1, len(request[2].OfAssistant.ToolCalls), "restored tool calls")
+	AssertEqual(t, "transfer_to_billing", request[2].OfAssistant.ToolCalls[0].Function.Name, "restored tool call name")
+
+	_, err = NewSwarm(client).LoadSession(store, "missing", nil)
+	AssertEqual(t, true, errors.Is(err, ErrSessionNotFound), "missing session")
+}
+
+func TestSessionStores(t *testing.T) {
+	redisStore := NewRedisSessionStore(newFakeRedis(t), "", 0)
+	defer redisStore.Close()
+	stores := map[string]SessionStore{
+		"file":  NewFileSessionStore(t.TempDir()),
+		"redis": redisStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			toolCall := []openai.ChatCompletionMessageToolCall{MockToolCall{ID: "call-1", Name: "lookup", Args: "{}"}.ToOpenAI()}
+			AssertNoError(t, store.Save(&SessionState{ID: "user/1", Agent: "assistant", Messages: []map[string]interface{}{
+				{"role": "assistant", "content": "", "tool_calls": toolCall},
+			}}), "Save")
+			// IDs differing only in separators do not share an entry
+			AssertNoError(t, store.Save(&SessionState{ID: "user_1", Agent: "other"}), "Save similar ID")
+
+			state, err := store.Load("user/1")
+			AssertNoError(t, err, "Load")
+			AssertEqual(t, "assistant", state.Agent, "stored agent")
+			restored, _ := state.Messages[0]["tool_calls"].([]openai.ChatCompletionMessageToolCall)
+			AssertEqual(t, 1, len(restored), "restored tool calls")
+			AssertEqual(t, "lookup", restored[0].Function.Name, "restored tool call name")
+
+			AssertNoError(t, store.Delete("user/1"), "Delete")
+			AssertNoError(t, store.Delete("user/1"), "Delete missing")
+			_, err = store.Load("user/1")
+			AssertEqual(t, true, errors.Is(err, ErrSessionNotFound), "deleted session")
+			state, err = store.Load("user_1")
+			AssertNoError(t, err, "Load similar ID")
+			AssertEqual(t, "other", state.Agent, "similar ID kept")
+		})
+	}
+}
+
+func TestSessionStream(t *testing.T) {
+	client := NewMockOpenAIClient()
+	client.AddStreamChunk(&openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{
+			{Delta: openai.ChatCompletionChunkChoiceDelta{Content: "Hello Ann"}},
+		},
+	})
+	store := NewMemorySessionStore()
+	session := NewSwarm(client).NewSession(NewAgent("assistant"), "user-3").WithStore(store)
+
+	chunks, err := session.Stream(context.Background(), "I am Ann")
+	AssertNoError(t, err, "Stream")
+	var content string
+	var response *Response
+	for chunk := range chunks {
+		if delta, ok := chunk["content"].(string); ok {
+			content += delta
+		}
+		if r, ok := chunk["response"].(*Response); ok {
+			response = r
+		}
+	}
+	AssertEqual(t, "Hello Ann", content, "streamed content")
+	AssertEqual(t, true, response != nil, "final response")
+	AssertEqual(t, 2, len(session.Messages), "session history")
+
+	state, err := store.Load("user-3")
+	AssertNoError(t, err, "load state")
+	AssertEqual(t, 2, len(state.Messages), "stored history")
+}
+
+func TestSessionStreamFailureKeepsHistory(t *testing.T) {
+	client := NewMockOpenAIClient()
+	client.Error = errors.New("service unavailable")
+	session := NewSwarm(client).NewSession(NewAgent("assistant"), "")
+
+	chunks, err := session.Stream(context.Background(), "Hi")
+	AssertNoError(t, err, "Stream")
+	var failure string
+	for chunk := range chunks {
+		if msg, ok := chunk["error"].(string); ok {
+			failure = msg
+		}
+	}
+	AssertEqual(t, true, strings.Contains(failure, "service unavailable"), "streamed error")
+	AssertEqual(t, 0, len(session.Messages), "history unchanged by the failed stream")
+}
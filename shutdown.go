@@ -0,0 +1,235 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrWorkflowShutdown is returned by WorkflowHandler.Wait for runs stopped
+// by Shutdown, and by Run once the workflow is shut down.
+var ErrWorkflowShutdown = errors.New("workflow shut down")
+
+// WorkflowCheckpoint captures a run stopped by Shutdown: its state and the
+// events it had not dispatched yet. It is JSON-serializable, so the run can
+// be continued with Workflow.Restore in another process.
+type WorkflowCheckpoint struct {
+	// RunID is the ID of the run, kept by the restored run
+	RunID string `json:"run_id"`
+	// Workflow is the name of the workflow
+	Workflow string `json:"workflow"`
+	// State is the run's Context state
+	State map[string]interface{} `json:"state,omitempty"`
+	// Events are the undispatched events, encoded with MarshalEvent, in the
+	// order they were sent. Pending delayed events are included with their
+	// full delay.
+	Events []json.RawMessage `json:"events,omitempty"`
+	// Interrupted reports that the drain timeout expired before the in-flight
+	// steps returned, so their work and the events they would have sent are
+	// missing
+	Interrupted bool `json:"interrupted,omitempty"`
+	// CreatedAt is when the checkpoint was taken
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Shutdown gracefully stops the run: no more events are dispatched to steps,
+// the steps in flight run to completion, and the run's state and
+// undispatched events, including those sent by the draining steps, are
+// captured in a checkpoint. Once ctx is done, the remaining steps are
+// cancelled and the checkpoint is marked Interrupted, and ctx's error is
+// returned with it.
+//
+// The run then finishes with ErrWorkflowShutdown, closing its streams. A
+// nil checkpoint is returned if the run had already finished.
+func (h *WorkflowHandler) Shutdown(ctx context.Context) (*WorkflowCheckpoint, error) {
+	h.shutdownOnce.Do(func() { close(h.shutdown) })
+	select {
+	case <-h.checkpointed:
+		return h.checkpoint, h.checkpointErr
+	case <-h.doneChan:
+		return h.checkpoint, h.checkpointErr
+	case <-ctx.Done():
+	}
+
+	h.ctx.Cancel()
+	select {
+	case <-h.checkpointed:
+	case <-h.doneChan:
+	}
+	if h.checkpointErr != nil {
+		return h.checkpoint, h.checkpointErr
+	}
+	return h.checkpoint, ctx.Err()
+}
+
+// shuttingDown reports whether Shutdown was called.
+func (h *WorkflowHandler) shuttingDown() bool {
+	select {
+	case <-h.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown rejects new runs with ErrWorkflowShutdown and shuts down the
+// active runs concurrently (see WorkflowHandler.Shutdown), returning the
+// checkpoints of the runs that were stopped. The drain timeout given by ctx
+// applies to all runs.
+func (w *Workflow) Shutdown(ctx context.Context) ([]*WorkflowCheckpoint, error) {
+	w.mu.Lock()
+	w.shutdown = true
+	handlers := make([]*WorkflowHandler, 0, len(w.active))
+	for handler := range w.active {
+		handlers = append(handlers, handler)
+	}
+	w.mu.Unlock()
+
+	var (
+		checkpoints []*WorkflowCheckpoint
+		errs        []error
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+	)
+	for _, handler := range handlers {
+		wg.Add(1)
+		go func(h *WorkflowHandler) {
+			defer wg.Done()
+			checkpoint, err := h.Shutdown(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if checkpoint != nil {
+				checkpoints = append(checkpoints, checkpoint)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("run %s: %w", h.ctx.RunID(), err))
+			}
+		}(handler)
+	}
+	wg.Wait()
+	return checkpoints, errors.Join(errs...)
+}
+
+// Restore continues a run from a checkpoint taken by Shutdown: the run keeps
+// its ID, its state is restored, and its undispatched events are dispatched
+// in their original order. Restored runs do not receive a new StartEvent.
+func (w *Workflow) Restore(ctx context.Context, checkpoint *WorkflowCheckpoint) (*WorkflowHandler, error) {
+	if checkpoint == nil {
+		return nil, errors.New("checkpoint cannot be nil")
+	}
+	events := make([]Event, 0, len(checkpoint.Events))
+	for i, data := range checkpoint.Events {
+		event, err := UnmarshalEvent(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore event %d: %w", i, err)
+		}
+		events = append(events, event)
+	}
+	return w.run(ctx, events, checkpoint)
+}
+
+// drain stops a run for Shutdown. It holds the events sent while the steps
+// in flight run to completion, or until the run is cancelled, and then
+// checkpoints the run together with the held events and pending delays.
+func (w *Workflow) drain(wfCtx *Context, handler *WorkflowHandler, steps *sync.WaitGroup, delays *pendingDelays, held []Event) {
+	for _, event := range delays.take() {
+		held = append(held, event)
+	}
+
+	stepsDone := make(chan struct{})
+	go func() {
+		steps.Wait()
+		close(stepsDone)
+	}()
+	interrupted := false
+	waiting := true
+	for waiting {
+		select {
+		case event := <-wfCtx.Events():
+			held = append(held, event)
+		case <-stepsDone:
+			waiting = false
+		case <-wfCtx.Context().Done():
+			interrupted, waiting = true, false
+		}
+	}
+	for buffered := true; buffered; {
+		select {
+		case event := <-wfCtx.Events():
+			held = append(held, event)
+		default:
+			buffered = false
+		}
+	}
+
+	checkpoint := &WorkflowCheckpoint{
+		RunID:       wfCtx.RunID(),
+		Workflow:    w.config.Name,
+		State:       wfCtx.Clone(),
+		Interrupted: interrupted,
+		CreatedAt:   time.Now(),
+	}
+	for _, event := range held {
+		data, err := MarshalEvent(event)
+		if err != nil {
+			handler.checkpointErr = fmt.Errorf("failed to checkpoint %s: %w", event.Type(), err)
+			continue
+		}
+		checkpoint.Events = append(checkpoint.Events, data)
+	}
+	handler.checkpoint = checkpoint
+	handler.fail(WorkflowStatusCancelled, ErrWorkflowShutdown)
+	close(handler.checkpointed)
+
+	// Release whatever still waits on the run
+	wfCtx.Cancel()
+}
+
+// pendingDelays tracks the DelayEvents of a run whose timers are pending.
+type pendingDelays struct {
+	mu     sync.Mutex
+	events map[*DelayEvent]uint64
+	next   uint64
+	stop   context.CancelFunc
+}
+
+// add records a scheduled delay, numbered in the order delays are added.
+func (p *pendingDelays) add(event *DelayEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next++
+	p.events[event] = p.next
+}
+
+// fire removes a delay whose timer expired, and reports whether it should
+// still be dispatched, that is, it was not taken by a shutdown.
+func (p *pendingDelays) fire(event *DelayEvent) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.events[event]; !ok {
+		return false
+	}
+	delete(p.events, event)
+	return true
+}
+
+// take stops the timers and returns the delays that have not fired, in the
+// order they were scheduled.
+func (p *pendingDelays) take() []*DelayEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop()
+	events := make([]*DelayEvent, 0, len(p.events))
+	for event := range p.events {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return p.events[events[i]] < p.events[events[j]]
+	})
+	p.events = make(map[*DelayEvent]uint64)
+	return events
+}
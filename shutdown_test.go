@@ -0,0 +1,124 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newShutdownWorkflow builds a workflow whose start step closes started,
+// blocks until release is closed and then sends a "Next" event that stops
+// the run.
+func newShutdownWorkflow(started chan<- struct{}, release <-chan struct{}) *Workflow {
+	workflow := NewWorkflow("shutdown")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		ctx.Set("visited", "start")
+		close(started)
+		<-release
+		return NewBaseEvent(EventType("Next"), map[string]interface{}{"n": 1}), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("next", EventType("Next"), func(ctx *Context, event Event) (Event, error) {
+		visited, _ := ctx.GetString("visited")
+		return NewStopEvent(visited + " next"), nil
+	}, StepConfig{}))
+	return workflow
+}
+
+func TestWorkflowShutdownAndRestore(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	workflow := newShutdownWorkflow(started, release)
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	<-started
+
+	type shutdownResult struct {
+		checkpoint *WorkflowCheckpoint
+		err        error
+	}
+	results := make(chan shutdownResult, 1)
+	go func() {
+		checkpoint, err := handler.Shutdown(context.Background())
+		results <- shutdownResult{checkpoint, err}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	result := <-results
+	AssertNoError(t, result.err, "shutdown")
+	checkpoint := result.checkpoint
+	AssertEqual(t, handler.Context().RunID(), checkpoint.RunID, "checkpoint run ID")
+	AssertEqual(t, "start", checkpoint.State["visited"], "checkpoint state")
+	AssertEqual(t, false, checkpoint.Interrupted, "interrupted")
+	AssertEqual(t, 1, len(checkpoint.Events), "checkpointed events")
+	_, err = handler.Wait()
+	AssertEqual(t, ErrWorkflowShutdown, err, "wait error")
+	AssertEqual(t, WorkflowStatusCancelled, handler.Status(), "status")
+
+	// The checkpoint survives serialization and continues in a new workflow
+	data, err := json.Marshal(checkpoint)
+	AssertNoError(t, err, "marshal checkpoint")
+	var restored WorkflowCheckpoint
+	AssertNoError(t, json.Unmarshal(data, &restored), "unmarshal checkpoint")
+
+	resumed, err := newShutdownWorkflow(nil, nil).Restore(context.Background(), &restored)
+	AssertNoError(t, err, "restore")
+	AssertEqual(t, checkpoint.RunID, resumed.Context().RunID(), "restored run ID")
+	value, err := resumed.WaitValue()
+	AssertNoError(t, err, "restored wait")
+	AssertEqual(t, "start next", value, "restored result")
+}
+
+func TestWorkflowShutdownTimeout(t *testing.T) {
+	workflow := NewWorkflow("stuck")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		ctx.SendAfter(time.Hour, NewBaseEvent(EventType("Later"), nil))
+		<-ctx.Context().Done()
+		return nil, nil
+	}, StepConfig{}))
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	checkpoints, err := workflow.Shutdown(ctx)
+	AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded), "shutdown error")
+	AssertEqual(t, 1, len(checkpoints), "checkpoints")
+	AssertEqual(t, true, checkpoints[0].Interrupted, "interrupted")
+	AssertEqual(t, 1, len(checkpoints[0].Events), "checkpointed events")
+
+	event, err := UnmarshalEvent(checkpoints[0].Events[0])
+	AssertNoError(t, err, "unmarshal delayed event")
+	delay, ok := event.(*DelayEvent)
+	AssertEqual(t, true, ok, "delay event type")
+	AssertEqual(t, time.Hour, delay.Delay, "delay")
+	AssertEqual(t, EventType("Later"), delay.Next.Type(), "delayed event type")
+
+	_, err = handler.Wait()
+	AssertEqual(t, ErrWorkflowShutdown, err, "wait error")
+	_, err = workflow.Run(context.Background(), map[string]interface{}{})
+	AssertEqual(t, ErrWorkflowShutdown, err, "run after shutdown")
+}
+
+func TestPendingDelaysOrder(t *testing.T) {
+	_, stop := context.WithCancel(context.Background())
+	delays := &pendingDelays{events: make(map[*DelayEvent]uint64), stop: stop}
+	var scheduled []*DelayEvent
+	for i := 0; i < 20; i++ {
+		event := NewDelayEvent(time.Hour, NewBaseEvent(EventType("Poll"), map[string]interface{}{"n": i}))
+		delays.add(event)
+		scheduled = append(scheduled, event)
+	}
+	AssertEqual(t, true, delays.fire(scheduled[3]), "fired delay")
+
+	taken := delays.take()
+	AssertEqual(t, 19, len(taken), "pending delays")
+	for i, event := range append(scheduled[:3:3], scheduled[4:]...) {
+		if taken[i] != event {
+			t.Fatalf("Expected delay %d in scheduling order", i)
+		}
+	}
+	AssertEqual(t, 0, len(delays.take()), "delays taken once")
+}
@@ -2,11 +2,18 @@ package swarm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"reflect"
+	"regexp"
 	"strings"
+	"text/template"
 	"time"
 
+	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,7 +23,7 @@ import (
 type SimpleFlow struct {
 	// Name is the name of the workflow.
 	Name string `yaml:"name" json:"name"`
-	// Model specifies the model used in the workflow.
+	// Model specifies the model used in the workflow, unless a step overrides it.
 	Model string `yaml:"model" json:"model"`
 	// MaxTurns defines the maximum number of turns allowed in the workflow.
 	MaxTurns int `yaml:"max_turns" json:"max_turns"`
@@ -30,6 +37,28 @@ type SimpleFlow struct {
 	Timeout time.Duration `yaml:"timeout" json:"timeout"`
 	// JSONMode indicates whether to use JSON format for input and output.
 	JSONMode bool `yaml:"json_mode" json:"json_mode"`
+	// Inputs are initial context variables shared by all steps. Step inputs
+	// take precedence over them.
+	Inputs map[string]interface{} `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	// Tools are declarative tools that steps reference by name.
+	Tools []SimpleFlowTool `yaml:"tools,omitempty" json:"tools,omitempty"`
+
+	// Progress is called when each step starts and finishes (optional).
+	Progress func(progress SimpleFlowProgress) `yaml:"-" json:"-"`
+
+	// Registry resolves step agents referenced by name (DefaultRegistry if nil).
+	Registry *AgentRegistry `yaml:"-" json:"-"`
+
+	// HTTPClient sends the requests of HTTP tools without their own Client
+	// (http.DefaultClient if nil).
+	HTTPClient *http.Client `yaml:"-" json:"-"`
+
+	// Checkpoint is the path of a sidecar file recording the steps completed
+	// by Run (optional), which is removed once the flow succeeds.
+	Checkpoint string `yaml:"-" json:"-"`
+	// Resume makes Run restore the steps recorded in Checkpoint and continue
+	// with the next step, instead of starting over.
+	Resume bool `yaml:"-" json:"-"`
 }
 
 // SimpleFlowStep defines a single step within a SimpleFlow workflow. Each step
@@ -40,15 +69,83 @@ type SimpleFlowStep struct {
 	Name string `yaml:"name" json:"name"`
 	// Instructions are the instructions for the workflow step.
 	Instructions string `yaml:"instructions" json:"instructions"`
-	// Inputs are the inputs required for the workflow step.
+	// Inputs are the inputs required for the workflow step. String values
+	// are text/templates rendered against the context variables and the
+	// outputs of earlier steps, e.g. "{{ steps.get-weather.output }}"; see
+	// stepTemplateData.
 	Inputs map[string]interface{} `yaml:"inputs" json:"inputs"`
+	// Tools names the declarative tools of the workflow available in this step.
+	Tools []string `yaml:"tools,omitempty" json:"tools,omitempty"`
 	// Timeout specifies the timeout for this step. If not set, uses workflow timeout.
 	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// AgentName references a registered agent to run this step. The agent's
+	// model settings and functions are used; its string instructions apply
+	// when Instructions is empty.
+	AgentName string `yaml:"agent,omitempty" json:"agent,omitempty"`
+	// When is an optional condition deciding whether the step runs: a
+	// text/template action evaluated against the context variables, which
+	// hold the flow inputs and the "<step>Result" outputs of earlier steps,
+	// e.g. `contains .triageResult "critical"`, and the step outputs of
+	// stepTemplateData, e.g. `eq steps.triage.data.severity "critical"`.
+	// The step is skipped when the
+	// condition renders as empty, "false", "0" or "<no value>".
+	When string `yaml:"when,omitempty" json:"when,omitempty"`
+	// Model overrides the workflow model for this step.
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+	// Temperature overrides the sampling temperature for this step.
+	Temperature *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	// MaxTokens limits the completion tokens of each model call in this step.
+	MaxTokens int `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	// JSONMode overrides the workflow JSON mode for this step.
+	JSONMode *bool `yaml:"json_mode,omitempty" json:"json_mode,omitempty"`
+	// OutputSchema is the JSON schema the step's final message must match.
+	// Invalid output is sent back to the agent with the validation errors.
+	OutputSchema *Schema `yaml:"output_schema,omitempty" json:"output_schema,omitempty"`
+	// ForEach makes the step run once for each item of a list: a
+	// text/template expression evaluated like When, e.g. `.files` or
+	// `steps.list-files.data`. A string value is decoded as a JSON array,
+	// or else split into lines. Each run sees the item and its position as
+	// the "item" and "index" context variables, and the step output is the
+	// JSON array of the outputs of all runs.
+	ForEach string `yaml:"foreach,omitempty" json:"foreach,omitempty"`
+	// Concurrency bounds how many items of a ForEach step run in parallel
+	// (default 1).
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	// OutputRetries bounds how often invalid output is re-asked
+	// (default DefaultOutputRetries).
+	OutputRetries int `yaml:"output_retries,omitempty" json:"output_retries,omitempty"`
+	// Retries is how often a failed step is retried, with the backoff of
+	// DefaultRetryPolicy.
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty"`
+	// Retry configures the retries and backoff of a failed step, overriding
+	// Retries. Unset fields default to those of DefaultRetryPolicy.
+	Retry *RetryPolicy `yaml:"retry,omitempty" json:"retry,omitempty"`
 
 	// Agent is the agent responsible for executing the workflow step.
 	Agent *Agent `yaml:"-" json:"-"`
 	// Functions are the functions that the agent can perform in this workflow step.
 	Functions []AgentFunction `yaml:"-" json:"-"`
+
+	condition *template.Template
+	items     *template.Template
+}
+
+// DefaultOutputRetries is the default number of times a SimpleFlow step is
+// re-asked for output matching its OutputSchema.
+const DefaultOutputRetries = 2
+
+// SimpleFlowProgress reports the progress of a SimpleFlow run.
+type SimpleFlowProgress struct {
+	// Step is the name of the step
+	Step string
+	// Index is the position of the step, from 0
+	Index int
+	// Total is the number of steps
+	Total int
+	// Result is the outcome of the step, or nil when the step starts
+	Result *SimpleStepResult
+	// Duration is the time the step took, once finished
+	Duration time.Duration
 }
 
 // SimpleStepResult contains the output and metadata from executing a workflow step.
@@ -57,6 +154,11 @@ type SimpleStepResult struct {
 	Content  string
 	Messages []map[string]interface{}
 	Error    error
+	// Skipped reports that the step's When condition was false
+	Skipped bool
+	// Resumed reports that the step was completed by an earlier run and
+	// restored from the flow's Checkpoint
+	Resumed bool
 }
 
 // Initialize prepares the workflow for execution by setting up default values,
@@ -66,9 +168,11 @@ type SimpleStepResult struct {
 // The function performs the following setup:
 //   - Sets default values for MaxTurns and Timeout if not specified
 //   - Validates the workflow has at least one step
-//   - Initializes agents for each step
+//   - Builds the declarative tools and initializes agents for each step
 //   - Configures step-specific timeouts
-//   - Sets up handoff functions between consecutive steps
+//   - Parses step When conditions and templated inputs
+//   - Parses step ForEach lists
+//   - Sets up handoff functions between consecutive steps, see handsOff
 //
 // Returns an error if the workflow configuration is invalid.
 func (w *SimpleFlow) Initialize() error {
@@ -83,30 +187,93 @@ func (w *SimpleFlow) Initialize() error {
 		return fmt.Errorf("workflow must have at least one step")
 	}
 
+	tools := make(map[string]AgentFunction, len(w.Tools))
+	for _, tool := range w.Tools {
+		if tool.Client == nil {
+			tool.Client = w.HTTPClient
+		}
+		fn, err := tool.Function()
+		if err != nil {
+			return err
+		}
+		tools[tool.Name] = fn
+	}
+
 	// Initialize Agent for each step.
 	for i := range w.Steps {
 		step := &w.Steps[i]
+		if step.Agent == nil && step.AgentName != "" {
+			agent, err := w.registry().Get(step.AgentName)
+			if err != nil {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+			if instructions, ok := agent.Instructions.(string); ok && step.Instructions == "" {
+				step.Instructions = instructions
+			}
+			// Clone the agent, since instructions and handoffs are added below
+			step.Agent = agent.Clone()
+		}
 		if step.Agent == nil {
 			step.Agent = NewAgent(step.Name)
 		}
 		if step.Timeout == 0 {
 			step.Timeout = w.Timeout / time.Duration(len(w.Steps))
 		}
+		if step.When != "" {
+			condition, err := parseCondition(step.When)
+			if err != nil {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+			step.condition = condition
+		}
+		if _, err := renderInputs(step.Inputs, nil); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		if step.ForEach != "" {
+			items, err := parseForEach(step.ForEach)
+			if err != nil {
+				return fmt.Errorf("step %s: %w", step.Name, err)
+			}
+			step.items = items
+		}
+		if step.Concurrency < 0 {
+			return fmt.Errorf("step %s: concurrency must be non-negative", step.Name)
+		}
+		if step.Retries < 0 {
+			return fmt.Errorf("step %s: retries must be non-negative", step.Name)
+		}
+		if policy := step.retryPolicy(); policy != nil {
+			if err := policy.validate(); err != nil {
+				return fmt.Errorf("step %s: invalid retry policy: %w", step.Name, err)
+			}
+		}
 
 		// Add step instructions
-		if i < len(w.Steps)-1 {
-			step.Agent.WithInstructions(fmt.Sprintf("%s\n\nHandoff to the next step after you finish your task.", step.Instructions))
+		instructions := step.Instructions
+		if step.OutputSchema != nil {
+			instructions = fmt.Sprintf("%s\n\nReply with only a JSON value matching this schema: %s", instructions, step.OutputSchema)
+		}
+		if w.handsOff(i) {
+			step.Agent.WithInstructions(fmt.Sprintf("%s\n\nHandoff to the next step after you finish your task.", instructions))
 		} else {
-			step.Agent.WithInstructions(step.Instructions)
+			step.Agent.WithInstructions(instructions)
 		}
 
-		// Add step functions
+		// Add step functions. Initialize runs again on Run, so functions
+		// added by an earlier call are kept once.
 		for _, f := range step.Functions {
-			step.Agent.AddFunction(f)
+			addFunctionOnce(step.Agent, f)
+		}
+		for _, name := range step.Tools {
+			fn, ok := tools[name]
+			if !ok {
+				return fmt.Errorf("step %s: unknown tool %q", step.Name, name)
+			}
+			addFunctionOnce(step.Agent, fn)
 		}
 
 		// Add handoff function if not last step
-		if i < len(w.Steps)-1 {
+		if w.handsOff(i) {
 			nextStep := &w.Steps[i+1]
 			handoffFunc := NewAgentFunction(
 				fmt.Sprintf("handoffTo%s", nextStep.Name),
@@ -119,32 +286,71 @@ func (w *SimpleFlow) Initialize() error {
 				},
 				[]Parameter{},
 			)
-			step.Agent.AddFunction(handoffFunc)
+			addFunctionOnce(step.Agent, handoffFunc)
 		}
 	}
 
 	return nil
 }
 
+// handsOff reports whether step i hands off to the next step. Steps with a
+// When condition are not handoff targets, since the runner decides whether
+// they run, and the runner also drives ForEach steps.
+func (w *SimpleFlow) handsOff(i int) bool {
+	if i >= len(w.Steps)-1 {
+		return false
+	}
+	next := &w.Steps[i+1]
+	return w.Steps[i].ForEach == "" && next.ForEach == "" && next.When == ""
+}
+
+// addFunctionOnce adds f to agent unless the agent has a function of the same
+// name.
+func addFunctionOnce(agent *Agent, f AgentFunction) {
+	for _, existing := range agent.Functions {
+		if existing.Name() == f.Name() {
+			return
+		}
+	}
+	agent.AddFunction(f)
+}
+
+// registry returns the workflow's registry or DefaultRegistry.
+func (w *SimpleFlow) registry() *AgentRegistry {
+	if w.Registry != nil {
+		return w.Registry
+	}
+	return DefaultRegistry
+}
+
+// reportProgress calls the Progress callback, if any.
+func (w *SimpleFlow) reportProgress(progress SimpleFlowProgress) {
+	if w.Progress != nil {
+		w.Progress(progress)
+	}
+}
+
 // LoadSimpleFlow creates a new SimpleFlow instance from a YAML configuration file.
 // The function reads the file, unmarshals the YAML content, and initializes the
-// workflow.
-func LoadSimpleFlow(path string) (*SimpleFlow, error) {
+// workflow. References to environment variables ("${NAME}") and secrets
+// ("${secret:name}") in the file are replaced with their values, so API keys
+// and URLs need not be hardcoded; Save writes the replaced values.
+func LoadSimpleFlow(path string, opts ...LoadOption) (*SimpleFlow, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read workflow file: %w", err)
 	}
 
-	var workflow SimpleFlow
-	if err := yaml.Unmarshal(data, &workflow); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal workflow: %w", err)
+	workflow, err := parseSimpleFlow(data, opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := workflow.Initialize(); err != nil {
 		return nil, fmt.Errorf("failed to initialize workflow: %w", err)
 	}
 
-	return &workflow, nil
+	return workflow, nil
 }
 
 // Save persists the workflow configuration to a YAML file at the specified path.
@@ -175,9 +381,22 @@ func (w *SimpleFlow) Save(path string) error {
 //
 // Returns the step execution result and any error encountered.
 func (w *SimpleFlow) executeStep(ctx context.Context, client *Swarm, step *SimpleFlowStep, contextVars map[string]interface{}, prevMessages []map[string]interface{}) (*SimpleStepResult, error) {
-	// Create step context with timeout
+	// Create step context with timeout and the step's sampling overrides
 	stepCtx, cancel := context.WithTimeout(ctx, step.Timeout)
 	defer cancel()
+	if step.Temperature != nil {
+		stepCtx = withTemperature(stepCtx, *step.Temperature)
+	}
+	if step.MaxTokens > 0 {
+		stepCtx = withMaxTokens(stepCtx, step.MaxTokens)
+	}
+	model, jsonMode := w.Model, w.JSONMode
+	if step.Model != "" {
+		model = step.Model
+	}
+	if step.JSONMode != nil {
+		jsonMode = *step.JSONMode
+	}
 
 	// Validate step configuration
 	if step.Agent == nil {
@@ -196,7 +415,7 @@ func (w *SimpleFlow) executeStep(ctx context.Context, client *Swarm, step *Simpl
 	// Prepare messages
 	messages := make([]map[string]interface{}, 0, len(prevMessages)+2)
 	systemRole := "system"
-	if strings.Contains(w.Model, "o1") || strings.Contains(w.Model, "o3") || strings.Contains(strings.ToLower(w.Model), "deekseek") {
+	if strings.Contains(model, "o1") || strings.Contains(model, "o3") || strings.Contains(strings.ToLower(model), "deekseek") {
 		systemRole = "user"
 	}
 	messages = append(messages, map[string]interface{}{
@@ -206,30 +425,67 @@ func (w *SimpleFlow) executeStep(ctx context.Context, client *Swarm, step *Simpl
 	messages = append(messages, prevMessages...)
 	messages = append(messages, map[string]interface{}{
 		"role":    "user",
-		"content": fmt.Sprintf("Context: %v", mergedVars),
+		"content": formatContext(mergedVars),
 	})
 
-	// Execute step with error handling
-	response, err := client.Run(stepCtx, step.Agent, messages, mergedVars, w.Model, false, w.Verbose, w.MaxTurns, true, w.JSONMode)
-	if err != nil {
-		return &SimpleStepResult{
-			StepName: step.Name,
-			Error:    fmt.Errorf("step %s execution failed: %w", step.Name, err),
-		}, err
+	// Execute step with error handling, re-asking for output that does not
+	// match the step's schema
+	retries := step.OutputRetries
+	if retries <= 0 {
+		retries = DefaultOutputRetries
 	}
+	var stepMessages []map[string]interface{}
+	for attempt := 0; ; attempt++ {
+		response, err := client.Run(stepCtx, step.Agent, messages, mergedVars, model, false, w.Verbose, w.MaxTurns, true, jsonMode)
+		if err != nil {
+			return &SimpleStepResult{
+				StepName: step.Name,
+				Error:    fmt.Errorf("step %s execution failed: %w", step.Name, err),
+			}, err
+		}
 
-	// Validate response
-	if response == nil || len(response.Messages) == 0 {
-		return nil, fmt.Errorf("step %s returned no response", step.Name)
+		// Validate response
+		if response == nil || len(response.Messages) == 0 {
+			return nil, fmt.Errorf("step %s returned no response", step.Name)
+		}
+		stepMessages = append(stepMessages, response.Messages...)
+
+		// Extract result
+		content, _ := response.Messages[len(response.Messages)-1]["content"].(string)
+		err = validateStepOutput(step.OutputSchema, content)
+		if err == nil {
+			return &SimpleStepResult{
+				StepName: step.Name,
+				Content:  content,
+				Messages: stepMessages,
+			}, nil
+		}
+		if attempt >= retries {
+			err = fmt.Errorf("step %s output is invalid after %d attempts: %w", step.Name, attempt+1, err)
+			return &SimpleStepResult{StepName: step.Name, Content: content, Messages: stepMessages, Error: err}, err
+		}
+		if w.Verbose {
+			fmt.Printf("Step %s output is invalid, retrying: %v\n", step.Name, err)
+		}
+		feedback := map[string]interface{}{
+			"role":    "user",
+			"content": fmt.Sprintf("Your reply is invalid: %v\nReply again with only the corrected JSON.", err),
+		}
+		stepMessages = append(stepMessages, feedback)
+		messages = append(append(messages, response.Messages...), feedback)
 	}
+}
 
-	// Extract result
-	content := response.Messages[len(response.Messages)-1]["content"].(string)
-	return &SimpleStepResult{
-		StepName: step.Name,
-		Content:  content,
-		Messages: response.Messages,
-	}, nil
+// validateStepOutput checks a step's output against its schema, if any.
+func validateStepOutput(schema *Schema, content string) error {
+	if schema == nil {
+		return nil
+	}
+	var value interface{}
+	if err := parseJSONReply(content, &value); err != nil {
+		return &SchemaValidationError{Errors: []string{fmt.Sprintf("$: %v", err)}}
+	}
+	return schema.Validate(value)
 }
 
 // Run executes all steps in the workflow sequentially, managing timeouts and
@@ -245,28 +501,85 @@ func (w *SimpleFlow) executeStep(ctx context.Context, client *Swarm, step *Simpl
 //   - []map[string]interface{}: The complete conversation history
 //   - error: Any error encountered during execution
 func (w *SimpleFlow) Run(ctx context.Context, client *Swarm) (string, []map[string]interface{}, error) {
-	// Create workflow context with timeout
-	wfCtx, cancel := context.WithTimeout(ctx, w.Timeout)
-	defer cancel()
-
-	// Initialize workflow
+	// Initialize workflow, which also defaults the timeout
 	if err := w.Initialize(); err != nil {
 		return "", nil, fmt.Errorf("failed to initialize workflow: %w", err)
 	}
 
+	// Create workflow context with timeout
+	wfCtx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
 	// Context variables to pass between steps
-	contextVars := make(map[string]interface{})
+	contextVars := make(map[string]interface{}, len(w.Inputs))
+	for k, v := range w.Inputs {
+		contextVars[k] = v
+	}
 	var messages []map[string]interface{}
 	var lastContent string
+	outputs := make(map[string]interface{}, len(w.Steps))
+	update := func(result *SimpleStepResult) {
+		messages = result.Messages
+		lastContent = result.Content
+		contextVars[fmt.Sprintf("%sResult", result.StepName)] = result.Content
+		outputs[result.StepName] = stepOutput(result.Content)
+	}
+
+	// Restore the steps completed by an earlier run
+	checkpoint, err := w.loadCheckpoint()
+	if err != nil {
+		return "", nil, err
+	}
+	for i, completed := range checkpoint.Steps {
+		result := &SimpleStepResult{StepName: completed.Name, Content: completed.Content, Messages: completed.Messages, Skipped: completed.Skipped, Resumed: true}
+		if !completed.Skipped {
+			update(result)
+		}
+		w.reportProgress(SimpleFlowProgress{Step: completed.Name, Index: i, Total: len(w.Steps), Result: result})
+	}
 
 	// Execute steps sequentially
 	for i, step := range w.Steps {
+		if i < len(checkpoint.Steps) {
+			continue
+		}
 		select {
 		case <-wfCtx.Done():
 			return "", nil, fmt.Errorf("workflow cancelled: %w", wfCtx.Err())
 		default:
-			// Execute single step
-			result, err := w.executeStep(wfCtx, client, &step, contextVars, messages)
+			// Skip steps whose condition is false
+			data := stepTemplateData(contextVars, outputs)
+			if step.condition != nil {
+				run, err := evalCondition(step.condition, data)
+				if err != nil {
+					return "", nil, fmt.Errorf("workflow failed at step %d (%s): %w", i+1, step.Name, err)
+				}
+				if !run {
+					if w.Verbose {
+						fmt.Printf("Step %s skipped\n", step.Name)
+					}
+					w.reportProgress(SimpleFlowProgress{Step: step.Name, Index: i, Total: len(w.Steps), Result: &SimpleStepResult{StepName: step.Name, Skipped: true}})
+					if err := w.saveCheckpoint(checkpoint, SimpleFlowCheckpointStep{Name: step.Name, Skipped: true}); err != nil {
+						return "", nil, err
+					}
+					continue
+				}
+			}
+
+			// Execute single step, or the step for each item of its list
+			w.reportProgress(SimpleFlowProgress{Step: step.Name, Index: i, Total: len(w.Steps)})
+			start := time.Now()
+			var result *SimpleStepResult
+			var err error
+			if step.items != nil {
+				result, err = w.executeForEach(wfCtx, client, &step, contextVars, outputs, messages)
+			} else {
+				result, err = w.executeRendered(wfCtx, client, &step, contextVars, outputs, messages)
+			}
+			if err != nil && result == nil {
+				result = &SimpleStepResult{StepName: step.Name, Error: err}
+			}
+			w.reportProgress(SimpleFlowProgress{Step: step.Name, Index: i, Total: len(w.Steps), Result: result, Duration: time.Since(start)})
 			if err != nil {
 				if w.Verbose {
 					fmt.Printf("Step %s failed: %v\n", step.Name, err)
@@ -276,12 +589,336 @@ func (w *SimpleFlow) Run(ctx context.Context, client *Swarm) (string, []map[stri
 
 			// Update state for next step
 			if result != nil {
-				messages = result.Messages
-				lastContent = result.Content
-				contextVars[fmt.Sprintf("%sResult", step.Name)] = result.Content
+				update(result)
+				if err := w.saveCheckpoint(checkpoint, SimpleFlowCheckpointStep{Name: step.Name, Content: result.Content, Messages: result.Messages}); err != nil {
+					return "", nil, err
+				}
 			}
 		}
 	}
 
+	if err := w.removeCheckpoint(); err != nil {
+		return "", nil, err
+	}
 	return lastContent, messages, nil
 }
+
+// executeRendered executes a step with its inputs rendered against the
+// context variables and the outputs of earlier steps. The step is copied, so
+// the flow keeps the input templates for the next run.
+func (w *SimpleFlow) executeRendered(ctx context.Context, client *Swarm, step *SimpleFlowStep, contextVars map[string]interface{}, outputs map[string]interface{}, prevMessages []map[string]interface{}) (*SimpleStepResult, error) {
+	inputs, err := renderInputs(step.Inputs, stepTemplateData(contextVars, outputs))
+	if err != nil {
+		return nil, err
+	}
+	rendered := *step
+	rendered.Inputs, _ = inputs.(map[string]interface{})
+	return w.executeStepWithRetry(ctx, client, &rendered, contextVars, prevMessages)
+}
+
+// executeForEach executes a ForEach step once for each item of its list, up
+// to step.Concurrency at a time, and collects the outputs into a JSON array.
+// Outputs that are JSON are kept as values rather than strings.
+func (w *SimpleFlow) executeForEach(ctx context.Context, client *Swarm, step *SimpleFlowStep, contextVars map[string]interface{}, outputs map[string]interface{}, prevMessages []map[string]interface{}) (*SimpleStepResult, error) {
+	items, err := evalForEach(step.items, stepTemplateData(contextVars, outputs))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, len(items))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(step.Concurrency, 1))
+	for i, item := range items {
+		itemVars := make(map[string]interface{}, len(contextVars)+2)
+		for k, v := range contextVars {
+			itemVars[k] = v
+		}
+		itemVars["item"] = item
+		itemVars["index"] = i
+		g.Go(func() error {
+			result, err := w.executeRendered(gctx, client, step, itemVars, outputs, prevMessages)
+			if err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+			var value interface{}
+			if err := parseJSONReply(result.Content, &value); err == nil {
+				results[i] = value
+			} else {
+				results[i] = result.Content
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode step outputs: %w", err)
+	}
+	return &SimpleStepResult{
+		StepName: step.Name,
+		Content:  string(content),
+		Messages: []map[string]interface{}{{"role": "assistant", "content": string(content)}},
+	}, nil
+}
+
+// executeStepWithRetry executes a step, retrying failures as configured by
+// the step's retry policy.
+func (w *SimpleFlow) executeStepWithRetry(ctx context.Context, client *Swarm, step *SimpleFlowStep, contextVars map[string]interface{}, prevMessages []map[string]interface{}) (*SimpleStepResult, error) {
+	policy := step.retryPolicy()
+	for attempt := 0; ; attempt++ {
+		result, err := w.executeStep(ctx, client, step, contextVars, prevMessages)
+		if err == nil || policy == nil || attempt >= policy.MaxRetries || !policy.shouldRetry(err) || ctx.Err() != nil {
+			return result, err
+		}
+		delay := policy.retryDelay(attempt, err)
+		if w.Verbose {
+			fmt.Printf("Step %s failed (attempt %d/%d), retrying in %v: %v\n", step.Name, attempt+1, policy.MaxRetries+1, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryPolicy returns the retry policy of the step, or nil if failures are
+// not retried.
+func (s *SimpleFlowStep) retryPolicy() *RetryPolicy {
+	if s.Retry != nil {
+		return s.Retry.withDefaults()
+	}
+	if s.Retries == 0 {
+		return nil
+	}
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = s.Retries
+	return policy
+}
+
+// conditionFuncs are the functions available in step When conditions, in
+// addition to those of template instructions.
+var conditionFuncs = template.FuncMap{
+	"contains": func(s interface{}, substr string) bool {
+		return strings.Contains(conditionString(s), substr)
+	},
+	"hasPrefix": func(s interface{}, prefix string) bool {
+		return strings.HasPrefix(conditionString(s), prefix)
+	},
+	"hasSuffix": func(s interface{}, suffix string) bool {
+		return strings.HasSuffix(conditionString(s), suffix)
+	},
+	// fromJSON decodes a JSON step output, e.g.
+	// {{eq (fromJSON .triageResult).severity "critical"}}
+	"fromJSON": func(s interface{}) (map[string]interface{}, error) {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(conditionString(s)), &v); err != nil {
+			return nil, fmt.Errorf("fromJSON: %w", err)
+		}
+		return v, nil
+	},
+}
+
+// parseCondition parses a When condition. Conditions without template
+// delimiters are wrapped in {{ }}.
+func parseCondition(when string) (*template.Template, error) {
+	if !strings.Contains(when, "{{") {
+		when = "{{" + when + "}}"
+	}
+	tmpl, err := template.New("when").Funcs(instructionFuncs).Funcs(conditionFuncs).Parse(rewriteStepRefs(when))
+	if err != nil {
+		return nil, fmt.Errorf("invalid when condition: %w", err)
+	}
+	return tmpl, nil
+}
+
+// evalCondition renders a When condition against the step template data and
+// reports whether it holds.
+func evalCondition(tmpl *template.Template, data map[string]interface{}) (bool, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return false, fmt.Errorf("failed to evaluate when condition: %w", err)
+	}
+	switch strings.TrimSpace(b.String()) {
+	case "", "false", "0", "<no value>":
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseForEach parses the list expression of a ForEach step, which is passed
+// to a "collect" function so its value is kept as is.
+func parseForEach(expr string) (*template.Template, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "{{") && strings.HasSuffix(expr, "}}") {
+		expr = strings.TrimSpace(expr[2 : len(expr)-2])
+	}
+	expr = strings.TrimSuffix(strings.TrimPrefix(rewriteStepRefs("{{"+expr+"}}"), "{{"), "}}")
+	funcs := template.FuncMap{"collect": func(interface{}) string { return "" }}
+	tmpl, err := template.New("foreach").Funcs(instructionFuncs).Funcs(conditionFuncs).Funcs(funcs).Parse("{{collect (" + expr + ")}}")
+	if err != nil {
+		return nil, fmt.Errorf("invalid foreach: %w", err)
+	}
+	return tmpl, nil
+}
+
+// evalForEach evaluates a ForEach expression into the list of items.
+func evalForEach(tmpl *template.Template, data map[string]interface{}) ([]interface{}, error) {
+	var value interface{}
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Funcs(template.FuncMap{"collect": func(v interface{}) string {
+		value = v
+		return ""
+	}})
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return nil, fmt.Errorf("failed to evaluate foreach: %w", err)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("foreach has no value")
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "[") {
+			var items []interface{}
+			if err := parseJSONReply(v, &items); err != nil {
+				return nil, fmt.Errorf("foreach is not a JSON array: %w", err)
+			}
+			return items, nil
+		}
+		var items []interface{}
+		for _, line := range strings.Split(v, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+		return items, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("foreach is a %T, not a list", value)
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// conditionString converts a template value to a string; missing values are
+// empty.
+func conditionString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// stepRefPattern matches references to step outputs in template actions.
+// Step names may contain hyphens, which template field names cannot, so
+// "steps.get-weather.output" is rewritten to
+// `(index .steps "get-weather").output`.
+var (
+	templateActionPattern = regexp.MustCompile(`(?s){{.*?}}`)
+	stepRefPattern        = regexp.MustCompile(`(^|[^\w.$"])steps\.([\w-]+)`)
+)
+
+// rewriteStepRefs rewrites the step output references of a template.
+func rewriteStepRefs(text string) string {
+	return templateActionPattern.ReplaceAllStringFunc(text, func(action string) string {
+		return stepRefPattern.ReplaceAllString(action, `${1}(index .steps "${2}")`)
+	})
+}
+
+// stepTemplateData returns the data of templated step inputs and When
+// conditions: the context variables, and "steps" mapping the name of each
+// finished step to its "output" text and, when the output is JSON, its
+// decoded "data".
+func stepTemplateData(contextVars map[string]interface{}, outputs map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(contextVars)+1)
+	for k, v := range contextVars {
+		data[k] = v
+	}
+	data["steps"] = outputs
+	return data
+}
+
+// stepOutput returns the template data of a step output.
+func stepOutput(content string) map[string]interface{} {
+	output := map[string]interface{}{"output": content, "data": nil}
+	var data interface{}
+	if err := parseJSONReply(content, &data); err == nil {
+		output["data"] = data
+	}
+	return output
+}
+
+// renderInputs renders the templated strings of step inputs, including those
+// nested in maps and lists. With nil data, the templates are only parsed.
+func renderInputs(v interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New("input").Funcs(instructionFuncs).Funcs(conditionFuncs).Option("missingkey=error").Parse(rewriteStepRefs(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid input template: %w", err)
+		}
+		if data == nil {
+			return v, nil
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("failed to render input: %w", err)
+		}
+		return b.String(), nil
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+		rendered := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			r, err := renderInputs(value, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			rendered[key] = r
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, value := range v {
+			r, err := renderInputs(value, data)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			rendered[i] = r
+		}
+		return rendered, nil
+	}
+	return v, nil
+}
+
+// formatContext renders the context variables of a step as a YAML document,
+// which keeps nested values and multi-line outputs readable to the model.
+func formatContext(vars map[string]interface{}) string {
+	if len(vars) == 0 {
+		return "Context: none"
+	}
+	data, err := yaml.Marshal(vars)
+	if err != nil {
+		data, _ = json.MarshalIndent(vars, "", "  ")
+	}
+	return "Context:\n" + strings.TrimRight(string(data), "\n")
+}
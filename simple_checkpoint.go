@@ -0,0 +1,134 @@
+package swarm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrCheckpointMismatch is returned by SimpleFlow.Run when resuming from a
+// checkpoint written by a different flow, or with different inputs.
+var ErrCheckpointMismatch = errors.New("checkpoint does not match the flow")
+
+// SimpleFlowCheckpoint records the steps a SimpleFlow run completed, so a
+// later run with Resume set continues after them.
+type SimpleFlowCheckpoint struct {
+	// Flow is the name of the flow
+	Flow string `json:"flow"`
+	// Inputs are the flow inputs of the run
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+	// Steps are the completed steps, in order
+	Steps []SimpleFlowCheckpointStep `json:"steps"`
+	// UpdatedAt is when the last step completed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SimpleFlowCheckpointStep is a step completed by a SimpleFlow run.
+type SimpleFlowCheckpointStep struct {
+	Name     string                   `json:"name"`
+	Content  string                   `json:"content,omitempty"`
+	Messages []map[string]interface{} `json:"messages,omitempty"`
+	// Skipped reports that the step's When condition was false
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// SimpleFlowCheckpointPath returns the default checkpoint path of the flow
+// loaded from path, a sidecar file next to it.
+func SimpleFlowCheckpointPath(path string) string {
+	return path + ".checkpoint.json"
+}
+
+// LoadSimpleFlowCheckpoint reads a checkpoint written by SimpleFlow.Run, or
+// returns ErrCheckpointNotFound.
+func LoadSimpleFlowCheckpoint(path string) (*SimpleFlowCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrCheckpointNotFound
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp SimpleFlowCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+	for _, step := range cp.Steps {
+		if err := restoreToolCalls(step.Messages); err != nil {
+			return nil, err
+		}
+	}
+	return &cp, nil
+}
+
+// loadCheckpoint returns the checkpoint a run continues: the one recorded in
+// w.Checkpoint when resuming, or else an empty one.
+func (w *SimpleFlow) loadCheckpoint() (*SimpleFlowCheckpoint, error) {
+	fresh := &SimpleFlowCheckpoint{Flow: w.Name, Inputs: w.Inputs}
+	if w.Checkpoint == "" || !w.Resume {
+		return fresh, nil
+	}
+	cp, err := LoadSimpleFlowCheckpoint(w.Checkpoint)
+	if errors.Is(err, ErrCheckpointNotFound) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cp.Flow != w.Name {
+		return nil, fmt.Errorf("%w: checkpoint of flow %q", ErrCheckpointMismatch, cp.Flow)
+	}
+	if len(cp.Steps) > len(w.Steps) {
+		return nil, fmt.Errorf("%w: %d steps completed, but the flow has %d", ErrCheckpointMismatch, len(cp.Steps), len(w.Steps))
+	}
+	for i, step := range cp.Steps {
+		if step.Name != w.Steps[i].Name {
+			return nil, fmt.Errorf("%w: step %d is %s, not %s", ErrCheckpointMismatch, i+1, w.Steps[i].Name, step.Name)
+		}
+	}
+	saved, err := json.Marshal(cp.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inputs: %w", err)
+	}
+	current, err := json.Marshal(w.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inputs: %w", err)
+	}
+	if !bytes.Equal(saved, current) {
+		return nil, fmt.Errorf("%w: inputs changed", ErrCheckpointMismatch)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint records a completed step in cp and writes cp atomically to
+// w.Checkpoint, if set.
+func (w *SimpleFlow) saveCheckpoint(cp *SimpleFlowCheckpoint, step SimpleFlowCheckpointStep) error {
+	cp.Steps = append(cp.Steps, step)
+	cp.UpdatedAt = time.Now()
+	if w.Checkpoint == "" {
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	tmp := w.Checkpoint + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(tmp, w.Checkpoint)
+}
+
+// removeCheckpoint removes the checkpoint of a finished run.
+func (w *SimpleFlow) removeCheckpoint() error {
+	if w.Checkpoint == "" {
+		return nil
+	}
+	if err := os.Remove(w.Checkpoint); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
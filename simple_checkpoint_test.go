@@ -0,0 +1,84 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// failingAfterClient replies with its script for the first n requests and
+// fails afterwards.
+type failingAfterClient struct {
+	*scriptedClient
+	n int
+}
+
+func (c *failingAfterClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if len(c.requests) >= c.n {
+		return nil, errors.New("service unavailable")
+	}
+	return c.scriptedClient.CreateChatCompletion(ctx, params)
+}
+
+func TestSimpleFlowResume(t *testing.T) {
+	path := SimpleFlowCheckpointPath(filepath.Join(t.TempDir(), "flow.yaml"))
+	var progress []string
+	newFlow := func() *SimpleFlow {
+		return &SimpleFlow{
+			Name:   "pipeline",
+			Inputs: map[string]interface{}{"topic": "bees"},
+			Steps: []SimpleFlowStep{
+				{Name: "one", Instructions: "One."},
+				{Name: "maybe", Instructions: "Maybe.", When: ".never"},
+				{Name: "two", Instructions: "Two.", Inputs: map[string]interface{}{"previous": "{{ steps.one.output }}"}},
+				{Name: "three", Instructions: "Three."},
+			},
+			Checkpoint: path,
+			Progress: func(p SimpleFlowProgress) {
+				if p.Result != nil && p.Result.Resumed {
+					progress = append(progress, p.Step)
+				}
+			},
+		}
+	}
+
+	// The first run fails at step two
+	_, _, err := newFlow().Run(context.Background(), NewSwarm(&failingAfterClient{scriptedClient: newScriptedClient("one"), n: 1}))
+	AssertError(t, err, "Expected the first run to fail")
+	checkpoint, err := LoadSimpleFlowCheckpoint(path)
+	AssertNoError(t, err, "LoadSimpleFlowCheckpoint")
+	AssertEqual(t, 2, len(checkpoint.Steps), "completed steps")
+	AssertEqual(t, true, checkpoint.Steps[1].Skipped, "skipped step")
+
+	// Changed inputs do not match the checkpoint
+	flow := newFlow()
+	flow.Resume = true
+	flow.Inputs["topic"] = "ants"
+	_, _, err = flow.Run(context.Background(), NewSwarm(newScriptedClient()))
+	AssertEqual(t, true, errors.Is(err, ErrCheckpointMismatch), "Expected ErrCheckpointMismatch")
+
+	// Resuming runs the remaining steps with the restored outputs
+	client := newScriptedClient("two", "three")
+	flow = newFlow()
+	flow.Resume = true
+	result, _, err := flow.Run(context.Background(), NewSwarm(client))
+	AssertNoError(t, err, "Resume")
+	AssertEqual(t, "three", result, "result")
+	AssertEqual(t, 2, len(client.requests), "requests")
+	AssertEqual(t, "one,maybe", strings.Join(progress, ","), "resumed steps")
+	context := client.requests[0].Messages[len(client.requests[0].Messages)-1].OfUser.Content.OfString.Value
+	if !strings.Contains(context, "previous: one") {
+		t.Errorf("Expected the restored output in the context, got %q", context)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed, got %v", err)
+	}
+
+	_, err = LoadSimpleFlowCheckpoint(path)
+	AssertEqual(t, true, errors.Is(err, ErrCheckpointNotFound), "Expected ErrCheckpointNotFound")
+}
@@ -0,0 +1,149 @@
+package swarm
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretProvider resolves the "${secret:name}" references of SimpleFlow
+// files, e.g. from a vault or a cloud secret manager.
+type SecretProvider func(name string) (string, error)
+
+// LoadOption configures LoadSimpleFlow and LoadWorkflow.
+type LoadOption func(*loadOptions)
+
+// loadOptions holds the settings applied by LoadOption functions.
+type loadOptions struct {
+	lookupEnv func(name string) (string, bool)
+	secrets   SecretProvider
+}
+
+// WithSecretProvider resolves "${secret:name}" references with provider.
+func WithSecretProvider(provider SecretProvider) LoadOption {
+	return func(o *loadOptions) {
+		o.secrets = provider
+	}
+}
+
+// WithEnvLookup resolves "${NAME}" references with lookup instead of the
+// process environment.
+func WithEnvLookup(lookup func(name string) (string, bool)) LoadOption {
+	return func(o *loadOptions) {
+		o.lookupEnv = lookup
+	}
+}
+
+// variablePattern matches "$${...}" escapes and "${...}" references.
+var variablePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// expandVariables replaces the references in s:
+//   - ${NAME} is the environment variable NAME, which must be set
+//   - ${NAME:-default} is NAME, or default if NAME is unset or empty
+//   - ${secret:name} is the secret name returned by the secret provider
+//   - $${...} is the literal text ${...}
+func (o *loadOptions) expandVariables(s string) (string, error) {
+	var expandErr error
+	expanded := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		ref := match[2 : len(match)-1]
+		if name, ok := strings.CutPrefix(ref, "secret:"); ok {
+			if o.secrets == nil {
+				expandErr = fmt.Errorf("secret %q referenced without a secret provider", name)
+				return match
+			}
+			value, err := o.secrets(name)
+			if err != nil {
+				expandErr = fmt.Errorf("failed to resolve secret %q: %w", name, err)
+				return match
+			}
+			return value
+		}
+
+		name, fallback, hasDefault := strings.Cut(ref, ":-")
+		value, ok := o.lookupEnv(name)
+		if hasDefault && value == "" {
+			return fallback
+		}
+		if !ok {
+			expandErr = fmt.Errorf("environment variable %s is not set", name)
+			return match
+		}
+		return value
+	})
+	return expanded, expandErr
+}
+
+// expandNode expands the references in the scalar values of a YAML document.
+// Expanded plain scalars are resolved again, so "${PORT}" decodes as a number.
+func (o *loadOptions) expandNode(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+		value, err := o.expandVariables(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Value = value
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	case yaml.MappingNode:
+		// Expand values only, keys are field names
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := o.expandNode(node.Content[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if err := o.expandNode(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseSimpleFlow decodes a SimpleFlow YAML document.
+func parseSimpleFlow(data []byte, opts ...LoadOption) (*SimpleFlow, error) {
+	var workflow SimpleFlow
+	if err := decodeYAML(data, &workflow, opts...); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// decodeYAML decodes a YAML document into v, expanding environment variable
+// and secret references.
+func decodeYAML(data []byte, v interface{}, opts ...LoadOption) error {
+	options := &loadOptions{lookupEnv: os.LookupEnv}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to unmarshal workflow: %w", err)
+	}
+	if doc.Kind == 0 {
+		return nil
+	}
+	if err := options.expandNode(&doc); err != nil {
+		return fmt.Errorf("failed to expand workflow variables: %w", err)
+	}
+	if err := doc.Decode(v); err != nil {
+		return fmt.Errorf("failed to unmarshal workflow: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package swarm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSimpleFlowVariables(t *testing.T) {
+	flowYAML := `
+name: ${FLOW_NAME}
+model: ${MODEL:-gpt-4o-mini}
+timeout: ${TIMEOUT}
+max_turns: ${MAX_TURNS}
+tools:
+  - name: lookup
+    type: http
+    url: "${API_BASE}/items/{{.id}}"
+    headers:
+      Authorization: "Bearer ${secret:api-token}"
+steps:
+  - name: ${FLOW_NAME}
+    instructions: "Costs are in $${CURRENCY}."
+`
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	AssertNoError(t, os.WriteFile(path, []byte(flowYAML), 0644), "WriteFile")
+	env := map[string]string{"FLOW_NAME": "lookup", "TIMEOUT": "2m", "MAX_TURNS": "5", "API_BASE": "https://api.example.com", "MODEL": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	secrets := func(name string) (string, error) {
+		if name != "api-token" {
+			return "", errors.New("unknown secret")
+		}
+		return "s3cret", nil
+	}
+
+	flow, err := LoadSimpleFlow(path, WithEnvLookup(lookup), WithSecretProvider(secrets))
+	AssertNoError(t, err, "LoadSimpleFlow")
+	AssertEqual(t, "lookup", flow.Name, "name")
+	AssertEqual(t, "gpt-4o-mini", flow.Model, "default value")
+	AssertEqual(t, 2*time.Minute, flow.Timeout, "timeout")
+	AssertEqual(t, 5, flow.MaxTurns, "max turns")
+	AssertEqual(t, "https://api.example.com/items/{{.id}}", flow.Tools[0].URL, "url")
+	AssertEqual(t, "Bearer s3cret", flow.Tools[0].Headers["Authorization"], "secret")
+	AssertEqual(t, "Costs are in ${CURRENCY}.", flow.Steps[0].Instructions, "escaped reference")
+
+	delete(env, "API_BASE")
+	_, err = LoadSimpleFlow(path, WithEnvLookup(lookup), WithSecretProvider(secrets))
+	if err == nil || !strings.Contains(err.Error(), "API_BASE") {
+		t.Errorf("Expected error for an unset variable, got %v", err)
+	}
+	env["API_BASE"] = "https://api.example.com"
+	_, err = LoadSimpleFlow(path, WithEnvLookup(lookup))
+	AssertError(t, err, "Expected error for a secret without a provider")
+	_, err = LoadSimpleFlow(path, WithEnvLookup(lookup), WithSecretProvider(func(string) (string, error) {
+		return "", errors.New("vault sealed")
+	}))
+	AssertError(t, err, "Expected the secret provider error")
+}
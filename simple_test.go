@@ -3,10 +3,17 @@ package swarm
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/openai/openai-go"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSimpleFlow(t *testing.T) {
@@ -190,3 +197,313 @@ func TestSimpleFlowSaveLoad(t *testing.T) {
 		t.Errorf("Expected input key=value, got %v", loaded.Steps[0].Inputs["key"])
 	}
 }
+
+func TestSimpleFlowInputsAndProgress(t *testing.T) {
+	client := newScriptedClient("facts", "summary")
+	var progress []string
+	workflow := &SimpleFlow{
+		Name:   "research",
+		Model:  "gpt-4o",
+		Inputs: map[string]interface{}{"topic": "bees", "depth": "short"},
+		Steps: []SimpleFlowStep{
+			{Name: "research", Instructions: "Research the topic.", Inputs: map[string]interface{}{"depth": "long"}},
+			{Name: "summarize", Instructions: "Summarize."},
+		},
+		Progress: func(p SimpleFlowProgress) {
+			if p.Result == nil {
+				progress = append(progress, fmt.Sprintf("start %s %d/%d", p.Step, p.Index+1, p.Total))
+			} else {
+				progress = append(progress, fmt.Sprintf("done %s: %s", p.Step, p.Result.Content))
+			}
+		},
+	}
+
+	result, _, err := workflow.Run(context.Background(), NewSwarm(client))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "summary", result, "result")
+	AssertEqual(t, "start research 1/2,done research: facts,start summarize 2/2,done summarize: summary", strings.Join(progress, ","), "progress")
+
+	context := client.requests[0].Messages[len(client.requests[0].Messages)-1].OfUser.Content.OfString.Value
+	AssertEqual(t, "Context:\ndepth: long\ntopic: bees", context, "flow inputs overridden by step inputs")
+}
+
+func TestSimpleFlowTemplatedInputs(t *testing.T) {
+	client := newScriptedClient(`{"city": "Paris", "temp": 21}`, "Take sunglasses.", "done")
+	workflow := &SimpleFlow{
+		Name:   "trip",
+		Inputs: map[string]interface{}{"traveler": "Ada"},
+		Steps: []SimpleFlowStep{
+			{Name: "get-weather", Instructions: "Get the weather."},
+			{Name: "advise", Instructions: "Advise.", Inputs: map[string]interface{}{
+				"weather": "{{ steps.get-weather.output }}",
+				"summary": "{{ .traveler }} goes to {{ steps.get-weather.data.city }} ({{ steps.get-weather.data.temp }}C)",
+			}},
+			{Name: "report", Instructions: "Report.", When: `eq steps.get-weather.data.city "Paris"`, Inputs: map[string]interface{}{
+				"notes": []interface{}{"{{ steps.advise.output }}"},
+			}},
+		},
+	}
+
+	_, _, err := workflow.Run(context.Background(), NewSwarm(client))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, 3, len(client.requests), "requests")
+	prompt := func(i int) string {
+		return client.requests[i].Messages[len(client.requests[i].Messages)-1].OfUser.Content.OfString.Value
+	}
+	for _, want := range []string{`weather: '{"city": "Paris", "temp": 21}'`, "summary: Ada goes to Paris (21C)", "traveler: Ada"} {
+		if !strings.Contains(prompt(1), want) {
+			t.Errorf("Expected %q in the context, got %q", want, prompt(1))
+		}
+	}
+	if !strings.Contains(prompt(2), "notes:\n    - Take sunglasses.") {
+		t.Errorf("Expected nested inputs to be rendered, got %q", prompt(2))
+	}
+	AssertEqual(t, "{{ steps.get-weather.output }}", workflow.Steps[1].Inputs["weather"], "templates are kept")
+
+	workflow = &SimpleFlow{Steps: []SimpleFlowStep{{Name: "a", Inputs: map[string]interface{}{"x": "{{ steps.missing.output }}"}}}}
+	_, _, err = workflow.Run(context.Background(), NewSwarm(newScriptedClient("ok")))
+	AssertError(t, err, "Expected error for a reference to an unknown step")
+
+	workflow = &SimpleFlow{Steps: []SimpleFlowStep{{Name: "a", Inputs: map[string]interface{}{"x": "{{ .x"}}}}
+	AssertError(t, workflow.Initialize(), "Expected error for an invalid input template")
+}
+
+func TestSimpleFlowWhen(t *testing.T) {
+	newFlow := func() *SimpleFlow {
+		return &SimpleFlow{
+			Name:   "support",
+			Inputs: map[string]interface{}{"tier": "gold"},
+			Steps: []SimpleFlowStep{
+				{Name: "triage", Instructions: "Triage the issue."},
+				{Name: "escalate", Instructions: "Escalate.", When: `contains .triageResult "critical"`},
+				{Name: "vip", Instructions: "Thank the customer.", When: `{{eq (fromJSON .triageResult).tier "gold"}}`},
+				{Name: "reply", Instructions: "Reply.", When: `.missing`},
+			},
+		}
+	}
+
+	var skipped []string
+	flow := newFlow()
+	flow.Progress = func(p SimpleFlowProgress) {
+		if p.Result != nil && p.Result.Skipped {
+			skipped = append(skipped, p.Step)
+		}
+	}
+	result, _, err := flow.Run(context.Background(), NewSwarm(newScriptedClient(`{"severity": "critical", "tier": "gold"}`, "paged on-call", "thanks")))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "thanks", result, "result")
+	AssertEqual(t, "reply", strings.Join(skipped, ","), "skipped steps")
+	if len(flow.Steps[0].Agent.Functions) != 0 {
+		t.Errorf("Expected no handoff to a conditional step, got %d functions", len(flow.Steps[0].Agent.Functions))
+	}
+
+	skipped = nil
+	flow = newFlow()
+	flow.Progress = func(p SimpleFlowProgress) {
+		if p.Result != nil && p.Result.Skipped {
+			skipped = append(skipped, p.Step)
+		}
+	}
+	result, _, err = flow.Run(context.Background(), NewSwarm(newScriptedClient(`{"severity": "low", "tier": "gold"}`, "thanks")))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "thanks", result, "result")
+	AssertEqual(t, "escalate,reply", strings.Join(skipped, ","), "skipped steps")
+
+	flow = newFlow()
+	flow.Steps[1].When = "contains .triageResult"
+	_, _, err = flow.Run(context.Background(), NewSwarm(newScriptedClient("not json")))
+	AssertError(t, err, "Expected error for a condition with missing arguments")
+	flow = newFlow()
+	flow.Steps[1].When = "{{if}}"
+	AssertError(t, flow.Initialize(), "Expected error for an invalid condition")
+}
+
+func TestSimpleFlowStepOverrides(t *testing.T) {
+	workflowYAML := `
+name: overrides
+model: gpt-4o
+json_mode: true
+steps:
+  - name: extract
+    instructions: "Extract the facts."
+    model: gpt-4o-mini
+    temperature: 0
+    max_tokens: 200
+  - name: synthesize
+    instructions: "Write the report."
+    json_mode: false
+`
+	var flow SimpleFlow
+	AssertNoError(t, yaml.Unmarshal([]byte(workflowYAML), &flow), "Unmarshal")
+	client := newScriptedClient(`{"facts": []}`, "report")
+	_, _, err := flow.Run(context.Background(), NewSwarm(client))
+	AssertNoError(t, err, "Run")
+
+	extract, synthesize := client.requests[0], client.requests[1]
+	AssertEqual(t, openai.ChatModel("gpt-4o-mini"), extract.Model, "step model")
+	AssertEqual(t, true, extract.Temperature.IsPresent(), "step temperature")
+	AssertEqual(t, 0.0, extract.Temperature.Or(1), "step temperature")
+	AssertEqual(t, int64(200), extract.MaxCompletionTokens.Or(0), "step max tokens")
+	AssertEqual(t, true, extract.ResponseFormat.OfJSONObject != nil, "workflow JSON mode")
+
+	AssertEqual(t, openai.ChatModel("gpt-4o"), synthesize.Model, "workflow model")
+	AssertEqual(t, false, synthesize.Temperature.IsPresent(), "no temperature")
+	AssertEqual(t, false, synthesize.MaxCompletionTokens.IsPresent(), "no max tokens")
+	AssertEqual(t, true, synthesize.ResponseFormat.OfJSONObject == nil, "step JSON mode")
+}
+
+func TestSimpleFlowOutputSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"type": "object", "required": ["severity"], "properties": {"severity": {"enum": ["low", "critical"]}}}`))
+	AssertNoError(t, err, "ParseSchema")
+	newFlow := func() *SimpleFlow {
+		return &SimpleFlow{
+			Name:  "triage",
+			Steps: []SimpleFlowStep{{Name: "triage", Instructions: "Triage the issue.", OutputSchema: schema, OutputRetries: 1}},
+		}
+	}
+
+	client := newScriptedClient(`{"severity": "urgent"}`, "```json\n{\"severity\": \"critical\"}\n```")
+	result, messages, err := newFlow().Run(context.Background(), NewSwarm(client))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "```json\n{\"severity\": \"critical\"}\n```", result, "result")
+	AssertEqual(t, 3, len(messages), "messages with the feedback")
+	feedback := client.requests[1].Messages[len(client.requests[1].Messages)-1].OfUser.Content.OfString.Value
+	if !strings.Contains(feedback, "$.severity") {
+		t.Errorf("Expected validation errors in the feedback, got %q", feedback)
+	}
+	if instructions := client.requests[0].Messages[0].OfSystem.Content.OfString.Value; !strings.Contains(instructions, `"required":["severity"]`) {
+		t.Errorf("Expected the schema in the instructions, got %q", instructions)
+	}
+
+	client = newScriptedClient("not json", `{}`)
+	_, _, err = newFlow().Run(context.Background(), NewSwarm(client))
+	var validationErr *SchemaValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected a schema validation error after the retries, got %v", err)
+	}
+	AssertEqual(t, 2, len(client.requests), "requests")
+}
+
+func TestSimpleFlowRetry(t *testing.T) {
+	flowYAML := `
+name: retry
+steps:
+  - name: fetch
+    instructions: Fetch.
+    retries: 1
+  - name: summarize
+    instructions: Summarize.
+    retry:
+      max_retries: 2
+      initial_interval: 1ms
+`
+	var workflow SimpleFlow
+	AssertNoError(t, yaml.Unmarshal([]byte(flowYAML), &workflow), "Unmarshal")
+	AssertEqual(t, time.Millisecond, workflow.Steps[1].retryPolicy().InitialInterval, "retry interval")
+	AssertEqual(t, DefaultRetryPolicy().Multiplier, workflow.Steps[1].retryPolicy().Multiplier, "default multiplier")
+
+	// Transient failures are retried; a short backoff keeps the test fast
+	workflow.Steps[0].Retry = &RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond}
+	flaky := &flakyClient{OpenAIClient: newScriptedClient("data", "summary"), errs: []error{
+		newRateLimitError(time.Millisecond),
+	}}
+	result, _, err := workflow.Run(context.Background(), NewSwarm(flaky))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "summary", result, "result")
+	AssertEqual(t, 3, flaky.calls, "calls")
+
+	// Failures beyond the retries fail the flow
+	flaky = &flakyClient{OpenAIClient: newScriptedClient("data"), errs: []error{
+		newRateLimitError(time.Millisecond), newRateLimitError(time.Millisecond),
+	}}
+	_, _, err = workflow.Run(context.Background(), NewSwarm(flaky))
+	_, limited := RetryAfter(err)
+	AssertEqual(t, true, limited, "Expected the rate limit error")
+	AssertEqual(t, 2, flaky.calls, "calls")
+
+	// Errors not listed in the policy are not retried
+	boom := errors.New("boom")
+	workflow.Steps[0].Retry.Errors = []error{context.DeadlineExceeded}
+	flaky = &flakyClient{OpenAIClient: newScriptedClient("data"), errs: []error{boom}}
+	_, _, err = workflow.Run(context.Background(), NewSwarm(flaky))
+	AssertEqual(t, true, errors.Is(err, boom), "Expected the step error")
+	AssertEqual(t, 1, flaky.calls, "calls")
+
+	workflow.Steps[0].Retry = &RetryPolicy{MaxRetries: 1, InitialInterval: time.Second, MaxInterval: time.Millisecond}
+	AssertError(t, workflow.Initialize(), "Expected error for an invalid retry policy")
+}
+
+func TestSimpleFlowForEach(t *testing.T) {
+	var mu sync.Mutex
+	var active, maxActive int
+	client := newRouterClient(func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, "list: files"):
+			return "a.txt\nb.txt\n\nc.txt\n"
+		case strings.Contains(prompt, "file: "):
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			file := contextValue(prompt, "file")
+			if file == "b.txt" {
+				return `{"file": "b.txt", "lines": 2}`
+			}
+			return "summary of " + file
+		case strings.Contains(prompt, "summaries: "):
+			return contextValue(prompt, "summaries")
+		}
+		return "unexpected"
+	})
+	workflow := &SimpleFlow{
+		Name: "summarize-files",
+		Steps: []SimpleFlowStep{
+			{Name: "list-files", Instructions: "List the files.", Inputs: map[string]interface{}{"list": "files"}},
+			{Name: "summarize", Instructions: "Summarize the file.", ForEach: "steps.list-files.output", Concurrency: 2,
+				Inputs: map[string]interface{}{"file": "{{ .item }}"}},
+			{Name: "report", Instructions: "Report.", Inputs: map[string]interface{}{"summaries": "{{ json steps.summarize.data }}"}},
+		},
+	}
+	AssertNoError(t, workflow.Initialize(), "Initialize")
+	for _, fn := range workflow.Steps[0].Agent.Functions {
+		if strings.HasPrefix(fn.Name(), "handoffTo") {
+			t.Errorf("Expected no handoff to a foreach step, got %s", fn.Name())
+		}
+	}
+
+	result, _, err := workflow.Run(context.Background(), NewSwarm(client))
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, `'["summary of a.txt",{"file":"b.txt","lines":2},"summary of c.txt"]'`, result, "collected outputs")
+	AssertEqual(t, 2, maxActive, "concurrency")
+
+	// Lists from the context, and values that are not lists
+	items, err := evalForEach(mustParseForEach(t, "{{ .files }}"), map[string]interface{}{"files": []string{"x", "y"}})
+	AssertNoError(t, err, "evalForEach")
+	AssertEqual(t, 2, len(items), "items")
+	items, err = evalForEach(mustParseForEach(t, ".files"), map[string]interface{}{"files": "```json\n[1, 2, 3]\n```"})
+	AssertNoError(t, err, "evalForEach JSON")
+	AssertEqual(t, 3, len(items), "JSON items")
+	_, err = evalForEach(mustParseForEach(t, ".count"), map[string]interface{}{"count": 3})
+	AssertError(t, err, "Expected error for a value that is not a list")
+	_, err = evalForEach(mustParseForEach(t, ".missing"), map[string]interface{}{})
+	AssertError(t, err, "Expected error for a missing list")
+	_, err = parseForEach(".files | (")
+	AssertError(t, err, "Expected error for an invalid expression")
+}
+
+// contextValue returns the value of key in a step context prompt.
+func contextValue(prompt, key string) string {
+	value := strings.SplitN(prompt, key+": ", 2)[1]
+	return strings.TrimSpace(strings.SplitN(value, "\n", 2)[0])
+}
+
+func mustParseForEach(t *testing.T, expr string) *template.Template {
+	t.Helper()
+	tmpl, err := parseForEach(expr)
+	AssertNoError(t, err, "parseForEach")
+	return tmpl
+}
@@ -0,0 +1,328 @@
+package swarm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Declarative tool types of SimpleFlow.
+const (
+	// ToolTypeHTTP sends an HTTP request and returns the response body
+	ToolTypeHTTP = "http"
+	// ToolTypeShell runs an allowlisted command, without a shell
+	ToolTypeShell = "shell"
+	// ToolTypeTemplate renders a text/template with the tool arguments
+	ToolTypeTemplate = "template"
+)
+
+// DefaultToolTimeout bounds declarative tool calls without a timeout.
+const DefaultToolTimeout = 30 * time.Second
+
+// maxToolOutput bounds the output of declarative tools returned to the model.
+const maxToolOutput = 64 << 10
+
+// SimpleFlowTool is a tool declared in a SimpleFlow YAML file, so flows and
+// their tools ship as one file. URL, header, body, command argument and
+// template strings are text/templates rendered with the tool arguments
+// chosen by the model, e.g. "https://api.example.com/users/{{.id}}". String
+// arguments are escaped in URLs, so the model cannot change the path or add
+// query parameters.
+type SimpleFlowTool struct {
+	// Name is the tool name referenced by steps
+	Name string `yaml:"name" json:"name"`
+	// Description tells the model what the tool does
+	Description string `yaml:"description" json:"description"`
+	// Type is ToolTypeHTTP, ToolTypeShell or ToolTypeTemplate
+	Type string `yaml:"type" json:"type"`
+	// Parameters are the arguments the model passes to the tool
+	Parameters []SimpleFlowToolParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// Timeout bounds each call (default DefaultToolTimeout)
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Method, URL, Headers and Body describe the request of HTTP tools
+	Method  string            `yaml:"method,omitempty" json:"method,omitempty"`
+	URL     string            `yaml:"url,omitempty" json:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty" json:"body,omitempty"`
+	// Client sends the requests of HTTP tools (default http.DefaultClient)
+	Client *http.Client `yaml:"-" json:"-"`
+
+	// Command is the program and arguments run by shell tools. If empty,
+	// the model passes the command line as the "command" argument, which
+	// is split on whitespace.
+	Command []string `yaml:"command,omitempty" json:"command,omitempty"`
+	// Allow lists the programs shell tools may run; it is required. Only the
+	// program (the first word of the command) is checked, never its
+	// arguments, so allow only programs that are safe with any arguments.
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	// Dir is the working directory of shell tools
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Template is the output of template tools
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+}
+
+// SimpleFlowToolParameter is an argument of a declarative tool.
+type SimpleFlowToolParameter struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Type is "string" (the default), "number", "integer" or "boolean"
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// toolParameterTypes maps declarative parameter types to Go types.
+var toolParameterTypes = map[string]reflect.Type{
+	"":        reflect.TypeOf(""),
+	"string":  reflect.TypeOf(""),
+	"number":  reflect.TypeOf(float64(0)),
+	"integer": reflect.TypeOf(int(0)),
+	"boolean": reflect.TypeOf(false),
+}
+
+// Function builds the AgentFunction of the tool.
+func (t SimpleFlowTool) Function() (AgentFunction, error) {
+	if t.Name == "" {
+		return nil, fmt.Errorf("%w: tool name is empty", ErrInvalidFunction)
+	}
+	params := make([]Parameter, 0, len(t.Parameters)+1)
+	for _, p := range t.Parameters {
+		typ, ok := toolParameterTypes[p.Type]
+		if !ok {
+			return nil, fmt.Errorf("%w: tool %s: unknown parameter type %q", ErrInvalidParameter, t.Name, p.Type)
+		}
+		description := p.Description
+		if description == "" {
+			description = p.Name
+		}
+		params = append(params, Parameter{Name: p.Name, Description: description, Type: typ, Required: p.Required})
+	}
+
+	var call func(cc *CallContext, args map[string]interface{}) (interface{}, error)
+	switch t.Type {
+	case ToolTypeHTTP:
+		if t.URL == "" {
+			return nil, fmt.Errorf("%w: http tool %s has no url", ErrInvalidFunction, t.Name)
+		}
+		templates, err := parseToolTemplates(t.Name, append([]string{t.URL, t.Body}, mapValues(t.Headers)...)...)
+		if err != nil {
+			return nil, err
+		}
+		call = t.httpCall(templates)
+
+	case ToolTypeShell:
+		if len(t.Allow) == 0 {
+			return nil, fmt.Errorf("%w: shell tool %s has no allowed commands", ErrInvalidFunction, t.Name)
+		}
+		if len(t.Command) == 0 {
+			params = append(params, Parameter{Name: "command", Description: fmt.Sprintf("The command line to run, one of: %s", strings.Join(t.Allow, ", ")), Type: reflect.TypeOf(""), Required: true})
+		} else if !slices.Contains(t.Allow, t.Command[0]) {
+			return nil, fmt.Errorf("%w: shell tool %s runs %s, which is not allowed", ErrInvalidFunction, t.Name, t.Command[0])
+		}
+		templates, err := parseToolTemplates(t.Name, t.Command...)
+		if err != nil {
+			return nil, err
+		}
+		call = t.shellCall(templates)
+
+	case ToolTypeTemplate:
+		templates, err := parseToolTemplates(t.Name, t.Template)
+		if err != nil {
+			return nil, err
+		}
+		call = func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+			return renderToolTemplate(templates[0], args)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: tool %s has unknown type %q", ErrInvalidFunction, t.Name, t.Type)
+	}
+
+	fn := NewContextFunction(t.Name, t.Description, call, params)
+	if err := fn.Validate(); err != nil {
+		return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+	}
+	return fn, nil
+}
+
+// httpCall returns the call of an HTTP tool. templates holds the URL, body
+// and header templates, in the sorted order of the header names.
+func (t SimpleFlowTool) httpCall(templates []*template.Template) func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+	headerNames := mapKeys(t.Headers)
+	method := strings.ToUpper(t.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	return func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+		rendered := make([]string, len(templates))
+		for i, tmpl := range templates {
+			values := args
+			if i == 0 {
+				values = urlArguments(args)
+			}
+			value, err := renderToolTemplate(tmpl, values)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = value
+		}
+		ctx, cancel := context.WithTimeout(cc, t.timeout())
+		defer cancel()
+
+		var body io.Reader
+		if rendered[1] != "" {
+			body = strings.NewReader(rendered[1])
+		}
+		req, err := http.NewRequestWithContext(ctx, method, rendered[0], body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request: %w", err)
+		}
+		for i, name := range headerNames {
+			req.Header.Set(name, rendered[2+i])
+		}
+		client := t.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxToolOutput))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return string(data), nil
+	}
+}
+
+// urlArguments returns args with their strings escaped for any part of a URL.
+func urlArguments(args map[string]interface{}) map[string]interface{} {
+	escaped := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		switch v := v.(type) {
+		case string:
+			escaped[k] = strings.ReplaceAll(url.QueryEscape(v), "+", "%20")
+		case map[string]interface{}:
+			escaped[k] = urlArguments(v)
+		default:
+			escaped[k] = v
+		}
+	}
+	return escaped
+}
+
+// shellCall returns the call of a shell tool. templates holds the command
+// templates, if the command is fixed.
+func (t SimpleFlowTool) shellCall(templates []*template.Template) func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+	return func(cc *CallContext, args map[string]interface{}) (interface{}, error) {
+		var argv []string
+		if len(templates) == 0 {
+			line, _ := args["command"].(string)
+			argv = strings.Fields(line)
+		} else {
+			for _, tmpl := range templates {
+				arg, err := renderToolTemplate(tmpl, args)
+				if err != nil {
+					return nil, err
+				}
+				argv = append(argv, arg)
+			}
+		}
+		if len(argv) == 0 {
+			return nil, fmt.Errorf("command is empty")
+		}
+		if !slices.Contains(t.Allow, argv[0]) {
+			return nil, fmt.Errorf("command %s is not allowed, use one of: %s", argv[0], strings.Join(t.Allow, ", "))
+		}
+
+		ctx, cancel := context.WithTimeout(cc, t.timeout())
+		defer cancel()
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Dir = t.Dir
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("command failed: %w: %s", err, truncateOutput(stderr.String()))
+		}
+		return truncateOutput(stdout.String()), nil
+	}
+}
+
+// timeout returns the timeout of each call of the tool.
+func (t SimpleFlowTool) timeout() time.Duration {
+	if t.Timeout > 0 {
+		return t.Timeout
+	}
+	return DefaultToolTimeout
+}
+
+// parseToolTemplates parses the templated strings of a tool.
+func parseToolTemplates(tool string, texts ...string) ([]*template.Template, error) {
+	templates := make([]*template.Template, len(texts))
+	for i, text := range texts {
+		tmpl, err := template.New(tool).Funcs(instructionFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tool %s: %v", ErrInvalidFunction, tool, err)
+		}
+		templates[i] = tmpl
+	}
+	return templates, nil
+}
+
+// renderToolTemplate renders a tool template with the tool arguments.
+func renderToolTemplate(tmpl *template.Template, args map[string]interface{}) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, args); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return strings.ReplaceAll(b.String(), "<no value>", ""), nil
+}
+
+// truncateOutput bounds tool output returned to the model, cutting on a rune
+// boundary so multibyte characters are not split.
+func truncateOutput(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	n := maxToolOutput
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "\n[truncated]"
+}
+
+// mapKeys returns the sorted keys of m.
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// mapValues returns the values of m in the sorted order of its keys.
+func mapValues(m map[string]string) []string {
+	keys := mapKeys(m)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = m[k]
+	}
+	return values
+}
@@ -0,0 +1,156 @@
+package swarm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSimpleFlowTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Token abc" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+	}))
+	defer server.Close()
+
+	flowYAML := `
+name: tools
+tools:
+  - name: create_ticket
+    description: Create a ticket
+    type: http
+    method: post
+    url: "` + server.URL + `/tickets/{{.project}}"
+    headers:
+      Authorization: "Token {{.token}}"
+    body: '{"title": {{json .title}}}'
+    parameters:
+      - name: project
+        required: true
+      - name: title
+      - name: token
+  - name: run
+    description: Run a command
+    type: shell
+    allow: [echo]
+  - name: greet
+    description: Greet someone
+    type: shell
+    command: [echo, "hello {{.name}}"]
+    allow: [echo]
+  - name: format
+    description: Format a summary
+    type: template
+    template: "{{upper .title}} ({{.count}})"
+    parameters:
+      - name: title
+      - name: count
+        type: integer
+steps:
+  - name: work
+    instructions: Do the work.
+    tools: [create_ticket, run, greet, format]
+`
+	var flow SimpleFlow
+	AssertNoError(t, yaml.Unmarshal([]byte(flowYAML), &flow), "Unmarshal")
+	AssertNoError(t, flow.Initialize(), "Initialize")
+	AssertNoError(t, flow.Initialize(), "Initialize again")
+	functions := make(map[string]AgentFunction)
+	for _, fn := range flow.Steps[0].Agent.Functions {
+		functions[fn.Name()] = fn
+	}
+	AssertEqual(t, 4, len(flow.Steps[0].Agent.Functions), "step tools are added once")
+
+	call := func(name string, args map[string]interface{}) (interface{}, error) {
+		return AsContextFunction(functions[name]).CallWithContext(&CallContext{Context: context.Background()}, args)
+	}
+	result, err := call("create_ticket", map[string]interface{}{"project": "ops", "title": `disk "full"`, "token": "abc"})
+	AssertNoError(t, err, "http tool")
+	AssertEqual(t, `POST /tickets/ops {"title": "disk \"full\""}`, result, "http tool result")
+	_, err = call("create_ticket", map[string]interface{}{"project": "ops"})
+	AssertError(t, err, "Expected error for a failed request")
+
+	result, err = call("run", map[string]interface{}{"command": "echo a  b"})
+	AssertNoError(t, err, "shell tool")
+	AssertEqual(t, "a b\n", result, "shell tool result")
+	_, err = call("run", map[string]interface{}{"command": "rm -rf /tmp/x"})
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Expected a disallowed command to fail, got %v", err)
+	}
+	result, err = call("greet", map[string]interface{}{"name": "Ada; rm -rf /"})
+	AssertNoError(t, err, "fixed shell tool")
+	AssertEqual(t, "hello Ada; rm -rf /\n", result, "arguments are not interpreted by a shell")
+
+	result, err = call("format", map[string]interface{}{"title": "report", "count": 3})
+	AssertNoError(t, err, "template tool")
+	AssertEqual(t, "REPORT (3)", result, "template tool result")
+}
+
+// recordingTransport records the URLs of requests and replies "ok".
+type recordingTransport struct {
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.String())
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+}
+
+func TestSimpleFlowHTTPToolEscaping(t *testing.T) {
+	transport := &recordingTransport{}
+	flow := &SimpleFlow{
+		HTTPClient: &http.Client{Transport: transport},
+		Tools: []SimpleFlowTool{{
+			Name:       "lookup",
+			Type:       ToolTypeHTTP,
+			URL:        "https://api.example.com/users/{{.id}}?q={{.query}}&limit={{.limit}}",
+			Parameters: []SimpleFlowToolParameter{{Name: "id"}, {Name: "query"}, {Name: "limit", Type: "integer"}},
+		}},
+		Steps: []SimpleFlowStep{{Name: "work", Instructions: "Look up.", Tools: []string{"lookup"}}},
+	}
+	AssertNoError(t, flow.Initialize(), "Initialize")
+
+	result, err := AsContextFunction(flow.Steps[0].Agent.Functions[0]).CallWithContext(&CallContext{Context: context.Background()},
+		map[string]interface{}{"id": "../admin?x=1#", "query": "a b&admin=true", "limit": 5})
+	AssertNoError(t, err, "http tool")
+	AssertEqual(t, "ok", result, "injected client used")
+	AssertEqual(t, 1, len(transport.urls), "requests")
+	AssertEqual(t, "https://api.example.com/users/..%2Fadmin%3Fx%3D1%23?q=a%20b%26admin%3Dtrue&limit=5", transport.urls[0], "escaped URL")
+}
+
+func TestSimpleFlowToolErrors(t *testing.T) {
+	for name, tool := range map[string]SimpleFlowTool{
+		"unknown type":       {Name: "t", Type: "ftp"},
+		"http without url":   {Name: "t", Type: ToolTypeHTTP},
+		"shell without list": {Name: "t", Type: ToolTypeShell, Command: []string{"ls"}},
+		"disallowed command": {Name: "t", Type: ToolTypeShell, Command: []string{"rm"}, Allow: []string{"ls"}},
+		"invalid template":   {Name: "t", Type: ToolTypeTemplate, Template: "{{"},
+		"invalid parameter":  {Name: "t", Type: ToolTypeTemplate, Parameters: []SimpleFlowToolParameter{{Name: "p", Type: "date"}}},
+	} {
+		if _, err := tool.Function(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	flow := &SimpleFlow{Steps: []SimpleFlowStep{{Name: "work", Tools: []string{"missing"}}}}
+	AssertError(t, flow.Initialize(), "Expected error for an unknown tool")
+}
+
+func TestTruncateOutputRuneBoundary(t *testing.T) {
+	// "é" is two bytes and straddles the limit
+	s := strings.Repeat("a", maxToolOutput-1) + "é" + "tail"
+	got := truncateOutput(s)
+	AssertEqual(t, true, utf8.ValidString(got), "valid UTF-8")
+	AssertEqual(t, strings.Repeat("a", maxToolOutput-1)+"\n[truncated]", got, "cut before the split rune")
+	AssertEqual(t, "short", truncateOutput("short"), "short output unchanged")
+}
@@ -0,0 +1,152 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoRoute is returned when a SkillRouter cannot pick an agent.
+var ErrNoRoute = errors.New("no agent matches the request")
+
+// WithSkills declares skill tags, e.g. "billing" or "refunds", used by
+// SkillRouter to route requests, and returns the agent for chaining.
+func (a *Agent) WithSkills(tags ...string) *Agent {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !a.HasSkill(tag) {
+			a.Skills = append(a.Skills, tag)
+		}
+	}
+	return a
+}
+
+// HasSkill reports whether the agent declares the skill (case-insensitive).
+func (a *Agent) HasSkill(tag string) bool {
+	for _, skill := range a.Skills {
+		if strings.EqualFold(skill, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkillRouter picks the best agent for a request from the agents' declared
+// skills. Requests mentioning a skill are routed to the agent matching the
+// most skills; otherwise an optional LLM-assisted pass chooses, and finally
+// the fallback agent is used.
+type SkillRouter struct {
+	// Agents are the routing targets
+	Agents []*Agent
+	// Classifier, if set, picks an agent when no skill matches literally
+	Classifier *Agent
+	// Fallback receives requests no agent matches
+	Fallback *Agent
+}
+
+// NewSkillRouter creates a router over agents.
+func NewSkillRouter(agents ...*Agent) *SkillRouter {
+	return &SkillRouter{Agents: agents}
+}
+
+// WithClassifier enables LLM-assisted routing with the given agent and
+// returns the router.
+func (r *SkillRouter) WithClassifier(classifier *Agent) *SkillRouter {
+	r.Classifier = classifier
+	return r
+}
+
+// WithFallback sets the agent for unmatched requests and returns the router.
+func (r *SkillRouter) WithFallback(agent *Agent) *SkillRouter {
+	r.Fallback = agent
+	return r
+}
+
+// Route returns the agent best suited to request.
+func (r *SkillRouter) Route(ctx context.Context, s *Swarm, request string) (*Agent, error) {
+	if agent := r.matchSkills(request); agent != nil {
+		return agent, nil
+	}
+	if r.Classifier != nil {
+		agent, err := r.classify(ctx, s, request)
+		if err != nil {
+			return nil, err
+		}
+		if agent != nil {
+			return agent, nil
+		}
+	}
+	if r.Fallback != nil {
+		return r.Fallback, nil
+	}
+	return nil, ErrNoRoute
+}
+
+// matchSkills returns the agent with the most skills mentioned in request,
+// preferring earlier agents on ties, or nil if none match.
+func (r *SkillRouter) matchSkills(request string) *Agent {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(request), isSkillSeparator), " ") + " "
+	var best *Agent
+	bestScore := 0
+	for _, agent := range r.Agents {
+		score := 0
+		for _, skill := range agent.Skills {
+			tag := strings.Join(strings.FieldsFunc(strings.ToLower(skill), isSkillSeparator), " ")
+			if tag != "" && strings.Contains(words, " "+tag+" ") {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = agent, score
+		}
+	}
+	return best
+}
+
+// isSkillSeparator splits requests and tags into words.
+func isSkillSeparator(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+}
+
+// classify asks the classifier agent to pick an agent by name.
+func (r *SkillRouter) classify(ctx context.Context, s *Swarm, request string) (*Agent, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Choose the agent best suited to handle the request.\n\nAgents:\n")
+	for _, agent := range r.Agents {
+		fmt.Fprintf(&prompt, "- %s: %s\n", agent.Name, strings.Join(agent.Skills, ", "))
+	}
+	fmt.Fprintf(&prompt, "\nRequest: %s\n\nReply with only the agent name, or \"none\" if no agent fits.", request)
+
+	response, err := s.Run(ctx, r.Classifier, []map[string]interface{}{
+		{"role": "user", "content": prompt.String()},
+	}, nil, "", false, false, 1, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify request: %w", err)
+	}
+	return matchAgentName(r.Agents, lastContent(response)), nil
+}
+
+// TriageAgent returns a front-door agent that hands requests off to the
+// router's agents. It gets one transfer function per agent, described by the
+// agent's skills, so no transfer functions need to be written by hand.
+func (r *SkillRouter) TriageAgent(name string) *Agent {
+	var roster strings.Builder
+	for _, agent := range r.Agents {
+		fmt.Fprintf(&roster, "\n- %s: %s", agent.Name, strings.Join(agent.Skills, ", "))
+	}
+	triage := NewAgent(name).WithInstructions(
+		"Determine which agent is best suited to handle the user's request, and transfer the conversation to that agent." + roster.String())
+	for _, agent := range r.Agents {
+		target := agent
+		triage.AddFunction(NewAgentFunction(
+			"transfer_to_"+toolName(target.Name),
+			fmt.Sprintf("Transfer to %s, skilled in: %s.", target.Name, strings.Join(target.Skills, ", ")),
+			func(args map[string]interface{}) (interface{}, error) {
+				return target, nil
+			},
+			[]Parameter{},
+		))
+	}
+	return triage
+}
@@ -0,0 +1,58 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSkillRouter(t *testing.T) {
+	billing := NewAgent("billing").WithSkills("billing", "refunds", "Billing")
+	support := NewAgent("support").WithSkills("technical support", "login")
+	AssertEqual(t, 2, len(billing.Skills), "duplicate skills are ignored")
+
+	router := NewSkillRouter(billing, support)
+	s := NewSwarm(NewMockOpenAIClient())
+
+	agent, err := router.Route(context.Background(), s, "I need a refund on my last Billing statement")
+	AssertNoError(t, err, "Route")
+	AssertEqual(t, billing, agent, "skill match")
+
+	agent, err = router.Route(context.Background(), s, "Can't login, need Technical Support!")
+	AssertNoError(t, err, "Route")
+	AssertEqual(t, support, agent, "multi-word skill")
+
+	_, err = router.Route(context.Background(), s, "tell me a joke")
+	AssertEqual(t, true, errors.Is(err, ErrNoRoute), "no route")
+
+	fallback := NewAgent("general")
+	agent, err = router.WithFallback(fallback).Route(context.Background(), s, "tell me a joke")
+	AssertNoError(t, err, "fallback")
+	AssertEqual(t, fallback, agent, "fallback agent")
+}
+
+func TestSkillRouterClassifier(t *testing.T) {
+	billing := NewAgent("billing").WithSkills("billing")
+	support := NewAgent("support").WithSkills("login")
+	client := newScriptedClient("support")
+
+	agent, err := NewSkillRouter(billing, support).
+		WithClassifier(NewAgent("classifier")).
+		Route(context.Background(), NewSwarm(client), "my password stopped working")
+	AssertNoError(t, err, "Route")
+	AssertEqual(t, support, agent, "LLM-assisted route")
+	prompt := client.requests[0].Messages[1].OfUser.Content.OfString.Value
+	AssertEqual(t, true, strings.Contains(prompt, "- support: login"), "prompt lists skills")
+}
+
+func TestSkillRouterTriageAgent(t *testing.T) {
+	billing := NewAgent("Billing Agent").WithSkills("billing")
+	triage := NewSkillRouter(billing, NewAgent("support")).TriageAgent("triage")
+
+	AssertEqual(t, 2, len(triage.Functions), "transfer functions")
+	AssertEqual(t, "transfer_to_Billing_Agent", triage.Functions[0].Name(), "function name")
+	target, err := triage.Functions[0].Call(nil)
+	AssertNoError(t, err, "transfer")
+	AssertEqual(t, billing, target, "transfer target")
+}
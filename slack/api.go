@@ -0,0 +1,109 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIURL is the base URL of the Slack Web API.
+const DefaultAPIURL = "https://slack.com/api/"
+
+// call invokes a Slack Web API method with a JSON body, authenticated with
+// token, and decodes the response into out.
+func (b *Bot) call(ctx context.Context, token, method string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(b.APIURL, "/")+"/"+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := b.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	data := new(bytes.Buffer)
+	if _, err := data.ReadFrom(resp.Body); err != nil {
+		return fmt.Errorf("slack %s failed: %w", method, err)
+	}
+	if err := json.Unmarshal(data.Bytes(), &result); err != nil {
+		return fmt.Errorf("slack %s returned %s: %w", method, resp.Status, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack %s failed: %s", method, result.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data.Bytes(), out)
+	}
+	return nil
+}
+
+// openConnection returns the WebSocket URL of a new Socket Mode connection.
+func (b *Bot) openConnection(ctx context.Context) (string, error) {
+	var resp struct {
+		URL string `json:"url"`
+	}
+	if err := b.call(ctx, b.AppToken, "apps.connections.open", struct{}{}, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// postMessage posts a message in a thread and returns its timestamp.
+func (b *Bot) postMessage(ctx context.Context, channel, threadTS, text string, blocks []block) (string, error) {
+	var resp struct {
+		TS string `json:"ts"`
+	}
+	err := b.call(ctx, b.BotToken, "chat.postMessage", message{Channel: channel, ThreadTS: threadTS, Text: text, Blocks: blocks}, &resp)
+	return resp.TS, err
+}
+
+// updateMessage replaces the text of a message, dropping its blocks.
+func (b *Bot) updateMessage(ctx context.Context, channel, ts, text string) error {
+	return b.call(ctx, b.BotToken, "chat.update", message{Channel: channel, TS: ts, Text: text, Blocks: []block{}}, nil)
+}
+
+// message is the body of chat.postMessage and chat.update.
+type message struct {
+	Channel  string  `json:"channel"`
+	TS       string  `json:"ts,omitempty"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+	Text     string  `json:"text"`
+	Blocks   []block `json:"blocks,omitempty"`
+}
+
+// block is a Block Kit layout block.
+type block map[string]interface{}
+
+// approvalBlocks asks to approve a tool call with buttons carrying requestID.
+func approvalBlocks(text, requestID string) []block {
+	button := func(label, actionID, style string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":      "button",
+			"text":      map[string]interface{}{"type": "plain_text", "text": label},
+			"action_id": actionID,
+			"value":     requestID,
+			"style":     style,
+		}
+	}
+	return []block{
+		{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": text}},
+		{"type": "actions", "block_id": requestID, "elements": []interface{}{
+			button("Approve", actionApprove, "primary"),
+			button("Deny", actionDeny, "danger"),
+		}},
+	}
+}
@@ -0,0 +1,474 @@
+// Package slack connects swarm agents and workflows to Slack through Socket
+// Mode, so no public endpoint is needed.
+//
+// Direct messages and mentions of the bot become user turns of a session per
+// Slack thread. The bot replies in the thread and edits its reply as the
+// answer streams in. Tool calls that require approval are posted as messages
+// with Approve and Deny buttons, and the run waits for a click.
+//
+// The Slack app needs Socket Mode with an app-level token (xapp-) holding
+// connections:write, a bot token (xoxb-) with chat:write, the app_mention and
+// message.im event subscriptions, and interactivity enabled.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultUpdateInterval is the minimum delay between edits of a streaming
+	// reply, keeping the bot within Slack's rate limits
+	DefaultUpdateInterval = time.Second
+	// DefaultApprovalTimeout is how long a run waits for an approval click
+	// before denying the tool call
+	DefaultApprovalTimeout = 10 * time.Minute
+
+	actionApprove = "swarm_approve"
+	actionDeny    = "swarm_deny"
+	thinking      = ":hourglass_flowing_sand: Thinking..."
+)
+
+// mentionPattern matches user mentions such as <@U123>.
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// Bot serves an agent, or a workflow, in Slack.
+type Bot struct {
+	// AppToken is the app-level token opening Socket Mode connections
+	AppToken string
+	// BotToken is the bot token used to post messages
+	BotToken string
+	// Swarm runs the agent's conversations
+	Swarm *swarm.Swarm
+	// Agent answers messages, unless Workflow is set
+	Agent *swarm.Agent
+	// Workflow, if set, builds a workflow run for each message. Its inputs
+	// are "message", "user", "channel" and "thread_ts"; progress events are
+	// shown while it runs and the StopEvent result is the reply.
+	Workflow func() (*swarm.Workflow, error)
+	// Store persists the sessions of the threads (default: in memory)
+	Store swarm.SessionStore
+	// MaxTurns limits the turns of each message (default swarm.DefaultSessionMaxTurns)
+	MaxTurns int
+	// UpdateInterval throttles edits of streaming replies (default DefaultUpdateInterval)
+	UpdateInterval time.Duration
+	// ApprovalTimeout bounds the wait for approval clicks (default DefaultApprovalTimeout)
+	ApprovalTimeout time.Duration
+	// APIURL is the base URL of the Slack Web API (default DefaultAPIURL)
+	APIURL string
+	// HTTPClient sends Web API requests (default http.DefaultClient)
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	sessions map[string]*swarm.Session
+	pending  map[string]chan bool
+}
+
+// NewBot creates a bot answering with agent.
+func NewBot(appToken, botToken string, s *swarm.Swarm, agent *swarm.Agent) *Bot {
+	return &Bot{
+		AppToken: appToken,
+		BotToken: botToken,
+		Swarm:    s,
+		Agent:    agent,
+		Store:    swarm.NewMemorySessionStore(),
+		APIURL:   DefaultAPIURL,
+		sessions: make(map[string]*swarm.Session),
+		pending:  make(map[string]chan bool),
+	}
+}
+
+// WithWorkflow makes the bot answer with runs of the workflows built by
+// factory and returns the bot.
+func (b *Bot) WithWorkflow(factory func() (*swarm.Workflow, error)) *Bot {
+	b.Workflow = factory
+	return b
+}
+
+// WithStore sets the session store and returns the bot.
+func (b *Bot) WithStore(store swarm.SessionStore) *Bot {
+	b.Store = store
+	return b
+}
+
+// WithMaxTurns sets the turn budget of each message and returns the bot.
+func (b *Bot) WithMaxTurns(maxTurns int) *Bot {
+	b.MaxTurns = maxTurns
+	return b
+}
+
+// WithUpdateInterval sets the minimum delay between edits of streaming
+// replies and returns the bot.
+func (b *Bot) WithUpdateInterval(interval time.Duration) *Bot {
+	b.UpdateInterval = interval
+	return b
+}
+
+// WithAPIURL sets the base URL of the Slack Web API and returns the bot.
+func (b *Bot) WithAPIURL(url string) *Bot {
+	b.APIURL = url
+	return b
+}
+
+// Run connects to Slack and serves messages until ctx is done, reconnecting
+// when Slack closes the connection.
+func (b *Bot) Run(ctx context.Context) error {
+	if b.Agent == nil && b.Workflow == nil {
+		return errors.New("slack bot needs an agent or a workflow")
+	}
+	for {
+		url, err := b.openConnection(ctx)
+		if err != nil {
+			return err
+		}
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to connect to slack: %w", err)
+		}
+		b.serve(ctx, conn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// envelope is a Socket Mode message.
+type envelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// messageEvent is a message or app_mention event.
+type messageEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	BotID       string `json:"bot_id"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	User        string `json:"user"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// blockActions is the payload of a button click.
+type blockActions struct {
+	Type string `json:"type"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		TS string `json:"ts"`
+	} `json:"message"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// serve handles the envelopes of a connection until it closes or Slack asks
+// to reconnect.
+func (b *Bot) serve(ctx context.Context, conn *websocket.Conn) {
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var env envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return
+		}
+		if env.EnvelopeID != "" {
+			if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return
+			}
+		}
+
+		switch env.Type {
+		case "disconnect":
+			return
+		case "events_api":
+			var payload struct {
+				Event messageEvent `json:"event"`
+			}
+			if json.Unmarshal(env.Payload, &payload) == nil && b.accepts(payload.Event) {
+				go b.handleMessage(ctx, payload.Event)
+			}
+		case "interactive":
+			var payload blockActions
+			if json.Unmarshal(env.Payload, &payload) == nil && payload.Type == "block_actions" {
+				b.handleActions(ctx, payload)
+			}
+		}
+	}
+}
+
+// accepts reports whether an event is a user turn for the bot: a mention, or
+// a direct message not sent by a bot.
+func (b *Bot) accepts(event messageEvent) bool {
+	if event.BotID != "" || event.Subtype != "" {
+		return false
+	}
+	return event.Type == "app_mention" || (event.Type == "message" && event.ChannelType == "im")
+}
+
+// handleMessage answers a user turn in its thread.
+func (b *Bot) handleMessage(ctx context.Context, event messageEvent) {
+	thread := event.ThreadTS
+	if thread == "" {
+		thread = event.TS
+	}
+	text := strings.TrimSpace(mentionPattern.ReplaceAllString(event.Text, ""))
+	if text == "" {
+		return
+	}
+	ts, err := b.postMessage(ctx, event.Channel, thread, thinking, nil)
+	if err != nil {
+		return
+	}
+	reply := &reply{bot: b, channel: event.Channel, ts: ts, interval: b.UpdateInterval}
+	if reply.interval <= 0 {
+		reply.interval = DefaultUpdateInterval
+	}
+
+	if b.Workflow != nil {
+		err = b.runWorkflow(ctx, event, thread, text, reply)
+	} else {
+		err = b.runAgent(ctx, event.Channel, thread, text, reply)
+	}
+	if err != nil {
+		reply.finish(ctx, ":warning: "+err.Error())
+	}
+}
+
+// runAgent streams the agent's answer into the reply.
+func (b *Bot) runAgent(ctx context.Context, channel, thread, text string, reply *reply) error {
+	session, err := b.session(channel + ":" + thread)
+	if err != nil {
+		return err
+	}
+	ctx = swarm.WithApprovalHandler(ctx, b.approver(ctx, channel, thread))
+	chunks, err := session.Stream(ctx, text)
+	if err != nil {
+		return err
+	}
+	var response *swarm.Response
+	for chunk := range chunks {
+		if r, ok := chunk["response"].(*swarm.Response); ok {
+			response = r
+			continue
+		}
+		if content, _ := chunk["content"].(string); content != "" && chunk["tool_calls"] == nil {
+			reply.append(ctx, content)
+		}
+	}
+	if response == nil {
+		return errors.New("the agent failed to answer")
+	}
+	if response.Error != nil {
+		return fmt.Errorf("the agent failed to answer: %w", response.Error)
+	}
+	reply.finish(ctx, finalContent(response.Messages))
+	return nil
+}
+
+// runWorkflow runs the workflow for a message, showing its progress in the
+// reply.
+func (b *Bot) runWorkflow(ctx context.Context, event messageEvent, thread, text string, reply *reply) error {
+	workflow, err := b.Workflow()
+	if err != nil {
+		return err
+	}
+	handler, err := workflow.Run(ctx, map[string]interface{}{
+		"message":   text,
+		"user":      event.User,
+		"channel":   event.Channel,
+		"thread_ts": thread,
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		for event := range handler.StreamFiltered(swarm.EventProgress) {
+			if progress, ok := event.(*swarm.ProgressEvent); ok {
+				reply.set(ctx, fmt.Sprintf(":hourglass_flowing_sand: %s (%.0f%%)", progress.Message, progress.Percent))
+			}
+		}
+	}()
+	result, err := handler.WaitValue()
+	if err != nil {
+		return err
+	}
+	if text, ok := result.(string); ok {
+		reply.finish(ctx, text)
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	reply.finish(ctx, "```\n"+string(data)+"\n```")
+	return nil
+}
+
+// approver returns an approval handler posting Approve and Deny buttons in
+// the thread and waiting for a click.
+func (b *Bot) approver(ctx context.Context, channel, thread string) swarm.ApprovalFunc {
+	return func(call swarm.ToolCall) (bool, error) {
+		requestID := swarm.NewID("approval-")
+		answer := make(chan bool, 1)
+		b.mu.Lock()
+		b.pending[requestID] = answer
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			delete(b.pending, requestID)
+			b.mu.Unlock()
+		}()
+
+		text := fmt.Sprintf("Approve calling `%s` with `%s`?", call.Function.Name, call.Function.Arguments)
+		ts, err := b.postMessage(ctx, channel, thread, text, approvalBlocks(text, requestID))
+		if err != nil {
+			return false, err
+		}
+		timeout := b.ApprovalTimeout
+		if timeout <= 0 {
+			timeout = DefaultApprovalTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case approved := <-answer:
+			return approved, nil
+		case <-timer.C:
+			b.updateMessage(ctx, channel, ts, fmt.Sprintf("Calling `%s` was denied: no answer within %s.", call.Function.Name, timeout))
+			return false, fmt.Errorf("approval timed out after %s", timeout)
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// handleActions delivers approval clicks.
+func (b *Bot) handleActions(ctx context.Context, payload blockActions) {
+	for _, action := range payload.Actions {
+		if action.ActionID != actionApprove && action.ActionID != actionDeny {
+			continue
+		}
+		b.mu.Lock()
+		answer, ok := b.pending[action.Value]
+		b.mu.Unlock()
+		if !ok {
+			continue
+		}
+		approved := action.ActionID == actionApprove
+		select {
+		case answer <- approved:
+		default:
+			continue
+		}
+		verdict := "denied"
+		if approved {
+			verdict = "approved"
+		}
+		go b.updateMessage(ctx, payload.Channel.ID, payload.Message.TS, fmt.Sprintf("Tool call %s by <@%s>.", verdict, payload.User.ID))
+	}
+}
+
+// session returns the session of a thread.
+func (b *Bot) session(id string) (*swarm.Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if session, ok := b.sessions[id]; ok {
+		return session, nil
+	}
+	session, err := b.Swarm.LoadSession(b.Store, id, b.Agent)
+	switch {
+	case errors.Is(err, swarm.ErrSessionNotFound):
+		session = b.Swarm.NewSession(b.Agent, id).WithStore(b.Store)
+	case err != nil:
+		return nil, err
+	}
+	session.WithMaxTurns(b.MaxTurns)
+	b.sessions[id] = session
+	return session, nil
+}
+
+// httpClient returns the bot's HTTP client.
+func (b *Bot) httpClient() *http.Client {
+	if b.HTTPClient != nil {
+		return b.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// reply is a bot message edited as an answer streams in.
+type reply struct {
+	bot      *Bot
+	channel  string
+	ts       string
+	interval time.Duration
+
+	mu      sync.Mutex
+	text    strings.Builder
+	updated time.Time
+	done    bool
+}
+
+// append adds streamed content, editing the message at most once per
+// interval.
+func (r *reply) append(ctx context.Context, content string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.text.WriteString(content)
+	if !r.done && time.Since(r.updated) >= r.interval {
+		r.updated = time.Now()
+		r.bot.updateMessage(ctx, r.channel, r.ts, r.text.String())
+	}
+}
+
+// set replaces the message text, unless the reply is finished.
+func (r *reply) set(ctx context.Context, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.done {
+		r.bot.updateMessage(ctx, r.channel, r.ts, text)
+	}
+}
+
+// finish writes the final text of the reply.
+func (r *reply) finish(ctx context.Context, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done = true
+	if text == "" {
+		text = "_(no reply)_"
+	}
+	r.bot.updateMessage(ctx, r.channel, r.ts, text)
+}
+
+// finalContent returns the last non-empty content of messages.
+func finalContent(messages []map[string]interface{}) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if text, ok := messages[i]["content"].(string); ok && text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/feiskyer/swarm-go"
+	"github.com/feiskyer/swarm-go/fakellm"
+	"github.com/gorilla/websocket"
+)
+
+// fakeSlack serves the Web API methods used by the bot and a Socket Mode
+// connection delivering the given envelopes. Approval requests are clicked
+// by user U2.
+type fakeSlack struct {
+	*httptest.Server
+	envelopes []map[string]interface{}
+
+	mu      sync.Mutex
+	acks    []string
+	posts   []message
+	updates []message
+	conn    *websocket.Conn
+}
+
+func newFakeSlack(t *testing.T, envelopes ...map[string]interface{}) *fakeSlack {
+	t.Helper()
+	slack := &fakeSlack{envelopes: envelopes}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "invalid_auth"})
+			return
+		}
+		url := "ws" + strings.TrimPrefix(slack.URL, "http") + "/ws"
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "url": url})
+	})
+	mux.HandleFunc("POST /api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		var msg message
+		json.NewDecoder(r.Body).Decode(&msg)
+		slack.mu.Lock()
+		slack.posts = append(slack.posts, msg)
+		ts := fmt.Sprintf("100.%d", len(slack.posts))
+		conn := slack.conn
+		slack.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": ts})
+
+		if len(msg.Blocks) > 0 {
+			requestID := msg.Blocks[1]["block_id"].(string)
+			conn.WriteJSON(map[string]interface{}{"envelope_id": "click", "type": "interactive", "payload": map[string]interface{}{
+				"type": "block_actions", "user": map[string]string{"id": "U2"},
+				"channel": map[string]string{"id": msg.Channel}, "message": map[string]string{"ts": ts},
+				"actions": []map[string]string{{"action_id": actionApprove, "value": requestID}},
+			}})
+		}
+	})
+	mux.HandleFunc("POST /api/chat.update", func(w http.ResponseWriter, r *http.Request) {
+		var msg message
+		json.NewDecoder(r.Body).Decode(&msg)
+		slack.mu.Lock()
+		slack.updates = append(slack.updates, msg)
+		slack.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+	})
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		slack.mu.Lock()
+		slack.conn = conn
+		slack.mu.Unlock()
+		conn.WriteJSON(map[string]interface{}{"type": "hello"})
+		for _, env := range slack.envelopes {
+			conn.WriteJSON(env)
+		}
+		for {
+			var ack map[string]string
+			if err := conn.ReadJSON(&ack); err != nil {
+				return
+			}
+			slack.mu.Lock()
+			slack.acks = append(slack.acks, ack["envelope_id"])
+			slack.mu.Unlock()
+		}
+	})
+	slack.Server = httptest.NewServer(mux)
+	t.Cleanup(slack.Close)
+	return slack
+}
+
+// waitForUpdate waits until a message is updated to text.
+func (s *fakeSlack) waitForUpdate(t *testing.T, text string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		for _, update := range s.updates {
+			if update.Text == text {
+				s.mu.Unlock()
+				return
+			}
+		}
+		s.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.Fatalf("no update to %q in %+v", text, s.updates)
+}
+
+func messageEnvelope(id string, event map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"envelope_id": id, "type": "events_api", "payload": map[string]interface{}{"event": event}}
+}
+
+func TestBotApprovalAndStreaming(t *testing.T) {
+	calls := 0
+	agent := swarm.NewAgent("ops").WithModel("gpt-4o").AddFunction(swarm.NewAgentFunction("delete_pod", "Delete a pod",
+		func(args map[string]interface{}) (interface{}, error) {
+			calls++
+			return "deleted", nil
+		},
+		[]swarm.Parameter{},
+	)).RequireApproval(func(call swarm.ToolCall) (bool, error) {
+		return false, nil
+	})
+	slack := newFakeSlack(t,
+		messageEnvelope("e1", map[string]interface{}{"type": "message", "channel_type": "im", "channel": "D1", "user": "U1", "text": "call delete_pod", "ts": "1.0"}),
+		// Messages of bots, including the bot's own, are ignored
+		messageEnvelope("e2", map[string]interface{}{"type": "message", "channel_type": "im", "channel": "D1", "bot_id": "B1", "text": "hi", "ts": "2.0"}),
+	)
+	llm := fakellm.New()
+	llm.When(fakellm.ToolResult("delete_pod")).Reply("echo deleted")
+	llm.When(fakellm.PromptContains("call delete_pod")).CallTool("delete_pod", map[string]interface{}{})
+	bot := NewBot("xapp-test", "xoxb-test", swarm.NewSwarm(llm), agent).WithAPIURL(slack.URL + "/api")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- bot.Run(ctx) }()
+
+	slack.waitForUpdate(t, "echo deleted")
+	slack.waitForUpdate(t, "Tool call approved by <@U2>.")
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected Run to stop with the context, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the approved tool to run once, ran %d times", calls)
+	}
+	slack.mu.Lock()
+	defer slack.mu.Unlock()
+	if len(slack.posts) != 2 || slack.posts[0].Text != thinking || slack.posts[0].ThreadTS != "1.0" {
+		t.Errorf("expected a placeholder reply and an approval request in the thread, got %+v", slack.posts)
+	}
+	slices.Sort(slack.acks)
+	if strings.Join(slack.acks, ",") != "click,e1,e2" {
+		t.Errorf("expected every envelope to be acknowledged, got %v", slack.acks)
+	}
+}
+
+func TestBotWorkflow(t *testing.T) {
+	slack := newFakeSlack(t,
+		messageEnvelope("e1", map[string]interface{}{"type": "app_mention", "channel": "C1", "user": "U1", "text": "<@UBOT> build it", "ts": "1.0"}),
+	)
+	bot := NewBot("xapp-test", "xoxb-test", nil, nil).WithAPIURL(slack.URL + "/api").WithWorkflow(func() (*swarm.Workflow, error) {
+		workflow := swarm.NewWorkflow("build")
+		err := workflow.AddStep(swarm.NewStartStep(func(ctx *swarm.Context, event swarm.Event) (swarm.Event, error) {
+			ctx.ReportProgress("build", 50, "compiling")
+			return swarm.NewStopEvent(fmt.Sprintf("%s for %s: done", event.Data()["message"], event.Data()["user"])), nil
+		}, nil))
+		return workflow, err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.Run(ctx)
+	slack.waitForUpdate(t, "build it for U1: done")
+}
+
+func TestBotRunErrors(t *testing.T) {
+	slack := newFakeSlack(t)
+	if err := NewBot("xapp-test", "xoxb-test", nil, nil).Run(context.Background()); err == nil {
+		t.Error("expected a bot without agent or workflow to fail")
+	}
+	bot := NewBot("xapp-wrong", "xoxb-test", nil, swarm.NewAgent("a")).WithAPIURL(slack.URL + "/api")
+	if err := bot.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// SnapshotVersion is the format version written by Context.Snapshot.
+const SnapshotVersion = 1
+
+// StateCodec encodes and decodes Context state values of a specific Go type.
+type StateCodec interface {
+	// Encode converts a value into JSON
+	Encode(value interface{}) (json.RawMessage, error)
+	// Decode converts JSON back into a value of the codec's type
+	Decode(data json.RawMessage) (interface{}, error)
+}
+
+// jsonStateCodec is a StateCodec that uses encoding/json for type T.
+type jsonStateCodec[T any] struct{}
+
+// Encode marshals the value as JSON.
+func (jsonStateCodec[T]) Encode(value interface{}) (json.RawMessage, error) {
+	return json.Marshal(value)
+}
+
+// Decode unmarshals JSON into a new T.
+func (jsonStateCodec[T]) Decode(data json.RawMessage) (interface{}, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// stateCodecRegistry maps Go types to named codecs.
+type stateCodecRegistry struct {
+	byName map[string]StateCodec
+	byType map[reflect.Type]string
+	mu     sync.RWMutex
+}
+
+var stateCodecs = &stateCodecRegistry{
+	byName: make(map[string]StateCodec),
+	byType: make(map[reflect.Type]string),
+}
+
+func init() {
+	RegisterStateType[string]("string")
+	RegisterStateType[bool]("bool")
+	RegisterStateType[int]("int")
+	RegisterStateType[int64]("int64")
+	RegisterStateType[float64]("float64")
+	RegisterStateType[[]string]("[]string")
+	RegisterStateType[[]int]("[]int")
+	RegisterStateType[[]interface{}]("[]interface{}")
+	RegisterStateType[map[string]string]("map[string]string")
+	RegisterStateType[map[string]interface{}]("map[string]interface{}")
+	RegisterStateType[time.Time]("time.Time")
+	RegisterStateType[time.Duration]("time.Duration")
+}
+
+// RegisterStateCodec registers a codec for values of type typ under name.
+// The name is written into snapshots, so it must stay stable across releases.
+func RegisterStateCodec(name string, typ reflect.Type, codec StateCodec) {
+	stateCodecs.mu.Lock()
+	defer stateCodecs.mu.Unlock()
+	stateCodecs.byName[name] = codec
+	stateCodecs.byType[typ] = name
+}
+
+// RegisterStateType registers a JSON-based codec for type T under name, so
+// values of T stored in a Context survive Snapshot and RestoreContext.
+func RegisterStateType[T any](name string) {
+	RegisterStateCodec(name, reflect.TypeOf((*T)(nil)).Elem(), jsonStateCodec[T]{})
+}
+
+// snapshotValue is a single encoded state entry.
+type snapshotValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// contextSnapshot is the serialized form of a Context.
+type contextSnapshot struct {
+	Version   int                      `json:"version"`
+	RunID     string                   `json:"run_id"`
+	CreatedAt time.Time                `json:"created_at"`
+	State     map[string]snapshotValue `json:"state"`
+}
+
+// Snapshot serializes the Context's run ID and state map to JSON. Every state
+// value must have a registered codec; nil values are preserved.
+// Returns an error naming the first key whose type cannot be encoded.
+func (c *Context) Snapshot() ([]byte, error) {
+	state := c.Clone()
+
+	snapshot := contextSnapshot{
+		Version:   SnapshotVersion,
+		RunID:     c.RunID(),
+		CreatedAt: time.Now(),
+		State:     make(map[string]snapshotValue, len(state)),
+	}
+
+	stateCodecs.mu.RLock()
+	defer stateCodecs.mu.RUnlock()
+	for key, value := range state {
+		if value == nil {
+			snapshot.State[key] = snapshotValue{Type: "nil", Value: json.RawMessage("null")}
+			continue
+		}
+		name, ok := stateCodecs.byType[reflect.TypeOf(value)]
+		if !ok {
+			return nil, fmt.Errorf("cannot snapshot key %q: no codec registered for type %T", key, value)
+		}
+		data, err := stateCodecs.byName[name].Encode(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot snapshot key %q: %w", key, err)
+		}
+		snapshot.State[key] = snapshotValue{Type: name, Value: data}
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// LoadSnapshot replaces the Context's state with the state from a snapshot
+// produced by Snapshot. The run ID is restored as well. Watchers are notified
+// of every key the snapshot adds, changes or removes.
+func (c *Context) LoadSnapshot(data []byte) error {
+	var snapshot contextSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snapshot.Version > SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	state := make(map[string]interface{}, len(snapshot.State))
+	stateCodecs.mu.RLock()
+	for key, entry := range snapshot.State {
+		if entry.Type == "nil" {
+			state[key] = nil
+			continue
+		}
+		codec, ok := stateCodecs.byName[entry.Type]
+		if !ok {
+			stateCodecs.mu.RUnlock()
+			return fmt.Errorf("cannot restore key %q: no codec registered for type %q", key, entry.Type)
+		}
+		value, err := codec.Decode(entry.Value)
+		if err != nil {
+			stateCodecs.mu.RUnlock()
+			return fmt.Errorf("cannot restore key %q: %w", key, err)
+		}
+		state[key] = value
+	}
+	stateCodecs.mu.RUnlock()
+
+	var inherited map[string]interface{}
+	if c.parent != nil {
+		inherited = c.parent.Clone()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := make(map[string]interface{}, len(inherited)+len(c.state))
+	for key, value := range inherited {
+		if !c.deleted[key] {
+			previous[key] = value
+		}
+	}
+	for key, value := range c.state {
+		previous[key] = value
+	}
+
+	c.state = state
+	c.deleted = make(map[string]bool)
+	for key := range inherited {
+		if _, ok := state[key]; !ok {
+			// Hide inherited keys the snapshot does not have
+			c.deleted[key] = true
+		}
+	}
+	if snapshot.RunID != "" {
+		c.runID = snapshot.RunID
+	}
+
+	for key, value := range previous {
+		if _, ok := state[key]; !ok {
+			c.notify(StateChange{Key: key, Previous: value, Deleted: true})
+		}
+	}
+	for key, value := range state {
+		if old, ok := previous[key]; !ok || !reflect.DeepEqual(old, value) {
+			c.notify(StateChange{Key: key, Value: value, Previous: old})
+		}
+	}
+	return nil
+}
+
+// RestoreContext creates a new Context from a snapshot produced by Snapshot.
+func RestoreContext(ctx context.Context, data []byte, opts ...ContextOption) (*Context, error) {
+	c := NewContext(ctx, opts...)
+	if err := c.LoadSnapshot(data); err != nil {
+		c.Cancel()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,178 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWorkflowStalled is returned by WorkflowHandler.Wait for runs that made no
+// progress for WorkflowConfig.StallTimeout.
+var ErrWorkflowStalled = errors.New("workflow stalled")
+
+// StallPolicy controls what the engine does when a run stalls: no step is
+// running and no event arrived for WorkflowConfig.StallTimeout, typically
+// because an event was emitted that no step handles.
+type StallPolicy string
+
+const (
+	// StallFail fails the run with ErrWorkflowStalled (the default)
+	StallFail StallPolicy = "fail"
+	// StallWarn publishes a StalledEvent to stream subscribers and keeps waiting
+	StallWarn StallPolicy = "warn"
+	// StallRoute sends a StalledEvent with the unhandled events to the steps
+	// handling EventStalled. The run fails if no step handles EventStalled
+	// or no event went unhandled.
+	StallRoute StallPolicy = "route"
+)
+
+// EventStalled signals that a workflow run made no progress.
+const EventStalled EventType = "StalledEvent"
+
+// StalledEvent reports a stalled run and the events no step handled since
+// the last progress.
+type StalledEvent struct {
+	BaseEvent
+	// Idle is how long the run has made no progress
+	Idle time.Duration `json:"idle"`
+	// Unhandled are the events no step handled
+	Unhandled []Event `json:"-"`
+}
+
+// NewStalledEvent creates a new StalledEvent.
+func NewStalledEvent(idle time.Duration, unhandled []Event) *StalledEvent {
+	return &StalledEvent{
+		BaseEvent: BaseEvent{
+			eventType: EventStalled,
+		},
+		Idle:      idle,
+		Unhandled: unhandled,
+	}
+}
+
+// stallDetector tracks the progress of a run for the run loop.
+type stallDetector struct {
+	timeout time.Duration
+	// active counts the running steps, tasks and delays
+	active atomic.Int64
+	// last is the time of the last progress in Unix nanoseconds
+	last atomic.Int64
+	// unhandled are the events without steps since the last progress,
+	// only accessed by the run loop
+	unhandled []Event
+}
+
+// newStallDetector creates a detector, disabled if timeout is not positive.
+func newStallDetector(timeout time.Duration) *stallDetector {
+	d := &stallDetector{timeout: timeout}
+	d.progress()
+	return d
+}
+
+// progress records that the run made progress.
+func (d *stallDetector) progress() {
+	d.last.Store(time.Now().UnixNano())
+	d.unhandled = nil
+}
+
+// start records that a step, task or delay started.
+func (d *stallDetector) start() {
+	d.active.Add(1)
+}
+
+// done records that a step, task or delay finished.
+func (d *stallDetector) done() {
+	d.last.Store(time.Now().UnixNano())
+	d.active.Add(-1)
+}
+
+// unhandledEvent records an event that no step handles.
+func (d *stallDetector) unhandledEvent(event Event) {
+	d.unhandled = append(d.unhandled, event)
+}
+
+// ticks returns the channel the run loop checks for stalls on, or nil if
+// stall detection is disabled.
+func (d *stallDetector) ticks() (<-chan time.Time, func()) {
+	if d.timeout <= 0 {
+		return nil, func() {}
+	}
+	interval := d.timeout / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+// idle returns how long the run has made no progress, or zero while steps
+// are running.
+func (d *stallDetector) idle() time.Duration {
+	if d.active.Load() > 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, d.last.Load()))
+}
+
+// stalled reports whether the run has made no progress for the timeout.
+func (d *stallDetector) stalled() bool {
+	return d.timeout > 0 && d.idle() >= d.timeout
+}
+
+// err describes the stall.
+func (d *stallDetector) err() error {
+	if len(d.unhandled) == 0 {
+		return fmt.Errorf("%w: no progress for %v", ErrWorkflowStalled, d.idle().Round(time.Millisecond))
+	}
+	types := make([]string, len(d.unhandled))
+	for i, event := range d.unhandled {
+		types[i] = string(event.Type())
+	}
+	return fmt.Errorf("%w: no progress for %v, no steps for %s", ErrWorkflowStalled, d.idle().Round(time.Millisecond), strings.Join(types, ", "))
+}
+
+// handleStall applies the stall policy to a stalled run, returning the error
+// that fails the run, if any.
+func (w *Workflow) handleStall(wfCtx *Context, stall *stallDetector, wg *sync.WaitGroup) error {
+	event := NewStalledEvent(stall.idle(), stall.unhandled)
+	if w.config.Verbose {
+		fmt.Printf("Workflow stalled: %v\n", stall.err())
+	}
+
+	switch w.config.StallPolicy {
+	case StallWarn:
+		if err := wfCtx.publish(event); err != nil && w.config.Verbose {
+			fmt.Printf("Failed to publish stalled event: %v\n", err)
+		}
+		stall.progress()
+		return nil
+
+	case StallRoute:
+		w.mu.RLock()
+		steps := w.stepMap[string(EventStalled)]
+		w.mu.RUnlock()
+		if len(steps) == 0 || len(event.Unhandled) == 0 {
+			return stall.err()
+		}
+		if err := wfCtx.publish(event); err != nil && w.config.Verbose {
+			fmt.Printf("Failed to publish stalled event: %v\n", err)
+		}
+		stall.progress()
+		for _, step := range steps {
+			wg.Add(1)
+			stall.start()
+			go func(s Step) {
+				defer wg.Done()
+				defer stall.done()
+				w.executeStep(wfCtx, s, event, nil)
+			}(step)
+		}
+		return nil
+
+	default:
+		return stall.err()
+	}
+}
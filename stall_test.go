@@ -0,0 +1,89 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStallWorkflow builds a workflow whose start step emits an event no step
+// handles.
+func newStallWorkflow(policy StallPolicy) *Workflow {
+	config := DefaultConfig()
+	config.StallTimeout = 50 * time.Millisecond
+	config.StallPolicy = policy
+	workflow := NewWorkflow("stall").WithConfig(config)
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		return NewBaseEvent(EventType("Orphan"), map[string]interface{}{"n": 1}), nil
+	}, StepConfig{}))
+	return workflow
+}
+
+func TestWorkflowStallFail(t *testing.T) {
+	handler, err := newStallWorkflow("").Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	_, err = handler.Wait()
+	AssertEqual(t, true, errors.Is(err, ErrWorkflowStalled), "Expected ErrWorkflowStalled")
+	if !strings.Contains(err.Error(), "Orphan") {
+		t.Errorf("Expected the unhandled event type in %q", err)
+	}
+	AssertEqual(t, WorkflowStatusFailed, handler.Status(), "status")
+}
+
+func TestWorkflowStallWarn(t *testing.T) {
+	handler, err := newStallWorkflow(StallWarn).Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	sub := handler.Context().Subscribe(EventStalled)
+
+	select {
+	case event := <-sub.Events():
+		stalled := event.(*StalledEvent)
+		AssertEqual(t, 1, len(stalled.Unhandled), "unhandled events")
+		AssertEqual(t, EventType("Orphan"), stalled.Unhandled[0].Type(), "unhandled event type")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a stalled event")
+	}
+	AssertEqual(t, WorkflowStatusRunning, handler.Status(), "status")
+
+	handler.Cancel()
+	_, err = handler.Wait()
+	AssertEqual(t, true, errors.Is(err, context.Canceled), "Expected context.Canceled")
+}
+
+func TestWorkflowStallRoute(t *testing.T) {
+	workflow := newStallWorkflow(StallRoute)
+	workflow.AddStep(NewStep("fallback", EventStalled, func(ctx *Context, event Event) (Event, error) {
+		stalled := event.(*StalledEvent)
+		return NewStopEvent(string(stalled.Unhandled[0].Type())), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	result, err := handler.WaitValue()
+	AssertNoError(t, err, "wait")
+	AssertEqual(t, "Orphan", result, "result")
+
+	// Without a fallback step the run fails
+	handler, err = newStallWorkflow(StallRoute).Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	_, err = handler.Wait()
+	AssertEqual(t, true, errors.Is(err, ErrWorkflowStalled), "Expected ErrWorkflowStalled")
+}
+
+func TestWorkflowStallIgnoresRunningSteps(t *testing.T) {
+	config := DefaultConfig()
+	config.StallTimeout = 20 * time.Millisecond
+	workflow := NewWorkflow("slow").WithConfig(config)
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		time.Sleep(100 * time.Millisecond)
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	result, err := handler.WaitValue()
+	AssertNoError(t, err, "wait")
+	AssertEqual(t, "done", result, "result")
+}
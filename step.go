@@ -1,6 +1,8 @@
 package swarm
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -22,28 +24,58 @@ type Step interface {
 // RetryPolicy configures step execution retry behavior using exponential backoff.
 type RetryPolicy struct {
 	// MaxRetries is the maximum number of retry attempts
-	MaxRetries int
+	MaxRetries int `yaml:"max_retries" json:"max_retries"`
 
 	// InitialInterval is the delay before first retry
-	InitialInterval time.Duration
+	InitialInterval time.Duration `yaml:"initial_interval" json:"initial_interval"`
 
 	// MaxInterval caps the maximum delay between retries
-	MaxInterval time.Duration
+	MaxInterval time.Duration `yaml:"max_interval" json:"max_interval"`
 
 	// Multiplier controls exponential backoff rate
-	Multiplier float64
+	Multiplier float64 `yaml:"multiplier" json:"multiplier"`
 
 	// Errors specifies which errors trigger retries. Empty means all errors.
-	Errors []error
+	Errors []error `yaml:"-" json:"-"`
 }
 
+// ErrorPolicy controls how the workflow reacts when a step fails after all retries.
+type ErrorPolicy string
+
+const (
+	// OnErrorFail terminates the workflow with the step's error (default)
+	OnErrorFail ErrorPolicy = "fail"
+	// OnErrorContinue ignores the failure and lets the rest of the workflow run
+	OnErrorContinue ErrorPolicy = "continue"
+	// OnErrorCompensate runs the step's Compensate handler instead of failing
+	OnErrorCompensate ErrorPolicy = "compensate"
+)
+
+// CompensateFunc handles a failed step. It receives the event the step was
+// handling and the final error, and may return a follow-up event (or nil).
+// Returning an error fails the workflow.
+type CompensateFunc func(ctx *Context, event Event, err error) (Event, error)
+
 // StepConfig holds step configuration settings
 type StepConfig struct {
 	MaxParallel int64
 	Timeout     time.Duration
 	RetryPolicy *RetryPolicy
+	// OnError selects the failure policy; empty means OnErrorFail
+	OnError ErrorPolicy
+	// Compensate is invoked when OnError is OnErrorCompensate
+	Compensate CompensateFunc
+	// CacheTTL enables result caching through WorkflowConfig.Cache. Zero
+	// disables caching; CacheForever caches without expiry.
+	CacheTTL time.Duration
+	// Produces optionally declares the types of the events the step emits,
+	// which Workflow.Validate checks against the other steps
+	Produces []EventType
 }
 
+// CacheForever can be used as StepConfig.CacheTTL to cache results without expiry.
+const CacheForever time.Duration = -1
+
 // StepFunc represents a workflow step function that processes an event and returns a new event or error.
 // The function receives a workflow context and an input event.
 type StepFunc func(ctx *Context, event Event) (Event, error)
@@ -89,6 +121,72 @@ func NewStep(name string, eventType EventType, handler StepFunc, config StepConf
 	}
 }
 
+// NewTypedStep creates a step whose handler works with typed values instead
+// of events. The handler receives the event decoded into In: the event itself
+// if it is an In, or else its data map and exported fields decoded via JSON.
+// The returned Out is sent as is if it is an Event, as a StopEvent result if
+// outputType is EventStop, or else as the data of an event of outputType. A
+// nil Out interface, e.g. a nil Event, sends no event. Unless config declares
+// the events the step produces, it is declared to produce outputType.
+func NewTypedStep[In, Out any](name string, eventType, outputType EventType, handler func(ctx *Context, in In) (Out, error), config StepConfig) Step {
+	if config.Produces == nil && outputType != "" {
+		config.Produces = []EventType{outputType}
+	}
+	return NewStep(name, eventType, func(ctx *Context, event Event) (Event, error) {
+		in, err := decodeEvent[In](event)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", name, err)
+		}
+		out, err := handler(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return encodeEvent(outputType, out)
+	}, config)
+}
+
+// decodeEvent decodes an event into T.
+func decodeEvent[T any](event Event) (T, error) {
+	var v T
+	if typed, ok := event.(T); ok {
+		return typed, nil
+	}
+	if data := event.Data(); len(data) > 0 {
+		if err := ToStruct(data, &v); err != nil {
+			return v, fmt.Errorf("failed to decode %s into %T: %w", event.Type(), v, err)
+		}
+	}
+	if _, ok := event.(*BaseEvent); ok {
+		return v, nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return v, fmt.Errorf("failed to marshal %s: %w", event.Type(), err)
+	}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return v, fmt.Errorf("failed to decode %s into %T: %w", event.Type(), v, err)
+	}
+	return v, nil
+}
+
+// encodeEvent wraps the output of a typed step into an event of eventType.
+func encodeEvent(eventType EventType, out interface{}) (Event, error) {
+	if out == nil {
+		return nil, nil
+	}
+	if event, ok := out.(Event); ok {
+		return event, nil
+	}
+	if eventType == EventStop {
+		return NewStopEvent(out), nil
+	}
+	data, err := ToMap(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T as %s: %w", out, eventType, err)
+	}
+	return NewBaseEvent(eventType, data), nil
+}
+
 // DefaultRetryPolicy returns the default retry policy
 func DefaultRetryPolicy() *RetryPolicy {
 	return &RetryPolicy{
@@ -98,3 +196,23 @@ func DefaultRetryPolicy() *RetryPolicy {
 		Multiplier:      2.0,
 	}
 }
+
+// withDefaults returns a copy of the policy with unset fields taken from
+// DefaultRetryPolicy, e.g. for policies configured in YAML files.
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	policy := *p
+	defaults := DefaultRetryPolicy()
+	if policy.MaxRetries == 0 {
+		policy.MaxRetries = defaults.MaxRetries
+	}
+	if policy.InitialInterval == 0 {
+		policy.InitialInterval = defaults.InitialInterval
+	}
+	if policy.MaxInterval == 0 {
+		policy.MaxInterval = max(defaults.MaxInterval, policy.InitialInterval)
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = defaults.Multiplier
+	}
+	return &policy
+}
@@ -0,0 +1,118 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// StopConditionMet is the StopReason of runs ended by a StopCondition.
+const StopConditionMet StopReason = "stop_condition"
+
+// RunState is the state of a run after a turn, as seen by stop conditions.
+type RunState struct {
+	// Turn is the turn that just finished, from 1
+	Turn int
+	// Agent is the agent that took the turn
+	Agent *Agent
+	// Message is the assistant message of the turn
+	Message map[string]interface{}
+	// ToolNames are the tools called in the turn
+	ToolNames []string
+	// Messages are all messages produced by the run so far
+	Messages []map[string]interface{}
+	// TokensUsed is the total number of tokens used by the run so far
+	TokensUsed int
+}
+
+// StopCondition decides after each turn with tool calls whether the run
+// should stop, returning a non-empty description of why, which becomes the
+// Response's StopDetail.
+type StopCondition func(state *RunState) string
+
+// stopConditionsContextKey carries the stop conditions of a run.
+type stopConditionsContextKey struct{}
+
+// WithStopConditions returns ctx carrying stop conditions for runs started
+// with it. They are checked in order after every turn that executes tools.
+func WithStopConditions(ctx context.Context, conditions ...StopCondition) context.Context {
+	existing, _ := ctx.Value(stopConditionsContextKey{}).([]StopCondition)
+	combined := append(append([]StopCondition(nil), existing...), conditions...)
+	return context.WithValue(ctx, stopConditionsContextKey{}, combined)
+}
+
+// checkStopConditions returns the description of the first met condition.
+func checkStopConditions(ctx context.Context, state *RunState) string {
+	conditions, _ := ctx.Value(stopConditionsContextKey{}).([]StopCondition)
+	for _, condition := range conditions {
+		if reason := condition(state); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// StopOnTool stops the run once any of the named tools has been called and
+// executed.
+func StopOnTool(names ...string) StopCondition {
+	return func(state *RunState) string {
+		for _, name := range state.ToolNames {
+			if containsString(names, name) {
+				return fmt.Sprintf("tool %q was called", name)
+			}
+		}
+		return ""
+	}
+}
+
+// StopOnMatch stops the run when the turn's assistant content matches re.
+func StopOnMatch(re *regexp.Regexp) StopCondition {
+	return func(state *RunState) string {
+		content, _ := state.Message["content"].(string)
+		if content != "" && re.MatchString(content) {
+			return fmt.Sprintf("output matched %s", re)
+		}
+		return ""
+	}
+}
+
+// StopOnJSON stops the run when the turn's assistant content is a JSON object
+// satisfying predicate.
+func StopOnJSON(predicate func(value map[string]interface{}) bool) StopCondition {
+	return func(state *RunState) string {
+		content, _ := state.Message["content"].(string)
+		if strings.TrimSpace(content) == "" {
+			return ""
+		}
+		var value map[string]interface{}
+		if err := parseJSONReply(content, &value); err != nil {
+			return ""
+		}
+		if predicate(value) {
+			return "output satisfied the JSON predicate"
+		}
+		return ""
+	}
+}
+
+// StopOnTokenBudget stops the run once it has used at least budget tokens.
+func StopOnTokenBudget(budget int) StopCondition {
+	return func(state *RunState) string {
+		if state.TokensUsed >= budget {
+			return fmt.Sprintf("token budget of %d reached (%d used)", budget, state.TokensUsed)
+		}
+		return ""
+	}
+}
+
+// toolCallNames returns the names of the called tools.
+func toolCallNames(toolCalls []openai.ChatCompletionMessageToolCall) []string {
+	names := make([]string, 0, len(toolCalls))
+	for _, call := range toolCalls {
+		names = append(names, call.Function.Name)
+	}
+	return names
+}
@@ -0,0 +1,52 @@
+package swarm
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestStopOnTool(t *testing.T) {
+	calls := 0
+	ctx := WithStopConditions(context.Background(), StopOnTool("search"))
+
+	response, err := NewSwarm(newFailingToolClient("search", `{}`, 5)).Run(ctx, newEchoAgent(&calls), []map[string]interface{}{
+		{"role": "user", "content": "Find"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, StopConditionMet, response.StopReason, "stop reason")
+	AssertEqual(t, `tool "search" was called`, response.StopDetail, "stop detail")
+	AssertEqual(t, 1, calls, "tool runs before stopping")
+	AssertEqual(t, 2, len(response.Messages), "message count")
+}
+
+func TestStopOnTokenBudget(t *testing.T) {
+	client := newFailingToolClient("search", `{}`, 5)
+	for _, completion := range client.CompletionResponse {
+		completion.Usage = openai.CompletionUsage{TotalTokens: 40}
+	}
+	calls := 0
+	ctx := WithStopConditions(context.Background(), StopOnTokenBudget(100))
+
+	response, err := NewSwarm(client).Run(ctx, newEchoAgent(&calls), []map[string]interface{}{
+		{"role": "user", "content": "Find"},
+	}, nil, "", false, false, 20, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, StopConditionMet, response.StopReason, "stop reason")
+	AssertEqual(t, 120, response.TokensUsed, "tokens used")
+	AssertEqual(t, 3, calls, "turns before the budget is hit")
+}
+
+func TestStopOnContent(t *testing.T) {
+	message := map[string]interface{}{"content": `{"status": "done", "answer": 42}`}
+	state := &RunState{Message: message}
+
+	AssertEqual(t, "output matched DONE|done", StopOnMatch(regexp.MustCompile("DONE|done"))(state), "regex match")
+	AssertEqual(t, "", StopOnMatch(regexp.MustCompile("failed"))(state), "regex mismatch")
+
+	isDone := StopOnJSON(func(value map[string]interface{}) bool { return value["status"] == "done" })
+	AssertEqual(t, "output satisfied the JSON predicate", isDone(state), "JSON predicate")
+	AssertEqual(t, "", isDone(&RunState{Message: map[string]interface{}{"content": "not json"}}), "non-JSON content")
+}
@@ -0,0 +1,175 @@
+package swarm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// StreamChunkType is the kind of model output carried by a StreamChunk.
+type StreamChunkType string
+
+const (
+	// StreamChunkContent carries a fragment of the reply text
+	StreamChunkContent StreamChunkType = "content"
+	// StreamChunkReasoning carries a fragment of the model's reasoning
+	StreamChunkReasoning StreamChunkType = "reasoning"
+	// StreamChunkToolCall carries a fragment of a tool call
+	StreamChunkToolCall StreamChunkType = "tool_call"
+)
+
+// ToolCallDelta is a fragment of a streamed tool call. The ID and name arrive
+// with the first fragment of a call, the arguments in pieces.
+type ToolCallDelta struct {
+	// Index identifies the tool call within the model turn
+	Index int `json:"index"`
+	// ID is the tool call ID, if carried by this fragment
+	ID string `json:"id,omitempty"`
+	// Name is the function name, if carried by this fragment
+	Name string `json:"name,omitempty"`
+	// Arguments is the next piece of the JSON arguments
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// StreamChunk is incremental model output. RunAndStream emits one under the
+// "chunk" key for every delta as it arrives, merged according to
+// Swarm.StreamCoalesce. Reasoning is only streamed as chunks; the complete
+// "content" and "tool_calls" blocks that follow repeat the chunks, so
+// consumers render either the chunks or the blocks.
+type StreamChunk struct {
+	// Type is the kind of output
+	Type StreamChunkType `json:"type"`
+	// Delta is the next piece of content or reasoning text
+	Delta string `json:"delta,omitempty"`
+	// ToolCallDelta is the next piece of a tool call
+	ToolCallDelta *ToolCallDelta `json:"tool_call_delta,omitempty"`
+	// Sender is the name of the agent producing the output
+	Sender string `json:"sender"`
+}
+
+// WithStreamCoalescing merges the deltas streamed by RunAndStream within
+// interval into one StreamChunk and returns the swarm.
+func (s *Swarm) WithStreamCoalescing(interval time.Duration) *Swarm {
+	s.StreamCoalesce = interval
+	return s
+}
+
+// streamChunks returns the chunks carried by a streamed completion chunk:
+// the reasoning, content and tool call deltas of its first choice.
+func streamChunks(chunk openai.ChatCompletionChunk, sender string) []*StreamChunk {
+	if len(chunk.Choices) == 0 {
+		return nil
+	}
+	delta := chunk.Choices[0].Delta
+	var chunks []*StreamChunk
+	if reasoning := reasoningContent(delta.JSON.ExtraFields); reasoning != "" {
+		chunks = append(chunks, &StreamChunk{Type: StreamChunkReasoning, Delta: reasoning, Sender: sender})
+	}
+	if delta.Content != "" {
+		chunks = append(chunks, &StreamChunk{Type: StreamChunkContent, Delta: delta.Content, Sender: sender})
+	}
+	for _, call := range delta.ToolCalls {
+		chunks = append(chunks, &StreamChunk{
+			Type: StreamChunkToolCall,
+			ToolCallDelta: &ToolCallDelta{
+				Index:     int(call.Index),
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+			Sender: sender,
+		})
+	}
+	return chunks
+}
+
+// continues reports whether next extends the output of c, so both can be
+// merged.
+func (c *StreamChunk) continues(next *StreamChunk) bool {
+	if c.Type != next.Type || c.Sender != next.Sender {
+		return false
+	}
+	if c.Type != StreamChunkToolCall {
+		return true
+	}
+	id := next.ToolCallDelta.ID
+	return c.ToolCallDelta.Index == next.ToolCallDelta.Index && (id == "" || id == c.ToolCallDelta.ID)
+}
+
+// merge appends the output of next to c.
+func (c *StreamChunk) merge(next *StreamChunk) {
+	c.Delta += next.Delta
+	if c.ToolCallDelta != nil {
+		if c.ToolCallDelta.Name == "" {
+			c.ToolCallDelta.Name = next.ToolCallDelta.Name
+		}
+		c.ToolCallDelta.Arguments += next.ToolCallDelta.Arguments
+	}
+}
+
+// chunkCoalescer merges consecutive chunks continuing each other that
+// arrive within interval of the first. A merged chunk is emitted when a
+// chunk arrives that can't be merged, when interval has passed since its
+// first delta, or on flush.
+type chunkCoalescer struct {
+	interval time.Duration
+	emit     func(*StreamChunk)
+	pending  *StreamChunk
+	started  time.Time
+	timer    *time.Timer
+	mu       sync.Mutex
+}
+
+// add emits chunk, or holds it back to merge it with the following ones.
+func (c *chunkCoalescer) add(chunk *StreamChunk) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending != nil && c.pending.continues(chunk) && time.Since(c.started) < c.interval {
+		c.pending.merge(chunk)
+		return
+	}
+	c.flushLocked()
+	if c.interval <= 0 {
+		c.emit(chunk)
+		return
+	}
+	c.pending, c.started = chunk, time.Now()
+	// Emit the chunk after interval even if no further delta arrives
+	c.timer = time.AfterFunc(c.interval, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.pending == chunk {
+			c.flushLocked()
+		}
+	})
+}
+
+// flush emits the chunk held back, if any.
+func (c *chunkCoalescer) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+// flushLocked emits the chunk held back, if any. The caller must hold c.mu.
+func (c *chunkCoalescer) flushLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.pending != nil {
+		c.emit(c.pending)
+		c.pending = nil
+	}
+}
+
+// finish emits the chunk held back if it continues the content, or the tool
+// call at index, that has just finished streaming.
+func (c *chunkCoalescer) finish(kind StreamChunkType, index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p := c.pending; p != nil && p.Type == kind && (p.ToolCallDelta == nil || p.ToolCallDelta.Index == index) {
+		c.flushLocked()
+	}
+}
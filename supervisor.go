@@ -0,0 +1,351 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Event types used by the supervisor workflow.
+const (
+	// EventSupervisorTask is the task type dispatched to workers
+	EventSupervisorTask EventType = "SupervisorTaskEvent"
+	// EventSupervisorTaskResult carries a worker's output back to the reviewer
+	EventSupervisorTaskResult EventType = "SupervisorTaskResultEvent"
+)
+
+// DefaultSupervisorIterations is the default maximum number of plan/review
+// iterations of a Supervisor.
+const DefaultSupervisorIterations = 3
+
+// SupervisorTask is a unit of work assigned by the planner to a worker.
+type SupervisorTask struct {
+	// ID identifies the task within its iteration
+	ID string `json:"id"`
+	// Worker is the name of the agent that performs the task
+	Worker string `json:"worker"`
+	// Instruction describes the work to do
+	Instruction string `json:"instruction"`
+	// Output is the worker's reply
+	Output string `json:"output,omitempty"`
+	// Error is set if the worker failed
+	Error string `json:"error,omitempty"`
+	// Iteration is the plan/review iteration the task belongs to, from 1
+	Iteration int `json:"iteration"`
+}
+
+// SupervisorResult is the outcome of a supervisor run.
+type SupervisorResult struct {
+	// Answer is the reviewer's final answer
+	Answer string `json:"answer"`
+	// Done is false if the run stopped at the iteration limit
+	Done bool `json:"done"`
+	// Iterations is the number of plan/review iterations run
+	Iterations int `json:"iterations"`
+	// Tasks lists every task with its output, by iteration and ID
+	Tasks []SupervisorTask `json:"tasks"`
+}
+
+// supervisorPlan is the JSON reply expected from the planner and reviewer.
+type supervisorPlan struct {
+	Done   bool             `json:"done"`
+	Answer string           `json:"answer"`
+	Tasks  []SupervisorTask `json:"tasks"`
+}
+
+// Supervisor implements the planner-worker pattern: a planner agent breaks a
+// goal into tasks, workers run them in parallel as a ParallelEvent, and a
+// reviewer inspects the results and either answers or plans another round.
+type Supervisor struct {
+	// Swarm runs the agents
+	Swarm *Swarm
+	// Planner decomposes the goal into tasks
+	Planner *Agent
+	// Reviewer reviews results and decides whether the goal is met
+	// (defaults to Planner)
+	Reviewer *Agent
+	// Workers perform tasks, selected by name
+	Workers []*Agent
+	// MaxIterations caps the plan/review iterations (default 3)
+	MaxIterations int
+	// MaxTurns caps the model and tool turns of each agent run
+	MaxTurns int
+	// Done optionally overrides the completion criterion; it is checked
+	// after each review in addition to the reviewer's decision
+	Done func(result *SupervisorResult) bool
+}
+
+// NewSupervisor creates a supervisor with the given planner and workers.
+func NewSupervisor(s *Swarm, planner *Agent, workers ...*Agent) *Supervisor {
+	return &Supervisor{
+		Swarm:         s,
+		Planner:       planner,
+		Workers:       workers,
+		MaxIterations: DefaultSupervisorIterations,
+		MaxTurns:      10,
+	}
+}
+
+// WithReviewer sets the agent that reviews results and returns the supervisor.
+func (sv *Supervisor) WithReviewer(reviewer *Agent) *Supervisor {
+	sv.Reviewer = reviewer
+	return sv
+}
+
+// WithMaxIterations sets the maximum plan/review iterations and returns the
+// supervisor.
+func (sv *Supervisor) WithMaxIterations(n int) *Supervisor {
+	if n > 0 {
+		sv.MaxIterations = n
+	}
+	return sv
+}
+
+// WithCompletion sets a completion criterion checked after each review, and
+// returns the supervisor.
+func (sv *Supervisor) WithCompletion(done func(result *SupervisorResult) bool) *Supervisor {
+	sv.Done = done
+	return sv
+}
+
+// Run runs the supervisor workflow for goal and returns the result.
+func (sv *Supervisor) Run(ctx context.Context, goal string) (*SupervisorResult, error) {
+	workflow, err := sv.Workflow()
+	if err != nil {
+		return nil, err
+	}
+	handler, err := workflow.Run(ctx, map[string]interface{}{"goal": goal})
+	if err != nil {
+		return nil, err
+	}
+	return WaitAs[*SupervisorResult](handler)
+}
+
+// Workflow builds the supervisor workflow. It starts from a StartEvent with a
+// "goal" input and stops with a *SupervisorResult.
+func (sv *Supervisor) Workflow() (*Workflow, error) {
+	if sv.Swarm == nil || sv.Planner == nil {
+		return nil, fmt.Errorf("%w: supervisor needs a swarm and a planner", ErrInvalidParameter)
+	}
+	if len(sv.Workers) == 0 {
+		return nil, fmt.Errorf("%w: supervisor has no workers", ErrInvalidParameter)
+	}
+
+	workflow := NewWorkflow("supervisor")
+	steps := []Step{
+		NewStep("plan", EventStart, sv.plan, StepConfig{}),
+		NewStep("work", EventSupervisorTask, sv.work, StepConfig{}),
+		NewStep("review", EventParallelResult, sv.review, StepConfig{}),
+	}
+	for _, step := range steps {
+		if err := workflow.AddStep(step); err != nil {
+			return nil, err
+		}
+	}
+	return workflow, nil
+}
+
+// plan asks the planner to decompose the goal and dispatches the tasks.
+func (sv *Supervisor) plan(ctx *Context, event Event) (Event, error) {
+	goal, _ := event.Data()["goal"].(string)
+	if goal == "" {
+		return nil, fmt.Errorf("%w: goal is required", ErrInvalidParameter)
+	}
+	ctx.Set("supervisor.goal", goal)
+	ctx.Set("supervisor.result", &SupervisorResult{})
+
+	prompt := fmt.Sprintf("Goal: %s\n\n%s\n\nBreak the goal into tasks for the workers. %s",
+		goal, sv.roster(), planFormat)
+	plan, err := sv.ask(ctx, sv.Planner, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("planner failed: %w", err)
+	}
+	return sv.dispatch(ctx, plan)
+}
+
+// work runs one task on its worker.
+func (sv *Supervisor) work(ctx *Context, event Event) (Event, error) {
+	var task SupervisorTask
+	if err := ToStruct(event.Data(), &task); err != nil {
+		return nil, fmt.Errorf("invalid supervisor task: %w", err)
+	}
+	worker := findAgent(sv.Workers, task.Worker)
+	if worker == nil {
+		return nil, fmt.Errorf("%w: unknown worker %q", ErrInvalidParameter, task.Worker)
+	}
+
+	goal, _ := ctx.GetString("supervisor.goal")
+	response, err := sv.Swarm.Run(ctx.Context(), worker, []map[string]interface{}{
+		{"role": "user", "content": fmt.Sprintf("Overall goal: %s\n\nYour task: %s", goal, task.Instruction)},
+	}, nil, "", false, false, sv.maxTurns(), true, false)
+	if err != nil {
+		return nil, err
+	}
+	task.Output = lastContent(response)
+
+	data, err := ToMap(task)
+	if err != nil {
+		return nil, err
+	}
+	return NewBaseEvent(EventSupervisorTaskResult, data), nil
+}
+
+// review collects the task results and either stops or dispatches new tasks.
+func (sv *Supervisor) review(ctx *Context, event Event) (Event, error) {
+	results := event.(*ParallelResultEvent)
+	value, _ := ctx.Get("supervisor.result")
+	prev, ok := value.(*SupervisorResult)
+	if !ok {
+		return nil, fmt.Errorf("supervisor state missing")
+	}
+	// Build a new result so a retried review does not count results twice
+	result := &SupervisorResult{Iterations: prev.Iterations + 1}
+	result.Tasks = append(append(result.Tasks, prev.Tasks...), collectTasks(results, result.Iterations)...)
+
+	goal, _ := ctx.GetString("supervisor.goal")
+	var report strings.Builder
+	fmt.Fprintf(&report, "Goal: %s\n\n%s\n\nResults so far:\n", goal, sv.roster())
+	for _, task := range result.Tasks {
+		fmt.Fprintf(&report, "- [%s] %s (%s): ", task.ID, task.Instruction, task.Worker)
+		if task.Error != "" {
+			fmt.Fprintf(&report, "FAILED: %s\n", task.Error)
+		} else {
+			fmt.Fprintf(&report, "%s\n", task.Output)
+		}
+	}
+	fmt.Fprintf(&report, "\nIf the goal is met, set done and give the final answer. Otherwise plan the remaining tasks. %s", planFormat)
+
+	reviewer := sv.Reviewer
+	if reviewer == nil {
+		reviewer = sv.Planner
+	}
+	decision, err := sv.ask(ctx, reviewer, report.String())
+	if err != nil {
+		return nil, fmt.Errorf("reviewer failed: %w", err)
+	}
+	result.Answer = decision.Answer
+	result.Done = decision.Done || len(decision.Tasks) == 0
+	if sv.Done != nil && sv.Done(result) {
+		result.Done = true
+	}
+
+	maxIterations := sv.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultSupervisorIterations
+	}
+	ctx.Set("supervisor.result", result)
+	if result.Done || result.Iterations >= maxIterations {
+		return NewStopEvent(result), nil
+	}
+	return sv.dispatch(ctx, decision)
+}
+
+// dispatch converts planned tasks into a ParallelEvent.
+func (sv *Supervisor) dispatch(ctx *Context, plan *supervisorPlan) (Event, error) {
+	if len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("planner returned no tasks")
+	}
+	value, _ := ctx.Get("supervisor.result")
+	result, _ := value.(*SupervisorResult)
+	iteration := 1
+	if result != nil {
+		iteration = result.Iterations + 1
+	}
+
+	tasks := make([]Task, 0, len(plan.Tasks))
+	for i, task := range plan.Tasks {
+		if findAgent(sv.Workers, task.Worker) == nil {
+			return nil, fmt.Errorf("%w: planner assigned unknown worker %q", ErrInvalidParameter, task.Worker)
+		}
+		if task.ID == "" {
+			task.ID = fmt.Sprintf("task-%d", i+1)
+		}
+		task.Iteration = iteration
+		// Task IDs must be unique across iterations
+		tasks = append(tasks, NewTask(fmt.Sprintf("%d-%s", iteration, task.ID), EventSupervisorTask, task))
+	}
+	return NewParallelEvent(tasks, "supervisor")
+}
+
+// collectTasks extracts task outcomes from a ParallelResultEvent in task order.
+func collectTasks(results *ParallelResultEvent, iteration int) []SupervisorTask {
+	tasks := make([]SupervisorTask, 0, len(results.Results))
+	for id, value := range results.Results {
+		var task SupervisorTask
+		switch v := value.(type) {
+		case *ErrorEvent:
+			task = SupervisorTask{ID: id, Error: fmt.Sprint(v.Error), Iteration: iteration}
+		case Event:
+			if err := ToStruct(v.Data(), &task); err != nil {
+				task = SupervisorTask{ID: id, Error: err.Error(), Iteration: iteration}
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	// Map iteration order is random; keep results deterministic
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+// planFormat describes the JSON reply expected from the planner and reviewer.
+const planFormat = `Reply with a JSON object: {"done": bool, "answer": string, "tasks": [{"id": string, "worker": string, "instruction": string}]}.`
+
+// roster describes the workers for planning prompts.
+func (sv *Supervisor) roster() string {
+	var b strings.Builder
+	b.WriteString("Workers:")
+	for _, worker := range sv.Workers {
+		b.WriteString("\n- " + worker.Name)
+		if instructions, ok := worker.Instructions.(string); ok && instructions != "" {
+			b.WriteString(": " + instructions)
+		}
+	}
+	return b.String()
+}
+
+// ask runs agent on prompt in JSON mode and parses the plan it returns.
+func (sv *Supervisor) ask(ctx *Context, agent *Agent, prompt string) (*supervisorPlan, error) {
+	response, err := sv.Swarm.Run(ctx.Context(), agent, []map[string]interface{}{
+		{"role": "user", "content": prompt},
+	}, nil, "", false, false, sv.maxTurns(), true, true)
+	if err != nil {
+		return nil, err
+	}
+	var plan supervisorPlan
+	if err := parseJSONReply(lastContent(response), &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (sv *Supervisor) maxTurns() int {
+	if sv.MaxTurns <= 0 {
+		return 10
+	}
+	return sv.MaxTurns
+}
+
+// lastContent returns the content of the last message of a response.
+func lastContent(response *Response) string {
+	if response == nil || len(response.Messages) == 0 {
+		return ""
+	}
+	content, _ := response.Messages[len(response.Messages)-1]["content"].(string)
+	return content
+}
+
+// parseJSONReply decodes a model reply as JSON, tolerating Markdown code fences.
+func parseJSONReply(content string, v interface{}) error {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return fmt.Errorf("invalid JSON reply: %w", err)
+	}
+	return nil
+}
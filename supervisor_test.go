@@ -0,0 +1,101 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// routerClient answers each request by passing the last user message to reply.
+// It is safe for concurrent use.
+type routerClient struct {
+	*MockOpenAIClient
+	reply func(prompt string) string
+	mu    sync.Mutex
+	calls int
+}
+
+func newRouterClient(reply func(prompt string) string) *routerClient {
+	return &routerClient{MockOpenAIClient: NewMockOpenAIClient(), reply: reply}
+}
+
+func (c *routerClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	var prompt string
+	for _, msg := range params.Messages {
+		if msg.OfUser != nil {
+			prompt = msg.OfUser.Content.OfString.Value
+		}
+	}
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: c.reply(prompt), Role: "assistant"}},
+		},
+	}, nil
+}
+
+func TestSupervisor(t *testing.T) {
+	client := newRouterClient(func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, "Your task: research"):
+			return "facts"
+		case strings.Contains(prompt, "Your task: "):
+			return "text"
+		case strings.Contains(prompt, "Break the goal"):
+			return "```json\n" + `{"tasks": [{"id": "a", "worker": "researcher", "instruction": "research"}, {"id": "b", "worker": "writer", "instruction": "draft"}]}` + "\n```"
+		case strings.Contains(prompt, "polish"):
+			return `{"done": true, "answer": "final article"}`
+		default:
+			return `{"done": false, "tasks": [{"id": "c", "worker": "writer", "instruction": "polish"}]}`
+		}
+	})
+	researcher := NewAgent("researcher").WithInstructions("Finds facts")
+	writer := NewAgent("writer").WithInstructions("Writes text")
+
+	result, err := NewSupervisor(NewSwarm(client), NewAgent("planner"), researcher, writer).
+		Run(context.Background(), "write an article")
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, true, result.Done, "done")
+	AssertEqual(t, "final article", result.Answer, "answer")
+	AssertEqual(t, 2, result.Iterations, "iterations")
+	AssertEqual(t, 3, len(result.Tasks), "task count")
+	AssertEqual(t, "facts", result.Tasks[0].Output, "research output")
+	AssertEqual(t, "polish", result.Tasks[2].Instruction, "second iteration task")
+	AssertEqual(t, 2, result.Tasks[2].Iteration, "task iteration")
+}
+
+func TestSupervisorIterationLimit(t *testing.T) {
+	client := newRouterClient(func(prompt string) string {
+		if strings.Contains(prompt, "Your task: ") {
+			return "partial"
+		}
+		return `{"done": false, "answer": "draft", "tasks": [{"worker": "writer", "instruction": "again"}]}`
+	})
+
+	result, err := NewSupervisor(NewSwarm(client), NewAgent("planner"), NewAgent("writer")).
+		WithMaxIterations(2).
+		Run(context.Background(), "never satisfied")
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, false, result.Done, "not done")
+	AssertEqual(t, 2, result.Iterations, "iterations")
+	AssertEqual(t, "draft", result.Answer, "best answer")
+
+	t.Run("completion criterion", func(t *testing.T) {
+		result, err := NewSupervisor(NewSwarm(client), NewAgent("planner"), NewAgent("writer")).
+			WithCompletion(func(r *SupervisorResult) bool { return len(r.Tasks) > 0 }).
+			Run(context.Background(), "stop early")
+		AssertNoError(t, err, "Run")
+		AssertEqual(t, true, result.Done, "done")
+		AssertEqual(t, 1, result.Iterations, "iterations")
+	})
+}
+
+func TestSupervisorValidation(t *testing.T) {
+	_, err := NewSupervisor(NewSwarm(NewMockOpenAIClient()), NewAgent("planner")).Workflow()
+	AssertError(t, err, "Expected error without workers")
+}
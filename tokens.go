@@ -0,0 +1,242 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrTokenBudgetExceeded is returned by TrimToBudget when the messages that must
+// be kept (system messages and the latest message) do not fit the budget.
+var ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+
+// Token accounting overheads used by OpenAI chat models.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// Tokenizer counts the tokens of a text. The method set matches tiktoken-go
+// codecs (e.g. github.com/tiktoken-go/tokenizer), so an exact BPE tokenizer can
+// be plugged in with RegisterTokenizer.
+type Tokenizer interface {
+	Count(text string) (int, error)
+}
+
+// EstimateTokenizer approximates token counts at four characters per token.
+// It is the fallback for models without a registered tokenizer and slightly
+// overestimates English text, which is the safe direction for trimming.
+type EstimateTokenizer struct{}
+
+// Count returns the estimated number of tokens in text.
+func (EstimateTokenizer) Count(text string) (int, error) {
+	return (utf8.RuneCountInString(text) + 3) / 4, nil
+}
+
+// tokenizers maps model name prefixes to tokenizers.
+var (
+	tokenizers   = make(map[string]Tokenizer)
+	tokenizersMu sync.RWMutex
+)
+
+// RegisterTokenizer sets the tokenizer used for models whose name starts with
+// modelPrefix. The longest matching prefix wins.
+func RegisterTokenizer(modelPrefix string, tokenizer Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	if tokenizer == nil {
+		delete(tokenizers, modelPrefix)
+		return
+	}
+	tokenizers[modelPrefix] = tokenizer
+}
+
+// TokenizerForModel returns the tokenizer registered for model, or
+// EstimateTokenizer if none matches.
+func TokenizerForModel(model string) Tokenizer {
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	best := ""
+	var match Tokenizer = EstimateTokenizer{}
+	for prefix, tokenizer := range tokenizers {
+		if strings.HasPrefix(model, prefix) && len(prefix) >= len(best) {
+			best, match = prefix, tokenizer
+		}
+	}
+	return match
+}
+
+// contextWindows lists the context window of well-known models by name prefix.
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-32k":     32768,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"o1":            200000,
+	"o3":            200000,
+	"o4-mini":       200000,
+}
+
+// ContextWindow returns the known context window of model in tokens, or 0 if
+// the model is unknown.
+func ContextWindow(model string) int {
+	best := ""
+	for prefix := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return contextWindows[best]
+}
+
+// CountMessageTokens returns the number of prompt tokens the messages consume
+// for model, including per-message overhead and reply priming.
+func CountMessageTokens(messages []map[string]interface{}, model string) (int, error) {
+	tokenizer := TokenizerForModel(model)
+	total := tokensPerReply
+	for _, msg := range messages {
+		n, err := messageTokens(tokenizer, msg)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// messageTokens counts the tokens of a single message.
+func messageTokens(tokenizer Tokenizer, msg map[string]interface{}) (int, error) {
+	var parts []string
+	for _, key := range []string{"role", "content", "name"} {
+		if s, ok := msg[key].(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	switch toolCalls := msg["tool_calls"].(type) {
+	case []openai.ChatCompletionMessageToolCall:
+		for _, tc := range toolCalls {
+			parts = append(parts, tc.Function.Name, tc.Function.Arguments)
+		}
+	case []map[string]interface{}:
+		for _, tc := range toolCalls {
+			if fn, ok := tc["function"].(map[string]interface{}); ok {
+				parts = append(parts, fmt.Sprint(fn["name"]), fmt.Sprint(fn["arguments"]))
+			}
+		}
+	}
+
+	total := tokensPerMessage
+	for _, part := range parts {
+		n, err := tokenizer.Count(part)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count tokens: %w", err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// TrimToBudget drops the oldest conversation messages until the history fits in
+// maxTokens for model. System messages and the latest message are always kept,
+// with the assistant message whose tool results end the history, and an
+// assistant message is dropped together with its tool results so no tool
+// message is left without its call. The input slice is not modified.
+//
+// Returns ErrTokenBudgetExceeded (with the kept messages) if even the required
+// messages do not fit.
+func TrimToBudget(messages []map[string]interface{}, model string, maxTokens int) ([]map[string]interface{}, error) {
+	if maxTokens <= 0 || len(messages) == 0 {
+		return messages, nil
+	}
+
+	tokenizer := TokenizerForModel(model)
+	costs := make([]int, len(messages))
+	total := tokensPerReply
+	for i, msg := range messages {
+		n, err := messageTokens(tokenizer, msg)
+		if err != nil {
+			return nil, err
+		}
+		costs[i] = n
+		total += n
+	}
+	if total <= maxTokens {
+		return messages, nil
+	}
+
+	dropped := make(map[int]bool)
+	// The latest message is kept with the call of trailing tool results
+	last := len(messages) - 1
+	for last > 0 && messages[last]["role"] == "tool" {
+		last--
+	}
+	for i := 0; i < last && total > maxTokens; i++ {
+		if role, _ := messages[i]["role"].(string); role == "system" || dropped[i] {
+			continue
+		}
+		// Drop the message together with any tool results that follow it
+		for j := i; j < last && (j == i || messages[j]["role"] == "tool"); j++ {
+			dropped[j] = true
+			total -= costs[j]
+		}
+	}
+
+	keptIdx := make([]int, 0, len(messages)-len(dropped))
+	for i := range messages {
+		if !dropped[i] {
+			keptIdx = append(keptIdx, i)
+		}
+	}
+	// Never start the conversation with orphaned tool results
+	for i := 0; i < len(keptIdx) && keptIdx[i] < last; {
+		role := messages[keptIdx[i]]["role"]
+		if role == "system" {
+			i++
+			continue
+		}
+		if role != "tool" {
+			break
+		}
+		total -= costs[keptIdx[i]]
+		keptIdx = append(keptIdx[:i], keptIdx[i+1:]...)
+	}
+	kept := make([]map[string]interface{}, len(keptIdx))
+	for i, idx := range keptIdx {
+		kept[i] = messages[idx]
+	}
+
+	if total > maxTokens {
+		return kept, fmt.Errorf("%w: %d tokens needed, budget is %d", ErrTokenBudgetExceeded, total, maxTokens)
+	}
+	return kept, nil
+}
+
+// trimHistory trims history to fit the agent's context window, leaving room for
+// the instructions and the response. Unknown models without an explicit
+// ContextWindow are not trimmed. It runs after compaction, as a last resort
+// for histories a Compactor left too large.
+func trimHistory(agent *Agent, instructions string, history []map[string]interface{}, model string) ([]map[string]interface{}, error) {
+	window := agent.ContextWindow
+	if window == 0 {
+		window = ContextWindow(model)
+	}
+	if window <= 0 {
+		return history, nil
+	}
+
+	reserved, err := messageTokens(TokenizerForModel(model), map[string]interface{}{"role": "system", "content": instructions})
+	if err != nil {
+		return nil, err
+	}
+	budget := window - reserved - agent.MaxTokens
+	if budget <= 0 {
+		return nil, fmt.Errorf("%w: instructions and max tokens exceed the %d token context window", ErrTokenBudgetExceeded, window)
+	}
+	return TrimToBudget(history, model, budget)
+}
@@ -0,0 +1,77 @@
+package swarm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTrimToBudget(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	messages := []map[string]interface{}{
+		{"role": "system", "content": "rules"},
+		{"role": "user", "content": long},
+		{"role": "assistant", "content": "", "tool_calls": []map[string]interface{}{
+			{"function": map[string]interface{}{"name": "lookup", "arguments": "{}"}},
+		}},
+		{"role": "tool", "content": long},
+		{"role": "user", "content": "latest question"},
+	}
+
+	total, err := CountMessageTokens(messages, "gpt-4o")
+	AssertNoError(t, err, "CountMessageTokens")
+
+	kept, err := TrimToBudget(messages, "gpt-4o", total)
+	AssertNoError(t, err, "TrimToBudget within budget")
+	AssertEqual(t, len(messages), len(kept), "nothing trimmed within budget")
+
+	kept, err = TrimToBudget(messages, "gpt-4o", 50)
+	AssertNoError(t, err, "TrimToBudget")
+	AssertEqual(t, 2, len(kept), "system and latest message kept")
+	AssertEqual(t, "system", kept[0]["role"], "system message kept")
+	AssertEqual(t, "latest question", kept[1]["content"], "latest message kept")
+
+	_, err = TrimToBudget(messages, "gpt-4o", 5)
+	if !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Errorf("Expected ErrTokenBudgetExceeded, got %v", err)
+	}
+}
+
+func TestTrimToBudgetTrailingToolResults(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	messages := []map[string]interface{}{
+		{"role": "user", "content": long},
+		{"role": "assistant", "content": "", "tool_calls": []map[string]interface{}{
+			{"function": map[string]interface{}{"name": "lookup", "arguments": "{}"}},
+		}},
+		{"role": "tool", "content": "result"},
+	}
+
+	kept, err := TrimToBudget(messages, "gpt-4o", 30)
+	AssertNoError(t, err, "TrimToBudget")
+	AssertEqual(t, 2, len(kept), "tool call and result kept")
+	AssertEqual(t, "assistant", kept[0]["role"], "tool call kept with its result")
+	AssertEqual(t, "tool", kept[1]["role"], "latest message kept")
+
+	kept, err = TrimToBudget(messages, "gpt-4o", 5)
+	if !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Errorf("Expected ErrTokenBudgetExceeded, got %v", err)
+	}
+	AssertEqual(t, 2, len(kept), "tool call kept over budget")
+}
+
+type wordTokenizer struct{}
+
+func (wordTokenizer) Count(text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+func TestRegisterTokenizer(t *testing.T) {
+	RegisterTokenizer("test-model", wordTokenizer{})
+	defer RegisterTokenizer("test-model", nil)
+
+	n, err := TokenizerForModel("test-model-v2").Count("one two three")
+	AssertNoError(t, err, "Count")
+	AssertEqual(t, 3, n, "registered tokenizer used")
+	AssertEqual(t, 128000, ContextWindow("gpt-4o-mini"), "known context window")
+}
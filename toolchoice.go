@@ -0,0 +1,96 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// Tool choices for Agent.WithToolChoice and WithToolChoice.
+var (
+	// ToolChoiceAuto lets the model decide whether to call tools
+	ToolChoiceAuto = openai.ChatCompletionToolChoiceOptionUnionParam{
+		OfAuto: openai.String(string(openai.ChatCompletionToolChoiceOptionAutoAuto)),
+	}
+	// ToolChoiceNone forbids tool calls
+	ToolChoiceNone = openai.ChatCompletionToolChoiceOptionUnionParam{
+		OfAuto: openai.String(string(openai.ChatCompletionToolChoiceOptionAutoNone)),
+	}
+	// RequireToolUse requires the model to call at least one tool
+	RequireToolUse = openai.ChatCompletionToolChoiceOptionUnionParam{
+		OfAuto: openai.String(string(openai.ChatCompletionToolChoiceOptionAutoRequired)),
+	}
+)
+
+// ForceTool returns a tool choice that requires the model to call the named
+// function.
+func ForceTool(name string) openai.ChatCompletionToolChoiceOptionUnionParam {
+	return openai.ChatCompletionToolChoiceOptionParamOfChatCompletionNamedToolChoice(
+		openai.ChatCompletionNamedToolChoiceFunctionParam{Name: name},
+	)
+}
+
+// WithToolChoice sets how the agent uses tools on every turn and returns the
+// agent for chaining.
+func (a *Agent) WithToolChoice(choice openai.ChatCompletionToolChoiceOptionUnionParam) *Agent {
+	a.ToolChoice = &choice
+	return a
+}
+
+// toolChoiceContextKey carries a per-run tool choice.
+type toolChoiceContextKey struct{}
+
+// WithToolChoice returns ctx carrying a tool choice for runs started with it,
+// overriding the agents' own ToolChoice. Forced and required choices only hold
+// until the model calls a tool; later turns fall back to auto so the run can
+// produce a reply.
+func WithToolChoice(ctx context.Context, choice openai.ChatCompletionToolChoiceOptionUnionParam) context.Context {
+	return context.WithValue(ctx, toolChoiceContextKey{}, choice)
+}
+
+// toolChoiceFromContext returns the per-run tool choice carried by ctx.
+func toolChoiceFromContext(ctx context.Context) (openai.ChatCompletionToolChoiceOptionUnionParam, bool) {
+	choice, ok := ctx.Value(toolChoiceContextKey{}).(openai.ChatCompletionToolChoiceOptionUnionParam)
+	return choice, ok
+}
+
+// releaseToolChoice returns ctx with a forced or required per-run tool choice
+// relaxed to auto, once the model has called tools.
+func releaseToolChoice(ctx context.Context) context.Context {
+	choice, ok := toolChoiceFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if choice.OfChatCompletionNamedToolChoice != nil ||
+		choice.OfAuto.Value == string(openai.ChatCompletionToolChoiceOptionAutoRequired) {
+		return WithToolChoice(ctx, ToolChoiceAuto)
+	}
+	return ctx
+}
+
+// resolveToolChoice returns the tool choice for the agent's next request, or
+// nil if the provider default applies. Forcing a tool the agent does not have
+// is an error.
+func resolveToolChoice(ctx context.Context, agent *Agent) (*openai.ChatCompletionToolChoiceOptionUnionParam, error) {
+	choice, ok := toolChoiceFromContext(ctx)
+	if !ok {
+		if agent.ToolChoice == nil {
+			return nil, nil
+		}
+		choice = *agent.ToolChoice
+	}
+	if named := choice.OfChatCompletionNamedToolChoice; named != nil {
+		found := false
+		for _, f := range agent.Functions {
+			if f != nil && f.Name() == named.Function.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: forced tool %q is not available to agent %q", ErrInvalidParameter, named.Function.Name, agent.Name)
+		}
+	}
+	return &choice, nil
+}
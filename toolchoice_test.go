@@ -0,0 +1,93 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/ssestream"
+)
+
+func newWeatherAgent() *Agent {
+	return NewAgent("weather").AddFunction(NewAgentFunction("getWeather", "Get the weather",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "sunny", nil
+		},
+		[]Parameter{},
+	))
+}
+
+func TestForceToolPerRun(t *testing.T) {
+	client := newScriptedClient("", "It is sunny")
+	client.CompletionResponse[0].Choices[0].Message.ToolCalls = []openai.ChatCompletionMessageToolCall{
+		MockToolCall{ID: "call-1", Name: "getWeather", Args: "{}"}.ToOpenAI(),
+	}
+
+	ctx := WithToolChoice(context.Background(), ForceTool("getWeather"))
+	response, err := NewSwarm(client).Run(ctx, newWeatherAgent(), []map[string]interface{}{
+		{"role": "user", "content": "Weather?"},
+	}, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run")
+	AssertEqual(t, "It is sunny", lastContent(response), "reply")
+
+	AssertEqual(t, 2, len(client.requests), "request count")
+	forced := client.requests[0].ToolChoice.OfChatCompletionNamedToolChoice
+	if forced == nil {
+		t.Fatal("expected a forced tool choice on the first turn")
+	}
+	AssertEqual(t, "getWeather", forced.Function.Name, "forced tool")
+	AssertEqual(t, "auto", client.requests[1].ToolChoice.OfAuto.Value, "tool choice after the tool call")
+}
+
+func TestToolChoiceOverridesAgent(t *testing.T) {
+	client := newScriptedClient("no tools", "tools required")
+	agent := newWeatherAgent().WithToolChoice(RequireToolUse)
+	messages := []map[string]interface{}{{"role": "user", "content": "Hi"}}
+
+	_, err := NewSwarm(client).Run(WithToolChoice(context.Background(), ToolChoiceNone), agent, messages, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run with ToolChoiceNone")
+	AssertEqual(t, "none", client.requests[0].ToolChoice.OfAuto.Value, "per-run choice")
+
+	_, err = NewSwarm(client).Run(context.Background(), agent, messages, nil, "", false, false, 10, true, false)
+	AssertNoError(t, err, "Run with agent choice")
+	AssertEqual(t, "required", client.requests[1].ToolChoice.OfAuto.Value, "agent choice")
+}
+
+func TestForceUnknownTool(t *testing.T) {
+	client := newScriptedClient("unused")
+	ctx := WithToolChoice(context.Background(), ForceTool("missing"))
+
+	_, err := NewSwarm(client).Run(ctx, newWeatherAgent(), []map[string]interface{}{
+		{"role": "user", "content": "Hi"},
+	}, nil, "", false, false, 10, true, false)
+	AssertError(t, err, "Run")
+	AssertEqual(t, 0, len(client.requests), "no request sent")
+}
+
+type streamParamsClient struct {
+	*MockOpenAIClient
+	params []openai.ChatCompletionNewParams
+}
+
+func (c *streamParamsClient) CreateChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams) (*ssestream.Stream[openai.ChatCompletionChunk], error) {
+	c.params = append(c.params, params)
+	return c.MockOpenAIClient.CreateChatCompletionStream(ctx, params)
+}
+
+func TestToolChoiceRunAndStream(t *testing.T) {
+	client := &streamParamsClient{MockOpenAIClient: NewMockOpenAIClient()}
+	client.AddStreamChunk(&openai.ChatCompletionChunk{
+		Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoiceDelta{Content: "hi"}}},
+	})
+
+	ctx := WithToolChoice(context.Background(), ToolChoiceNone)
+	ch, err := NewSwarm(client).RunAndStream(ctx, newWeatherAgent(), []map[string]interface{}{
+		{"role": "user", "content": "Hi"},
+	}, nil, "", false, 10, true, false)
+	AssertNoError(t, err, "RunAndStream")
+	for range ch {
+	}
+
+	AssertEqual(t, 1, len(client.params), "request count")
+	AssertEqual(t, "none", client.params[0].ToolChoice.OfAuto.Value, "tool choice")
+}
@@ -0,0 +1,137 @@
+package swarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ToolErrorKind classifies failed tool calls.
+type ToolErrorKind string
+
+const (
+	// ToolErrorNotFound means the model called a tool the agent does not have
+	ToolErrorNotFound ToolErrorKind = "tool_not_found"
+	// ToolErrorInvalidArguments means the arguments were not valid JSON
+	ToolErrorInvalidArguments ToolErrorKind = "invalid_arguments"
+	// ToolErrorDenied means an approval hook rejected the call
+	ToolErrorDenied ToolErrorKind = "denied"
+	// ToolErrorExecution means the function returned an error
+	ToolErrorExecution ToolErrorKind = "execution_failed"
+	// ToolErrorResult means the function's result could not be converted
+	ToolErrorResult ToolErrorKind = "invalid_result"
+)
+
+// DefaultMaxToolFailures is the default failure cap of a ToolErrorPolicy.
+const DefaultMaxToolFailures = 3
+
+// ToolErrorPolicy controls how failed tool calls are reported to the model
+// and how many are tolerated. Without a policy, failures are reported as
+// plain text and never stop the run.
+type ToolErrorPolicy struct {
+	// MaxFailures caps consecutive failures of the same tool in a run; the run
+	// stops with a *ToolFailureError once a tool fails more often (default 3,
+	// negative disables the cap)
+	MaxFailures int
+}
+
+// ToolFailureError is returned when a tool keeps failing beyond the
+// ToolErrorPolicy's cap.
+type ToolFailureError struct {
+	// Tool is the failing tool
+	Tool string
+	// Kind classifies the last failure
+	Kind ToolErrorKind
+	// Failures is the number of consecutive failures
+	Failures int
+	// Message describes the last failure
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ToolFailureError) Error() string {
+	return fmt.Sprintf("tool %q failed %d times in a row (%s): %s", e.Tool, e.Failures, e.Kind, e.Message)
+}
+
+// WithToolErrorPolicy sets the tool error policy and returns the swarm.
+func (s *Swarm) WithToolErrorPolicy(policy *ToolErrorPolicy) *Swarm {
+	s.ToolErrors = policy
+	return s
+}
+
+// toolErrorContent formats a tool failure for the model. With a policy set,
+// it is a JSON object with the failure kind and, where it helps the model
+// correct the call, the tool's parameter schema or the available tools.
+func (s *Swarm) toolErrorContent(kind ToolErrorKind, name, errMsg string, fn AgentFunction, functions map[string]AgentFunction) string {
+	if s.ToolErrors == nil {
+		return fmt.Sprintf("Error: %s", errMsg)
+	}
+	details := map[string]interface{}{
+		"error":   kind,
+		"tool":    name,
+		"message": errMsg,
+	}
+	switch kind {
+	case ToolErrorNotFound:
+		available := make([]string, 0, len(functions))
+		for toolName := range functions {
+			available = append(available, toolName)
+		}
+		sort.Strings(available)
+		details["available_tools"] = available
+	case ToolErrorInvalidArguments, ToolErrorExecution:
+		if schema := toolParameters(fn); schema != nil {
+			details["parameters"] = schema
+		}
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Sprintf("Error: %s", errMsg)
+	}
+	return string(data)
+}
+
+// toolParameters returns the JSON Schema of fn's model-visible parameters.
+func toolParameters(fn AgentFunction) map[string]interface{} {
+	funcJSON := FunctionToJSON(fn)
+	if funcJSON == nil {
+		return nil
+	}
+	params, _ := funcJSON["function"].(map[string]interface{})["parameters"].(map[string]interface{})
+	if props, ok := params["properties"].(map[string]interface{}); ok {
+		delete(props, ContextVariablesName)
+		delete(props, FilesName)
+	}
+	return params
+}
+
+// toolFailures tracks consecutive failures per tool during a run.
+type toolFailures map[string]int
+
+// record counts the turn's tool results and returns a *ToolFailureError once
+// a tool exceeds the policy's cap.
+func (f toolFailures) record(policy *ToolErrorPolicy, results []ToolResult) error {
+	if policy == nil {
+		return nil
+	}
+	limit := policy.MaxFailures
+	if limit == 0 {
+		limit = DefaultMaxToolFailures
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			delete(f, result.Name)
+			continue
+		}
+		f[result.Name]++
+		if limit > 0 && f[result.Name] > limit {
+			return &ToolFailureError{
+				Tool:     result.Name,
+				Kind:     result.ErrorKind,
+				Failures: f[result.Name],
+				Message:  result.Error,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package swarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// newFailingToolClient calls tool with args on every turn.
+func newFailingToolClient(tool, args string, turns int) *MockOpenAIClient {
+	client := NewMockOpenAIClient()
+	for i := 0; i < turns; i++ {
+		client.SetCompletionResponse(&openai.ChatCompletion{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role:      "assistant",
+				ToolCalls: []openai.ChatCompletionMessageToolCall{MockToolCall{ID: "call", Name: tool, Args: args}.ToOpenAI()},
+			}}},
+		})
+	}
+	return client
+}
+
+func newCityAgent(err error) *Agent {
+	return NewAgent("weather").AddFunction(NewAgentFunction("getWeather", "Get the weather",
+		func(args map[string]interface{}) (interface{}, error) {
+			return nil, err
+		},
+		[]Parameter{{Name: "city", Type: reflect.TypeOf(""), Description: "The city", Required: true}},
+	))
+}
+
+func TestToolErrorPolicyCapsFailures(t *testing.T) {
+	swarm := NewSwarm(newFailingToolClient("getWeather", `{"city": "Atlantis"}`, 10)).
+		WithToolErrorPolicy(&ToolErrorPolicy{MaxFailures: 2})
+
+	_, err := swarm.Run(context.Background(), newCityAgent(errors.New("unknown city")), []map[string]interface{}{
+		{"role": "user", "content": "Weather in Atlantis?"},
+	}, nil, "", false, false, 20, true, false)
+
+	var failure *ToolFailureError
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected a ToolFailureError, got %v", err)
+	}
+	AssertEqual(t, "getWeather", failure.Tool, "failing tool")
+	AssertEqual(t, ToolErrorExecution, failure.Kind, "failure kind")
+	AssertEqual(t, 3, failure.Failures, "failure count")
+}
+
+func TestToolErrorPolicyStructuredErrors(t *testing.T) {
+	swarm := NewSwarm(NewMockOpenAIClient()).WithToolErrorPolicy(&ToolErrorPolicy{})
+	agent := newCityAgent(nil)
+
+	response, err := swarm.handleToolCalls(context.Background(), []openai.ChatCompletionMessageToolCall{
+		MockToolCall{ID: "1", Name: "getWeather", Args: "{city"}.ToOpenAI(),
+		MockToolCall{ID: "2", Name: "getForecast", Args: "{}"}.ToOpenAI(),
+	}, agent.Functions, nil, false)
+	AssertNoError(t, err, "handleToolCalls")
+
+	var invalid struct {
+		Error      ToolErrorKind          `json:"error"`
+		Tool       string                 `json:"tool"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	AssertNoError(t, json.Unmarshal([]byte(response.Messages[0]["content"].(string)), &invalid), "invalid arguments details")
+	AssertEqual(t, ToolErrorInvalidArguments, invalid.Error, "invalid arguments kind")
+	AssertEqual(t, "getWeather", invalid.Tool, "invalid arguments tool")
+	_, hasCity := invalid.Parameters["properties"].(map[string]interface{})["city"]
+	AssertEqual(t, true, hasCity, "schema hint")
+
+	var missing struct {
+		Error     ToolErrorKind `json:"error"`
+		Available []string      `json:"available_tools"`
+	}
+	AssertNoError(t, json.Unmarshal([]byte(response.Messages[1]["content"].(string)), &missing), "not found details")
+	AssertEqual(t, ToolErrorNotFound, missing.Error, "not found kind")
+	AssertEqual(t, 1, len(missing.Available), "available tools")
+	AssertEqual(t, "getWeather", missing.Available[0], "available tool")
+}
+
+func TestToolFailuresResetOnSuccess(t *testing.T) {
+	failures := toolFailures{}
+	policy := &ToolErrorPolicy{MaxFailures: 1}
+
+	AssertNoError(t, failures.record(policy, []ToolResult{{Name: "a", Error: "boom"}}), "first failure")
+	AssertNoError(t, failures.record(policy, []ToolResult{{Name: "a", Result: "ok"}}), "success")
+	AssertNoError(t, failures.record(policy, []ToolResult{{Name: "a", Error: "boom"}}), "failure after success")
+	AssertError(t, failures.record(policy, []ToolResult{{Name: "a", Error: "boom"}}), "second consecutive failure")
+	AssertNoError(t, failures.record(nil, []ToolResult{{Name: "a", Error: "boom"}}), "no policy")
+}
@@ -0,0 +1,93 @@
+package swarm
+
+import (
+	"context"
+
+	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies spans created by this package.
+const instrumentationName = "github.com/feiskyer/swarm-go"
+
+// Span attribute keys used by swarm spans.
+const (
+	attrAgent        = attribute.Key("swarm.agent")
+	attrTurn         = attribute.Key("swarm.turn")
+	attrTool         = attribute.Key("swarm.tool")
+	attrModel        = attribute.Key("gen_ai.request.model")
+	attrInputTokens  = attribute.Key("gen_ai.usage.input_tokens")
+	attrOutputTokens = attribute.Key("gen_ai.usage.output_tokens")
+	attrTotalTokens  = attribute.Key("gen_ai.usage.total_tokens")
+	attrWorkflow     = attribute.Key("workflow.name")
+	attrRunID        = attribute.Key("workflow.run_id")
+	attrStep         = attribute.Key("workflow.step")
+	attrTask         = attribute.Key("workflow.task")
+	attrEventType    = attribute.Key("workflow.event_type")
+	attrAttempt      = attribute.Key("workflow.attempt")
+)
+
+// tracerFrom returns a tracer from tp, or a no-op tracer when tp is nil.
+func tracerFrom(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
+
+// WithTracerProvider enables OpenTelemetry spans for runs, turns, chat
+// completions and tool calls, and returns the swarm.
+func (s *Swarm) WithTracerProvider(tp trace.TracerProvider) *Swarm {
+	s.TracerProvider = tp
+	return s
+}
+
+// WithTracerProvider enables OpenTelemetry spans for the workflow run and each
+// step attempt, and returns the workflow.
+func (w *Workflow) WithTracerProvider(tp trace.TracerProvider) *Workflow {
+	w.config.TracerProvider = tp
+	return w
+}
+
+// tracer returns the swarm's tracer.
+func (s *Swarm) tracer() trace.Tracer {
+	return tracerFrom(s.TracerProvider)
+}
+
+// resolveModel returns the model used for an agent request.
+func resolveModel(agent *Agent, modelOverride string) string {
+	if modelOverride != "" {
+		return modelOverride
+	}
+	return agent.Model
+}
+
+// startChatSpan starts a span around a single chat completion request.
+func (s *Swarm) startChatSpan(ctx context.Context, agent *Agent, modelOverride string) (context.Context, trace.Span) {
+	return s.tracer().Start(ctx, "chat.completion", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrAgent.String(agent.Name),
+			attrModel.String(resolveModel(agent, modelOverride)),
+		))
+}
+
+// setUsageAttributes records token counts on a span.
+func setUsageAttributes(span trace.Span, usage openai.CompletionUsage) {
+	span.SetAttributes(
+		attrInputTokens.Int64(usage.PromptTokens),
+		attrOutputTokens.Int64(usage.CompletionTokens),
+		attrTotalTokens.Int64(usage.TotalTokens),
+	)
+}
+
+// endSpan records err (if any) on the span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
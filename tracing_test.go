@@ -0,0 +1,161 @@
+package swarm
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// spanRecorder is a TracerProvider that records ended spans, so the tests do
+// not depend on the OpenTelemetry SDK.
+type spanRecorder struct {
+	embedded.TracerProvider
+
+	mu     sync.Mutex
+	nextID uint64
+	ended  []*recordedSpan
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{recorder: r}
+}
+
+// Ended returns the spans ended so far.
+func (r *spanRecorder) Ended() []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*recordedSpan(nil), r.ended...)
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	recorder *spanRecorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.recorder.mu.Lock()
+	t.recorder.nextID++
+	id := t.recorder.nextID
+	t.recorder.mu.Unlock()
+
+	parent := trace.SpanContextFromContext(ctx)
+	traceID := parent.TraceID()
+	if !parent.HasTraceID() {
+		binary.BigEndian.PutUint64(traceID[8:], id)
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], id)
+
+	config := trace.NewSpanStartConfig(opts...)
+	span := &recordedSpan{
+		recorder:    t.recorder,
+		name:        name,
+		parent:      parent,
+		spanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}),
+		attributes:  config.Attributes(),
+	}
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// recordedSpan is a span captured by spanRecorder.
+type recordedSpan struct {
+	embedded.Span
+	recorder *spanRecorder
+
+	name        string
+	parent      trace.SpanContext
+	spanContext trace.SpanContext
+	attributes  []attribute.KeyValue
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.ended = append(s.recorder.ended, s)
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.attributes = append(s.attributes, kv...)
+}
+
+func (s *recordedSpan) SetName(name string) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.name = name
+}
+
+func (s *recordedSpan) AddEvent(string, ...trace.EventOption)   {}
+func (s *recordedSpan) AddLink(trace.Link)                      {}
+func (s *recordedSpan) RecordError(error, ...trace.EventOption) {}
+func (s *recordedSpan) SetStatus(codes.Code, string)            {}
+func (s *recordedSpan) IsRecording() bool                       { return true }
+func (s *recordedSpan) SpanContext() trace.SpanContext          { return s.spanContext }
+func (s *recordedSpan) TracerProvider() trace.TracerProvider    { return s.recorder }
+
+func TestRunTracing(t *testing.T) {
+	recorder := &spanRecorder{}
+
+	mockClient := NewMockOpenAIClient()
+	mockClient.SetCompletionResponse(&openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "Hi", Role: "assistant"}},
+		},
+		Usage: openai.CompletionUsage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7},
+	})
+	swarm := NewSwarm(mockClient).WithTracerProvider(recorder)
+
+	agent := NewAgent("Tracer")
+	agent.Model = "gpt-4o"
+	messages := []map[string]interface{}{{"role": "user", "content": "Hello"}}
+	_, err := swarm.Run(context.Background(), agent, messages, nil, "", false, false, 1, true, false)
+	AssertNoError(t, err, "Run")
+
+	spans := make(map[string]*recordedSpan)
+	for _, span := range recorder.Ended() {
+		spans[span.name] = span
+	}
+	for _, name := range []string{"swarm.run", "swarm.turn", "chat.completion"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("Expected %s span", name)
+		}
+	}
+
+	attrs := make(map[string]interface{})
+	for _, kv := range spans["chat.completion"].attributes {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	AssertEqual(t, "gpt-4o", attrs["gen_ai.request.model"], "model attribute")
+	AssertEqual(t, int64(7), attrs["gen_ai.usage.total_tokens"], "token attribute")
+	AssertEqual(t, spans["swarm.turn"].spanContext.SpanID(), spans["chat.completion"].parent.SpanID(), "completion nested in turn")
+}
+
+func TestWorkflowTracing(t *testing.T) {
+	recorder := &spanRecorder{}
+
+	workflow := NewWorkflow("traced").WithTracerProvider(recorder)
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	_, err = handler.Wait()
+	AssertNoError(t, err, "Wait")
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.name)
+	}
+	AssertEqual(t, 2, len(names), "step and run spans")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/openai/openai-go"
 )
@@ -90,7 +91,8 @@ func NewAgentFunction(name string, desc string, fn func(map[string]interface{})
 type Agent struct {
 	// Name is the unique identifier for the agent
 	Name string
-	// Instructions define the agent's behavior and role
+	// Instructions define the agent's behavior and role: a string, a func
+	// returning a string, or a *template.Template from NewTemplateInstructions
 	Instructions interface{}
 	// Functions are the tools available to this agent
 	Functions []AgentFunction
@@ -104,6 +106,25 @@ type Agent struct {
 	ToolChoice *openai.ChatCompletionToolChoiceOptionUnionParam
 	// ParallelToolCalls indicates if multiple tools can be called in parallel
 	ParallelToolCalls bool
+	// ContextWindow overrides the model's context window in tokens. The oldest
+	// history is trimmed before each request so the prompt plus MaxTokens fits.
+	// Zero uses the known window of the model; negative disables trimming.
+	ContextWindow int
+	// Compaction compacts long histories before they reach the context
+	// window, e.g. by summarizing the oldest messages (optional)
+	Compaction *CompactionConfig
+	// ReasoningEffort ("low", "medium" or "high") is sent to reasoning models
+	// that support it and ignored for other models
+	ReasoningEffort string
+	// Audio enables transcription of audio messages and, optionally, spoken
+	// replies. The client must implement AudioClient.
+	Audio *AudioOptions
+	// HostedTools are provider-executed tools such as web_search
+	HostedTools []HostedTool
+	// Memory optionally recalls and records facts across conversations
+	Memory Memory
+	// Skills are tags describing what the agent handles, used by SkillRouter
+	Skills []string
 }
 
 // Response encapsulates the result of an agent interaction.
@@ -121,8 +142,89 @@ type Response struct {
 	// TokensUsed tracks the number of tokens used in this response
 	TokensUsed int
 
-	// Cost tracks the estimated cost of this response
+	// Cost tracks the estimated cost of this response, see ModelPricing
 	Cost float64
+
+	// Usage totals the tokens and cost of all turns of the run
+	Usage Usage
+
+	// Handoffs lists the agent transfers made during the run, in order
+	Handoffs []HandoffRecord
+
+	// StopReason explains why the run ended
+	StopReason StopReason
+
+	// StopDetail describes what tripped a LoopGuard or StopCondition, if any
+	StopDetail string
+
+	// Error is the error that ended a streamed run, if it failed
+	Error error
+
+	// History is the compacted conversation, replacing the run's input
+	// messages, if the agent's CompactionConfig compacted it; nil otherwise.
+	// It ends with the Messages added since the compaction.
+	History []map[string]interface{}
+
+	// toolResults describes the tool calls handled in a turn, for streaming
+	toolResults []ToolResult
+}
+
+// ToolResult describes an executed tool call. RunAndStream emits one under
+// the "tool_result" key for each call before the next model turn starts.
+type ToolResult struct {
+	// ID is the tool call ID
+	ID string `json:"id"`
+	// Name is the name of the called function
+	Name string `json:"name"`
+	// Arguments are the JSON arguments the model passed
+	Arguments string `json:"arguments"`
+	// Result is the function's output, if it succeeded
+	Result string `json:"result,omitempty"`
+	// Error describes why the call failed, if it did
+	Error string `json:"error,omitempty"`
+	// ErrorKind classifies the failure
+	ErrorKind ToolErrorKind `json:"error_kind,omitempty"`
+	// Duration is how long the function ran
+	Duration time.Duration `json:"duration"`
+}
+
+// HandoffRecord describes a transfer of the conversation between agents.
+type HandoffRecord struct {
+	// From is the name of the agent that handed off
+	From string `json:"from"`
+	// To is the name of the agent that took over
+	To string `json:"to"`
+	// Tool is the name of the function that performed the transfer
+	Tool string `json:"tool"`
+	// Reason is the "reason" argument of the transfer call, if any
+	Reason string `json:"reason,omitempty"`
+	// Turn is the turn of the run in which the transfer happened, from 1
+	Turn int `json:"turn"`
+}
+
+// Conversation returns the history to continue the conversation with: the
+// run's input messages followed by its Messages, or the compacted History.
+func (r *Response) Conversation(messages []map[string]interface{}) []map[string]interface{} {
+	if r.History != nil {
+		return append([]map[string]interface{}(nil), r.History...)
+	}
+	return append(messages[:len(messages):len(messages)], r.Messages...)
+}
+
+// AgentTrail returns the names of the agents that handled the run, in order,
+// starting with the agent the run began with.
+func (r *Response) AgentTrail() []string {
+	if len(r.Handoffs) == 0 {
+		if r.Agent == nil {
+			return nil
+		}
+		return []string{r.Agent.Name}
+	}
+	trail := []string{r.Handoffs[0].From}
+	for _, handoff := range r.Handoffs {
+		trail = append(trail, handoff.To)
+	}
+	return trail
 }
 
 // Result represents the outcome of a function execution.
@@ -186,6 +288,20 @@ func (a *Agent) WithMaxTokens(tokens int) *Agent {
 	return a
 }
 
+// WithContextWindow sets the context window used for history trimming and
+// returns the agent for chaining.
+func (a *Agent) WithContextWindow(tokens int) *Agent {
+	a.ContextWindow = tokens
+	return a
+}
+
+// WithReasoningEffort sets the reasoning effort for reasoning models and
+// returns the agent for chaining.
+func (a *Agent) WithReasoningEffort(effort string) *Agent {
+	a.ReasoningEffort = effort
+	return a
+}
+
 // WithTemperature sets the temperature for the agent and returns the agent for chaining.
 func (a *Agent) WithTemperature(temp float32) *Agent {
 	if temp < 0 {
@@ -207,6 +323,71 @@ func (a *Agent) AddFunction(f AgentFunction) *Agent {
 	return a
 }
 
+// Clone returns a deep copy of the agent that can be modified, or used
+// concurrently with the original, without affecting it. SimpleAgentFunctions
+// are copied; other AgentFunction implementations are shared.
+func (a *Agent) Clone() *Agent {
+	if a == nil {
+		return nil
+	}
+	clone := *a
+	if a.Functions != nil {
+		clone.Functions = make([]AgentFunction, len(a.Functions))
+		for i, f := range a.Functions {
+			if simple, ok := f.(*SimpleAgentFunction); ok && simple != nil {
+				copied := *simple
+				copied.ParametersList = append([]Parameter(nil), simple.ParametersList...)
+				f = &copied
+			}
+			clone.Functions[i] = f
+		}
+	}
+	if a.ToolChoice != nil {
+		toolChoice := *a.ToolChoice
+		clone.ToolChoice = &toolChoice
+	}
+	if a.Audio != nil {
+		audio := *a.Audio
+		clone.Audio = &audio
+	}
+	if a.Compaction != nil {
+		compaction := *a.Compaction
+		clone.Compaction = &compaction
+	}
+	if a.Skills != nil {
+		clone.Skills = append([]string(nil), a.Skills...)
+	}
+	if a.HostedTools != nil {
+		clone.HostedTools = make([]HostedTool, len(a.HostedTools))
+		for i, tool := range a.HostedTools {
+			tool.VectorStoreIDs = append([]string(nil), tool.VectorStoreIDs...)
+			clone.HostedTools[i] = tool
+		}
+	}
+	return &clone
+}
+
+// Spawn creates n clones of the agent named "<name>-1" to "<name>-n", e.g. a
+// pool of reviewers for parallel tasks. configure, if not nil, is called with
+// each clone and its 1-based index to parameterize it:
+//
+//	personas := []string{"security expert", "performance expert"}
+//	reviewers := reviewer.Spawn(len(personas), func(i int, a *Agent) {
+//		a.WithInstructions("You review code as a " + personas[i-1] + ".")
+//	})
+func (a *Agent) Spawn(n int, configure func(i int, agent *Agent)) []*Agent {
+	agents := make([]*Agent, 0, max(n, 0))
+	for i := 1; i <= n; i++ {
+		clone := a.Clone()
+		clone.Name = fmt.Sprintf("%s-%d", a.Name, i)
+		if configure != nil {
+			configure(i, clone)
+		}
+		agents = append(agents, clone)
+	}
+	return agents
+}
+
 // Parameter represents a function parameter with its metadata
 type Parameter struct {
 	Name        string
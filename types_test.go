@@ -79,3 +79,43 @@ func TestResult(t *testing.T) {
 		t.Errorf("Expected context variable 'key' to be 'value', got %v", v)
 	}
 }
+
+func TestAgentClone(t *testing.T) {
+	fn := NewAgentFunction("lookup", "Looks things up", func(map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	}, []Parameter{{Name: "query", Type: reflect.TypeOf("")}})
+	agent := NewAgent("researcher").AddFunction(fn).WithFileSearch("vs_1").WithAudio(AudioOptions{Voice: "nova"}).
+		WithCompaction(DropOldestCompactor(), 1000)
+
+	clone := agent.Clone()
+	clone.Name = "copy"
+	clone.Functions[0].(*SimpleAgentFunction).NameString = "renamed"
+	clone.Functions = append(clone.Functions, fn)
+	clone.HostedTools[0].VectorStoreIDs[0] = "vs_2"
+	clone.Audio.Voice = "echo"
+	clone.Compaction.Threshold = 2000
+
+	AssertEqual(t, "researcher", agent.Name, "name")
+	AssertEqual(t, 1, len(agent.Functions), "functions")
+	AssertEqual(t, "lookup", agent.Functions[0].Name(), "function name")
+	AssertEqual(t, "vs_1", agent.HostedTools[0].VectorStoreIDs[0], "vector store")
+	AssertEqual(t, "nova", agent.Audio.Voice, "audio voice")
+	AssertEqual(t, 1000, agent.Compaction.Threshold, "compaction threshold")
+
+	result, err := clone.Functions[0].Call(nil)
+	AssertNoError(t, err, "cloned function call")
+	AssertEqual(t, "found", result, "cloned function result")
+}
+
+func TestAgentSpawn(t *testing.T) {
+	personas := []string{"security", "performance", "style"}
+	reviewers := NewAgent("reviewer").Spawn(len(personas), func(i int, a *Agent) {
+		a.WithInstructions("Review for " + personas[i-1])
+	})
+
+	AssertEqual(t, 3, len(reviewers), "spawn count")
+	AssertEqual(t, "reviewer-1", reviewers[0].Name, "first name")
+	AssertEqual(t, "reviewer-3", reviewers[2].Name, "last name")
+	AssertEqual(t, "Review for performance", reviewers[1].Instructions, "persona")
+	AssertEqual(t, 0, len(NewAgent("x").Spawn(0, nil)), "zero spawn")
+}
@@ -0,0 +1,147 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go"
+)
+
+// Usage is the token usage and cost of model requests.
+type Usage struct {
+	// Requests counts the chat completion requests
+	Requests int `json:"requests"`
+	// PromptTokens and CompletionTokens split TotalTokens by direction
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// Cost is the price of the tokens per the registered ModelPricing, plus
+	// any cost reported by Context.RecordUsage
+	Cost float64 `json:"cost"`
+}
+
+// Add adds other to u.
+func (u *Usage) Add(other Usage) {
+	u.Requests += other.Requests
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
+
+// newUsage returns the usage of one request to model.
+func newUsage(model string, usage openai.CompletionUsage) Usage {
+	u := Usage{
+		Requests:         1,
+		PromptTokens:     int(usage.PromptTokens),
+		CompletionTokens: int(usage.CompletionTokens),
+		TotalTokens:      int(usage.TotalTokens),
+	}
+	if pricing, ok := PricingForModel(model); ok {
+		u.Cost = (float64(u.PromptTokens)*pricing.Prompt + float64(u.CompletionTokens)*pricing.Completion) / 1e6
+	}
+	return u
+}
+
+// requestModel returns the model a request was made with: the requested one,
+// or the one the provider reported if the agent uses the client's default.
+func requestModel(agent *Agent, modelOverride, reported string) string {
+	if model := resolveModel(agent, modelOverride); model != "" {
+		return model
+	}
+	return reported
+}
+
+// UsageTracker accumulates the Usage of many requests, such as all the agent
+// runs of a workflow. It is safe for concurrent use.
+type UsageTracker struct {
+	usage Usage
+	mu    sync.Mutex
+}
+
+// NewUsageTracker creates an empty tracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{}
+}
+
+// Add adds usage to the totals.
+func (t *UsageTracker) Add(usage Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.Add(usage)
+}
+
+// Usage returns the totals so far.
+func (t *UsageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// usageTrackersContextKey carries the trackers receiving the usage of
+// requests.
+type usageTrackersContextKey struct{}
+
+// WithUsageTracker returns ctx carrying tracker, so the usage of every chat
+// completion request made by a Swarm with ctx, or a context derived from it,
+// is added to tracker. Trackers added to a context carrying others receive
+// the usage in addition, e.g. a task, its workflow run and an engine.
+func WithUsageTracker(ctx context.Context, tracker *UsageTracker) context.Context {
+	if tracker == nil {
+		return ctx
+	}
+	existing, _ := ctx.Value(usageTrackersContextKey{}).([]*UsageTracker)
+	trackers := make([]*UsageTracker, len(existing), len(existing)+1)
+	copy(trackers, existing)
+	return context.WithValue(ctx, usageTrackersContextKey{}, append(trackers, tracker))
+}
+
+// trackUsage adds usage to the trackers carried by ctx.
+func trackUsage(ctx context.Context, usage Usage) {
+	trackers, _ := ctx.Value(usageTrackersContextKey{}).([]*UsageTracker)
+	for _, tracker := range trackers {
+		tracker.Add(usage)
+	}
+}
+
+// ModelPricing is the price of a model's tokens in USD per million.
+type ModelPricing struct {
+	Prompt     float64 `yaml:"prompt" json:"prompt"`
+	Completion float64 `yaml:"completion" json:"completion"`
+}
+
+var (
+	// modelPricing maps lowercase model name prefixes to their pricing.
+	modelPricing   = map[string]ModelPricing{}
+	modelPricingMu sync.RWMutex
+)
+
+// RegisterModelPricing sets the pricing of models whose name starts with
+// modelPrefix (case-insensitive), used to compute the Cost of Usage. The
+// longest matching prefix wins.
+func RegisterModelPricing(modelPrefix string, pricing ModelPricing) {
+	modelPricingMu.Lock()
+	defer modelPricingMu.Unlock()
+	modelPricing[strings.ToLower(modelPrefix)] = pricing
+}
+
+// PricingForModel returns the registered pricing of model, ignoring
+// provider prefixes such as "azure/".
+func PricingForModel(model string) (ModelPricing, bool) {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	modelPricingMu.RLock()
+	defer modelPricingMu.RUnlock()
+	best, found := "", false
+	var pricing ModelPricing
+	for prefix, p := range modelPricing {
+		if strings.HasPrefix(name, prefix) && (!found || len(prefix) > len(best)) {
+			best, pricing, found = prefix, p, true
+		}
+	}
+	return pricing, found
+}
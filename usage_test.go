@@ -0,0 +1,116 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// usageClient replies "done" to every request, reporting 30 prompt and 10
+// completion tokens. It is safe for concurrent use.
+type usageClient struct {
+	*MockOpenAIClient
+}
+
+func (c *usageClient) CreateChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Content: "done", Role: "assistant"}},
+		},
+		Usage: openai.CompletionUsage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40},
+	}, nil
+}
+
+func TestUsageAcrossTurns(t *testing.T) {
+	RegisterModelPricing("usage-turns", ModelPricing{Prompt: 1000, Completion: 2000})
+	client := newToolCallClient("lookup", "{}", "found it")
+	client.CompletionResponse[0].Usage = openai.CompletionUsage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25}
+	client.CompletionResponse[1].Usage = openai.CompletionUsage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50}
+	agent := NewAgent("agent").WithModel("azure/usage-turns-mini").AddFunction(NewAgentFunction("lookup", "Look up",
+		func(args map[string]interface{}) (interface{}, error) {
+			return "result", nil
+		},
+		[]Parameter{},
+	))
+
+	tracker := NewUsageTracker()
+	response, err := NewSwarm(client).Run(WithUsageTracker(context.Background(), tracker), agent, []map[string]interface{}{
+		{"role": "user", "content": "look it up"},
+	}, nil, "", false, false, 5, true, false)
+	AssertNoError(t, err, "Run")
+
+	expected := Usage{Requests: 2, PromptTokens: 60, CompletionTokens: 15, TotalTokens: 75, Cost: 0.09}
+	AssertEqual(t, expected, response.Usage, "response usage")
+	AssertEqual(t, 75, response.TokensUsed, "tokens used")
+	AssertEqual(t, 0.09, response.Cost, "cost")
+	AssertEqual(t, expected, tracker.Usage(), "tracked usage")
+}
+
+func TestPricingForModel(t *testing.T) {
+	RegisterModelPricing("usage-prefix", ModelPricing{Prompt: 1})
+	RegisterModelPricing("usage-prefix-large", ModelPricing{Prompt: 2})
+
+	pricing, ok := PricingForModel("Usage-Prefix-Large-2")
+	AssertEqual(t, true, ok, "registered model")
+	AssertEqual(t, 2.0, pricing.Prompt, "longest prefix wins")
+	pricing, ok = PricingForModel("openai/usage-prefix-small")
+	AssertEqual(t, true, ok, "provider prefix ignored")
+	AssertEqual(t, 1.0, pricing.Prompt, "shorter prefix")
+	_, ok = PricingForModel("unpriced")
+	AssertEqual(t, false, ok, "unregistered model")
+}
+
+func TestWorkflowUsage(t *testing.T) {
+	swarm := NewSwarm(&usageClient{NewMockOpenAIClient()})
+	agent := NewAgent("agent")
+	ask := func(ctx *Context) error {
+		_, err := swarm.Run(ctx.Context(), agent, []map[string]interface{}{
+			{"role": "user", "content": "hi"},
+		}, nil, "", false, false, 1, true, false)
+		return err
+	}
+
+	var live Usage
+	workflow := NewWorkflow("usage")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		if err := ask(ctx); err != nil {
+			return nil, err
+		}
+		var tasks []Task
+		for i := 0; i < 3; i++ {
+			tasks = append(tasks, Task{ID: fmt.Sprintf("task-%d", i), Type: EventType("Ask"), Timeout: time.Second})
+		}
+		return NewParallelEvent(tasks, "start")
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("ask", EventType("Ask"), func(ctx *Context, event Event) (Event, error) {
+		if err := ask(ctx); err != nil {
+			return nil, err
+		}
+		return NewBaseEvent(EventType("Answered"), nil), nil
+	}, StepConfig{}))
+	var handler *WorkflowHandler
+	ready := make(chan struct{})
+	workflow.AddStep(NewStep("collect", EventParallelResult, func(ctx *Context, event Event) (Event, error) {
+		<-ready
+		live = handler.Usage()
+		result := event.(*ParallelResultEvent)
+		AssertEqual(t, Usage{Requests: 1, PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40}, result.Tasks["task-1"].Usage, "task usage")
+		AssertEqual(t, 120, result.Usage.TotalTokens, "parallel usage")
+		ctx.RecordUsage(5, 0.5)
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	close(ready)
+	result, err := handler.Wait()
+	AssertNoError(t, err, "wait")
+	AssertEqual(t, Usage{Requests: 4, PromptTokens: 120, CompletionTokens: 40, TotalTokens: 160}, live, "live usage")
+	AssertEqual(t, Usage{Requests: 4, PromptTokens: 120, CompletionTokens: 40, TotalTokens: 165, Cost: 0.5}, result.Usage, "run usage")
+	AssertEqual(t, 165, result.TokensUsed, "tokens")
+	AssertEqual(t, 0.5, result.Cost, "cost")
+	AssertEqual(t, result.Usage, handler.Usage(), "final live usage")
+}
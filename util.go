@@ -1,11 +1,8 @@
 package swarm
 
 import (
-	"bufio"
-	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"reflect"
 	"strings"
@@ -21,11 +18,14 @@ const (
 
 // StreamResponse represents a streaming response chunk from an AI agent.
 type StreamResponse struct {
-	Content   string     `json:"content,omitempty"`    // The text content of the response
-	Sender    string     `json:"sender,omitempty"`     // The identity of the sender
-	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Any function calls made by the agent
-	Delim     string     `json:"delim,omitempty"`      // Delimiter for streaming chunks
-	Response  *Response  `json:"response,omitempty"`   // Complete response object if present
+	Content    string       `json:"content,omitempty"`     // The text content of the response
+	Sender     string       `json:"sender,omitempty"`      // The identity of the sender
+	ToolCalls  []ToolCall   `json:"tool_calls,omitempty"`  // Any function calls made by the agent
+	Delim      string       `json:"delim,omitempty"`       // Delimiter for streaming chunks
+	ToolResult *ToolResult  `json:"tool_result,omitempty"` // Outcome of an executed tool call
+	Chunk      *StreamChunk `json:"chunk,omitempty"`       // Incremental model output
+	Error      string       `json:"error,omitempty"`       // Error that ended the run
+	Response   *Response    `json:"response,omitempty"`    // Complete response object if present
 }
 
 // ToolCall represents a call to a specific tool or function by an AI agent.
@@ -185,13 +185,14 @@ func processAndPrintStreamingResponse(responseChan <-chan map[string]interface{}
 			lastSender = resp.Sender
 		}
 
-		if resp.Content != "" {
+		// Print the reply as it streams in
+		if chunk := resp.Chunk; chunk != nil && chunk.Type == StreamChunkContent {
 			if content == "" && lastSender != "" {
 				fmt.Printf("%s%s:%s ", colorBlue, lastSender, colorReset)
 				lastSender = ""
 			}
-			fmt.Print(resp.Content)
-			content += resp.Content
+			fmt.Print(chunk.Delta)
+			content += chunk.Delta
 		}
 
 		if len(resp.ToolCalls) > 0 {
@@ -205,11 +206,23 @@ func processAndPrintStreamingResponse(responseChan <-chan map[string]interface{}
 			}
 		}
 
+		if result := resp.ToolResult; result != nil {
+			output := result.Result
+			if result.Error != "" {
+				output = result.Error
+			}
+			fmt.Printf("%s%s returned:%s %s (%s)\n", colorPurple, result.Name, colorReset, output, result.Duration.Round(time.Millisecond))
+		}
+
 		if resp.Delim == "end" && content != "" {
 			fmt.Println()
 			content = ""
 		}
 
+		if resp.Error != "" {
+			fmt.Printf("Error in run: %s\n", resp.Error)
+		}
+
 		if resp.Response != nil {
 			return resp.Response
 		}
@@ -341,72 +354,15 @@ func formatArgs(args map[string]interface{}) string {
 
 // RunDemoLoop starts an interactive CLI session for testing and demonstrating
 // agent capabilities. It provides a REPL-like interface for communicating with
-// AI agents and visualizing their responses and tool calls.
-//
-// Parameters:
-//   - startingAgent: initial agent configuration
-//   - contextVariables: map of context variables for the session
-//   - stream: enable streaming mode for responses
-//   - debug: enable debug output
-func RunDemoLoop(startingAgent *Agent, contextVariables map[string]interface{}, stream bool, debug bool) {
-	fmt.Println("Starting Swarm CLI 🐝")
-
-	client, err := NewDefaultSwarm()
-	if err != nil {
-		fmt.Printf("Error creating Swarm client: %v\n", err)
-		return
-	}
-
-	messages := make([]map[string]interface{}, 0)
-	agent := startingAgent
-
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Printf("%sUser%s: ", colorGray, colorReset)
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				fmt.Println("Exiting Swarm CLI 🐝")
-				return
-			}
-
-			fmt.Printf("Error reading input: %v\n", err)
-			continue
-		}
-
-		input = strings.TrimSpace(input)
-		if input == "" {
-			continue
-		}
-
-		messages = append(messages, map[string]interface{}{
-			"role":    "user",
-			"content": input,
-		})
-
-		ctx := context.Background()
-		if stream {
-			responseChan, err := client.RunAndStream(ctx, agent, messages, contextVariables, "gpt-4o", debug, 10, true, false)
-			if err != nil {
-				fmt.Printf("Error in stream: %v\n", err)
-				continue
-			}
-
-			response := processAndPrintStreamingResponse(responseChan)
-			if response != nil {
-				messages = append(messages, response.Messages...)
-				agent = response.Agent
-			}
-		} else {
-			response, err := client.Run(ctx, agent, messages, contextVariables, "gpt-4o", false, debug, 10, true, false)
-			if err != nil {
-				fmt.Printf("Error in run: %v\n", err)
-				continue
-			}
-
-			prettyPrintMessages(response.Messages)
-			messages = append(messages, response.Messages...)
-			agent = response.Agent
-		}
+// AI agents and visualizing their responses and tool calls. See RunRepl for
+// the commands and input syntax and the options; unless options.Transcript
+// is set, RunDemoLoop keeps the conversation across restarts in the file
+// named by the SWARM_TRANSCRIPT environment variable, if set. With
+// options.Prompt or piped input, it answers non-interactively. It returns
+// RunRepl's exit code, for the caller to exit with.
+func RunDemoLoop(startingAgent *Agent, options ReplOptions) int {
+	if options.Transcript == "" {
+		options.Transcript = os.Getenv(TranscriptEnv)
 	}
+	return RunRepl(startingAgent, options)
 }
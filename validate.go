@@ -0,0 +1,81 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidWorkflow is returned by Workflow.Validate for workflows that
+// cannot run to completion.
+var ErrInvalidWorkflow = errors.New("invalid workflow")
+
+// Validate checks the event graph of the workflow before it runs, instead of
+// letting a run wait forever for events nobody handles:
+//   - a step handles EventStart
+//   - every event type declared in StepConfig.Produces has a handler, and
+//     steps producing EventParallel have an EventParallelResult consumer
+//   - EventStop can be reached from EventStart
+//
+// Steps that do not declare the events they produce may emit anything, so
+// the last check only fails when every reachable step declares its events.
+// All problems are reported, joined, and each wraps ErrInvalidWorkflow.
+func (w *Workflow) Validate() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var errs []error
+	if len(w.stepMap[string(EventStart)]) == 0 {
+		errs = append(errs, fmt.Errorf("%w: no step handles %s", ErrInvalidWorkflow, EventStart))
+	}
+	for _, step := range w.steps {
+		for _, produced := range step.Config().Produces {
+			switch produced {
+			case EventStop, EventError, EventProgress, EventDelay, EventInputRequired:
+				// Handled by the engine or the caller
+			case EventParallel:
+				if len(w.stepMap[string(EventParallelResult)]) == 0 {
+					errs = append(errs, fmt.Errorf("%w: step %s produces %s, but no step handles %s", ErrInvalidWorkflow, step.Name(), produced, EventParallelResult))
+				}
+			default:
+				if len(w.stepMap[string(produced)]) == 0 {
+					errs = append(errs, fmt.Errorf("%w: step %s produces %s, which no step handles", ErrInvalidWorkflow, step.Name(), produced))
+				}
+			}
+		}
+	}
+	if len(errs) == 0 && !w.canStop() {
+		errs = append(errs, fmt.Errorf("%w: no step reachable from %s produces %s", ErrInvalidWorkflow, EventStart, EventStop))
+	}
+	return errors.Join(errs...)
+}
+
+// canStop reports whether a step reachable from EventStart may produce
+// EventStop. The caller must hold w.mu.
+func (w *Workflow) canStop() bool {
+	visited := map[EventType]bool{EventStart: true}
+	queue := []EventType{EventStart}
+	for len(queue) > 0 {
+		eventType := queue[0]
+		queue = queue[1:]
+		for _, step := range w.stepMap[string(eventType)] {
+			produces := step.Config().Produces
+			if len(produces) == 0 {
+				return true
+			}
+			for _, produced := range produces {
+				switch produced {
+				case EventStop, EventDelay:
+					// Delayed events are not declared, so they may stop
+					return true
+				case EventParallel:
+					produced = EventParallelResult
+				}
+				if !visited[produced] {
+					visited[produced] = true
+					queue = append(queue, produced)
+				}
+			}
+		}
+	}
+	return false
+}
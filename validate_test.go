@@ -0,0 +1,71 @@
+package swarm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	noop := func(*Context, Event) (Event, error) { return nil, nil }
+	newWorkflow := func(steps ...Step) *Workflow {
+		workflow := NewWorkflow("validate")
+		for _, step := range steps {
+			AssertNoError(t, workflow.AddStep(step), "AddStep")
+		}
+		return workflow
+	}
+	produces := func(types ...EventType) StepConfig {
+		return StepConfig{Produces: types}
+	}
+
+	for name, tc := range map[string]struct {
+		workflow *Workflow
+		problem  string
+	}{
+		"valid": {workflow: newWorkflow(
+			NewStep("start", EventStart, noop, produces(EventParallel)),
+			NewStep("reduce", EventParallelResult, noop, produces("Reduced")),
+			NewStep("finish", "Reduced", noop, produces(EventStop)),
+		)},
+		"undeclared steps may stop": {workflow: newWorkflow(
+			NewStep("start", EventStart, noop, produces("Next")),
+			NewStep("next", "Next", noop, StepConfig{}),
+		)},
+		"typed steps declare their output": {workflow: newWorkflow(
+			NewTypedStep("start", EventStart, EventStop, func(*Context, map[string]interface{}) (string, error) { return "", nil }, StepConfig{}),
+		)},
+		"no start step": {
+			workflow: newWorkflow(NewStep("next", "Next", noop, StepConfig{})),
+			problem:  "no step handles StartEvent",
+		},
+		"unhandled event": {
+			workflow: newWorkflow(NewStep("start", EventStart, noop, produces("Next", EventStop))),
+			problem:  "step start produces Next, which no step handles",
+		},
+		"parallel without reducer": {
+			workflow: newWorkflow(NewStep("start", EventStart, noop, produces(EventParallel, EventStop))),
+			problem:  "no step handles ParallelResultEvent",
+		},
+		"no path to stop": {
+			workflow: newWorkflow(
+				NewStep("start", EventStart, noop, produces("Ping")),
+				NewStep("ping", "Ping", noop, produces("Pong")),
+				NewStep("pong", "Pong", noop, produces("Ping")),
+				NewStep("finish", "Unreachable", noop, produces(EventStop)),
+			),
+			problem: "no step reachable from StartEvent produces StopEvent",
+		},
+	} {
+		err := tc.workflow.Validate()
+		if tc.problem == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidWorkflow) || !strings.Contains(err.Error(), tc.problem) {
+			t.Errorf("%s: expected %q, got %v", name, tc.problem, err)
+		}
+	}
+}
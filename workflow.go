@@ -2,12 +2,14 @@ package swarm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -23,10 +25,13 @@ import (
 // Steps are mapped to events based on their EventType, allowing multiple
 // steps to handle the same event type in parallel.
 type Workflow struct {
-	config  WorkflowConfig
-	steps   []Step
-	stepMap map[string][]Step
-	mu      sync.RWMutex
+	config       WorkflowConfig
+	steps        []Step
+	stepMap      map[string][]Step
+	interceptors []EventInterceptor
+	active       map[*WorkflowHandler]struct{}
+	shutdown     bool
+	mu           sync.RWMutex
 }
 
 // WorkflowConfig holds workflow-level configuration settings.
@@ -36,8 +41,42 @@ type WorkflowConfig struct {
 	Verbose    bool          `yaml:"verbose" json:"verbose"`
 	Timeout    time.Duration `yaml:"timeout" json:"timeout"`
 	MaxRetries int           `yaml:"max_retries" json:"max_retries"`
+	// EventBuffer is the engine event channel buffer size (default 100)
+	EventBuffer int `yaml:"event_buffer" json:"event_buffer"`
+	// StreamBuffer is the buffer size of stream subscriptions (default 100)
+	StreamBuffer int `yaml:"stream_buffer" json:"stream_buffer"`
+	// OverflowPolicy controls stream subscribers with full buffers (default block)
+	OverflowPolicy OverflowPolicy `yaml:"overflow_policy" json:"overflow_policy"`
+	// MaxParallelTasks limits the concurrent tasks of a ParallelEvent that
+	// does not set its own limit (default 10)
+	MaxParallelTasks int `yaml:"max_parallel_tasks" json:"max_parallel_tasks"`
+	// StallTimeout enables stall detection: a run with no running steps that
+	// receives no event for this long is stalled (default disabled)
+	StallTimeout time.Duration `yaml:"stall_timeout" json:"stall_timeout"`
+	// StallPolicy controls stalled runs (default fail)
+	StallPolicy StallPolicy `yaml:"stall_policy" json:"stall_policy"`
+	// LLMRateLimit limits the LLM requests of all steps and parallel tasks
+	// of each run that pass their Context's context.Context to Swarm.Run
+	LLMRateLimit *LLMRateLimit `yaml:"llm_rate_limit,omitempty" json:"llm_rate_limit,omitempty"`
+	// Scheduler optionally defers low-priority parallel tasks to off-peak windows
+	Scheduler *Scheduler `yaml:"-" json:"-"`
+	// Cache stores results of steps that set StepConfig.CacheTTL
+	Cache StepCache `yaml:"-" json:"-"`
+	// TaskStore records completed tasks that carry an idempotency key, by
+	// run ID, so a restored run skips them. Results that are neither a
+	// BaseEvent nor of a registered event type are not recorded.
+	TaskStore StepCache `yaml:"-" json:"-"`
+	// TaskStoreTTL is how long completed tasks are recorded (default forever)
+	TaskStoreTTL time.Duration `yaml:"task_store_ttl" json:"task_store_ttl"`
+	// AuditLogger records every step start, retry and finish
+	AuditLogger AuditLogger `yaml:"-" json:"-"`
+	// TracerProvider optionally enables OpenTelemetry spans for runs and steps
+	TracerProvider trace.TracerProvider `yaml:"-" json:"-"`
 }
 
+// DefaultMaxParallelTasks is the default limit of concurrent parallel tasks.
+const DefaultMaxParallelTasks = 10
+
 // NewWorkflow creates a new workflow instance with the given name.
 func NewWorkflow(name string) *Workflow {
 	config := DefaultConfig()
@@ -46,6 +85,7 @@ func NewWorkflow(name string) *Workflow {
 	return &Workflow{
 		config:  config,
 		stepMap: make(map[string][]Step),
+		active:  make(map[*WorkflowHandler]struct{}),
 	}
 }
 
@@ -64,6 +104,15 @@ func (w *Workflow) WithConfig(config WorkflowConfig) *Workflow {
 	return w
 }
 
+// Use registers event interceptors that are installed on the Context of every
+// run, so they also observe the initial StartEvent.
+func (w *Workflow) Use(interceptors ...EventInterceptor) *Workflow {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.interceptors = append(w.interceptors, interceptors...)
+	return w
+}
+
 // AddStep adds a step to the workflow. Returns an error if the step is invalid.
 func (w *Workflow) AddStep(step Step) error {
 	if err := w.validateStep(step); err != nil {
@@ -97,19 +146,34 @@ func (w *Workflow) validateStep(step Step) error {
 	if config.Timeout < 0 {
 		return fmt.Errorf("timeout must be non-negative")
 	}
-	if config.RetryPolicy != nil {
-		if config.RetryPolicy.MaxRetries < 0 {
-			return fmt.Errorf("max retries must be non-negative")
-		}
-		if config.RetryPolicy.InitialInterval <= 0 {
-			return fmt.Errorf("initial interval must be positive")
-		}
-		if config.RetryPolicy.MaxInterval < config.RetryPolicy.InitialInterval {
-			return fmt.Errorf("max interval must be greater than or equal to initial interval")
-		}
-		if config.RetryPolicy.Multiplier <= 0 {
-			return fmt.Errorf("multiplier must be positive")
+	switch config.OnError {
+	case "", OnErrorFail, OnErrorContinue:
+	case OnErrorCompensate:
+		if config.Compensate == nil {
+			return fmt.Errorf("on error %q requires a Compensate handler", config.OnError)
 		}
+	default:
+		return fmt.Errorf("unknown on error policy %q", config.OnError)
+	}
+	if config.RetryPolicy != nil {
+		return config.RetryPolicy.validate()
+	}
+	return nil
+}
+
+// validate validates the retry policy
+func (p *RetryPolicy) validate() error {
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("max retries must be non-negative")
+	}
+	if p.InitialInterval <= 0 {
+		return fmt.Errorf("initial interval must be positive")
+	}
+	if p.MaxInterval < p.InitialInterval {
+		return fmt.Errorf("max interval must be greater than or equal to initial interval")
+	}
+	if p.Multiplier <= 0 {
+		return fmt.Errorf("multiplier must be positive")
 	}
 	return nil
 }
@@ -123,6 +187,30 @@ func (p *RetryPolicy) calculateBackoff(attempt int) time.Duration {
 	return interval
 }
 
+// retryDelay returns how long to wait before retrying after err: the backoff
+// for the attempt, or the provider's requested wait if err is a rate limit
+// asking for longer.
+func (p *RetryPolicy) retryDelay(attempt int, err error) time.Duration {
+	backoff := p.calculateBackoff(attempt)
+	if wait, ok := RetryAfter(err); ok && wait > backoff {
+		return wait
+	}
+	return backoff
+}
+
+// wait waits for the delay before the retry following attempt, returning the
+// context error if ctx is done first.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int, err error) error {
+	timer := time.NewTimer(p.retryDelay(attempt, err))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // shouldRetry determines if an error should be retried
 func (p *RetryPolicy) shouldRetry(err error) bool {
 	if len(p.Errors) == 0 {
@@ -147,6 +235,11 @@ func (w *Workflow) Initialize() error {
 	if w.config.MaxRetries == 0 {
 		w.config.MaxRetries = 3
 	}
+	if w.config.LLMRateLimit != nil {
+		if err := w.config.LLMRateLimit.validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -161,18 +254,28 @@ func (w *Workflow) executeStep(wfCtx *Context, step Step, event Event, sem *sema
 	// Acquire semaphore if rate limiting is enabled
 	if sem != nil {
 		if err := sem.Acquire(stepCtx, 1); err != nil {
-			wfCtx.SendEvent(NewErrorEvent(fmt.Errorf("failed to acquire semaphore: %w", err)))
+			errEvent := NewErrorEvent(fmt.Errorf("failed to acquire semaphore: %w", err)).WithStep(step.Name())
+			setCausation(errEvent, event)
+			wfCtx.SendEvent(errEvent)
 			return
 		}
 		defer sem.Release(1)
 	}
 
+	// Reuse a cached result from a previous run
+	cached, cacheKey, ok := w.loadCachedResult(step, event)
+	if ok {
+		setCausation(cached, event)
+		wfCtx.SendEvent(cached)
+		return
+	}
+
 	// Execute step with retries
 	var result Event
 	var lastErr error
 	retryPolicy := config.RetryPolicy
 	for i := 0; i < retryPolicy.MaxRetries; i++ {
-		result, lastErr = step.Handle(wfCtx, event)
+		result, lastErr = w.handleStep(wfCtx, step, event, "", i+1)
 		if lastErr == nil {
 			break
 		}
@@ -180,8 +283,11 @@ func (w *Workflow) executeStep(wfCtx *Context, step Step, event Event, sem *sema
 			fmt.Printf("Step %s failed (attempt %d/%d): %v\n", step.Name(), i+1, retryPolicy.MaxRetries, lastErr)
 		}
 		if i < retryPolicy.MaxRetries-1 && retryPolicy.shouldRetry(lastErr) {
-			backoff := retryPolicy.calculateBackoff(i)
-			time.Sleep(backoff)
+			w.auditRetry(wfCtx, step, event, "", i+2, lastErr)
+			if err := retryPolicy.wait(wfCtx.Context(), i, lastErr); err != nil {
+				lastErr = err
+				break
+			}
 		} else {
 			break
 		}
@@ -191,15 +297,113 @@ func (w *Workflow) executeStep(wfCtx *Context, step Step, event Event, sem *sema
 		if w.config.Verbose {
 			fmt.Printf("Step %s failed after %d retries: %v\n", step.Name(), retryPolicy.MaxRetries, lastErr)
 		}
-		wfCtx.SendEvent(NewErrorEvent(lastErr))
-		return
+		result, lastErr = w.handleStepFailure(wfCtx, step, event, lastErr)
+		if lastErr != nil {
+			errEvent := NewErrorEvent(lastErr).WithStep(step.Name())
+			if step.EventType() == EventError {
+				// Failures of error handlers are final
+				errEvent.handled = true
+			}
+			setCausation(errEvent, event)
+			wfCtx.SendEvent(errEvent)
+			return
+		}
 	}
 
 	if result != nil {
+		w.storeCachedResult(step, cacheKey, result)
+		setCausation(result, event)
 		wfCtx.SendEvent(result)
 	}
 }
 
+// handleStep runs a single attempt of a step, recording it in the audit log
+// and the run's step traces.
+func (w *Workflow) handleStep(ctx *Context, step Step, event Event, taskID string, attempt int) (Event, error) {
+	record := AuditRecord{
+		RunID:     ctx.RunID(),
+		Step:      step.Name(),
+		TaskID:    taskID,
+		EventType: event.Type(),
+		Attempt:   attempt,
+	}
+	record.Action = AuditStepStart
+	audit(w.config.AuditLogger, record, w.config.Verbose)
+
+	_, span := tracerFrom(w.config.TracerProvider).Start(ctx.Context(), "workflow.step", trace.WithAttributes(
+		attrStep.String(step.Name()),
+		attrTask.String(taskID),
+		attrEventType.String(string(event.Type())),
+		attrAttempt.Int(attempt),
+		attrRunID.String(ctx.RunID()),
+	))
+	start := time.Now()
+	result, err := step.Handle(ctx, event)
+	endSpan(span, err)
+	record.Action = AuditStepFinish
+	record.Duration = time.Since(start)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	audit(w.config.AuditLogger, record, w.config.Verbose)
+
+	trace := StepTrace{
+		Step:      step.Name(),
+		TaskID:    taskID,
+		EventType: event.Type(),
+		Attempt:   attempt,
+		StartedAt: start,
+		Duration:  record.Duration,
+		Error:     record.Error,
+	}
+	if carrier, ok := event.(MetadataCarrier); ok {
+		trace.EventID = carrier.Metadata().EventID
+	}
+	if result != nil {
+		trace.Output = result.Type()
+	}
+	ctx.recorder.addStep(trace)
+	return result, err
+}
+
+// auditRetry records that a failed step is about to be retried.
+func (w *Workflow) auditRetry(ctx *Context, step Step, event Event, taskID string, attempt int, err error) {
+	audit(w.config.AuditLogger, AuditRecord{
+		Action:    AuditStepRetry,
+		RunID:     ctx.RunID(),
+		Step:      step.Name(),
+		TaskID:    taskID,
+		EventType: event.Type(),
+		Attempt:   attempt,
+		Error:     err.Error(),
+	}, w.config.Verbose)
+}
+
+// handleStepFailure applies the step's OnError policy to a failed step.
+// It returns the event to dispatch instead (possibly nil), or the error that
+// should fail the workflow.
+func (w *Workflow) handleStepFailure(wfCtx *Context, step Step, event Event, err error) (Event, error) {
+	config := step.Config()
+	switch config.OnError {
+	case OnErrorContinue:
+		if w.config.Verbose {
+			fmt.Printf("Step %s failed, continuing: %v\n", step.Name(), err)
+		}
+		return nil, nil
+	case OnErrorCompensate:
+		if config.Compensate == nil {
+			return nil, err
+		}
+		result, cerr := config.Compensate(wfCtx, event, err)
+		if cerr != nil {
+			return nil, fmt.Errorf("compensation for step %s failed: %w (original error: %v)", step.Name(), cerr, err)
+		}
+		return result, nil
+	default:
+		return nil, err
+	}
+}
+
 // WorkflowStatus represents the current state of a workflow execution.
 type WorkflowStatus string
 
@@ -214,37 +418,114 @@ const (
 	WorkflowStatusFailed WorkflowStatus = "failed"
 	// WorkflowStatusCancelled indicates the workflow has been cancelled
 	WorkflowStatusCancelled WorkflowStatus = "cancelled"
+	// WorkflowStatusPaused indicates the workflow holds its events until resumed
+	WorkflowStatusPaused WorkflowStatus = "paused"
 )
 
+// ErrWorkflowNotRunning is returned when pausing a workflow that is not
+// running, or resuming one that is not paused.
+var ErrWorkflowNotRunning = errors.New("workflow is not running")
+
 // WorkflowHandler manages workflow execution and provides status updates.
 type WorkflowHandler struct {
 	ctx      *Context
 	result   interface{}
+	event    Event
 	err      error
 	doneChan chan struct{}
 	errChan  chan error
 	status   WorkflowStatus
+	resume   chan struct{}
 	statusM  sync.RWMutex
+
+	shutdown      chan struct{}
+	shutdownOnce  sync.Once
+	checkpointed  chan struct{}
+	checkpoint    *WorkflowCheckpoint
+	checkpointErr error
 }
 
 // NewWorkflowHandler creates a new workflow handler
 func NewWorkflowHandler(ctx *Context) *WorkflowHandler {
 	return &WorkflowHandler{
-		ctx:      ctx,
-		doneChan: make(chan struct{}),
-		errChan:  make(chan error, 1),
-		status:   WorkflowStatusPending,
+		ctx:          ctx,
+		doneChan:     make(chan struct{}),
+		errChan:      make(chan error, 1),
+		status:       WorkflowStatusPending,
+		shutdown:     make(chan struct{}),
+		checkpointed: make(chan struct{}),
 	}
 }
 
-// Wait blocks until the workflow completes and returns the result or error.
-func (h *WorkflowHandler) Wait() (interface{}, error) {
+// Wait blocks until the workflow completes and returns its result, with the
+// error that failed the run, if any. The result is never nil; for failed runs
+// it reports the steps executed so far.
+func (h *WorkflowHandler) Wait() (*WorkflowResult, error) {
 	select {
 	case <-h.doneChan:
-		return h.result, h.err
-	case err := <-h.errChan:
+		return h.workflowResult(), h.err
+	case err, ok := <-h.errChan:
+		if !ok {
+			// errChan is closed right after doneChan
+			return h.workflowResult(), h.err
+		}
+		return h.workflowResult(), err
+	}
+}
+
+// WaitValue blocks until the workflow completes and returns the raw result of
+// its StopEvent, or the error that failed the run.
+func (h *WorkflowHandler) WaitValue() (interface{}, error) {
+	result, err := h.Wait()
+	if err != nil {
 		return nil, err
 	}
+	return result.Value, nil
+}
+
+// workflowResult returns the result of the run so far.
+func (h *WorkflowHandler) workflowResult() *WorkflowResult {
+	result := &WorkflowResult{
+		RunID:  h.ctx.RunID(),
+		Value:  h.result,
+		Status: h.Status(),
+		Event:  h.event,
+	}
+	h.ctx.recorder.fill(result)
+	return result
+}
+
+// fail ends the run with status and err.
+func (h *WorkflowHandler) fail(status WorkflowStatus, err error) {
+	h.err = err
+	h.ctx.recorder.finish()
+	h.setStatus(status)
+	h.errChan <- err
+}
+
+// WaitAs blocks until the workflow completes and returns its result as T.
+// Results of type T are returned directly; other results (for example maps
+// produced by NewStopEvent) are decoded into T via JSON. A result that cannot be
+// decoded returns an error describing both types.
+func WaitAs[T any](h *WorkflowHandler) (T, error) {
+	var zero T
+	result, err := h.WaitValue()
+	if err != nil {
+		return zero, err
+	}
+	if typed, ok := result.(T); ok {
+		return typed, nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return zero, fmt.Errorf("workflow result of type %T cannot be converted to %T: %w", result, zero, err)
+	}
+	var typed T
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return zero, fmt.Errorf("workflow result of type %T cannot be converted to %T: %w", result, zero, err)
+	}
+	return typed, nil
 }
 
 // Context returns the workflow context.
@@ -253,10 +534,46 @@ func (h *WorkflowHandler) Context() *Context {
 }
 
 // Stream returns a channel for receiving workflow events.
+// Every call returns the same shared channel; use Subscribe when several
+// consumers need to observe the workflow independently.
 func (h *WorkflowHandler) Stream() <-chan Event {
 	return h.ctx.Stream()
 }
 
+// Subscribe returns a new independent subscription to workflow events.
+// Each subscription has its own buffer, so consumers never steal events from
+// each other. Optional event type patterns restrict delivery (see
+// MatchEventTypes). The subscription's channel is closed when the workflow
+// finishes or Unsubscribe is called.
+func (h *WorkflowHandler) Subscribe(types ...EventType) *Subscription {
+	return h.ctx.Subscribe(types...)
+}
+
+// StreamFiltered returns a new channel that only receives workflow events whose
+// type matches one of the given patterns (see MatchEventTypes). The channel is
+// independent of Stream and is closed when the workflow finishes.
+func (h *WorkflowHandler) StreamFiltered(types ...EventType) <-chan Event {
+	return h.ctx.Subscribe(types...).Events()
+}
+
+// StreamFunc returns a new channel that only receives workflow events accepted
+// by filter. A nil filter accepts every event.
+func (h *WorkflowHandler) StreamFunc(filter EventFilter) <-chan Event {
+	return h.ctx.Bus().SubscribeFunc(filter, h.ctx.Bus().bufferSize).Events()
+}
+
+// TaskConcurrency returns the number of parallel tasks running now, the peak
+// and the current limit.
+func (h *WorkflowHandler) TaskConcurrency() TaskConcurrency {
+	return h.ctx.recorder.taskConcurrency()
+}
+
+// Usage returns the usage of the run so far, updated live as agent runs and
+// parallel tasks finish their requests.
+func (h *WorkflowHandler) Usage() Usage {
+	return h.ctx.recorder.usage.Usage()
+}
+
 // Cancel stops workflow execution.
 func (h *WorkflowHandler) Cancel() {
 	h.ctx.Cancel()
@@ -276,17 +593,107 @@ func (h *WorkflowHandler) setStatus(status WorkflowStatus) {
 	h.status = status
 }
 
-// executeParallelTasks executes multiple tasks in parallel with rate limiting
-func (w *Workflow) executeParallelTasks(wfCtx *Context, event *ParallelEvent, sem *semaphore.Weighted) {
+// Pause stops dispatching events to steps until Resume is called. Steps that
+// are already executing run to completion, and the events they send are held
+// in the event queue. Pausing a workflow that is not running returns
+// ErrWorkflowNotRunning.
+func (h *WorkflowHandler) Pause() error {
+	h.statusM.Lock()
+	defer h.statusM.Unlock()
+	if h.status != WorkflowStatusRunning {
+		return fmt.Errorf("%w: %s", ErrWorkflowNotRunning, h.status)
+	}
+	h.status = WorkflowStatusPaused
+	h.resume = make(chan struct{})
+	return nil
+}
+
+// Resume continues dispatching the events of a paused workflow. Resuming a
+// workflow that is not paused returns ErrWorkflowNotRunning.
+func (h *WorkflowHandler) Resume() error {
+	h.statusM.Lock()
+	defer h.statusM.Unlock()
+	if h.status != WorkflowStatusPaused {
+		return fmt.Errorf("%w: %s", ErrWorkflowNotRunning, h.status)
+	}
+	h.status = WorkflowStatusRunning
+	close(h.resume)
+	h.resume = nil
+	return nil
+}
+
+// paused returns a channel closed on Resume while the workflow is paused,
+// or nil.
+func (h *WorkflowHandler) paused() <-chan struct{} {
+	h.statusM.RLock()
+	defer h.statusM.RUnlock()
+	return h.resume
+}
+
+// ErrParallelTasksFailed is returned when the failed tasks of a ParallelEvent
+// with FailFast or MinSuccessRate fail the workflow.
+var ErrParallelTasksFailed = errors.New("parallel tasks failed")
+
+// executeParallelTasks executes multiple tasks in parallel. At most
+// ParallelEvent.MaxParallel (or WorkflowConfig.MaxParallelTasks) tasks run at
+// once, and at most the largest StepConfig.MaxParallel of their steps of each
+// task type.
+func (w *Workflow) executeParallelTasks(wfCtx *Context, event *ParallelEvent) {
 	start := time.Now()
 	results := make(map[string]interface{})
 	errors := make(map[string]error)
+	tasks := make(map[string]TaskMetadata)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(wfCtx.Context(), w.config.Timeout)
+	// Tasks stop with the run, or on the first failure of a FailFast event,
+	// and must finish within the workflow timeout
+	runCtx, cancel := context.WithCancel(wfCtx.Context())
 	defer cancel()
+	batchCtx, cancelBatch := context.WithTimeout(runCtx, w.config.Timeout)
+	defer cancelBatch()
+
+	// Limit the concurrent tasks, overall and of each type
+	limit := w.config.MaxParallelTasks
+	if event.MaxParallel > 0 {
+		limit = event.MaxParallel
+	}
+	if limit <= 0 {
+		limit = DefaultMaxParallelTasks
+	}
+	sem := semaphore.NewWeighted(int64(limit))
+	typeSems := make(map[EventType]*semaphore.Weighted)
+	w.mu.RLock()
+	for _, task := range event.Tasks {
+		maxParallel := int64(0)
+		for _, step := range w.stepMap[string(task.Type)] {
+			maxParallel = max(maxParallel, step.Config().MaxParallel)
+		}
+		if maxParallel > 0 {
+			typeSems[task.Type] = semaphore.NewWeighted(maxParallel)
+		}
+	}
+	w.mu.RUnlock()
+	wfCtx.recorder.setTaskLimit(limit)
+
+	// fail records a failed task. The first failure of a FailFast event
+	// cancels the remaining tasks, recorded as cancelled.
+	var firstFailure string
+	fail := func(t *Task, status TaskStatus, err error, result *ErrorEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstFailure != "" {
+			status = TaskStatusCancelled
+			err = fmt.Errorf("task cancelled after task %s failed: %w", firstFailure, err)
+			result = NewErrorEvent(err).WithTask(t.ID)
+		} else if event.FailFast {
+			firstFailure = t.ID
+			cancel()
+		}
+		t.Status, t.Error = status, err
+		errors[t.ID] = err
+		results[t.ID] = result
+	}
 
 	// Process each task
 	for _, task := range event.Tasks {
@@ -294,25 +701,69 @@ func (w *Workflow) executeParallelTasks(wfCtx *Context, event *ParallelEvent, se
 		go func(t Task) {
 			defer wg.Done()
 
+			// Record the outcome of the task
+			meta := TaskMetadata{Type: t.Type, Priority: t.Priority}
+			usage := NewUsageTracker()
+			defer func() {
+				mu.Lock()
+				defer mu.Unlock()
+				meta.Status = t.Status
+				meta.Usage = usage.Usage()
+				if t.Error != nil {
+					meta.Error = t.Error.Error()
+				}
+				tasks[t.ID] = meta
+			}()
+
+			// Skip tasks already completed in a previous attempt
+			if result, ok := w.completedTask(wfCtx, t); ok {
+				t.Status = TaskStatusComplete
+				setCausation(result, event)
+				wfCtx.stamp(result)
+				mu.Lock()
+				results[t.ID] = result
+				mu.Unlock()
+				return
+			}
+
+			// Defer low-priority tasks until the scheduler admits them, and
+			// time them from their admission, which may come hours later
+			ctx := batchCtx
+			if w.config.Scheduler != nil {
+				if err := w.config.Scheduler.Admit(runCtx, "task", t.ID, t.Priority); err != nil {
+					err = fmt.Errorf("task not scheduled: %w", err)
+					fail(&t, TaskStatusCancelled, err, NewErrorEvent(err).WithTask(t.ID))
+					return
+				}
+				var cancelTask context.CancelFunc
+				ctx, cancelTask = context.WithTimeout(runCtx, w.config.Timeout)
+				defer cancelTask()
+			}
+
 			taskCtx, taskCancel := context.WithTimeout(ctx, t.Timeout)
 			defer taskCancel()
 
 			// Update task status
 			t.Status = TaskStatusRunning
 
-			// Acquire semaphore if rate limiting is enabled
-			if sem != nil {
-				if err := sem.Acquire(taskCtx, 1); err != nil {
-					t.Status = TaskStatusFailed
-					t.Error = fmt.Errorf("failed to acquire semaphore: %w", err)
-					mu.Lock()
-					errors[t.ID] = t.Error
-					results[t.ID] = NewErrorEvent(t.Error)
-					mu.Unlock()
+			// Wait for a slot of the task type, then an overall one
+			for _, s := range []*semaphore.Weighted{typeSems[t.Type], sem} {
+				if s == nil {
+					continue
+				}
+				if err := s.Acquire(taskCtx, 1); err != nil {
+					err = fmt.Errorf("failed to acquire semaphore: %w", err)
+					fail(&t, TaskStatusFailed, err, NewErrorEvent(err).WithTask(t.ID))
 					return
 				}
-				defer sem.Release(1)
+				defer s.Release(1)
 			}
+			wfCtx.recorder.taskStarted()
+			defer wfCtx.recorder.taskFinished()
+			taskStart := time.Now()
+			defer func() {
+				meta.Duration = time.Since(taskStart)
+			}()
 
 			// Find matching steps for task type
 			w.mu.RLock()
@@ -320,30 +771,33 @@ func (w *Workflow) executeParallelTasks(wfCtx *Context, event *ParallelEvent, se
 			w.mu.RUnlock()
 
 			if len(steps) == 0 {
-				t.Status = TaskStatusFailed
-				t.Error = fmt.Errorf("no steps found for task type: %s", t.Type)
-				mu.Lock()
-				errors[t.ID] = t.Error
-				results[t.ID] = NewErrorEvent(t.Error)
-				mu.Unlock()
+				err := fmt.Errorf("no steps found for task type: %s", t.Type)
+				fail(&t, TaskStatusFailed, err, NewErrorEvent(err).WithTask(t.ID))
 				return
 			}
 
 			// Create task event
 			data, err := ToMap(t.Payload)
 			if err != nil {
-				t.Status = TaskStatusFailed
-				t.Error = fmt.Errorf("failed to marshal task payload: %w", err)
-				mu.Lock()
-				errors[t.ID] = t.Error
-				results[t.ID] = NewErrorEvent(t.Error)
-				mu.Unlock()
+				err = fmt.Errorf("failed to marshal task payload: %w", err)
+				fail(&t, TaskStatusFailed, err, NewErrorEvent(err).WithTask(t.ID))
 				return
 			}
 			taskEvent := &BaseEvent{
 				eventType: t.Type,
 				data:      data,
 			}
+			setCausation(taskEvent, event)
+			wfCtx.stamp(taskEvent)
+
+			// Isolate task state writes until the task succeeds
+			scope := wfCtx.Child()
+			defer scope.Cancel()
+			scope.ctx = WithUsageTracker(scope.ctx, usage)
+			if event.FailFast {
+				// Stop the steps of running tasks when another task fails
+				defer context.AfterFunc(ctx, scope.Cancel)()
+			}
 
 			// Execute each matching step with retries
 			for _, step := range steps {
@@ -351,63 +805,129 @@ func (w *Workflow) executeParallelTasks(wfCtx *Context, event *ParallelEvent, se
 				var lastErr error
 				retryPolicy := step.Config().RetryPolicy
 				for i := 0; i < retryPolicy.MaxRetries; i++ {
-					result, lastErr = step.Handle(wfCtx, taskEvent)
+					result, lastErr = w.handleStep(scope, step, taskEvent, t.ID, i+1)
+					meta.Attempts++
 					if lastErr == nil {
 						break
 					}
 					if w.config.Verbose {
 						fmt.Printf("Task %s step %s failed (attempt %d/%d): %v\n", t.ID, step.Name(), i+1, retryPolicy.MaxRetries, lastErr)
 					}
-					if i < retryPolicy.MaxRetries-1 && retryPolicy.shouldRetry(lastErr) {
-						backoff := retryPolicy.calculateBackoff(i)
-						time.Sleep(backoff)
+					if i < retryPolicy.MaxRetries-1 && retryPolicy.shouldRetry(lastErr) && scope.Context().Err() == nil {
+						w.auditRetry(scope, step, taskEvent, t.ID, i+2, lastErr)
+						if err := retryPolicy.wait(scope.Context(), i, lastErr); err != nil {
+							lastErr = err
+							break
+						}
 					} else {
 						break
 					}
 				}
 
 				if lastErr != nil {
-					t.Status = TaskStatusFailed
-					t.Error = lastErr
 					if w.config.Verbose {
 						fmt.Printf("Task %s step %s failed after %d retries: %v\n", t.ID, step.Name(), retryPolicy.MaxRetries, lastErr)
 					}
-					mu.Lock()
-					errors[t.ID] = lastErr
-					results[t.ID] = NewErrorEvent(lastErr)
-					mu.Unlock()
+					fail(&t, TaskStatusFailed, lastErr, NewErrorEvent(lastErr).WithTask(t.ID).WithStep(step.Name()))
 					return
 				}
 
 				if result != nil {
 					t.Status = TaskStatusComplete
+					setCausation(result, taskEvent)
+					wfCtx.stamp(result)
 					mu.Lock()
 					results[t.ID] = result
 					mu.Unlock()
 				}
 			}
+			t.Status = TaskStatusComplete
+			scope.Merge()
+
+			mu.Lock()
+			result, _ := results[t.ID].(Event)
+			mu.Unlock()
+			w.markTaskCompleted(wfCtx, t, result)
 		}(task)
 	}
 
 	// Wait for all tasks to complete
 	wg.Wait()
 
+	// Fail the workflow if the failed tasks exceed the event's tolerance
+	if err := event.checkOutcome(errors, firstFailure); err != nil {
+		errEvent := NewErrorEvent(err).WithStep(event.SourceStep)
+		setCausation(errEvent, event)
+		wfCtx.SendEvent(errEvent)
+		return
+	}
+
 	// Send parallel result event with execution stats
 	duration := time.Since(start)
-	wfCtx.SendEvent(NewParallelResultEvent(results, errors, duration, event.SourceStep))
+	resultEvent := NewParallelResultEvent(results, errors, duration, event.SourceStep)
+	resultEvent.Order = event.order()
+	resultEvent.Tasks = tasks
+	for _, meta := range tasks {
+		resultEvent.Usage.Add(meta.Usage)
+	}
+	setCausation(resultEvent, event)
+	wfCtx.SendEvent(resultEvent)
 }
 
 // Run executes the workflow with the given context and input parameters.
 // Returns a WorkflowHandler for monitoring execution.
 func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*WorkflowHandler, error) {
+	return w.run(ctx, []Event{NewStartEvent(inputs)}, nil)
+}
+
+// run executes the workflow from the initial events, restoring the run ID
+// and state of checkpoint if set. The extra options configure the run's
+// Context.
+func (w *Workflow) run(ctx context.Context, initial []Event, checkpoint *WorkflowCheckpoint, extra ...ContextOption) (*WorkflowHandler, error) {
 	if err := w.Initialize(); err != nil {
 		return nil, fmt.Errorf("failed to initialize workflow: %w", err)
 	}
 
 	// Create workflow context with timeout
-	wfCtx := NewContext(ctx)
+	var opts []ContextOption
+	if w.config.EventBuffer > 0 {
+		opts = append(opts, WithEventBuffer(w.config.EventBuffer))
+	}
+	if w.config.StreamBuffer > 0 {
+		opts = append(opts, WithStreamBuffer(w.config.StreamBuffer))
+	}
+	if w.config.OverflowPolicy != "" {
+		opts = append(opts, WithOverflowPolicy(w.config.OverflowPolicy))
+	}
+	opts = append(opts, extra...)
+	if checkpoint != nil {
+		opts = append(opts, WithRunID(checkpoint.RunID))
+	}
+	if w.config.LLMRateLimit != nil {
+		ctx = w.config.LLMRateLimit.apply(ctx)
+	}
+	ctx, runSpan := tracerFrom(w.config.TracerProvider).Start(ctx, "workflow.run",
+		trace.WithAttributes(attrWorkflow.String(w.config.Name)))
+	wfCtx := NewContext(ctx, opts...)
+	if checkpoint != nil {
+		for key, value := range checkpoint.State {
+			wfCtx.Set(key, value)
+		}
+	}
+	runSpan.SetAttributes(attrRunID.String(wfCtx.RunID()))
 	handler := NewWorkflowHandler(wfCtx)
 
+	w.mu.Lock()
+	if w.shutdown {
+		w.mu.Unlock()
+		wfCtx.Cancel()
+		endSpan(runSpan, ErrWorkflowShutdown)
+		return nil, ErrWorkflowShutdown
+	}
+	wfCtx.Use(w.interceptors...)
+	w.active[handler] = struct{}{}
+	w.mu.Unlock()
+
 	// Create WaitGroup to track step executions
 	var wg sync.WaitGroup
 	stepErrors := make(chan error, 1)
@@ -415,6 +935,11 @@ func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*Wor
 	// Start workflow in background
 	go func() {
 		defer func() {
+			// Subscribers have received every event the run handled, so
+			// close them before waiting, or steps blocked publishing to a
+			// subscriber that stopped reading would never finish
+			wfCtx.closeStreams()
+
 			// Wait for all steps to complete
 			done := make(chan struct{})
 			go func() {
@@ -425,41 +950,101 @@ func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*Wor
 			select {
 			case <-done:
 				// All steps completed successfully
-				if handler.Status() != WorkflowStatusComplete && handler.Status() != WorkflowStatusFailed {
+				if status := handler.Status(); status != WorkflowStatusComplete && status != WorkflowStatusFailed && status != WorkflowStatusCancelled {
 					handler.setStatus(WorkflowStatusComplete)
 				}
 			case err := <-stepErrors:
 				// Step execution failed
-				handler.err = err
-				handler.errChan <- err
-				handler.setStatus(WorkflowStatusFailed)
+				handler.fail(WorkflowStatusFailed, err)
 			}
 
+			wfCtx.recorder.finish()
+			endSpan(runSpan, handler.err)
 			close(handler.doneChan)
 			close(handler.errChan)
+
+			w.mu.Lock()
+			delete(w.active, handler)
+			w.mu.Unlock()
 		}()
 
+		// Pending delayed events are discarded once the run loop exits,
+		// unless a shutdown checkpoints them
+		delayCtx, stopDelays := context.WithCancel(wfCtx.Context())
+		defer stopDelays()
+		delays := &pendingDelays{events: make(map[*DelayEvent]uint64), stop: stopDelays}
+
+		stall := newStallDetector(w.config.StallTimeout)
+		stallTicks, stopStallTicks := stall.ticks()
+		defer stopStallTicks()
+
 		// Update status
 		handler.setStatus(WorkflowStatusRunning)
 
-		// Send start event
-		startEvent := NewStartEvent(inputs)
-		wfCtx.SendEvent(startEvent)
+		// Send the start event, or the events of a restored checkpoint
+		if checkpoint == nil {
+			for _, event := range initial {
+				wfCtx.SendEvent(event)
+			}
+		} else {
+			go func() {
+				for _, event := range initial {
+					if err := wfCtx.SendEvent(event); err != nil {
+						return
+					}
+				}
+			}()
+		}
 
-		// Process events
+		// Process events until the run is cancelled, through its parent
+		// context or WorkflowHandler.Cancel, or shut down
 		for {
 			select {
-			case <-ctx.Done():
-				handler.err = ctx.Err()
-				handler.errChan <- ctx.Err()
-				handler.setStatus(WorkflowStatusCancelled)
+			case <-handler.shutdown:
+				w.drain(wfCtx, handler, &wg, delays, nil)
+				return
+
+			case <-wfCtx.Context().Done():
+				if handler.shuttingDown() {
+					w.drain(wfCtx, handler, &wg, delays, nil)
+					return
+				}
+				handler.fail(WorkflowStatusCancelled, wfCtx.Context().Err())
 				return
 
+			case <-stallTicks:
+				if handler.paused() != nil {
+					stall.progress()
+					continue
+				}
+				if !stall.stalled() {
+					continue
+				}
+				if err := w.handleStall(wfCtx, stall, &wg); err != nil {
+					handler.fail(WorkflowStatusFailed, err)
+					return
+				}
+
 			case event := <-wfCtx.Events():
+				// Hold the event while the run is paused
+				if resume := handler.paused(); resume != nil {
+					select {
+					case <-resume:
+					case <-handler.shutdown:
+						w.drain(wfCtx, handler, &wg, delays, []Event{event})
+						return
+					case <-wfCtx.Context().Done():
+						if handler.shuttingDown() {
+							w.drain(wfCtx, handler, &wg, delays, []Event{event})
+							return
+						}
+						handler.fail(WorkflowStatusCancelled, wfCtx.Context().Err())
+						return
+					}
+				}
+				stall.progress()
 				if event == nil {
-					handler.err = fmt.Errorf("received nil event")
-					handler.errChan <- handler.err
-					handler.setStatus(WorkflowStatusFailed)
+					handler.fail(WorkflowStatusFailed, fmt.Errorf("received nil event"))
 					return
 				}
 
@@ -468,26 +1053,70 @@ func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*Wor
 					// Workflow complete
 					stopEvent := event.(*StopEvent)
 					handler.result = stopEvent.Result
+					handler.event = stopEvent
 					handler.setStatus(WorkflowStatusComplete)
 					return
 
 				case EventError:
 					// Handle error event
 					errorEvent := event.(*ErrorEvent)
-					handler.err = errorEvent.Error
-					handler.errChan <- errorEvent.Error
-					handler.setStatus(WorkflowStatusFailed)
+					w.mu.RLock()
+					steps := w.stepMap[string(EventError)]
+					w.mu.RUnlock()
+
+					// Give registered error handlers one chance to recover
+					if len(steps) > 0 && !errorEvent.handled {
+						errorEvent.handled = true
+						for _, step := range steps {
+							wg.Add(1)
+							stall.start()
+							go func(s Step) {
+								defer wg.Done()
+								defer stall.done()
+								w.executeStep(wfCtx, s, errorEvent, nil)
+							}(step)
+						}
+						continue
+					}
+
+					handler.event = errorEvent
+					handler.fail(WorkflowStatusFailed, errorEvent.Error)
 					return
 
+				case EventDelay:
+					// Dispatch the wrapped event once the delay elapses
+					delayEvent := event.(*DelayEvent)
+					delays.add(delayEvent)
+					wg.Add(1)
+					stall.start()
+					go func() {
+						defer wg.Done()
+						defer stall.done()
+						timer := time.NewTimer(delayEvent.Delay)
+						defer timer.Stop()
+						select {
+						case <-delayCtx.Done():
+							return
+						case <-timer.C:
+						}
+						if !delays.fire(delayEvent) {
+							return
+						}
+						setCausation(delayEvent.Next, delayEvent)
+						if err := wfCtx.SendEvent(delayEvent.Next); err != nil && w.config.Verbose {
+							fmt.Printf("Failed to dispatch delayed %s: %v\n", delayEvent.Next.Type(), err)
+						}
+					}()
+
 				case EventParallel:
 					// Handle parallel execution
 					parallelEvent := event.(*ParallelEvent)
-					maxParallel := int64(10) // Default to 10 parallel tasks
-					sem := semaphore.NewWeighted(maxParallel)
 					wg.Add(1)
+					stall.start()
 					go func() {
 						defer wg.Done()
-						w.executeParallelTasks(wfCtx, parallelEvent, sem)
+						defer stall.done()
+						w.executeParallelTasks(wfCtx, parallelEvent)
 					}()
 
 				case EventParallelResult:
@@ -501,14 +1130,17 @@ func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*Wor
 						if w.config.Verbose {
 							fmt.Printf("No steps found for parallel result handler\n")
 						}
+						stall.unhandledEvent(event)
 						continue
 					}
 
 					// Execute matching steps
 					for _, step := range steps {
 						wg.Add(1)
+						stall.start()
 						go func(s Step) {
 							defer wg.Done()
+							defer stall.done()
 							w.executeStep(wfCtx, s, resultEvent, nil)
 						}(step)
 					}
@@ -523,6 +1155,7 @@ func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*Wor
 						if w.config.Verbose {
 							fmt.Printf("No steps found for event type: %s\n", event.Type())
 						}
+						stall.unhandledEvent(event)
 						continue
 					}
 
@@ -541,8 +1174,10 @@ func (w *Workflow) Run(ctx context.Context, inputs map[string]interface{}) (*Wor
 					// Execute matching steps
 					for _, step := range steps {
 						wg.Add(1)
+						stall.start()
 						go func(s Step) {
 							defer wg.Done()
+							defer stall.done()
 							w.executeStep(wfCtx, s, event, sem)
 						}(step)
 					}
@@ -0,0 +1,133 @@
+package swarm
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// WorkflowDefinition is the YAML form of a Workflow: its configuration and
+// the steps wiring event types to handlers registered in a HandlerRegistry.
+//
+//	name: orders
+//	timeout: 2m
+//	steps:
+//	  - name: validate
+//	    event: StartEvent
+//	    handler: validate-order
+//	    retry: {max_retries: 5, initial_interval: 500ms}
+//	    produces: [OrderValidated]
+//	  - name: fulfill
+//	    event: OrderValidated
+//	    max_parallel: 4
+//	    on_error: continue
+//	    produces: [StopEvent]
+type WorkflowDefinition struct {
+	WorkflowConfig `yaml:",inline"`
+	// Steps are the steps of the workflow
+	Steps []WorkflowStepDefinition `yaml:"steps" json:"steps"`
+}
+
+// WorkflowStepDefinition is the YAML form of a workflow step.
+type WorkflowStepDefinition struct {
+	// Name is the name of the step
+	Name string `yaml:"name" json:"name"`
+	// Event is the type of the events the step handles
+	Event EventType `yaml:"event" json:"event"`
+	// Handler is the name of the registered handler (default Name)
+	Handler string `yaml:"handler,omitempty" json:"handler,omitempty"`
+	// MaxParallel limits the concurrent executions of the step
+	MaxParallel int64 `yaml:"max_parallel,omitempty" json:"max_parallel,omitempty"`
+	// Timeout bounds each execution of the step
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Retry configures the retries of the step (default DefaultRetryPolicy).
+	// Unset fields default to those of DefaultRetryPolicy.
+	Retry *RetryPolicy `yaml:"retry,omitempty" json:"retry,omitempty"`
+	// OnError is OnErrorFail (the default) or OnErrorContinue
+	OnError ErrorPolicy `yaml:"on_error,omitempty" json:"on_error,omitempty"`
+	// CacheTTL enables result caching, see StepConfig.CacheTTL
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty" json:"cache_ttl,omitempty"`
+	// Produces declares the types of the events the step emits, see
+	// Workflow.Validate
+	Produces []EventType `yaml:"produces,omitempty" json:"produces,omitempty"`
+}
+
+// LoadWorkflow creates a Workflow from a YAML file, looking up the step
+// handlers in registry (DefaultHandlerRegistry if nil). Environment variable
+// and secret references are expanded as by LoadSimpleFlow.
+func LoadWorkflow(path string, registry *HandlerRegistry, opts ...LoadOption) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow file: %w", err)
+	}
+
+	var def WorkflowDefinition
+	if err := decodeYAML(data, &def, opts...); err != nil {
+		return nil, err
+	}
+	return def.Build(registry)
+}
+
+// Build creates and validates the Workflow of the definition, looking up the
+// step handlers in registry (DefaultHandlerRegistry if nil). Unset
+// configuration fields default to those of DefaultConfig.
+func (d *WorkflowDefinition) Build(registry *HandlerRegistry) (*Workflow, error) {
+	if registry == nil {
+		registry = DefaultHandlerRegistry
+	}
+	if len(d.Steps) == 0 {
+		return nil, fmt.Errorf("workflow must have at least one step")
+	}
+
+	config := d.WorkflowConfig
+	defaults := DefaultConfig()
+	if config.MaxTurns == 0 {
+		config.MaxTurns = defaults.MaxTurns
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	}
+	workflow := NewWorkflow(config.Name).WithConfig(config)
+
+	for _, s := range d.Steps {
+		if s.Event == "" {
+			return nil, fmt.Errorf("step %s: event is required", s.Name)
+		}
+		switch s.OnError {
+		case "", OnErrorFail, OnErrorContinue:
+		case OnErrorCompensate:
+			return nil, fmt.Errorf("step %s: on_error %q needs a Compensate handler, which is only supported in Go", s.Name, s.OnError)
+		default:
+			return nil, fmt.Errorf("step %s: unknown on_error %q", s.Name, s.OnError)
+		}
+		name := s.Handler
+		if name == "" {
+			name = s.Name
+		}
+		handler, err := registry.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", s.Name, err)
+		}
+
+		stepConfig := StepConfig{
+			MaxParallel: s.MaxParallel,
+			Timeout:     s.Timeout,
+			OnError:     s.OnError,
+			CacheTTL:    s.CacheTTL,
+			Produces:    s.Produces,
+		}
+		if s.Retry != nil {
+			stepConfig.RetryPolicy = s.Retry.withDefaults()
+		}
+		if err := workflow.AddStep(NewStep(s.Name, s.Event, handler, stepConfig)); err != nil {
+			return nil, fmt.Errorf("step %s: %w", s.Name, err)
+		}
+	}
+	if err := workflow.Validate(); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
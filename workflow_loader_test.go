@@ -0,0 +1,89 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadWorkflow(t *testing.T) {
+	registry := NewHandlerRegistry()
+	attempts := 0
+	registry.MustRegister("validate-order", func(ctx *Context, event Event) (Event, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("inventory unavailable")
+		}
+		return NewBaseEvent(EventType("OrderValidated"), map[string]interface{}{"order": 42}), nil
+	})
+	registry.MustRegister("fulfill", func(ctx *Context, event Event) (Event, error) {
+		return NewStopEvent("fulfilled"), nil
+	})
+	AssertEqual(t, true, errors.Is(registry.Register("fulfill", func(*Context, Event) (Event, error) { return nil, nil }), ErrHandlerRegistered), "duplicate handler")
+	AssertEqual(t, "fulfill,validate-order", strings.Join(registry.Names(), ","), "handler names")
+
+	workflowYAML := `
+name: ${WORKFLOW_NAME}
+timeout: 10s
+steps:
+  - name: validate
+    event: StartEvent
+    handler: validate-order
+    retry:
+      max_retries: 2
+      initial_interval: 1ms
+    produces: [OrderValidated]
+  - name: fulfill
+    event: OrderValidated
+    max_parallel: 4
+    timeout: 1s
+    on_error: continue
+`
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	AssertNoError(t, os.WriteFile(path, []byte(workflowYAML), 0644), "WriteFile")
+	lookup := func(name string) (string, bool) { return "orders", name == "WORKFLOW_NAME" }
+	workflow, err := LoadWorkflow(path, registry, WithEnvLookup(lookup))
+	AssertNoError(t, err, "LoadWorkflow")
+	AssertEqual(t, "orders", workflow.config.Name, "name")
+	AssertEqual(t, 30, workflow.config.MaxTurns, "default max turns")
+
+	validate, fulfill := workflow.steps[0].Config(), workflow.steps[1].Config()
+	AssertEqual(t, 2, validate.RetryPolicy.MaxRetries, "max retries")
+	AssertEqual(t, time.Millisecond, validate.RetryPolicy.InitialInterval, "initial interval")
+	AssertEqual(t, DefaultRetryPolicy().Multiplier, validate.RetryPolicy.Multiplier, "default multiplier")
+	AssertEqual(t, int64(4), fulfill.MaxParallel, "max parallel")
+	AssertEqual(t, time.Second, fulfill.Timeout, "timeout")
+	AssertEqual(t, OnErrorContinue, fulfill.OnError, "on error")
+	AssertEqual(t, DefaultRetryPolicy().MaxRetries, fulfill.RetryPolicy.MaxRetries, "default retry policy")
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "Run")
+	result, err := handler.WaitValue()
+	AssertNoError(t, err, "Wait")
+	AssertEqual(t, "fulfilled", result, "result")
+	AssertEqual(t, 2, attempts, "attempts")
+}
+
+func TestWorkflowDefinitionErrors(t *testing.T) {
+	registry := NewHandlerRegistry().MustRegister("step", func(*Context, Event) (Event, error) { return nil, nil })
+	for name, def := range map[string]WorkflowDefinition{
+		"no steps":        {},
+		"missing event":   {Steps: []WorkflowStepDefinition{{Name: "step"}}},
+		"unknown handler": {Steps: []WorkflowStepDefinition{{Name: "other", Event: EventStart}}},
+		"compensate":      {Steps: []WorkflowStepDefinition{{Name: "step", Event: EventStart, OnError: OnErrorCompensate}}},
+		"unhandled event": {Steps: []WorkflowStepDefinition{{Name: "step", Event: EventStart, Produces: []EventType{"Next"}}}},
+		"invalid retry":   {Steps: []WorkflowStepDefinition{{Name: "step", Event: EventStart, Retry: &RetryPolicy{InitialInterval: time.Second, MaxInterval: time.Millisecond}}}},
+	} {
+		if _, err := def.Build(registry); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	def := WorkflowDefinition{Steps: []WorkflowStepDefinition{{Name: "other", Event: EventStart}}}
+	_, err := def.Build(registry)
+	AssertEqual(t, true, errors.Is(err, ErrHandlerNotFound), "Expected ErrHandlerNotFound")
+}
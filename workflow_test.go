@@ -2,7 +2,10 @@ package swarm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -99,7 +102,7 @@ func TestWorkflow(t *testing.T) {
 	}
 
 	// Wait for completion
-	result, err := handler.Wait()
+	result, err := handler.WaitValue()
 	if err != nil {
 		t.Fatalf("Workflow execution failed: %v", err)
 	}
@@ -115,3 +118,553 @@ func TestWorkflow(t *testing.T) {
 		t.Errorf("Expected status=success, got %v", status)
 	}
 }
+
+func TestWorkflowHandlerSubscribe(t *testing.T) {
+	gate := make(chan struct{})
+	workflow := NewWorkflow("fan-out")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		<-gate
+		return NewBaseEvent(EventType("Next"), nil), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("next", EventType("Next"), func(ctx *Context, event Event) (Event, error) {
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	first := handler.Subscribe()
+	second := handler.Subscribe()
+	close(gate)
+
+	if _, err := handler.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	for _, sub := range []*Subscription{first, second} {
+		seen := make(map[EventType]bool)
+		for event := range sub.Events() {
+			seen[event.Type()] = true
+		}
+		AssertEqual(t, true, seen[EventType("Next")], "subscriber receives Next")
+		AssertEqual(t, true, seen[EventStop], "subscriber receives Stop")
+	}
+}
+
+func TestWorkflowHandlerStalledSubscriber(t *testing.T) {
+	gate, sending := make(chan struct{}), make(chan struct{})
+	workflow := NewWorkflow("stalled")
+	workflow.AddStep(NewStep("tick", EventStart, func(ctx *Context, event Event) (Event, error) {
+		<-gate
+		close(sending)
+		for i := 0; i < 3; i++ {
+			ctx.SendEvent(NewBaseEvent(EventType("Tick"), nil))
+		}
+		return nil, nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("stop", EventStart, func(ctx *Context, event Event) (Event, error) {
+		<-sending
+		time.Sleep(20 * time.Millisecond)
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	// A subscriber that never reads must not hold up the end of the run
+	handler.Context().Bus().SubscribeWith(MatchEventTypes("Tick"), 0, OverflowBlock)
+	close(gate)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err = handler.Wait()
+	}()
+	select {
+	case <-done:
+		AssertNoError(t, err, "wait")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait blocked on a subscriber that stopped reading")
+	}
+
+	// Stream has the events sent before its first call
+	seen := make(map[EventType]bool)
+	for event := range handler.Stream() {
+		seen[event.Type()] = true
+	}
+	AssertEqual(t, true, seen[EventStart], "stream receives Start")
+	AssertEqual(t, true, seen[EventStop], "stream receives Stop")
+}
+
+func TestWorkflowHandlerCancel(t *testing.T) {
+	workflow := NewWorkflow("wait")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		<-ctx.Context().Done()
+		return nil, nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	handler.Cancel()
+
+	_, err = handler.Wait()
+	AssertEqual(t, context.Canceled, err, "wait error")
+	<-handler.doneChan
+	AssertEqual(t, WorkflowStatusCancelled, handler.Status(), "status after the steps returned")
+}
+
+func TestWorkflowRetryWaitStopsOnCancel(t *testing.T) {
+	retry := &RetryPolicy{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	for _, parallel := range []bool{false, true} {
+		attempts := make(chan struct{}, 3)
+		workflow := NewWorkflow("flaky")
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			if parallel {
+				return NewParallelEvent([]Task{{ID: "flaky", Type: EventType("Flaky"), Timeout: time.Hour}}, "start")
+			}
+			return NewBaseEvent(EventType("Flaky"), nil), nil
+		}, StepConfig{}))
+		// The provider asks to wait for an hour before retrying
+		workflow.AddStep(NewStep("flaky", EventType("Flaky"), func(ctx *Context, event Event) (Event, error) {
+			attempts <- struct{}{}
+			return nil, newRateLimitError(time.Hour)
+		}, StepConfig{RetryPolicy: retry}))
+
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+		AssertNoError(t, err, "Run")
+		<-attempts
+		handler.Cancel()
+		select {
+		case <-handler.doneChan:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("retry wait ignored the cancellation (parallel %v)", parallel)
+		}
+		AssertEqual(t, 0, len(attempts), "no retry after the cancellation")
+	}
+}
+
+func TestWorkflowHandlerStreamFiltered(t *testing.T) {
+	gate := make(chan struct{})
+	workflow := NewWorkflow("filtered")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		<-gate
+		return NewBaseEvent(EventType("Progress"), nil), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("progress", EventType("Progress"), func(ctx *Context, event Event) (Event, error) {
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	progress := handler.StreamFiltered(EventType("Progress"))
+	stops := handler.StreamFunc(func(event Event) bool { return event.Type() == EventStop })
+	close(gate)
+
+	if _, err := handler.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	for name, ch := range map[EventType]<-chan Event{"Progress": progress, EventStop: stops} {
+		n := 0
+		for event := range ch {
+			AssertEqual(t, name, event.Type(), "filtered event type")
+			n++
+		}
+		AssertEqual(t, 1, n, "filtered event count")
+	}
+}
+
+func TestWaitAs(t *testing.T) {
+	type summary struct {
+		Topic string `json:"topic"`
+		Pages int    `json:"pages"`
+	}
+
+	run := func(result func() *StopEvent) *WorkflowHandler {
+		workflow := NewWorkflow("typed")
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			return result(), nil
+		}, StepConfig{}))
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return handler
+	}
+
+	typed, err := WaitAs[summary](run(func() *StopEvent {
+		return NewTypedStopEvent(summary{Topic: "go", Pages: 3})
+	}))
+	AssertNoError(t, err, "WaitAs typed result")
+	AssertEqual(t, 3, typed.Pages, "typed result")
+
+	decoded, err := WaitAs[summary](run(func() *StopEvent {
+		return NewStopEvent(map[string]interface{}{"topic": "ai", "pages": 7})
+	}))
+	AssertNoError(t, err, "WaitAs map result")
+	AssertEqual(t, "ai", decoded.Topic, "decoded result")
+
+	_, err = WaitAs[summary](run(func() *StopEvent {
+		return NewStopEvent("plain text")
+	}))
+	AssertError(t, err, "WaitAs mismatched result")
+}
+
+func TestWorkflowDelayEvent(t *testing.T) {
+	workflow := NewWorkflow("delay")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		if err := ctx.SendAfter(20*time.Millisecond, NewBaseEvent(EventType("Poll"), nil)); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("poll", EventType("Poll"), func(ctx *Context, event Event) (Event, error) {
+		return NewStopEvent("polled"), nil
+	}, StepConfig{}))
+
+	start := time.Now()
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	result, err := handler.WaitValue()
+	AssertNoError(t, err, "Wait")
+	AssertEqual(t, "polled", result, "delayed event result")
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected delayed dispatch, finished after %v", elapsed)
+	}
+}
+
+func TestStepOnErrorPolicies(t *testing.T) {
+	noRetry := &RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	failing := func(ctx *Context, event Event) (Event, error) {
+		return nil, fmt.Errorf("enrichment unavailable")
+	}
+
+	run := func(config StepConfig) (interface{}, error) {
+		config.RetryPolicy = noRetry
+		workflow := NewWorkflow("on-error")
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			ctx.SendAfter(20*time.Millisecond, NewStopEvent("main"))
+			return NewBaseEvent(EventType("Enrich"), nil), nil
+		}, StepConfig{}))
+		if err := workflow.AddStep(NewStep("enrich", EventType("Enrich"), failing, config)); err != nil {
+			t.Fatalf("AddStep failed: %v", err)
+		}
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return handler.WaitValue()
+	}
+
+	_, err := run(StepConfig{})
+	AssertError(t, err, "default policy fails the workflow")
+
+	result, err := run(StepConfig{OnError: OnErrorContinue})
+	AssertNoError(t, err, "continue policy")
+	AssertEqual(t, "main", result, "workflow continues")
+
+	result, err = run(StepConfig{
+		OnError: OnErrorCompensate,
+		Compensate: func(ctx *Context, event Event, err error) (Event, error) {
+			return NewStopEvent("compensated"), nil
+		},
+	})
+	AssertNoError(t, err, "compensate policy")
+	AssertEqual(t, "compensated", result, "compensation result")
+
+	workflow := NewWorkflow("invalid-on-error")
+	AssertError(t, workflow.AddStep(NewStep("enrich", EventType("Enrich"), failing, StepConfig{OnError: "retry"})), "unknown policy")
+	AssertError(t, workflow.AddStep(NewStep("enrich", EventType("Enrich"), failing, StepConfig{OnError: OnErrorCompensate})), "compensate without handler")
+}
+
+func TestWorkflowErrorHandlerStep(t *testing.T) {
+	noRetry := &RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+
+	run := func(recover StepFunc) (interface{}, error) {
+		workflow := NewWorkflow("error-handler")
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			return nil, fmt.Errorf("boom")
+		}, StepConfig{RetryPolicy: noRetry}))
+		workflow.AddStep(NewStep("recover", EventError, recover, StepConfig{RetryPolicy: noRetry}))
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return handler.WaitValue()
+	}
+
+	result, err := run(func(ctx *Context, event Event) (Event, error) {
+		errEvent := event.(*ErrorEvent)
+		return NewStopEvent(map[string]interface{}{"failed_step": errEvent.StepName}), nil
+	})
+	AssertNoError(t, err, "recovered workflow")
+	AssertEqual(t, "start", result.(map[string]interface{})["failed_step"], "StepName populated")
+
+	_, err = run(func(ctx *Context, event Event) (Event, error) {
+		return event, nil
+	})
+	AssertError(t, err, "re-raised error")
+
+	_, err = run(func(ctx *Context, event Event) (Event, error) {
+		return nil, fmt.Errorf("handler failed")
+	})
+	AssertError(t, err, "failing error handler")
+}
+
+func TestWorkflowHandlerPause(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var nextRan atomic.Bool
+	workflow := NewWorkflow("pause")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		close(started)
+		<-release
+		return NewBaseEvent(EventType("Next"), nil), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("next", EventType("Next"), func(ctx *Context, event Event) (Event, error) {
+		nextRan.Store(true)
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	<-started
+	AssertNoError(t, handler.Pause(), "pause")
+	AssertEqual(t, WorkflowStatusPaused, handler.Status(), "status after pause")
+	AssertError(t, handler.Pause(), "pausing twice")
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	AssertEqual(t, false, nextRan.Load(), "step dispatched while paused")
+
+	AssertNoError(t, handler.Resume(), "resume")
+	result, err := handler.WaitValue()
+	AssertNoError(t, err, "wait")
+	AssertEqual(t, "done", result, "result")
+	AssertEqual(t, true, nextRan.Load(), "step dispatched after resume")
+	AssertError(t, handler.Resume(), "resuming a finished workflow")
+}
+
+func TestNewTypedStep(t *testing.T) {
+	type request struct {
+		Topic string `json:"topic"`
+	}
+	type outline struct {
+		Topic    string   `json:"topic"`
+		Chapters []string `json:"chapters"`
+	}
+	type book struct {
+		Title string `json:"title"`
+		Pages int    `json:"pages"`
+	}
+
+	workflow := NewWorkflow("typed-steps")
+	workflow.AddStep(NewTypedStep("plan", EventStart, "Planned", func(ctx *Context, in request) (outline, error) {
+		return outline{Topic: in.Topic, Chapters: []string{"Intro", "Usage"}}, nil
+	}, StepConfig{}))
+	workflow.AddStep(NewTypedStep("outline", "Planned", "", func(ctx *Context, in outline) (Event, error) {
+		return NewEvent(EventType("TestOutline"), testOutlineEvent{Topic: in.Topic, Chapters: in.Chapters}), nil
+	}, StepConfig{}))
+	workflow.AddStep(NewTypedStep("write", "TestOutline", EventStop, func(ctx *Context, in *testOutlineEvent) (book, error) {
+		return book{Title: in.Topic, Pages: 10 * len(in.Chapters)}, nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{"topic": "go"})
+	AssertNoError(t, err, "Run")
+	result, err := WaitAs[book](handler)
+	AssertNoError(t, err, "WaitAs")
+	AssertEqual(t, book{Title: "go", Pages: 20}, result, "result")
+
+	// Struct events are decoded from their exported fields
+	decoded, err := decodeEvent[outline](NewEvent(EventType("TestOutline"), testOutlineEvent{Topic: "ai", Chapters: []string{"One"}}))
+	AssertNoError(t, err, "decodeEvent")
+	AssertEqual(t, "ai", decoded.Topic, "decoded topic")
+	_, err = decodeEvent[outline](NewStartEvent(map[string]interface{}{"chapters": "not a list"}))
+	AssertError(t, err, "Expected error for data that does not match")
+	_, err = encodeEvent("Next", "plain text")
+	AssertError(t, err, "Expected error for output that is not an object")
+	event, err := encodeEvent("Next", Event(nil))
+	AssertNoError(t, err, "encodeEvent nil")
+	AssertEqual(t, nil, event, "nil output sends no event")
+}
+
+func TestParallelTaskConcurrency(t *testing.T) {
+	run := func(config WorkflowConfig, eventLimit int, stepLimit int64) (*WorkflowResult, int32) {
+		var active, peak atomic.Int32
+		workflow := NewWorkflow("parallel-limits").WithConfig(config)
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			var tasks []Task
+			for i := 0; i < 8; i++ {
+				tasks = append(tasks, NewTask(fmt.Sprintf("task%d", i), EventType("Work"), nil))
+			}
+			parallel, err := NewParallelEvent(tasks, "start")
+			if err != nil {
+				return nil, err
+			}
+			return parallel.WithMaxParallel(eventLimit), nil
+		}, StepConfig{}))
+		workflow.AddStep(NewStep("work", EventType("Work"), func(ctx *Context, event Event) (Event, error) {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return NewBaseEvent(EventType("Done"), nil), nil
+		}, StepConfig{MaxParallel: stepLimit}))
+		workflow.AddStep(NewStep("collect", EventParallelResult, func(ctx *Context, event Event) (Event, error) {
+			return NewStopEvent("done"), nil
+		}, StepConfig{}))
+
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+		AssertNoError(t, err, "run")
+		result, err := handler.Wait()
+		AssertNoError(t, err, "wait")
+		AssertEqual(t, 0, handler.TaskConcurrency().Running, "running tasks after the run")
+		return result, peak.Load()
+	}
+
+	config := DefaultConfig()
+	config.MaxParallelTasks = 3
+	result, peak := run(config, 0, 0)
+	AssertEqual(t, 3, result.Tasks.Limit, "workflow limit")
+	if peak > 3 || int32(result.Tasks.Peak) != peak {
+		t.Errorf("Expected at most 3 concurrent tasks, got %d (reported %d)", peak, result.Tasks.Peak)
+	}
+
+	result, peak = run(config, 2, 0)
+	AssertEqual(t, 2, result.Tasks.Limit, "event limit")
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent tasks, got %d", peak)
+	}
+
+	_, peak = run(config, 0, 1)
+	AssertEqual(t, int32(1), peak, "step limit")
+
+	result, _ = run(DefaultConfig(), 0, 0)
+	AssertEqual(t, DefaultMaxParallelTasks, result.Tasks.Limit, "default limit")
+}
+
+func TestParallelResultOrder(t *testing.T) {
+	type output struct {
+		N int `json:"n"`
+	}
+	retry := &RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	var flaky atomic.Int32
+	var resultEvent *ParallelResultEvent
+	workflow := NewWorkflow("parallel-order")
+	workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+		return NewParallelEvent([]Task{
+			NewTask("first", EventType("Work"), map[string]interface{}{"n": 1}),
+			NewTask("second", EventType("Work"), map[string]interface{}{"n": 2}).WithPriority(5),
+			NewTask("third", EventType("Work"), map[string]interface{}{"n": 3, "flaky": true}).WithPriority(1),
+		}, "start")
+	}, StepConfig{}))
+	workflow.AddStep(NewStep("work", EventType("Work"), func(ctx *Context, event Event) (Event, error) {
+		if event.Data()["flaky"] == true && flaky.Add(1) == 1 {
+			return nil, fmt.Errorf("flaky")
+		}
+		return NewBaseEvent(EventType("Done"), map[string]interface{}{"n": event.Data()["n"]}), nil
+	}, StepConfig{RetryPolicy: retry}))
+	workflow.AddStep(NewStep("collect", EventParallelResult, func(ctx *Context, event Event) (Event, error) {
+		resultEvent = event.(*ParallelResultEvent)
+		return NewStopEvent("done"), nil
+	}, StepConfig{}))
+
+	handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+	AssertNoError(t, err, "run")
+	_, err = handler.Wait()
+	AssertNoError(t, err, "wait")
+
+	AssertEqual(t, "first,second,third", strings.Join(resultEvent.Order, ","), "submission order")
+	outputs, err := ResultsAs[output](resultEvent)
+	AssertNoError(t, err, "ResultsAs")
+	AssertEqual(t, 3, len(outputs), "decoded results")
+	for i, out := range outputs {
+		AssertEqual(t, i+1, out.N, "decoded result order")
+	}
+	AssertEqual(t, 3, len(resultEvent.OrderedResults()), "ordered results")
+
+	third := resultEvent.Tasks["third"]
+	AssertEqual(t, 2, third.Attempts, "retried attempts")
+	AssertEqual(t, 1, third.Priority, "priority")
+	AssertEqual(t, TaskStatusComplete, third.Status, "status")
+	AssertEqual(t, 1, resultEvent.Tasks["first"].Attempts, "attempts")
+
+	// A failed task fails the decoding
+	resultEvent.Errors["second"] = fmt.Errorf("boom")
+	_, err = ResultsAs[output](resultEvent)
+	AssertError(t, err, "Expected the failed task to fail ResultsAs")
+}
+
+func TestParallelFailurePolicies(t *testing.T) {
+	noRetry := &RetryPolicy{MaxRetries: 1, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+	run := func(configure func(*ParallelEvent) *ParallelEvent, failures int) (*WorkflowResult, error) {
+		workflow := NewWorkflow("parallel-failures")
+		workflow.AddStep(NewStep("start", EventStart, func(ctx *Context, event Event) (Event, error) {
+			var tasks []Task
+			for i := 0; i < 4; i++ {
+				tasks = append(tasks, NewTask(fmt.Sprintf("task%d", i), EventType("Work"), map[string]interface{}{"fail": i < failures}))
+			}
+			parallel, err := NewParallelEvent(tasks, "start")
+			if err != nil {
+				return nil, err
+			}
+			return configure(parallel), nil
+		}, StepConfig{}))
+		workflow.AddStep(NewStep("work", EventType("Work"), func(ctx *Context, event Event) (Event, error) {
+			if event.Data()["fail"] == true {
+				return nil, fmt.Errorf("boom")
+			}
+			select {
+			case <-ctx.Context().Done():
+				return nil, ctx.Context().Err()
+			case <-time.After(200 * time.Millisecond):
+				return NewBaseEvent(EventType("Done"), nil), nil
+			}
+		}, StepConfig{RetryPolicy: noRetry}))
+		workflow.AddStep(NewStep("collect", EventParallelResult, func(ctx *Context, event Event) (Event, error) {
+			return NewStopEvent(event.(*ParallelResultEvent).Failed), nil
+		}, StepConfig{}))
+
+		handler, err := workflow.Run(context.Background(), map[string]interface{}{})
+		AssertNoError(t, err, "run")
+		return handler.Wait()
+	}
+
+	// Mixed results pass downstream by default and above the threshold
+	result, err := run(func(e *ParallelEvent) *ParallelEvent { return e }, 3)
+	AssertNoError(t, err, "default policy")
+	AssertEqual(t, 3, result.Value, "failed tasks")
+	result, err = run(func(e *ParallelEvent) *ParallelEvent { return e.WithMinSuccessRate(0.75) }, 1)
+	AssertNoError(t, err, "above the minimum success rate")
+	AssertEqual(t, 1, result.Value, "failed tasks")
+
+	result, err = run(func(e *ParallelEvent) *ParallelEvent { return e.WithMinSuccessRate(0.75) }, 2)
+	AssertEqual(t, true, errors.Is(err, ErrParallelTasksFailed), "Expected ErrParallelTasksFailed below the threshold")
+	errorEvent, ok := result.Event.(*ErrorEvent)
+	AssertEqual(t, true, ok, "terminating event is an ErrorEvent")
+	AssertEqual(t, "start", errorEvent.StepName, "source step")
+
+	// Fail fast cancels the running tasks
+	start := time.Now()
+	_, err = run(func(e *ParallelEvent) *ParallelEvent { return e.WithFailFast() }, 1)
+	AssertEqual(t, true, errors.Is(err, ErrParallelTasksFailed), "Expected ErrParallelTasksFailed on the first failure")
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("Expected the remaining tasks to be cancelled, run took %v", elapsed)
+	}
+
+	event, _ := NewParallelEvent([]Task{NewTask("task", EventType("Work"), nil)}, "start")
+	AssertError(t, event.WithMinSuccessRate(1.5).Validate(), "Expected an invalid success rate")
+}